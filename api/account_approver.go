@@ -0,0 +1,124 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	db "github.com/roman-adamchik/simplebank/db/sqlc"
+	"github.com/roman-adamchik/simplebank/token"
+)
+
+type accountApproversURI struct {
+	ID int64 `uri:"id" binding:"required,min=1"`
+}
+
+type addAccountApproverRequest struct {
+	Username string `json:"username" binding:"required,alphanum"`
+}
+
+func (server *Server) addAccountApprover(ctx *gin.Context) {
+	var uri accountApproversURI
+	var req addAccountApproverRequest
+
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		respondError(ctx, http.StatusBadRequest, err)
+		return
+	}
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		respondError(ctx, http.StatusBadRequest, err)
+		return
+	}
+
+	if _, ok := server.ownedAccount(ctx, uri.ID); !ok {
+		return
+	}
+
+	approver, err := server.store.CreateAccountApprover(ctx, db.CreateAccountApproverParams{
+		AccountID: uri.ID,
+		Username:  req.Username,
+	})
+	if err != nil {
+		if errors.Is(err, db.ErrUniqueViolation) || errors.Is(err, db.ErrForeignKeyViolation) {
+			respondError(ctx, http.StatusForbidden, err)
+			return
+		}
+		respondError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, approver)
+}
+
+func (server *Server) listAccountApprovers(ctx *gin.Context) {
+	var uri accountApproversURI
+
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		respondError(ctx, http.StatusBadRequest, err)
+		return
+	}
+
+	if _, ok := server.ownedAccount(ctx, uri.ID); !ok {
+		return
+	}
+
+	approvers, err := server.store.ListAccountApprovers(ctx, uri.ID)
+	if err != nil {
+		respondError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, approvers)
+}
+
+type removeAccountApproverURI struct {
+	ID       int64  `uri:"id" binding:"required,min=1"`
+	Username string `uri:"username" binding:"required,alphanum"`
+}
+
+func (server *Server) removeAccountApprover(ctx *gin.Context) {
+	var uri removeAccountApproverURI
+
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		respondError(ctx, http.StatusBadRequest, err)
+		return
+	}
+
+	if _, ok := server.ownedAccount(ctx, uri.ID); !ok {
+		return
+	}
+
+	err := server.store.DeleteAccountApprover(ctx, db.DeleteAccountApproverParams{
+		AccountID: uri.ID,
+		Username:  uri.Username,
+	})
+	if err != nil {
+		respondError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{})
+}
+
+// ownedAccount checks that the account exists and belongs to the authenticated user
+func (server *Server) ownedAccount(ctx *gin.Context, accountID int64) (db.Account, bool) {
+	account, err := server.store.GetAccount(ctx, accountID)
+	if err != nil {
+		if errors.Is(err, db.ErrRecordNotFound) {
+			respondError(ctx, http.StatusNotFound, fmt.Errorf("%w: [%d]", errAccountNotFound, accountID))
+			return account, false
+		}
+		respondError(ctx, http.StatusInternalServerError, err)
+		return account, false
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	if account.Owner != authPayload.Username {
+		err := fmt.Errorf("account [%d] doesn't belong to the authenticated user", accountID)
+		respondError(ctx, http.StatusForbidden, err)
+		return account, false
+	}
+
+	return account, true
+}
@@ -0,0 +1,230 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	mockdb "github.com/roman-adamchik/simplebank/db/mock"
+	db "github.com/roman-adamchik/simplebank/db/sqlc"
+	"github.com/roman-adamchik/simplebank/util"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func TestAddAccountApproverAPI(t *testing.T) {
+	owner, _ := getRandomUser(t)
+	approver, _ := getRandomUser(t)
+	account := getRandomAccount()
+	account.Owner = owner.Username
+
+	accountApprover := db.AccountApprover{
+		AccountID: account.ID,
+		Username:  approver.Username,
+	}
+
+	testCases := []struct {
+		name          string
+		username      string
+		body          gin.H
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:     "OK",
+			username: owner.Username,
+			body:     gin.H{"username": approver.Username},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetAccount(gomock.Any(), gomock.Eq(account.ID)).
+					Times(1).
+					Return(account, nil)
+				store.EXPECT().
+					CreateAccountApprover(gomock.Any(), gomock.Eq(db.CreateAccountApproverParams{
+						AccountID: account.ID,
+						Username:  approver.Username,
+					})).
+					Times(1).
+					Return(accountApprover, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var gotApprover db.AccountApprover
+				err := json.Unmarshal(recorder.Body.Bytes(), &gotApprover)
+				require.NoError(t, err)
+				require.Equal(t, accountApprover, gotApprover)
+			},
+		},
+		{
+			name:     "NotOwner",
+			username: approver.Username,
+			body:     gin.H{"username": approver.Username},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetAccount(gomock.Any(), gomock.Eq(account.ID)).
+					Times(1).
+					Return(account, nil)
+				store.EXPECT().
+					CreateAccountApprover(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name:     "AccountNotFound",
+			username: owner.Username,
+			body:     gin.H{"username": approver.Username},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetAccount(gomock.Any(), gomock.Eq(account.ID)).
+					Times(1).
+					Return(db.Account{}, db.ErrRecordNotFound)
+				store.EXPECT().
+					CreateAccountApprover(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name:     "UserNotFound",
+			username: owner.Username,
+			body:     gin.H{"username": approver.Username},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetAccount(gomock.Any(), gomock.Eq(account.ID)).
+					Times(1).
+					Return(account, nil)
+				store.EXPECT().
+					CreateAccountApprover(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.AccountApprover{}, &db.ConstraintError{Violation: db.ErrForeignKeyViolation, Constraint: "account_approvers_username_fkey"})
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name:     "InvalidUsername",
+			username: owner.Username,
+			body:     gin.H{"username": "not valid!"},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetAccount(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			data, err := json.Marshal(tc.body)
+			require.NoError(t, err)
+
+			url := fmt.Sprintf("/accounts/%d/approvers", account.ID)
+			request, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(data))
+			require.NoError(t, err)
+
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, tc.username, time.Minute)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestListAccountApproversAPI(t *testing.T) {
+	owner, _ := getRandomUser(t)
+	account := getRandomAccount()
+	account.Owner = owner.Username
+
+	approvers := []db.AccountApprover{
+		{AccountID: account.ID, Username: util.RandomOwner()},
+		{AccountID: account.ID, Username: util.RandomOwner()},
+	}
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().
+		GetAccount(gomock.Any(), gomock.Eq(account.ID)).
+		Times(1).
+		Return(account, nil)
+	store.EXPECT().
+		ListAccountApprovers(gomock.Any(), gomock.Eq(account.ID)).
+		Times(1).
+		Return(approvers, nil)
+
+	server := newTestServer(t, store)
+	recorder := httptest.NewRecorder()
+
+	url := fmt.Sprintf("/accounts/%d/approvers", account.ID)
+	request, err := http.NewRequest(http.MethodGet, url, nil)
+	require.NoError(t, err)
+
+	addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, owner.Username, time.Minute)
+	server.router.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusOK, recorder.Code)
+
+	var gotApprovers []db.AccountApprover
+	err = json.Unmarshal(recorder.Body.Bytes(), &gotApprovers)
+	require.NoError(t, err)
+	require.Equal(t, approvers, gotApprovers)
+}
+
+func TestRemoveAccountApproverAPI(t *testing.T) {
+	owner, _ := getRandomUser(t)
+	account := getRandomAccount()
+	account.Owner = owner.Username
+	username := util.RandomOwner()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().
+		GetAccount(gomock.Any(), gomock.Eq(account.ID)).
+		Times(1).
+		Return(account, nil)
+	store.EXPECT().
+		DeleteAccountApprover(gomock.Any(), gomock.Eq(db.DeleteAccountApproverParams{
+			AccountID: account.ID,
+			Username:  username,
+		})).
+		Times(1).
+		Return(nil)
+
+	server := newTestServer(t, store)
+	recorder := httptest.NewRecorder()
+
+	url := fmt.Sprintf("/accounts/%d/approvers/%s", account.ID, username)
+	request, err := http.NewRequest(http.MethodDelete, url, nil)
+	require.NoError(t, err)
+
+	addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, owner.Username, time.Minute)
+	server.router.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusOK, recorder.Code)
+}
@@ -0,0 +1,162 @@
+package api
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-contrib/sse"
+	"github.com/gin-gonic/gin"
+	db "github.com/roman-adamchik/simplebank/db/sqlc"
+	"github.com/roman-adamchik/simplebank/token"
+)
+
+const (
+	// streamHeartbeat keeps idle streams from being closed by proxies
+	streamHeartbeat  = 15 * time.Second
+	streamReplaySize = 100
+	// streamReplayMax is the number of missed entries replayed at most, a client that missed more is told to resync
+	streamReplayMax   = 1000
+	lastEventIDHeader = "Last-Event-ID"
+)
+
+// list of events sent on the account stream
+const (
+	streamEventEntry   = "entry"
+	streamEventBalance = "balance"
+	// streamEventResync tells a client that missed too many entries to reload its accounts and reconnect without an event id
+	streamEventResync = "resync"
+)
+
+// streamAccountUpdates streams the entries and balances of the accounts of the authenticated user as Server-Sent Events.
+// Every entry event carries the entry id as its event id. A client reconnecting with the Last-Event-ID header
+// (or the last_event_id query parameter) first receives the entries it missed and the current balances of their accounts,
+// unless it missed more than streamReplayMax entries, in which case it receives a resync event and the stream ends
+func (server *Server) streamAccountUpdates(ctx *gin.Context) {
+	lastEventID := ctx.GetHeader(lastEventIDHeader)
+	if lastEventID == "" {
+		lastEventID = ctx.Query("last_event_id")
+	}
+	var fromID int64
+	if lastEventID != "" {
+		var err error
+		fromID, err = strconv.ParseInt(lastEventID, 10, 64)
+		if err != nil || fromID < 0 {
+			err = fmt.Errorf("invalid last event id %q", lastEventID)
+			respondError(ctx, http.StatusBadRequest, err)
+			return
+		}
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	// subscribe before the replay, so nothing committed in between is missed
+	sub := server.updates.Subscribe(authPayload.Username)
+	defer sub.Close()
+
+	// the first page of the replay is read before the response starts, so that an error can still be reported
+	var missed []db.Entry
+	if fromID > 0 {
+		var err error
+		missed, err = server.listMissedEntries(ctx, authPayload.Username, fromID)
+		if err != nil {
+			respondError(ctx, http.StatusInternalServerError, err)
+			return
+		}
+	}
+
+	ctx.Header("Content-Type", sse.ContentType)
+	ctx.Header("Cache-Control", "no-cache")
+	ctx.Header("Connection", "keep-alive")
+	ctx.Header("X-Accel-Buffering", "no")
+	ctx.Status(http.StatusOK)
+
+	// the missed entries are sent a page at a time, and a client that missed more than streamReplayMax entries
+	// is told to resync, so that an old event id does not make the server load the whole history of the accounts.
+	// Entries are committed out of id order, so live updates are only skipped if they were replayed
+	replayed := make(map[int64]bool)
+	var accountIDs []int64
+	seen := make(map[int64]bool)
+	for len(missed) > 0 {
+		for _, entry := range missed {
+			replayed[entry.ID] = true
+			if !seen[entry.AccountID] {
+				seen[entry.AccountID] = true
+				accountIDs = append(accountIDs, entry.AccountID)
+			}
+			writeStreamEvent(ctx.Writer, streamEventEntry, entry.ID, entry)
+		}
+		ctx.Writer.Flush()
+
+		if len(missed) < streamReplaySize {
+			break
+		}
+		if len(replayed) >= streamReplayMax {
+			writeStreamEvent(ctx.Writer, streamEventResync, 0, gin.H{"message": "too many missed entries, reload the accounts and reconnect without an event id"})
+			ctx.Writer.Flush()
+			return
+		}
+
+		var err error
+		missed, err = server.listMissedEntries(ctx, authPayload.Username, missed[len(missed)-1].ID)
+		if err != nil {
+			// the client reconnects with the last entry it received to resume the replay
+			log.Printf("cannot replay the entries of %s: %v", authPayload.Username, err)
+			return
+		}
+	}
+
+	for _, accountID := range accountIDs {
+		account, err := server.store.GetAccount(ctx, accountID)
+		if err != nil {
+			log.Printf("cannot replay the balance of account %d: %v", accountID, err)
+			return
+		}
+		writeStreamEvent(ctx.Writer, streamEventBalance, 0, account)
+	}
+	ctx.Writer.Flush()
+
+	heartbeat := time.NewTicker(streamHeartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-ctx.Request.Context().Done():
+			return
+		case <-heartbeat.C:
+			_, _ = io.WriteString(ctx.Writer, ": ping\n\n")
+		case update, ok := <-sub.C:
+			if !ok {
+				// the client fell behind, it reconnects with its last event id to catch up
+				return
+			}
+			if replayed[update.Entry.ID] {
+				continue
+			}
+			writeStreamEvent(ctx.Writer, streamEventEntry, update.Entry.ID, update.Entry)
+			writeStreamEvent(ctx.Writer, streamEventBalance, 0, update.Account)
+		}
+		ctx.Writer.Flush()
+	}
+}
+
+// listMissedEntries lists a page of the entries of the accounts of an owner created after the given entry
+func (server *Server) listMissedEntries(ctx *gin.Context, owner string, afterID int64) ([]db.Entry, error) {
+	return server.store.ListOwnerEntriesAfter(ctx, db.ListOwnerEntriesAfterParams{
+		Owner: owner,
+		ID:    afterID,
+		Limit: streamReplaySize,
+	})
+}
+
+// writeStreamEvent writes a Server-Sent Event with a JSON payload, the event id is left out if it is 0
+func writeStreamEvent(w io.Writer, event string, id int64, data any) {
+	e := sse.Event{Event: event, Data: data}
+	if id > 0 {
+		e.Id = strconv.FormatInt(id, 10)
+	}
+	_ = sse.Encode(w, e)
+}
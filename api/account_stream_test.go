@@ -0,0 +1,220 @@
+package api
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	mockdb "github.com/roman-adamchik/simplebank/db/mock"
+	db "github.com/roman-adamchik/simplebank/db/sqlc"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+type streamEvent struct {
+	event string
+	id    string
+	data  string
+}
+
+// readStreamEvent reads the next event of a Server-Sent Events stream, skipping comments
+func readStreamEvent(t *testing.T, reader *bufio.Reader) streamEvent {
+	t.Helper()
+
+	var e streamEvent
+	for {
+		line, err := reader.ReadString('\n')
+		require.NoError(t, err)
+		line = strings.TrimSuffix(line, "\n")
+
+		switch {
+		case line == "":
+			if e.event != "" {
+				return e
+			}
+		case strings.HasPrefix(line, "event:"):
+			e.event = strings.TrimSpace(strings.TrimPrefix(line, "event:"))
+		case strings.HasPrefix(line, "id:"):
+			e.id = strings.TrimSpace(strings.TrimPrefix(line, "id:"))
+		case strings.HasPrefix(line, "data:"):
+			e.data = strings.TrimSpace(strings.TrimPrefix(line, "data:"))
+		}
+	}
+}
+
+func TestStreamAccountUpdatesAPI(t *testing.T) {
+	user, _ := getRandomUser(t)
+	account := getRandomAccount()
+	account.Owner = user.Username
+
+	missed := []db.Entry{
+		{ID: 11, AccountID: account.ID, Amount: 10},
+		{ID: 13, AccountID: account.ID, Amount: -5},
+	}
+	live := db.AccountUpdate{
+		Entry:   db.Entry{ID: 12, AccountID: account.ID, Amount: 7},
+		Account: account,
+	}
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().
+		ListOwnerEntriesAfter(gomock.Any(), gomock.Eq(db.ListOwnerEntriesAfterParams{
+			Owner: user.Username,
+			ID:    10,
+			Limit: streamReplaySize,
+		})).
+		Times(1).
+		Return(missed, nil)
+	store.EXPECT().
+		GetAccount(gomock.Any(), gomock.Eq(account.ID)).
+		Times(1).
+		Return(account, nil)
+
+	server := newTestServer(t, store)
+	httpServer := httptest.NewServer(server.router)
+	defer httpServer.Close()
+
+	request, err := http.NewRequest(http.MethodGet, httpServer.URL+"/accounts/stream", nil)
+	require.NoError(t, err)
+	request.Header.Set(lastEventIDHeader, "10")
+	addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
+
+	response, err := http.DefaultClient.Do(request)
+	require.NoError(t, err)
+	defer response.Body.Close()
+	require.Equal(t, http.StatusOK, response.StatusCode)
+	require.Contains(t, response.Header.Get("Content-Type"), "text/event-stream")
+
+	reader := bufio.NewReader(response.Body)
+
+	// the missed entries are replayed first, followed by the current balance
+	for _, entry := range missed {
+		e := readStreamEvent(t, reader)
+		require.Equal(t, streamEventEntry, e.event)
+		require.Equal(t, strconv.FormatInt(entry.ID, 10), e.id)
+
+		var got db.Entry
+		require.NoError(t, json.Unmarshal([]byte(e.data), &got))
+		require.Equal(t, entry.ID, got.ID)
+		require.Equal(t, entry.Amount, got.Amount)
+	}
+	e := readStreamEvent(t, reader)
+	require.Equal(t, streamEventBalance, e.event)
+	require.Empty(t, e.id)
+
+	// a replayed entry is not sent twice, an entry committed out of order is
+	server.updates.Publish(db.AccountUpdate{Entry: missed[1], Account: account})
+	server.updates.Publish(live)
+
+	e = readStreamEvent(t, reader)
+	require.Equal(t, streamEventEntry, e.event)
+	require.Equal(t, "12", e.id)
+
+	e = readStreamEvent(t, reader)
+	require.Equal(t, streamEventBalance, e.event)
+
+	var gotAccount db.Account
+	require.NoError(t, json.Unmarshal([]byte(e.data), &gotAccount))
+	require.Equal(t, account.ID, gotAccount.ID)
+	require.Equal(t, account.Balance, gotAccount.Balance)
+}
+
+func TestStreamAccountUpdatesResync(t *testing.T) {
+	user, _ := getRandomUser(t)
+	account := getRandomAccount()
+	account.Owner = user.Username
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// every page is full, as for an event id far behind on a busy account
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().
+		ListOwnerEntriesAfter(gomock.Any(), gomock.Any()).
+		Times(streamReplayMax / streamReplaySize).
+		DoAndReturn(func(_ any, arg db.ListOwnerEntriesAfterParams) ([]db.Entry, error) {
+			require.Equal(t, user.Username, arg.Owner)
+			entries := make([]db.Entry, arg.Limit)
+			for i := range entries {
+				entries[i] = db.Entry{ID: arg.ID + int64(i) + 1, AccountID: account.ID, Amount: 1}
+			}
+			return entries, nil
+		})
+	store.EXPECT().
+		GetAccount(gomock.Any(), gomock.Any()).
+		Times(0)
+
+	server := newTestServer(t, store)
+	httpServer := httptest.NewServer(server.router)
+	defer httpServer.Close()
+
+	request, err := http.NewRequest(http.MethodGet, httpServer.URL+"/accounts/stream", nil)
+	require.NoError(t, err)
+	request.Header.Set(lastEventIDHeader, "1")
+	addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
+
+	response, err := http.DefaultClient.Do(request)
+	require.NoError(t, err)
+	defer response.Body.Close()
+	require.Equal(t, http.StatusOK, response.StatusCode)
+
+	reader := bufio.NewReader(response.Body)
+	for i := range streamReplayMax {
+		e := readStreamEvent(t, reader)
+		require.Equal(t, streamEventEntry, e.event)
+		require.Equal(t, strconv.Itoa(i+2), e.id)
+	}
+
+	// the replay stops at the cap and the stream ends
+	e := readStreamEvent(t, reader)
+	require.Equal(t, streamEventResync, e.event)
+	require.Empty(t, e.id)
+
+	_, err = reader.ReadString('\n')
+	require.ErrorIs(t, err, io.EOF)
+}
+
+func TestStreamAccountUpdatesInvalidLastEventID(t *testing.T) {
+	user, _ := getRandomUser(t)
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().
+		ListOwnerEntriesAfter(gomock.Any(), gomock.Any()).
+		Times(0)
+
+	server := newTestServer(t, store)
+	recorder := httptest.NewRecorder()
+
+	request, err := http.NewRequest(http.MethodGet, "/accounts/stream?last_event_id=abc", nil)
+	require.NoError(t, err)
+
+	addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
+	server.router.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusBadRequest, recorder.Code)
+}
+
+func TestStreamAccountUpdatesNoAuthorization(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	server := newTestServer(t, mockdb.NewMockStore(ctrl))
+	recorder := httptest.NewRecorder()
+
+	request, err := http.NewRequest(http.MethodGet, "/accounts/stream", nil)
+	require.NoError(t, err)
+
+	server.router.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusUnauthorized, recorder.Code)
+}
@@ -48,7 +48,7 @@ func TestGetAccountAPI(t *testing.T) {
 				store.EXPECT().
 					GetAccount(gomock.Any(), gomock.Eq(account.ID)).
 					Times(1).
-					Return(db.Account{}, pgx.ErrNoRows)
+					Return(db.Account{}, db.ErrRecordNotFound)
 			},
 			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
 				require.Equal(t, http.StatusNotFound, recorder.Code)
@@ -123,7 +123,7 @@ func TestCreateAccountAPI(t *testing.T) {
 			currency: account.Currency,
 			buildStubs: func(store *mockdb.MockStore) {
 				store.EXPECT().
-					CreateAccount(gomock.Any(), gomock.Eq(db.CreateAccountParams{
+					CreateAccountTx(gomock.Any(), gomock.Eq(db.CreateAccountParams{
 						Owner:    account.Owner,
 						Balance:  0,
 						Currency: account.Currency,
@@ -142,7 +142,7 @@ func TestCreateAccountAPI(t *testing.T) {
 			currency: account.Currency,
 			buildStubs: func(store *mockdb.MockStore) {
 				store.EXPECT().
-					CreateAccount(gomock.Any(), gomock.Any()).
+					CreateAccountTx(gomock.Any(), gomock.Any()).
 					Times(0)
 			},
 			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
@@ -155,7 +155,7 @@ func TestCreateAccountAPI(t *testing.T) {
 			currency: "Invalid",
 			buildStubs: func(store *mockdb.MockStore) {
 				store.EXPECT().
-					CreateAccount(gomock.Any(), gomock.Any()).
+					CreateAccountTx(gomock.Any(), gomock.Any()).
 					Times(0)
 			},
 			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
@@ -168,7 +168,7 @@ func TestCreateAccountAPI(t *testing.T) {
 			currency: account.Currency,
 			buildStubs: func(store *mockdb.MockStore) {
 				store.EXPECT().
-					CreateAccount(gomock.Any(), gomock.Eq(db.CreateAccountParams{
+					CreateAccountTx(gomock.Any(), gomock.Eq(db.CreateAccountParams{
 						Owner:    account.Owner,
 						Balance:  0,
 						Currency: account.Currency,
@@ -180,6 +180,37 @@ func TestCreateAccountAPI(t *testing.T) {
 				require.Equal(t, http.StatusInternalServerError, recorder.Code)
 			},
 		},
+		{
+			name:     "AlreadyExists",
+			owner:    account.Owner,
+			currency: account.Currency,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					CreateAccountTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.Account{}, &db.ConstraintError{Violation: db.ErrUniqueViolation, Constraint: "owner_currency_key"})
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+				requireProblem(t, recorder, codeAlreadyExists)
+			},
+		},
+		{
+			name:     "Retryable",
+			owner:    account.Owner,
+			currency: account.Currency,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					CreateAccountTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.Account{}, fmt.Errorf("%w: could not serialize access", db.ErrRetryable))
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusServiceUnavailable, recorder.Code)
+				requireProblem(t, recorder, codeRetryable)
+				require.Equal(t, "1", recorder.Header().Get("Retry-After"))
+			},
+		},
 	}
 
 	for i := range testCases {
@@ -246,10 +277,11 @@ func TestDeleteAccountAPI(t *testing.T) {
 				store.EXPECT().
 					DeleteAccount(gomock.Any(), gomock.Eq(account.ID)).
 					Times(1).
-					Return(pgx.ErrNoRows)
+					Return(db.ErrRecordNotFound)
 			},
 			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
 				require.Equal(t, http.StatusNotFound, recorder.Code)
+				requireProblem(t, recorder, "account_not_found")
 			},
 		},
 		{
@@ -262,6 +294,7 @@ func TestDeleteAccountAPI(t *testing.T) {
 			},
 			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
 				require.Equal(t, http.StatusBadRequest, recorder.Code)
+				requireProblem(t, recorder, "validation_failed")
 			},
 		},
 		{
@@ -275,6 +308,7 @@ func TestDeleteAccountAPI(t *testing.T) {
 			},
 			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
 				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+				requireProblem(t, recorder, "internal_error")
 			},
 		},
 	}
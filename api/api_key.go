@@ -0,0 +1,417 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/netip"
+	"slices"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgtype"
+	db "github.com/roman-adamchik/simplebank/db/sqlc"
+	"github.com/roman-adamchik/simplebank/token"
+	"github.com/roman-adamchik/simplebank/util"
+)
+
+// list of scopes an API key or an OAuth client can be given
+const (
+	ScopeAccountsRead   = "accounts:read"
+	ScopeAccountsWrite  = "accounts:write"
+	ScopeTransfersRead  = "transfers:read"
+	ScopeTransfersWrite = "transfers:write"
+	ScopeHoldsRead      = "holds:read"
+	ScopeHoldsWrite     = "holds:write"
+	ScopeWebhooksRead   = "webhooks:read"
+	ScopeWebhooksWrite  = "webhooks:write"
+	ScopeProfileRead    = "profile:read"
+)
+
+var scopes = []string{
+	ScopeAccountsRead, ScopeAccountsWrite,
+	ScopeTransfersRead, ScopeTransfersWrite,
+	ScopeHoldsRead, ScopeHoldsWrite,
+	ScopeWebhooksRead, ScopeWebhooksWrite,
+	ScopeProfileRead,
+}
+
+// routeScopes maps the routes scoped credentials (API keys and tokens of OAuth clients) can call
+// to the scope they need, keyed by method and path.
+// The other routes only accept the user's own access tokens, so that a key cannot manage keys, passwords or 2FA
+var routeScopes = map[string]string{
+	"POST /transfers":                          ScopeTransfersWrite,
+	"POST /transfers/:id/approve":              ScopeTransfersWrite,
+	"POST /transfers/:id/reject":               ScopeTransfersWrite,
+	"POST /transfers/batch":                    ScopeTransfersWrite,
+	"GET /transfers/batch/:id":                 ScopeTransfersRead,
+	"POST /standing_orders":                    ScopeTransfersWrite,
+	"GET /standing_orders/:id":                 ScopeTransfersRead,
+	"GET /standing_orders/:id/runs":            ScopeTransfersRead,
+	"POST /standing_orders/:id/pause":          ScopeTransfersWrite,
+	"POST /standing_orders/:id/resume":         ScopeTransfersWrite,
+	"POST /standing_orders/:id/cancel":         ScopeTransfersWrite,
+	"POST /accounts/:id/approvers":             ScopeAccountsWrite,
+	"GET /accounts/:id/approvers":              ScopeAccountsRead,
+	"DELETE /accounts/:id/approvers/:username": ScopeAccountsWrite,
+	"GET /accounts/:id/entries":                ScopeAccountsRead,
+	"GET /accounts/stream":                     ScopeAccountsRead,
+	"POST /holds":                              ScopeHoldsWrite,
+	"GET /holds/:id":                           ScopeHoldsRead,
+	"POST /holds/:id/capture":                  ScopeHoldsWrite,
+	"POST /holds/:id/release":                  ScopeHoldsWrite,
+	"POST /webhooks":                           ScopeWebhooksWrite,
+	"GET /webhooks":                            ScopeWebhooksRead,
+	"GET /webhooks/:id":                        ScopeWebhooksRead,
+	"PUT /webhooks/:id":                        ScopeWebhooksWrite,
+	"DELETE /webhooks/:id":                     ScopeWebhooksWrite,
+	"POST /webhooks/:id/enable":                ScopeWebhooksWrite,
+	"GET /webhooks/:id/deliveries":             ScopeWebhooksRead,
+	"GET /users/me":                            ScopeProfileRead,
+}
+
+const (
+	// apiKeyPrefix starts every API key, so that leaked keys are easy to search for
+	apiKeyPrefix = "sbk_"
+	// apiKeyDisplayLength is how much of a key is kept to tell it apart
+	apiKeyDisplayLength = len(apiKeyPrefix) + 6
+	// apiKeyTouchInterval is how often the last use of a key is written down at most
+	apiKeyTouchInterval = time.Minute
+)
+
+var (
+	errInvalidAPIKey      = errors.New("api key is invalid")
+	errAPIKeyExpired      = errors.New("api key is expired")
+	errAPIKeyRevoked      = errors.New("api key is revoked")
+	errScopeNotAllowed    = errors.New("scoped credentials cannot access this resource")
+	errAPIKeyExpiryInPast = errors.New("expires_at must be in the future")
+)
+
+// authorizeAPIKey checks an API key against the route it is used on and the client IP.
+// It responds with an error and returns nil if the key cannot be used
+func (server *Server) authorizeAPIKey(ctx *gin.Context, key string) *token.Payload {
+	apiKey, err := server.store.GetAPIKeyByHash(ctx, util.HashSecret(key))
+	if err != nil {
+		if errors.Is(err, db.ErrRecordNotFound) {
+			abortWithError(ctx, http.StatusUnauthorized, errInvalidAPIKey)
+			return nil
+		}
+		abortWithError(ctx, http.StatusInternalServerError, err)
+		return nil
+	}
+
+	now := time.Now()
+	if apiKey.RevokedAt.Valid {
+		abortWithError(ctx, http.StatusUnauthorized, errAPIKeyRevoked)
+		return nil
+	}
+	if apiKey.ExpiresAt.Valid && !now.Before(apiKey.ExpiresAt.Time) {
+		abortWithError(ctx, http.StatusUnauthorized, errAPIKeyExpired)
+		return nil
+	}
+
+	if !ipAllowed(apiKey.AllowedIps, ctx.ClientIP()) {
+		err := fmt.Errorf("api key cannot be used from %s", ctx.ClientIP())
+		abortWithError(ctx, http.StatusForbidden, err)
+		return nil
+	}
+
+	if !checkRouteScope(ctx, "api key", apiKey.Scopes) {
+		return nil
+	}
+
+	// the last use is only informative, so a failure to write it down does not refuse the request
+	err = server.store.TouchAPIKey(ctx, db.TouchAPIKeyParams{
+		LastUsedAt: pgtype.Timestamptz{Time: now, Valid: true},
+		ID:         apiKey.ID,
+		UsedBefore: pgtype.Timestamptz{Time: now.Add(-apiKeyTouchInterval), Valid: true},
+	})
+	if err != nil {
+		log.Printf("cannot record use of api key %d: %v", apiKey.ID, err)
+	}
+
+	return &token.Payload{
+		Username:  apiKey.Username,
+		IssuedAt:  apiKey.CreatedAt.Time,
+		ExpiredAt: apiKey.ExpiresAt.Time,
+	}
+}
+
+// checkRouteScope checks that a scoped credential can call the current route.
+// It responds with an error and returns false if it cannot
+func checkRouteScope(ctx *gin.Context, credential string, granted []string) bool {
+	scope, ok := routeScopes[ctx.Request.Method+" "+ctx.FullPath()]
+	if !ok {
+		abortWithError(ctx, http.StatusForbidden, errScopeNotAllowed)
+		return false
+	}
+	if !slices.Contains(granted, scope) {
+		err := fmt.Errorf("%s is missing the %s scope", credential, scope)
+		abortWithError(ctx, http.StatusForbidden, err)
+		return false
+	}
+
+	return true
+}
+
+// ipAllowed checks a client IP against the IPs and CIDR ranges of an API key, any IP is allowed if there are none
+func ipAllowed(allowedIPs []string, clientIP string) bool {
+	if len(allowedIPs) == 0 {
+		return true
+	}
+
+	ip, err := netip.ParseAddr(clientIP)
+	if err != nil {
+		return false
+	}
+	ip = ip.Unmap()
+
+	for _, allowed := range allowedIPs {
+		if prefix, err := netip.ParsePrefix(allowed); err == nil {
+			if prefix.Contains(ip) {
+				return true
+			}
+			continue
+		}
+		if addr, err := netip.ParseAddr(allowed); err == nil && addr.Unmap() == ip {
+			return true
+		}
+	}
+
+	return false
+}
+
+type createAPIKeyRequest struct {
+	Name       string     `json:"name" binding:"required,max=64"`
+	Scopes     []string   `json:"scopes" binding:"required,min=1,unique,dive,scope"`
+	AllowedIPs []string   `json:"allowed_ips" binding:"omitempty,dive,ip|cidr"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+}
+
+type apiKeyResponse struct {
+	ID         int64              `json:"id"`
+	Username   string             `json:"username"`
+	Name       string             `json:"name"`
+	KeyPrefix  string             `json:"key_prefix"`
+	Scopes     []string           `json:"scopes"`
+	AllowedIPs []string           `json:"allowed_ips"`
+	CreatedBy  string             `json:"created_by"`
+	ExpiresAt  pgtype.Timestamptz `json:"expires_at"`
+	LastUsedAt pgtype.Timestamptz `json:"last_used_at"`
+	RevokedAt  pgtype.Timestamptz `json:"revoked_at"`
+	CreatedAt  pgtype.Timestamptz `json:"created_at"`
+}
+
+func newAPIKeyResponse(apiKey db.ApiKey) apiKeyResponse {
+	return apiKeyResponse{
+		ID:         apiKey.ID,
+		Username:   apiKey.Username,
+		Name:       apiKey.Name,
+		KeyPrefix:  apiKey.KeyPrefix,
+		Scopes:     apiKey.Scopes,
+		AllowedIPs: apiKey.AllowedIps,
+		CreatedBy:  apiKey.CreatedBy,
+		ExpiresAt:  apiKey.ExpiresAt,
+		LastUsedAt: apiKey.LastUsedAt,
+		RevokedAt:  apiKey.RevokedAt,
+		CreatedAt:  apiKey.CreatedAt,
+	}
+}
+
+type createAPIKeyResponse struct {
+	// Key is only shown once, only its hash is stored
+	Key    string         `json:"key"`
+	APIKey apiKeyResponse `json:"api_key"`
+}
+
+// createAPIKey creates an API key for the authenticated user
+func (server *Server) createAPIKey(ctx *gin.Context) {
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	server.issueAPIKey(ctx, authPayload.Username, authPayload.Username)
+}
+
+type userAPIKeysURI struct {
+	Username string `uri:"username" binding:"required,alphanum"`
+}
+
+// createUserAPIKey lets an admin create an API key for another user, such as a service account
+func (server *Server) createUserAPIKey(ctx *gin.Context) {
+	var uri userAPIKeysURI
+
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		respondError(ctx, http.StatusBadRequest, err)
+		return
+	}
+
+	user, err := server.store.GetUser(ctx, uri.Username)
+	if err != nil {
+		if errors.Is(err, db.ErrRecordNotFound) {
+			respondError(ctx, http.StatusNotFound, err)
+			return
+		}
+		respondError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+	if user.DeactivatedAt.Valid {
+		respondError(ctx, http.StatusConflict, db.ErrUserDeactivated)
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	server.issueAPIKey(ctx, user.Username, authPayload.Username)
+}
+
+// issueAPIKey generates an API key for a user and stores its hash
+func (server *Server) issueAPIKey(ctx *gin.Context, username string, createdBy string) {
+	var req createAPIKeyRequest
+
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		respondError(ctx, http.StatusBadRequest, err)
+		return
+	}
+
+	var expiresAt pgtype.Timestamptz
+	if req.ExpiresAt != nil {
+		if !req.ExpiresAt.After(time.Now()) {
+			respondError(ctx, http.StatusBadRequest, errAPIKeyExpiryInPast)
+			return
+		}
+		expiresAt = pgtype.Timestamptz{Time: *req.ExpiresAt, Valid: true}
+	}
+	allowedIPs := req.AllowedIPs
+	if allowedIPs == nil {
+		allowedIPs = []string{}
+	}
+
+	secret, err := util.RandomSecret(32)
+	if err != nil {
+		respondError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+	key := apiKeyPrefix + secret
+
+	apiKey, err := server.store.CreateAPIKey(ctx, db.CreateAPIKeyParams{
+		Username:   username,
+		Name:       req.Name,
+		KeyPrefix:  key[:apiKeyDisplayLength],
+		KeyHash:    util.HashSecret(key),
+		Scopes:     req.Scopes,
+		AllowedIps: allowedIPs,
+		CreatedBy:  createdBy,
+		ExpiresAt:  expiresAt,
+	})
+	if err != nil {
+		respondError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	rsp := createAPIKeyResponse{
+		Key:    key,
+		APIKey: newAPIKeyResponse(apiKey),
+	}
+	ctx.JSON(http.StatusOK, rsp)
+}
+
+// listAPIKeys lists the API keys of the authenticated user, including the revoked ones
+func (server *Server) listAPIKeys(ctx *gin.Context) {
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	server.renderAPIKeys(ctx, authPayload.Username)
+}
+
+// listUserAPIKeys lets an admin list the API keys of a user
+func (server *Server) listUserAPIKeys(ctx *gin.Context) {
+	var uri userAPIKeysURI
+
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		respondError(ctx, http.StatusBadRequest, err)
+		return
+	}
+
+	server.renderAPIKeys(ctx, uri.Username)
+}
+
+func (server *Server) renderAPIKeys(ctx *gin.Context, username string) {
+	apiKeys, err := server.store.ListAPIKeys(ctx, username)
+	if err != nil {
+		respondError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	rsp := make([]apiKeyResponse, len(apiKeys))
+	for i, apiKey := range apiKeys {
+		rsp[i] = newAPIKeyResponse(apiKey)
+	}
+	ctx.JSON(http.StatusOK, rsp)
+}
+
+type apiKeyURI struct {
+	ID int64 `uri:"id" binding:"required,min=1"`
+}
+
+// revokeAPIKey revokes an API key of the authenticated user
+func (server *Server) revokeAPIKey(ctx *gin.Context) {
+	apiKey, ok := server.getAPIKey(ctx)
+	if !ok {
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	if apiKey.Username != authPayload.Username {
+		err := fmt.Errorf("api key [%d] doesn't belong to the authenticated user", apiKey.ID)
+		respondError(ctx, http.StatusForbidden, err)
+		return
+	}
+
+	server.markAPIKeyRevoked(ctx, apiKey)
+}
+
+// revokeUserAPIKey lets an admin revoke any API key
+func (server *Server) revokeUserAPIKey(ctx *gin.Context) {
+	apiKey, ok := server.getAPIKey(ctx)
+	if !ok {
+		return
+	}
+
+	server.markAPIKeyRevoked(ctx, apiKey)
+}
+
+func (server *Server) getAPIKey(ctx *gin.Context) (db.ApiKey, bool) {
+	var uri apiKeyURI
+
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		respondError(ctx, http.StatusBadRequest, err)
+		return db.ApiKey{}, false
+	}
+
+	apiKey, err := server.store.GetAPIKey(ctx, uri.ID)
+	if err != nil {
+		if errors.Is(err, db.ErrRecordNotFound) {
+			respondError(ctx, http.StatusNotFound, err)
+			return apiKey, false
+		}
+		respondError(ctx, http.StatusInternalServerError, err)
+		return apiKey, false
+	}
+
+	return apiKey, true
+}
+
+func (server *Server) markAPIKeyRevoked(ctx *gin.Context, apiKey db.ApiKey) {
+	apiKey, err := server.store.RevokeAPIKey(ctx, db.RevokeAPIKeyParams{
+		RevokedAt: pgtype.Timestamptz{Time: time.Now(), Valid: true},
+		ID:        apiKey.ID,
+	})
+	if err != nil {
+		if errors.Is(err, db.ErrRecordNotFound) {
+			respondError(ctx, http.StatusConflict, errAPIKeyRevoked)
+			return
+		}
+		respondError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, newAPIKeyResponse(apiKey))
+}
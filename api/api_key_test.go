@@ -0,0 +1,813 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	mockdb "github.com/roman-adamchik/simplebank/db/mock"
+	db "github.com/roman-adamchik/simplebank/db/sqlc"
+	"github.com/roman-adamchik/simplebank/util"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func randomAPIKey(t *testing.T, username string) (db.ApiKey, string) {
+	t.Helper()
+
+	secret, err := util.RandomSecret(32)
+	require.NoError(t, err)
+	key := apiKeyPrefix + secret
+
+	apiKey := db.ApiKey{
+		ID:         util.RandomInt(1, 1000),
+		Username:   username,
+		Name:       util.RandomOwner(),
+		KeyPrefix:  key[:apiKeyDisplayLength],
+		KeyHash:    util.HashSecret(key),
+		Scopes:     []string{ScopeProfileRead},
+		AllowedIps: []string{},
+		CreatedBy:  username,
+		CreatedAt:  pgtype.Timestamptz{Time: time.Now(), Valid: true},
+	}
+	return apiKey, key
+}
+
+func TestIPAllowed(t *testing.T) {
+	testCases := []struct {
+		name       string
+		allowedIPs []string
+		clientIP   string
+		allowed    bool
+	}{
+		{name: "AnyIP", allowedIPs: nil, clientIP: "198.51.100.7", allowed: true},
+		{name: "SameIP", allowedIPs: []string{"198.51.100.7"}, clientIP: "198.51.100.7", allowed: true},
+		{name: "OtherIP", allowedIPs: []string{"198.51.100.7"}, clientIP: "198.51.100.8", allowed: false},
+		{name: "InRange", allowedIPs: []string{"192.0.2.1", "198.51.100.0/24"}, clientIP: "198.51.100.8", allowed: true},
+		{name: "OutOfRange", allowedIPs: []string{"198.51.100.0/24"}, clientIP: "203.0.113.1", allowed: false},
+		{name: "IPv6Range", allowedIPs: []string{"2001:db8::/32"}, clientIP: "2001:db8::1", allowed: true},
+		{name: "MappedIPv4", allowedIPs: []string{"198.51.100.7"}, clientIP: "::ffff:198.51.100.7", allowed: true},
+		{name: "InvalidClientIP", allowedIPs: []string{"198.51.100.7"}, clientIP: "unknown", allowed: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.allowed, ipAllowed(tc.allowedIPs, tc.clientIP))
+		})
+	}
+}
+
+func TestRouteScopesMatchRoutes(t *testing.T) {
+	server := newTestServer(t, nil)
+
+	routes := map[string]bool{}
+	for _, route := range server.router.Routes() {
+		routes[route.Method+" "+route.Path] = true
+	}
+	for route, scope := range routeScopes {
+		require.True(t, routes[route], "no route %s", route)
+		require.Contains(t, scopes, scope)
+	}
+}
+
+func TestAPIKeyAuthMiddleware(t *testing.T) {
+	user, _ := getRandomUser(t)
+	apiKey, key := randomAPIKey(t, user.Username)
+	clientIP := "198.51.100.7"
+	fromAccount := getRandomAccount()
+	fromAccount.Owner = user.Username
+	order := getRandomStandingOrder(fromAccount, getRandomAccount())
+
+	expectAPIKey := func(store *mockdb.MockStore, apiKey db.ApiKey) {
+		store.EXPECT().
+			GetAPIKeyByHash(gomock.Any(), gomock.Eq(util.HashSecret(key))).
+			Times(1).
+			Return(apiKey, nil)
+	}
+	expectTouch := func(store *mockdb.MockStore, err error) {
+		store.EXPECT().
+			TouchAPIKey(gomock.Any(), gomock.Any()).
+			Times(1).
+			DoAndReturn(func(_ any, arg db.TouchAPIKeyParams) error {
+				require.Equal(t, apiKey.ID, arg.ID)
+				require.WithinDuration(t, time.Now(), arg.LastUsedAt.Time, time.Second)
+				require.Equal(t, arg.LastUsedAt.Time.Add(-apiKeyTouchInterval), arg.UsedBefore.Time)
+				return err
+			})
+	}
+	expectNoUser := func(store *mockdb.MockStore) {
+		store.EXPECT().
+			GetUser(gomock.Any(), gomock.Any()).
+			Times(0)
+	}
+
+	testCases := []struct {
+		name          string
+		method        string
+		path          string
+		forwardedFor  string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:   "OK",
+			method: http.MethodGet,
+			path:   "/users/me",
+			buildStubs: func(store *mockdb.MockStore) {
+				expectAPIKey(store, apiKey)
+				expectTouch(store, nil)
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(user, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				requireBodyMatchUser(t, recorder.Body, user)
+			},
+		},
+		{
+			name:   "AllowedIP",
+			method: http.MethodGet,
+			path:   "/users/me",
+			buildStubs: func(store *mockdb.MockStore) {
+				restricted := apiKey
+				restricted.AllowedIps = []string{"198.51.100.0/24"}
+				expectAPIKey(store, restricted)
+				expectTouch(store, nil)
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(user, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name:   "TouchError",
+			method: http.MethodGet,
+			path:   "/users/me",
+			buildStubs: func(store *mockdb.MockStore) {
+				expectAPIKey(store, apiKey)
+				expectTouch(store, pgx.ErrTxClosed)
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(user, nil)
+			},
+			// the last use is only informative, so the request goes on
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name:   "UnknownKey",
+			method: http.MethodGet,
+			path:   "/users/me",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetAPIKeyByHash(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.ApiKey{}, db.ErrRecordNotFound)
+				expectNoUser(store)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+				requireProblem(t, recorder, "invalid_api_key")
+			},
+		},
+		{
+			name:   "Revoked",
+			method: http.MethodGet,
+			path:   "/users/me",
+			buildStubs: func(store *mockdb.MockStore) {
+				revoked := apiKey
+				revoked.RevokedAt = pgtype.Timestamptz{Time: time.Now(), Valid: true}
+				expectAPIKey(store, revoked)
+				expectNoUser(store)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+				requireProblem(t, recorder, "api_key_revoked")
+			},
+		},
+		{
+			name:   "Expired",
+			method: http.MethodGet,
+			path:   "/users/me",
+			buildStubs: func(store *mockdb.MockStore) {
+				expired := apiKey
+				expired.ExpiresAt = pgtype.Timestamptz{Time: time.Now().Add(-time.Minute), Valid: true}
+				expectAPIKey(store, expired)
+				expectNoUser(store)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+				requireProblem(t, recorder, "api_key_expired")
+			},
+		},
+		{
+			name:   "IPNotAllowed",
+			method: http.MethodGet,
+			path:   "/users/me",
+			buildStubs: func(store *mockdb.MockStore) {
+				restricted := apiKey
+				restricted.AllowedIps = []string{"203.0.113.0/24"}
+				expectAPIKey(store, restricted)
+				expectNoUser(store)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			// the client is not a trusted proxy, so the IP it forwards is ignored
+			name:         "SpoofedForwardedFor",
+			method:       http.MethodGet,
+			path:         "/users/me",
+			forwardedFor: "203.0.113.9",
+			buildStubs: func(store *mockdb.MockStore) {
+				restricted := apiKey
+				restricted.AllowedIps = []string{"203.0.113.9"}
+				expectAPIKey(store, restricted)
+				expectNoUser(store)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name:   "MissingScope",
+			method: http.MethodGet,
+			path:   "/webhooks",
+			buildStubs: func(store *mockdb.MockStore) {
+				expectAPIKey(store, apiKey)
+				store.EXPECT().
+					ListWebhookSubscriptions(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+				require.Contains(t, recorder.Body.String(), ScopeWebhooksRead)
+			},
+		},
+		{
+			name:   "CancelStandingOrder",
+			method: http.MethodPost,
+			path:   fmt.Sprintf("/standing_orders/%d/cancel", order.ID),
+			buildStubs: func(store *mockdb.MockStore) {
+				transfersWrite := apiKey
+				transfersWrite.Scopes = []string{ScopeTransfersWrite}
+				expectAPIKey(store, transfersWrite)
+				expectTouch(store, nil)
+				store.EXPECT().
+					GetStandingOrder(gomock.Any(), gomock.Eq(order.ID)).
+					Times(1).
+					Return(order, nil)
+				store.EXPECT().
+					GetAccount(gomock.Any(), gomock.Eq(fromAccount.ID)).
+					Times(1).
+					Return(fromAccount, nil)
+				store.EXPECT().
+					CancelStandingOrder(gomock.Any(), gomock.Eq(order.ID)).
+					Times(1).
+					Return(order, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name:   "RouteWithoutScope",
+			method: http.MethodGet,
+			path:   "/api_keys",
+			buildStubs: func(store *mockdb.MockStore) {
+				allScopes := apiKey
+				allScopes.Scopes = scopes
+				expectAPIKey(store, allScopes)
+				store.EXPECT().
+					ListAPIKeys(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+				requireProblem(t, recorder, "scope_not_allowed")
+			},
+		},
+		{
+			name:   "AdminRoute",
+			method: http.MethodGet,
+			path:   "/admin/transfer_limits",
+			buildStubs: func(store *mockdb.MockStore) {
+				allScopes := apiKey
+				allScopes.Scopes = scopes
+				expectAPIKey(store, allScopes)
+				expectNoUser(store)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name:   "InternalError",
+			method: http.MethodGet,
+			path:   "/users/me",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetAPIKeyByHash(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.ApiKey{}, pgx.ErrTxClosed)
+				expectNoUser(store)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			request, err := http.NewRequest(tc.method, tc.path, nil)
+			require.NoError(t, err)
+			request.RemoteAddr = clientIP + ":54321"
+			request.Header.Set(authorizationHeaderKey, "ApiKey "+key)
+			if tc.forwardedFor != "" {
+				request.Header.Set("X-Forwarded-For", tc.forwardedFor)
+			}
+
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestCreateAPIKeyAPI(t *testing.T) {
+	user, _ := getRandomUser(t)
+	name := "payroll"
+	expiresAt := time.Now().Add(24 * time.Hour).UTC().Truncate(time.Second)
+
+	testCases := []struct {
+		name          string
+		body          gin.H
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			body: gin.H{
+				"name":        name,
+				"scopes":      []string{ScopeAccountsRead, ScopeTransfersWrite},
+				"allowed_ips": []string{"198.51.100.7", "203.0.113.0/24"},
+				"expires_at":  expiresAt,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					CreateAPIKey(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ any, arg db.CreateAPIKeyParams) (db.ApiKey, error) {
+						require.Equal(t, user.Username, arg.Username)
+						require.Equal(t, user.Username, arg.CreatedBy)
+						require.Equal(t, name, arg.Name)
+						require.Equal(t, []string{ScopeAccountsRead, ScopeTransfersWrite}, arg.Scopes)
+						require.Equal(t, []string{"198.51.100.7", "203.0.113.0/24"}, arg.AllowedIps)
+						require.True(t, arg.ExpiresAt.Time.Equal(expiresAt))
+						require.True(t, strings.HasPrefix(arg.KeyPrefix, apiKeyPrefix))
+						require.Len(t, arg.KeyPrefix, apiKeyDisplayLength)
+						return db.ApiKey{
+							ID:         1,
+							Username:   arg.Username,
+							Name:       arg.Name,
+							KeyPrefix:  arg.KeyPrefix,
+							KeyHash:    arg.KeyHash,
+							Scopes:     arg.Scopes,
+							AllowedIps: arg.AllowedIps,
+							CreatedBy:  arg.CreatedBy,
+							ExpiresAt:  arg.ExpiresAt,
+						}, nil
+					})
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var rsp createAPIKeyResponse
+				err := json.Unmarshal(recorder.Body.Bytes(), &rsp)
+				require.NoError(t, err)
+				require.True(t, strings.HasPrefix(rsp.Key, rsp.APIKey.KeyPrefix))
+				require.Equal(t, name, rsp.APIKey.Name)
+				require.NotContains(t, recorder.Body.String(), util.HashSecret(rsp.Key))
+			},
+		},
+		{
+			name: "NoAllowedIPs",
+			body: gin.H{"name": name, "scopes": []string{ScopeHoldsRead}},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					CreateAPIKey(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ any, arg db.CreateAPIKeyParams) (db.ApiKey, error) {
+						require.NotNil(t, arg.AllowedIps)
+						require.Empty(t, arg.AllowedIps)
+						require.False(t, arg.ExpiresAt.Valid)
+						return db.ApiKey{Username: arg.Username, Scopes: arg.Scopes}, nil
+					})
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name: "UnknownScope",
+			body: gin.H{"name": name, "scopes": []string{"admin"}},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					CreateAPIKey(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "NoScopes",
+			body: gin.H{"name": name, "scopes": []string{}},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					CreateAPIKey(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "InvalidAllowedIP",
+			body: gin.H{"name": name, "scopes": []string{ScopeHoldsRead}, "allowed_ips": []string{"not-an-ip"}},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					CreateAPIKey(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "ExpiryInPast",
+			body: gin.H{"name": name, "scopes": []string{ScopeHoldsRead}, "expires_at": time.Now().Add(-time.Hour)},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					CreateAPIKey(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+				requireProblem(t, recorder, "validation_failed")
+			},
+		},
+		{
+			name: "InternalError",
+			body: gin.H{"name": name, "scopes": []string{ScopeHoldsRead}},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					CreateAPIKey(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.ApiKey{}, pgx.ErrTxClosed)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			data, err := json.Marshal(tc.body)
+			require.NoError(t, err)
+
+			request, err := http.NewRequest(http.MethodPost, "/api_keys", bytes.NewReader(data))
+			require.NoError(t, err)
+
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestCreateUserAPIKeyAPI(t *testing.T) {
+	admin, _ := getRandomUser(t)
+	admin.Role = util.AdminRole
+	serviceAccount, _ := getRandomUser(t)
+	body := gin.H{"name": "settlement", "scopes": []string{ScopeTransfersWrite}}
+
+	testCases := []struct {
+		name          string
+		username      string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:     "OK",
+			username: serviceAccount.Username,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(admin.Username)).
+					Times(1).
+					Return(admin, nil)
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(serviceAccount.Username)).
+					Times(1).
+					Return(serviceAccount, nil)
+				store.EXPECT().
+					CreateAPIKey(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ any, arg db.CreateAPIKeyParams) (db.ApiKey, error) {
+						require.Equal(t, serviceAccount.Username, arg.Username)
+						require.Equal(t, admin.Username, arg.CreatedBy)
+						return db.ApiKey{Username: arg.Username, CreatedBy: arg.CreatedBy, Scopes: arg.Scopes}, nil
+					})
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name:     "UserNotFound",
+			username: serviceAccount.Username,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(admin.Username)).
+					Times(1).
+					Return(admin, nil)
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(serviceAccount.Username)).
+					Times(1).
+					Return(db.User{}, db.ErrRecordNotFound)
+				store.EXPECT().
+					CreateAPIKey(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name:     "UserDeactivated",
+			username: serviceAccount.Username,
+			buildStubs: func(store *mockdb.MockStore) {
+				deactivated := serviceAccount
+				deactivated.DeactivatedAt = pgtype.Timestamptz{Time: time.Now(), Valid: true}
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(admin.Username)).
+					Times(1).
+					Return(admin, nil)
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(serviceAccount.Username)).
+					Times(1).
+					Return(deactivated, nil)
+				store.EXPECT().
+					CreateAPIKey(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusConflict, recorder.Code)
+			},
+		},
+		{
+			name:     "NotAdmin",
+			username: serviceAccount.Username,
+			buildStubs: func(store *mockdb.MockStore) {
+				depositor := admin
+				depositor.Role = util.DepositorRole
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(admin.Username)).
+					Times(1).
+					Return(depositor, nil)
+				store.EXPECT().
+					CreateAPIKey(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			data, err := json.Marshal(body)
+			require.NoError(t, err)
+
+			url := fmt.Sprintf("/admin/users/%s/api_keys", tc.username)
+			request, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(data))
+			require.NoError(t, err)
+
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, admin.Username, time.Minute)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestListAPIKeysAPI(t *testing.T) {
+	user, _ := getRandomUser(t)
+	apiKey1, _ := randomAPIKey(t, user.Username)
+	apiKey2, _ := randomAPIKey(t, user.Username)
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().
+		ListAPIKeys(gomock.Any(), gomock.Eq(user.Username)).
+		Times(1).
+		Return([]db.ApiKey{apiKey1, apiKey2}, nil)
+
+	server := newTestServer(t, store)
+	recorder := httptest.NewRecorder()
+
+	request, err := http.NewRequest(http.MethodGet, "/api_keys", nil)
+	require.NoError(t, err)
+
+	addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
+	server.router.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusOK, recorder.Code)
+
+	var rsp []apiKeyResponse
+	err = json.Unmarshal(recorder.Body.Bytes(), &rsp)
+	require.NoError(t, err)
+	require.Len(t, rsp, 2)
+	require.Equal(t, apiKey1.ID, rsp[0].ID)
+	require.Equal(t, apiKey2.KeyPrefix, rsp[1].KeyPrefix)
+	require.NotContains(t, recorder.Body.String(), apiKey1.KeyHash)
+}
+
+func TestRevokeAPIKeyAPI(t *testing.T) {
+	user, _ := getRandomUser(t)
+	other, _ := getRandomUser(t)
+	apiKey, _ := randomAPIKey(t, user.Username)
+
+	testCases := []struct {
+		name          string
+		id            int64
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			id:   apiKey.ID,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetAPIKey(gomock.Any(), gomock.Eq(apiKey.ID)).
+					Times(1).
+					Return(apiKey, nil)
+				store.EXPECT().
+					RevokeAPIKey(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ any, arg db.RevokeAPIKeyParams) (db.ApiKey, error) {
+						require.Equal(t, apiKey.ID, arg.ID)
+						require.WithinDuration(t, time.Now(), arg.RevokedAt.Time, time.Second)
+						revoked := apiKey
+						revoked.RevokedAt = arg.RevokedAt
+						return revoked, nil
+					})
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var rsp apiKeyResponse
+				err := json.Unmarshal(recorder.Body.Bytes(), &rsp)
+				require.NoError(t, err)
+				require.True(t, rsp.RevokedAt.Valid)
+			},
+		},
+		{
+			name: "NotOwner",
+			id:   apiKey.ID,
+			buildStubs: func(store *mockdb.MockStore) {
+				otherKey := apiKey
+				otherKey.Username = other.Username
+				store.EXPECT().
+					GetAPIKey(gomock.Any(), gomock.Eq(apiKey.ID)).
+					Times(1).
+					Return(otherKey, nil)
+				store.EXPECT().
+					RevokeAPIKey(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name: "NotFound",
+			id:   apiKey.ID,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetAPIKey(gomock.Any(), gomock.Eq(apiKey.ID)).
+					Times(1).
+					Return(db.ApiKey{}, db.ErrRecordNotFound)
+				store.EXPECT().
+					RevokeAPIKey(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name: "AlreadyRevoked",
+			id:   apiKey.ID,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetAPIKey(gomock.Any(), gomock.Eq(apiKey.ID)).
+					Times(1).
+					Return(apiKey, nil)
+				store.EXPECT().
+					RevokeAPIKey(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.ApiKey{}, db.ErrRecordNotFound)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusConflict, recorder.Code)
+			},
+		},
+		{
+			name: "InvalidID",
+			id:   0,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetAPIKey(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			url := fmt.Sprintf("/api_keys/%d", tc.id)
+			request, err := http.NewRequest(http.MethodDelete, url, nil)
+			require.NoError(t, err)
+
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
@@ -0,0 +1,188 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	db "github.com/roman-adamchik/simplebank/db/sqlc"
+	"github.com/roman-adamchik/simplebank/token"
+)
+
+type createHoldRequest struct {
+	AccountID int64     `json:"account_id" binding:"required,min=1"`
+	Amount    int64     `json:"amount" binding:"required,gt=0"`
+	Currency  string    `json:"currency" binding:"required,currency"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+func (server *Server) createHold(ctx *gin.Context) {
+	var req createHoldRequest
+
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		respondError(ctx, http.StatusBadRequest, err)
+		return
+	}
+
+	expiresAt := req.ExpiresAt
+	if expiresAt.IsZero() {
+		ttl := server.config.HoldTTL
+		if ttl <= 0 {
+			ttl = db.DefaultHoldTTL
+		}
+		expiresAt = time.Now().Add(ttl)
+	} else if !expiresAt.After(time.Now()) {
+		err := fmt.Errorf("hold expiry %s is in the past", expiresAt.Format(time.RFC3339))
+		respondError(ctx, http.StatusBadRequest, err)
+		return
+	}
+
+	account, ok := server.ownedAccount(ctx, req.AccountID)
+	if !ok {
+		return
+	}
+	if err := checkAccountCurrency(account, req.Currency); err != nil {
+		respondError(ctx, http.StatusBadRequest, err)
+		return
+	}
+
+	result, err := server.store.CreateHoldTx(ctx, db.CreateHoldTxParams{
+		AccountID: req.AccountID,
+		Amount:    req.Amount,
+		ExpiresAt: expiresAt,
+	})
+	if err != nil {
+		var limitErr *db.LimitExceededError
+		if errors.As(err, &limitErr) {
+			respondLimitExceeded(ctx, limitErr)
+			return
+		}
+		if errors.Is(err, db.ErrInsufficientFunds) || errors.Is(err, db.ErrApprovalRequired) ||
+			errors.Is(err, db.ErrAccountClosed) {
+			respondError(ctx, http.StatusForbidden, err)
+			return
+		}
+		respondError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, result)
+}
+
+type holdURI struct {
+	ID int64 `uri:"id" binding:"required,min=1"`
+}
+
+func (server *Server) getHold(ctx *gin.Context) {
+	hold, _, ok := server.ownedHold(ctx)
+	if !ok {
+		return
+	}
+
+	ctx.JSON(http.StatusOK, hold)
+}
+
+type captureHoldRequest struct {
+	ToAccountID int64  `json:"to_account_id" binding:"required,min=1"`
+	Amount      int64  `json:"amount" binding:"omitempty,gt=0"`
+	Currency    string `json:"currency" binding:"required,currency"`
+}
+
+func (server *Server) captureHold(ctx *gin.Context) {
+	var req captureHoldRequest
+
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		respondError(ctx, http.StatusBadRequest, err)
+		return
+	}
+
+	hold, account, ok := server.ownedHold(ctx)
+	if !ok {
+		return
+	}
+	if req.ToAccountID == hold.AccountID {
+		err := fmt.Errorf("hold [%d] cannot be captured to its own account", hold.ID)
+		respondError(ctx, http.StatusBadRequest, err)
+		return
+	}
+	if err := checkAccountCurrency(account, req.Currency); err != nil {
+		respondError(ctx, http.StatusBadRequest, err)
+		return
+	}
+	if !server.validAccount(ctx, req.ToAccountID, req.Currency) {
+		return
+	}
+
+	// the full held amount is captured unless a part of it is given
+	amount := req.Amount
+	if amount == 0 {
+		amount = hold.Amount
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	result, err := server.store.CaptureHoldTx(ctx, db.CaptureHoldTxParams{
+		HoldID:      hold.ID,
+		ToAccountID: req.ToAccountID,
+		Amount:      amount,
+		Initiator:   authPayload.Username,
+	})
+	if err != nil {
+		server.handleHoldError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, result)
+}
+
+func (server *Server) releaseHold(ctx *gin.Context) {
+	hold, _, ok := server.ownedHold(ctx)
+	if !ok {
+		return
+	}
+
+	hold, err := server.store.ReleaseHoldTx(ctx, hold.ID)
+	if err != nil {
+		server.handleHoldError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, hold)
+}
+
+// ownedHold binds the hold of the request and checks that its account belongs to the authenticated user
+func (server *Server) ownedHold(ctx *gin.Context) (db.Hold, db.Account, bool) {
+	var uri holdURI
+
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		respondError(ctx, http.StatusBadRequest, err)
+		return db.Hold{}, db.Account{}, false
+	}
+
+	hold, err := server.store.GetHold(ctx, uri.ID)
+	if err != nil {
+		if errors.Is(err, db.ErrRecordNotFound) {
+			respondError(ctx, http.StatusNotFound, err)
+			return hold, db.Account{}, false
+		}
+		respondError(ctx, http.StatusInternalServerError, err)
+		return hold, db.Account{}, false
+	}
+
+	account, ok := server.ownedAccount(ctx, hold.AccountID)
+	return hold, account, ok
+}
+
+func (server *Server) handleHoldError(ctx *gin.Context, err error) {
+	switch {
+	case errors.Is(err, db.ErrCaptureExceedsHold):
+		respondError(ctx, http.StatusBadRequest, err)
+	case errors.Is(err, db.ErrInsufficientFunds), errors.Is(err, db.ErrAccountClosed):
+		respondError(ctx, http.StatusForbidden, err)
+	case errors.Is(err, db.ErrHoldNotActive), errors.Is(err, db.ErrHoldExpired):
+		respondError(ctx, http.StatusConflict, err)
+	default:
+		respondError(ctx, http.StatusInternalServerError, err)
+	}
+}
@@ -0,0 +1,186 @@
+package api
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	db "github.com/roman-adamchik/simplebank/db/sqlc"
+	"github.com/roman-adamchik/simplebank/util"
+)
+
+const (
+	// loginFreeAttempts is the number of failed logins allowed before delays start
+	loginFreeAttempts = 3
+	loginBaseDelay    = time.Second
+	loginMaxDelay     = 30 * time.Second
+
+	defaultLoginLockoutThreshold   = 10
+	defaultLoginIPLockoutThreshold = 100
+	defaultLoginLockoutDuration    = 15 * time.Minute
+)
+
+// errInvalidCredentials is the error of every failed login, so that it does not tell whether the user exists
+var errInvalidCredentials = errors.New("invalid username or password")
+
+var errLoginThrottled = errors.New("too many failed login attempts, try again later")
+
+// newDummyPasswordHash returns the hash checked against the password of an unknown user,
+// made by the hasher of the passwords so that the login takes as long as for an existing user
+func newDummyPasswordHash(hasher util.PasswordHasher) (string, error) {
+	return hasher.Hash("dummy password")
+}
+
+// loginThrottlePolicy decides how long logins are refused after repeated failures:
+// every failure past the free attempts doubles the delay before the next login is allowed,
+// and reaching the lockout threshold refuses logins for the lockout duration
+type loginThrottlePolicy struct {
+	lockoutThreshold int32
+	lockoutDuration  time.Duration
+}
+
+// blockedUntil is the time before which no login is allowed after the failures of a throttle
+func (policy loginThrottlePolicy) blockedUntil(throttle db.LoginThrottle) time.Time {
+	lastFailure := throttle.LastFailureAt.Time
+
+	if throttle.Failures >= policy.lockoutThreshold {
+		return lastFailure.Add(policy.lockoutDuration)
+	}
+	if throttle.Failures <= loginFreeAttempts {
+		return time.Time{}
+	}
+
+	delay := loginBaseDelay
+	for i := int32(loginFreeAttempts + 1); i < throttle.Failures && delay < loginMaxDelay; i++ {
+		delay *= 2
+	}
+	return lastFailure.Add(min(delay, loginMaxDelay))
+}
+
+// loginPolicies are the throttle policies of usernames and client IPs set by the config
+func (server *Server) loginPolicies() (user loginThrottlePolicy, ip loginThrottlePolicy) {
+	user = loginThrottlePolicy{
+		lockoutThreshold: int32(server.config.LoginLockoutThreshold),
+		lockoutDuration:  server.config.LoginLockoutDuration,
+	}
+	if user.lockoutThreshold <= 0 {
+		user.lockoutThreshold = defaultLoginLockoutThreshold
+	}
+	if user.lockoutDuration <= 0 {
+		user.lockoutDuration = defaultLoginLockoutDuration
+	}
+
+	ip = user
+	ip.lockoutThreshold = int32(server.config.LoginIPLockoutThreshold)
+	if ip.lockoutThreshold <= 0 {
+		ip.lockoutThreshold = defaultLoginIPLockoutThreshold
+	}
+
+	return user, ip
+}
+
+// startLoginAttempt counts a login of the username from the client IP against its throttles before its credentials
+// are checked. It returns the time before which the login is refused, zero if it can go on
+func (server *Server) startLoginAttempt(ctx *gin.Context, username string, now time.Time) (time.Time, error) {
+	userKey := db.UserThrottleKey(username)
+	userPolicy, ipPolicy := server.loginPolicies()
+
+	return server.store.StartLoginAttemptTx(ctx, db.StartLoginAttemptTxParams{
+		Username: username,
+		ClientIP: ctx.ClientIP(),
+		Now:      now,
+		Window:   userPolicy.lockoutDuration,
+		BlockedUntil: func(throttles []db.LoginThrottle) time.Time {
+			var blockedUntil time.Time
+			for _, throttle := range throttles {
+				policy := ipPolicy
+				if throttle.Key == userKey {
+					policy = userPolicy
+				}
+				if until := policy.blockedUntil(throttle); until.After(blockedUntil) {
+					blockedUntil = until
+				}
+			}
+
+			if !blockedUntil.After(now) {
+				return time.Time{}
+			}
+			return blockedUntil
+		},
+	})
+}
+
+// recordLoginAttempt records the outcome of a login, an empty failure reason meaning success
+func (server *Server) recordLoginAttempt(ctx *gin.Context, username string, failureReason string) error {
+	_, err := server.store.RecordLoginAttemptTx(ctx, db.RecordLoginAttemptTxParams{
+		Username:      username,
+		ClientIP:      ctx.ClientIP(),
+		UserAgent:     ctx.Request.UserAgent(),
+		FailureReason: failureReason,
+	})
+	return err
+}
+
+// throttledLoginResponse refuses a login until the given time
+func throttledLoginResponse(ctx *gin.Context, blockedUntil time.Time, now time.Time) {
+	retryAfter := math.Ceil(blockedUntil.Sub(now).Seconds())
+	ctx.Header("Retry-After", strconv.Itoa(int(retryAfter)))
+	respondError(ctx, http.StatusTooManyRequests, errLoginThrottled)
+}
+
+type loginUserURI struct {
+	Username string `uri:"username" binding:"required,alphanum"`
+}
+
+// unlockUser lifts the lockout of a username, the lockouts of client IPs expire on their own
+func (server *Server) unlockUser(ctx *gin.Context) {
+	var uri loginUserURI
+
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		respondError(ctx, http.StatusBadRequest, err)
+		return
+	}
+
+	err := server.store.DeleteLoginThrottle(ctx, db.UserThrottleKey(uri.Username))
+	if err != nil {
+		respondError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "user " + uri.Username + " unlocked"})
+}
+
+type listLoginAttemptsRequest struct {
+	PageID   int32 `form:"page_id" binding:"required,min=1"`
+	PageSize int32 `form:"page_size" binding:"required,min=5,max=100"`
+}
+
+// listLoginAttempts returns the audit log of the logins of a username, newest first
+func (server *Server) listLoginAttempts(ctx *gin.Context) {
+	var uri loginUserURI
+	var req listLoginAttemptsRequest
+
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		respondError(ctx, http.StatusBadRequest, err)
+		return
+	}
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		respondError(ctx, http.StatusBadRequest, err)
+		return
+	}
+
+	attempts, err := server.store.ListLoginAttempts(ctx, db.ListLoginAttemptsParams{
+		Username: uri.Username,
+		Limit:    req.PageSize,
+		Offset:   (req.PageID - 1) * req.PageSize,
+	})
+	if err != nil {
+		respondError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, attempts)
+}
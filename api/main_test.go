@@ -1,12 +1,16 @@
 package api
 
 import (
+	"context"
 	"os"
 	"testing"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	mockdb "github.com/roman-adamchik/simplebank/db/mock"
 	db "github.com/roman-adamchik/simplebank/db/sqlc"
+	"github.com/roman-adamchik/simplebank/stream"
+	"github.com/roman-adamchik/simplebank/task"
 	"github.com/roman-adamchik/simplebank/util"
 	"github.com/stretchr/testify/require"
 )
@@ -17,14 +21,35 @@ func newTestServer(t *testing.T, store db.Store) *Server {
 	config := util.Config{
 		TokenSymmetricKey:   util.RandomString(32),
 		AccessTokenDuration: time.Minute,
+		MFAEncryptionKey:    util.RandomString(32),
 	}
 
-	server, err := NewServer(config, store)
+	if mockStore, ok := store.(*mockdb.MockStore); ok {
+		expectTestSessions(mockStore)
+	}
+
+	server, err := NewServer(config, store, stream.NewBroker(), task.NewMemory())
 	require.NoError(t, err)
 
 	return server
 }
 
+// recordTasks processes the tasks of a type enqueued by the server and records their payloads
+func recordTasks(t *testing.T, server *Server, taskType string) (*task.Memory, *[]string) {
+	t.Helper()
+
+	payloads := []string{}
+	queue := server.distributor.(*task.Memory)
+	queue.Handle(taskType, func(_ context.Context, payload []byte) error {
+		payloads = append(payloads, string(payload))
+		return nil
+	})
+	require.NoError(t, queue.Start())
+	t.Cleanup(queue.Shutdown)
+
+	return queue, &payloads
+}
+
 // TestMain sets up the test environment before running all tests in this package.
 // It configures Gin to run in test mode, which disables debug logging and
 // improves test performance.
@@ -0,0 +1,293 @@
+package api
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	db "github.com/roman-adamchik/simplebank/db/sqlc"
+	"github.com/roman-adamchik/simplebank/mfa"
+	"github.com/roman-adamchik/simplebank/token"
+	"github.com/roman-adamchik/simplebank/util"
+)
+
+const (
+	defaultMFAIssuer        = "Simple Bank"
+	defaultMFATokenDuration = 5 * time.Minute
+)
+
+var (
+	errInvalidMFACode = errors.New("invalid two-factor authentication code")
+	errMFANotEnrolled = errors.New("two-factor authentication is not set up")
+)
+
+// mfaTokenKey derives the key of mfa tokens from the key of access tokens
+func mfaTokenKey(symmetricKey string) string {
+	mac := hmac.New(sha256.New, []byte(symmetricKey))
+	mac.Write([]byte("mfa token"))
+	return string(mac.Sum(nil))
+}
+
+func (server *Server) mfaIssuer() string {
+	if server.config.MFAIssuer == "" {
+		return defaultMFAIssuer
+	}
+	return server.config.MFAIssuer
+}
+
+func (server *Server) mfaTokenDuration() time.Duration {
+	if server.config.MFATokenDuration <= 0 {
+		return defaultMFATokenDuration
+	}
+	return server.config.MFATokenDuration
+}
+
+type enrollTOTPResponse struct {
+	Secret     string `json:"secret"`
+	OtpauthURI string `json:"otpauth_uri"`
+}
+
+// enrollTOTP generates a new TOTP secret for the authenticated user, it is only asked for at login
+// once confirmed with a code. Enrolling again before confirming replaces the secret
+func (server *Server) enrollTOTP(ctx *gin.Context) {
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	secret, err := mfa.GenerateTOTPSecret()
+	if err != nil {
+		respondError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+	encryptedSecret, err := server.mfaCipher.Encrypt(secret, authPayload.Username)
+	if err != nil {
+		respondError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	_, err = server.store.UpsertTOTPSecret(ctx, db.UpsertTOTPSecretParams{
+		Username:        authPayload.Username,
+		EncryptedSecret: encryptedSecret,
+	})
+	if err != nil {
+		if errors.Is(err, db.ErrRecordNotFound) {
+			respondError(ctx, http.StatusConflict, db.ErrMFAAlreadyEnabled)
+			return
+		}
+		respondError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	rsp := enrollTOTPResponse{
+		Secret:     secret,
+		OtpauthURI: mfa.TOTPURI(server.mfaIssuer(), authPayload.Username, secret),
+	}
+	ctx.JSON(http.StatusOK, rsp)
+}
+
+type confirmTOTPRequest struct {
+	Code string `json:"code" binding:"required,numeric,len=6"`
+}
+
+type confirmTOTPResponse struct {
+	// RecoveryCodes are only shown once, each of them can replace a TOTP code at login once
+	RecoveryCodes []string     `json:"recovery_codes"`
+	User          userResponse `json:"user"`
+}
+
+// confirmTOTP enables 2FA for the authenticated user once they prove their authenticator generates valid codes
+func (server *Server) confirmTOTP(ctx *gin.Context) {
+	var req confirmTOTPRequest
+
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		respondError(ctx, http.StatusBadRequest, err)
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	totpSecret, err := server.store.GetTOTPSecret(ctx, authPayload.Username)
+	if err != nil {
+		if errors.Is(err, db.ErrRecordNotFound) {
+			respondError(ctx, http.StatusNotFound, errMFANotEnrolled)
+			return
+		}
+		respondError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+	if totpSecret.IsConfirmed {
+		respondError(ctx, http.StatusConflict, db.ErrMFAAlreadyEnabled)
+		return
+	}
+
+	secret, err := server.mfaCipher.Decrypt(totpSecret.EncryptedSecret, totpSecret.Username)
+	if err != nil {
+		respondError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+	step, ok := mfa.ValidateTOTP(secret, req.Code, time.Now())
+	if !ok {
+		respondError(ctx, http.StatusBadRequest, errInvalidMFACode)
+		return
+	}
+
+	recoveryCodes, err := mfa.GenerateRecoveryCodes(mfa.RecoveryCodeCount)
+	if err != nil {
+		respondError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+	hashes := make([]string, len(recoveryCodes))
+	for i, code := range recoveryCodes {
+		hashes[i] = util.HashSecret(code)
+	}
+
+	user, err := server.store.EnableMFATx(ctx, db.EnableMFATxParams{
+		Username:           authPayload.Username,
+		Step:               step,
+		RecoveryCodeHashes: hashes,
+	})
+	if err != nil {
+		if errors.Is(err, db.ErrMFAAlreadyEnabled) {
+			respondError(ctx, http.StatusConflict, err)
+			return
+		}
+		respondError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	rsp := confirmTOTPResponse{
+		RecoveryCodes: recoveryCodes,
+		User:          newUserResponse(user),
+	}
+	ctx.JSON(http.StatusOK, rsp)
+}
+
+type mfaRequiredResponse struct {
+	MFARequired bool `json:"mfa_required"`
+	// MFAToken is exchanged with a TOTP or recovery code for the access token, it is no access token itself
+	MFAToken string `json:"mfa_token"`
+}
+
+type loginUserMFARequest struct {
+	MFAToken string `json:"mfa_token" binding:"required"`
+	Code     string `json:"code" binding:"required,max=32"`
+}
+
+// loginUserMFA completes the login of a user with 2FA enabled, exchanging the mfa token given for their password
+// and a TOTP or recovery code for an access token. Wrong codes count against the login throttles
+func (server *Server) loginUserMFA(ctx *gin.Context) {
+	var req loginUserMFARequest
+
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		respondError(ctx, http.StatusBadRequest, err)
+		return
+	}
+
+	payload, err := server.mfaTokenMaker.VerifyToken(req.MFAToken, server.tokenOptions(mfaTokenAudience))
+	if err != nil {
+		respondError(ctx, http.StatusUnauthorized, err)
+		return
+	}
+
+	now := time.Now()
+	blockedUntil, err := server.startLoginAttempt(ctx, payload.Username, now)
+	if err != nil {
+		respondError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+	if !blockedUntil.IsZero() {
+		if err := server.recordLoginAttempt(ctx, payload.Username, db.LoginThrottled); err != nil {
+			respondError(ctx, http.StatusInternalServerError, err)
+			return
+		}
+		throttledLoginResponse(ctx, blockedUntil, now)
+		return
+	}
+
+	totpSecret, err := server.store.GetTOTPSecret(ctx, payload.Username)
+	if err != nil {
+		if errors.Is(err, db.ErrRecordNotFound) {
+			respondError(ctx, http.StatusUnauthorized, errMFANotEnrolled)
+			return
+		}
+		respondError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+	if !totpSecret.IsConfirmed {
+		respondError(ctx, http.StatusUnauthorized, errMFANotEnrolled)
+		return
+	}
+
+	ok, err := server.useMFACode(ctx, totpSecret, req.Code, now)
+	if err != nil {
+		respondError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	failureReason := ""
+	if !ok {
+		failureReason = db.LoginWrongMFACode
+	}
+	if err := server.recordLoginAttempt(ctx, payload.Username, failureReason); err != nil {
+		respondError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+	if !ok {
+		respondError(ctx, http.StatusUnauthorized, errInvalidMFACode)
+		return
+	}
+
+	user, err := server.store.GetUser(ctx, payload.Username)
+	if err != nil {
+		respondError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+	// the user may have deactivated themselves since giving their password
+	if user.DeactivatedAt.Valid {
+		respondError(ctx, http.StatusForbidden, db.ErrUserDeactivated)
+		return
+	}
+
+	accessToken, session, err := server.createSession(ctx, user)
+	if err != nil {
+		respondError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	rsp := loginUserResponse{
+		AccessToken: accessToken,
+		SessionID:   session.ID,
+		User:        newUserResponse(user),
+	}
+	ctx.JSON(http.StatusOK, rsp)
+}
+
+// useMFACode checks a TOTP or recovery code of a user and uses it up, so that it cannot be used again
+func (server *Server) useMFACode(ctx *gin.Context, totpSecret db.TotpSecret, code string, now time.Time) (bool, error) {
+	secret, err := server.mfaCipher.Decrypt(totpSecret.EncryptedSecret, totpSecret.Username)
+	if err != nil {
+		return false, err
+	}
+
+	if step, ok := mfa.ValidateTOTP(secret, code, now); ok {
+		// a step that is not after the last accepted one is a replayed code
+		_, err = server.store.UseTOTPStep(ctx, db.UseTOTPStepParams{
+			Step:     step,
+			Username: totpSecret.Username,
+		})
+	} else {
+		_, err = server.store.UseRecoveryCode(ctx, db.UseRecoveryCodeParams{
+			Username: totpSecret.Username,
+			CodeHash: util.HashSecret(mfa.NormalizeRecoveryCode(code)),
+		})
+	}
+	if err != nil {
+		if errors.Is(err, db.ErrRecordNotFound) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	return true, nil
+}
@@ -0,0 +1,524 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgtype"
+	mockdb "github.com/roman-adamchik/simplebank/db/mock"
+	db "github.com/roman-adamchik/simplebank/db/sqlc"
+	"github.com/roman-adamchik/simplebank/mfa"
+	"github.com/roman-adamchik/simplebank/util"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func TestEnrollTOTPAPI(t *testing.T) {
+	user, _ := getRandomUser(t)
+
+	testCases := []struct {
+		name          string
+		setupAuth     bool
+		buildStubs    func(store *mockdb.MockStore, server *Server, encrypted *string)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder, server *Server, encrypted string)
+	}{
+		{
+			name:      "OK",
+			setupAuth: true,
+			buildStubs: func(store *mockdb.MockStore, server *Server, encrypted *string) {
+				store.EXPECT().
+					UpsertTOTPSecret(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ any, arg db.UpsertTOTPSecretParams) (db.TotpSecret, error) {
+						require.Equal(t, user.Username, arg.Username)
+						*encrypted = arg.EncryptedSecret
+						return db.TotpSecret{Username: arg.Username, EncryptedSecret: arg.EncryptedSecret}, nil
+					})
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, server *Server, encrypted string) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var rsp enrollTOTPResponse
+				err := json.Unmarshal(recorder.Body.Bytes(), &rsp)
+				require.NoError(t, err)
+				require.Contains(t, rsp.OtpauthURI, "secret="+rsp.Secret)
+
+				// only the encrypted secret is stored
+				require.NotContains(t, encrypted, rsp.Secret)
+				secret, err := server.mfaCipher.Decrypt(encrypted, user.Username)
+				require.NoError(t, err)
+				require.Equal(t, rsp.Secret, secret)
+			},
+		},
+		{
+			name:      "AlreadyEnabled",
+			setupAuth: true,
+			buildStubs: func(store *mockdb.MockStore, server *Server, encrypted *string) {
+				store.EXPECT().
+					UpsertTOTPSecret(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.TotpSecret{}, db.ErrRecordNotFound)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, server *Server, encrypted string) {
+				require.Equal(t, http.StatusConflict, recorder.Code)
+			},
+		},
+		{
+			name: "NoAuthorization",
+			buildStubs: func(store *mockdb.MockStore, server *Server, encrypted *string) {
+				store.EXPECT().
+					UpsertTOTPSecret(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, server *Server, encrypted string) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			server := newTestServer(t, store)
+			var encrypted string
+			tc.buildStubs(store, server, &encrypted)
+
+			recorder := httptest.NewRecorder()
+			request, err := http.NewRequest(http.MethodPost, "/users/mfa/totp", nil)
+			require.NoError(t, err)
+
+			if tc.setupAuth {
+				addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
+			}
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder, server, encrypted)
+		})
+	}
+}
+
+func TestConfirmTOTPAPI(t *testing.T) {
+	user, _ := getRandomUser(t)
+	secret, err := mfa.GenerateTOTPSecret()
+	require.NoError(t, err)
+
+	codeTime := time.Now()
+	code, err := mfa.GenerateTOTP(secret, codeTime)
+	require.NoError(t, err)
+	wrongCode := "000000"
+	if code == wrongCode {
+		wrongCode = "111111"
+	}
+
+	var recoveryCodeHashes []string
+
+	testCases := []struct {
+		name          string
+		code          string
+		buildStubs    func(store *mockdb.MockStore, totpSecret db.TotpSecret)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			code: code,
+			buildStubs: func(store *mockdb.MockStore, totpSecret db.TotpSecret) {
+				store.EXPECT().
+					GetTOTPSecret(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(totpSecret, nil)
+				store.EXPECT().
+					EnableMFATx(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ any, arg db.EnableMFATxParams) (db.User, error) {
+						require.Equal(t, user.Username, arg.Username)
+						require.Equal(t, mfa.TOTPStep(codeTime), arg.Step)
+						recoveryCodeHashes = arg.RecoveryCodeHashes
+
+						enabled := user
+						enabled.IsMfaEnabled = true
+						return enabled, nil
+					})
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var rsp confirmTOTPResponse
+				err := json.Unmarshal(recorder.Body.Bytes(), &rsp)
+				require.NoError(t, err)
+				require.True(t, rsp.User.IsMfaEnabled)
+				require.Len(t, rsp.RecoveryCodes, mfa.RecoveryCodeCount)
+
+				// only the hashes of the recovery codes are stored
+				require.Len(t, recoveryCodeHashes, mfa.RecoveryCodeCount)
+				for i, code := range rsp.RecoveryCodes {
+					require.Equal(t, util.HashSecret(code), recoveryCodeHashes[i])
+				}
+			},
+		},
+		{
+			name: "InvalidCode",
+			code: wrongCode,
+			buildStubs: func(store *mockdb.MockStore, totpSecret db.TotpSecret) {
+				store.EXPECT().
+					GetTOTPSecret(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(totpSecret, nil)
+				store.EXPECT().
+					EnableMFATx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "InvalidBody",
+			code: "abc",
+			buildStubs: func(store *mockdb.MockStore, totpSecret db.TotpSecret) {
+				store.EXPECT().
+					GetTOTPSecret(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "NotEnrolled",
+			code: code,
+			buildStubs: func(store *mockdb.MockStore, totpSecret db.TotpSecret) {
+				store.EXPECT().
+					GetTOTPSecret(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(db.TotpSecret{}, db.ErrRecordNotFound)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name: "AlreadyConfirmed",
+			code: code,
+			buildStubs: func(store *mockdb.MockStore, totpSecret db.TotpSecret) {
+				totpSecret.IsConfirmed = true
+				store.EXPECT().
+					GetTOTPSecret(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(totpSecret, nil)
+				store.EXPECT().
+					EnableMFATx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusConflict, recorder.Code)
+			},
+		},
+		{
+			name: "ConfirmedConcurrently",
+			code: code,
+			buildStubs: func(store *mockdb.MockStore, totpSecret db.TotpSecret) {
+				store.EXPECT().
+					GetTOTPSecret(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(totpSecret, nil)
+				store.EXPECT().
+					EnableMFATx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.User{}, db.ErrMFAAlreadyEnabled)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusConflict, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			server := newTestServer(t, store)
+
+			encrypted, err := server.mfaCipher.Encrypt(secret, user.Username)
+			require.NoError(t, err)
+			tc.buildStubs(store, db.TotpSecret{Username: user.Username, EncryptedSecret: encrypted})
+
+			recorder := httptest.NewRecorder()
+			data, err := json.Marshal(gin.H{"code": tc.code})
+			require.NoError(t, err)
+
+			request, err := http.NewRequest(http.MethodPost, "/users/mfa/totp/confirm", bytes.NewReader(data))
+			require.NoError(t, err)
+
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestLoginUserMFAAPI(t *testing.T) {
+	user, _ := getRandomUser(t)
+	user.IsMfaEnabled = true
+	clientIP := "192.0.2.1"
+
+	secret, err := mfa.GenerateTOTPSecret()
+	require.NoError(t, err)
+	codeTime := time.Now()
+	code, err := mfa.GenerateTOTP(secret, codeTime)
+	require.NoError(t, err)
+	recoveryCode := "k3xq-7hma-2pzd-wn5c"
+
+	expectThrottles := func(store *mockdb.MockStore, throttles ...db.LoginThrottle) {
+		store.EXPECT().
+			StartLoginAttemptTx(gomock.Any(), gomock.Any()).
+			Times(1).
+			DoAndReturn(func(_ any, arg db.StartLoginAttemptTxParams) (time.Time, error) {
+				require.Equal(t, user.Username, arg.Username)
+				require.Equal(t, clientIP, arg.ClientIP)
+				return arg.BlockedUntil(throttles), nil
+			})
+	}
+	expectAttempt := func(store *mockdb.MockStore, failureReason string) {
+		store.EXPECT().
+			RecordLoginAttemptTx(gomock.Any(), gomock.Any()).
+			Times(1).
+			DoAndReturn(func(_ any, arg db.RecordLoginAttemptTxParams) (db.LoginAttempt, error) {
+				require.Equal(t, user.Username, arg.Username)
+				require.Equal(t, failureReason, arg.FailureReason)
+				return db.LoginAttempt{}, nil
+			})
+	}
+	requireAccessToken := func(t *testing.T, recorder *httptest.ResponseRecorder, server *Server) {
+		require.Equal(t, http.StatusOK, recorder.Code)
+
+		var rsp loginUserResponse
+		err := json.Unmarshal(recorder.Body.Bytes(), &rsp)
+		require.NoError(t, err)
+		require.Equal(t, user.Username, rsp.User.Username)
+
+		payload, err := server.tokenMaker.VerifyToken(rsp.AccessToken, server.tokenOptions(server.tokenAudience()))
+		require.NoError(t, err)
+		require.Equal(t, user.Username, payload.Username)
+		require.Equal(t, rsp.SessionID, payload.SessionID)
+	}
+	requireInvalidCode := func(t *testing.T, recorder *httptest.ResponseRecorder, server *Server) {
+		require.Equal(t, http.StatusUnauthorized, recorder.Code)
+		requireProblem(t, recorder, "invalid_mfa_code")
+	}
+
+	testCases := []struct {
+		name string
+		code string
+		// accessToken sends an access token instead of the mfa token
+		accessToken   bool
+		buildStubs    func(store *mockdb.MockStore, totpSecret db.TotpSecret)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder, server *Server)
+	}{
+		{
+			name: "OKTOTP",
+			code: code,
+			buildStubs: func(store *mockdb.MockStore, totpSecret db.TotpSecret) {
+				expectThrottles(store)
+				store.EXPECT().
+					GetTOTPSecret(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(totpSecret, nil)
+				store.EXPECT().
+					UseTOTPStep(gomock.Any(), gomock.Eq(db.UseTOTPStepParams{
+						Step:     mfa.TOTPStep(codeTime),
+						Username: user.Username,
+					})).
+					Times(1).
+					Return(totpSecret, nil)
+				expectAttempt(store, "")
+				expectCreateSession(t, store, user.Username)
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(user, nil)
+			},
+			checkResponse: requireAccessToken,
+		},
+		{
+			name: "OKRecoveryCode",
+			code: "K3XQ 7HMA 2PZD WN5C",
+			buildStubs: func(store *mockdb.MockStore, totpSecret db.TotpSecret) {
+				expectThrottles(store)
+				store.EXPECT().
+					GetTOTPSecret(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(totpSecret, nil)
+				store.EXPECT().
+					UseRecoveryCode(gomock.Any(), gomock.Eq(db.UseRecoveryCodeParams{
+						Username: user.Username,
+						CodeHash: util.HashSecret(recoveryCode),
+					})).
+					Times(1).
+					Return(db.RecoveryCode{Username: user.Username, IsUsed: true}, nil)
+				expectAttempt(store, "")
+				expectCreateSession(t, store, user.Username)
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(user, nil)
+			},
+			checkResponse: requireAccessToken,
+		},
+		{
+			name: "ReplayedTOTP",
+			code: code,
+			buildStubs: func(store *mockdb.MockStore, totpSecret db.TotpSecret) {
+				expectThrottles(store)
+				store.EXPECT().
+					GetTOTPSecret(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(totpSecret, nil)
+				store.EXPECT().
+					UseTOTPStep(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.TotpSecret{}, db.ErrRecordNotFound)
+				expectAttempt(store, db.LoginWrongMFACode)
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: requireInvalidCode,
+		},
+		{
+			name: "WrongCode",
+			code: "not-a-code",
+			buildStubs: func(store *mockdb.MockStore, totpSecret db.TotpSecret) {
+				expectThrottles(store)
+				store.EXPECT().
+					GetTOTPSecret(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(totpSecret, nil)
+				store.EXPECT().
+					UseRecoveryCode(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.RecoveryCode{}, db.ErrRecordNotFound)
+				expectAttempt(store, db.LoginWrongMFACode)
+			},
+			checkResponse: requireInvalidCode,
+		},
+		{
+			name:        "AccessTokenRejected",
+			code:        code,
+			accessToken: true,
+			buildStubs: func(store *mockdb.MockStore, totpSecret db.TotpSecret) {
+				store.EXPECT().
+					GetTOTPSecret(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, server *Server) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "Throttled",
+			code: code,
+			buildStubs: func(store *mockdb.MockStore, totpSecret db.TotpSecret) {
+				expectThrottles(store, db.LoginThrottle{
+					Key:           db.UserThrottleKey(user.Username),
+					Failures:      defaultLoginLockoutThreshold,
+					LastFailureAt: pgtype.Timestamptz{Time: time.Now(), Valid: true},
+				})
+				expectAttempt(store, db.LoginThrottled)
+				store.EXPECT().
+					GetTOTPSecret(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, server *Server) {
+				require.Equal(t, http.StatusTooManyRequests, recorder.Code)
+			},
+		},
+		{
+			name: "MFADisabled",
+			code: code,
+			buildStubs: func(store *mockdb.MockStore, totpSecret db.TotpSecret) {
+				expectThrottles(store)
+				store.EXPECT().
+					GetTOTPSecret(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(db.TotpSecret{}, db.ErrRecordNotFound)
+				store.EXPECT().
+					RecordLoginAttemptTx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, server *Server) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			server := newTestServer(t, store)
+
+			encrypted, err := server.mfaCipher.Encrypt(secret, user.Username)
+			require.NoError(t, err)
+			tc.buildStubs(store, db.TotpSecret{Username: user.Username, EncryptedSecret: encrypted, IsConfirmed: true})
+
+			maker := server.mfaTokenMaker
+			if tc.accessToken {
+				maker = server.tokenMaker
+			}
+			mfaToken, err := maker.CreateToken(server.mfaTokenClaims(user.Username), time.Minute)
+			require.NoError(t, err)
+
+			recorder := httptest.NewRecorder()
+			data, err := json.Marshal(gin.H{"mfa_token": mfaToken, "code": tc.code})
+			require.NoError(t, err)
+
+			request, err := http.NewRequest(http.MethodPost, "/users/login/mfa", bytes.NewReader(data))
+			require.NoError(t, err)
+			request.RemoteAddr = clientIP + ":4321"
+
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder, server)
+		})
+	}
+}
+
+func TestMFATokenIsNoAccessToken(t *testing.T) {
+	user, _ := getRandomUser(t)
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().
+		UpsertTOTPSecret(gomock.Any(), gomock.Any()).
+		Times(0)
+	server := newTestServer(t, store)
+
+	mfaToken, err := server.mfaTokenMaker.CreateToken(server.mfaTokenClaims(user.Username), time.Minute)
+	require.NoError(t, err)
+
+	recorder := httptest.NewRecorder()
+	request, err := http.NewRequest(http.MethodPost, "/users/mfa/totp", nil)
+	require.NoError(t, err)
+	request.Header.Set(authorizationHeaderKey, authorizationTypeBearer+" "+mfaToken)
+
+	server.router.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusUnauthorized, recorder.Code)
+}
@@ -0,0 +1,156 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	db "github.com/roman-adamchik/simplebank/db/sqlc"
+	"github.com/roman-adamchik/simplebank/token"
+	"github.com/roman-adamchik/simplebank/util"
+)
+
+const (
+	authorizationHeaderKey  = "authorization"
+	authorizationTypeBearer = "bearer"
+	authorizationTypeAPIKey = "apikey"
+	authorizationPayloadKey = "authorization_payload"
+)
+
+// list of features that can be restricted to users with a verified email
+const (
+	RestrictTransfers = "transfers"
+	RestrictHolds     = "holds"
+	RestrictWebhooks  = "webhooks"
+)
+
+var restrictions = []string{RestrictTransfers, RestrictHolds, RestrictWebhooks}
+
+// validRestrictions checks that the configured restrictions are known features
+func validRestrictions(features []string) error {
+	for _, feature := range features {
+		if !slices.Contains(restrictions, feature) {
+			return fmt.Errorf("unknown restriction %q", feature)
+		}
+	}
+
+	return nil
+}
+
+// authMiddleware creates a gin middleware for authorization. It accepts the user's own access tokens
+// on every route, and API keys and access tokens of OAuth clients on the routes their scopes allow
+func (server *Server) authMiddleware() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		authorizationHeader := ctx.GetHeader(authorizationHeaderKey)
+		if len(authorizationHeader) == 0 {
+			err := errors.New("authorization header is not provided")
+			abortWithError(ctx, http.StatusUnauthorized, err)
+			return
+		}
+
+		fields := strings.Fields(authorizationHeader)
+		if len(fields) < 2 {
+			err := errors.New("invalid authorization header format")
+			abortWithError(ctx, http.StatusUnauthorized, err)
+			return
+		}
+
+		var payload *token.Payload
+		authorizationType := strings.ToLower(fields[0])
+		switch authorizationType {
+		case authorizationTypeBearer:
+			var err error
+			payload, err = server.tokenMaker.VerifyToken(fields[1], server.tokenOptions(server.tokenAudience()))
+			if err != nil {
+				abortWithError(ctx, http.StatusUnauthorized, err)
+				return
+			}
+			switch {
+			case payload.ClientID != "":
+				if !checkRouteScope(ctx, "access token", payload.Scopes) || !server.authorizeOAuthToken(ctx, payload) {
+					return
+				}
+			case payload.SessionID == 0:
+				// the user's own tokens are only issued with a session, so that they can be signed out
+				abortWithError(ctx, http.StatusUnauthorized, errNoSession)
+				return
+			case !server.authorizeSession(ctx, payload):
+				return
+			}
+		case authorizationTypeAPIKey:
+			payload = server.authorizeAPIKey(ctx, fields[1])
+			if payload == nil {
+				return
+			}
+		default:
+			err := fmt.Errorf("unsupported authorization type %s", authorizationType)
+			abortWithError(ctx, http.StatusUnauthorized, err)
+			return
+		}
+
+		ctx.Set(authorizationPayloadKey, payload)
+		ctx.Next()
+	}
+}
+
+// adminMiddleware only lets through users with the admin role.
+// It must run after authMiddleware
+func (server *Server) adminMiddleware() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+		user, err := server.store.GetUser(ctx, authPayload.Username)
+		if err != nil {
+			if errors.Is(err, db.ErrRecordNotFound) {
+				abortWithError(ctx, http.StatusUnauthorized, err)
+				return
+			}
+			abortWithError(ctx, http.StatusInternalServerError, err)
+			return
+		}
+
+		if user.Role != util.AdminRole {
+			err := fmt.Errorf("user %s is not allowed to access this resource", user.Username)
+			abortWithError(ctx, http.StatusForbidden, err)
+			return
+		}
+
+		ctx.Next()
+	}
+}
+
+// restrictUnverified only lets through users with a verified email if the feature is restricted
+// by the config, it lets everyone through otherwise.
+// It must run after authMiddleware
+func (server *Server) restrictUnverified(feature string) gin.HandlerFunc {
+	if !slices.Contains(server.config.UnverifiedRestrictions, feature) {
+		return func(ctx *gin.Context) {
+			ctx.Next()
+		}
+	}
+
+	return func(ctx *gin.Context) {
+		authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+		user, err := server.store.GetUser(ctx, authPayload.Username)
+		if err != nil {
+			if errors.Is(err, db.ErrRecordNotFound) {
+				abortWithError(ctx, http.StatusUnauthorized, err)
+				return
+			}
+			abortWithError(ctx, http.StatusInternalServerError, err)
+			return
+		}
+
+		if !user.IsEmailVerified {
+			err := fmt.Errorf("user %s must verify their email to use %s", user.Username, feature)
+			abortWithError(ctx, http.StatusForbidden, err)
+			return
+		}
+
+		ctx.Next()
+	}
+}
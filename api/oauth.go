@@ -0,0 +1,566 @@
+package api
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/jackc/pgx/v5/pgtype"
+	db "github.com/roman-adamchik/simplebank/db/sqlc"
+	"github.com/roman-adamchik/simplebank/token"
+	"github.com/roman-adamchik/simplebank/util"
+)
+
+const (
+	// oauthCodeTTL is how long a client has to exchange an authorization code
+	oauthCodeTTL = 5 * time.Minute
+	// defaultOAuthRefreshTokenTTL is how long a refresh token can be used if the config does not set it
+	defaultOAuthRefreshTokenTTL = 30 * 24 * time.Hour
+)
+
+// error codes of the OAuth token and revocation endpoints, see RFC 6749 section 5.2
+const (
+	oauthInvalidRequest       = "invalid_request"
+	oauthInvalidClient        = "invalid_client"
+	oauthInvalidGrant         = "invalid_grant"
+	oauthInvalidScope         = "invalid_scope"
+	oauthUnsupportedGrantType = "unsupported_grant_type"
+	oauthAccessDenied         = "access_denied"
+)
+
+var (
+	errUnknownOAuthClient    = errors.New("client is unknown or its credentials are wrong")
+	errInvalidRedirectURI    = errors.New("redirect_uri is not registered for the client")
+	errInvalidCodeVerifier   = errors.New("code_verifier must be 43 to 128 characters")
+	errMissingGrantParameter = errors.New("a parameter of the grant is missing")
+	errOAuthTokenRevoked     = errors.New("the access token was issued before the password of the user was changed")
+)
+
+func (server *Server) oauthRefreshTokenTTL() time.Duration {
+	if server.config.OAuthRefreshTokenTTL <= 0 {
+		return defaultOAuthRefreshTokenTTL
+	}
+	return server.config.OAuthRefreshTokenTTL
+}
+
+// authorizeOAuthToken checks that the user who granted an app its access token can still use the bank.
+// Access tokens of apps have no session, so they stop working once the user is deactivated or changes their password
+func (server *Server) authorizeOAuthToken(ctx *gin.Context, payload *token.Payload) bool {
+	user, err := server.store.GetUser(ctx, payload.Username)
+	if err != nil {
+		if errors.Is(err, db.ErrRecordNotFound) {
+			abortWithError(ctx, http.StatusUnauthorized, token.ErrInvalidToken)
+			return false
+		}
+		abortWithError(ctx, http.StatusInternalServerError, err)
+		return false
+	}
+	if user.DeactivatedAt.Valid {
+		abortWithError(ctx, http.StatusUnauthorized, db.ErrUserDeactivated)
+		return false
+	}
+	if user.PasswordChangedAt.Time.After(payload.IssuedAt) {
+		abortWithError(ctx, http.StatusUnauthorized, errOAuthTokenRevoked)
+		return false
+	}
+
+	return true
+}
+
+type createOAuthClientRequest struct {
+	Name         string   `json:"name" binding:"required,max=64"`
+	RedirectURIs []string `json:"redirect_uris" binding:"required,min=1,unique,dive,url"`
+	Scopes       []string `json:"scopes" binding:"required,min=1,unique,dive,scope"`
+	// Confidential clients get a secret, public clients such as mobile apps rely on PKCE alone
+	Confidential bool `json:"confidential"`
+}
+
+type oauthClientResponse struct {
+	ID           string             `json:"id"`
+	Name         string             `json:"name"`
+	RedirectURIs []string           `json:"redirect_uris"`
+	Scopes       []string           `json:"scopes"`
+	Confidential bool               `json:"confidential"`
+	CreatedBy    string             `json:"created_by"`
+	CreatedAt    pgtype.Timestamptz `json:"created_at"`
+}
+
+func newOAuthClientResponse(client db.OauthClient) oauthClientResponse {
+	return oauthClientResponse{
+		ID:           client.ID,
+		Name:         client.Name,
+		RedirectURIs: client.RedirectUris,
+		Scopes:       client.Scopes,
+		Confidential: client.SecretHash != "",
+		CreatedBy:    client.CreatedBy,
+		CreatedAt:    client.CreatedAt,
+	}
+}
+
+type createOAuthClientResponse struct {
+	// ClientSecret is only shown once, only its hash is stored
+	ClientSecret string              `json:"client_secret,omitempty"`
+	Client       oauthClientResponse `json:"client"`
+}
+
+// createOAuthClient lets an admin register a third-party app
+func (server *Server) createOAuthClient(ctx *gin.Context) {
+	var req createOAuthClientRequest
+
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		respondError(ctx, http.StatusBadRequest, err)
+		return
+	}
+
+	clientID, err := util.RandomSecret(16)
+	if err != nil {
+		respondError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	var secret, secretHash string
+	if req.Confidential {
+		secret, err = util.RandomSecret(32)
+		if err != nil {
+			respondError(ctx, http.StatusInternalServerError, err)
+			return
+		}
+		secretHash = util.HashSecret(secret)
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	client, err := server.store.CreateOAuthClient(ctx, db.CreateOAuthClientParams{
+		ID:           clientID,
+		Name:         req.Name,
+		SecretHash:   secretHash,
+		RedirectUris: req.RedirectURIs,
+		Scopes:       req.Scopes,
+		CreatedBy:    authPayload.Username,
+	})
+	if err != nil {
+		respondError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	rsp := createOAuthClientResponse{
+		ClientSecret: secret,
+		Client:       newOAuthClientResponse(client),
+	}
+	ctx.JSON(http.StatusOK, rsp)
+}
+
+// listOAuthClients lets an admin list the registered apps
+func (server *Server) listOAuthClients(ctx *gin.Context) {
+	clients, err := server.store.ListOAuthClients(ctx)
+	if err != nil {
+		respondError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	rsp := make([]oauthClientResponse, len(clients))
+	for i, client := range clients {
+		rsp[i] = newOAuthClientResponse(client)
+	}
+	ctx.JSON(http.StatusOK, rsp)
+}
+
+type authorizeRequest struct {
+	ResponseType string `form:"response_type" json:"response_type" binding:"required,eq=code"`
+	ClientID     string `form:"client_id" json:"client_id" binding:"required"`
+	RedirectURI  string `form:"redirect_uri" json:"redirect_uri" binding:"required"`
+	// Scope is space delimited, the client gets all the scopes it is registered for if empty
+	Scope               string `form:"scope" json:"scope"`
+	State               string `form:"state" json:"state"`
+	CodeChallenge       string `form:"code_challenge" json:"code_challenge" binding:"required,len=43"`
+	CodeChallengeMethod string `form:"code_challenge_method" json:"code_challenge_method" binding:"required,eq=S256"`
+}
+
+type consentResponse struct {
+	ClientID    string   `json:"client_id"`
+	ClientName  string   `json:"client_name"`
+	Scopes      []string `json:"scopes"`
+	RedirectURI string   `json:"redirect_uri"`
+	State       string   `json:"state"`
+}
+
+// getAuthorization checks an authorization request of a client and returns what the consent screen shows the user
+func (server *Server) getAuthorization(ctx *gin.Context) {
+	var req authorizeRequest
+
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		respondError(ctx, http.StatusBadRequest, err)
+		return
+	}
+
+	client, scopes, ok := server.checkAuthorizeRequest(ctx, req)
+	if !ok {
+		return
+	}
+
+	rsp := consentResponse{
+		ClientID:    client.ID,
+		ClientName:  client.Name,
+		Scopes:      scopes,
+		RedirectURI: req.RedirectURI,
+		State:       req.State,
+	}
+	ctx.JSON(http.StatusOK, rsp)
+}
+
+type consentRequest struct {
+	authorizeRequest
+	Approve bool `json:"approve"`
+}
+
+type consentDecisionResponse struct {
+	// RedirectURI sends the user back to the client with either a code or an error
+	RedirectURI string `json:"redirect_uri"`
+}
+
+// decideAuthorization records the decision of the user on the consent screen.
+// An approval creates an authorization code bound to the PKCE challenge of the client
+func (server *Server) decideAuthorization(ctx *gin.Context) {
+	var req consentRequest
+
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		respondError(ctx, http.StatusBadRequest, err)
+		return
+	}
+
+	client, scopes, ok := server.checkAuthorizeRequest(ctx, req.authorizeRequest)
+	if !ok {
+		return
+	}
+
+	user, ok := server.getAuthUser(ctx)
+	if !ok {
+		return
+	}
+	if user.DeactivatedAt.Valid {
+		respondError(ctx, http.StatusForbidden, db.ErrUserDeactivated)
+		return
+	}
+
+	params := url.Values{}
+	if req.State != "" {
+		params.Set("state", req.State)
+	}
+
+	if !req.Approve {
+		params.Set("error", oauthAccessDenied)
+		ctx.JSON(http.StatusOK, consentDecisionResponse{RedirectURI: withQuery(req.RedirectURI, params)})
+		return
+	}
+
+	code, err := util.RandomSecret(32)
+	if err != nil {
+		respondError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	_, err = server.store.CreateOAuthAuthorizationCode(ctx, db.CreateOAuthAuthorizationCodeParams{
+		CodeHash:      util.HashSecret(code),
+		ClientID:      client.ID,
+		Username:      user.Username,
+		RedirectUri:   req.RedirectURI,
+		Scopes:        scopes,
+		CodeChallenge: req.CodeChallenge,
+		ExpiresAt:     pgtype.Timestamptz{Time: time.Now().Add(oauthCodeTTL), Valid: true},
+	})
+	if err != nil {
+		respondError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	params.Set("code", code)
+	ctx.JSON(http.StatusOK, consentDecisionResponse{RedirectURI: withQuery(req.RedirectURI, params)})
+}
+
+// checkAuthorizeRequest checks the client, redirect URI and scopes of an authorization request.
+// Errors are not sent to the redirect URI, since it cannot be trusted before it is checked
+func (server *Server) checkAuthorizeRequest(ctx *gin.Context, req authorizeRequest) (db.OauthClient, []string, bool) {
+	client, err := server.store.GetOAuthClient(ctx, req.ClientID)
+	if err != nil {
+		if errors.Is(err, db.ErrRecordNotFound) {
+			respondError(ctx, http.StatusBadRequest, errUnknownOAuthClient)
+			return client, nil, false
+		}
+		respondError(ctx, http.StatusInternalServerError, err)
+		return client, nil, false
+	}
+
+	if !slices.Contains(client.RedirectUris, req.RedirectURI) {
+		respondError(ctx, http.StatusBadRequest, errInvalidRedirectURI)
+		return client, nil, false
+	}
+
+	scopes := strings.Fields(req.Scope)
+	if len(scopes) == 0 {
+		scopes = client.Scopes
+	}
+	for _, scope := range scopes {
+		if !slices.Contains(client.Scopes, scope) {
+			err := fmt.Errorf("client cannot ask for the %s scope", scope)
+			respondError(ctx, http.StatusBadRequest, err)
+			return client, nil, false
+		}
+	}
+
+	return client, slices.Compact(slices.Sorted(slices.Values(scopes))), true
+}
+
+// withQuery adds parameters to the query of a redirect URI, keeping the ones it already has
+func withQuery(redirectURI string, params url.Values) string {
+	u, err := url.Parse(redirectURI)
+	if err != nil {
+		return redirectURI
+	}
+
+	query := u.Query()
+	for key, values := range params {
+		query[key] = values
+	}
+	u.RawQuery = query.Encode()
+	return u.String()
+}
+
+type oauthErrorResponse struct {
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description,omitempty"`
+}
+
+// oauthError responds with an error in the format OAuth clients expect
+func oauthError(ctx *gin.Context, status int, code string, err error) {
+	rsp := oauthErrorResponse{Error: code}
+	if err != nil {
+		rsp.ErrorDescription = err.Error()
+	}
+	if status == http.StatusUnauthorized {
+		ctx.Header("WWW-Authenticate", `Basic realm="oauth"`)
+	}
+	ctx.JSON(status, rsp)
+}
+
+// authenticateOAuthClient checks the client credentials sent with HTTP Basic auth or in the form.
+// Public clients only send their ID. It responds with an error and returns false if the client is unknown
+func (server *Server) authenticateOAuthClient(ctx *gin.Context) (db.OauthClient, bool) {
+	clientID, secret, ok := ctx.Request.BasicAuth()
+	if ok {
+		// the credentials are form encoded before they are put in the header, see RFC 6749 section 2.3.1
+		clientID, _ = url.QueryUnescape(clientID)
+		secret, _ = url.QueryUnescape(secret)
+	} else {
+		clientID = ctx.PostForm("client_id")
+		secret = ctx.PostForm("client_secret")
+	}
+
+	client, err := server.store.GetOAuthClient(ctx, clientID)
+	if err != nil {
+		if errors.Is(err, db.ErrRecordNotFound) {
+			oauthError(ctx, http.StatusUnauthorized, oauthInvalidClient, errUnknownOAuthClient)
+			return client, false
+		}
+		respondError(ctx, http.StatusInternalServerError, err)
+		return client, false
+	}
+
+	if client.SecretHash != "" &&
+		subtle.ConstantTimeCompare([]byte(util.HashSecret(secret)), []byte(client.SecretHash)) != 1 {
+		oauthError(ctx, http.StatusUnauthorized, oauthInvalidClient, errUnknownOAuthClient)
+		return client, false
+	}
+
+	return client, true
+}
+
+type oauthTokenRequest struct {
+	GrantType    string `form:"grant_type" binding:"required"`
+	Code         string `form:"code"`
+	RedirectURI  string `form:"redirect_uri"`
+	CodeVerifier string `form:"code_verifier"`
+	RefreshToken string `form:"refresh_token"`
+	Scope        string `form:"scope"`
+}
+
+type oauthTokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int64  `json:"expires_in"`
+	RefreshToken string `json:"refresh_token"`
+	Scope        string `json:"scope"`
+}
+
+// createOAuthToken is the token endpoint of the authorization server. It exchanges authorization codes
+// and rotates refresh tokens, issuing access tokens limited to the scopes of the grant
+func (server *Server) createOAuthToken(ctx *gin.Context) {
+	ctx.Header("Cache-Control", "no-store")
+	ctx.Header("Pragma", "no-cache")
+
+	var req oauthTokenRequest
+	if err := ctx.ShouldBindWith(&req, binding.Form); err != nil {
+		oauthError(ctx, http.StatusBadRequest, oauthInvalidRequest, err)
+		return
+	}
+
+	client, ok := server.authenticateOAuthClient(ctx)
+	if !ok {
+		return
+	}
+
+	refreshToken, err := util.RandomSecret(32)
+	if err != nil {
+		respondError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+	now := time.Now()
+
+	var grant db.OauthRefreshToken
+	switch req.GrantType {
+	case "authorization_code":
+		if req.Code == "" || req.RedirectURI == "" {
+			oauthError(ctx, http.StatusBadRequest, oauthInvalidRequest, errMissingGrantParameter)
+			return
+		}
+		if len(req.CodeVerifier) < 43 || len(req.CodeVerifier) > 128 {
+			oauthError(ctx, http.StatusBadRequest, oauthInvalidRequest, errInvalidCodeVerifier)
+			return
+		}
+
+		grant, err = server.store.ExchangeAuthorizationCodeTx(ctx, db.ExchangeAuthorizationCodeTxParams{
+			CodeHash:              util.HashSecret(req.Code),
+			ClientID:              client.ID,
+			RedirectURI:           req.RedirectURI,
+			CodeChallenge:         pkceChallenge(req.CodeVerifier),
+			RefreshTokenHash:      util.HashSecret(refreshToken),
+			RefreshTokenExpiresAt: now.Add(server.oauthRefreshTokenTTL()),
+			Now:                   now,
+		})
+	case "refresh_token":
+		if req.RefreshToken == "" {
+			oauthError(ctx, http.StatusBadRequest, oauthInvalidRequest, errMissingGrantParameter)
+			return
+		}
+
+		grant, err = server.store.RefreshOAuthTokenTx(ctx, db.RefreshOAuthTokenTxParams{
+			TokenHash:    util.HashSecret(req.RefreshToken),
+			ClientID:     client.ID,
+			Scopes:       strings.Fields(req.Scope),
+			NewTokenHash: util.HashSecret(refreshToken),
+			ExpiresAt:    now.Add(server.oauthRefreshTokenTTL()),
+			Now:          now,
+		})
+	default:
+		err := fmt.Errorf("grant type %q is not supported", req.GrantType)
+		oauthError(ctx, http.StatusBadRequest, oauthUnsupportedGrantType, err)
+		return
+	}
+	if err != nil {
+		if errors.Is(err, db.ErrInvalidGrant) {
+			oauthError(ctx, http.StatusBadRequest, oauthInvalidGrant, err)
+			return
+		}
+		if errors.Is(err, db.ErrInvalidScope) {
+			oauthError(ctx, http.StatusBadRequest, oauthInvalidScope, err)
+			return
+		}
+		respondError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	accessToken, err := server.tokenMaker.CreateToken(token.Claims{
+		Username: grant.Username,
+		Issuer:   server.tokenIssuer(),
+		Audience: []string{server.tokenAudience()},
+		ClientID: grant.ClientID,
+		Scopes:   grant.Scopes,
+	}, server.config.AccessTokenDuration)
+	if err != nil {
+		respondError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	rsp := oauthTokenResponse{
+		AccessToken:  accessToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    int64(server.config.AccessTokenDuration.Seconds()),
+		RefreshToken: refreshToken,
+		Scope:        strings.Join(grant.Scopes, " "),
+	}
+	ctx.JSON(http.StatusOK, rsp)
+}
+
+// pkceChallenge computes the S256 challenge of a PKCE code verifier
+func pkceChallenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// revokeOAuthToken revokes a refresh token of the authenticated client, see RFC 7009.
+// Access tokens cannot be revoked and expire on their own. Unknown tokens are not an error,
+// so that a client cannot probe which tokens exist
+func (server *Server) revokeOAuthToken(ctx *gin.Context) {
+	client, ok := server.authenticateOAuthClient(ctx)
+	if !ok {
+		return
+	}
+
+	refreshToken := ctx.PostForm("token")
+	if refreshToken == "" {
+		oauthError(ctx, http.StatusBadRequest, oauthInvalidRequest, errMissingGrantParameter)
+		return
+	}
+
+	err := server.store.RevokeOAuthRefreshTokenByHash(ctx, db.RevokeOAuthRefreshTokenByHashParams{
+		RevokedAt: pgtype.Timestamptz{Time: time.Now(), Valid: true},
+		TokenHash: util.HashSecret(refreshToken),
+		ClientID:  client.ID,
+	})
+	if err != nil {
+		respondError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	ctx.Status(http.StatusOK)
+}
+
+type oauthGrantURI struct {
+	ClientID string `uri:"client_id" binding:"required"`
+}
+
+// revokeOAuthGrant lets the authenticated user disconnect an app by revoking all its refresh tokens.
+// The access tokens already issued stay valid until they expire
+func (server *Server) revokeOAuthGrant(ctx *gin.Context) {
+	var uri oauthGrantURI
+
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		respondError(ctx, http.StatusBadRequest, err)
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	revoked, err := server.store.RevokeOAuthGrant(ctx, db.RevokeOAuthGrantParams{
+		RevokedAt: pgtype.Timestamptz{Time: time.Now(), Valid: true},
+		ClientID:  uri.ClientID,
+		Username:  authPayload.Username,
+	})
+	if err != nil {
+		respondError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+	if revoked == 0 {
+		err := fmt.Errorf("client %s has no access to the authenticated user", uri.ClientID)
+		respondError(ctx, http.StatusNotFound, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"revoked": revoked})
+}
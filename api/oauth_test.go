@@ -0,0 +1,661 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgtype"
+	mockdb "github.com/roman-adamchik/simplebank/db/mock"
+	db "github.com/roman-adamchik/simplebank/db/sqlc"
+	"github.com/roman-adamchik/simplebank/token"
+	"github.com/roman-adamchik/simplebank/util"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"golang.org/x/oauth2"
+)
+
+const oauthTestRedirectURI = "https://partner.example.com/callback"
+
+func randomOAuthClient(t *testing.T, createdBy string) (db.OauthClient, string) {
+	t.Helper()
+
+	secret, err := util.RandomSecret(32)
+	require.NoError(t, err)
+
+	client := db.OauthClient{
+		ID:           util.RandomString(16),
+		Name:         util.RandomOwner(),
+		SecretHash:   util.HashSecret(secret),
+		RedirectUris: []string{oauthTestRedirectURI},
+		Scopes:       []string{ScopeAccountsRead, ScopeProfileRead},
+		CreatedBy:    createdBy,
+		CreatedAt:    pgtype.Timestamptz{Time: time.Now(), Valid: true},
+	}
+	return client, secret
+}
+
+// TestOAuthAuthorizationCodeFlow drives the whole flow with a standard OAuth2 client:
+// consent, code exchange with PKCE, a scoped API call, refresh and revocation
+func TestOAuthAuthorizationCodeFlow(t *testing.T) {
+	user, _ := getRandomUser(t)
+	client, secret := randomOAuthClient(t, "admin")
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().
+		GetOAuthClient(gomock.Any(), gomock.Eq(client.ID)).
+		AnyTimes().
+		Return(client, nil)
+	store.EXPECT().
+		GetUser(gomock.Any(), gomock.Eq(user.Username)).
+		AnyTimes().
+		Return(user, nil)
+
+	server := newTestServer(t, store)
+	httpServer := httptest.NewServer(server.router)
+	defer httpServer.Close()
+
+	config := oauth2.Config{
+		ClientID:     client.ID,
+		ClientSecret: secret,
+		Endpoint: oauth2.Endpoint{
+			AuthURL:   httpServer.URL + "/oauth/authorize",
+			TokenURL:  httpServer.URL + "/oauth/token",
+			AuthStyle: oauth2.AuthStyleInHeader,
+		},
+		RedirectURL: oauthTestRedirectURI,
+		Scopes:      []string{ScopeProfileRead},
+	}
+	verifier := oauth2.GenerateVerifier()
+	authURL, err := url.Parse(config.AuthCodeURL("xyz", oauth2.S256ChallengeOption(verifier)))
+	require.NoError(t, err)
+
+	// the consent screen shows the app and the scopes it asks for
+	request, err := http.NewRequest(http.MethodGet, "/oauth/authorize?"+authURL.RawQuery, nil)
+	require.NoError(t, err)
+	addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
+	recorder := httptest.NewRecorder()
+	server.router.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusOK, recorder.Code)
+
+	var consent consentResponse
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &consent))
+	require.Equal(t, client.Name, consent.ClientName)
+	require.Equal(t, []string{ScopeProfileRead}, consent.Scopes)
+	require.Equal(t, "xyz", consent.State)
+
+	// the user approves and is sent back to the app with a code
+	var codeHash, challenge string
+	store.EXPECT().
+		CreateOAuthAuthorizationCode(gomock.Any(), gomock.Any()).
+		Times(1).
+		DoAndReturn(func(_ any, arg db.CreateOAuthAuthorizationCodeParams) (db.OauthAuthorizationCode, error) {
+			require.Equal(t, client.ID, arg.ClientID)
+			require.Equal(t, user.Username, arg.Username)
+			require.Equal(t, oauthTestRedirectURI, arg.RedirectUri)
+			require.Equal(t, []string{ScopeProfileRead}, arg.Scopes)
+			require.WithinDuration(t, time.Now().Add(oauthCodeTTL), arg.ExpiresAt.Time, time.Second)
+			codeHash, challenge = arg.CodeHash, arg.CodeChallenge
+			return db.OauthAuthorizationCode{CodeHash: arg.CodeHash}, nil
+		})
+
+	body := gin.H{"approve": true}
+	for key := range authURL.Query() {
+		body[key] = authURL.Query().Get(key)
+	}
+	data, err := json.Marshal(body)
+	require.NoError(t, err)
+	request, err = http.NewRequest(http.MethodPost, "/oauth/authorize", bytes.NewReader(data))
+	require.NoError(t, err)
+	addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
+	recorder = httptest.NewRecorder()
+	server.router.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusOK, recorder.Code)
+
+	var decision consentDecisionResponse
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &decision))
+	redirect, err := url.Parse(decision.RedirectURI)
+	require.NoError(t, err)
+	require.Equal(t, "xyz", redirect.Query().Get("state"))
+	code := redirect.Query().Get("code")
+	require.Equal(t, codeHash, util.HashSecret(code))
+
+	// the app exchanges the code, proving it started the flow with the code verifier
+	var refreshHash string
+	store.EXPECT().
+		ExchangeAuthorizationCodeTx(gomock.Any(), gomock.Any()).
+		Times(1).
+		DoAndReturn(func(_ any, arg db.ExchangeAuthorizationCodeTxParams) (db.OauthRefreshToken, error) {
+			require.Equal(t, codeHash, arg.CodeHash)
+			require.Equal(t, client.ID, arg.ClientID)
+			require.Equal(t, oauthTestRedirectURI, arg.RedirectURI)
+			require.Equal(t, challenge, arg.CodeChallenge)
+			refreshHash = arg.RefreshTokenHash
+			return db.OauthRefreshToken{
+				TokenHash: arg.RefreshTokenHash,
+				ClientID:  client.ID,
+				Username:  user.Username,
+				Scopes:    []string{ScopeProfileRead},
+			}, nil
+		})
+
+	ctx := context.Background()
+	tok, err := config.Exchange(ctx, code, oauth2.VerifierOption(verifier))
+	require.NoError(t, err)
+	require.Equal(t, "Bearer", tok.TokenType)
+	require.Equal(t, refreshHash, util.HashSecret(tok.RefreshToken))
+	require.Equal(t, ScopeProfileRead, tok.Extra("scope"))
+
+	// the access token only works within its scopes
+	httpClient := config.Client(ctx, tok)
+	rsp, err := httpClient.Get(httpServer.URL + "/users/me")
+	require.NoError(t, err)
+	rsp.Body.Close()
+	require.Equal(t, http.StatusOK, rsp.StatusCode)
+
+	rsp, err = httpClient.Get(httpServer.URL + "/api_keys")
+	require.NoError(t, err)
+	rsp.Body.Close()
+	require.Equal(t, http.StatusForbidden, rsp.StatusCode)
+
+	// an expired access token is refreshed with a rotated refresh token
+	var newRefreshHash string
+	store.EXPECT().
+		RefreshOAuthTokenTx(gomock.Any(), gomock.Any()).
+		Times(1).
+		DoAndReturn(func(_ any, arg db.RefreshOAuthTokenTxParams) (db.OauthRefreshToken, error) {
+			require.Equal(t, refreshHash, arg.TokenHash)
+			require.Equal(t, client.ID, arg.ClientID)
+			require.NotEqual(t, refreshHash, arg.NewTokenHash)
+			newRefreshHash = arg.NewTokenHash
+			return db.OauthRefreshToken{
+				TokenHash: arg.NewTokenHash,
+				ClientID:  client.ID,
+				Username:  user.Username,
+				Scopes:    []string{ScopeProfileRead},
+			}, nil
+		})
+
+	tok.Expiry = time.Now().Add(-time.Minute)
+	refreshed, err := config.TokenSource(ctx, tok).Token()
+	require.NoError(t, err)
+	require.NotEqual(t, tok.AccessToken, refreshed.AccessToken)
+	require.Equal(t, newRefreshHash, util.HashSecret(refreshed.RefreshToken))
+
+	// the app disconnects by revoking its refresh token
+	store.EXPECT().
+		RevokeOAuthRefreshTokenByHash(gomock.Any(), gomock.Any()).
+		Times(1).
+		DoAndReturn(func(_ any, arg db.RevokeOAuthRefreshTokenByHashParams) error {
+			require.Equal(t, newRefreshHash, arg.TokenHash)
+			require.Equal(t, client.ID, arg.ClientID)
+			return nil
+		})
+
+	form := url.Values{"token": {refreshed.RefreshToken}}
+	request, err = http.NewRequest(http.MethodPost, httpServer.URL+"/oauth/revoke", strings.NewReader(form.Encode()))
+	require.NoError(t, err)
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	request.SetBasicAuth(client.ID, secret)
+	rsp, err = http.DefaultClient.Do(request)
+	require.NoError(t, err)
+	rsp.Body.Close()
+	require.Equal(t, http.StatusOK, rsp.StatusCode)
+}
+
+func TestDecideAuthorizationAPI(t *testing.T) {
+	user, _ := getRandomUser(t)
+	client, _ := randomOAuthClient(t, "admin")
+	verifier := oauth2.GenerateVerifier()
+
+	validBody := func() gin.H {
+		return gin.H{
+			"response_type":         "code",
+			"client_id":             client.ID,
+			"redirect_uri":          oauthTestRedirectURI,
+			"scope":                 ScopeAccountsRead,
+			"state":                 "xyz",
+			"code_challenge":        oauth2.S256ChallengeFromVerifier(verifier),
+			"code_challenge_method": "S256",
+			"approve":               true,
+		}
+	}
+	expectClient := func(store *mockdb.MockStore) {
+		store.EXPECT().
+			GetOAuthClient(gomock.Any(), gomock.Eq(client.ID)).
+			Times(1).
+			Return(client, nil)
+	}
+	expectNoCode := func(store *mockdb.MockStore) {
+		store.EXPECT().
+			CreateOAuthAuthorizationCode(gomock.Any(), gomock.Any()).
+			Times(0)
+	}
+
+	testCases := []struct {
+		name          string
+		body          func() gin.H
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "Denied",
+			body: func() gin.H {
+				body := validBody()
+				body["approve"] = false
+				return body
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				expectClient(store)
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(user, nil)
+				expectNoCode(store)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var rsp consentDecisionResponse
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &rsp))
+				require.Equal(t, oauthTestRedirectURI+"?error=access_denied&state=xyz", rsp.RedirectURI)
+			},
+		},
+		{
+			name: "UnknownClient",
+			body: validBody,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetOAuthClient(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.OauthClient{}, db.ErrRecordNotFound)
+				expectNoCode(store)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+				requireProblem(t, recorder, "unknown_oauth_client")
+			},
+		},
+		{
+			name: "UnregisteredRedirectURI",
+			body: func() gin.H {
+				body := validBody()
+				body["redirect_uri"] = "https://attacker.example.com/callback"
+				return body
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				expectClient(store)
+				expectNoCode(store)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+				requireProblem(t, recorder, "invalid_redirect_uri")
+			},
+		},
+		{
+			name: "ScopeNotRegistered",
+			body: func() gin.H {
+				body := validBody()
+				body["scope"] = ScopeAccountsRead + " " + ScopeTransfersWrite
+				return body
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				expectClient(store)
+				expectNoCode(store)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+				require.Contains(t, recorder.Body.String(), ScopeTransfersWrite)
+			},
+		},
+		{
+			name: "PlainChallenge",
+			body: func() gin.H {
+				body := validBody()
+				body["code_challenge_method"] = "plain"
+				return body
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetOAuthClient(gomock.Any(), gomock.Any()).
+					Times(0)
+				expectNoCode(store)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "DeactivatedUser",
+			body: validBody,
+			buildStubs: func(store *mockdb.MockStore) {
+				deactivated := user
+				deactivated.DeactivatedAt = pgtype.Timestamptz{Time: time.Now(), Valid: true}
+				expectClient(store)
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(deactivated, nil)
+				expectNoCode(store)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			data, err := json.Marshal(tc.body())
+			require.NoError(t, err)
+
+			request, err := http.NewRequest(http.MethodPost, "/oauth/authorize", bytes.NewReader(data))
+			require.NoError(t, err)
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
+
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestCreateOAuthTokenAPI(t *testing.T) {
+	user, _ := getRandomUser(t)
+	client, secret := randomOAuthClient(t, "admin")
+	verifier := oauth2.GenerateVerifier()
+
+	validForm := func() url.Values {
+		return url.Values{
+			"grant_type":    {"authorization_code"},
+			"code":          {"code"},
+			"redirect_uri":  {oauthTestRedirectURI},
+			"code_verifier": {verifier},
+		}
+	}
+	expectClient := func(store *mockdb.MockStore) {
+		store.EXPECT().
+			GetOAuthClient(gomock.Any(), gomock.Eq(client.ID)).
+			Times(1).
+			Return(client, nil)
+	}
+	expectNoExchange := func(store *mockdb.MockStore) {
+		store.EXPECT().
+			ExchangeAuthorizationCodeTx(gomock.Any(), gomock.Any()).
+			Times(0)
+	}
+
+	testCases := []struct {
+		name          string
+		form          func() url.Values
+		secret        string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:   "OK",
+			form:   validForm,
+			secret: secret,
+			buildStubs: func(store *mockdb.MockStore) {
+				expectClient(store)
+				store.EXPECT().
+					ExchangeAuthorizationCodeTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ any, arg db.ExchangeAuthorizationCodeTxParams) (db.OauthRefreshToken, error) {
+						require.Equal(t, util.HashSecret("code"), arg.CodeHash)
+						require.Equal(t, oauth2.S256ChallengeFromVerifier(verifier), arg.CodeChallenge)
+						require.WithinDuration(t, time.Now().Add(defaultOAuthRefreshTokenTTL), arg.RefreshTokenExpiresAt, time.Second)
+						return db.OauthRefreshToken{
+							ClientID: client.ID,
+							Username: user.Username,
+							Scopes:   client.Scopes,
+						}, nil
+					})
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				require.Equal(t, "no-store", recorder.Header().Get("Cache-Control"))
+
+				var rsp oauthTokenResponse
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &rsp))
+				require.NotEmpty(t, rsp.AccessToken)
+				require.NotEmpty(t, rsp.RefreshToken)
+				require.Equal(t, strings.Join(client.Scopes, " "), rsp.Scope)
+			},
+		},
+		{
+			name:   "WrongSecret",
+			form:   validForm,
+			secret: "wrong",
+			buildStubs: func(store *mockdb.MockStore) {
+				expectClient(store)
+				expectNoExchange(store)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+				require.NotEmpty(t, recorder.Header().Get("WWW-Authenticate"))
+				requireOAuthError(t, recorder.Body, oauthInvalidClient)
+			},
+		},
+		{
+			name: "ShortCodeVerifier",
+			form: func() url.Values {
+				form := validForm()
+				form.Set("code_verifier", "short")
+				return form
+			},
+			secret: secret,
+			buildStubs: func(store *mockdb.MockStore) {
+				expectClient(store)
+				expectNoExchange(store)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+				requireOAuthError(t, recorder.Body, oauthInvalidRequest)
+			},
+		},
+		{
+			name:   "InvalidGrant",
+			form:   validForm,
+			secret: secret,
+			buildStubs: func(store *mockdb.MockStore) {
+				expectClient(store)
+				store.EXPECT().
+					ExchangeAuthorizationCodeTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.OauthRefreshToken{}, db.ErrInvalidGrant)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+				requireOAuthError(t, recorder.Body, oauthInvalidGrant)
+			},
+		},
+		{
+			name: "InvalidScope",
+			form: func() url.Values {
+				return url.Values{
+					"grant_type":    {"refresh_token"},
+					"refresh_token": {"token"},
+					"scope":         {ScopeTransfersWrite},
+				}
+			},
+			secret: secret,
+			buildStubs: func(store *mockdb.MockStore) {
+				expectClient(store)
+				store.EXPECT().
+					RefreshOAuthTokenTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.OauthRefreshToken{}, db.ErrInvalidScope)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+				requireOAuthError(t, recorder.Body, oauthInvalidScope)
+			},
+		},
+		{
+			name: "UnsupportedGrantType",
+			form: func() url.Values {
+				return url.Values{"grant_type": {"password"}}
+			},
+			secret: secret,
+			buildStubs: func(store *mockdb.MockStore) {
+				expectClient(store)
+				expectNoExchange(store)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+				requireOAuthError(t, recorder.Body, oauthUnsupportedGrantType)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			request, err := http.NewRequest(http.MethodPost, "/oauth/token", strings.NewReader(tc.form().Encode()))
+			require.NoError(t, err)
+			request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			request.SetBasicAuth(client.ID, tc.secret)
+
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func requireOAuthError(t *testing.T, body io.Reader, code string) {
+	t.Helper()
+
+	var rsp oauthErrorResponse
+	require.NoError(t, json.NewDecoder(body).Decode(&rsp))
+	require.Equal(t, code, rsp.Error)
+}
+
+func TestOAuthAccessTokenAuth(t *testing.T) {
+	user, _ := getRandomUser(t)
+	client, _ := randomOAuthClient(t, "admin")
+
+	testCases := []struct {
+		name          string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(2).
+					Return(user, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name: "UserDeactivated",
+			buildStubs: func(store *mockdb.MockStore) {
+				deactivated := user
+				deactivated.DeactivatedAt = pgtype.Timestamptz{Time: time.Now(), Valid: true}
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(deactivated, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+				requireProblem(t, recorder, "user_deactivated")
+			},
+		},
+		{
+			name: "PasswordChanged",
+			buildStubs: func(store *mockdb.MockStore) {
+				changed := user
+				changed.PasswordChangedAt = pgtype.Timestamptz{Time: time.Now().Add(time.Minute), Valid: true}
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(changed, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+				requireProblem(t, recorder, "token_revoked")
+			},
+		},
+		{
+			name: "UserNotFound",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(db.User{}, db.ErrRecordNotFound)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+				requireProblem(t, recorder, "invalid_token")
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			accessToken, err := server.tokenMaker.CreateToken(token.Claims{
+				Username: user.Username,
+				Issuer:   defaultTokenIssuer,
+				Audience: []string{defaultTokenAudience},
+				ClientID: client.ID,
+				Scopes:   []string{ScopeProfileRead},
+			}, time.Minute)
+			require.NoError(t, err)
+
+			request, err := http.NewRequest(http.MethodGet, "/users/me", nil)
+			require.NoError(t, err)
+			request.Header.Set(authorizationHeaderKey, authorizationTypeBearer+" "+accessToken)
+
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
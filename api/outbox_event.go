@@ -0,0 +1,67 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	db "github.com/roman-adamchik/simplebank/db/sqlc"
+)
+
+type listOutboxEventsRequest struct {
+	FromID   int64 `form:"from_id" binding:"required,min=1"`
+	PageSize int32 `form:"page_size" binding:"required,min=5,max=100"`
+}
+
+type outboxEventResponse struct {
+	db.OutboxEvent
+	// Payload is embedded as JSON instead of the base64 encoding of the raw bytes
+	Payload json.RawMessage `json:"payload"`
+}
+
+func (server *Server) listOutboxEvents(ctx *gin.Context) {
+	var req listOutboxEventsRequest
+
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		respondError(ctx, http.StatusBadRequest, err)
+		return
+	}
+
+	events, err := server.store.ListOutboxEvents(ctx, db.ListOutboxEventsParams{
+		ID:    req.FromID,
+		Limit: req.PageSize,
+	})
+	if err != nil {
+		respondError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	rsp := make([]outboxEventResponse, len(events))
+	for i, event := range events {
+		rsp[i] = outboxEventResponse{OutboxEvent: event, Payload: event.Payload}
+	}
+
+	ctx.JSON(http.StatusOK, rsp)
+}
+
+type replayOutboxEventsRequest struct {
+	FromID int64 `json:"from_id" binding:"required,min=1"`
+}
+
+// replayOutboxEvents marks the events from the given offset as unsent, so the relay delivers them again in order
+func (server *Server) replayOutboxEvents(ctx *gin.Context) {
+	var req replayOutboxEventsRequest
+
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		respondError(ctx, http.StatusBadRequest, err)
+		return
+	}
+
+	replayed, err := server.store.ReplayOutboxEvents(ctx, req.FromID)
+	if err != nil {
+		respondError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"replayed": replayed})
+}
@@ -0,0 +1,88 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	db "github.com/roman-adamchik/simplebank/db/sqlc"
+	"github.com/roman-adamchik/simplebank/passwordpolicy"
+	"github.com/roman-adamchik/simplebank/token"
+)
+
+var errWrongCurrentPassword = errors.New("current password is incorrect")
+
+// checkPasswordPolicy refuses a new password of a user breaking the password policy,
+// responding with every rule it breaks. It returns whether the password can be used
+func (server *Server) checkPasswordPolicy(ctx *gin.Context, password string, user db.User) bool {
+	err := server.passwordPolicy.Check(password, user.Username, user.Email)
+	if err == nil {
+		return true
+	}
+
+	var policyErr *passwordpolicy.Error
+	if errors.As(err, &policyErr) {
+		p := newProblem(ctx, http.StatusBadRequest, err)
+		p.Extensions = map[string]any{"violations": policyErr.Violations}
+		writeProblem(ctx, p)
+		return false
+	}
+
+	respondError(ctx, http.StatusInternalServerError, err)
+	return false
+}
+
+type changePasswordRequest struct {
+	CurrentPassword string `json:"current_password" binding:"required"`
+	NewPassword     string `json:"new_password" binding:"required"`
+}
+
+// changePassword sets a new password for the authenticated user, who must give their current one.
+// Every session of the user is signed out, including the one of the request, so they log in again with the new password
+func (server *Server) changePassword(ctx *gin.Context) {
+	var req changePasswordRequest
+
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		respondError(ctx, http.StatusBadRequest, err)
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	user, err := server.store.GetUser(ctx, authPayload.Username)
+	if err != nil {
+		if errors.Is(err, db.ErrRecordNotFound) {
+			respondError(ctx, http.StatusNotFound, err)
+			return
+		}
+		respondError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	if err := server.passwordHasher.Verify(req.CurrentPassword, user.HashedPassword); err != nil {
+		respondError(ctx, http.StatusBadRequest, errWrongCurrentPassword)
+		return
+	}
+	if !server.checkPasswordPolicy(ctx, req.NewPassword, user) {
+		return
+	}
+
+	hashedPassword, err := server.passwordHasher.Hash(req.NewPassword)
+	if err != nil {
+		respondError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	user, err = server.store.ChangePasswordTx(ctx, db.ChangePasswordTxParams{
+		Username:       user.Username,
+		HashedPassword: hashedPassword,
+		Now:            time.Now(),
+	})
+	if err != nil {
+		respondError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, newUserResponse(user))
+}
@@ -0,0 +1,115 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgtype"
+	db "github.com/roman-adamchik/simplebank/db/sqlc"
+	"github.com/roman-adamchik/simplebank/task"
+	"github.com/roman-adamchik/simplebank/util"
+	"github.com/roman-adamchik/simplebank/worker"
+)
+
+// passwordResetRequestedMessage is the response to every valid reset request,
+// so that it does not tell whether an email belongs to a user
+const passwordResetRequestedMessage = "if the email belongs to a user, a password reset link has been sent to it"
+
+// passwordResetRequestWindow is the period repeated reset requests for an email are dropped over
+const passwordResetRequestWindow = time.Minute
+
+type requestPasswordResetRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+// requestPasswordReset sends a password reset link to an email.
+// The user is looked up by the background task, so the response and its timing are the same for any email
+func (server *Server) requestPasswordReset(ctx *gin.Context) {
+	var req requestPasswordResetRequest
+
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		respondError(ctx, http.StatusBadRequest, err)
+		return
+	}
+
+	// the task id drops repeated requests for an email within the window of the first one
+	err := server.distributor.Enqueue(ctx, worker.TaskSendPasswordReset,
+		worker.SendPasswordResetPayload{Email: req.Email},
+		task.Queue(task.QueueCritical),
+		task.ID(passwordResetTaskID(req.Email, time.Now())),
+	)
+	if err != nil && !errors.Is(err, task.ErrDuplicateTask) {
+		respondError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	ctx.JSON(http.StatusAccepted, gin.H{"message": passwordResetRequestedMessage})
+}
+
+// passwordResetTaskID identifies the reset requests for an email within the window of the given time.
+// A task that failed every retry keeps its id, so the id must not outlive the window
+func passwordResetTaskID(email string, now time.Time) string {
+	window := now.Truncate(passwordResetRequestWindow).Unix()
+	return fmt.Sprintf("password-reset-%s-%d", util.HashSecret(strings.ToLower(email)), window)
+}
+
+type confirmPasswordResetRequest struct {
+	Token       string `json:"token" binding:"required,min=32,max=128"`
+	NewPassword string `json:"new_password" binding:"required"`
+}
+
+// confirmPasswordReset sets a new password with the token of a reset link
+func (server *Server) confirmPasswordReset(ctx *gin.Context) {
+	var req confirmPasswordResetRequest
+
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		respondError(ctx, http.StatusBadRequest, err)
+		return
+	}
+
+	tokenHash := util.HashSecret(req.Token)
+	now := time.Now()
+
+	// the user of the token is needed to check the password, which leaves the token unused if it is refused
+	user, err := server.store.GetPasswordResetUser(ctx, db.GetPasswordResetUserParams{
+		TokenHash: tokenHash,
+		Now:       pgtype.Timestamptz{Time: now, Valid: true},
+	})
+	if err != nil {
+		if errors.Is(err, db.ErrRecordNotFound) {
+			respondError(ctx, http.StatusBadRequest, db.ErrInvalidPasswordReset)
+			return
+		}
+		respondError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+	if !server.checkPasswordPolicy(ctx, req.NewPassword, user) {
+		return
+	}
+
+	hashedPassword, err := server.passwordHasher.Hash(req.NewPassword)
+	if err != nil {
+		respondError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	_, err = server.store.ResetPasswordTx(ctx, db.ResetPasswordTxParams{
+		TokenHash:      tokenHash,
+		HashedPassword: hashedPassword,
+		Now:            now,
+	})
+	if err != nil {
+		if errors.Is(err, db.ErrInvalidPasswordReset) {
+			respondError(ctx, http.StatusBadRequest, err)
+			return
+		}
+		respondError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "password has been reset"})
+}
@@ -0,0 +1,262 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	mockdb "github.com/roman-adamchik/simplebank/db/mock"
+	db "github.com/roman-adamchik/simplebank/db/sqlc"
+	"github.com/roman-adamchik/simplebank/passwordpolicy"
+	"github.com/roman-adamchik/simplebank/util"
+	"github.com/roman-adamchik/simplebank/worker"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func TestRequestPasswordResetAPI(t *testing.T) {
+	email := util.RandomEmail()
+
+	testCases := []struct {
+		name          string
+		body          gin.H
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder, sent []string)
+	}{
+		{
+			name: "OK",
+			body: gin.H{"email": email},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, sent []string) {
+				require.Equal(t, http.StatusAccepted, recorder.Code)
+				require.JSONEq(t, `{"message":"`+passwordResetRequestedMessage+`"}`, recorder.Body.String())
+				require.Len(t, sent, 1)
+				require.JSONEq(t, `{"email":"`+email+`"}`, sent[0])
+			},
+		},
+		{
+			name: "InvalidEmail",
+			body: gin.H{"email": "invalid-email"},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, sent []string) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+				require.Empty(t, sent)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			// the user is never looked up while handling the request
+			store := mockdb.NewMockStore(ctrl)
+			server := newTestServer(t, store)
+			queue, sent := recordTasks(t, server, worker.TaskSendPasswordReset)
+			recorder := httptest.NewRecorder()
+
+			data, err := json.Marshal(tc.body)
+			require.NoError(t, err)
+
+			request, err := http.NewRequest(http.MethodPost, "/users/password_reset", bytes.NewReader(data))
+			require.NoError(t, err)
+
+			server.router.ServeHTTP(recorder, request)
+			queue.Wait()
+			tc.checkResponse(t, recorder, *sent)
+		})
+	}
+}
+
+func TestRequestPasswordResetRepeated(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	server := newTestServer(t, store)
+
+	// the task queue is not started, so the first request is still queued when the second one comes
+	data, err := json.Marshal(gin.H{"email": util.RandomEmail()})
+	require.NoError(t, err)
+	for range 2 {
+		recorder := httptest.NewRecorder()
+		request, err := http.NewRequest(http.MethodPost, "/users/password_reset", bytes.NewReader(data))
+		require.NoError(t, err)
+
+		server.router.ServeHTTP(recorder, request)
+		require.Equal(t, http.StatusAccepted, recorder.Code)
+	}
+}
+
+func TestPasswordResetTaskID(t *testing.T) {
+	email := util.RandomEmail()
+	now := time.Now().Truncate(passwordResetRequestWindow)
+
+	id := passwordResetTaskID(email, now)
+	require.Equal(t, id, passwordResetTaskID(strings.ToUpper(email), now.Add(passwordResetRequestWindow-time.Second)))
+	require.NotEqual(t, id, passwordResetTaskID(util.RandomEmail(), now))
+
+	// a dead-lettered task keeps its id, which must not stop the requests of the next window
+	require.NotEqual(t, id, passwordResetTaskID(email, now.Add(passwordResetRequestWindow)))
+}
+
+func TestConfirmPasswordResetAPI(t *testing.T) {
+	user, _ := getRandomUser(t)
+	token, err := util.RandomSecret(32)
+	require.NoError(t, err)
+	newPassword := util.RandomString(8)
+
+	expectResetUser := func(store *mockdb.MockStore) {
+		store.EXPECT().
+			GetPasswordResetUser(gomock.Any(), gomock.Any()).
+			Times(1).
+			DoAndReturn(func(_ any, arg db.GetPasswordResetUserParams) (db.User, error) {
+				require.Equal(t, util.HashSecret(token), arg.TokenHash)
+				require.WithinDuration(t, time.Now(), arg.Now.Time, time.Second)
+				return user, nil
+			})
+	}
+
+	testCases := []struct {
+		name          string
+		body          gin.H
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			body: gin.H{"token": token, "new_password": newPassword},
+			buildStubs: func(store *mockdb.MockStore) {
+				expectResetUser(store)
+				store.EXPECT().
+					ResetPasswordTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ any, arg db.ResetPasswordTxParams) (db.User, error) {
+						require.Equal(t, util.HashSecret(token), arg.TokenHash)
+						require.NoError(t, util.CheckPassword(newPassword, arg.HashedPassword))
+						require.WithinDuration(t, time.Now(), arg.Now, time.Second)
+						return user, nil
+					})
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name: "InvalidToken",
+			body: gin.H{"token": token, "new_password": newPassword},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetPasswordResetUser(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.User{}, db.ErrRecordNotFound)
+				store.EXPECT().
+					ResetPasswordTx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+				requireProblem(t, recorder, "invalid_password_reset")
+			},
+		},
+		{
+			name: "TokenUsedMeanwhile",
+			body: gin.H{"token": token, "new_password": newPassword},
+			buildStubs: func(store *mockdb.MockStore) {
+				expectResetUser(store)
+				store.EXPECT().
+					ResetPasswordTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.User{}, db.ErrInvalidPasswordReset)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "ShortPassword",
+			body: gin.H{"token": token, "new_password": "12345"},
+			buildStubs: func(store *mockdb.MockStore) {
+				expectResetUser(store)
+				store.EXPECT().
+					ResetPasswordTx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				requirePolicyViolations(t, recorder, passwordpolicy.RuleMinLength)
+			},
+		},
+		{
+			name: "PasswordContainsUsername",
+			body: gin.H{"token": token, "new_password": "my-" + user.Username + "-password"},
+			buildStubs: func(store *mockdb.MockStore) {
+				expectResetUser(store)
+				store.EXPECT().
+					ResetPasswordTx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				requirePolicyViolations(t, recorder, passwordpolicy.RuleUsername)
+			},
+		},
+		{
+			name: "MissingToken",
+			body: gin.H{"new_password": newPassword},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetPasswordResetUser(gomock.Any(), gomock.Any()).
+					Times(0)
+				store.EXPECT().
+					ResetPasswordTx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "InternalError",
+			body: gin.H{"token": token, "new_password": newPassword},
+			buildStubs: func(store *mockdb.MockStore) {
+				expectResetUser(store)
+				store.EXPECT().
+					ResetPasswordTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.User{}, pgx.ErrTxClosed)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			data, err := json.Marshal(tc.body)
+			require.NoError(t, err)
+
+			request, err := http.NewRequest(http.MethodPost, "/users/password_reset/confirm", bytes.NewReader(data))
+			require.NoError(t, err)
+
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
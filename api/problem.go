@@ -0,0 +1,414 @@
+package api
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	db "github.com/roman-adamchik/simplebank/db/sqlc"
+	"github.com/roman-adamchik/simplebank/passwordpolicy"
+	"github.com/roman-adamchik/simplebank/token"
+	"github.com/roman-adamchik/simplebank/webhook"
+)
+
+const (
+	problemContentType = "application/problem+json"
+	// problemTypePrefix makes the code of a problem its type URI
+	problemTypePrefix = "urn:simplebank:problem:"
+	// correlationIDHeader carries the ID internal errors are logged with
+	correlationIDHeader = "X-Correlation-ID"
+)
+
+// stable codes of problems clients can branch on
+const (
+	codeValidationFailed  = "validation_failed"
+	codeMalformedRequest  = "malformed_request"
+	codeInternalError     = "internal_error"
+	codeNotFound          = "not_found"
+	codeAlreadyExists     = "already_exists"
+	codeInvalidReference  = "invalid_reference"
+	codeLimitExceeded     = "transfer_limit_exceeded"
+	codeWeakPassword      = "weak_password"
+	codeInsufficientFunds = "insufficient_funds"
+	codeCurrencyMismatch  = "currency_mismatch"
+	codeAccountNotFound   = "account_not_found"
+	codeRetryable         = "retryable"
+
+	// codes of the errors in problemCodes
+	codeAccountClosed            = "account_closed"
+	codeHoldNotActive            = "hold_not_active"
+	codeHoldExpired              = "hold_expired"
+	codeCaptureExceedsHold       = "capture_exceeds_hold"
+	codeApprovalRequired         = "approval_required"
+	codeTransferNotPending       = "transfer_not_pending"
+	codeTransferExpired          = "transfer_expired"
+	codeInitiatorCannotDecide    = "initiator_cannot_decide"
+	codeMFAAlreadyEnabled        = "mfa_already_enabled"
+	codeUserDeactivated          = "user_deactivated"
+	codeInvalidPasswordReset     = "invalid_password_reset"
+	codeInvalidVerifyEmail       = "invalid_verify_email"
+	codeVerifyEmailThrottled     = "verify_email_throttled"
+	codeTokenExpired             = "token_expired"
+	codeTokenNotValidYet         = "token_not_valid_yet"
+	codeInvalidTokenIssuer       = "invalid_token_issuer"
+	codeInvalidTokenAudience     = "invalid_token_audience"
+	codeInvalidToken             = "invalid_token"
+	codeInvalidAPIKey            = "invalid_api_key"
+	codeAPIKeyExpired            = "api_key_expired"
+	codeAPIKeyRevoked            = "api_key_revoked"
+	codeScopeNotAllowed          = "scope_not_allowed"
+	codeInvalidCredentials       = "invalid_credentials"
+	codeLoginThrottled           = "login_throttled"
+	codeInvalidMFACode           = "invalid_mfa_code"
+	codeMFANotEnrolled           = "mfa_not_enrolled"
+	codeWrongCurrentPassword     = "wrong_current_password"
+	codeNothingToUpdate          = "nothing_to_update"
+	codePasswordRequired         = "password_required"
+	codeSessionRevoked           = "session_revoked"
+	codeNoSession                = "no_session"
+	codeUnknownOAuthClient       = "unknown_oauth_client"
+	codeInvalidRedirectURI       = "invalid_redirect_uri"
+	codeTokenRevoked             = "token_revoked"
+	codeForbiddenWebhookAddress  = "forbidden_webhook_address"
+	codeInvalidWebAuthnChallenge = "invalid_webauthn_challenge"
+	codeInvalidPasskey           = "invalid_passkey"
+	codeClonedPasskey            = "cloned_passkey"
+
+	// codes of the errors without one of their own, by status
+	codeBadRequest      = "bad_request"
+	codeUnauthorized    = "unauthorized"
+	codeForbidden       = "forbidden"
+	codeConflict        = "conflict"
+	codeTooManyRequests = "too_many_requests"
+)
+
+var (
+	errAccountNotFound  = errors.New("account not found")
+	errCurrencyMismatch = errors.New("currency mismatch")
+	errResourceNotFound = errors.New("resource not found")
+	errInternal         = errors.New("an internal error occurred, report the correlation id if it persists")
+)
+
+// problemCodes are the codes of the errors handlers respond with, looked up with errors.Is.
+// Errors without a code get one from their status
+var problemCodes = []struct {
+	err  error
+	code string
+}{
+	{db.ErrInsufficientFunds, codeInsufficientFunds},
+	{errCurrencyMismatch, codeCurrencyMismatch},
+	{errAccountNotFound, codeAccountNotFound},
+	{db.ErrAccountClosed, codeAccountClosed},
+	{db.ErrHoldNotActive, codeHoldNotActive},
+	{db.ErrHoldExpired, codeHoldExpired},
+	{db.ErrCaptureExceedsHold, codeCaptureExceedsHold},
+	{db.ErrApprovalRequired, codeApprovalRequired},
+	{db.ErrTransferNotPending, codeTransferNotPending},
+	{db.ErrTransferExpired, codeTransferExpired},
+	{db.ErrInitiatorCannotDecide, codeInitiatorCannotDecide},
+	{db.ErrMFAAlreadyEnabled, codeMFAAlreadyEnabled},
+	{db.ErrUserDeactivated, codeUserDeactivated},
+	{db.ErrInvalidPasswordReset, codeInvalidPasswordReset},
+	{db.ErrInvalidVerifyEmail, codeInvalidVerifyEmail},
+	{errVerifyEmailThrottled, codeVerifyEmailThrottled},
+	{token.ErrExpiredToken, codeTokenExpired},
+	{token.ErrTokenNotValidYet, codeTokenNotValidYet},
+	{token.ErrInvalidIssuer, codeInvalidTokenIssuer},
+	{token.ErrInvalidAudience, codeInvalidTokenAudience},
+	{token.ErrInvalidToken, codeInvalidToken},
+	{errInvalidAPIKey, codeInvalidAPIKey},
+	{errAPIKeyExpired, codeAPIKeyExpired},
+	{errAPIKeyRevoked, codeAPIKeyRevoked},
+	{errScopeNotAllowed, codeScopeNotAllowed},
+	{errAPIKeyExpiryInPast, codeValidationFailed},
+	{errInvalidCredentials, codeInvalidCredentials},
+	{errLoginThrottled, codeLoginThrottled},
+	{errInvalidMFACode, codeInvalidMFACode},
+	{errMFANotEnrolled, codeMFANotEnrolled},
+	{errWrongCurrentPassword, codeWrongCurrentPassword},
+	{errNothingToUpdate, codeNothingToUpdate},
+	{errPasswordRequired, codePasswordRequired},
+	{errSessionRevoked, codeSessionRevoked},
+	{errNoSession, codeNoSession},
+	{errUnknownOAuthClient, codeUnknownOAuthClient},
+	{errInvalidRedirectURI, codeInvalidRedirectURI},
+	{errOAuthTokenRevoked, codeTokenRevoked},
+	{webhook.ErrForbiddenAddress, codeForbiddenWebhookAddress},
+	{errInvalidWebAuthnChallenge, codeInvalidWebAuthnChallenge},
+	{errInvalidPasskey, codeInvalidPasskey},
+	{errClonedPasskey, codeClonedPasskey},
+	{errResourceNotFound, codeNotFound},
+}
+
+// statusCodes are the codes of errors without one of their own
+var statusCodes = map[int]string{
+	http.StatusBadRequest:      codeBadRequest,
+	http.StatusUnauthorized:    codeUnauthorized,
+	http.StatusForbidden:       codeForbidden,
+	http.StatusNotFound:        codeNotFound,
+	http.StatusConflict:        codeConflict,
+	http.StatusTooManyRequests: codeTooManyRequests,
+}
+
+// problem is an error response in the problem details format of RFC 7807
+type problem struct {
+	Type     string `json:"type"`
+	Title    string `json:"title"`
+	Status   int    `json:"status"`
+	Detail   string `json:"detail"`
+	Instance string `json:"instance"`
+	// Code is the stable code of the problem, its type without the prefix
+	Code string `json:"code"`
+	// Errors are the fields of a request that failed validation
+	Errors []fieldError `json:"errors,omitempty"`
+	// CorrelationID is the ID an internal error was logged with
+	CorrelationID string `json:"correlation_id,omitempty"`
+	// Extensions are members added next to the standard ones, such as the violations of a weak password
+	Extensions map[string]any `json:"-"`
+}
+
+type fieldError struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+func (p problem) MarshalJSON() ([]byte, error) {
+	type members problem
+	data, err := json.Marshal(members(p))
+	if err != nil || len(p.Extensions) == 0 {
+		return data, err
+	}
+
+	merged := map[string]any{}
+	if err := json.Unmarshal(data, &merged); err != nil {
+		return nil, err
+	}
+	for key, value := range p.Extensions {
+		merged[key] = value
+	}
+	return json.Marshal(merged)
+}
+
+// newProblem describes an error a request failed with. Internal errors are logged with a correlation ID
+// and only the ID is given to the client, so that database and other internals are not leaked.
+// A transaction conflicting with concurrent ones is reported as unavailable, so the client can try again
+func newProblem(ctx *gin.Context, status int, err error) problem {
+	retryable := errors.Is(err, db.ErrRetryable)
+	if retryable {
+		status = http.StatusServiceUnavailable
+	}
+
+	p := problem{
+		Title:    http.StatusText(status),
+		Status:   status,
+		Detail:   err.Error(),
+		Instance: ctx.Request.URL.Path,
+	}
+
+	var validationErrs validator.ValidationErrors
+	var typeErr *json.UnmarshalTypeError
+	var syntaxErr *json.SyntaxError
+	switch {
+	case retryable:
+		p.Code = codeRetryable
+		p.Detail = "the request conflicted with concurrent requests, try again"
+		ctx.Header("Retry-After", "1")
+	case status >= http.StatusInternalServerError:
+		p.Code = codeInternalError
+		p.CorrelationID = newCorrelationID()
+		p.Detail = errInternal.Error()
+		ctx.Header(correlationIDHeader, p.CorrelationID)
+		log.Printf("internal error %s on %s %s: %v", p.CorrelationID, ctx.Request.Method, ctx.Request.URL.Path, err)
+	case errors.As(err, &validationErrs):
+		p.Code = codeValidationFailed
+		p.Detail = "the request has invalid fields"
+		p.Errors = make([]fieldError, len(validationErrs))
+		for i, fieldErr := range validationErrs {
+			p.Errors[i] = newFieldError(fieldErr)
+		}
+	case errors.As(err, &typeErr):
+		p.Code = codeValidationFailed
+		p.Detail = "the request has invalid fields"
+		p.Errors = []fieldError{{
+			Field:   typeErr.Field,
+			Rule:    "type",
+			Message: fmt.Sprintf("must be of type %s", jsonTypeName(typeErr.Type)),
+		}}
+	case errors.As(err, &syntaxErr), errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF):
+		p.Code = codeMalformedRequest
+		p.Detail = "the request body is not valid JSON"
+	case errors.Is(err, db.ErrRecordNotFound):
+		p.Code = codeNotFound
+		p.Detail = errResourceNotFound.Error()
+	case errors.Is(err, db.ErrUniqueViolation):
+		p.Code = codeAlreadyExists
+		p.Detail = "a record with the same values already exists"
+	case errors.Is(err, db.ErrForeignKeyViolation):
+		p.Code = codeInvalidReference
+		p.Detail = "a record the request refers to does not exist"
+	default:
+		p.Code = problemCode(status, err)
+	}
+
+	p.Type = problemTypePrefix + p.Code
+	return p
+}
+
+// problemCode is the code of an error, or the one of its status if it has none
+func problemCode(status int, err error) string {
+	var limitErr *db.LimitExceededError
+	if errors.As(err, &limitErr) {
+		return codeLimitExceeded
+	}
+	var policyErr *passwordpolicy.Error
+	if errors.As(err, &policyErr) {
+		return codeWeakPassword
+	}
+
+	for _, known := range problemCodes {
+		if errors.Is(err, known.err) {
+			return known.code
+		}
+	}
+
+	if code, ok := statusCodes[status]; ok {
+		return code
+	}
+	return strings.ReplaceAll(strings.ToLower(http.StatusText(status)), " ", "_")
+}
+
+// respondError responds to a request with the problem of an error
+func respondError(ctx *gin.Context, status int, err error) {
+	writeProblem(ctx, newProblem(ctx, status, err))
+}
+
+// abortWithError responds with the problem of an error and stops the handlers after the current one
+func abortWithError(ctx *gin.Context, status int, err error) {
+	ctx.Abort()
+	respondError(ctx, status, err)
+}
+
+func writeProblem(ctx *gin.Context, p problem) {
+	ctx.Header("Content-Type", problemContentType)
+	ctx.JSON(p.Status, p)
+}
+
+func newCorrelationID() string {
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return "unknown"
+	}
+	return hex.EncodeToString(id)
+}
+
+// newFieldError translates the failed validation of a field into a message for clients
+func newFieldError(fieldErr validator.FieldError) fieldError {
+	// the namespace starts with the name of the request struct
+	field := fieldErr.Namespace()
+	if _, rest, ok := strings.Cut(field, "."); ok {
+		field = rest
+	}
+
+	return fieldError{
+		Field:   field,
+		Rule:    fieldErr.Tag(),
+		Message: validationMessage(fieldErr),
+	}
+}
+
+func validationMessage(fieldErr validator.FieldError) string {
+	param := fieldErr.Param()
+	sized := fieldErr.Kind() == reflect.String || fieldErr.Kind() == reflect.Slice || fieldErr.Kind() == reflect.Map
+	unit := ""
+	if fieldErr.Kind() == reflect.String {
+		unit = " characters"
+	} else if sized {
+		unit = " items"
+	}
+
+	switch fieldErr.Tag() {
+	case "required":
+		return "is required"
+	case "min":
+		if sized {
+			return fmt.Sprintf("must have at least %s%s", param, unit)
+		}
+		return fmt.Sprintf("must be at least %s", param)
+	case "max":
+		if sized {
+			return fmt.Sprintf("must have at most %s%s", param, unit)
+		}
+		return fmt.Sprintf("must be at most %s", param)
+	case "len":
+		return fmt.Sprintf("must have exactly %s%s", param, unit)
+	case "gt":
+		return fmt.Sprintf("must be greater than %s", param)
+	case "gte":
+		return fmt.Sprintf("must be at least %s", param)
+	case "lt":
+		return fmt.Sprintf("must be less than %s", param)
+	case "lte":
+		return fmt.Sprintf("must be at most %s", param)
+	case "oneof":
+		return fmt.Sprintf("must be one of: %s", strings.ReplaceAll(param, " ", ", "))
+	case "email":
+		return "must be a valid email address"
+	case "alphanum":
+		return "must contain only letters and digits"
+	case "url", "http_url":
+		return "must be a valid URL"
+	case "currency":
+		return "must be a supported currency"
+	case "tier":
+		return "must be a valid tier"
+	case "webhook_event":
+		return "must be a known webhook event"
+	case "scope":
+		return "must be a known scope"
+	default:
+		return fmt.Sprintf("failed the %s check", fieldErr.Tag())
+	}
+}
+
+// jsonTypeName is how a Go type is called in JSON
+func jsonTypeName(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.String:
+		return "string"
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "integer"
+	case reflect.Float32, reflect.Float64:
+		return "number"
+	case reflect.Slice, reflect.Array:
+		return "array"
+	default:
+		return "object"
+	}
+}
+
+// jsonFieldName names the fields of requests in validation errors as clients send them
+func jsonFieldName(field reflect.StructField) string {
+	for _, key := range []string{"json", "uri", "form"} {
+		name, _, _ := strings.Cut(field.Tag.Get(key), ",")
+		if name == "-" {
+			return ""
+		}
+		if name != "" {
+			return name
+		}
+	}
+	return field.Name
+}
@@ -0,0 +1,261 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	db "github.com/roman-adamchik/simplebank/db/sqlc"
+	"github.com/roman-adamchik/simplebank/token"
+	"github.com/roman-adamchik/simplebank/webhook"
+	"github.com/stretchr/testify/require"
+)
+
+// requireProblem checks that a response is a problem with the given code
+func requireProblem(t *testing.T, recorder *httptest.ResponseRecorder, code string) problem {
+	t.Helper()
+
+	require.Equal(t, problemContentType, recorder.Header().Get("Content-Type"))
+
+	var p problem
+	err := json.Unmarshal(recorder.Body.Bytes(), &p)
+	require.NoError(t, err)
+	require.Equal(t, code, p.Code)
+	require.Equal(t, problemTypePrefix+code, p.Type)
+	require.Equal(t, recorder.Code, p.Status)
+	require.Equal(t, http.StatusText(recorder.Code), p.Title)
+	require.NotEmpty(t, p.Detail)
+	require.NotEmpty(t, p.Instance)
+
+	return p
+}
+
+type problemTestRequest struct {
+	Owner    string `json:"owner" binding:"required,alphanum"`
+	Amount   int64  `json:"amount" binding:"gt=0"`
+	Currency string `json:"currency" binding:"required,currency"`
+	Legs     []struct {
+		Amount int64 `json:"amount" binding:"required,min=1"`
+	} `json:"legs" binding:"omitempty,dive"`
+}
+
+func TestProblemResponse(t *testing.T) {
+	testCases := []struct {
+		name          string
+		body          string
+		err           error
+		status        int
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:   "ValidationFailed",
+			body:   `{"owner":"not valid!","amount":0,"legs":[{"amount":0}]}`,
+			status: http.StatusBadRequest,
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				p := requireProblem(t, recorder, codeValidationFailed)
+				require.Equal(t, []fieldError{
+					{Field: "owner", Rule: "alphanum", Message: "must contain only letters and digits"},
+					{Field: "amount", Rule: "gt", Message: "must be greater than 0"},
+					{Field: "currency", Rule: "required", Message: "is required"},
+					{Field: "legs[0].amount", Rule: "required", Message: "is required"},
+				}, p.Errors)
+			},
+		},
+		{
+			name:   "WrongType",
+			body:   `{"owner":"alice","amount":"ten","currency":"USD"}`,
+			status: http.StatusBadRequest,
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				p := requireProblem(t, recorder, codeValidationFailed)
+				require.Equal(t, []fieldError{{Field: "amount", Rule: "type", Message: "must be of type integer"}}, p.Errors)
+			},
+		},
+		{
+			name:   "MalformedJSON",
+			body:   `{"owner":`,
+			status: http.StatusBadRequest,
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				requireProblem(t, recorder, codeMalformedRequest)
+			},
+		},
+		{
+			name:   "KnownError",
+			err:    fmt.Errorf("cannot transfer: %w", db.ErrInsufficientFunds),
+			status: http.StatusForbidden,
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				p := requireProblem(t, recorder, codeInsufficientFunds)
+				require.Equal(t, "cannot transfer: insufficient funds", p.Detail)
+			},
+		},
+		{
+			name:   "ErrorWithoutCode",
+			err:    errors.New("account [1] doesn't belong to the authenticated user"),
+			status: http.StatusForbidden,
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				requireProblem(t, recorder, "forbidden")
+			},
+		},
+		{
+			name:   "NoRows",
+			err:    db.ErrRecordNotFound,
+			status: http.StatusNotFound,
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				p := requireProblem(t, recorder, codeNotFound)
+				require.Equal(t, errResourceNotFound.Error(), p.Detail)
+			},
+		},
+		{
+			name:   "UniqueViolation",
+			err:    &db.ConstraintError{Violation: db.ErrUniqueViolation, Constraint: "owner_currency_key"},
+			status: http.StatusForbidden,
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				requireProblem(t, recorder, codeAlreadyExists)
+				require.NotContains(t, recorder.Body.String(), "owner_currency_key")
+			},
+		},
+		{
+			name:   "ForeignKeyViolation",
+			err:    &db.ConstraintError{Violation: db.ErrForeignKeyViolation, Constraint: "accounts_owner_fkey"},
+			status: http.StatusForbidden,
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				requireProblem(t, recorder, codeInvalidReference)
+				require.NotContains(t, recorder.Body.String(), "accounts_owner_fkey")
+			},
+		},
+		{
+			name:   "InternalError",
+			err:    fmt.Errorf("cannot query: %w", pgx.ErrTxClosed),
+			status: http.StatusInternalServerError,
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				p := requireProblem(t, recorder, codeInternalError)
+				require.NotEmpty(t, p.CorrelationID)
+				require.Equal(t, p.CorrelationID, recorder.Header().Get(correlationIDHeader))
+				require.NotContains(t, recorder.Body.String(), pgx.ErrTxClosed.Error())
+			},
+		},
+		{
+			name:   "LimitExceeded",
+			err:    &db.LimitExceededError{Limit: db.LimitDailyAccount, Currency: "USD", Max: 100, Remaining: 10},
+			status: http.StatusForbidden,
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				requireProblem(t, recorder, codeLimitExceeded)
+
+				var rsp map[string]any
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &rsp))
+				require.Equal(t, db.LimitDailyAccount, rsp["limit"])
+				require.Equal(t, float64(10), rsp["remaining"])
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			server := newTestServer(t, nil)
+
+			problemPath := "/problem"
+			server.router.POST(problemPath, func(ctx *gin.Context) {
+				var limitErr *db.LimitExceededError
+				switch {
+				case tc.err == nil:
+					var req problemTestRequest
+					err := ctx.ShouldBindJSON(&req)
+					require.Error(t, err)
+					respondError(ctx, tc.status, err)
+				case errors.As(tc.err, &limitErr):
+					respondLimitExceeded(ctx, limitErr)
+				default:
+					respondError(ctx, tc.status, tc.err)
+				}
+			})
+
+			recorder := httptest.NewRecorder()
+			request, err := http.NewRequest(http.MethodPost, problemPath, bytes.NewBufferString(tc.body))
+			require.NoError(t, err)
+
+			server.router.ServeHTTP(recorder, request)
+			require.Equal(t, tc.status, recorder.Code)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+// TestProblemCodes pins the code of every known error, since clients branch on them
+func TestProblemCodes(t *testing.T) {
+	codes := []struct {
+		err  error
+		code string
+	}{
+		{db.ErrInsufficientFunds, "insufficient_funds"},
+		{errCurrencyMismatch, "currency_mismatch"},
+		{errAccountNotFound, "account_not_found"},
+		{db.ErrAccountClosed, "account_closed"},
+		{db.ErrHoldNotActive, "hold_not_active"},
+		{db.ErrHoldExpired, "hold_expired"},
+		{db.ErrCaptureExceedsHold, "capture_exceeds_hold"},
+		{db.ErrApprovalRequired, "approval_required"},
+		{db.ErrTransferNotPending, "transfer_not_pending"},
+		{db.ErrTransferExpired, "transfer_expired"},
+		{db.ErrInitiatorCannotDecide, "initiator_cannot_decide"},
+		{db.ErrMFAAlreadyEnabled, "mfa_already_enabled"},
+		{db.ErrUserDeactivated, "user_deactivated"},
+		{db.ErrInvalidPasswordReset, "invalid_password_reset"},
+		{db.ErrInvalidVerifyEmail, "invalid_verify_email"},
+		{errVerifyEmailThrottled, "verify_email_throttled"},
+		{token.ErrExpiredToken, "token_expired"},
+		{token.ErrTokenNotValidYet, "token_not_valid_yet"},
+		{token.ErrInvalidIssuer, "invalid_token_issuer"},
+		{token.ErrInvalidAudience, "invalid_token_audience"},
+		{token.ErrInvalidToken, "invalid_token"},
+		{errInvalidAPIKey, "invalid_api_key"},
+		{errAPIKeyExpired, "api_key_expired"},
+		{errAPIKeyRevoked, "api_key_revoked"},
+		{errScopeNotAllowed, "scope_not_allowed"},
+		{errAPIKeyExpiryInPast, "validation_failed"},
+		{errInvalidCredentials, "invalid_credentials"},
+		{errLoginThrottled, "login_throttled"},
+		{errInvalidMFACode, "invalid_mfa_code"},
+		{errMFANotEnrolled, "mfa_not_enrolled"},
+		{errWrongCurrentPassword, "wrong_current_password"},
+		{errNothingToUpdate, "nothing_to_update"},
+		{errPasswordRequired, "password_required"},
+		{errSessionRevoked, "session_revoked"},
+		{errNoSession, "no_session"},
+		{errUnknownOAuthClient, "unknown_oauth_client"},
+		{errInvalidRedirectURI, "invalid_redirect_uri"},
+		{errOAuthTokenRevoked, "token_revoked"},
+		{webhook.ErrForbiddenAddress, "forbidden_webhook_address"},
+		{errInvalidWebAuthnChallenge, "invalid_webauthn_challenge"},
+		{errInvalidPasskey, "invalid_passkey"},
+		{errClonedPasskey, "cloned_passkey"},
+		{errResourceNotFound, "not_found"},
+	}
+	require.Len(t, codes, len(problemCodes), "every error in problemCodes must be pinned here")
+
+	for _, tc := range codes {
+		err := fmt.Errorf("wrapped: %w", tc.err)
+		require.Equal(t, tc.code, problemCode(http.StatusBadRequest, err), tc.err.Error())
+	}
+
+	statuses := map[int]string{
+		http.StatusBadRequest:      "bad_request",
+		http.StatusUnauthorized:    "unauthorized",
+		http.StatusForbidden:       "forbidden",
+		http.StatusNotFound:        "not_found",
+		http.StatusConflict:        "conflict",
+		http.StatusTooManyRequests: "too_many_requests",
+	}
+	require.Len(t, statuses, len(statusCodes), "every status in statusCodes must be pinned here")
+
+	for status, code := range statuses {
+		require.Equal(t, code, problemCode(status, errors.New("no code")), http.StatusText(status))
+	}
+	require.Equal(t, "payment_required", problemCode(http.StatusPaymentRequired, errors.New("no code")))
+}
@@ -0,0 +1,162 @@
+package api
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgtype"
+	db "github.com/roman-adamchik/simplebank/db/sqlc"
+	"github.com/roman-adamchik/simplebank/token"
+)
+
+var (
+	errNothingToUpdate  = errors.New("no profile field to update")
+	errPasswordRequired = errors.New("password is required to change the email")
+)
+
+// getAuthUser loads the authenticated user, responding with an error if it cannot
+func (server *Server) getAuthUser(ctx *gin.Context) (db.User, bool) {
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	user, err := server.store.GetUser(ctx, authPayload.Username)
+	if err != nil {
+		if errors.Is(err, db.ErrRecordNotFound) {
+			respondError(ctx, http.StatusNotFound, err)
+			return db.User{}, false
+		}
+		respondError(ctx, http.StatusInternalServerError, err)
+		return db.User{}, false
+	}
+
+	return user, true
+}
+
+// getCurrentUser returns the profile of the authenticated user
+func (server *Server) getCurrentUser(ctx *gin.Context) {
+	user, ok := server.getAuthUser(ctx)
+	if !ok {
+		return
+	}
+
+	ctx.JSON(http.StatusOK, newUserResponse(user))
+}
+
+type updateCurrentUserRequest struct {
+	FullName *string `json:"full_name" binding:"omitempty,min=1"`
+	Email    *string `json:"email" binding:"omitempty,email"`
+	// Password is the current password of the user, only needed to change the email
+	Password string `json:"password"`
+}
+
+// updateCurrentUser updates the profile fields given for the authenticated user.
+// A new email must be verified again, and needs the password so that a stolen token cannot take over the account
+func (server *Server) updateCurrentUser(ctx *gin.Context) {
+	var req updateCurrentUserRequest
+
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		respondError(ctx, http.StatusBadRequest, err)
+		return
+	}
+
+	user, ok := server.getAuthUser(ctx)
+	if !ok {
+		return
+	}
+	if user.DeactivatedAt.Valid {
+		respondError(ctx, http.StatusForbidden, db.ErrUserDeactivated)
+		return
+	}
+
+	arg := db.UpdateUserParams{Username: user.Username}
+	if req.FullName != nil {
+		arg.FullName = pgtype.Text{String: *req.FullName, Valid: true}
+	}
+	emailChanged := req.Email != nil && !strings.EqualFold(*req.Email, user.Email)
+	if emailChanged {
+		if req.Password == "" {
+			respondError(ctx, http.StatusBadRequest, errPasswordRequired)
+			return
+		}
+		if err := server.passwordHasher.Verify(req.Password, user.HashedPassword); err != nil {
+			respondError(ctx, http.StatusBadRequest, errWrongCurrentPassword)
+			return
+		}
+		arg.Email = pgtype.Text{String: *req.Email, Valid: true}
+		arg.IsEmailVerified = pgtype.Bool{Bool: false, Valid: true}
+	}
+	if !arg.FullName.Valid && !arg.Email.Valid {
+		respondError(ctx, http.StatusBadRequest, errNothingToUpdate)
+		return
+	}
+
+	user, err := server.store.UpdateUser(ctx, arg)
+	if err != nil {
+		if errors.Is(err, db.ErrUniqueViolation) {
+			respondError(ctx, http.StatusForbidden, err)
+			return
+		}
+		respondError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	if emailChanged {
+		// the user can ask for another verification email if this one cannot be queued
+		if err := server.sendVerifyEmail(ctx, user); err != nil {
+			log.Printf("cannot enqueue verification email of user %s: %v", user.Username, err)
+		}
+	}
+
+	ctx.JSON(http.StatusOK, newUserResponse(user))
+}
+
+type deactivateCurrentUserRequest struct {
+	Password string `json:"password" binding:"required"`
+}
+
+type deactivateCurrentUserResponse struct {
+	User           userResponse `json:"user"`
+	ClosedAccounts []db.Account `json:"closed_accounts"`
+}
+
+// deactivateCurrentUser deactivates the authenticated user, who can no longer log in,
+// and closes their accounts without funds
+func (server *Server) deactivateCurrentUser(ctx *gin.Context) {
+	var req deactivateCurrentUserRequest
+
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		respondError(ctx, http.StatusBadRequest, err)
+		return
+	}
+
+	user, ok := server.getAuthUser(ctx)
+	if !ok {
+		return
+	}
+	if err := server.passwordHasher.Verify(req.Password, user.HashedPassword); err != nil {
+		respondError(ctx, http.StatusBadRequest, errWrongCurrentPassword)
+		return
+	}
+
+	result, err := server.store.DeactivateUserTx(ctx, db.DeactivateUserTxParams{
+		Username: user.Username,
+		Now:      time.Now(),
+	})
+	if err != nil {
+		if errors.Is(err, db.ErrUserDeactivated) {
+			respondError(ctx, http.StatusConflict, err)
+			return
+		}
+		respondError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	rsp := deactivateCurrentUserResponse{
+		User:           newUserResponse(result.User),
+		ClosedAccounts: result.ClosedAccounts,
+	}
+	ctx.JSON(http.StatusOK, rsp)
+}
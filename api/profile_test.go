@@ -0,0 +1,466 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	mockdb "github.com/roman-adamchik/simplebank/db/mock"
+	db "github.com/roman-adamchik/simplebank/db/sqlc"
+	"github.com/roman-adamchik/simplebank/util"
+	"github.com/roman-adamchik/simplebank/worker"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func TestGetCurrentUserAPI(t *testing.T) {
+	user, _ := getRandomUser(t)
+
+	testCases := []struct {
+		name          string
+		setupAuth     bool
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:      "OK",
+			setupAuth: true,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(user, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				requireBodyMatchUser(t, recorder.Body, user)
+			},
+		},
+		{
+			name:      "NotFound",
+			setupAuth: true,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(db.User{}, db.ErrRecordNotFound)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name: "NoAuthorization",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			request, err := http.NewRequest(http.MethodGet, "/users/me", nil)
+			require.NoError(t, err)
+
+			if tc.setupAuth {
+				addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
+			}
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestUpdateCurrentUserAPI(t *testing.T) {
+	user, password := getRandomUser(t)
+	user.IsEmailVerified = true
+	newFullName := util.RandomOwner()
+	newEmail := util.RandomEmail()
+	deactivated := user
+	deactivated.DeactivatedAt = pgtype.Timestamptz{Time: time.Now(), Valid: true}
+
+	expectGetUser := func(store *mockdb.MockStore, user db.User) {
+		store.EXPECT().
+			GetUser(gomock.Any(), gomock.Eq(user.Username)).
+			Times(1).
+			Return(user, nil)
+	}
+
+	testCases := []struct {
+		name          string
+		body          gin.H
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder, sent []string)
+	}{
+		{
+			name: "UpdateFullName",
+			body: gin.H{"full_name": newFullName},
+			buildStubs: func(store *mockdb.MockStore) {
+				expectGetUser(store, user)
+				store.EXPECT().
+					UpdateUser(gomock.Any(), gomock.Eq(db.UpdateUserParams{
+						FullName: pgtype.Text{String: newFullName, Valid: true},
+						Username: user.Username,
+					})).
+					Times(1).
+					DoAndReturn(func(_ any, arg db.UpdateUserParams) (db.User, error) {
+						updated := user
+						updated.FullName = arg.FullName.String
+						return updated, nil
+					})
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, sent []string) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				updated := user
+				updated.FullName = newFullName
+				requireBodyMatchUser(t, recorder.Body, updated)
+				require.Empty(t, sent)
+			},
+		},
+		{
+			name: "UpdateEmail",
+			body: gin.H{"email": newEmail, "password": password},
+			buildStubs: func(store *mockdb.MockStore) {
+				expectGetUser(store, user)
+				store.EXPECT().
+					UpdateUser(gomock.Any(), gomock.Eq(db.UpdateUserParams{
+						Email:           pgtype.Text{String: newEmail, Valid: true},
+						IsEmailVerified: pgtype.Bool{Bool: false, Valid: true},
+						Username:        user.Username,
+					})).
+					Times(1).
+					DoAndReturn(func(_ any, arg db.UpdateUserParams) (db.User, error) {
+						updated := user
+						updated.Email = arg.Email.String
+						updated.IsEmailVerified = false
+						return updated, nil
+					})
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, sent []string) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				updated := user
+				updated.Email = newEmail
+				updated.IsEmailVerified = false
+				requireBodyMatchUser(t, recorder.Body, updated)
+				require.Len(t, sent, 1)
+				require.JSONEq(t, fmt.Sprintf(`{"username":%q}`, user.Username), sent[0])
+			},
+		},
+		{
+			name: "SameEmail",
+			body: gin.H{"email": user.Email},
+			buildStubs: func(store *mockdb.MockStore) {
+				expectGetUser(store, user)
+				store.EXPECT().
+					UpdateUser(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, sent []string) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+				requireProblem(t, recorder, "nothing_to_update")
+			},
+		},
+		{
+			name: "EmailWithoutPassword",
+			body: gin.H{"email": newEmail},
+			buildStubs: func(store *mockdb.MockStore) {
+				expectGetUser(store, user)
+				store.EXPECT().
+					UpdateUser(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, sent []string) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+				requireProblem(t, recorder, "password_required")
+				require.Empty(t, sent)
+			},
+		},
+		{
+			name: "EmailWithWrongPassword",
+			body: gin.H{"email": newEmail, "password": "wrong-password"},
+			buildStubs: func(store *mockdb.MockStore) {
+				expectGetUser(store, user)
+				store.EXPECT().
+					UpdateUser(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, sent []string) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+				requireProblem(t, recorder, "wrong_current_password")
+				require.Empty(t, sent)
+			},
+		},
+		{
+			name: "EmailTaken",
+			body: gin.H{"email": newEmail, "password": password},
+			buildStubs: func(store *mockdb.MockStore) {
+				expectGetUser(store, user)
+				store.EXPECT().
+					UpdateUser(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.User{}, &db.ConstraintError{Violation: db.ErrUniqueViolation, Constraint: "users_email_key"})
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, sent []string) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+				require.Empty(t, sent)
+			},
+		},
+		{
+			name: "InvalidEmail",
+			body: gin.H{"email": "invalid-email", "password": password},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, sent []string) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "EmptyFullName",
+			body: gin.H{"full_name": ""},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, sent []string) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "NothingToUpdate",
+			body: gin.H{},
+			buildStubs: func(store *mockdb.MockStore) {
+				expectGetUser(store, user)
+				store.EXPECT().
+					UpdateUser(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, sent []string) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "Deactivated",
+			body: gin.H{"full_name": newFullName},
+			buildStubs: func(store *mockdb.MockStore) {
+				expectGetUser(store, deactivated)
+				store.EXPECT().
+					UpdateUser(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, sent []string) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+				requireProblem(t, recorder, "user_deactivated")
+			},
+		},
+		{
+			name: "InternalError",
+			body: gin.H{"full_name": newFullName},
+			buildStubs: func(store *mockdb.MockStore) {
+				expectGetUser(store, user)
+				store.EXPECT().
+					UpdateUser(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.User{}, pgx.ErrTxClosed)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, sent []string) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			queue, sent := recordTasks(t, server, worker.TaskSendVerifyEmail)
+			recorder := httptest.NewRecorder()
+
+			data, err := json.Marshal(tc.body)
+			require.NoError(t, err)
+
+			request, err := http.NewRequest(http.MethodPatch, "/users/me", bytes.NewReader(data))
+			require.NoError(t, err)
+
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
+			server.router.ServeHTTP(recorder, request)
+			queue.Wait()
+			tc.checkResponse(t, recorder, *sent)
+		})
+	}
+}
+
+func TestDeactivateCurrentUserAPI(t *testing.T) {
+	user, password := getRandomUser(t)
+	deactivated := user
+	deactivated.DeactivatedAt = pgtype.Timestamptz{Time: time.Now(), Valid: true}
+	closedAccount := getRandomAccount()
+	closedAccount.Owner = user.Username
+	closedAccount.Balance = 0
+	closedAccount.ClosedAt = deactivated.DeactivatedAt
+
+	testCases := []struct {
+		name          string
+		body          gin.H
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			body: gin.H{"password": password},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(user, nil)
+				store.EXPECT().
+					DeactivateUserTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ any, arg db.DeactivateUserTxParams) (db.DeactivateUserTxResult, error) {
+						require.Equal(t, user.Username, arg.Username)
+						require.WithinDuration(t, time.Now(), arg.Now, time.Second)
+						return db.DeactivateUserTxResult{
+							User:           deactivated,
+							ClosedAccounts: []db.Account{closedAccount},
+						}, nil
+					})
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var rsp deactivateCurrentUserResponse
+				err := json.Unmarshal(recorder.Body.Bytes(), &rsp)
+				require.NoError(t, err)
+				require.Equal(t, user.Username, rsp.User.Username)
+				require.True(t, rsp.User.DeactivatedAt.Valid)
+				require.Len(t, rsp.ClosedAccounts, 1)
+				require.Equal(t, closedAccount.ID, rsp.ClosedAccounts[0].ID)
+				require.True(t, rsp.ClosedAccounts[0].ClosedAt.Valid)
+			},
+		},
+		{
+			name: "WrongPassword",
+			body: gin.H{"password": "wrong-password"},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(user, nil)
+				store.EXPECT().
+					DeactivateUserTx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+				requireProblem(t, recorder, "wrong_current_password")
+			},
+		},
+		{
+			name: "MissingPassword",
+			body: gin.H{},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Any()).
+					Times(0)
+				store.EXPECT().
+					DeactivateUserTx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "AlreadyDeactivated",
+			body: gin.H{"password": password},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(deactivated, nil)
+				store.EXPECT().
+					DeactivateUserTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.DeactivateUserTxResult{}, db.ErrUserDeactivated)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusConflict, recorder.Code)
+			},
+		},
+		{
+			name: "InternalError",
+			body: gin.H{"password": password},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(user, nil)
+				store.EXPECT().
+					DeactivateUserTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.DeactivateUserTxResult{}, pgx.ErrTxClosed)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			data, err := json.Marshal(tc.body)
+			require.NoError(t, err)
+
+			request, err := http.NewRequest(http.MethodPost, "/users/me/deactivate", bytes.NewReader(data))
+			require.NoError(t, err)
+
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
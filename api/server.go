@@ -1,36 +1,89 @@
 package api
 
 import (
+	"expvar"
 	"fmt"
 
 	"github.com/gin-gonic/gin"
 	"github.com/gin-gonic/gin/binding"
 	"github.com/go-playground/validator/v10"
+	"github.com/go-webauthn/webauthn/webauthn"
 	db "github.com/roman-adamchik/simplebank/db/sqlc"
+	"github.com/roman-adamchik/simplebank/mfa"
+	"github.com/roman-adamchik/simplebank/passwordpolicy"
+	"github.com/roman-adamchik/simplebank/stream"
+	"github.com/roman-adamchik/simplebank/task"
 	"github.com/roman-adamchik/simplebank/token"
 	"github.com/roman-adamchik/simplebank/util"
 )
 
 type Server struct {
-	config     util.Config
-	store      db.Store
-	tokenMaker token.Maker
-	router     *gin.Engine
+	config      util.Config
+	store       db.Store
+	tokenMaker  token.Maker
+	router      *gin.Engine
+	updates     *stream.Broker
+	distributor task.Distributor
+	mfaCipher   *mfa.Cipher
+	// mfaTokenMaker makes the tokens of logins waiting for their second factor,
+	// its key differs from the one of access tokens so that neither is accepted for the other
+	mfaTokenMaker     token.Maker
+	passwordHasher    util.PasswordHasher
+	dummyPasswordHash string
+	passwordPolicy    *passwordpolicy.Policy
+	webAuthn          *webauthn.WebAuthn
 }
 
-func NewServer(config util.Config, store db.Store) (*Server, error) {
+func NewServer(config util.Config, store db.Store, updates *stream.Broker, distributor task.Distributor) (*Server, error) {
 	tokenMaker, err := token.NewPasetoMaker(config.TokenSymmetricKey)
 	if err != nil {
 		return nil, fmt.Errorf("cannot create token maker: %w", err)
 	}
+	if err := validRestrictions(config.UnverifiedRestrictions); err != nil {
+		return nil, err
+	}
+	mfaCipher, err := mfa.NewCipher(config.MFAEncryptionKey)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create mfa cipher: %w", err)
+	}
+	mfaTokenMaker, err := token.NewPasetoMaker(mfaTokenKey(config.TokenSymmetricKey))
+	if err != nil {
+		return nil, fmt.Errorf("cannot create mfa token maker: %w", err)
+	}
+	passwordHasher, err := util.NewPasswordHasher(config)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create password hasher: %w", err)
+	}
+	dummyPasswordHash, err := newDummyPasswordHash(passwordHasher)
+	if err != nil {
+		return nil, fmt.Errorf("cannot hash dummy password: %w", err)
+	}
+	passwordPolicy, err := passwordpolicy.New(config)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create password policy: %w", err)
+	}
+	webAuthn, err := newWebAuthn(config)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create webauthn relying party: %w", err)
+	}
 
 	server := &Server{
-		config:     config,
-		store:      store,
-		tokenMaker: tokenMaker,
+		config:            config,
+		store:             store,
+		tokenMaker:        tokenMaker,
+		updates:           updates,
+		distributor:       distributor,
+		mfaCipher:         mfaCipher,
+		mfaTokenMaker:     mfaTokenMaker,
+		passwordHasher:    passwordHasher,
+		dummyPasswordHash: dummyPasswordHash,
+		passwordPolicy:    passwordPolicy,
+		webAuthn:          webAuthn,
 	}
 	server.setupValidators()
-	server.setupRouter()
+	if err := server.setupRouter(); err != nil {
+		return nil, err
+	}
 
 	return server, nil
 }
@@ -39,12 +92,13 @@ func (server *Server) Start(address string) error {
 	return server.router.Run(address)
 }
 
-func errorResponse(err error) gin.H {
-	return gin.H{"error": err.Error()}
-}
-
-func (server *Server) setupRouter() {
+func (server *Server) setupRouter() error {
 	router := gin.Default()
+	// the client IP is only taken from X-Forwarded-For when the request comes through a trusted proxy,
+	// so that a client cannot choose the IP its logins are throttled by and its API keys are checked against
+	if err := router.SetTrustedProxies(server.config.TrustedProxies); err != nil {
+		return fmt.Errorf("cannot set trusted proxies: %w", err)
+	}
 
 	router.POST("/accounts", server.createAccount)
 	router.GET("/accounts/:id", server.getAccount)
@@ -54,14 +108,98 @@ func (server *Server) setupRouter() {
 	router.POST("/users", server.createUser)
 
 	router.POST("/users/login", server.loginUser)
+	router.POST("/users/login/mfa", server.loginUserMFA)
+	router.POST("/users/login/webauthn", server.beginWebAuthnLogin)
+	router.POST("/users/login/webauthn/finish", server.finishWebAuthnLogin)
+	router.GET("/verify_email", server.verifyEmail)
+	router.POST("/users/password_reset", server.requestPasswordReset)
+	router.POST("/users/password_reset/confirm", server.confirmPasswordReset)
+	router.POST("/oauth/token", server.createOAuthToken)
+	router.POST("/oauth/revoke", server.revokeOAuthToken)
+
+	authRoutes := router.Group("/").Use(server.authMiddleware())
+	authRoutes.GET("/users/me", server.getCurrentUser)
+	authRoutes.PATCH("/users/me", server.updateCurrentUser)
+	authRoutes.POST("/users/me/deactivate", server.deactivateCurrentUser)
+	authRoutes.GET("/users/me/sessions", server.listSessions)
+	authRoutes.DELETE("/users/me/sessions/:id", server.revokeSession)
+	authRoutes.POST("/users/me/sessions/revoke_others", server.revokeOtherSessions)
+	authRoutes.POST("/users/verify_email", server.resendVerifyEmail)
+	authRoutes.POST("/users/password", server.changePassword)
+	authRoutes.POST("/users/mfa/totp", server.enrollTOTP)
+	authRoutes.POST("/users/mfa/totp/confirm", server.confirmTOTP)
+	authRoutes.POST("/users/webauthn/credentials", server.beginWebAuthnRegistration)
+	authRoutes.POST("/users/webauthn/credentials/finish", server.finishWebAuthnRegistration)
+	authRoutes.GET("/users/webauthn/credentials", server.listWebAuthnCredentials)
+	authRoutes.DELETE("/users/webauthn/credentials/:id", server.deleteWebAuthnCredential)
+	authRoutes.POST("/api_keys", server.createAPIKey)
+	authRoutes.GET("/api_keys", server.listAPIKeys)
+	authRoutes.DELETE("/api_keys/:id", server.revokeAPIKey)
+	authRoutes.GET("/oauth/authorize", server.getAuthorization)
+	authRoutes.POST("/oauth/authorize", server.decideAuthorization)
+	authRoutes.DELETE("/oauth/grants/:client_id", server.revokeOAuthGrant)
+	authRoutes.POST("/transfers", server.restrictUnverified(RestrictTransfers), server.createTransfer)
+	authRoutes.POST("/transfers/:id/approve", server.approveTransfer)
+	authRoutes.POST("/transfers/:id/reject", server.rejectTransfer)
+	authRoutes.POST("/transfers/batch", server.restrictUnverified(RestrictTransfers), server.createTransferBatch)
+	authRoutes.GET("/transfers/batch/:id", server.getTransferBatch)
+	authRoutes.POST("/standing_orders", server.restrictUnverified(RestrictTransfers), server.createStandingOrder)
+	authRoutes.GET("/standing_orders/:id", server.getStandingOrder)
+	authRoutes.GET("/standing_orders/:id/runs", server.listStandingOrderRuns)
+	authRoutes.POST("/standing_orders/:id/pause", server.pauseStandingOrder)
+	authRoutes.POST("/standing_orders/:id/resume", server.resumeStandingOrder)
+	authRoutes.POST("/standing_orders/:id/cancel", server.cancelStandingOrder)
+	authRoutes.POST("/accounts/:id/approvers", server.addAccountApprover)
+	authRoutes.GET("/accounts/:id/approvers", server.listAccountApprovers)
+	authRoutes.DELETE("/accounts/:id/approvers/:username", server.removeAccountApprover)
+	authRoutes.GET("/accounts/:id/entries", server.listEntries)
+	authRoutes.GET("/accounts/stream", server.streamAccountUpdates)
+	authRoutes.POST("/holds", server.restrictUnverified(RestrictHolds), server.createHold)
+	authRoutes.GET("/holds/:id", server.getHold)
+	authRoutes.POST("/holds/:id/capture", server.captureHold)
+	authRoutes.POST("/holds/:id/release", server.releaseHold)
+	authRoutes.POST("/webhooks", server.restrictUnverified(RestrictWebhooks), server.createWebhookSubscription)
+	authRoutes.GET("/webhooks", server.listWebhookSubscriptions)
+	authRoutes.GET("/webhooks/:id", server.getWebhookSubscription)
+	authRoutes.PUT("/webhooks/:id", server.updateWebhookSubscription)
+	authRoutes.DELETE("/webhooks/:id", server.deleteWebhookSubscription)
+	authRoutes.POST("/webhooks/:id/enable", server.enableWebhookSubscription)
+	authRoutes.GET("/webhooks/:id/deliveries", server.listWebhookDeliveries)
 
-	router.POST("/transfers", server.createTransfer)
+	adminRoutes := router.Group("/admin").Use(server.authMiddleware(), server.adminMiddleware())
+	adminRoutes.GET("/transfer_limits", server.listTransferLimits)
+	adminRoutes.PUT("/transfer_limits/:tier/:currency", server.setTransferLimit)
+	adminRoutes.DELETE("/transfer_limits/:tier/:currency", server.deleteTransferLimit)
+	adminRoutes.GET("/fee_schedules", server.listFeeSchedules)
+	adminRoutes.PUT("/fee_schedules/:tier/:currency", server.setFeeSchedule)
+	adminRoutes.DELETE("/fee_schedules/:tier/:currency", server.deleteFeeSchedule)
+	adminRoutes.GET("/revenue_accounts", server.listRevenueAccounts)
+	adminRoutes.PUT("/revenue_accounts/:currency", server.setRevenueAccount)
+	adminRoutes.PUT("/users/:username/tier", server.updateUserTier)
+	adminRoutes.POST("/users/:username/unlock", server.unlockUser)
+	adminRoutes.GET("/users/:username/login_attempts", server.listLoginAttempts)
+	adminRoutes.POST("/users/:username/api_keys", server.createUserAPIKey)
+	adminRoutes.GET("/users/:username/api_keys", server.listUserAPIKeys)
+	adminRoutes.DELETE("/api_keys/:id", server.revokeUserAPIKey)
+	adminRoutes.GET("/users/:username/sessions", server.listUserSessions)
+	adminRoutes.DELETE("/users/:username/sessions", server.revokeUserSessions)
+	adminRoutes.DELETE("/sessions/:id", server.revokeUserSession)
+	adminRoutes.POST("/oauth_clients", server.createOAuthClient)
+	adminRoutes.GET("/oauth_clients", server.listOAuthClients)
+	adminRoutes.GET("/events", server.listOutboxEvents)
+	adminRoutes.POST("/events/replay", server.replayOutboxEvents)
+	adminRoutes.GET("/metrics", gin.WrapH(expvar.Handler()))
 
 	server.router = router
+	return nil
 }
 
 func (server *Server) setupValidators() {
 	if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
+		v.RegisterTagNameFunc(jsonFieldName)
 		v.RegisterValidation("currency", validCurrency)
+		v.RegisterValidation("tier", validTier)
+		v.RegisterValidation("webhook_event", validWebhookEvent)
+		v.RegisterValidation("scope", validScope)
 	}
 }
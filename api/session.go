@@ -0,0 +1,267 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgtype"
+	db "github.com/roman-adamchik/simplebank/db/sqlc"
+	"github.com/roman-adamchik/simplebank/token"
+)
+
+const (
+	// sessionTouchInterval is how often the last use of a session is written down at most
+	sessionTouchInterval = time.Minute
+	// maxUserAgentLength keeps clients from storing arbitrary amounts of data in their sessions
+	maxUserAgentLength = 512
+)
+
+var (
+	errSessionRevoked = errors.New("session is signed out")
+	errNoSession      = errors.New("the access token does not belong to a session")
+)
+
+// createSession starts a session for a user who just logged in and creates its access token
+func (server *Server) createSession(ctx *gin.Context, user db.User) (string, db.Session, error) {
+	now := time.Now()
+	userAgent := ctx.Request.UserAgent()
+	if len(userAgent) > maxUserAgentLength {
+		userAgent = userAgent[:maxUserAgentLength]
+	}
+
+	session, err := server.store.CreateSession(ctx, db.CreateSessionParams{
+		Username:   user.Username,
+		UserAgent:  userAgent,
+		ClientIp:   ctx.ClientIP(),
+		ExpiresAt:  pgtype.Timestamptz{Time: now.Add(server.config.AccessTokenDuration), Valid: true},
+		LastUsedAt: pgtype.Timestamptz{Time: now, Valid: true},
+	})
+	if err != nil {
+		return "", session, err
+	}
+
+	claims := server.accessTokenClaims(user)
+	claims.SessionID = session.ID
+	accessToken, err := server.tokenMaker.CreateToken(claims, server.config.AccessTokenDuration)
+	return accessToken, session, err
+}
+
+// authorizeSession checks that the session of an access token is not signed out and writes down its use.
+// It responds with an error and returns false if the token cannot be used
+func (server *Server) authorizeSession(ctx *gin.Context, payload *token.Payload) bool {
+	session, err := server.store.GetSession(ctx, payload.SessionID)
+	if err != nil {
+		if errors.Is(err, db.ErrRecordNotFound) {
+			abortWithError(ctx, http.StatusUnauthorized, token.ErrInvalidToken)
+			return false
+		}
+		abortWithError(ctx, http.StatusInternalServerError, err)
+		return false
+	}
+	if session.Username != payload.Username {
+		abortWithError(ctx, http.StatusUnauthorized, token.ErrInvalidToken)
+		return false
+	}
+	if session.RevokedAt.Valid {
+		abortWithError(ctx, http.StatusUnauthorized, errSessionRevoked)
+		return false
+	}
+
+	// the session was just read, so most requests skip the write
+	now := time.Now()
+	usedBefore := now.Add(-sessionTouchInterval)
+	if session.LastUsedAt.Time.Before(usedBefore) {
+		err = server.store.TouchSession(ctx, db.TouchSessionParams{
+			LastUsedAt: pgtype.Timestamptz{Time: now, Valid: true},
+			ID:         session.ID,
+			UsedBefore: pgtype.Timestamptz{Time: usedBefore, Valid: true},
+		})
+		if err != nil {
+			log.Printf("cannot touch session %d: %v", session.ID, err)
+		}
+	}
+
+	return true
+}
+
+type sessionResponse struct {
+	ID         int64              `json:"id"`
+	Username   string             `json:"username"`
+	UserAgent  string             `json:"user_agent"`
+	ClientIP   string             `json:"client_ip"`
+	ExpiresAt  pgtype.Timestamptz `json:"expires_at"`
+	LastUsedAt pgtype.Timestamptz `json:"last_used_at"`
+	RevokedAt  pgtype.Timestamptz `json:"revoked_at"`
+	CreatedAt  pgtype.Timestamptz `json:"created_at"`
+	// Current is set on the session the request was made with
+	Current bool `json:"current"`
+}
+
+func newSessionResponse(session db.Session, currentID int64) sessionResponse {
+	return sessionResponse{
+		ID:         session.ID,
+		Username:   session.Username,
+		UserAgent:  session.UserAgent,
+		ClientIP:   session.ClientIp,
+		ExpiresAt:  session.ExpiresAt,
+		LastUsedAt: session.LastUsedAt,
+		RevokedAt:  session.RevokedAt,
+		CreatedAt:  session.CreatedAt,
+		Current:    session.ID == currentID,
+	}
+}
+
+// listSessions lists the active sessions of the authenticated user, the most recently used first
+func (server *Server) listSessions(ctx *gin.Context) {
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	server.renderSessions(ctx, authPayload.Username, authPayload.SessionID)
+}
+
+type userSessionsURI struct {
+	Username string `uri:"username" binding:"required,alphanum"`
+}
+
+// listUserSessions lets an admin list the active sessions of a user
+func (server *Server) listUserSessions(ctx *gin.Context) {
+	var uri userSessionsURI
+
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		respondError(ctx, http.StatusBadRequest, err)
+		return
+	}
+
+	server.renderSessions(ctx, uri.Username, 0)
+}
+
+func (server *Server) renderSessions(ctx *gin.Context, username string, currentID int64) {
+	sessions, err := server.store.ListActiveSessions(ctx, db.ListActiveSessionsParams{
+		Username: username,
+		Now:      pgtype.Timestamptz{Time: time.Now(), Valid: true},
+	})
+	if err != nil {
+		respondError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	rsp := make([]sessionResponse, len(sessions))
+	for i, session := range sessions {
+		rsp[i] = newSessionResponse(session, currentID)
+	}
+	ctx.JSON(http.StatusOK, rsp)
+}
+
+type sessionURI struct {
+	ID int64 `uri:"id" binding:"required,min=1"`
+}
+
+// revokeSession signs out a session of the authenticated user, such as a lost device
+func (server *Server) revokeSession(ctx *gin.Context) {
+	session, ok := server.getSession(ctx)
+	if !ok {
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	if session.Username != authPayload.Username {
+		err := fmt.Errorf("session [%d] doesn't belong to the authenticated user", session.ID)
+		respondError(ctx, http.StatusForbidden, err)
+		return
+	}
+
+	server.markSessionRevoked(ctx, session)
+}
+
+// revokeUserSession lets an admin sign out any session
+func (server *Server) revokeUserSession(ctx *gin.Context) {
+	session, ok := server.getSession(ctx)
+	if !ok {
+		return
+	}
+
+	server.markSessionRevoked(ctx, session)
+}
+
+func (server *Server) getSession(ctx *gin.Context) (db.Session, bool) {
+	var uri sessionURI
+
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		respondError(ctx, http.StatusBadRequest, err)
+		return db.Session{}, false
+	}
+
+	session, err := server.store.GetSession(ctx, uri.ID)
+	if err != nil {
+		if errors.Is(err, db.ErrRecordNotFound) {
+			respondError(ctx, http.StatusNotFound, err)
+			return session, false
+		}
+		respondError(ctx, http.StatusInternalServerError, err)
+		return session, false
+	}
+
+	return session, true
+}
+
+func (server *Server) markSessionRevoked(ctx *gin.Context, session db.Session) {
+	session, err := server.store.RevokeSession(ctx, db.RevokeSessionParams{
+		RevokedAt: pgtype.Timestamptz{Time: time.Now(), Valid: true},
+		ID:        session.ID,
+	})
+	if err != nil {
+		if errors.Is(err, db.ErrRecordNotFound) {
+			respondError(ctx, http.StatusConflict, errSessionRevoked)
+			return
+		}
+		respondError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, newSessionResponse(session, 0))
+}
+
+// revokeOtherSessions signs out every session of the authenticated user but the one of the request
+func (server *Server) revokeOtherSessions(ctx *gin.Context) {
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	if authPayload.SessionID == 0 {
+		respondError(ctx, http.StatusBadRequest, errNoSession)
+		return
+	}
+
+	revoked, err := server.store.RevokeOtherSessions(ctx, db.RevokeOtherSessionsParams{
+		RevokedAt: pgtype.Timestamptz{Time: time.Now(), Valid: true},
+		Username:  authPayload.Username,
+		KeepID:    authPayload.SessionID,
+	})
+	if err != nil {
+		respondError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"revoked": revoked})
+}
+
+// revokeUserSessions lets an admin sign out every session of a user, such as after an account takeover
+func (server *Server) revokeUserSessions(ctx *gin.Context) {
+	var uri userSessionsURI
+
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		respondError(ctx, http.StatusBadRequest, err)
+		return
+	}
+
+	revoked, err := server.store.RevokeUserSessions(ctx, db.RevokeUserSessionsParams{
+		RevokedAt: pgtype.Timestamptz{Time: time.Now(), Valid: true},
+		Username:  uri.Username,
+	})
+	if err != nil {
+		respondError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"revoked": revoked})
+}
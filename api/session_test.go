@@ -0,0 +1,554 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	mockdb "github.com/roman-adamchik/simplebank/db/mock"
+	db "github.com/roman-adamchik/simplebank/db/sqlc"
+	"github.com/roman-adamchik/simplebank/token"
+	"github.com/roman-adamchik/simplebank/util"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func randomSession(username string) db.Session {
+	now := time.Now()
+	return db.Session{
+		ID:         util.RandomInt(1, 1000),
+		Username:   username,
+		UserAgent:  "Mozilla/5.0",
+		ClientIp:   "198.51.100.7",
+		ExpiresAt:  pgtype.Timestamptz{Time: now.Add(time.Minute), Valid: true},
+		LastUsedAt: pgtype.Timestamptz{Time: now, Valid: true},
+		CreatedAt:  pgtype.Timestamptz{Time: now, Valid: true},
+	}
+}
+
+// expectCreateSession expects a login to start a session for a user
+func expectCreateSession(t *testing.T, store *mockdb.MockStore, username string) {
+	store.EXPECT().
+		CreateSession(gomock.Any(), gomock.Any()).
+		Times(1).
+		DoAndReturn(func(_ any, arg db.CreateSessionParams) (db.Session, error) {
+			require.Equal(t, username, arg.Username)
+			require.NotEmpty(t, arg.ClientIp)
+			require.True(t, arg.ExpiresAt.Time.After(arg.LastUsedAt.Time))
+			session := randomSession(username)
+			session.UserAgent = arg.UserAgent
+			session.ClientIp = arg.ClientIp
+			return session, nil
+		})
+}
+
+// addSessionAuthorization adds an access token of a session to a request, like the ones logins create
+func addSessionAuthorization(t *testing.T, request *http.Request, tokenMaker token.Maker, session db.Session) {
+	t.Helper()
+
+	accessToken, err := tokenMaker.CreateToken(token.Claims{
+		Username:  session.Username,
+		Issuer:    defaultTokenIssuer,
+		Audience:  []string{defaultTokenAudience},
+		SessionID: session.ID,
+	}, time.Minute)
+	require.NoError(t, err)
+
+	request.Header.Set(authorizationHeaderKey, fmt.Sprintf("%s %s", authorizationTypeBearer, accessToken))
+}
+
+func TestSessionAuthMiddleware(t *testing.T) {
+	user, _ := getRandomUser(t)
+	session := randomSession(user.Username)
+
+	expectSession := func(store *mockdb.MockStore, session db.Session) {
+		store.EXPECT().
+			GetSession(gomock.Any(), gomock.Eq(session.ID)).
+			Times(1).
+			Return(session, nil)
+	}
+	expectUser := func(store *mockdb.MockStore, times int) {
+		store.EXPECT().
+			GetUser(gomock.Any(), gomock.Eq(user.Username)).
+			Times(times).
+			Return(user, nil)
+	}
+
+	testCases := []struct {
+		name          string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			buildStubs: func(store *mockdb.MockStore) {
+				expectSession(store, session)
+				// the session was used recently, so its last use is not written down again
+				store.EXPECT().
+					TouchSession(gomock.Any(), gomock.Any()).
+					Times(0)
+				expectUser(store, 1)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name: "Touch",
+			buildStubs: func(store *mockdb.MockStore) {
+				idle := session
+				idle.LastUsedAt = pgtype.Timestamptz{Time: time.Now().Add(-time.Hour), Valid: true}
+				expectSession(store, idle)
+				store.EXPECT().
+					TouchSession(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ any, arg db.TouchSessionParams) error {
+						require.Equal(t, session.ID, arg.ID)
+						require.WithinDuration(t, time.Now(), arg.LastUsedAt.Time, time.Second)
+						require.Equal(t, arg.LastUsedAt.Time.Add(-sessionTouchInterval), arg.UsedBefore.Time)
+						return nil
+					})
+				expectUser(store, 1)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name: "TouchError",
+			buildStubs: func(store *mockdb.MockStore) {
+				idle := session
+				idle.LastUsedAt = pgtype.Timestamptz{Time: time.Now().Add(-time.Hour), Valid: true}
+				expectSession(store, idle)
+				store.EXPECT().
+					TouchSession(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(pgx.ErrTxClosed)
+				expectUser(store, 1)
+			},
+			// the last use is only informative, so the request goes on
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name: "Revoked",
+			buildStubs: func(store *mockdb.MockStore) {
+				revoked := session
+				revoked.RevokedAt = pgtype.Timestamptz{Time: time.Now(), Valid: true}
+				expectSession(store, revoked)
+				expectUser(store, 0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+				requireProblem(t, recorder, "session_revoked")
+			},
+		},
+		{
+			name: "OtherUser",
+			buildStubs: func(store *mockdb.MockStore) {
+				other := session
+				other.Username = util.RandomOwner()
+				expectSession(store, other)
+				expectUser(store, 0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "NotFound",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetSession(gomock.Any(), gomock.Eq(session.ID)).
+					Times(1).
+					Return(db.Session{}, db.ErrRecordNotFound)
+				expectUser(store, 0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "InternalError",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetSession(gomock.Any(), gomock.Eq(session.ID)).
+					Times(1).
+					Return(db.Session{}, pgx.ErrTxClosed)
+				expectUser(store, 0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			request, err := http.NewRequest(http.MethodGet, "/users/me", nil)
+			require.NoError(t, err)
+			addSessionAuthorization(t, request, server.tokenMaker, session)
+
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestListSessionsAPI(t *testing.T) {
+	user, _ := getRandomUser(t)
+	current := randomSession(user.Username)
+	other := randomSession(user.Username)
+	other.ID = current.ID + 1
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().
+		GetSession(gomock.Any(), gomock.Eq(current.ID)).
+		Times(1).
+		Return(current, nil)
+	store.EXPECT().
+		ListActiveSessions(gomock.Any(), gomock.Any()).
+		Times(1).
+		DoAndReturn(func(_ any, arg db.ListActiveSessionsParams) ([]db.Session, error) {
+			require.Equal(t, user.Username, arg.Username)
+			require.WithinDuration(t, time.Now(), arg.Now.Time, time.Second)
+			return []db.Session{other, current}, nil
+		})
+
+	server := newTestServer(t, store)
+	recorder := httptest.NewRecorder()
+
+	request, err := http.NewRequest(http.MethodGet, "/users/me/sessions", nil)
+	require.NoError(t, err)
+	addSessionAuthorization(t, request, server.tokenMaker, current)
+
+	server.router.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusOK, recorder.Code)
+
+	var rsp []sessionResponse
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &rsp))
+	require.Len(t, rsp, 2)
+	require.Equal(t, other.ID, rsp[0].ID)
+	require.False(t, rsp[0].Current)
+	require.Equal(t, current.ID, rsp[1].ID)
+	require.True(t, rsp[1].Current)
+	require.Equal(t, current.UserAgent, rsp[1].UserAgent)
+	require.Equal(t, current.ClientIp, rsp[1].ClientIP)
+}
+
+func TestRevokeSessionAPI(t *testing.T) {
+	user, _ := getRandomUser(t)
+	session := randomSession(user.Username)
+
+	testCases := []struct {
+		name          string
+		sessionID     int64
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:      "OK",
+			sessionID: session.ID,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetSession(gomock.Any(), gomock.Eq(session.ID)).
+					Times(1).
+					Return(session, nil)
+				store.EXPECT().
+					RevokeSession(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ any, arg db.RevokeSessionParams) (db.Session, error) {
+						require.Equal(t, session.ID, arg.ID)
+						revoked := session
+						revoked.RevokedAt = arg.RevokedAt
+						return revoked, nil
+					})
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var rsp sessionResponse
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &rsp))
+				require.True(t, rsp.RevokedAt.Valid)
+			},
+		},
+		{
+			name:      "OtherUser",
+			sessionID: session.ID,
+			buildStubs: func(store *mockdb.MockStore) {
+				other := session
+				other.Username = util.RandomOwner()
+				store.EXPECT().
+					GetSession(gomock.Any(), gomock.Eq(session.ID)).
+					Times(1).
+					Return(other, nil)
+				store.EXPECT().
+					RevokeSession(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name:      "AlreadyRevoked",
+			sessionID: session.ID,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetSession(gomock.Any(), gomock.Eq(session.ID)).
+					Times(1).
+					Return(session, nil)
+				store.EXPECT().
+					RevokeSession(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.Session{}, db.ErrRecordNotFound)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusConflict, recorder.Code)
+			},
+		},
+		{
+			name:      "NotFound",
+			sessionID: session.ID,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetSession(gomock.Any(), gomock.Eq(session.ID)).
+					Times(1).
+					Return(db.Session{}, db.ErrRecordNotFound)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name:      "InvalidID",
+			sessionID: 0,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetSession(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			url := fmt.Sprintf("/users/me/sessions/%d", tc.sessionID)
+			request, err := http.NewRequest(http.MethodDelete, url, nil)
+			require.NoError(t, err)
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
+
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestRevokeOtherSessionsAPI(t *testing.T) {
+	user, _ := getRandomUser(t)
+	current := randomSession(user.Username)
+
+	testCases := []struct {
+		name          string
+		setupAuth     func(t *testing.T, request *http.Request, tokenMaker token.Maker)
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addSessionAuthorization(t, request, tokenMaker, current)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetSession(gomock.Any(), gomock.Eq(current.ID)).
+					Times(1).
+					Return(current, nil)
+				store.EXPECT().
+					RevokeOtherSessions(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ any, arg db.RevokeOtherSessionsParams) (int64, error) {
+						require.Equal(t, user.Username, arg.Username)
+						require.Equal(t, current.ID, arg.KeepID)
+						return 2, nil
+					})
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				require.JSONEq(t, `{"revoked":2}`, recorder.Body.String())
+			},
+		},
+		{
+			name: "NoSession",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addSessionlessAuthorization(t, request, tokenMaker, user.Username)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					RevokeOtherSessions(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+				requireProblem(t, recorder, "no_session")
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			request, err := http.NewRequest(http.MethodPost, "/users/me/sessions/revoke_others", nil)
+			require.NoError(t, err)
+			tc.setupAuth(t, request, server.tokenMaker)
+
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestAdminSessionsAPI(t *testing.T) {
+	admin, _ := getRandomUser(t)
+	admin.Role = util.AdminRole
+	user, _ := getRandomUser(t)
+	session := randomSession(user.Username)
+
+	testCases := []struct {
+		name          string
+		method        string
+		url           string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:   "List",
+			method: http.MethodGet,
+			url:    "/admin/users/" + user.Username + "/sessions",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					ListActiveSessions(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ any, arg db.ListActiveSessionsParams) ([]db.Session, error) {
+						require.Equal(t, user.Username, arg.Username)
+						return []db.Session{session}, nil
+					})
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var rsp []sessionResponse
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &rsp))
+				require.Len(t, rsp, 1)
+				require.Equal(t, session.ID, rsp[0].ID)
+				require.False(t, rsp[0].Current)
+			},
+		},
+		{
+			name:   "RevokeOne",
+			method: http.MethodDelete,
+			url:    fmt.Sprintf("/admin/sessions/%d", session.ID),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetSession(gomock.Any(), gomock.Eq(session.ID)).
+					Times(1).
+					Return(session, nil)
+				store.EXPECT().
+					RevokeSession(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(session, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name:   "RevokeAll",
+			method: http.MethodDelete,
+			url:    "/admin/users/" + user.Username + "/sessions",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					RevokeUserSessions(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ any, arg db.RevokeUserSessionsParams) (int64, error) {
+						require.Equal(t, user.Username, arg.Username)
+						return 3, nil
+					})
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				require.JSONEq(t, `{"revoked":3}`, recorder.Body.String())
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			store.EXPECT().
+				GetUser(gomock.Any(), gomock.Eq(admin.Username)).
+				Times(1).
+				Return(admin, nil)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			request, err := http.NewRequest(tc.method, tc.url, nil)
+			require.NoError(t, err)
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, admin.Username, time.Minute)
+
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
@@ -0,0 +1,294 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgtype"
+	db "github.com/roman-adamchik/simplebank/db/sqlc"
+	"github.com/roman-adamchik/simplebank/token"
+)
+
+type createStandingOrderRequest struct {
+	FromAccountID  int64      `json:"from_account_id" binding:"required,min=1"`
+	ToAccountID    int64      `json:"to_account_id" binding:"required,min=1"`
+	Amount         int64      `json:"amount" binding:"required,gt=0"`
+	Currency       string     `json:"currency" binding:"required,currency"`
+	Frequency      string     `json:"frequency" binding:"required,oneof=weekly monthly custom"`
+	IntervalCount  int32      `json:"interval_count" binding:"omitempty,min=1"`
+	CronExpression string     `json:"cron_expression" binding:"required_if=Frequency custom"`
+	StartAt        time.Time  `json:"start_at" binding:"required"`
+	EndAt          *time.Time `json:"end_at"`
+	MaxOccurrences int32      `json:"max_occurrences" binding:"omitempty,min=1"`
+}
+
+func (server *Server) createStandingOrder(ctx *gin.Context) {
+	var req createStandingOrderRequest
+
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		respondError(ctx, http.StatusBadRequest, err)
+		return
+	}
+
+	order := db.StandingOrder{
+		Frequency:     req.Frequency,
+		IntervalCount: 1,
+		StartAt:       pgtype.Timestamptz{Time: req.StartAt, Valid: true},
+	}
+	if req.IntervalCount > 0 {
+		order.IntervalCount = req.IntervalCount
+	}
+	if req.Frequency == db.FrequencyCustom {
+		order.CronExpression = req.CronExpression
+	}
+	if req.EndAt != nil {
+		if !req.EndAt.After(req.StartAt) {
+			err := fmt.Errorf("end_at must be after start_at")
+			respondError(ctx, http.StatusBadRequest, err)
+			return
+		}
+		order.EndAt = pgtype.Timestamptz{Time: *req.EndAt, Valid: true}
+	}
+	if req.MaxOccurrences > 0 {
+		order.MaxOccurrences = pgtype.Int4{Int32: req.MaxOccurrences, Valid: true}
+	}
+
+	// occurrences before the standing order is created are skipped, as they are when it is resumed
+	after := req.StartAt.Add(-time.Nanosecond)
+	if now := time.Now(); now.After(after) {
+		after = now
+	}
+	nextRunAt, err := order.NextRunAfter(after)
+	if err != nil {
+		respondError(ctx, http.StatusBadRequest, err)
+		return
+	}
+	if !nextRunAt.Valid {
+		err := fmt.Errorf("standing order has no occurrences left between now and end_at")
+		respondError(ctx, http.StatusBadRequest, err)
+		return
+	}
+
+	// a standing order can only debit an account of the authenticated user
+	fromAccount, ok := server.ownedAccount(ctx, req.FromAccountID)
+	if !ok || !checkTransferAccount(ctx, fromAccount, req.Currency) {
+		return
+	}
+	if !server.validAccount(ctx, req.ToAccountID, req.Currency) {
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	arg := db.CreateStandingOrderParams{
+		FromAccountID:  req.FromAccountID,
+		ToAccountID:    req.ToAccountID,
+		Amount:         req.Amount,
+		Frequency:      order.Frequency,
+		IntervalCount:  order.IntervalCount,
+		CronExpression: order.CronExpression,
+		StartAt:        order.StartAt,
+		EndAt:          order.EndAt,
+		MaxOccurrences: order.MaxOccurrences,
+		NextRunAt:      nextRunAt,
+		Creator:        authPayload.Username,
+	}
+
+	order, err = server.store.CreateStandingOrder(ctx, arg)
+	if err != nil {
+		respondError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, order)
+}
+
+type standingOrderRequest struct {
+	ID int64 `uri:"id" binding:"required,min=1"`
+}
+
+func (server *Server) getStandingOrder(ctx *gin.Context) {
+	var req standingOrderRequest
+
+	if err := ctx.ShouldBindUri(&req); err != nil {
+		respondError(ctx, http.StatusBadRequest, err)
+		return
+	}
+
+	order, ok := server.getStandingOrderByID(ctx, req.ID)
+	if !ok {
+		return
+	}
+
+	ctx.JSON(http.StatusOK, order)
+}
+
+type listStandingOrderRunsRequest struct {
+	PageID   int32 `form:"page_id" binding:"required,min=1"`
+	PageSize int32 `form:"page_size" binding:"required,min=5,max=10"`
+}
+
+func (server *Server) listStandingOrderRuns(ctx *gin.Context) {
+	var uri standingOrderRequest
+	var req listStandingOrderRunsRequest
+
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		respondError(ctx, http.StatusBadRequest, err)
+		return
+	}
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		respondError(ctx, http.StatusBadRequest, err)
+		return
+	}
+
+	if _, ok := server.getStandingOrderByID(ctx, uri.ID); !ok {
+		return
+	}
+
+	arg := db.ListStandingOrderRunsParams{
+		StandingOrderID: uri.ID,
+		Limit:           req.PageSize,
+		Offset:          (req.PageID - 1) * req.PageSize,
+	}
+
+	runs, err := server.store.ListStandingOrderRuns(ctx, arg)
+	if err != nil {
+		respondError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, runs)
+}
+
+func (server *Server) pauseStandingOrder(ctx *gin.Context) {
+	var req standingOrderRequest
+
+	if err := ctx.ShouldBindUri(&req); err != nil {
+		respondError(ctx, http.StatusBadRequest, err)
+		return
+	}
+
+	order, ok := server.getStandingOrderByID(ctx, req.ID)
+	if !ok {
+		return
+	}
+	if order.Status != db.StandingOrderActive {
+		err := fmt.Errorf("standing order [%d] cannot be paused: status is %s", order.ID, order.Status)
+		respondError(ctx, http.StatusConflict, err)
+		return
+	}
+
+	order, err := server.store.PauseStandingOrder(ctx, req.ID)
+	if err != nil {
+		if errors.Is(err, db.ErrRecordNotFound) {
+			err = fmt.Errorf("standing order [%d] changed its status concurrently", req.ID)
+			respondError(ctx, http.StatusConflict, err)
+			return
+		}
+		respondError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, order)
+}
+
+func (server *Server) resumeStandingOrder(ctx *gin.Context) {
+	var req standingOrderRequest
+
+	if err := ctx.ShouldBindUri(&req); err != nil {
+		respondError(ctx, http.StatusBadRequest, err)
+		return
+	}
+
+	order, ok := server.getStandingOrderByID(ctx, req.ID)
+	if !ok {
+		return
+	}
+	if order.Status != db.StandingOrderPaused {
+		err := fmt.Errorf("standing order [%d] cannot be resumed: status is %s", order.ID, order.Status)
+		respondError(ctx, http.StatusConflict, err)
+		return
+	}
+
+	// occurrences missed while the standing order was paused are skipped
+	nextRunAt, err := order.NextRunAfter(time.Now())
+	if err != nil {
+		respondError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+	if !nextRunAt.Valid {
+		err := fmt.Errorf("standing order [%d] has no occurrences left", order.ID)
+		respondError(ctx, http.StatusConflict, err)
+		return
+	}
+
+	order, err = server.store.ResumeStandingOrder(ctx, db.ResumeStandingOrderParams{
+		ID:        req.ID,
+		NextRunAt: nextRunAt,
+	})
+	if err != nil {
+		if errors.Is(err, db.ErrRecordNotFound) {
+			err = fmt.Errorf("standing order [%d] changed its status concurrently", req.ID)
+			respondError(ctx, http.StatusConflict, err)
+			return
+		}
+		respondError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, order)
+}
+
+func (server *Server) cancelStandingOrder(ctx *gin.Context) {
+	var req standingOrderRequest
+
+	if err := ctx.ShouldBindUri(&req); err != nil {
+		respondError(ctx, http.StatusBadRequest, err)
+		return
+	}
+
+	order, ok := server.getStandingOrderByID(ctx, req.ID)
+	if !ok {
+		return
+	}
+	if order.Status != db.StandingOrderActive && order.Status != db.StandingOrderPaused {
+		err := fmt.Errorf("standing order [%d] cannot be cancelled: status is %s", order.ID, order.Status)
+		respondError(ctx, http.StatusConflict, err)
+		return
+	}
+
+	order, err := server.store.CancelStandingOrder(ctx, req.ID)
+	if err != nil {
+		if errors.Is(err, db.ErrRecordNotFound) {
+			err = fmt.Errorf("standing order [%d] changed its status concurrently", req.ID)
+			respondError(ctx, http.StatusConflict, err)
+			return
+		}
+		respondError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, order)
+}
+
+// getStandingOrderByID loads a standing order debiting an account of the authenticated user
+func (server *Server) getStandingOrderByID(ctx *gin.Context, id int64) (db.StandingOrder, bool) {
+	order, err := server.store.GetStandingOrder(ctx, id)
+	if err != nil {
+		if errors.Is(err, db.ErrRecordNotFound) {
+			respondError(ctx, http.StatusNotFound, err)
+			return order, false
+		}
+		respondError(ctx, http.StatusInternalServerError, err)
+		return order, false
+	}
+
+	if _, ok := server.ownedAccount(ctx, order.FromAccountID); !ok {
+		return order, false
+	}
+
+	return order, true
+}
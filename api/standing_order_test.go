@@ -0,0 +1,991 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	mockdb "github.com/roman-adamchik/simplebank/db/mock"
+	db "github.com/roman-adamchik/simplebank/db/sqlc"
+	"github.com/roman-adamchik/simplebank/util"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func getRandomStandingOrder(fromAccount, toAccount db.Account) db.StandingOrder {
+	startAt := time.Date(2030, time.January, 31, 9, 0, 0, 0, time.UTC)
+
+	return db.StandingOrder{
+		ID:            util.RandomInt(1, 1000),
+		FromAccountID: fromAccount.ID,
+		ToAccountID:   toAccount.ID,
+		Amount:        util.RandomInt(1, 100),
+		Frequency:     db.FrequencyMonthly,
+		IntervalCount: 1,
+		StartAt:       pgtype.Timestamptz{Time: startAt, Valid: true},
+		NextRunAt:     pgtype.Timestamptz{Time: startAt, Valid: true},
+		Status:        db.StandingOrderActive,
+	}
+}
+
+func TestCreateStandingOrderAPI(t *testing.T) {
+	user, _ := getRandomUser(t)
+	fromAccount := getRandomAccount()
+	fromAccount.Owner = user.Username
+	toAccount := getRandomAccount()
+	toAccount.Currency = fromAccount.Currency
+	order := getRandomStandingOrder(fromAccount, toAccount)
+	order.Creator = user.Username
+
+	testCases := []struct {
+		name          string
+		body          gin.H
+		noAuth        bool
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			body: gin.H{
+				"from_account_id": fromAccount.ID,
+				"to_account_id":   toAccount.ID,
+				"amount":          order.Amount,
+				"currency":        fromAccount.Currency,
+				"frequency":       db.FrequencyMonthly,
+				"start_at":        order.StartAt.Time.Format(time.RFC3339),
+				"max_occurrences": 12,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetAccount(gomock.Any(), gomock.Eq(fromAccount.ID)).
+					Times(1).
+					Return(fromAccount, nil)
+				store.EXPECT().
+					GetAccount(gomock.Any(), gomock.Eq(toAccount.ID)).
+					Times(1).
+					Return(toAccount, nil)
+				store.EXPECT().
+					CreateStandingOrder(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(ctx interface{}, arg db.CreateStandingOrderParams) (db.StandingOrder, error) {
+						require.Equal(t, fromAccount.ID, arg.FromAccountID)
+						require.Equal(t, toAccount.ID, arg.ToAccountID)
+						require.Equal(t, order.Amount, arg.Amount)
+						require.Equal(t, db.FrequencyMonthly, arg.Frequency)
+						require.Equal(t, int32(1), arg.IntervalCount)
+						require.Empty(t, arg.CronExpression)
+						require.True(t, order.StartAt.Time.Equal(arg.StartAt.Time))
+						require.False(t, arg.EndAt.Valid)
+						require.Equal(t, pgtype.Int4{Int32: 12, Valid: true}, arg.MaxOccurrences)
+						require.True(t, order.StartAt.Time.Equal(arg.NextRunAt.Time))
+						require.Equal(t, user.Username, arg.Creator)
+						return order, nil
+					})
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				requireBodyMatchStandingOrder(t, recorder.Body, order)
+			},
+		},
+		{
+			name: "OKCustomSchedule",
+			body: gin.H{
+				"from_account_id": fromAccount.ID,
+				"to_account_id":   toAccount.ID,
+				"amount":          order.Amount,
+				"currency":        fromAccount.Currency,
+				"frequency":       db.FrequencyCustom,
+				"cron_expression": "0 12 * * 1",
+				"start_at":        "2030-01-01T00:00:00Z",
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetAccount(gomock.Any(), gomock.Any()).
+					Times(2).
+					Return(fromAccount, nil)
+				store.EXPECT().
+					CreateStandingOrder(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(ctx interface{}, arg db.CreateStandingOrderParams) (db.StandingOrder, error) {
+						require.Equal(t, "0 12 * * 1", arg.CronExpression)
+						require.True(t, time.Date(2030, time.January, 7, 12, 0, 0, 0, time.UTC).Equal(arg.NextRunAt.Time))
+						return order, nil
+					})
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name: "OKStartInPast",
+			body: gin.H{
+				"from_account_id": fromAccount.ID,
+				"to_account_id":   toAccount.ID,
+				"amount":          order.Amount,
+				"currency":        fromAccount.Currency,
+				"frequency":       db.FrequencyWeekly,
+				"start_at":        "2020-01-06T09:00:00Z",
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetAccount(gomock.Any(), gomock.Any()).
+					Times(2).
+					Return(fromAccount, nil)
+				store.EXPECT().
+					CreateStandingOrder(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(ctx interface{}, arg db.CreateStandingOrderParams) (db.StandingOrder, error) {
+						// the occurrences missed since start_at are skipped instead of all running at once
+						now := time.Now()
+						require.True(t, arg.NextRunAt.Time.After(now))
+						require.False(t, arg.NextRunAt.Time.After(now.Add(7*24*time.Hour)))
+						require.Equal(t, time.Monday, arg.NextRunAt.Time.UTC().Weekday())
+						require.Equal(t, 9, arg.NextRunAt.Time.UTC().Hour())
+						return order, nil
+					})
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name: "BadRequestEndInPast",
+			body: gin.H{
+				"from_account_id": fromAccount.ID,
+				"to_account_id":   toAccount.ID,
+				"amount":          order.Amount,
+				"currency":        fromAccount.Currency,
+				"frequency":       db.FrequencyWeekly,
+				"start_at":        "2020-01-06T09:00:00Z",
+				"end_at":          "2020-12-31T00:00:00Z",
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					CreateStandingOrder(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "BadRequestInvalidFrequency",
+			body: gin.H{
+				"from_account_id": fromAccount.ID,
+				"to_account_id":   toAccount.ID,
+				"amount":          order.Amount,
+				"currency":        fromAccount.Currency,
+				"frequency":       "daily",
+				"start_at":        order.StartAt.Time.Format(time.RFC3339),
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					CreateStandingOrder(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "BadRequestMissingCronExpression",
+			body: gin.H{
+				"from_account_id": fromAccount.ID,
+				"to_account_id":   toAccount.ID,
+				"amount":          order.Amount,
+				"currency":        fromAccount.Currency,
+				"frequency":       db.FrequencyCustom,
+				"start_at":        order.StartAt.Time.Format(time.RFC3339),
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					CreateStandingOrder(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "BadRequestInvalidCronExpression",
+			body: gin.H{
+				"from_account_id": fromAccount.ID,
+				"to_account_id":   toAccount.ID,
+				"amount":          order.Amount,
+				"currency":        fromAccount.Currency,
+				"frequency":       db.FrequencyCustom,
+				"cron_expression": "61 * * * *",
+				"start_at":        order.StartAt.Time.Format(time.RFC3339),
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					CreateStandingOrder(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "BadRequestEndBeforeStart",
+			body: gin.H{
+				"from_account_id": fromAccount.ID,
+				"to_account_id":   toAccount.ID,
+				"amount":          order.Amount,
+				"currency":        fromAccount.Currency,
+				"frequency":       db.FrequencyWeekly,
+				"start_at":        order.StartAt.Time.Format(time.RFC3339),
+				"end_at":          order.StartAt.Time.Add(-time.Hour).Format(time.RFC3339),
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					CreateStandingOrder(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "BadRequestAmountZero",
+			body: gin.H{
+				"from_account_id": fromAccount.ID,
+				"to_account_id":   toAccount.ID,
+				"amount":          0,
+				"currency":        fromAccount.Currency,
+				"frequency":       db.FrequencyWeekly,
+				"start_at":        order.StartAt.Time.Format(time.RFC3339),
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					CreateStandingOrder(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "NotFoundToAccount",
+			body: gin.H{
+				"from_account_id": fromAccount.ID,
+				"to_account_id":   toAccount.ID,
+				"amount":          order.Amount,
+				"currency":        fromAccount.Currency,
+				"frequency":       db.FrequencyWeekly,
+				"start_at":        order.StartAt.Time.Format(time.RFC3339),
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetAccount(gomock.Any(), gomock.Eq(fromAccount.ID)).
+					Times(1).
+					Return(fromAccount, nil)
+				store.EXPECT().
+					GetAccount(gomock.Any(), gomock.Eq(toAccount.ID)).
+					Times(1).
+					Return(db.Account{}, db.ErrRecordNotFound)
+				store.EXPECT().
+					CreateStandingOrder(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name: "ForbiddenNotOwnerOfFromAccount",
+			body: gin.H{
+				"from_account_id": toAccount.ID,
+				"to_account_id":   fromAccount.ID,
+				"amount":          order.Amount,
+				"currency":        fromAccount.Currency,
+				"frequency":       db.FrequencyWeekly,
+				"start_at":        order.StartAt.Time.Format(time.RFC3339),
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetAccount(gomock.Any(), gomock.Eq(toAccount.ID)).
+					Times(1).
+					Return(toAccount, nil)
+				store.EXPECT().
+					CreateStandingOrder(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name: "NoAuthorization",
+			body: gin.H{
+				"from_account_id": fromAccount.ID,
+				"to_account_id":   toAccount.ID,
+				"amount":          order.Amount,
+				"currency":        fromAccount.Currency,
+				"frequency":       db.FrequencyWeekly,
+				"start_at":        order.StartAt.Time.Format(time.RFC3339),
+			},
+			noAuth: true,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetAccount(gomock.Any(), gomock.Any()).
+					Times(0)
+				store.EXPECT().
+					CreateStandingOrder(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "InternalServerError",
+			body: gin.H{
+				"from_account_id": fromAccount.ID,
+				"to_account_id":   toAccount.ID,
+				"amount":          order.Amount,
+				"currency":        fromAccount.Currency,
+				"frequency":       db.FrequencyWeekly,
+				"start_at":        order.StartAt.Time.Format(time.RFC3339),
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetAccount(gomock.Any(), gomock.Any()).
+					Times(2).
+					Return(fromAccount, nil)
+				store.EXPECT().
+					CreateStandingOrder(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.StandingOrder{}, pgx.ErrTxClosed)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			data, err := json.Marshal(tc.body)
+			require.NoError(t, err)
+
+			request, err := http.NewRequest(http.MethodPost, "/standing_orders", bytes.NewBuffer(data))
+			require.NoError(t, err)
+
+			if !tc.noAuth {
+				addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
+			}
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestPauseStandingOrderAPI(t *testing.T) {
+	user, _ := getRandomUser(t)
+	fromAccount := getRandomAccount()
+	fromAccount.Owner = user.Username
+	order := getRandomStandingOrder(fromAccount, getRandomAccount())
+	paused := order
+	paused.Status = db.StandingOrderPaused
+
+	testCases := []struct {
+		name          string
+		orderID       int64
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:    "OK",
+			orderID: order.ID,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetStandingOrder(gomock.Any(), gomock.Eq(order.ID)).
+					Times(1).
+					Return(order, nil)
+				store.EXPECT().
+					GetAccount(gomock.Any(), gomock.Eq(fromAccount.ID)).
+					Times(1).
+					Return(fromAccount, nil)
+				store.EXPECT().
+					PauseStandingOrder(gomock.Any(), gomock.Eq(order.ID)).
+					Times(1).
+					Return(paused, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				requireBodyMatchStandingOrder(t, recorder.Body, paused)
+			},
+		},
+		{
+			name:    "NotFound",
+			orderID: order.ID,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetStandingOrder(gomock.Any(), gomock.Eq(order.ID)).
+					Times(1).
+					Return(db.StandingOrder{}, db.ErrRecordNotFound)
+				store.EXPECT().
+					PauseStandingOrder(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name:    "ForbiddenNotOwner",
+			orderID: order.ID,
+			buildStubs: func(store *mockdb.MockStore) {
+				otherAccount := fromAccount
+				otherAccount.Owner = util.RandomOwner()
+				store.EXPECT().
+					GetStandingOrder(gomock.Any(), gomock.Eq(order.ID)).
+					Times(1).
+					Return(order, nil)
+				store.EXPECT().
+					GetAccount(gomock.Any(), gomock.Eq(fromAccount.ID)).
+					Times(1).
+					Return(otherAccount, nil)
+				store.EXPECT().
+					PauseStandingOrder(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name:    "ConflictAlreadyPaused",
+			orderID: order.ID,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetStandingOrder(gomock.Any(), gomock.Eq(order.ID)).
+					Times(1).
+					Return(paused, nil)
+				store.EXPECT().
+					GetAccount(gomock.Any(), gomock.Eq(fromAccount.ID)).
+					Times(1).
+					Return(fromAccount, nil)
+				store.EXPECT().
+					PauseStandingOrder(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusConflict, recorder.Code)
+			},
+		},
+		{
+			name:    "ConflictConcurrentUpdate",
+			orderID: order.ID,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetStandingOrder(gomock.Any(), gomock.Eq(order.ID)).
+					Times(1).
+					Return(order, nil)
+				store.EXPECT().
+					GetAccount(gomock.Any(), gomock.Eq(fromAccount.ID)).
+					Times(1).
+					Return(fromAccount, nil)
+				store.EXPECT().
+					PauseStandingOrder(gomock.Any(), gomock.Eq(order.ID)).
+					Times(1).
+					Return(db.StandingOrder{}, db.ErrRecordNotFound)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusConflict, recorder.Code)
+			},
+		},
+		{
+			name:    "BadRequestInvalidID",
+			orderID: 0,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetStandingOrder(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			url := fmt.Sprintf("/standing_orders/%d/pause", tc.orderID)
+			request, err := http.NewRequest(http.MethodPost, url, nil)
+			require.NoError(t, err)
+
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestCancelStandingOrderAPI(t *testing.T) {
+	user, _ := getRandomUser(t)
+	fromAccount := getRandomAccount()
+	fromAccount.Owner = user.Username
+	order := getRandomStandingOrder(fromAccount, getRandomAccount())
+	paused := order
+	paused.Status = db.StandingOrderPaused
+	cancelled := order
+	cancelled.Status = db.StandingOrderCancelled
+	cancelled.NextRunAt = pgtype.Timestamptz{}
+
+	testCases := []struct {
+		name          string
+		orderID       int64
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:    "OK",
+			orderID: order.ID,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetStandingOrder(gomock.Any(), gomock.Eq(order.ID)).
+					Times(1).
+					Return(order, nil)
+				store.EXPECT().
+					GetAccount(gomock.Any(), gomock.Eq(fromAccount.ID)).
+					Times(1).
+					Return(fromAccount, nil)
+				store.EXPECT().
+					CancelStandingOrder(gomock.Any(), gomock.Eq(order.ID)).
+					Times(1).
+					Return(cancelled, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				requireBodyMatchStandingOrder(t, recorder.Body, cancelled)
+			},
+		},
+		{
+			name:    "OKPaused",
+			orderID: order.ID,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetStandingOrder(gomock.Any(), gomock.Eq(order.ID)).
+					Times(1).
+					Return(paused, nil)
+				store.EXPECT().
+					GetAccount(gomock.Any(), gomock.Eq(fromAccount.ID)).
+					Times(1).
+					Return(fromAccount, nil)
+				store.EXPECT().
+					CancelStandingOrder(gomock.Any(), gomock.Eq(order.ID)).
+					Times(1).
+					Return(cancelled, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				requireBodyMatchStandingOrder(t, recorder.Body, cancelled)
+			},
+		},
+		{
+			name:    "NotFound",
+			orderID: order.ID,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetStandingOrder(gomock.Any(), gomock.Eq(order.ID)).
+					Times(1).
+					Return(db.StandingOrder{}, db.ErrRecordNotFound)
+				store.EXPECT().
+					CancelStandingOrder(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name:    "ForbiddenNotOwner",
+			orderID: order.ID,
+			buildStubs: func(store *mockdb.MockStore) {
+				otherAccount := fromAccount
+				otherAccount.Owner = util.RandomOwner()
+				store.EXPECT().
+					GetStandingOrder(gomock.Any(), gomock.Eq(order.ID)).
+					Times(1).
+					Return(order, nil)
+				store.EXPECT().
+					GetAccount(gomock.Any(), gomock.Eq(fromAccount.ID)).
+					Times(1).
+					Return(otherAccount, nil)
+				store.EXPECT().
+					CancelStandingOrder(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name:    "ConflictAlreadyCancelled",
+			orderID: order.ID,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetStandingOrder(gomock.Any(), gomock.Eq(order.ID)).
+					Times(1).
+					Return(cancelled, nil)
+				store.EXPECT().
+					GetAccount(gomock.Any(), gomock.Eq(fromAccount.ID)).
+					Times(1).
+					Return(fromAccount, nil)
+				store.EXPECT().
+					CancelStandingOrder(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusConflict, recorder.Code)
+			},
+		},
+		{
+			name:    "ConflictConcurrentUpdate",
+			orderID: order.ID,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetStandingOrder(gomock.Any(), gomock.Eq(order.ID)).
+					Times(1).
+					Return(order, nil)
+				store.EXPECT().
+					GetAccount(gomock.Any(), gomock.Eq(fromAccount.ID)).
+					Times(1).
+					Return(fromAccount, nil)
+				store.EXPECT().
+					CancelStandingOrder(gomock.Any(), gomock.Eq(order.ID)).
+					Times(1).
+					Return(db.StandingOrder{}, db.ErrRecordNotFound)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusConflict, recorder.Code)
+			},
+		},
+		{
+			name:    "BadRequestInvalidID",
+			orderID: 0,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetStandingOrder(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			url := fmt.Sprintf("/standing_orders/%d/cancel", tc.orderID)
+			request, err := http.NewRequest(http.MethodPost, url, nil)
+			require.NoError(t, err)
+
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestResumeStandingOrderAPI(t *testing.T) {
+	user, _ := getRandomUser(t)
+	fromAccount := getRandomAccount()
+	fromAccount.Owner = user.Username
+	order := getRandomStandingOrder(fromAccount, getRandomAccount())
+	order.Status = db.StandingOrderPaused
+	resumed := order
+	resumed.Status = db.StandingOrderActive
+
+	finished := order
+	finished.MaxOccurrences = pgtype.Int4{Int32: 3, Valid: true}
+	finished.Occurrences = 3
+
+	testCases := []struct {
+		name          string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetStandingOrder(gomock.Any(), gomock.Eq(order.ID)).
+					Times(1).
+					Return(order, nil)
+				store.EXPECT().
+					GetAccount(gomock.Any(), gomock.Eq(fromAccount.ID)).
+					Times(1).
+					Return(fromAccount, nil)
+				store.EXPECT().
+					ResumeStandingOrder(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(ctx interface{}, arg db.ResumeStandingOrderParams) (db.StandingOrder, error) {
+						require.Equal(t, order.ID, arg.ID)
+						require.True(t, arg.NextRunAt.Valid)
+						require.False(t, arg.NextRunAt.Time.Before(order.StartAt.Time))
+						return resumed, nil
+					})
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				requireBodyMatchStandingOrder(t, recorder.Body, resumed)
+			},
+		},
+		{
+			name: "ConflictNotPaused",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetStandingOrder(gomock.Any(), gomock.Eq(order.ID)).
+					Times(1).
+					Return(resumed, nil)
+				store.EXPECT().
+					GetAccount(gomock.Any(), gomock.Eq(fromAccount.ID)).
+					Times(1).
+					Return(fromAccount, nil)
+				store.EXPECT().
+					ResumeStandingOrder(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusConflict, recorder.Code)
+			},
+		},
+		{
+			name: "ConflictNoOccurrencesLeft",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetStandingOrder(gomock.Any(), gomock.Eq(order.ID)).
+					Times(1).
+					Return(finished, nil)
+				store.EXPECT().
+					GetAccount(gomock.Any(), gomock.Eq(fromAccount.ID)).
+					Times(1).
+					Return(fromAccount, nil)
+				store.EXPECT().
+					ResumeStandingOrder(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusConflict, recorder.Code)
+			},
+		},
+		{
+			name: "InternalServerError",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetStandingOrder(gomock.Any(), gomock.Eq(order.ID)).
+					Times(1).
+					Return(order, nil)
+				store.EXPECT().
+					GetAccount(gomock.Any(), gomock.Eq(fromAccount.ID)).
+					Times(1).
+					Return(fromAccount, nil)
+				store.EXPECT().
+					ResumeStandingOrder(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.StandingOrder{}, pgx.ErrTxClosed)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			url := fmt.Sprintf("/standing_orders/%d/resume", order.ID)
+			request, err := http.NewRequest(http.MethodPost, url, nil)
+			require.NoError(t, err)
+
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestListStandingOrderRunsAPI(t *testing.T) {
+	user, _ := getRandomUser(t)
+	fromAccount := getRandomAccount()
+	fromAccount.Owner = user.Username
+	order := getRandomStandingOrder(fromAccount, getRandomAccount())
+
+	n := 5
+	runs := make([]db.StandingOrderRun, n)
+	for i := range runs {
+		runs[i] = db.StandingOrderRun{
+			ID:              util.RandomInt(1, 1000),
+			StandingOrderID: order.ID,
+			Status:          db.StandingOrderRunSucceeded,
+			TransferID:      pgtype.Int8{Int64: util.RandomInt(1, 1000), Valid: true},
+		}
+	}
+
+	testCases := []struct {
+		name          string
+		query         string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:  "OK",
+			query: fmt.Sprintf("page_id=%d&page_size=%d", 1, n),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetStandingOrder(gomock.Any(), gomock.Eq(order.ID)).
+					Times(1).
+					Return(order, nil)
+				store.EXPECT().
+					GetAccount(gomock.Any(), gomock.Eq(fromAccount.ID)).
+					Times(1).
+					Return(fromAccount, nil)
+				store.EXPECT().
+					ListStandingOrderRuns(gomock.Any(), gomock.Eq(db.ListStandingOrderRunsParams{
+						StandingOrderID: order.ID,
+						Limit:           int32(n),
+						Offset:          0,
+					})).
+					Times(1).
+					Return(runs, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				data, err := io.ReadAll(recorder.Body)
+				require.NoError(t, err)
+
+				var gotRuns []db.StandingOrderRun
+				err = json.Unmarshal(data, &gotRuns)
+				require.NoError(t, err)
+				require.Equal(t, runs, gotRuns)
+			},
+		},
+		{
+			name:  "NotFound",
+			query: fmt.Sprintf("page_id=%d&page_size=%d", 1, n),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetStandingOrder(gomock.Any(), gomock.Eq(order.ID)).
+					Times(1).
+					Return(db.StandingOrder{}, db.ErrRecordNotFound)
+				store.EXPECT().
+					ListStandingOrderRuns(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name:  "BadRequestPageSize",
+			query: fmt.Sprintf("page_id=%d&page_size=%d", 1, 100),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetStandingOrder(gomock.Any(), gomock.Any()).
+					Times(0)
+				store.EXPECT().
+					ListStandingOrderRuns(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			url := fmt.Sprintf("/standing_orders/%d/runs?%s", order.ID, tc.query)
+			request, err := http.NewRequest(http.MethodGet, url, nil)
+			require.NoError(t, err)
+
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func requireBodyMatchStandingOrder(t *testing.T, body *bytes.Buffer, order db.StandingOrder) {
+	t.Helper()
+
+	data, err := io.ReadAll(body)
+	require.NoError(t, err)
+
+	var gotOrder db.StandingOrder
+	err = json.Unmarshal(data, &gotOrder)
+	require.NoError(t, err)
+	require.Equal(t, order.ID, gotOrder.ID)
+	require.Equal(t, order.FromAccountID, gotOrder.FromAccountID)
+	require.Equal(t, order.ToAccountID, gotOrder.ToAccountID)
+	require.Equal(t, order.Amount, gotOrder.Amount)
+	require.Equal(t, order.Frequency, gotOrder.Frequency)
+	require.Equal(t, order.Status, gotOrder.Status)
+	require.True(t, order.NextRunAt.Time.Equal(gotOrder.NextRunAt.Time))
+}
@@ -0,0 +1,55 @@
+package api
+
+import (
+	db "github.com/roman-adamchik/simplebank/db/sqlc"
+	"github.com/roman-adamchik/simplebank/token"
+)
+
+const (
+	defaultTokenIssuer   = "simplebank"
+	defaultTokenAudience = "simplebank-api"
+	// mfaTokenAudience keeps mfa tokens from being accepted as access tokens, on top of their own key
+	mfaTokenAudience = "simplebank-mfa"
+)
+
+func (server *Server) tokenIssuer() string {
+	if server.config.TokenIssuer == "" {
+		return defaultTokenIssuer
+	}
+	return server.config.TokenIssuer
+}
+
+func (server *Server) tokenAudience() string {
+	if server.config.TokenAudience == "" {
+		return defaultTokenAudience
+	}
+	return server.config.TokenAudience
+}
+
+// accessTokenClaims are the claims of the access tokens a user gets when they log in
+func (server *Server) accessTokenClaims(user db.User) token.Claims {
+	return token.Claims{
+		Username: user.Username,
+		Role:     user.Role,
+		Issuer:   server.tokenIssuer(),
+		Audience: []string{server.tokenAudience()},
+	}
+}
+
+// mfaTokenClaims are the claims of the tokens of logins waiting for their second factor
+func (server *Server) mfaTokenClaims(username string) token.Claims {
+	return token.Claims{
+		Username: username,
+		Issuer:   server.tokenIssuer(),
+		Audience: []string{mfaTokenAudience},
+	}
+}
+
+// tokenOptions are what the server expects of the tokens it is sent for an audience
+func (server *Server) tokenOptions(audience string) token.VerifyOptions {
+	return token.VerifyOptions{
+		Issuer:    server.tokenIssuer(),
+		Audience:  audience,
+		ClockSkew: server.config.TokenClockSkew,
+	}
+}
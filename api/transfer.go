@@ -6,8 +6,8 @@ import (
 	"net/http"
 
 	"github.com/gin-gonic/gin"
-	"github.com/jackc/pgx/v5"
 	db "github.com/roman-adamchik/simplebank/db/sqlc"
+	"github.com/roman-adamchik/simplebank/token"
 )
 
 type transferRequest struct {
@@ -21,48 +21,185 @@ func (server *Server) createTransfer(ctx *gin.Context) {
 	var req transferRequest
 
 	if err := ctx.ShouldBindJSON(&req); err != nil {
-		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		respondError(ctx, http.StatusBadRequest, err)
 		return
 	}
 
-	if !server.validAccount(ctx, req.FromAccountID, req.Currency) {
+	// money can only be sent from an account of the authenticated user
+	fromAccount, ok := server.ownedAccount(ctx, req.FromAccountID)
+	if !ok || !checkTransferAccount(ctx, fromAccount, req.Currency) {
 		return
 	}
 	if !server.validAccount(ctx, req.ToAccountID, req.Currency) {
 		return
 	}
 
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
 	arg := db.TransferTxParams{
 		FromAccountID: req.FromAccountID,
 		ToAccountID:   req.ToAccountID,
 		Amount:        req.Amount,
+		Initiator:     authPayload.Username,
+		PendingTTL:    server.config.PendingTransferTTL,
 	}
 
 	result, err := server.store.TransferTx(ctx, arg)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		var limitErr *db.LimitExceededError
+		if errors.As(err, &limitErr) {
+			respondLimitExceeded(ctx, limitErr)
+			return
+		}
+		if errors.Is(err, db.ErrInsufficientFunds) || errors.Is(err, db.ErrAccountClosed) {
+			respondError(ctx, http.StatusForbidden, err)
+			return
+		}
+		respondError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	// the transfer waits for approval by a second user
+	if result.Transfer.Status == db.TransferPending {
+		ctx.JSON(http.StatusAccepted, result)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, result)
+}
+
+type decideTransferRequest struct {
+	ID int64 `uri:"id" binding:"required,min=1"`
+}
+
+func (server *Server) approveTransfer(ctx *gin.Context) {
+	arg, ok := server.bindTransferDecision(ctx)
+	if !ok {
+		return
+	}
+
+	result, err := server.store.ApproveTransferTx(ctx, arg)
+	if err != nil {
+		server.handleTransferDecisionError(ctx, err)
 		return
 	}
 
 	ctx.JSON(http.StatusOK, result)
 }
 
+func (server *Server) rejectTransfer(ctx *gin.Context) {
+	arg, ok := server.bindTransferDecision(ctx)
+	if !ok {
+		return
+	}
+
+	transfer, err := server.store.RejectTransferTx(ctx, arg)
+	if err != nil {
+		server.handleTransferDecisionError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, transfer)
+}
+
+// bindTransferDecision checks that the authenticated user is an approver of the account the money is sent from
+func (server *Server) bindTransferDecision(ctx *gin.Context) (db.DecideTransferTxParams, bool) {
+	var req decideTransferRequest
+
+	if err := ctx.ShouldBindUri(&req); err != nil {
+		respondError(ctx, http.StatusBadRequest, err)
+		return db.DecideTransferTxParams{}, false
+	}
+
+	transfer, err := server.store.GetTransfer(ctx, req.ID)
+	if err != nil {
+		if errors.Is(err, db.ErrRecordNotFound) {
+			respondError(ctx, http.StatusNotFound, err)
+			return db.DecideTransferTxParams{}, false
+		}
+		respondError(ctx, http.StatusInternalServerError, err)
+		return db.DecideTransferTxParams{}, false
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	_, err = server.store.GetAccountApprover(ctx, db.GetAccountApproverParams{
+		AccountID: transfer.FromAccountID,
+		Username:  authPayload.Username,
+	})
+	if err != nil {
+		if errors.Is(err, db.ErrRecordNotFound) {
+			err = fmt.Errorf("user %s is not an approver of account [%d]", authPayload.Username, transfer.FromAccountID)
+			respondError(ctx, http.StatusForbidden, err)
+			return db.DecideTransferTxParams{}, false
+		}
+		respondError(ctx, http.StatusInternalServerError, err)
+		return db.DecideTransferTxParams{}, false
+	}
+
+	return db.DecideTransferTxParams{
+		TransferID: transfer.ID,
+		Approver:   authPayload.Username,
+	}, true
+}
+
+func (server *Server) handleTransferDecisionError(ctx *gin.Context, err error) {
+	var limitErr *db.LimitExceededError
+	switch {
+	case errors.As(err, &limitErr):
+		respondLimitExceeded(ctx, limitErr)
+	case errors.Is(err, db.ErrInitiatorCannotDecide), errors.Is(err, db.ErrAccountClosed):
+		respondError(ctx, http.StatusForbidden, err)
+	case errors.Is(err, db.ErrTransferNotPending), errors.Is(err, db.ErrTransferExpired):
+		respondError(ctx, http.StatusConflict, err)
+	default:
+		respondError(ctx, http.StatusInternalServerError, err)
+	}
+}
+
+// respondLimitExceeded responds with the limit a transfer exceeds and how much of it remains
+func respondLimitExceeded(ctx *gin.Context, err *db.LimitExceededError) {
+	p := newProblem(ctx, http.StatusForbidden, err)
+	p.Extensions = map[string]any{
+		"limit":     err.Limit,
+		"currency":  err.Currency,
+		"max":       err.Max,
+		"remaining": err.Remaining,
+	}
+	writeProblem(ctx, p)
+}
+
 func (server *Server) validAccount(ctx *gin.Context, accountID int64, currency string) bool {
 	account, err := server.store.GetAccount(ctx, accountID)
 	if err != nil {
-		if errors.Is(err, pgx.ErrNoRows) {
-			ctx.JSON(http.StatusNotFound, errorResponse(err))
+		if errors.Is(err, db.ErrRecordNotFound) {
+			respondError(ctx, http.StatusNotFound, fmt.Errorf("%w: [%d]", errAccountNotFound, accountID))
 			return false
 		}
-		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		respondError(ctx, http.StatusInternalServerError, err)
 		return false
 	}
 
-	if account.Currency != currency {
-		err = fmt.Errorf("account [%d] currency mismatch: %s to %s", accountID, account.Currency, currency)
-		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+	return checkTransferAccount(ctx, account, currency)
+}
+
+// checkTransferAccount checks that money can be moved in the given currency to or from an account
+func checkTransferAccount(ctx *gin.Context, account db.Account, currency string) bool {
+	if account.ClosedAt.Valid {
+		err := fmt.Errorf("%w: [%d]", db.ErrAccountClosed, account.ID)
+		respondError(ctx, http.StatusForbidden, err)
+		return false
+	}
+	if err := checkAccountCurrency(account, currency); err != nil {
+		respondError(ctx, http.StatusBadRequest, err)
 		return false
 	}
 
 	return true
 }
+
+func checkAccountCurrency(account db.Account, currency string) error {
+	if account.Currency != currency {
+		return fmt.Errorf("account [%d] %w: %s to %s", account.ID, errCurrencyMismatch, account.Currency, currency)
+	}
+
+	return nil
+}
@@ -0,0 +1,139 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	db "github.com/roman-adamchik/simplebank/db/sqlc"
+	"github.com/roman-adamchik/simplebank/token"
+)
+
+type createTransferBatchRequest struct {
+	Mode      string            `json:"mode" binding:"required,oneof=atomic best_effort"`
+	Transfers []transferRequest `json:"transfers" binding:"required,min=1,max=1000,dive"`
+}
+
+func (server *Server) createTransferBatch(ctx *gin.Context) {
+	var req createTransferBatchRequest
+
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		respondError(ctx, http.StatusBadRequest, err)
+		return
+	}
+
+	// most batches are paid out from the same funding account, so every account is only loaded once
+	accounts := make(map[int64]db.Account)
+	getAccount := func(accountID int64) (db.Account, error) {
+		if account, ok := accounts[accountID]; ok {
+			return account, nil
+		}
+		account, err := server.store.GetAccount(ctx, accountID)
+		if err != nil {
+			return account, err
+		}
+		accounts[accountID] = account
+		return account, nil
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	arg := db.BatchTransferTxParams{
+		Mode:    req.Mode,
+		Legs:    make([]db.BatchTransferLeg, len(req.Transfers)),
+		Creator: authPayload.Username,
+	}
+
+	for i, transfer := range req.Transfers {
+		arg.Legs[i].TransferTxParams = db.TransferTxParams{
+			FromAccountID: transfer.FromAccountID,
+			ToAccountID:   transfer.ToAccountID,
+			Amount:        transfer.Amount,
+			Initiator:     authPayload.Username,
+		}
+
+		for _, accountID := range []int64{transfer.FromAccountID, transfer.ToAccountID} {
+			account, err := getAccount(accountID)
+			status := http.StatusBadRequest
+			switch {
+			case errors.Is(err, db.ErrRecordNotFound):
+				status = http.StatusNotFound
+				err = fmt.Errorf("%w: [%d]", errAccountNotFound, accountID)
+			case err != nil:
+				respondError(ctx, http.StatusInternalServerError, err)
+				return
+			case accountID == transfer.FromAccountID && account.Owner != authPayload.Username:
+				// money can only be sent from accounts of the authenticated user, whatever the mode of the batch
+				err = fmt.Errorf("transfer [%d]: account [%d] doesn't belong to the authenticated user", i, accountID)
+				respondError(ctx, http.StatusForbidden, err)
+				return
+			case account.ClosedAt.Valid:
+				status = http.StatusForbidden
+				err = fmt.Errorf("%w: [%d]", db.ErrAccountClosed, account.ID)
+			default:
+				err = checkAccountCurrency(account, transfer.Currency)
+			}
+			if err == nil {
+				continue
+			}
+
+			// an atomic batch is rejected as a whole, best effort batches only skip the invalid leg
+			if req.Mode == db.BatchModeAtomic {
+				err = fmt.Errorf("transfer [%d]: %w", i, err)
+				respondError(ctx, status, err)
+				return
+			}
+			arg.Legs[i].RejectReason = err.Error()
+			break
+		}
+	}
+
+	result, err := server.store.BatchTransferTx(ctx, arg)
+	if err != nil {
+		respondError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, result)
+}
+
+type getTransferBatchRequest struct {
+	ID int64 `uri:"id" binding:"required,min=1"`
+}
+
+func (server *Server) getTransferBatch(ctx *gin.Context) {
+	var req getTransferBatchRequest
+
+	if err := ctx.ShouldBindUri(&req); err != nil {
+		respondError(ctx, http.StatusBadRequest, err)
+		return
+	}
+
+	batch, err := server.store.GetTransferBatch(ctx, req.ID)
+	if err != nil {
+		if errors.Is(err, db.ErrRecordNotFound) {
+			respondError(ctx, http.StatusNotFound, err)
+			return
+		}
+		respondError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	if batch.Creator != authPayload.Username {
+		err := fmt.Errorf("transfer batch [%d] doesn't belong to the authenticated user", batch.ID)
+		respondError(ctx, http.StatusForbidden, err)
+		return
+	}
+
+	items, err := server.store.ListTransferBatchItems(ctx, batch.ID)
+	if err != nil {
+		respondError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, db.BatchTransferTxResult{
+		Batch: batch,
+		Items: items,
+	})
+}
@@ -0,0 +1,455 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	mockdb "github.com/roman-adamchik/simplebank/db/mock"
+	db "github.com/roman-adamchik/simplebank/db/sqlc"
+	"github.com/roman-adamchik/simplebank/util"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func TestCreateTransferBatchAPI(t *testing.T) {
+	user, _ := getRandomUser(t)
+	fundingAccount := getRandomAccount()
+	fundingAccount.Owner = user.Username
+	account1 := getRandomAccount()
+	account1.ID = fundingAccount.ID + 1000
+	account1.Currency = fundingAccount.Currency
+	account2 := getRandomAccount()
+	account2.ID = fundingAccount.ID + 2000
+	account2.Currency = util.USD
+	if fundingAccount.Currency == util.USD {
+		account2.Currency = util.EUR
+	}
+	currency := fundingAccount.Currency
+
+	transfers := []transferRequest{
+		{FromAccountID: fundingAccount.ID, ToAccountID: account1.ID, Amount: 10, Currency: currency},
+		{FromAccountID: fundingAccount.ID, ToAccountID: account2.ID, Amount: 20, Currency: currency},
+	}
+
+	batchResult := db.BatchTransferTxResult{
+		Batch: db.TransferBatch{
+			ID:     util.RandomInt(1, 1000),
+			Mode:   db.BatchModeBestEffort,
+			Status: db.BatchPartiallyCompleted,
+		},
+		Items: []db.TransferBatchItem{},
+	}
+
+	buildAccountStubs := func(store *mockdb.MockStore) {
+		store.EXPECT().
+			GetAccount(gomock.Any(), gomock.Eq(fundingAccount.ID)).
+			Times(1).
+			Return(fundingAccount, nil)
+		store.EXPECT().
+			GetAccount(gomock.Any(), gomock.Eq(account1.ID)).
+			Times(1).
+			Return(account1, nil)
+		store.EXPECT().
+			GetAccount(gomock.Any(), gomock.Eq(account2.ID)).
+			Times(1).
+			Return(account2, nil)
+	}
+
+	testCases := []struct {
+		name          string
+		body          createTransferBatchRequest
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OKAtomic",
+			body: createTransferBatchRequest{
+				Mode:      db.BatchModeAtomic,
+				Transfers: transfers[:1],
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetAccount(gomock.Any(), gomock.Eq(fundingAccount.ID)).
+					Times(1).
+					Return(fundingAccount, nil)
+				store.EXPECT().
+					GetAccount(gomock.Any(), gomock.Eq(account1.ID)).
+					Times(1).
+					Return(account1, nil)
+				store.EXPECT().
+					BatchTransferTx(gomock.Any(), gomock.Eq(db.BatchTransferTxParams{
+						Mode:    db.BatchModeAtomic,
+						Creator: user.Username,
+						Legs: []db.BatchTransferLeg{
+							{TransferTxParams: db.TransferTxParams{
+								FromAccountID: fundingAccount.ID,
+								ToAccountID:   account1.ID,
+								Amount:        10,
+								Initiator:     user.Username,
+							}},
+						},
+					})).
+					Times(1).
+					Return(batchResult, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				requireBodyMatchBatchResult(t, recorder.Body, batchResult)
+			},
+		},
+		{
+			name: "AtomicRejectsInvalidLeg",
+			body: createTransferBatchRequest{
+				Mode:      db.BatchModeAtomic,
+				Transfers: transfers,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				buildAccountStubs(store)
+				store.EXPECT().
+					BatchTransferTx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "BestEffortRejectsInvalidLeg",
+			body: createTransferBatchRequest{
+				Mode:      db.BatchModeBestEffort,
+				Transfers: transfers,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				buildAccountStubs(store)
+				store.EXPECT().
+					BatchTransferTx(gomock.Any(), gomock.Eq(db.BatchTransferTxParams{
+						Mode:    db.BatchModeBestEffort,
+						Creator: user.Username,
+						Legs: []db.BatchTransferLeg{
+							{TransferTxParams: db.TransferTxParams{
+								FromAccountID: fundingAccount.ID,
+								ToAccountID:   account1.ID,
+								Amount:        10,
+								Initiator:     user.Username,
+							}},
+							{
+								TransferTxParams: db.TransferTxParams{
+									FromAccountID: fundingAccount.ID,
+									ToAccountID:   account2.ID,
+									Amount:        20,
+									Initiator:     user.Username,
+								},
+								RejectReason: fmt.Sprintf("account [%d] currency mismatch: %s to %s", account2.ID, account2.Currency, currency),
+							},
+						},
+					})).
+					Times(1).
+					Return(batchResult, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				requireBodyMatchBatchResult(t, recorder.Body, batchResult)
+			},
+		},
+		{
+			name: "ForbiddenNotOwnerOfFromAccount",
+			body: createTransferBatchRequest{
+				Mode: db.BatchModeBestEffort,
+				Transfers: []transferRequest{
+					transfers[0],
+					{FromAccountID: account1.ID, ToAccountID: fundingAccount.ID, Amount: 10, Currency: currency},
+				},
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetAccount(gomock.Any(), gomock.Eq(fundingAccount.ID)).
+					Times(1).
+					Return(fundingAccount, nil)
+				store.EXPECT().
+					GetAccount(gomock.Any(), gomock.Eq(account1.ID)).
+					Times(1).
+					Return(account1, nil)
+				store.EXPECT().
+					BatchTransferTx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name: "AtomicRejectsClosedAccount",
+			body: createTransferBatchRequest{
+				Mode:      db.BatchModeAtomic,
+				Transfers: transfers[:1],
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				closedAccount := account1
+				closedAccount.ClosedAt = pgtype.Timestamptz{Time: time.Now(), Valid: true}
+				store.EXPECT().
+					GetAccount(gomock.Any(), gomock.Eq(fundingAccount.ID)).
+					Times(1).
+					Return(fundingAccount, nil)
+				store.EXPECT().
+					GetAccount(gomock.Any(), gomock.Eq(account1.ID)).
+					Times(1).
+					Return(closedAccount, nil)
+				store.EXPECT().
+					BatchTransferTx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+				requireProblem(t, recorder, "account_closed")
+			},
+		},
+		{
+			name: "AccountNotFound",
+			body: createTransferBatchRequest{
+				Mode:      db.BatchModeAtomic,
+				Transfers: transfers[:1],
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetAccount(gomock.Any(), gomock.Eq(fundingAccount.ID)).
+					Times(1).
+					Return(db.Account{}, db.ErrRecordNotFound)
+				store.EXPECT().
+					BatchTransferTx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name: "InvalidMode",
+			body: createTransferBatchRequest{
+				Mode:      "sometimes",
+				Transfers: transfers,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetAccount(gomock.Any(), gomock.Any()).
+					Times(0)
+				store.EXPECT().
+					BatchTransferTx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "InvalidLeg",
+			body: createTransferBatchRequest{
+				Mode: db.BatchModeBestEffort,
+				Transfers: []transferRequest{
+					{FromAccountID: fundingAccount.ID, ToAccountID: account1.ID, Amount: -1, Currency: currency},
+				},
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetAccount(gomock.Any(), gomock.Any()).
+					Times(0)
+				store.EXPECT().
+					BatchTransferTx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "EmptyBatch",
+			body: createTransferBatchRequest{
+				Mode:      db.BatchModeAtomic,
+				Transfers: []transferRequest{},
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					BatchTransferTx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "InternalError",
+			body: createTransferBatchRequest{
+				Mode:      db.BatchModeBestEffort,
+				Transfers: transfers,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				buildAccountStubs(store)
+				store.EXPECT().
+					BatchTransferTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.BatchTransferTxResult{}, pgx.ErrTxClosed)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			data, err := json.Marshal(tc.body)
+			require.NoError(t, err)
+
+			request, err := http.NewRequest(http.MethodPost, "/transfers/batch", bytes.NewReader(data))
+			require.NoError(t, err)
+
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestGetTransferBatchAPI(t *testing.T) {
+	user, _ := getRandomUser(t)
+	batchResult := db.BatchTransferTxResult{
+		Batch: db.TransferBatch{
+			ID:      util.RandomInt(1, 1000),
+			Mode:    db.BatchModeAtomic,
+			Status:  db.BatchCompleted,
+			Creator: user.Username,
+		},
+	}
+	batchResult.Items = []db.TransferBatchItem{
+		{
+			BatchID:       batchResult.Batch.ID,
+			ItemIndex:     0,
+			FromAccountID: util.RandomInt(1, 1000),
+			ToAccountID:   util.RandomInt(1, 1000),
+			Amount:        util.RandomMoney(),
+			Status:        db.BatchItemSucceeded,
+			TransferID:    pgtype.Int8{Int64: util.RandomInt(1, 1000), Valid: true},
+		},
+	}
+
+	testCases := []struct {
+		name          string
+		batchID       int64
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:    "OK",
+			batchID: batchResult.Batch.ID,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetTransferBatch(gomock.Any(), gomock.Eq(batchResult.Batch.ID)).
+					Times(1).
+					Return(batchResult.Batch, nil)
+				store.EXPECT().
+					ListTransferBatchItems(gomock.Any(), gomock.Eq(batchResult.Batch.ID)).
+					Times(1).
+					Return(batchResult.Items, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				requireBodyMatchBatchResult(t, recorder.Body, batchResult)
+			},
+		},
+		{
+			name:    "ForbiddenNotCreator",
+			batchID: batchResult.Batch.ID,
+			buildStubs: func(store *mockdb.MockStore) {
+				otherBatch := batchResult.Batch
+				otherBatch.Creator = util.RandomOwner()
+				store.EXPECT().
+					GetTransferBatch(gomock.Any(), gomock.Eq(batchResult.Batch.ID)).
+					Times(1).
+					Return(otherBatch, nil)
+				store.EXPECT().
+					ListTransferBatchItems(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name:    "NotFound",
+			batchID: batchResult.Batch.ID,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetTransferBatch(gomock.Any(), gomock.Eq(batchResult.Batch.ID)).
+					Times(1).
+					Return(db.TransferBatch{}, db.ErrRecordNotFound)
+				store.EXPECT().
+					ListTransferBatchItems(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name:    "InvalidID",
+			batchID: 0,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetTransferBatch(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			url := fmt.Sprintf("/transfers/batch/%d", tc.batchID)
+			request, err := http.NewRequest(http.MethodGet, url, nil)
+			require.NoError(t, err)
+
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func requireBodyMatchBatchResult(t *testing.T, body *bytes.Buffer, result db.BatchTransferTxResult) {
+	t.Helper()
+
+	var gotResult db.BatchTransferTxResult
+	err := json.Unmarshal(body.Bytes(), &gotResult)
+	require.NoError(t, err)
+	require.Equal(t, result, gotResult)
+}
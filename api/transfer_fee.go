@@ -0,0 +1,135 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	db "github.com/roman-adamchik/simplebank/db/sqlc"
+)
+
+func (server *Server) listFeeSchedules(ctx *gin.Context) {
+	schedules, err := server.store.ListFeeSchedules(ctx)
+	if err != nil {
+		respondError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, schedules)
+}
+
+type feeScheduleURI struct {
+	Tier     string `uri:"tier" binding:"required,tier"`
+	Currency string `uri:"currency" binding:"required,currency"`
+}
+
+// setFeeScheduleRequest holds the fees of a tier; an omitted minimum or maximum means no bound
+type setFeeScheduleRequest struct {
+	FlatAmount    int64  `json:"flat_amount" binding:"min=0"`
+	PercentageBps int32  `json:"percentage_bps" binding:"min=0,max=10000"`
+	MinAmount     *int64 `json:"min_amount" binding:"omitempty,gt=0"`
+	MaxAmount     *int64 `json:"max_amount" binding:"omitempty,gt=0"`
+}
+
+func (server *Server) setFeeSchedule(ctx *gin.Context) {
+	var uri feeScheduleURI
+	var req setFeeScheduleRequest
+
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		respondError(ctx, http.StatusBadRequest, err)
+		return
+	}
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		respondError(ctx, http.StatusBadRequest, err)
+		return
+	}
+	if req.MinAmount != nil && req.MaxAmount != nil && *req.MinAmount > *req.MaxAmount {
+		err := errors.New("min_amount must not be greater than max_amount")
+		respondError(ctx, http.StatusBadRequest, err)
+		return
+	}
+
+	arg := db.UpsertFeeScheduleParams{
+		Tier:          uri.Tier,
+		Currency:      uri.Currency,
+		FlatAmount:    req.FlatAmount,
+		PercentageBps: req.PercentageBps,
+		MinAmount:     optionalAmount(req.MinAmount),
+		MaxAmount:     optionalAmount(req.MaxAmount),
+	}
+
+	schedule, err := server.store.UpsertFeeSchedule(ctx, arg)
+	if err != nil {
+		respondError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, schedule)
+}
+
+func (server *Server) deleteFeeSchedule(ctx *gin.Context) {
+	var uri feeScheduleURI
+
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		respondError(ctx, http.StatusBadRequest, err)
+		return
+	}
+
+	err := server.store.DeleteFeeSchedule(ctx, db.DeleteFeeScheduleParams{
+		Tier:     uri.Tier,
+		Currency: uri.Currency,
+	})
+	if err != nil {
+		respondError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{})
+}
+
+func (server *Server) listRevenueAccounts(ctx *gin.Context) {
+	accounts, err := server.store.ListRevenueAccounts(ctx)
+	if err != nil {
+		respondError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, accounts)
+}
+
+type revenueAccountURI struct {
+	Currency string `uri:"currency" binding:"required,currency"`
+}
+
+type setRevenueAccountRequest struct {
+	AccountID int64 `json:"account_id" binding:"required,min=1"`
+}
+
+func (server *Server) setRevenueAccount(ctx *gin.Context) {
+	var uri revenueAccountURI
+	var req setRevenueAccountRequest
+
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		respondError(ctx, http.StatusBadRequest, err)
+		return
+	}
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		respondError(ctx, http.StatusBadRequest, err)
+		return
+	}
+
+	if !server.validAccount(ctx, req.AccountID, uri.Currency) {
+		return
+	}
+
+	account, err := server.store.SetRevenueAccount(ctx, db.SetRevenueAccountParams{
+		Currency:  uri.Currency,
+		AccountID: req.AccountID,
+	})
+	if err != nil {
+		respondError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, account)
+}
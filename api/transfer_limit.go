@@ -0,0 +1,132 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgtype"
+	db "github.com/roman-adamchik/simplebank/db/sqlc"
+)
+
+func (server *Server) listTransferLimits(ctx *gin.Context) {
+	limits, err := server.store.ListTransferLimits(ctx)
+	if err != nil {
+		respondError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, limits)
+}
+
+type transferLimitURI struct {
+	Tier     string `uri:"tier" binding:"required,tier"`
+	Currency string `uri:"currency" binding:"required,currency"`
+}
+
+// setTransferLimitRequest holds the limits of a tier; an omitted limit means no limit
+type setTransferLimitRequest struct {
+	MaxSingleAmount      *int64 `json:"max_single_amount" binding:"omitempty,gt=0"`
+	DailyAccountAmount   *int64 `json:"daily_account_amount" binding:"omitempty,gt=0"`
+	MonthlyAccountAmount *int64 `json:"monthly_account_amount" binding:"omitempty,gt=0"`
+	DailyUserAmount      *int64 `json:"daily_user_amount" binding:"omitempty,gt=0"`
+	MonthlyUserAmount    *int64 `json:"monthly_user_amount" binding:"omitempty,gt=0"`
+	ApprovalThreshold    *int64 `json:"approval_threshold" binding:"omitempty,gt=0"`
+}
+
+func (server *Server) setTransferLimit(ctx *gin.Context) {
+	var uri transferLimitURI
+	var req setTransferLimitRequest
+
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		respondError(ctx, http.StatusBadRequest, err)
+		return
+	}
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		respondError(ctx, http.StatusBadRequest, err)
+		return
+	}
+
+	arg := db.UpsertTransferLimitParams{
+		Tier:                 uri.Tier,
+		Currency:             uri.Currency,
+		MaxSingleAmount:      optionalAmount(req.MaxSingleAmount),
+		DailyAccountAmount:   optionalAmount(req.DailyAccountAmount),
+		MonthlyAccountAmount: optionalAmount(req.MonthlyAccountAmount),
+		DailyUserAmount:      optionalAmount(req.DailyUserAmount),
+		MonthlyUserAmount:    optionalAmount(req.MonthlyUserAmount),
+		ApprovalThreshold:    optionalAmount(req.ApprovalThreshold),
+	}
+
+	limit, err := server.store.UpsertTransferLimit(ctx, arg)
+	if err != nil {
+		respondError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, limit)
+}
+
+func (server *Server) deleteTransferLimit(ctx *gin.Context) {
+	var uri transferLimitURI
+
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		respondError(ctx, http.StatusBadRequest, err)
+		return
+	}
+
+	err := server.store.DeleteTransferLimit(ctx, db.DeleteTransferLimitParams{
+		Tier:     uri.Tier,
+		Currency: uri.Currency,
+	})
+	if err != nil {
+		respondError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{})
+}
+
+type updateUserTierURI struct {
+	Username string `uri:"username" binding:"required,alphanum"`
+}
+
+type updateUserTierRequest struct {
+	Tier string `json:"tier" binding:"required,tier"`
+}
+
+func (server *Server) updateUserTier(ctx *gin.Context) {
+	var uri updateUserTierURI
+	var req updateUserTierRequest
+
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		respondError(ctx, http.StatusBadRequest, err)
+		return
+	}
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		respondError(ctx, http.StatusBadRequest, err)
+		return
+	}
+
+	user, err := server.store.UpdateUserTier(ctx, db.UpdateUserTierParams{
+		Username: uri.Username,
+		Tier:     req.Tier,
+	})
+	if err != nil {
+		if errors.Is(err, db.ErrRecordNotFound) {
+			respondError(ctx, http.StatusNotFound, err)
+			return
+		}
+		respondError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, newUserResponse(user))
+}
+
+func optionalAmount(amount *int64) pgtype.Int8 {
+	if amount == nil {
+		return pgtype.Int8{}
+	}
+	return pgtype.Int8{Int64: *amount, Valid: true}
+}
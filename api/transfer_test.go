@@ -3,21 +3,27 @@ package api
 import (
 	"bytes"
 	"encoding/json"
+	"fmt"
 	"io"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
 	mockdb "github.com/roman-adamchik/simplebank/db/mock"
 	db "github.com/roman-adamchik/simplebank/db/sqlc"
+	"github.com/roman-adamchik/simplebank/token"
 	"github.com/roman-adamchik/simplebank/util"
 	"github.com/stretchr/testify/require"
 	"go.uber.org/mock/gomock"
 )
 
 func TestCreateTransferAPI(t *testing.T) {
+	user, _ := getRandomUser(t)
 	fromAccount := getRandomAccount()
+	fromAccount.Owner = user.Username
 	toAccount := getRandomAccount()
 	currency := fromAccount.Currency
 	toAccount.Currency = currency
@@ -48,9 +54,16 @@ func TestCreateTransferAPI(t *testing.T) {
 		},
 	}
 
+	pendingResult := transferResult
+	pendingResult.Transfer.Status = db.TransferPending
+	pendingResult.Transfer.Initiator = user.Username
+	pendingResult.FromEntry = db.Entry{}
+	pendingResult.ToEntry = db.Entry{}
+
 	testCases := []struct {
 		name          string
 		body          transferRequest
+		setupAuth     func(t *testing.T, request *http.Request, tokenMaker token.Maker)
 		buildStubs    func(store *mockdb.MockStore)
 		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
 	}{
@@ -76,6 +89,7 @@ func TestCreateTransferAPI(t *testing.T) {
 						FromAccountID: fromAccount.ID,
 						ToAccountID:   toAccount.ID,
 						Amount:        amount,
+						Initiator:     user.Username,
 					})).
 					Times(1).
 					Return(transferResult, nil)
@@ -217,7 +231,7 @@ func TestCreateTransferAPI(t *testing.T) {
 				store.EXPECT().
 					GetAccount(gomock.Any(), gomock.Eq(fromAccount.ID)).
 					Times(1).
-					Return(db.Account{}, pgx.ErrNoRows)
+					Return(db.Account{}, db.ErrRecordNotFound)
 				store.EXPECT().
 					GetAccount(gomock.Any(), gomock.Eq(toAccount.ID)).
 					Times(0)
@@ -227,6 +241,31 @@ func TestCreateTransferAPI(t *testing.T) {
 			},
 			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
 				require.Equal(t, http.StatusNotFound, recorder.Code)
+				requireProblem(t, recorder, "account_not_found")
+			},
+		},
+		{
+			name: "ForbiddenNotOwnerOfFromAccount",
+			body: transferRequest{
+				FromAccountID: toAccount.ID,
+				ToAccountID:   fromAccount.ID,
+				Amount:        amount,
+				Currency:      currency,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetAccount(gomock.Any(), gomock.Eq(toAccount.ID)).
+					Times(1).
+					Return(toAccount, nil)
+				store.EXPECT().
+					GetAccount(gomock.Any(), gomock.Eq(fromAccount.ID)).
+					Times(0)
+				store.EXPECT().
+					TransferTx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
 			},
 		},
 		{
@@ -245,7 +284,7 @@ func TestCreateTransferAPI(t *testing.T) {
 				store.EXPECT().
 					GetAccount(gomock.Any(), gomock.Eq(toAccount.ID)).
 					Times(1).
-					Return(db.Account{}, pgx.ErrNoRows)
+					Return(db.Account{}, db.ErrRecordNotFound)
 				store.EXPECT().
 					TransferTx(gomock.Any(), gomock.Any()).
 					Times(0)
@@ -254,6 +293,33 @@ func TestCreateTransferAPI(t *testing.T) {
 				require.Equal(t, http.StatusNotFound, recorder.Code)
 			},
 		},
+		{
+			name: "ForbiddenClosedToAccount",
+			body: transferRequest{
+				FromAccountID: fromAccount.ID,
+				ToAccountID:   toAccount.ID,
+				Amount:        amount,
+				Currency:      currency,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				closedToAccount := toAccount
+				closedToAccount.ClosedAt = pgtype.Timestamptz{Time: time.Now(), Valid: true}
+				store.EXPECT().
+					GetAccount(gomock.Any(), gomock.Eq(fromAccount.ID)).
+					Times(1).
+					Return(fromAccount, nil)
+				store.EXPECT().
+					GetAccount(gomock.Any(), gomock.Eq(toAccount.ID)).
+					Times(1).
+					Return(closedToAccount, nil)
+				store.EXPECT().
+					TransferTx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
 		{
 			name: "BadRequestCurrencyMismatchFromAccount",
 			body: transferRequest{
@@ -279,6 +345,7 @@ func TestCreateTransferAPI(t *testing.T) {
 			},
 			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
 				require.Equal(t, http.StatusBadRequest, recorder.Code)
+				requireProblem(t, recorder, "currency_mismatch")
 			},
 		},
 		{
@@ -296,7 +363,10 @@ func TestCreateTransferAPI(t *testing.T) {
 					Return(fromAccount, nil)
 				// Set toAccount to have a different currency
 				toAccountWithDifferentCurrency := toAccount
-				toAccountWithDifferentCurrency.Currency = "ILS"
+				toAccountWithDifferentCurrency.Currency = util.USD
+				if currency == util.USD {
+					toAccountWithDifferentCurrency.Currency = util.EUR
+				}
 				store.EXPECT().
 					GetAccount(gomock.Any(), gomock.Eq(toAccount.ID)).
 					Times(1).
@@ -355,6 +425,7 @@ func TestCreateTransferAPI(t *testing.T) {
 						FromAccountID: fromAccount.ID,
 						ToAccountID:   toAccount.ID,
 						Amount:        amount,
+						Initiator:     user.Username,
 					})).
 					Times(1).
 					Return(db.TransferTxResult{}, pgx.ErrTxClosed)
@@ -363,6 +434,182 @@ func TestCreateTransferAPI(t *testing.T) {
 				require.Equal(t, http.StatusInternalServerError, recorder.Code)
 			},
 		},
+		{
+			name: "InternalServerErrorNoRevenueAccount",
+			body: transferRequest{
+				FromAccountID: fromAccount.ID,
+				ToAccountID:   toAccount.ID,
+				Amount:        amount,
+				Currency:      currency,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetAccount(gomock.Any(), gomock.Eq(fromAccount.ID)).
+					Times(1).
+					Return(fromAccount, nil)
+				store.EXPECT().
+					GetAccount(gomock.Any(), gomock.Eq(toAccount.ID)).
+					Times(1).
+					Return(toAccount, nil)
+				store.EXPECT().
+					TransferTx(gomock.Any(), gomock.Eq(db.TransferTxParams{
+						FromAccountID: fromAccount.ID,
+						ToAccountID:   toAccount.ID,
+						Amount:        amount,
+						Initiator:     user.Username,
+					})).
+					Times(1).
+					Return(db.TransferTxResult{}, db.ErrNoRevenueAccount)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// a missing revenue account is a configuration error the client cannot fix
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+				requireProblem(t, recorder, "internal_error")
+			},
+		},
+		{
+			name: "AcceptedPendingApproval",
+			body: transferRequest{
+				FromAccountID: fromAccount.ID,
+				ToAccountID:   toAccount.ID,
+				Amount:        amount,
+				Currency:      currency,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetAccount(gomock.Any(), gomock.Eq(fromAccount.ID)).
+					Times(1).
+					Return(fromAccount, nil)
+				store.EXPECT().
+					GetAccount(gomock.Any(), gomock.Eq(toAccount.ID)).
+					Times(1).
+					Return(toAccount, nil)
+				store.EXPECT().
+					TransferTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(pendingResult, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusAccepted, recorder.Code)
+				requireBodyMatchTransferResult(t, recorder.Body, &pendingResult)
+			},
+		},
+		{
+			name: "ForbiddenInsufficientFunds",
+			body: transferRequest{
+				FromAccountID: fromAccount.ID,
+				ToAccountID:   toAccount.ID,
+				Amount:        amount,
+				Currency:      currency,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetAccount(gomock.Any(), gomock.Eq(fromAccount.ID)).
+					Times(1).
+					Return(fromAccount, nil)
+				store.EXPECT().
+					GetAccount(gomock.Any(), gomock.Eq(toAccount.ID)).
+					Times(1).
+					Return(toAccount, nil)
+				store.EXPECT().
+					TransferTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.TransferTxResult{}, db.ErrInsufficientFunds)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+				requireProblem(t, recorder, "insufficient_funds")
+			},
+		},
+		{
+			name: "ForbiddenAccountClosedInTransferTx",
+			body: transferRequest{
+				FromAccountID: fromAccount.ID,
+				ToAccountID:   toAccount.ID,
+				Amount:        amount,
+				Currency:      currency,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetAccount(gomock.Any(), gomock.Eq(fromAccount.ID)).
+					Times(1).
+					Return(fromAccount, nil)
+				store.EXPECT().
+					GetAccount(gomock.Any(), gomock.Eq(toAccount.ID)).
+					Times(1).
+					Return(toAccount, nil)
+				store.EXPECT().
+					TransferTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.TransferTxResult{}, db.ErrAccountClosed)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+				requireProblem(t, recorder, "account_closed")
+			},
+		},
+		{
+			name: "NoAuthorization",
+			body: transferRequest{
+				FromAccountID: fromAccount.ID,
+				ToAccountID:   toAccount.ID,
+				Amount:        amount,
+				Currency:      currency,
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetAccount(gomock.Any(), gomock.Any()).
+					Times(0)
+				store.EXPECT().
+					TransferTx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "ForbiddenLimitExceeded",
+			body: transferRequest{
+				FromAccountID: fromAccount.ID,
+				ToAccountID:   toAccount.ID,
+				Amount:        amount,
+				Currency:      currency,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetAccount(gomock.Any(), gomock.Eq(fromAccount.ID)).
+					Times(1).
+					Return(fromAccount, nil)
+				store.EXPECT().
+					GetAccount(gomock.Any(), gomock.Eq(toAccount.ID)).
+					Times(1).
+					Return(toAccount, nil)
+				store.EXPECT().
+					TransferTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.TransferTxResult{}, &db.LimitExceededError{
+						Limit:     db.LimitDailyAccount,
+						Currency:  currency,
+						Max:       100,
+						Remaining: 10,
+					})
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+				requireProblem(t, recorder, "transfer_limit_exceeded")
+
+				var gotError db.LimitExceededError
+				err := json.Unmarshal(recorder.Body.Bytes(), &gotError)
+				require.NoError(t, err)
+				require.Equal(t, db.LimitDailyAccount, gotError.Limit)
+				require.Equal(t, currency, gotError.Currency)
+				require.Equal(t, int64(100), gotError.Max)
+				require.Equal(t, int64(10), gotError.Remaining)
+			},
+		},
 	}
 
 	for i := range testCases {
@@ -385,6 +632,11 @@ func TestCreateTransferAPI(t *testing.T) {
 			request, err := http.NewRequest("POST", "/transfers", bytes.NewBuffer(data))
 			require.NoError(t, err)
 
+			if tc.setupAuth != nil {
+				tc.setupAuth(t, request, server.tokenMaker)
+			} else {
+				addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
+			}
 			server.router.ServeHTTP(recorder, request)
 			tc.checkResponse(t, recorder)
 		})
@@ -404,3 +656,313 @@ func requireBodyMatchTransferResult(t *testing.T, body *bytes.Buffer, result *db
 	require.Equal(t, result.FromEntry, gotResult.FromEntry)
 	require.Equal(t, result.ToEntry, gotResult.ToEntry)
 }
+
+func TestApproveTransferAPI(t *testing.T) {
+	initiator, _ := getRandomUser(t)
+	approver, _ := getRandomUser(t)
+
+	transfer := db.Transfer{
+		ID:            util.RandomInt(1, 1000),
+		FromAccountID: util.RandomInt(1, 1000),
+		ToAccountID:   util.RandomInt(1001, 2000),
+		Amount:        util.RandomInt(1, 1000),
+		Status:        db.TransferPending,
+		Initiator:     initiator.Username,
+	}
+	approvedTransfer := transfer
+	approvedTransfer.Status = db.TransferCompleted
+	approvedTransfer.Approver = approver.Username
+	result := db.TransferTxResult{Transfer: approvedTransfer}
+
+	buildApproverStubs := func(store *mockdb.MockStore) {
+		store.EXPECT().
+			GetTransfer(gomock.Any(), gomock.Eq(transfer.ID)).
+			Times(1).
+			Return(transfer, nil)
+		store.EXPECT().
+			GetAccountApprover(gomock.Any(), gomock.Eq(db.GetAccountApproverParams{
+				AccountID: transfer.FromAccountID,
+				Username:  approver.Username,
+			})).
+			Times(1).
+			Return(db.AccountApprover{AccountID: transfer.FromAccountID, Username: approver.Username}, nil)
+	}
+	decision := db.DecideTransferTxParams{
+		TransferID: transfer.ID,
+		Approver:   approver.Username,
+	}
+
+	testCases := []struct {
+		name          string
+		transferID    int64
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:       "OK",
+			transferID: transfer.ID,
+			buildStubs: func(store *mockdb.MockStore) {
+				buildApproverStubs(store)
+				store.EXPECT().
+					ApproveTransferTx(gomock.Any(), gomock.Eq(decision)).
+					Times(1).
+					Return(result, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				requireBodyMatchTransferResult(t, recorder.Body, &result)
+			},
+		},
+		{
+			name:       "NotFound",
+			transferID: transfer.ID,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetTransfer(gomock.Any(), gomock.Eq(transfer.ID)).
+					Times(1).
+					Return(db.Transfer{}, db.ErrRecordNotFound)
+				store.EXPECT().
+					ApproveTransferTx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name:       "NotApprover",
+			transferID: transfer.ID,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetTransfer(gomock.Any(), gomock.Eq(transfer.ID)).
+					Times(1).
+					Return(transfer, nil)
+				store.EXPECT().
+					GetAccountApprover(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.AccountApprover{}, db.ErrRecordNotFound)
+				store.EXPECT().
+					ApproveTransferTx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name:       "InitiatorCannotApprove",
+			transferID: transfer.ID,
+			buildStubs: func(store *mockdb.MockStore) {
+				buildApproverStubs(store)
+				store.EXPECT().
+					ApproveTransferTx(gomock.Any(), gomock.Eq(decision)).
+					Times(1).
+					Return(db.TransferTxResult{}, db.ErrInitiatorCannotDecide)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name:       "AccountClosed",
+			transferID: transfer.ID,
+			buildStubs: func(store *mockdb.MockStore) {
+				buildApproverStubs(store)
+				store.EXPECT().
+					ApproveTransferTx(gomock.Any(), gomock.Eq(decision)).
+					Times(1).
+					Return(db.TransferTxResult{}, db.ErrAccountClosed)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+				requireProblem(t, recorder, "account_closed")
+			},
+		},
+		{
+			name:       "NotPending",
+			transferID: transfer.ID,
+			buildStubs: func(store *mockdb.MockStore) {
+				buildApproverStubs(store)
+				store.EXPECT().
+					ApproveTransferTx(gomock.Any(), gomock.Eq(decision)).
+					Times(1).
+					Return(db.TransferTxResult{}, db.ErrTransferNotPending)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusConflict, recorder.Code)
+			},
+		},
+		{
+			name:       "Expired",
+			transferID: transfer.ID,
+			buildStubs: func(store *mockdb.MockStore) {
+				buildApproverStubs(store)
+				store.EXPECT().
+					ApproveTransferTx(gomock.Any(), gomock.Eq(decision)).
+					Times(1).
+					Return(db.TransferTxResult{}, db.ErrTransferExpired)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusConflict, recorder.Code)
+			},
+		},
+		{
+			name:       "LimitExceeded",
+			transferID: transfer.ID,
+			buildStubs: func(store *mockdb.MockStore) {
+				buildApproverStubs(store)
+				store.EXPECT().
+					ApproveTransferTx(gomock.Any(), gomock.Eq(decision)).
+					Times(1).
+					Return(db.TransferTxResult{}, &db.LimitExceededError{Limit: db.LimitMonthlyUser})
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name:       "InvalidID",
+			transferID: 0,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetTransfer(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			url := fmt.Sprintf("/transfers/%d/approve", tc.transferID)
+			request, err := http.NewRequest(http.MethodPost, url, nil)
+			require.NoError(t, err)
+
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, approver.Username, time.Minute)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestRejectTransferAPI(t *testing.T) {
+	approver, _ := getRandomUser(t)
+
+	transfer := db.Transfer{
+		ID:            util.RandomInt(1, 1000),
+		FromAccountID: util.RandomInt(1, 1000),
+		ToAccountID:   util.RandomInt(1001, 2000),
+		Amount:        util.RandomInt(1, 1000),
+		Status:        db.TransferPending,
+		Initiator:     util.RandomOwner(),
+	}
+	rejectedTransfer := transfer
+	rejectedTransfer.Status = db.TransferRejected
+	rejectedTransfer.Approver = approver.Username
+
+	testCases := []struct {
+		name          string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetTransfer(gomock.Any(), gomock.Eq(transfer.ID)).
+					Times(1).
+					Return(transfer, nil)
+				store.EXPECT().
+					GetAccountApprover(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.AccountApprover{AccountID: transfer.FromAccountID, Username: approver.Username}, nil)
+				store.EXPECT().
+					RejectTransferTx(gomock.Any(), gomock.Eq(db.DecideTransferTxParams{
+						TransferID: transfer.ID,
+						Approver:   approver.Username,
+					})).
+					Times(1).
+					Return(rejectedTransfer, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var gotTransfer db.Transfer
+				err := json.Unmarshal(recorder.Body.Bytes(), &gotTransfer)
+				require.NoError(t, err)
+				require.Equal(t, rejectedTransfer, gotTransfer)
+			},
+		},
+		{
+			name: "NotPending",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetTransfer(gomock.Any(), gomock.Eq(transfer.ID)).
+					Times(1).
+					Return(transfer, nil)
+				store.EXPECT().
+					GetAccountApprover(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.AccountApprover{AccountID: transfer.FromAccountID, Username: approver.Username}, nil)
+				store.EXPECT().
+					RejectTransferTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.Transfer{}, db.ErrTransferNotPending)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusConflict, recorder.Code)
+			},
+		},
+		{
+			name: "InternalError",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetTransfer(gomock.Any(), gomock.Eq(transfer.ID)).
+					Times(1).
+					Return(db.Transfer{}, pgx.ErrTxClosed)
+				store.EXPECT().
+					RejectTransferTx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			url := fmt.Sprintf("/transfers/%d/reject", transfer.ID)
+			request, err := http.NewRequest(http.MethodPost, url, nil)
+			require.NoError(t, err)
+
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, approver.Username, time.Minute)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
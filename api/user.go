@@ -2,20 +2,18 @@ package api
 
 import (
 	"errors"
+	"log"
 	"net/http"
+	"time"
 
 	"github.com/gin-gonic/gin"
-	"github.com/jackc/pgerrcode"
-	"github.com/jackc/pgx/v5"
-	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgtype"
 	db "github.com/roman-adamchik/simplebank/db/sqlc"
-	"github.com/roman-adamchik/simplebank/util"
 )
 
 type createUserRequest struct {
 	Username string `json:"username" binding:"required,alphanum"`
-	Password string `json:"password" binding:"required,min=6"`
+	Password string `json:"password" binding:"required"`
 	FullName string `json:"full_name" binding:"required"`
 	Email    string `json:"email" binding:"required,email"`
 }
@@ -24,6 +22,11 @@ type userResponse struct {
 	Username          string             `json:"username"`
 	FullName          string             `json:"full_name"`
 	Email             string             `json:"email"`
+	Role              string             `json:"role"`
+	Tier              string             `json:"tier"`
+	IsEmailVerified   bool               `json:"is_email_verified"`
+	IsMfaEnabled      bool               `json:"is_mfa_enabled"`
+	DeactivatedAt     pgtype.Timestamptz `json:"deactivated_at"`
 	PasswordChangedAt pgtype.Timestamptz `json:"password_changed_at"`
 	CreatedAt         pgtype.Timestamptz `json:"created_at"`
 }
@@ -33,6 +36,11 @@ func newUserResponse(user db.User) userResponse {
 		Username:          user.Username,
 		FullName:          user.FullName,
 		Email:             user.Email,
+		Role:              user.Role,
+		Tier:              user.Tier,
+		IsEmailVerified:   user.IsEmailVerified,
+		IsMfaEnabled:      user.IsMfaEnabled,
+		DeactivatedAt:     user.DeactivatedAt,
 		PasswordChangedAt: user.PasswordChangedAt,
 		CreatedAt:         user.CreatedAt,
 	}
@@ -42,13 +50,17 @@ func (server *Server) createUser(ctx *gin.Context) {
 	var req createUserRequest
 
 	if err := ctx.ShouldBindJSON(&req); err != nil {
-		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		respondError(ctx, http.StatusBadRequest, err)
 		return
 	}
 
-	hashedPassword, err := util.HashPassword(req.Password)
+	if !server.checkPasswordPolicy(ctx, req.Password, db.User{Username: req.Username, Email: req.Email}) {
+		return
+	}
+
+	hashedPassword, err := server.passwordHasher.Hash(req.Password)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		respondError(ctx, http.StatusInternalServerError, err)
 		return
 	}
 
@@ -59,34 +71,35 @@ func (server *Server) createUser(ctx *gin.Context) {
 		Email:          req.Email,
 	}
 
-	user, err := server.store.CreateUser(ctx, arg)
+	user, err := server.store.CreateUserTx(ctx, arg)
 	if err != nil {
-		if pgxErr, ok := err.(*pgconn.PgError); ok {
-			switch pgxErr.Code {
-			case pgerrcode.UniqueViolation:
-				ctx.JSON(http.StatusForbidden, errorResponse(err))
-				return
-			default:
-				ctx.JSON(http.StatusInternalServerError, errorResponse(err))
-				return
-			}
+		if errors.Is(err, db.ErrUniqueViolation) {
+			respondError(ctx, http.StatusForbidden, err)
+			return
 		}
-		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		respondError(ctx, http.StatusInternalServerError, err)
 		return
 	}
 
+	// the user can ask for another verification email if this one cannot be queued
+	if err := server.sendVerifyEmail(ctx, user); err != nil {
+		log.Printf("cannot enqueue verification email of user %s: %v", user.Username, err)
+	}
+
 	rsp := newUserResponse(user)
 
 	ctx.JSON(http.StatusOK, rsp)
 }
 
+// loginUserRequest does not apply the password policy, which only governs the passwords that are set
 type loginUserRequest struct {
 	Username string `json:"username" binding:"required,alphanum"`
-	Password string `json:"password" binding:"required,min=6"`
+	Password string `json:"password" binding:"required"`
 }
 
 type loginUserResponse struct {
 	AccessToken string       `json:"access_token"`
+	SessionID   int64        `json:"session_id"`
 	User        userResponse `json:"user"`
 }
 
@@ -94,36 +107,106 @@ func (server *Server) loginUser(ctx *gin.Context) {
 	var req loginUserRequest
 
 	if err := ctx.ShouldBindJSON(&req); err != nil {
-		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		respondError(ctx, http.StatusBadRequest, err)
 		return
 	}
 
-	user, err := server.store.GetUser(ctx, req.Username)
+	now := time.Now()
+	blockedUntil, err := server.startLoginAttempt(ctx, req.Username, now)
 	if err != nil {
-		if errors.Is(err, pgx.ErrNoRows) {
-			ctx.JSON(http.StatusNotFound, errorResponse(err))
+		respondError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+	if !blockedUntil.IsZero() {
+		if err := server.recordLoginAttempt(ctx, req.Username, db.LoginThrottled); err != nil {
+			respondError(ctx, http.StatusInternalServerError, err)
 			return
 		}
-		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		throttledLoginResponse(ctx, blockedUntil, now)
 		return
 	}
 
-	err = util.CheckPassword(req.Password, user.HashedPassword)
+	failureReason := ""
+	user, err := server.store.GetUser(ctx, req.Username)
 	if err != nil {
-		ctx.JSON(http.StatusUnauthorized, errorResponse(err))
+		if !errors.Is(err, db.ErrRecordNotFound) {
+			respondError(ctx, http.StatusInternalServerError, err)
+			return
+		}
+		// an unknown user takes as long as a wrong password
+		_ = server.passwordHasher.Verify(req.Password, server.dummyPasswordHash)
+		failureReason = db.LoginUnknownUser
+	} else if err := server.passwordHasher.Verify(req.Password, user.HashedPassword); err != nil {
+		failureReason = db.LoginWrongPassword
+	} else if user.DeactivatedAt.Valid {
+		failureReason = db.LoginDeactivated
+	} else if server.passwordHasher.NeedsRehash(user.HashedPassword) {
+		server.rehashPassword(ctx, user, req.Password)
+	}
+
+	// the login of a user with 2FA is recorded once their code is checked, so that a right password
+	// does not reset the throttle of their wrong codes, only the failure counted for the password is taken back
+	if failureReason == "" && user.IsMfaEnabled {
+		err := server.store.UncountLoginFailures(ctx, []string{db.UserThrottleKey(user.Username), db.IPThrottleKey(ctx.ClientIP())})
+		if err != nil {
+			respondError(ctx, http.StatusInternalServerError, err)
+			return
+		}
+
+		mfaToken, err := server.mfaTokenMaker.CreateToken(server.mfaTokenClaims(user.Username), server.mfaTokenDuration())
+		if err != nil {
+			respondError(ctx, http.StatusInternalServerError, err)
+			return
+		}
+
+		ctx.JSON(http.StatusOK, mfaRequiredResponse{MFARequired: true, MFAToken: mfaToken})
+		return
+	}
+
+	if err := server.recordLoginAttempt(ctx, req.Username, failureReason); err != nil {
+		respondError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+	if failureReason == db.LoginDeactivated {
+		respondError(ctx, http.StatusForbidden, db.ErrUserDeactivated)
+		return
+	}
+	if failureReason != "" {
+		respondError(ctx, http.StatusUnauthorized, errInvalidCredentials)
 		return
 	}
 
-	accessToken, err := server.tokenMaker.CreateToken(user.Username, server.config.AccessTokenDuration)
+	accessToken, session, err := server.createSession(ctx, user)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		respondError(ctx, http.StatusInternalServerError, err)
 		return
 	}
 
 	rsp := loginUserResponse{
 		AccessToken: accessToken,
+		SessionID:   session.ID,
 		User:        newUserResponse(user),
 	}
 
 	ctx.JSON(http.StatusOK, rsp)
 }
+
+// rehashPassword replaces an outdated password hash with one of the preferred algorithm and parameters,
+// which needs the password and so can only be done at login. A failure is only logged, the old hash still works
+func (server *Server) rehashPassword(ctx *gin.Context, user db.User, password string) {
+	hashedPassword, err := server.passwordHasher.Hash(password)
+	if err != nil {
+		log.Printf("cannot rehash password of user %s: %v", user.Username, err)
+		return
+	}
+
+	// the hash is only replaced if the password was not changed in the meantime
+	err = server.store.RehashUserPassword(ctx, db.RehashUserPasswordParams{
+		NewHashedPassword: hashedPassword,
+		Username:          user.Username,
+		OldHashedPassword: user.HashedPassword,
+	})
+	if err != nil {
+		log.Printf("cannot rehash password of user %s: %v", user.Username, err)
+	}
+}
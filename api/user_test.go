@@ -7,23 +7,26 @@ import (
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
-	"github.com/jackc/pgerrcode"
 	"github.com/jackc/pgx/v5"
-	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgtype"
 	mockdb "github.com/roman-adamchik/simplebank/db/mock"
 	db "github.com/roman-adamchik/simplebank/db/sqlc"
+	"github.com/roman-adamchik/simplebank/passwordpolicy"
 	"github.com/roman-adamchik/simplebank/util"
+	"github.com/roman-adamchik/simplebank/worker"
 	"github.com/stretchr/testify/require"
 	"go.uber.org/mock/gomock"
+	"golang.org/x/crypto/bcrypt"
 )
 
 func getRandomUser(t *testing.T) (user db.User, password string) {
 	t.Helper()
 
-	password = util.RandomString(6)
+	password = util.RandomString(10)
 	hashedPassword, err := util.HashPassword(password)
 	require.NoError(t, err)
 
@@ -51,6 +54,8 @@ func requireBodyMatchUser(t *testing.T, body *bytes.Buffer, user db.User) {
 	require.Equal(t, user.Username, gotUser.Username)
 	require.Equal(t, user.FullName, gotUser.FullName)
 	require.Equal(t, user.Email, gotUser.Email)
+	require.Equal(t, user.IsEmailVerified, gotUser.IsEmailVerified)
+	require.Equal(t, user.IsMfaEnabled, gotUser.IsMfaEnabled)
 	require.Equal(t, user.PasswordChangedAt, gotUser.PasswordChangedAt)
 	require.Equal(t, user.CreatedAt, gotUser.CreatedAt)
 }
@@ -74,7 +79,7 @@ func TestCreateUserAPI(t *testing.T) {
 			},
 			buildStubs: func(store *mockdb.MockStore) {
 				store.EXPECT().
-					CreateUser(gomock.Any(), gomock.Any()).
+					CreateUserTx(gomock.Any(), gomock.Any()).
 					Times(1).
 					DoAndReturn(func(ctx interface{}, arg db.CreateUserParams) (db.User, error) {
 						require.Equal(t, user.Username, arg.Username)
@@ -100,7 +105,7 @@ func TestCreateUserAPI(t *testing.T) {
 			},
 			buildStubs: func(store *mockdb.MockStore) {
 				store.EXPECT().
-					CreateUser(gomock.Any(), gomock.Any()).
+					CreateUserTx(gomock.Any(), gomock.Any()).
 					Times(0)
 			},
 			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
@@ -117,7 +122,7 @@ func TestCreateUserAPI(t *testing.T) {
 			},
 			buildStubs: func(store *mockdb.MockStore) {
 				store.EXPECT().
-					CreateUser(gomock.Any(), gomock.Any()).
+					CreateUserTx(gomock.Any(), gomock.Any()).
 					Times(0)
 			},
 			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
@@ -134,11 +139,28 @@ func TestCreateUserAPI(t *testing.T) {
 			},
 			buildStubs: func(store *mockdb.MockStore) {
 				store.EXPECT().
-					CreateUser(gomock.Any(), gomock.Any()).
+					CreateUserTx(gomock.Any(), gomock.Any()).
 					Times(0)
 			},
 			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
-				require.Equal(t, http.StatusBadRequest, recorder.Code)
+				requirePolicyViolations(t, recorder, passwordpolicy.RuleMinLength)
+			},
+		},
+		{
+			name: "BadRequestPasswordContainsEmail",
+			body: createUserRequest{
+				Username: user.Username,
+				Password: strings.Split(user.Email, "@")[0] + "-password",
+				FullName: user.FullName,
+				Email:    user.Email,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					CreateUserTx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				requirePolicyViolations(t, recorder, passwordpolicy.RuleEmail)
 			},
 		},
 		{
@@ -151,7 +173,7 @@ func TestCreateUserAPI(t *testing.T) {
 			},
 			buildStubs: func(store *mockdb.MockStore) {
 				store.EXPECT().
-					CreateUser(gomock.Any(), gomock.Any()).
+					CreateUserTx(gomock.Any(), gomock.Any()).
 					Times(0)
 			},
 			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
@@ -168,7 +190,7 @@ func TestCreateUserAPI(t *testing.T) {
 			},
 			buildStubs: func(store *mockdb.MockStore) {
 				store.EXPECT().
-					CreateUser(gomock.Any(), gomock.Any()).
+					CreateUserTx(gomock.Any(), gomock.Any()).
 					Times(0)
 			},
 			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
@@ -185,7 +207,7 @@ func TestCreateUserAPI(t *testing.T) {
 			},
 			buildStubs: func(store *mockdb.MockStore) {
 				store.EXPECT().
-					CreateUser(gomock.Any(), gomock.Any()).
+					CreateUserTx(gomock.Any(), gomock.Any()).
 					Times(0)
 			},
 			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
@@ -202,7 +224,7 @@ func TestCreateUserAPI(t *testing.T) {
 			},
 			buildStubs: func(store *mockdb.MockStore) {
 				store.EXPECT().
-					CreateUser(gomock.Any(), gomock.Any()).
+					CreateUserTx(gomock.Any(), gomock.Any()).
 					Times(0)
 			},
 			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
@@ -218,13 +240,10 @@ func TestCreateUserAPI(t *testing.T) {
 				Email:    user.Email,
 			},
 			buildStubs: func(store *mockdb.MockStore) {
-				pgErr := &pgconn.PgError{
-					Code: pgerrcode.UniqueViolation,
-				}
 				store.EXPECT().
-					CreateUser(gomock.Any(), gomock.Any()).
+					CreateUserTx(gomock.Any(), gomock.Any()).
 					Times(1).
-					Return(db.User{}, pgErr)
+					Return(db.User{}, &db.ConstraintError{Violation: db.ErrUniqueViolation, Constraint: "users_pkey"})
 			},
 			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
 				require.Equal(t, http.StatusForbidden, recorder.Code)
@@ -240,7 +259,7 @@ func TestCreateUserAPI(t *testing.T) {
 			},
 			buildStubs: func(store *mockdb.MockStore) {
 				store.EXPECT().
-					CreateUser(gomock.Any(), gomock.Any()).
+					CreateUserTx(gomock.Any(), gomock.Any()).
 					Times(1).
 					Return(db.User{}, pgx.ErrTxClosed)
 			},
@@ -261,6 +280,7 @@ func TestCreateUserAPI(t *testing.T) {
 			tc.buildStubs(store)
 
 			server := newTestServer(t, store)
+			queue, sent := recordTasks(t, server, worker.TaskSendVerifyEmail)
 			recorder := httptest.NewRecorder()
 
 			data, err := json.Marshal(tc.body)
@@ -272,20 +292,58 @@ func TestCreateUserAPI(t *testing.T) {
 			request.Header.Set("Content-Type", "application/json")
 
 			server.router.ServeHTTP(recorder, request)
+			queue.Wait()
 			tc.checkResponse(t, recorder)
+
+			// a verification email is sent to every new user
+			if recorder.Code == http.StatusOK {
+				require.Len(t, *sent, 1)
+				require.JSONEq(t, fmt.Sprintf(`{"username":%q}`, user.Username), (*sent)[0])
+			} else {
+				require.Empty(t, *sent)
+			}
 		})
 	}
 }
 
 func TestLoginUserAPI(t *testing.T) {
 	user, password := getRandomUser(t)
-	fmt.Println("user:", user)
+	clientIP := "192.0.2.1"
+	userKey := db.UserThrottleKey(user.Username)
+	ipKey := db.IPThrottleKey(clientIP)
+
+	expectThrottles := func(store *mockdb.MockStore, throttles ...db.LoginThrottle) {
+		store.EXPECT().
+			StartLoginAttemptTx(gomock.Any(), gomock.Any()).
+			Times(1).
+			DoAndReturn(func(_ any, arg db.StartLoginAttemptTxParams) (time.Time, error) {
+				require.Equal(t, user.Username, arg.Username)
+				require.Equal(t, clientIP, arg.ClientIP)
+				require.Equal(t, defaultLoginLockoutDuration, arg.Window)
+				return arg.BlockedUntil(throttles), nil
+			})
+	}
+	expectAttempt := func(store *mockdb.MockStore, failureReason string) {
+		store.EXPECT().
+			RecordLoginAttemptTx(gomock.Any(), gomock.Any()).
+			Times(1).
+			DoAndReturn(func(_ any, arg db.RecordLoginAttemptTxParams) (db.LoginAttempt, error) {
+				require.Equal(t, user.Username, arg.Username)
+				require.Equal(t, clientIP, arg.ClientIP)
+				require.Equal(t, failureReason, arg.FailureReason)
+				return db.LoginAttempt{}, nil
+			})
+	}
+	requireInvalidCredentials := func(t *testing.T, recorder *httptest.ResponseRecorder) {
+		require.Equal(t, http.StatusUnauthorized, recorder.Code)
+		requireProblem(t, recorder, "invalid_credentials")
+	}
+	now := pgtype.Timestamptz{Time: time.Now(), Valid: true}
 
 	testCases := []struct {
 		name          string
 		body          map[string]interface{}
 		buildStubs    func(store *mockdb.MockStore)
-		setupToken    func(server *Server)
 		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
 	}{
 		{
@@ -295,12 +353,14 @@ func TestLoginUserAPI(t *testing.T) {
 				"password": password,
 			},
 			buildStubs: func(store *mockdb.MockStore) {
+				expectThrottles(store)
 				store.EXPECT().
 					GetUser(gomock.Any(), gomock.Eq(user.Username)).
 					Times(1).
 					Return(user, nil)
+				expectAttempt(store, "")
+				expectCreateSession(t, store, user.Username)
 			},
-			setupToken: func(server *Server) {},
 			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
 				require.Equal(t, http.StatusOK, recorder.Code)
 
@@ -316,6 +376,128 @@ func TestLoginUserAPI(t *testing.T) {
 				require.Equal(t, user.Email, resp.User.Email)
 			},
 		},
+		{
+			name: "RehashOutdatedHash",
+			body: map[string]interface{}{
+				"username": user.Username,
+				"password": password,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				bcryptUser := user
+				bcryptHash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.MinCost)
+				require.NoError(t, err)
+				bcryptUser.HashedPassword = string(bcryptHash)
+
+				expectThrottles(store)
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(bcryptUser, nil)
+				store.EXPECT().
+					RehashUserPassword(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ any, arg db.RehashUserPasswordParams) error {
+						require.Equal(t, user.Username, arg.Username)
+						require.Equal(t, bcryptUser.HashedPassword, arg.OldHashedPassword)
+						require.True(t, strings.HasPrefix(arg.NewHashedPassword, "$argon2id$"))
+						require.NoError(t, util.CheckPassword(password, arg.NewHashedPassword))
+						return nil
+					})
+				expectAttempt(store, "")
+				expectCreateSession(t, store, user.Username)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name: "RehashError",
+			body: map[string]interface{}{
+				"username": user.Username,
+				"password": password,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				bcryptUser := user
+				bcryptHash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.MinCost)
+				require.NoError(t, err)
+				bcryptUser.HashedPassword = string(bcryptHash)
+
+				expectThrottles(store)
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(bcryptUser, nil)
+				store.EXPECT().
+					RehashUserPassword(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(pgx.ErrTxClosed)
+				expectAttempt(store, "")
+				expectCreateSession(t, store, user.Username)
+			},
+			// the old hash still works, so the login goes on
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name: "Deactivated",
+			body: map[string]interface{}{
+				"username": user.Username,
+				"password": password,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				deactivated := user
+				deactivated.DeactivatedAt = now
+
+				expectThrottles(store)
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(deactivated, nil)
+				expectAttempt(store, db.LoginDeactivated)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+				requireProblem(t, recorder, "user_deactivated")
+			},
+		},
+		{
+			name: "MFARequired",
+			body: map[string]interface{}{
+				"username": user.Username,
+				"password": password,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				mfaUser := user
+				mfaUser.IsMfaEnabled = true
+
+				expectThrottles(store)
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(mfaUser, nil)
+				// the failure counted for the password is taken back
+				store.EXPECT().
+					UncountLoginFailures(gomock.Any(), gomock.Eq([]string{userKey, ipKey})).
+					Times(1).
+					Return(nil)
+				// the login is recorded once the second factor is checked
+				store.EXPECT().
+					RecordLoginAttemptTx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var resp map[string]any
+				err := json.Unmarshal(recorder.Body.Bytes(), &resp)
+				require.NoError(t, err)
+				require.Equal(t, true, resp["mfa_required"])
+				require.NotEmpty(t, resp["mfa_token"])
+				require.NotContains(t, resp, "access_token")
+				require.NotContains(t, resp, "user")
+			},
+		},
 		{
 			name: "BadRequestInvalidBody",
 			body: map[string]interface{}{
@@ -323,11 +505,13 @@ func TestLoginUserAPI(t *testing.T) {
 				"password": password,
 			},
 			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					StartLoginAttemptTx(gomock.Any(), gomock.Any()).
+					Times(0)
 				store.EXPECT().
 					GetUser(gomock.Any(), gomock.Any()).
 					Times(0)
 			},
-			setupToken: func(server *Server) {},
 			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
 				require.Equal(t, http.StatusBadRequest, recorder.Code)
 			},
@@ -335,19 +519,18 @@ func TestLoginUserAPI(t *testing.T) {
 		{
 			name: "UserNotFound",
 			body: map[string]interface{}{
-				"username": "notExisting",
+				"username": user.Username,
 				"password": password,
 			},
 			buildStubs: func(store *mockdb.MockStore) {
+				expectThrottles(store)
 				store.EXPECT().
-					GetUser(gomock.Any(), gomock.Eq("notExisting")).
+					GetUser(gomock.Any(), gomock.Eq(user.Username)).
 					Times(1).
-					Return(db.User{}, pgx.ErrNoRows)
-			},
-			setupToken: func(server *Server) {},
-			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
-				require.Equal(t, http.StatusNotFound, recorder.Code)
+					Return(db.User{}, db.ErrRecordNotFound)
+				expectAttempt(store, db.LoginUnknownUser)
 			},
+			checkResponse: requireInvalidCredentials,
 		},
 		{
 			name: "InternalServerError",
@@ -356,12 +539,15 @@ func TestLoginUserAPI(t *testing.T) {
 				"password": password,
 			},
 			buildStubs: func(store *mockdb.MockStore) {
+				expectThrottles(store)
 				store.EXPECT().
 					GetUser(gomock.Any(), gomock.Eq(user.Username)).
 					Times(1).
 					Return(db.User{}, pgx.ErrTxClosed)
+				store.EXPECT().
+					RecordLoginAttemptTx(gomock.Any(), gomock.Any()).
+					Times(0)
 			},
-			setupToken: func(server *Server) {},
 			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
 				require.Equal(t, http.StatusInternalServerError, recorder.Code)
 			},
@@ -373,14 +559,147 @@ func TestLoginUserAPI(t *testing.T) {
 				"password": "wrong_password",
 			},
 			buildStubs: func(store *mockdb.MockStore) {
+				expectThrottles(store)
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(user, nil)
+				expectAttempt(store, db.LoginWrongPassword)
+			},
+			checkResponse: requireInvalidCredentials,
+		},
+		{
+			// the password policy is not applied on login, so a short password is checked like any other
+			name: "UnauthorizedShortPassword",
+			body: map[string]interface{}{
+				"username": user.Username,
+				"password": "abc",
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				expectThrottles(store)
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(user, nil)
+				expectAttempt(store, db.LoginWrongPassword)
+			},
+			checkResponse: requireInvalidCredentials,
+		},
+		{
+			name: "Delayed",
+			body: map[string]interface{}{
+				"username": user.Username,
+				"password": password,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				expectThrottles(store, db.LoginThrottle{Key: userKey, Failures: loginFreeAttempts + 2, LastFailureAt: now})
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Any()).
+					Times(0)
+				expectAttempt(store, db.LoginThrottled)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusTooManyRequests, recorder.Code)
+				require.Equal(t, "2", recorder.Header().Get("Retry-After"))
+			},
+		},
+		{
+			name: "DelayPassed",
+			body: map[string]interface{}{
+				"username": user.Username,
+				"password": password,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				lastFailure := pgtype.Timestamptz{Time: now.Time.Add(-time.Minute), Valid: true}
+				expectThrottles(store, db.LoginThrottle{Key: userKey, Failures: loginFreeAttempts + 2, LastFailureAt: lastFailure})
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(user, nil)
+				expectAttempt(store, "")
+				expectCreateSession(t, store, user.Username)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name: "UserLockedOut",
+			body: map[string]interface{}{
+				"username": user.Username,
+				"password": password,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				expectThrottles(store, db.LoginThrottle{Key: userKey, Failures: defaultLoginLockoutThreshold, LastFailureAt: now})
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Any()).
+					Times(0)
+				expectAttempt(store, db.LoginThrottled)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusTooManyRequests, recorder.Code)
+				require.Equal(t, "900", recorder.Header().Get("Retry-After"))
+			},
+		},
+		{
+			name: "IPLockedOut",
+			body: map[string]interface{}{
+				"username": user.Username,
+				"password": password,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				// failures of an IP only lock it out past the IP threshold
+				expectThrottles(store,
+					db.LoginThrottle{Key: userKey, Failures: 1, LastFailureAt: now},
+					db.LoginThrottle{Key: ipKey, Failures: defaultLoginIPLockoutThreshold, LastFailureAt: now},
+				)
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Any()).
+					Times(0)
+				expectAttempt(store, db.LoginThrottled)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusTooManyRequests, recorder.Code)
+			},
+		},
+		{
+			name: "ThrottleError",
+			body: map[string]interface{}{
+				"username": user.Username,
+				"password": password,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					StartLoginAttemptTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(time.Time{}, pgx.ErrTxClosed)
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+		{
+			name: "RecordAttemptError",
+			body: map[string]interface{}{
+				"username": user.Username,
+				"password": "wrong_password",
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				expectThrottles(store)
 				store.EXPECT().
 					GetUser(gomock.Any(), gomock.Eq(user.Username)).
 					Times(1).
 					Return(user, nil)
+				store.EXPECT().
+					RecordLoginAttemptTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.LoginAttempt{}, pgx.ErrTxClosed)
 			},
-			setupToken: func(server *Server) {},
 			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
-				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
 			},
 		},
 	}
@@ -396,9 +715,6 @@ func TestLoginUserAPI(t *testing.T) {
 			tc.buildStubs(store)
 
 			server := newTestServer(t, store)
-			if tc.setupToken != nil {
-				tc.setupToken(server)
-			}
 			recorder := httptest.NewRecorder()
 
 			data, err := json.Marshal(tc.body)
@@ -408,6 +724,9 @@ func TestLoginUserAPI(t *testing.T) {
 			request, err := http.NewRequest("POST", url, bytes.NewBuffer(data))
 			require.NoError(t, err)
 			request.Header.Set("Content-Type", "application/json")
+			request.RemoteAddr = clientIP + ":4321"
+			// a client cannot choose the IP its logins are throttled by
+			request.Header.Set("X-Forwarded-For", "203.0.113.9")
 
 			server.router.ServeHTTP(recorder, request)
 			tc.checkResponse(t, recorder)
@@ -1,7 +1,10 @@
 package api
 
 import (
+	"slices"
+
 	"github.com/go-playground/validator/v10"
+	db "github.com/roman-adamchik/simplebank/db/sqlc"
 	"github.com/roman-adamchik/simplebank/util"
 )
 
@@ -12,3 +15,27 @@ var validCurrency validator.Func = func(fieldLevel validator.FieldLevel) bool {
 
 	return false
 }
+
+var validTier validator.Func = func(fieldLevel validator.FieldLevel) bool {
+	if tier, ok := fieldLevel.Field().Interface().(string); ok {
+		return util.IsSupportedTier(tier)
+	}
+
+	return false
+}
+
+var validWebhookEvent validator.Func = func(fieldLevel validator.FieldLevel) bool {
+	if eventType, ok := fieldLevel.Field().Interface().(string); ok {
+		return db.IsWebhookEventType(eventType)
+	}
+
+	return false
+}
+
+var validScope validator.Func = func(fieldLevel validator.FieldLevel) bool {
+	if scope, ok := fieldLevel.Field().Interface().(string); ok {
+		return slices.Contains(scopes, scope)
+	}
+
+	return false
+}
@@ -0,0 +1,111 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgtype"
+	db "github.com/roman-adamchik/simplebank/db/sqlc"
+	"github.com/roman-adamchik/simplebank/task"
+	"github.com/roman-adamchik/simplebank/token"
+	"github.com/roman-adamchik/simplebank/util"
+	"github.com/roman-adamchik/simplebank/worker"
+)
+
+const (
+	defaultVerifyEmailLimit = 3
+	// verifyEmailWindow is the period the number of verification links sent to a user is limited over
+	verifyEmailWindow = time.Hour
+)
+
+var errVerifyEmailThrottled = errors.New("too many verification emails requested, try again later")
+
+type verifyEmailRequest struct {
+	EmailID    int64  `form:"email_id" binding:"required,min=1"`
+	SecretCode string `form:"secret_code" binding:"required,min=32,max=128"`
+}
+
+type verifyEmailResponse struct {
+	IsVerified bool `json:"is_verified"`
+}
+
+// verifyEmail handles the link of a verification email
+func (server *Server) verifyEmail(ctx *gin.Context) {
+	var req verifyEmailRequest
+
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		respondError(ctx, http.StatusBadRequest, err)
+		return
+	}
+
+	result, err := server.store.VerifyEmailTx(ctx, db.VerifyEmailTxParams{
+		EmailID:        req.EmailID,
+		SecretCodeHash: util.HashSecret(req.SecretCode),
+	})
+	if err != nil {
+		if errors.Is(err, db.ErrInvalidVerifyEmail) {
+			respondError(ctx, http.StatusBadRequest, err)
+			return
+		}
+		respondError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, verifyEmailResponse{IsVerified: result.User.IsEmailVerified})
+}
+
+// resendVerifyEmail sends a new verification link to the authenticated user,
+// the links sent before stay valid until they expire. At most VERIFY_EMAIL_LIMIT links are sent to a user per hour
+func (server *Server) resendVerifyEmail(ctx *gin.Context) {
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	user, err := server.store.GetUser(ctx, authPayload.Username)
+	if err != nil {
+		if errors.Is(err, db.ErrRecordNotFound) {
+			respondError(ctx, http.StatusNotFound, err)
+			return
+		}
+		respondError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+	if user.IsEmailVerified {
+		err := fmt.Errorf("email of user %s is already verified", user.Username)
+		respondError(ctx, http.StatusConflict, err)
+		return
+	}
+
+	limit := server.config.VerifyEmailLimit
+	if limit <= 0 {
+		limit = defaultVerifyEmailLimit
+	}
+	sent, err := server.store.CountVerifyEmailsSince(ctx, db.CountVerifyEmailsSinceParams{
+		Username: user.Username,
+		Since:    pgtype.Timestamptz{Time: time.Now().Add(-verifyEmailWindow), Valid: true},
+	})
+	if err != nil {
+		respondError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+	if sent >= int64(limit) {
+		respondError(ctx, http.StatusTooManyRequests, errVerifyEmailThrottled)
+		return
+	}
+
+	if err := server.sendVerifyEmail(ctx, user); err != nil {
+		respondError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	ctx.JSON(http.StatusAccepted, gin.H{"message": fmt.Sprintf("verification email sent to %s", user.Email)})
+}
+
+// sendVerifyEmail enqueues the task sending a verification link to the user
+func (server *Server) sendVerifyEmail(ctx *gin.Context, user db.User) error {
+	return server.distributor.Enqueue(ctx, worker.TaskSendVerifyEmail,
+		worker.SendVerifyEmailPayload{Username: user.Username},
+		task.Queue(task.QueueCritical),
+	)
+}
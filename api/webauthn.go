@@ -0,0 +1,511 @@
+package api
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/webauthn"
+	"github.com/jackc/pgx/v5/pgtype"
+	db "github.com/roman-adamchik/simplebank/db/sqlc"
+	"github.com/roman-adamchik/simplebank/token"
+	"github.com/roman-adamchik/simplebank/util"
+)
+
+const (
+	defaultWebAuthnRPID          = "localhost"
+	defaultWebAuthnRPDisplayName = "Simple Bank"
+	// webAuthnChallengeTTL is how long a user has to answer a registration or login challenge
+	webAuthnChallengeTTL = 5 * time.Minute
+	// webAuthnUserHandleSize is the size of the random user handles stored on authenticators
+	webAuthnUserHandleSize = 32
+)
+
+// ceremonies a webauthn challenge is issued for
+const (
+	webAuthnRegistration = "registration"
+	webAuthnLogin        = "login"
+)
+
+var (
+	errInvalidWebAuthnChallenge = errors.New("challenge is unknown, already answered or expired")
+	errInvalidPasskey           = errors.New("passkey is unknown or its signature is invalid")
+	errClonedPasskey            = errors.New("signature counter of the passkey did not increase, it may have been cloned")
+)
+
+// newWebAuthn creates the relying party passkeys are registered with, attestations are not asked for
+// since the bank does not restrict which authenticators can be used
+func newWebAuthn(config util.Config) (*webauthn.WebAuthn, error) {
+	rpID := config.WebAuthnRPID
+	if rpID == "" {
+		rpID = defaultWebAuthnRPID
+	}
+	displayName := config.WebAuthnRPDisplayName
+	if displayName == "" {
+		displayName = defaultWebAuthnRPDisplayName
+	}
+	origins := config.WebAuthnRPOrigins
+	if len(origins) == 0 {
+		origins = []string{"https://" + rpID}
+	}
+
+	timeout := webauthn.TimeoutConfig{
+		Enforce:    true,
+		Timeout:    webAuthnChallengeTTL,
+		TimeoutUVD: webAuthnChallengeTTL,
+	}
+	return webauthn.New(&webauthn.Config{
+		RPID:                  rpID,
+		RPDisplayName:         displayName,
+		RPOrigins:             origins,
+		AttestationPreference: protocol.PreferNoAttestation,
+		Timeouts: webauthn.TimeoutsConfig{
+			Login:        timeout,
+			Registration: timeout,
+		},
+	})
+}
+
+// webAuthnUser is a user with their passkeys as seen by the webauthn library
+type webAuthnUser struct {
+	user        db.User
+	handle      []byte
+	credentials []db.WebauthnCredential
+}
+
+// newWebAuthnUser takes the user handle of the passkeys of a user, or a new random one for their first passkey
+func newWebAuthnUser(user db.User, credentials []db.WebauthnCredential) (webAuthnUser, error) {
+	if len(credentials) > 0 {
+		return webAuthnUser{user: user, handle: credentials[0].UserHandle, credentials: credentials}, nil
+	}
+
+	handle := make([]byte, webAuthnUserHandleSize)
+	if _, err := rand.Read(handle); err != nil {
+		return webAuthnUser{}, fmt.Errorf("cannot generate user handle: %w", err)
+	}
+	return webAuthnUser{user: user, handle: handle, credentials: credentials}, nil
+}
+
+func (u webAuthnUser) WebAuthnID() []byte {
+	return u.handle
+}
+
+func (u webAuthnUser) WebAuthnName() string {
+	return u.user.Username
+}
+
+func (u webAuthnUser) WebAuthnDisplayName() string {
+	return u.user.FullName
+}
+
+func (u webAuthnUser) WebAuthnCredentials() []webauthn.Credential {
+	credentials := make([]webauthn.Credential, len(u.credentials))
+	for i, credential := range u.credentials {
+		transports := make([]protocol.AuthenticatorTransport, len(credential.Transports))
+		for j, transport := range credential.Transports {
+			transports[j] = protocol.AuthenticatorTransport(transport)
+		}
+
+		credentials[i] = webauthn.Credential{
+			ID:              credential.CredentialID,
+			PublicKey:       credential.PublicKey,
+			AttestationType: credential.AttestationType,
+			Transport:       transports,
+			Flags: webauthn.CredentialFlags{
+				BackupEligible: credential.BackupEligible,
+				BackupState:    credential.BackupState,
+			},
+			Authenticator: webauthn.Authenticator{
+				AAGUID:    credential.Aaguid,
+				SignCount: uint32(credential.SignCount),
+			},
+		}
+	}
+	return credentials
+}
+
+// saveWebAuthnChallenge stores the session data of a ceremony until the challenge is answered.
+// It responds with an error and returns false if it cannot be stored
+func (server *Server) saveWebAuthnChallenge(ctx *gin.Context, ceremony string, username pgtype.Text, session *webauthn.SessionData) bool {
+	sessionData, err := json.Marshal(session)
+	if err != nil {
+		respondError(ctx, http.StatusInternalServerError, err)
+		return false
+	}
+
+	_, err = server.store.CreateWebAuthnChallenge(ctx, db.CreateWebAuthnChallengeParams{
+		Challenge:   session.Challenge,
+		Ceremony:    ceremony,
+		Username:    username,
+		SessionData: sessionData,
+		ExpiresAt:   pgtype.Timestamptz{Time: time.Now().Add(webAuthnChallengeTTL), Valid: true},
+	})
+	if err != nil {
+		respondError(ctx, http.StatusInternalServerError, err)
+		return false
+	}
+
+	return true
+}
+
+// useWebAuthnChallenge marks the challenge answered by a client as used and returns the session data of its ceremony.
+// It responds with an error and returns false if the challenge cannot be used
+func (server *Server) useWebAuthnChallenge(ctx *gin.Context, ceremony string, challenge string) (db.WebauthnChallenge, webauthn.SessionData, bool) {
+	var session webauthn.SessionData
+
+	stored, err := server.store.UseWebAuthnChallenge(ctx, db.UseWebAuthnChallengeParams{
+		Now:       pgtype.Timestamptz{Time: time.Now(), Valid: true},
+		Challenge: challenge,
+		Ceremony:  ceremony,
+	})
+	if err != nil {
+		if errors.Is(err, db.ErrRecordNotFound) {
+			respondError(ctx, http.StatusBadRequest, errInvalidWebAuthnChallenge)
+			return stored, session, false
+		}
+		respondError(ctx, http.StatusInternalServerError, err)
+		return stored, session, false
+	}
+
+	if err := json.Unmarshal(stored.SessionData, &session); err != nil {
+		respondError(ctx, http.StatusInternalServerError, err)
+		return stored, session, false
+	}
+
+	return stored, session, true
+}
+
+// beginWebAuthnRegistration starts the registration of a passkey for the authenticated user,
+// the options it returns are passed to navigator.credentials.create
+func (server *Server) beginWebAuthnRegistration(ctx *gin.Context) {
+	user, ok := server.getAuthUser(ctx)
+	if !ok {
+		return
+	}
+
+	credentials, err := server.store.ListWebAuthnCredentials(ctx, user.Username)
+	if err != nil {
+		respondError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+	waUser, err := newWebAuthnUser(user, credentials)
+	if err != nil {
+		respondError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	// passkeys must be discoverable, logins do not ask for a username
+	creation, session, err := server.webAuthn.BeginRegistration(
+		waUser,
+		webauthn.WithExclusions(webauthn.Credentials(waUser.WebAuthnCredentials()).CredentialDescriptors()),
+		webauthn.WithResidentKeyRequirement(protocol.ResidentKeyRequirementRequired),
+	)
+	if err != nil {
+		respondError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	username := pgtype.Text{String: user.Username, Valid: true}
+	if !server.saveWebAuthnChallenge(ctx, webAuthnRegistration, username, session) {
+		return
+	}
+
+	ctx.JSON(http.StatusOK, creation)
+}
+
+type finishWebAuthnRegistrationRequest struct {
+	Name string `json:"name" binding:"required,max=64"`
+	// Credential is the PublicKeyCredential returned by navigator.credentials.create
+	Credential json.RawMessage `json:"credential" binding:"required"`
+}
+
+// finishWebAuthnRegistration checks the answer of the authenticator to a registration challenge
+// and stores the new passkey
+func (server *Server) finishWebAuthnRegistration(ctx *gin.Context) {
+	var req finishWebAuthnRegistrationRequest
+
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		respondError(ctx, http.StatusBadRequest, err)
+		return
+	}
+
+	parsed, err := protocol.ParseCredentialCreationResponseBytes(req.Credential)
+	if err != nil {
+		respondError(ctx, http.StatusBadRequest, err)
+		return
+	}
+
+	challenge, session, ok := server.useWebAuthnChallenge(ctx, webAuthnRegistration, parsed.Response.CollectedClientData.Challenge)
+	if !ok {
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	if challenge.Username.String != authPayload.Username {
+		err := errors.New("challenge was issued to another user")
+		respondError(ctx, http.StatusForbidden, err)
+		return
+	}
+
+	user, ok := server.getAuthUser(ctx)
+	if !ok {
+		return
+	}
+
+	// the user handle was chosen when the challenge was issued
+	waUser := webAuthnUser{user: user, handle: session.UserID}
+	credential, err := server.webAuthn.CreateCredential(waUser, session, parsed)
+	if err != nil {
+		respondError(ctx, http.StatusBadRequest, err)
+		return
+	}
+
+	transports := make([]string, len(credential.Transport))
+	for i, transport := range credential.Transport {
+		transports[i] = string(transport)
+	}
+
+	stored, err := server.store.CreateWebAuthnCredential(ctx, db.CreateWebAuthnCredentialParams{
+		CredentialID:    credential.ID,
+		Username:        user.Username,
+		UserHandle:      session.UserID,
+		Name:            req.Name,
+		PublicKey:       credential.PublicKey,
+		AttestationType: credential.AttestationType,
+		Transports:      transports,
+		Aaguid:          credential.Authenticator.AAGUID,
+		SignCount:       int64(credential.Authenticator.SignCount),
+		BackupEligible:  credential.Flags.BackupEligible,
+		BackupState:     credential.Flags.BackupState,
+	})
+	if err != nil {
+		if errors.Is(err, db.ErrUniqueViolation) {
+			respondError(ctx, http.StatusForbidden, err)
+			return
+		}
+		respondError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, newWebAuthnCredentialResponse(stored))
+}
+
+type webAuthnCredentialResponse struct {
+	ID              int64              `json:"id"`
+	Name            string             `json:"name"`
+	AttestationType string             `json:"attestation_type"`
+	Transports      []string           `json:"transports"`
+	BackupEligible  bool               `json:"backup_eligible"`
+	BackupState     bool               `json:"backup_state"`
+	LastUsedAt      pgtype.Timestamptz `json:"last_used_at"`
+	CreatedAt       pgtype.Timestamptz `json:"created_at"`
+}
+
+func newWebAuthnCredentialResponse(credential db.WebauthnCredential) webAuthnCredentialResponse {
+	return webAuthnCredentialResponse{
+		ID:              credential.ID,
+		Name:            credential.Name,
+		AttestationType: credential.AttestationType,
+		Transports:      credential.Transports,
+		BackupEligible:  credential.BackupEligible,
+		BackupState:     credential.BackupState,
+		LastUsedAt:      credential.LastUsedAt,
+		CreatedAt:       credential.CreatedAt,
+	}
+}
+
+// listWebAuthnCredentials lists the passkeys of the authenticated user
+func (server *Server) listWebAuthnCredentials(ctx *gin.Context) {
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	credentials, err := server.store.ListWebAuthnCredentials(ctx, authPayload.Username)
+	if err != nil {
+		respondError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	rsp := make([]webAuthnCredentialResponse, len(credentials))
+	for i, credential := range credentials {
+		rsp[i] = newWebAuthnCredentialResponse(credential)
+	}
+	ctx.JSON(http.StatusOK, rsp)
+}
+
+type webAuthnCredentialURI struct {
+	ID int64 `uri:"id" binding:"required,min=1"`
+}
+
+// deleteWebAuthnCredential removes a passkey of the authenticated user, such as one of a lost device
+func (server *Server) deleteWebAuthnCredential(ctx *gin.Context) {
+	var uri webAuthnCredentialURI
+
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		respondError(ctx, http.StatusBadRequest, err)
+		return
+	}
+
+	credential, err := server.store.GetWebAuthnCredential(ctx, uri.ID)
+	if err != nil {
+		if errors.Is(err, db.ErrRecordNotFound) {
+			respondError(ctx, http.StatusNotFound, err)
+			return
+		}
+		respondError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	if credential.Username != authPayload.Username {
+		err := fmt.Errorf("passkey [%d] doesn't belong to the authenticated user", credential.ID)
+		respondError(ctx, http.StatusForbidden, err)
+		return
+	}
+
+	if err := server.store.DeleteWebAuthnCredential(ctx, credential.ID); err != nil {
+		respondError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, newWebAuthnCredentialResponse(credential))
+}
+
+// beginWebAuthnLogin starts a passkey login, the options it returns are passed to navigator.credentials.get.
+// No username is asked for, the passkey the user picks names them
+func (server *Server) beginWebAuthnLogin(ctx *gin.Context) {
+	assertion, session, err := server.webAuthn.BeginDiscoverableLogin(
+		webauthn.WithUserVerification(protocol.VerificationRequired),
+	)
+	if err != nil {
+		respondError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	if !server.saveWebAuthnChallenge(ctx, webAuthnLogin, pgtype.Text{}, session) {
+		return
+	}
+
+	ctx.JSON(http.StatusOK, assertion)
+}
+
+type finishWebAuthnLoginRequest struct {
+	// Credential is the PublicKeyCredential returned by navigator.credentials.get
+	Credential json.RawMessage `json:"credential" binding:"required"`
+}
+
+// finishWebAuthnLogin checks the answer of the authenticator to a login challenge and logs the user in
+// as loginUser does. The passkey verifies the user on its own, so no second factor is asked for
+func (server *Server) finishWebAuthnLogin(ctx *gin.Context) {
+	var req finishWebAuthnLoginRequest
+
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		respondError(ctx, http.StatusBadRequest, err)
+		return
+	}
+
+	parsed, err := protocol.ParseCredentialRequestResponseBytes(req.Credential)
+	if err != nil {
+		respondError(ctx, http.StatusBadRequest, err)
+		return
+	}
+
+	_, session, ok := server.useWebAuthnChallenge(ctx, webAuthnLogin, parsed.Response.CollectedClientData.Challenge)
+	if !ok {
+		return
+	}
+
+	stored, err := server.store.GetWebAuthnCredentialByCredentialID(ctx, parsed.RawID)
+	if err != nil {
+		if errors.Is(err, db.ErrRecordNotFound) {
+			respondError(ctx, http.StatusUnauthorized, errInvalidPasskey)
+			return
+		}
+		respondError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	now := time.Now()
+	blockedUntil, err := server.startLoginAttempt(ctx, stored.Username, now)
+	if err != nil {
+		respondError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+	if !blockedUntil.IsZero() {
+		if err := server.recordLoginAttempt(ctx, stored.Username, db.LoginThrottled); err != nil {
+			respondError(ctx, http.StatusInternalServerError, err)
+			return
+		}
+		throttledLoginResponse(ctx, blockedUntil, now)
+		return
+	}
+
+	user, err := server.store.GetUser(ctx, stored.Username)
+	if err != nil {
+		respondError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+	credentials, err := server.store.ListWebAuthnCredentials(ctx, user.Username)
+	if err != nil {
+		respondError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	waUser := webAuthnUser{user: user, handle: stored.UserHandle, credentials: credentials}
+	findUser := func(rawID, userHandle []byte) (webauthn.User, error) {
+		return waUser, nil
+	}
+
+	failureReason := ""
+	loginErr := errInvalidPasskey
+	_, credential, err := server.webAuthn.ValidatePasskeyLogin(findUser, session, parsed)
+	if err != nil {
+		failureReason = db.LoginInvalidPasskey
+	} else if credential.Authenticator.CloneWarning {
+		failureReason = db.LoginInvalidPasskey
+		loginErr = errClonedPasskey
+	} else if user.DeactivatedAt.Valid {
+		failureReason = db.LoginDeactivated
+	}
+
+	if err := server.recordLoginAttempt(ctx, user.Username, failureReason); err != nil {
+		respondError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+	if failureReason == db.LoginDeactivated {
+		respondError(ctx, http.StatusForbidden, db.ErrUserDeactivated)
+		return
+	}
+	if failureReason != "" {
+		respondError(ctx, http.StatusUnauthorized, loginErr)
+		return
+	}
+
+	_, err = server.store.UpdateWebAuthnCredentialUse(ctx, db.UpdateWebAuthnCredentialUseParams{
+		SignCount:   int64(credential.Authenticator.SignCount),
+		BackupState: credential.Flags.BackupState,
+		LastUsedAt:  pgtype.Timestamptz{Time: now, Valid: true},
+		ID:          stored.ID,
+	})
+	if err != nil {
+		respondError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	accessToken, loginSession, err := server.createSession(ctx, user)
+	if err != nil {
+		respondError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	rsp := loginUserResponse{
+		AccessToken: accessToken,
+		SessionID:   loginSession.ID,
+		User:        newUserResponse(user),
+	}
+
+	ctx.JSON(http.StatusOK, rsp)
+}
@@ -0,0 +1,833 @@
+package api
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/protocol/webauthncbor"
+	"github.com/go-webauthn/webauthn/protocol/webauthncose"
+	"github.com/go-webauthn/webauthn/webauthn"
+	"github.com/jackc/pgx/v5/pgtype"
+	mockdb "github.com/roman-adamchik/simplebank/db/mock"
+	db "github.com/roman-adamchik/simplebank/db/sqlc"
+	"github.com/roman-adamchik/simplebank/util"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+// the relying party of test servers, which use the default config
+const (
+	testWebAuthnRPID   = defaultWebAuthnRPID
+	testWebAuthnOrigin = "https://" + defaultWebAuthnRPID
+)
+
+// authenticator data flags, see https://www.w3.org/TR/webauthn/#sctn-authenticator-data
+const (
+	authDataUserPresent  = 0x01
+	authDataUserVerified = 0x04
+	authDataAttested     = 0x40
+)
+
+// softAuthenticator is an authenticator in software with an ES256 passkey,
+// it answers registration challenges with attestations of format none
+type softAuthenticator struct {
+	t            *testing.T
+	origin       string
+	credentialID []byte
+	userHandle   []byte
+	key          *ecdsa.PrivateKey
+	signCount    uint32
+}
+
+func newSoftAuthenticator(t *testing.T) *softAuthenticator {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	return &softAuthenticator{
+		t:            t,
+		origin:       testWebAuthnOrigin,
+		credentialID: []byte(util.RandomString(16)),
+		key:          key,
+	}
+}
+
+// publicKey is the COSE encoding of the public key of the passkey
+func (a *softAuthenticator) publicKey() []byte {
+	point, err := a.key.PublicKey.Bytes()
+	require.NoError(a.t, err)
+
+	publicKey, err := webauthncbor.Marshal(webauthncose.EC2PublicKeyData{
+		PublicKeyData: webauthncose.PublicKeyData{
+			KeyType:   int64(webauthncose.EllipticKey),
+			Algorithm: int64(webauthncose.AlgES256),
+		},
+		Curve:  int64(webauthncose.P256),
+		XCoord: point[1:33],
+		YCoord: point[33:],
+	})
+	require.NoError(a.t, err)
+	return publicKey
+}
+
+// credential is the passkey as the server stores it once registered
+func (a *softAuthenticator) credential(username string) db.WebauthnCredential {
+	return db.WebauthnCredential{
+		ID:              util.RandomInt(1, 1000),
+		CredentialID:    a.credentialID,
+		Username:        username,
+		UserHandle:      a.userHandle,
+		Name:            "laptop",
+		PublicKey:       a.publicKey(),
+		AttestationType: "none",
+		Transports:      []string{"internal"},
+		Aaguid:          make([]byte, 16),
+		SignCount:       int64(a.signCount),
+		CreatedAt:       pgtype.Timestamptz{Time: time.Now(), Valid: true},
+	}
+}
+
+func (a *softAuthenticator) authData(attested bool) []byte {
+	rpIDHash := sha256.Sum256([]byte(testWebAuthnRPID))
+
+	var data bytes.Buffer
+	data.Write(rpIDHash[:])
+	flags := byte(authDataUserPresent | authDataUserVerified)
+	if attested {
+		flags |= authDataAttested
+	}
+	data.WriteByte(flags)
+	data.Write(binary.BigEndian.AppendUint32(nil, a.signCount))
+
+	if attested {
+		data.Write(make([]byte, 16)) // AAGUID
+		data.Write(binary.BigEndian.AppendUint16(nil, uint16(len(a.credentialID))))
+		data.Write(a.credentialID)
+		data.Write(a.publicKey())
+	}
+	return data.Bytes()
+}
+
+func (a *softAuthenticator) clientData(ceremony string, challenge protocol.URLEncodedBase64) []byte {
+	clientData, err := json.Marshal(map[string]any{
+		"type":      ceremony,
+		"challenge": challenge.String(),
+		"origin":    a.origin,
+	})
+	require.NoError(a.t, err)
+	return clientData
+}
+
+// create answers a registration challenge like navigator.credentials.create
+func (a *softAuthenticator) create(creation protocol.PublicKeyCredentialCreationOptions) json.RawMessage {
+	// the user handle is a string once the options went through JSON
+	switch userID := creation.User.ID.(type) {
+	case protocol.URLEncodedBase64:
+		a.userHandle = userID
+	case string:
+		userHandle, err := base64.RawURLEncoding.DecodeString(userID)
+		require.NoError(a.t, err)
+		a.userHandle = userHandle
+	default:
+		a.t.Fatalf("unexpected user handle %v", userID)
+	}
+
+	attestationObject, err := webauthncbor.Marshal(map[string]any{
+		"fmt":      "none",
+		"attStmt":  map[string]any{},
+		"authData": a.authData(true),
+	})
+	require.NoError(a.t, err)
+
+	return a.marshal(map[string]any{
+		"clientDataJSON":    encodeBase64URL(a.clientData("webauthn.create", creation.Challenge)),
+		"attestationObject": encodeBase64URL(attestationObject),
+		"transports":        []string{"internal"},
+	})
+}
+
+// get answers a login challenge like navigator.credentials.get, signing it with the passkey
+func (a *softAuthenticator) get(assertion protocol.PublicKeyCredentialRequestOptions) json.RawMessage {
+	a.signCount++
+	authData := a.authData(false)
+	clientData := a.clientData("webauthn.get", assertion.Challenge)
+
+	clientDataHash := sha256.Sum256(clientData)
+	digest := sha256.Sum256(append(authData, clientDataHash[:]...))
+	signature, err := ecdsa.SignASN1(rand.Reader, a.key, digest[:])
+	require.NoError(a.t, err)
+
+	return a.marshal(map[string]any{
+		"clientDataJSON":    encodeBase64URL(clientData),
+		"authenticatorData": encodeBase64URL(authData),
+		"signature":         encodeBase64URL(signature),
+		"userHandle":        encodeBase64URL(a.userHandle),
+	})
+}
+
+func (a *softAuthenticator) marshal(response map[string]any) json.RawMessage {
+	credential, err := json.Marshal(map[string]any{
+		"id":                      encodeBase64URL(a.credentialID),
+		"rawId":                   encodeBase64URL(a.credentialID),
+		"type":                    "public-key",
+		"authenticatorAttachment": "platform",
+		"response":                response,
+	})
+	require.NoError(a.t, err)
+	return credential
+}
+
+func encodeBase64URL(data []byte) string {
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// webAuthnChallenge stores the session data of a ceremony as the server does
+func webAuthnChallenge(t *testing.T, ceremony string, username string, session *webauthn.SessionData) db.WebauthnChallenge {
+	t.Helper()
+
+	sessionData, err := json.Marshal(session)
+	require.NoError(t, err)
+
+	return db.WebauthnChallenge{
+		Challenge:   session.Challenge,
+		Ceremony:    ceremony,
+		Username:    pgtype.Text{String: username, Valid: username != ""},
+		SessionData: sessionData,
+		ExpiresAt:   pgtype.Timestamptz{Time: time.Now().Add(webAuthnChallengeTTL), Valid: true},
+	}
+}
+
+func serveJSON(t *testing.T, server *Server, method string, url string, body any, setupAuth func(request *http.Request)) *httptest.ResponseRecorder {
+	t.Helper()
+
+	data, err := json.Marshal(body)
+	require.NoError(t, err)
+
+	request, err := http.NewRequest(method, url, bytes.NewReader(data))
+	require.NoError(t, err)
+	if setupAuth != nil {
+		setupAuth(request)
+	}
+
+	recorder := httptest.NewRecorder()
+	server.router.ServeHTTP(recorder, request)
+	return recorder
+}
+
+func TestWebAuthnPasskeyFlow(t *testing.T) {
+	user, _ := getRandomUser(t)
+	authenticator := newSoftAuthenticator(t)
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	server := newTestServer(t, store)
+	authorize := func(request *http.Request) {
+		addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
+	}
+
+	// the store keeps the challenges and the passkey, as the db would
+	challenges := map[string]db.WebauthnChallenge{}
+	credentials := []db.WebauthnCredential{}
+	store.EXPECT().
+		CreateWebAuthnChallenge(gomock.Any(), gomock.Any()).
+		AnyTimes().
+		DoAndReturn(func(_ any, arg db.CreateWebAuthnChallengeParams) (db.WebauthnChallenge, error) {
+			challenge := db.WebauthnChallenge{
+				Challenge:   arg.Challenge,
+				Ceremony:    arg.Ceremony,
+				Username:    arg.Username,
+				SessionData: arg.SessionData,
+				ExpiresAt:   arg.ExpiresAt,
+			}
+			challenges[arg.Challenge] = challenge
+			return challenge, nil
+		})
+	store.EXPECT().
+		UseWebAuthnChallenge(gomock.Any(), gomock.Any()).
+		AnyTimes().
+		DoAndReturn(func(_ any, arg db.UseWebAuthnChallengeParams) (db.WebauthnChallenge, error) {
+			challenge, ok := challenges[arg.Challenge]
+			if !ok || challenge.Ceremony != arg.Ceremony {
+				return db.WebauthnChallenge{}, db.ErrRecordNotFound
+			}
+			delete(challenges, arg.Challenge)
+			return challenge, nil
+		})
+	store.EXPECT().
+		GetUser(gomock.Any(), gomock.Eq(user.Username)).
+		AnyTimes().
+		Return(user, nil)
+	store.EXPECT().
+		ListWebAuthnCredentials(gomock.Any(), gomock.Eq(user.Username)).
+		AnyTimes().
+		DoAndReturn(func(_ any, _ string) ([]db.WebauthnCredential, error) {
+			return credentials, nil
+		})
+	store.EXPECT().
+		CreateWebAuthnCredential(gomock.Any(), gomock.Any()).
+		Times(1).
+		DoAndReturn(func(_ any, arg db.CreateWebAuthnCredentialParams) (db.WebauthnCredential, error) {
+			credential := db.WebauthnCredential{
+				ID:              1,
+				CredentialID:    arg.CredentialID,
+				Username:        arg.Username,
+				UserHandle:      arg.UserHandle,
+				Name:            arg.Name,
+				PublicKey:       arg.PublicKey,
+				AttestationType: arg.AttestationType,
+				Transports:      arg.Transports,
+				Aaguid:          arg.Aaguid,
+				SignCount:       arg.SignCount,
+			}
+			credentials = append(credentials, credential)
+			return credential, nil
+		})
+	store.EXPECT().
+		GetWebAuthnCredentialByCredentialID(gomock.Any(), gomock.Eq(authenticator.credentialID)).
+		Times(2).
+		DoAndReturn(func(_ any, _ []byte) (db.WebauthnCredential, error) {
+			return credentials[0], nil
+		})
+	store.EXPECT().
+		StartLoginAttemptTx(gomock.Any(), gomock.Any()).
+		Times(2).
+		Return(time.Time{}, nil)
+	store.EXPECT().
+		RecordLoginAttemptTx(gomock.Any(), gomock.Any()).
+		Times(2).
+		DoAndReturn(func(_ any, arg db.RecordLoginAttemptTxParams) (db.LoginAttempt, error) {
+			require.Equal(t, user.Username, arg.Username)
+			require.Empty(t, arg.FailureReason)
+			return db.LoginAttempt{}, nil
+		})
+	store.EXPECT().
+		UpdateWebAuthnCredentialUse(gomock.Any(), gomock.Any()).
+		Times(2).
+		DoAndReturn(func(_ any, arg db.UpdateWebAuthnCredentialUseParams) (db.WebauthnCredential, error) {
+			require.Equal(t, int64(authenticator.signCount), arg.SignCount)
+			credentials[0].SignCount = arg.SignCount
+			credentials[0].LastUsedAt = arg.LastUsedAt
+			return credentials[0], nil
+		})
+	store.EXPECT().
+		CreateSession(gomock.Any(), gomock.Any()).
+		Times(2).
+		DoAndReturn(func(_ any, arg db.CreateSessionParams) (db.Session, error) {
+			return randomSession(arg.Username), nil
+		})
+
+	// register the passkey
+	recorder := serveJSON(t, server, http.MethodPost, "/users/webauthn/credentials", nil, authorize)
+	require.Equal(t, http.StatusOK, recorder.Code)
+	var creation protocol.CredentialCreation
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &creation))
+	require.Equal(t, testWebAuthnRPID, creation.Response.RelyingParty.ID)
+	require.Equal(t, protocol.PreferNoAttestation, creation.Response.Attestation)
+
+	recorder = serveJSON(t, server, http.MethodPost, "/users/webauthn/credentials/finish", gin.H{
+		"name":       "laptop",
+		"credential": authenticator.create(creation.Response),
+	}, authorize)
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.Len(t, credentials, 1)
+	require.Equal(t, authenticator.credentialID, credentials[0].CredentialID)
+	require.Equal(t, authenticator.userHandle, credentials[0].UserHandle)
+	require.Equal(t, "none", credentials[0].AttestationType)
+
+	// log in with it twice, the signature counter going up
+	for range 2 {
+		recorder = serveJSON(t, server, http.MethodPost, "/users/login/webauthn", nil, nil)
+		require.Equal(t, http.StatusOK, recorder.Code)
+		var assertion protocol.CredentialAssertion
+		require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &assertion))
+		require.Empty(t, assertion.Response.AllowedCredentials)
+		require.Equal(t, protocol.VerificationRequired, assertion.Response.UserVerification)
+
+		recorder = serveJSON(t, server, http.MethodPost, "/users/login/webauthn/finish", gin.H{
+			"credential": authenticator.get(assertion.Response),
+		}, nil)
+		require.Equal(t, http.StatusOK, recorder.Code)
+
+		var rsp loginUserResponse
+		require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &rsp))
+		payload, err := server.tokenMaker.VerifyToken(rsp.AccessToken, server.tokenOptions(server.tokenAudience()))
+		require.NoError(t, err)
+		require.Equal(t, user.Username, payload.Username)
+		require.Equal(t, rsp.SessionID, payload.SessionID)
+	}
+	require.Equal(t, int64(2), credentials[0].SignCount)
+	require.Empty(t, challenges)
+}
+
+func TestFinishWebAuthnRegistrationAPI(t *testing.T) {
+	user, _ := getRandomUser(t)
+	otherUser, _ := getRandomUser(t)
+
+	relyingParty, err := newWebAuthn(util.Config{})
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name          string
+		username      string
+		origin        string
+		buildStubs    func(store *mockdb.MockStore, challenge db.WebauthnChallenge)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:     "OK",
+			username: user.Username,
+			origin:   testWebAuthnOrigin,
+			buildStubs: func(store *mockdb.MockStore, challenge db.WebauthnChallenge) {
+				store.EXPECT().
+					UseWebAuthnChallenge(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ any, arg db.UseWebAuthnChallengeParams) (db.WebauthnChallenge, error) {
+						require.Equal(t, challenge.Challenge, arg.Challenge)
+						require.Equal(t, webAuthnRegistration, arg.Ceremony)
+						return challenge, nil
+					})
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(user, nil)
+				store.EXPECT().
+					CreateWebAuthnCredential(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ any, arg db.CreateWebAuthnCredentialParams) (db.WebauthnCredential, error) {
+						require.Equal(t, user.Username, arg.Username)
+						require.Equal(t, "laptop", arg.Name)
+						require.Equal(t, "none", arg.AttestationType)
+						require.Equal(t, []string{"internal"}, arg.Transports)
+						return db.WebauthnCredential{ID: 1, Name: arg.Name, AttestationType: arg.AttestationType}, nil
+					})
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name:     "ChallengeUsed",
+			username: user.Username,
+			origin:   testWebAuthnOrigin,
+			buildStubs: func(store *mockdb.MockStore, challenge db.WebauthnChallenge) {
+				store.EXPECT().
+					UseWebAuthnChallenge(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.WebauthnChallenge{}, db.ErrRecordNotFound)
+				store.EXPECT().
+					CreateWebAuthnCredential(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+				requireProblem(t, recorder, "invalid_webauthn_challenge")
+			},
+		},
+		{
+			name:     "OtherUser",
+			username: otherUser.Username,
+			origin:   testWebAuthnOrigin,
+			buildStubs: func(store *mockdb.MockStore, challenge db.WebauthnChallenge) {
+				store.EXPECT().
+					UseWebAuthnChallenge(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(challenge, nil)
+				store.EXPECT().
+					CreateWebAuthnCredential(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name:     "WrongOrigin",
+			username: user.Username,
+			origin:   "https://evil.example",
+			buildStubs: func(store *mockdb.MockStore, challenge db.WebauthnChallenge) {
+				store.EXPECT().
+					UseWebAuthnChallenge(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(challenge, nil)
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(user, nil)
+				store.EXPECT().
+					CreateWebAuthnCredential(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			waUser, err := newWebAuthnUser(user, nil)
+			require.NoError(t, err)
+			creation, session, err := relyingParty.BeginRegistration(waUser)
+			require.NoError(t, err)
+
+			authenticator := newSoftAuthenticator(t)
+			authenticator.origin = tc.origin
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store, webAuthnChallenge(t, webAuthnRegistration, user.Username, session))
+
+			server := newTestServer(t, store)
+			recorder := serveJSON(t, server, http.MethodPost, "/users/webauthn/credentials/finish", gin.H{
+				"name":       "laptop",
+				"credential": authenticator.create(creation.Response),
+			}, func(request *http.Request) {
+				addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, tc.username, time.Minute)
+			})
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestFinishWebAuthnLoginAPI(t *testing.T) {
+	user, _ := getRandomUser(t)
+	clientIP := "192.0.2.1"
+
+	relyingParty, err := newWebAuthn(util.Config{})
+	require.NoError(t, err)
+
+	expectChallenge := func(store *mockdb.MockStore, challenge db.WebauthnChallenge) {
+		store.EXPECT().
+			UseWebAuthnChallenge(gomock.Any(), gomock.Any()).
+			Times(1).
+			DoAndReturn(func(_ any, arg db.UseWebAuthnChallengeParams) (db.WebauthnChallenge, error) {
+				require.Equal(t, challenge.Challenge, arg.Challenge)
+				require.Equal(t, webAuthnLogin, arg.Ceremony)
+				return challenge, nil
+			})
+	}
+	expectCredential := func(store *mockdb.MockStore, user db.User, credential db.WebauthnCredential) {
+		store.EXPECT().
+			GetWebAuthnCredentialByCredentialID(gomock.Any(), gomock.Eq(credential.CredentialID)).
+			Times(1).
+			Return(credential, nil)
+		store.EXPECT().
+			StartLoginAttemptTx(gomock.Any(), gomock.Any()).
+			Times(1).
+			Return(time.Time{}, nil)
+		store.EXPECT().
+			GetUser(gomock.Any(), gomock.Eq(user.Username)).
+			Times(1).
+			Return(user, nil)
+		store.EXPECT().
+			ListWebAuthnCredentials(gomock.Any(), gomock.Eq(user.Username)).
+			Times(1).
+			Return([]db.WebauthnCredential{credential}, nil)
+	}
+	expectAttempt := func(store *mockdb.MockStore, failureReason string) {
+		store.EXPECT().
+			RecordLoginAttemptTx(gomock.Any(), gomock.Any()).
+			Times(1).
+			DoAndReturn(func(_ any, arg db.RecordLoginAttemptTxParams) (db.LoginAttempt, error) {
+				require.Equal(t, user.Username, arg.Username)
+				require.Equal(t, clientIP, arg.ClientIP)
+				require.Equal(t, failureReason, arg.FailureReason)
+				return db.LoginAttempt{}, nil
+			})
+	}
+	expectNoLogin := func(store *mockdb.MockStore) {
+		store.EXPECT().
+			UpdateWebAuthnCredentialUse(gomock.Any(), gomock.Any()).
+			Times(0)
+		store.EXPECT().
+			CreateSession(gomock.Any(), gomock.Any()).
+			Times(0)
+	}
+
+	testCases := []struct {
+		name string
+		// setup changes the registered passkey or the authenticator answering the challenge
+		setup         func(authenticator *softAuthenticator, credential *db.WebauthnCredential, user *db.User)
+		buildStubs    func(store *mockdb.MockStore, challenge db.WebauthnChallenge, credential db.WebauthnCredential, user db.User)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:  "OK",
+			setup: func(authenticator *softAuthenticator, credential *db.WebauthnCredential, user *db.User) {},
+			buildStubs: func(store *mockdb.MockStore, challenge db.WebauthnChallenge, credential db.WebauthnCredential, user db.User) {
+				expectChallenge(store, challenge)
+				expectCredential(store, user, credential)
+				expectAttempt(store, "")
+				store.EXPECT().
+					UpdateWebAuthnCredentialUse(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ any, arg db.UpdateWebAuthnCredentialUseParams) (db.WebauthnCredential, error) {
+						require.Equal(t, credential.ID, arg.ID)
+						require.Equal(t, credential.SignCount+1, arg.SignCount)
+						require.True(t, arg.LastUsedAt.Valid)
+						return credential, nil
+					})
+				expectCreateSession(t, store, user.Username)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name: "UnknownPasskey",
+			setup: func(authenticator *softAuthenticator, credential *db.WebauthnCredential, user *db.User) {
+				authenticator.credentialID = []byte(util.RandomString(16))
+			},
+			buildStubs: func(store *mockdb.MockStore, challenge db.WebauthnChallenge, credential db.WebauthnCredential, user db.User) {
+				expectChallenge(store, challenge)
+				store.EXPECT().
+					GetWebAuthnCredentialByCredentialID(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.WebauthnCredential{}, db.ErrRecordNotFound)
+				store.EXPECT().
+					RecordLoginAttemptTx(gomock.Any(), gomock.Any()).
+					Times(0)
+				expectNoLogin(store)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+				requireProblem(t, recorder, "invalid_passkey")
+			},
+		},
+		{
+			name: "WrongKey",
+			setup: func(authenticator *softAuthenticator, credential *db.WebauthnCredential, user *db.User) {
+				other := newSoftAuthenticator(t)
+				authenticator.key = other.key
+			},
+			buildStubs: func(store *mockdb.MockStore, challenge db.WebauthnChallenge, credential db.WebauthnCredential, user db.User) {
+				expectChallenge(store, challenge)
+				expectCredential(store, user, credential)
+				expectAttempt(store, db.LoginInvalidPasskey)
+				expectNoLogin(store)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+				requireProblem(t, recorder, "invalid_passkey")
+			},
+		},
+		{
+			name: "WrongUserHandle",
+			setup: func(authenticator *softAuthenticator, credential *db.WebauthnCredential, user *db.User) {
+				credential.UserHandle = []byte(util.RandomString(32))
+			},
+			buildStubs: func(store *mockdb.MockStore, challenge db.WebauthnChallenge, credential db.WebauthnCredential, user db.User) {
+				expectChallenge(store, challenge)
+				expectCredential(store, user, credential)
+				expectAttempt(store, db.LoginInvalidPasskey)
+				expectNoLogin(store)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "ClonedPasskey",
+			setup: func(authenticator *softAuthenticator, credential *db.WebauthnCredential, user *db.User) {
+				credential.SignCount = 10
+			},
+			buildStubs: func(store *mockdb.MockStore, challenge db.WebauthnChallenge, credential db.WebauthnCredential, user db.User) {
+				expectChallenge(store, challenge)
+				expectCredential(store, user, credential)
+				expectAttempt(store, db.LoginInvalidPasskey)
+				expectNoLogin(store)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+				requireProblem(t, recorder, "cloned_passkey")
+			},
+		},
+		{
+			name: "Deactivated",
+			setup: func(authenticator *softAuthenticator, credential *db.WebauthnCredential, user *db.User) {
+				user.DeactivatedAt = pgtype.Timestamptz{Time: time.Now(), Valid: true}
+			},
+			buildStubs: func(store *mockdb.MockStore, challenge db.WebauthnChallenge, credential db.WebauthnCredential, user db.User) {
+				expectChallenge(store, challenge)
+				expectCredential(store, user, credential)
+				expectAttempt(store, db.LoginDeactivated)
+				expectNoLogin(store)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name:  "Throttled",
+			setup: func(authenticator *softAuthenticator, credential *db.WebauthnCredential, user *db.User) {},
+			buildStubs: func(store *mockdb.MockStore, challenge db.WebauthnChallenge, credential db.WebauthnCredential, user db.User) {
+				expectChallenge(store, challenge)
+				store.EXPECT().
+					GetWebAuthnCredentialByCredentialID(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(credential, nil)
+				store.EXPECT().
+					StartLoginAttemptTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ any, arg db.StartLoginAttemptTxParams) (time.Time, error) {
+						require.Equal(t, user.Username, arg.Username)
+						return arg.BlockedUntil([]db.LoginThrottle{{
+							Key:           db.UserThrottleKey(user.Username),
+							Failures:      defaultLoginLockoutThreshold,
+							LastFailureAt: pgtype.Timestamptz{Time: time.Now(), Valid: true},
+						}}), nil
+					})
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Any()).
+					Times(0)
+				expectAttempt(store, db.LoginThrottled)
+				expectNoLogin(store)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusTooManyRequests, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			authenticator := newSoftAuthenticator(t)
+			authenticator.userHandle = []byte(util.RandomString(32))
+			authenticator.signCount = 3
+			credential := authenticator.credential(user.Username)
+			user := user
+			tc.setup(authenticator, &credential, &user)
+
+			assertion, session, err := relyingParty.BeginDiscoverableLogin(
+				webauthn.WithUserVerification(protocol.VerificationRequired),
+			)
+			require.NoError(t, err)
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store, webAuthnChallenge(t, webAuthnLogin, "", session), credential, user)
+
+			server := newTestServer(t, store)
+			data, err := json.Marshal(gin.H{"credential": authenticator.get(assertion.Response)})
+			require.NoError(t, err)
+
+			request, err := http.NewRequest(http.MethodPost, "/users/login/webauthn/finish", bytes.NewReader(data))
+			require.NoError(t, err)
+			request.RemoteAddr = clientIP + ":4321"
+
+			recorder := httptest.NewRecorder()
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestDeleteWebAuthnCredentialAPI(t *testing.T) {
+	user, _ := getRandomUser(t)
+	otherUser, _ := getRandomUser(t)
+	credential := newSoftAuthenticator(t).credential(user.Username)
+
+	testCases := []struct {
+		name          string
+		username      string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:     "OK",
+			username: user.Username,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetWebAuthnCredential(gomock.Any(), gomock.Eq(credential.ID)).
+					Times(1).
+					Return(credential, nil)
+				store.EXPECT().
+					DeleteWebAuthnCredential(gomock.Any(), gomock.Eq(credential.ID)).
+					Times(1).
+					Return(nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name:     "NotFound",
+			username: user.Username,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetWebAuthnCredential(gomock.Any(), gomock.Eq(credential.ID)).
+					Times(1).
+					Return(db.WebauthnCredential{}, db.ErrRecordNotFound)
+				store.EXPECT().
+					DeleteWebAuthnCredential(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name:     "OtherUser",
+			username: otherUser.Username,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetWebAuthnCredential(gomock.Any(), gomock.Eq(credential.ID)).
+					Times(1).
+					Return(credential, nil)
+				store.EXPECT().
+					DeleteWebAuthnCredential(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			url := fmt.Sprintf("/users/webauthn/credentials/%d", credential.ID)
+			request, err := http.NewRequest(http.MethodDelete, url, nil)
+			require.NoError(t, err)
+
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, tc.username, time.Minute)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
@@ -0,0 +1,251 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgtype"
+	db "github.com/roman-adamchik/simplebank/db/sqlc"
+	"github.com/roman-adamchik/simplebank/token"
+	"github.com/roman-adamchik/simplebank/webhook"
+)
+
+type webhookSubscriptionResponse struct {
+	ID           int64              `json:"id"`
+	Owner        string             `json:"owner"`
+	URL          string             `json:"url"`
+	EventTypes   []string           `json:"event_types"`
+	FailureCount int32              `json:"failure_count"`
+	DisabledAt   pgtype.Timestamptz `json:"disabled_at"`
+	CreatedAt    pgtype.Timestamptz `json:"created_at"`
+}
+
+func newWebhookSubscriptionResponse(subscription db.WebhookSubscription) webhookSubscriptionResponse {
+	return webhookSubscriptionResponse{
+		ID:           subscription.ID,
+		Owner:        subscription.Owner,
+		URL:          subscription.Url,
+		EventTypes:   subscription.EventTypes,
+		FailureCount: subscription.FailureCount,
+		DisabledAt:   subscription.DisabledAt,
+		CreatedAt:    subscription.CreatedAt,
+	}
+}
+
+type createWebhookSubscriptionRequest struct {
+	URL        string   `json:"url" binding:"required,http_url,max=2048"`
+	EventTypes []string `json:"event_types" binding:"required,min=1,dive,webhook_event"`
+	// Secret signs the deliveries, a random one is generated if it is not given
+	Secret string `json:"secret" binding:"omitempty,min=16,max=128"`
+}
+
+// createWebhookSubscriptionResponse is the only response that contains the signing secret
+type createWebhookSubscriptionResponse struct {
+	webhookSubscriptionResponse
+	Secret string `json:"secret"`
+}
+
+func (server *Server) createWebhookSubscription(ctx *gin.Context) {
+	var req createWebhookSubscriptionRequest
+
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		respondError(ctx, http.StatusBadRequest, err)
+		return
+	}
+	if err := webhook.CheckURL(ctx, req.URL); err != nil {
+		respondError(ctx, http.StatusBadRequest, err)
+		return
+	}
+
+	secret := req.Secret
+	if secret == "" {
+		var err error
+		secret, err = webhook.NewSecret()
+		if err != nil {
+			respondError(ctx, http.StatusInternalServerError, err)
+			return
+		}
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	subscription, err := server.store.CreateWebhookSubscription(ctx, db.CreateWebhookSubscriptionParams{
+		Owner:      authPayload.Username,
+		Url:        req.URL,
+		EventTypes: req.EventTypes,
+		Secret:     secret,
+	})
+	if err != nil {
+		if errors.Is(err, db.ErrForeignKeyViolation) {
+			respondError(ctx, http.StatusForbidden, err)
+			return
+		}
+		respondError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, createWebhookSubscriptionResponse{
+		webhookSubscriptionResponse: newWebhookSubscriptionResponse(subscription),
+		Secret:                      subscription.Secret,
+	})
+}
+
+func (server *Server) listWebhookSubscriptions(ctx *gin.Context) {
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	subscriptions, err := server.store.ListWebhookSubscriptions(ctx, authPayload.Username)
+	if err != nil {
+		respondError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	rsp := make([]webhookSubscriptionResponse, len(subscriptions))
+	for i, subscription := range subscriptions {
+		rsp[i] = newWebhookSubscriptionResponse(subscription)
+	}
+
+	ctx.JSON(http.StatusOK, rsp)
+}
+
+type webhookSubscriptionURI struct {
+	ID int64 `uri:"id" binding:"required,min=1"`
+}
+
+func (server *Server) getWebhookSubscription(ctx *gin.Context) {
+	subscription, ok := server.ownedWebhookSubscription(ctx)
+	if !ok {
+		return
+	}
+
+	ctx.JSON(http.StatusOK, newWebhookSubscriptionResponse(subscription))
+}
+
+type updateWebhookSubscriptionRequest struct {
+	URL        string   `json:"url" binding:"required,http_url,max=2048"`
+	EventTypes []string `json:"event_types" binding:"required,min=1,dive,webhook_event"`
+}
+
+func (server *Server) updateWebhookSubscription(ctx *gin.Context) {
+	var req updateWebhookSubscriptionRequest
+
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		respondError(ctx, http.StatusBadRequest, err)
+		return
+	}
+	if err := webhook.CheckURL(ctx, req.URL); err != nil {
+		respondError(ctx, http.StatusBadRequest, err)
+		return
+	}
+
+	subscription, ok := server.ownedWebhookSubscription(ctx)
+	if !ok {
+		return
+	}
+
+	subscription, err := server.store.UpdateWebhookSubscription(ctx, db.UpdateWebhookSubscriptionParams{
+		ID:         subscription.ID,
+		Url:        req.URL,
+		EventTypes: req.EventTypes,
+	})
+	if err != nil {
+		respondError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, newWebhookSubscriptionResponse(subscription))
+}
+
+// enableWebhookSubscription enables a subscription disabled after repeated failures,
+// its pending deliveries are sent again
+func (server *Server) enableWebhookSubscription(ctx *gin.Context) {
+	subscription, ok := server.ownedWebhookSubscription(ctx)
+	if !ok {
+		return
+	}
+
+	subscription, err := server.store.UpdateWebhookSubscriptionFailures(ctx, db.UpdateWebhookSubscriptionFailuresParams{
+		ID: subscription.ID,
+	})
+	if err != nil {
+		respondError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, newWebhookSubscriptionResponse(subscription))
+}
+
+func (server *Server) deleteWebhookSubscription(ctx *gin.Context) {
+	subscription, ok := server.ownedWebhookSubscription(ctx)
+	if !ok {
+		return
+	}
+
+	err := server.store.DeleteWebhookSubscription(ctx, subscription.ID)
+	if err != nil {
+		respondError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "webhook subscription deleted"})
+}
+
+type listWebhookDeliveriesRequest struct {
+	PageID   int32 `form:"page_id" binding:"required,min=1"`
+	PageSize int32 `form:"page_size" binding:"required,min=5,max=100"`
+}
+
+// listWebhookDeliveries returns the delivery log of a subscription, newest first
+func (server *Server) listWebhookDeliveries(ctx *gin.Context) {
+	var req listWebhookDeliveriesRequest
+
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		respondError(ctx, http.StatusBadRequest, err)
+		return
+	}
+
+	subscription, ok := server.ownedWebhookSubscription(ctx)
+	if !ok {
+		return
+	}
+
+	deliveries, err := server.store.ListWebhookDeliveries(ctx, db.ListWebhookDeliveriesParams{
+		SubscriptionID: subscription.ID,
+		Limit:          req.PageSize,
+		Offset:         (req.PageID - 1) * req.PageSize,
+	})
+	if err != nil {
+		respondError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, deliveries)
+}
+
+// ownedWebhookSubscription binds the subscription of the request and checks that it belongs to the authenticated user
+func (server *Server) ownedWebhookSubscription(ctx *gin.Context) (db.WebhookSubscription, bool) {
+	var uri webhookSubscriptionURI
+
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		respondError(ctx, http.StatusBadRequest, err)
+		return db.WebhookSubscription{}, false
+	}
+
+	subscription, err := server.store.GetWebhookSubscription(ctx, uri.ID)
+	if err != nil {
+		if errors.Is(err, db.ErrRecordNotFound) {
+			respondError(ctx, http.StatusNotFound, err)
+			return subscription, false
+		}
+		respondError(ctx, http.StatusInternalServerError, err)
+		return subscription, false
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	if subscription.Owner != authPayload.Username {
+		err := fmt.Errorf("webhook subscription [%d] doesn't belong to the authenticated user", subscription.ID)
+		respondError(ctx, http.StatusForbidden, err)
+		return subscription, false
+	}
+
+	return subscription, true
+}
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS "standing_order_runs";
+DROP TABLE IF EXISTS "standing_orders";
\ No newline at end of file
@@ -0,0 +1,46 @@
+CREATE TABLE "standing_orders" (
+  "id" bigserial PRIMARY KEY,
+  "from_account_id" bigint NOT NULL,
+  "to_account_id" bigint NOT NULL,
+  "amount" bigint NOT NULL,
+  "frequency" varchar NOT NULL,
+  "interval_count" int NOT NULL DEFAULT 1,
+  "cron_expression" varchar NOT NULL DEFAULT '',
+  "start_at" timestamptz NOT NULL,
+  "end_at" timestamptz,
+  "max_occurrences" int,
+  "occurrences" int NOT NULL DEFAULT 0,
+  "next_run_at" timestamptz,
+  "status" varchar NOT NULL DEFAULT 'active',
+  "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+CREATE TABLE "standing_order_runs" (
+  "id" bigserial PRIMARY KEY,
+  "standing_order_id" bigint NOT NULL,
+  "scheduled_at" timestamptz NOT NULL,
+  "status" varchar NOT NULL,
+  "transfer_id" bigint,
+  "error" varchar NOT NULL DEFAULT '',
+  "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+CREATE INDEX ON "standing_orders" ("status", "next_run_at");
+
+CREATE INDEX ON "standing_order_runs" ("standing_order_id");
+
+COMMENT ON COLUMN "standing_orders"."frequency" IS 'weekly, monthly or custom';
+
+COMMENT ON COLUMN "standing_orders"."cron_expression" IS 'Only used by custom schedules';
+
+COMMENT ON COLUMN "standing_orders"."status" IS 'active, paused, completed or cancelled';
+
+COMMENT ON COLUMN "standing_order_runs"."status" IS 'succeeded or failed';
+
+ALTER TABLE "standing_orders" ADD FOREIGN KEY ("from_account_id") REFERENCES "accounts" ("id");
+
+ALTER TABLE "standing_orders" ADD FOREIGN KEY ("to_account_id") REFERENCES "accounts" ("id");
+
+ALTER TABLE "standing_order_runs" ADD FOREIGN KEY ("standing_order_id") REFERENCES "standing_orders" ("id");
+
+ALTER TABLE "standing_order_runs" ADD FOREIGN KEY ("transfer_id") REFERENCES "transfers" ("id");
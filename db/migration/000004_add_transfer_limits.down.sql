@@ -0,0 +1,6 @@
+DROP TABLE IF EXISTS "transfer_usages";
+DROP TABLE IF EXISTS "transfer_limits";
+
+ALTER TABLE IF EXISTS "users" DROP COLUMN IF EXISTS "tier";
+
+ALTER TABLE IF EXISTS "users" DROP COLUMN IF EXISTS "role";
\ No newline at end of file
@@ -0,0 +1,30 @@
+ALTER TABLE "users" ADD COLUMN "role" varchar NOT NULL DEFAULT 'depositor';
+
+ALTER TABLE "users" ADD COLUMN "tier" varchar NOT NULL DEFAULT 'standard';
+
+CREATE TABLE "transfer_limits" (
+  "tier" varchar NOT NULL,
+  "currency" varchar NOT NULL,
+  "max_single_amount" bigint,
+  "daily_account_amount" bigint,
+  "monthly_account_amount" bigint,
+  "daily_user_amount" bigint,
+  "monthly_user_amount" bigint,
+  "updated_at" timestamptz NOT NULL DEFAULT (now()),
+  PRIMARY KEY ("tier", "currency")
+);
+
+CREATE TABLE "transfer_usages" (
+  "subject" varchar NOT NULL,
+  "currency" varchar NOT NULL,
+  "period" varchar NOT NULL,
+  "period_start" date NOT NULL,
+  "amount" bigint NOT NULL DEFAULT 0,
+  PRIMARY KEY ("subject", "currency", "period", "period_start")
+);
+
+COMMENT ON COLUMN "transfer_limits"."max_single_amount" IS 'NULL means no limit';
+
+COMMENT ON COLUMN "transfer_usages"."subject" IS 'account:<id> or user:<username>';
+
+COMMENT ON COLUMN "transfer_usages"."period" IS 'day or month';
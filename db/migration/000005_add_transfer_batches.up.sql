@@ -0,0 +1,28 @@
+CREATE TABLE "transfer_batches" (
+  "id" bigserial PRIMARY KEY,
+  "mode" varchar NOT NULL,
+  "status" varchar NOT NULL,
+  "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+CREATE TABLE "transfer_batch_items" (
+  "batch_id" bigint NOT NULL,
+  "item_index" int NOT NULL,
+  "from_account_id" bigint NOT NULL,
+  "to_account_id" bigint NOT NULL,
+  "amount" bigint NOT NULL,
+  "status" varchar NOT NULL,
+  "transfer_id" bigint,
+  "error" varchar NOT NULL DEFAULT '',
+  PRIMARY KEY ("batch_id", "item_index")
+);
+
+COMMENT ON COLUMN "transfer_batches"."mode" IS 'atomic or best_effort';
+
+COMMENT ON COLUMN "transfer_batches"."status" IS 'processing, completed, partially_completed or failed';
+
+COMMENT ON COLUMN "transfer_batch_items"."status" IS 'succeeded, failed or rolled_back';
+
+ALTER TABLE "transfer_batch_items" ADD FOREIGN KEY ("batch_id") REFERENCES "transfer_batches" ("id");
+
+ALTER TABLE "transfer_batch_items" ADD FOREIGN KEY ("transfer_id") REFERENCES "transfers" ("id");
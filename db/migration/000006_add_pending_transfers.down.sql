@@ -0,0 +1,17 @@
+DROP TABLE IF EXISTS "account_approvers";
+
+ALTER TABLE IF EXISTS "transfer_limits" DROP COLUMN IF EXISTS "approval_threshold";
+
+ALTER TABLE IF EXISTS "accounts" DROP COLUMN IF EXISTS "held_balance";
+
+DROP INDEX IF EXISTS "transfers_status_expires_at_idx";
+
+ALTER TABLE IF EXISTS "transfers" DROP COLUMN IF EXISTS "decided_at";
+
+ALTER TABLE IF EXISTS "transfers" DROP COLUMN IF EXISTS "expires_at";
+
+ALTER TABLE IF EXISTS "transfers" DROP COLUMN IF EXISTS "approver";
+
+ALTER TABLE IF EXISTS "transfers" DROP COLUMN IF EXISTS "initiator";
+
+ALTER TABLE IF EXISTS "transfers" DROP COLUMN IF EXISTS "status";
\ No newline at end of file
@@ -0,0 +1,34 @@
+ALTER TABLE "transfers" ADD COLUMN "status" varchar NOT NULL DEFAULT 'completed';
+
+ALTER TABLE "transfers" ADD COLUMN "initiator" varchar NOT NULL DEFAULT '';
+
+ALTER TABLE "transfers" ADD COLUMN "approver" varchar NOT NULL DEFAULT '';
+
+ALTER TABLE "transfers" ADD COLUMN "expires_at" timestamptz;
+
+ALTER TABLE "transfers" ADD COLUMN "decided_at" timestamptz;
+
+ALTER TABLE "accounts" ADD COLUMN "held_balance" bigint NOT NULL DEFAULT 0;
+
+ALTER TABLE "transfer_limits" ADD COLUMN "approval_threshold" bigint;
+
+CREATE TABLE "account_approvers" (
+  "account_id" bigint NOT NULL,
+  "username" varchar NOT NULL,
+  "created_at" timestamptz NOT NULL DEFAULT (now()),
+  PRIMARY KEY ("account_id", "username")
+);
+
+CREATE INDEX ON "transfers" ("status", "expires_at");
+
+COMMENT ON COLUMN "transfers"."status" IS 'pending, approved, rejected, completed or expired';
+
+COMMENT ON COLUMN "transfers"."approver" IS 'User who approved or rejected a pending transfer';
+
+COMMENT ON COLUMN "accounts"."held_balance" IS 'Funds reserved by pending transfers';
+
+COMMENT ON COLUMN "transfer_limits"."approval_threshold" IS 'Transfers above it need a second approval, NULL means never';
+
+ALTER TABLE "account_approvers" ADD FOREIGN KEY ("account_id") REFERENCES "accounts" ("id");
+
+ALTER TABLE "account_approvers" ADD FOREIGN KEY ("username") REFERENCES "users" ("username");
@@ -0,0 +1,7 @@
+DROP TABLE IF EXISTS "holds";
+
+COMMENT ON COLUMN "accounts"."held_balance" IS 'Funds reserved by pending transfers';
+
+COMMENT ON COLUMN "accounts"."balance" IS NULL;
+
+ALTER TABLE IF EXISTS "accounts" DROP COLUMN IF EXISTS "available_balance";
\ No newline at end of file
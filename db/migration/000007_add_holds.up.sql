@@ -0,0 +1,30 @@
+ALTER TABLE "accounts" ADD COLUMN "available_balance" bigint NOT NULL GENERATED ALWAYS AS ("balance" - "held_balance") STORED;
+
+CREATE TABLE "holds" (
+  "id" bigserial PRIMARY KEY,
+  "account_id" bigint NOT NULL,
+  "amount" bigint NOT NULL,
+  "captured_amount" bigint NOT NULL DEFAULT 0,
+  "status" varchar NOT NULL DEFAULT 'active',
+  "transfer_id" bigint,
+  "expires_at" timestamptz NOT NULL,
+  "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+CREATE INDEX ON "holds" ("account_id");
+
+CREATE INDEX ON "holds" ("status", "expires_at");
+
+COMMENT ON COLUMN "accounts"."balance" IS 'Ledger balance';
+
+COMMENT ON COLUMN "accounts"."held_balance" IS 'Funds reserved by pending transfers and active holds';
+
+COMMENT ON COLUMN "accounts"."available_balance" IS 'Ledger balance minus held funds';
+
+COMMENT ON COLUMN "holds"."status" IS 'active, captured, released or expired';
+
+COMMENT ON COLUMN "holds"."transfer_id" IS 'Transfer the hold was captured into';
+
+ALTER TABLE "holds" ADD FOREIGN KEY ("account_id") REFERENCES "accounts" ("id");
+
+ALTER TABLE "holds" ADD FOREIGN KEY ("transfer_id") REFERENCES "transfers" ("id");
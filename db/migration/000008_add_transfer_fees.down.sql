@@ -0,0 +1,13 @@
+DROP INDEX IF EXISTS "entries_transfer_id_idx";
+
+ALTER TABLE IF EXISTS "entries" DROP COLUMN IF EXISTS "transfer_id";
+
+ALTER TABLE IF EXISTS "entries" DROP COLUMN IF EXISTS "kind";
+
+ALTER TABLE IF EXISTS "transfers" DROP COLUMN IF EXISTS "fee_account_id";
+
+ALTER TABLE IF EXISTS "transfers" DROP COLUMN IF EXISTS "fee";
+
+DROP TABLE IF EXISTS "revenue_accounts";
+
+DROP TABLE IF EXISTS "fee_schedules";
\ No newline at end of file
@@ -0,0 +1,44 @@
+CREATE TABLE "fee_schedules" (
+  "tier" varchar NOT NULL,
+  "currency" varchar NOT NULL,
+  "flat_amount" bigint NOT NULL DEFAULT 0,
+  "percentage_bps" int NOT NULL DEFAULT 0,
+  "min_amount" bigint,
+  "max_amount" bigint,
+  "updated_at" timestamptz NOT NULL DEFAULT (now()),
+  PRIMARY KEY ("tier", "currency")
+);
+
+CREATE TABLE "revenue_accounts" (
+  "currency" varchar PRIMARY KEY,
+  "account_id" bigint NOT NULL,
+  "updated_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+ALTER TABLE "transfers" ADD COLUMN "fee" bigint NOT NULL DEFAULT 0;
+
+ALTER TABLE "transfers" ADD COLUMN "fee_account_id" bigint;
+
+ALTER TABLE "entries" ADD COLUMN "kind" varchar NOT NULL DEFAULT 'transfer';
+
+ALTER TABLE "entries" ADD COLUMN "transfer_id" bigint;
+
+CREATE INDEX ON "entries" ("transfer_id");
+
+COMMENT ON COLUMN "fee_schedules"."percentage_bps" IS 'Percentage of the amount in basis points';
+
+COMMENT ON COLUMN "fee_schedules"."min_amount" IS 'NULL means no minimum';
+
+COMMENT ON COLUMN "fee_schedules"."max_amount" IS 'NULL means no maximum';
+
+COMMENT ON COLUMN "revenue_accounts"."account_id" IS 'Account the fees in the currency are posted to';
+
+COMMENT ON COLUMN "transfers"."fee" IS 'Charged to the sender on top of the amount';
+
+COMMENT ON COLUMN "entries"."kind" IS 'transfer or fee';
+
+ALTER TABLE "revenue_accounts" ADD FOREIGN KEY ("account_id") REFERENCES "accounts" ("id");
+
+ALTER TABLE "transfers" ADD FOREIGN KEY ("fee_account_id") REFERENCES "accounts" ("id");
+
+ALTER TABLE "entries" ADD FOREIGN KEY ("transfer_id") REFERENCES "transfers" ("id");
@@ -0,0 +1,15 @@
+CREATE TABLE "outbox_events" (
+  "id" bigserial PRIMARY KEY,
+  "aggregate_type" varchar NOT NULL,
+  "aggregate_id" varchar NOT NULL,
+  "event_type" varchar NOT NULL,
+  "payload" jsonb NOT NULL,
+  "created_at" timestamptz NOT NULL DEFAULT (now()),
+  "sent_at" timestamptz
+);
+
+CREATE INDEX "outbox_events_unsent_idx" ON "outbox_events" ("id") WHERE "sent_at" IS NULL;
+
+COMMENT ON COLUMN "outbox_events"."id" IS 'Offset of the event, events are delivered in id order';
+
+COMMENT ON COLUMN "outbox_events"."sent_at" IS 'NULL until the event is delivered';
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS "webhook_deliveries";
+
+DROP TABLE IF EXISTS "webhook_subscriptions";
\ No newline at end of file
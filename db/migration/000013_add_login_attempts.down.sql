@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS "login_throttles";
+
+DROP TABLE IF EXISTS "login_attempts";
\ No newline at end of file
@@ -0,0 +1,27 @@
+CREATE TABLE "login_attempts" (
+  "id" bigserial PRIMARY KEY,
+  "username" varchar NOT NULL,
+  "client_ip" varchar NOT NULL,
+  "user_agent" varchar NOT NULL DEFAULT '',
+  "succeeded" bool NOT NULL,
+  "failure_reason" varchar NOT NULL DEFAULT '',
+  "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+CREATE TABLE "login_throttles" (
+  "key" varchar PRIMARY KEY,
+  "failures" int NOT NULL DEFAULT 0,
+  "last_failure_at" timestamptz NOT NULL
+);
+
+CREATE INDEX ON "login_attempts" ("username", "id");
+
+CREATE INDEX ON "login_attempts" ("client_ip", "id");
+
+COMMENT ON COLUMN "login_attempts"."username" IS 'Username as given, it may not belong to a user';
+
+COMMENT ON COLUMN "login_attempts"."failure_reason" IS 'unknown_user, wrong_password or throttled, empty if the login succeeded';
+
+COMMENT ON COLUMN "login_throttles"."key" IS 'user:<username> or ip:<client ip>';
+
+COMMENT ON COLUMN "login_throttles"."failures" IS 'Failed logins since the last success, counted again from 1 once the last failure is older than the window';
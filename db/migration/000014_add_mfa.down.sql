@@ -0,0 +1,7 @@
+DROP TABLE IF EXISTS "recovery_codes";
+
+DROP TABLE IF EXISTS "totp_secrets";
+
+COMMENT ON COLUMN "login_attempts"."failure_reason" IS 'unknown_user, wrong_password or throttled, empty if the login succeeded';
+
+ALTER TABLE IF EXISTS "users" DROP COLUMN IF EXISTS "is_mfa_enabled";
\ No newline at end of file
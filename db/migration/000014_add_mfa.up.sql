@@ -0,0 +1,33 @@
+ALTER TABLE "users" ADD COLUMN "is_mfa_enabled" bool NOT NULL DEFAULT false;
+
+CREATE TABLE "totp_secrets" (
+  "username" varchar PRIMARY KEY,
+  "encrypted_secret" varchar NOT NULL,
+  "is_confirmed" bool NOT NULL DEFAULT false,
+  "last_used_step" bigint NOT NULL DEFAULT 0,
+  "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+CREATE TABLE "recovery_codes" (
+  "id" bigserial PRIMARY KEY,
+  "username" varchar NOT NULL,
+  "code_hash" varchar UNIQUE NOT NULL,
+  "is_used" bool NOT NULL DEFAULT false,
+  "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+CREATE INDEX ON "recovery_codes" ("username");
+
+COMMENT ON COLUMN "totp_secrets"."encrypted_secret" IS 'TOTP secret encrypted with the MFA encryption key';
+
+COMMENT ON COLUMN "totp_secrets"."is_confirmed" IS 'Set once the user proved they can generate codes, only then is the secret asked for at login';
+
+COMMENT ON COLUMN "totp_secrets"."last_used_step" IS 'Time step of the last accepted code, so that a code cannot be replayed';
+
+COMMENT ON COLUMN "login_attempts"."failure_reason" IS 'unknown_user, wrong_password, wrong_mfa_code or throttled, empty if the login succeeded';
+
+COMMENT ON COLUMN "recovery_codes"."code_hash" IS 'SHA-256 of the recovery code, the code itself is only shown to the user once';
+
+ALTER TABLE "totp_secrets" ADD FOREIGN KEY ("username") REFERENCES "users" ("username");
+
+ALTER TABLE "recovery_codes" ADD FOREIGN KEY ("username") REFERENCES "users" ("username");
@@ -0,0 +1,5 @@
+COMMENT ON COLUMN "login_attempts"."failure_reason" IS 'unknown_user, wrong_password, wrong_mfa_code or throttled, empty if the login succeeded';
+
+ALTER TABLE IF EXISTS "accounts" DROP COLUMN IF EXISTS "closed_at";
+
+ALTER TABLE IF EXISTS "users" DROP COLUMN IF EXISTS "deactivated_at";
\ No newline at end of file
@@ -0,0 +1,9 @@
+ALTER TABLE "users" ADD COLUMN "deactivated_at" timestamptz;
+
+ALTER TABLE "accounts" ADD COLUMN "closed_at" timestamptz;
+
+COMMENT ON COLUMN "users"."deactivated_at" IS 'Set once the user deactivated themselves, they can no longer log in';
+
+COMMENT ON COLUMN "accounts"."closed_at" IS 'Set once the account is closed, it can no longer be used in transfers';
+
+COMMENT ON COLUMN "login_attempts"."failure_reason" IS 'unknown_user, wrong_password, wrong_mfa_code, deactivated or throttled, empty if the login succeeded';
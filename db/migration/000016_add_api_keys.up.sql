@@ -0,0 +1,28 @@
+CREATE TABLE "api_keys" (
+  "id" bigserial PRIMARY KEY,
+  "username" varchar NOT NULL,
+  "name" varchar NOT NULL,
+  "key_prefix" varchar NOT NULL,
+  "key_hash" varchar UNIQUE NOT NULL,
+  "scopes" varchar[] NOT NULL,
+  "allowed_ips" varchar[] NOT NULL DEFAULT '{}',
+  "created_by" varchar NOT NULL,
+  "expires_at" timestamptz,
+  "last_used_at" timestamptz,
+  "revoked_at" timestamptz,
+  "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+CREATE INDEX ON "api_keys" ("username");
+
+COMMENT ON COLUMN "api_keys"."key_prefix" IS 'Start of the key, shown so that the user can tell their keys apart';
+
+COMMENT ON COLUMN "api_keys"."key_hash" IS 'SHA-256 of the key, the key itself is only shown to the user once';
+
+COMMENT ON COLUMN "api_keys"."allowed_ips" IS 'IPs and CIDR ranges the key can be used from, any if empty';
+
+COMMENT ON COLUMN "api_keys"."created_by" IS 'User who created the key, an admin for the keys of service accounts';
+
+ALTER TABLE "api_keys" ADD FOREIGN KEY ("username") REFERENCES "users" ("username");
+
+ALTER TABLE "api_keys" ADD FOREIGN KEY ("created_by") REFERENCES "users" ("username");
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS "oauth_refresh_tokens";
+DROP TABLE IF EXISTS "oauth_authorization_codes";
+DROP TABLE IF EXISTS "oauth_clients";
\ No newline at end of file
@@ -0,0 +1,56 @@
+CREATE TABLE "oauth_clients" (
+  "id" varchar PRIMARY KEY,
+  "name" varchar NOT NULL,
+  "secret_hash" varchar NOT NULL DEFAULT '',
+  "redirect_uris" varchar[] NOT NULL,
+  "scopes" varchar[] NOT NULL,
+  "created_by" varchar NOT NULL,
+  "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+CREATE TABLE "oauth_authorization_codes" (
+  "code_hash" varchar PRIMARY KEY,
+  "client_id" varchar NOT NULL,
+  "username" varchar NOT NULL,
+  "redirect_uri" varchar NOT NULL,
+  "scopes" varchar[] NOT NULL,
+  "code_challenge" varchar NOT NULL,
+  "expires_at" timestamptz NOT NULL,
+  "used_at" timestamptz,
+  "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+CREATE TABLE "oauth_refresh_tokens" (
+  "id" bigserial PRIMARY KEY,
+  "token_hash" varchar UNIQUE NOT NULL,
+  "client_id" varchar NOT NULL,
+  "username" varchar NOT NULL,
+  "scopes" varchar[] NOT NULL,
+  "expires_at" timestamptz NOT NULL,
+  "revoked_at" timestamptz,
+  "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+CREATE INDEX ON "oauth_refresh_tokens" ("client_id", "username");
+
+COMMENT ON COLUMN "oauth_clients"."secret_hash" IS 'SHA-256 of the client secret, empty for public clients that cannot keep a secret';
+
+COMMENT ON COLUMN "oauth_clients"."scopes" IS 'Scopes the client is allowed to ask users for';
+
+COMMENT ON COLUMN "oauth_authorization_codes"."code_hash" IS 'SHA-256 of the code, the code itself is only sent to the client';
+
+COMMENT ON COLUMN "oauth_authorization_codes"."code_challenge" IS 'PKCE S256 challenge the code verifier must match';
+
+COMMENT ON COLUMN "oauth_refresh_tokens"."token_hash" IS 'SHA-256 of the token, the token itself is only sent to the client';
+
+COMMENT ON COLUMN "oauth_refresh_tokens"."revoked_at" IS 'Set when the token is rotated or revoked';
+
+ALTER TABLE "oauth_clients" ADD FOREIGN KEY ("created_by") REFERENCES "users" ("username");
+
+ALTER TABLE "oauth_authorization_codes" ADD FOREIGN KEY ("client_id") REFERENCES "oauth_clients" ("id");
+
+ALTER TABLE "oauth_authorization_codes" ADD FOREIGN KEY ("username") REFERENCES "users" ("username");
+
+ALTER TABLE "oauth_refresh_tokens" ADD FOREIGN KEY ("client_id") REFERENCES "oauth_clients" ("id");
+
+ALTER TABLE "oauth_refresh_tokens" ADD FOREIGN KEY ("username") REFERENCES "users" ("username");
@@ -0,0 +1,20 @@
+CREATE TABLE "sessions" (
+  "id" bigserial PRIMARY KEY,
+  "username" varchar NOT NULL,
+  "user_agent" varchar NOT NULL,
+  "client_ip" varchar NOT NULL,
+  "expires_at" timestamptz NOT NULL,
+  "last_used_at" timestamptz NOT NULL DEFAULT (now()),
+  "revoked_at" timestamptz,
+  "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+CREATE INDEX ON "sessions" ("username");
+
+COMMENT ON COLUMN "sessions"."expires_at" IS 'When the access token of the session expires';
+
+COMMENT ON COLUMN "sessions"."last_used_at" IS 'Updated at most once a minute from the auth middleware';
+
+COMMENT ON COLUMN "sessions"."revoked_at" IS 'Set when the user or support signs the session out';
+
+ALTER TABLE "sessions" ADD FOREIGN KEY ("username") REFERENCES "users" ("username");
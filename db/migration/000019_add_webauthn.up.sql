@@ -0,0 +1,46 @@
+CREATE TABLE "webauthn_credentials" (
+  "id" bigserial PRIMARY KEY,
+  "credential_id" bytea UNIQUE NOT NULL,
+  "username" varchar NOT NULL,
+  "user_handle" bytea NOT NULL,
+  "name" varchar NOT NULL,
+  "public_key" bytea NOT NULL,
+  "attestation_type" varchar NOT NULL,
+  "transports" varchar[] NOT NULL,
+  "aaguid" bytea NOT NULL,
+  "sign_count" bigint NOT NULL DEFAULT 0,
+  "backup_eligible" boolean NOT NULL DEFAULT false,
+  "backup_state" boolean NOT NULL DEFAULT false,
+  "last_used_at" timestamptz,
+  "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+CREATE TABLE "webauthn_challenges" (
+  "challenge" varchar PRIMARY KEY,
+  "ceremony" varchar NOT NULL,
+  "username" varchar,
+  "session_data" jsonb NOT NULL,
+  "expires_at" timestamptz NOT NULL,
+  "used_at" timestamptz,
+  "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+CREATE INDEX ON "webauthn_credentials" ("username");
+
+COMMENT ON COLUMN "webauthn_credentials"."credential_id" IS 'ID the authenticator gave the credential';
+
+COMMENT ON COLUMN "webauthn_credentials"."user_handle" IS 'Random ID of the user stored on the authenticator, shared by all passkeys of the user';
+
+COMMENT ON COLUMN "webauthn_credentials"."public_key" IS 'COSE encoded public key of the credential';
+
+COMMENT ON COLUMN "webauthn_credentials"."sign_count" IS 'Signature counter of the last assertion, a counter that does not increase hints at a cloned authenticator';
+
+COMMENT ON COLUMN "webauthn_challenges"."ceremony" IS 'registration or login';
+
+COMMENT ON COLUMN "webauthn_challenges"."username" IS 'User registering a passkey, null for logins where the passkey names the user';
+
+COMMENT ON COLUMN "webauthn_challenges"."session_data" IS 'Session data the ceremony is finished with';
+
+ALTER TABLE "webauthn_credentials" ADD FOREIGN KEY ("username") REFERENCES "users" ("username");
+
+ALTER TABLE "webauthn_challenges" ADD FOREIGN KEY ("username") REFERENCES "users" ("username");
@@ -0,0 +1,3 @@
+ALTER TABLE "transfer_batches" ADD COLUMN "creator" varchar NOT NULL DEFAULT '';
+
+COMMENT ON COLUMN "transfer_batches"."creator" IS 'User who created the batch, only they can read it';
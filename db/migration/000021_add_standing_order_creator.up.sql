@@ -0,0 +1,11 @@
+ALTER TABLE "standing_orders" ADD COLUMN "creator" varchar NOT NULL DEFAULT '';
+
+-- the existing standing orders were created by the owner of the account they send from
+UPDATE "standing_orders"
+SET "creator" = "accounts"."owner"
+FROM "accounts"
+WHERE "accounts"."id" = "standing_orders"."from_account_id";
+
+ALTER TABLE "standing_orders" ALTER COLUMN "creator" DROP DEFAULT;
+
+COMMENT ON COLUMN "standing_orders"."creator" IS 'User who created the standing order, its transfers are initiated by them';
@@ -0,0 +1,3 @@
+COMMENT ON COLUMN "outbox_events"."id" IS 'Offset of the event, events are delivered in id order';
+
+ALTER TABLE IF EXISTS "outbox_events" DROP COLUMN IF EXISTS "claimed_until";
@@ -0,0 +1,5 @@
+ALTER TABLE "outbox_events" ADD COLUMN "claimed_until" timestamptz;
+
+COMMENT ON COLUMN "outbox_events"."id" IS 'Offset of the event, events are relayed in id order as far as their transactions committed in that order';
+
+COMMENT ON COLUMN "outbox_events"."claimed_until" IS 'Time until which a relay publishing the unsent event keeps other relays from claiming it';
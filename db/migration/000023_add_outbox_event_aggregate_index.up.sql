@@ -0,0 +1 @@
+CREATE INDEX "outbox_events_unsent_aggregate_idx" ON "outbox_events" ("aggregate_type", "aggregate_id", "id") WHERE "sent_at" IS NULL;
@@ -0,0 +1,6 @@
+-- the codes cannot be recovered from their hashes, so the links sent before are no longer valid
+DROP INDEX IF EXISTS "verify_emails_username_created_at_idx";
+
+ALTER TABLE IF EXISTS "verify_emails" RENAME COLUMN "secret_code_hash" TO "secret_code";
+
+COMMENT ON COLUMN "verify_emails"."secret_code" IS 'Code of the verification link, it can be used once before it expires';
@@ -0,0 +1,8 @@
+ALTER TABLE "verify_emails" RENAME COLUMN "secret_code" TO "secret_code_hash";
+
+-- the codes sent before are hashed in place, so their links stay valid
+UPDATE "verify_emails" SET "secret_code_hash" = encode(sha256(convert_to("secret_code_hash", 'UTF8')), 'hex');
+
+CREATE INDEX ON "verify_emails" ("username", "created_at");
+
+COMMENT ON COLUMN "verify_emails"."secret_code_hash" IS 'SHA-256 of the code of the verification link, the code itself is only sent to the user';
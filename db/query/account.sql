@@ -34,6 +34,21 @@ SET balance = balance + sqlc.arg(amount)
 WHERE id = sqlc.arg(id)
 RETURNING *;
 
+-- name: AddAccountHeldBalance :one
+UPDATE accounts
+SET held_balance = held_balance + sqlc.arg(amount)
+WHERE id = sqlc.arg(id)
+RETURNING *;
+
 -- name: DeleteAccount :exec
 DELETE FROM accounts
 WHERE id = $1;
+
+-- name: CloseZeroBalanceAccounts :many
+UPDATE accounts
+SET closed_at = sqlc.arg(closed_at)
+WHERE owner = sqlc.arg(owner)
+  AND balance = 0
+  AND held_balance = 0
+  AND closed_at IS NULL
+RETURNING *;
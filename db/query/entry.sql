@@ -6,6 +6,16 @@ INSERT INTO entries (
   $1, $2
 ) RETURNING *;
 
+-- name: CreateTransferEntry :one
+INSERT INTO entries (
+  account_id,
+  amount,
+  kind,
+  transfer_id
+) VALUES (
+  $1, $2, $3, $4
+) RETURNING *;
+
 -- name: GetEntry :one
 SELECT * FROM entries
 WHERE id = $1 LIMIT 1;
@@ -16,3 +26,10 @@ WHERE account_id = $1
 ORDER BY id
   LIMIT $2
 OFFSET $3;
+
+-- name: ListOwnerEntriesAfter :many
+SELECT entries.* FROM entries
+JOIN accounts ON accounts.id = entries.account_id
+WHERE accounts.owner = $1 AND entries.id > $2
+ORDER BY entries.id
+LIMIT $3;
@@ -0,0 +1,48 @@
+-- name: UpsertFeeSchedule :one
+INSERT INTO fee_schedules (
+  tier,
+  currency,
+  flat_amount,
+  percentage_bps,
+  min_amount,
+  max_amount
+) VALUES (
+  $1, $2, $3, $4, $5, $6
+) ON CONFLICT (tier, currency) DO UPDATE SET
+  flat_amount = EXCLUDED.flat_amount,
+  percentage_bps = EXCLUDED.percentage_bps,
+  min_amount = EXCLUDED.min_amount,
+  max_amount = EXCLUDED.max_amount,
+  updated_at = now()
+RETURNING *;
+
+-- name: GetFeeSchedule :one
+SELECT * FROM fee_schedules
+WHERE tier = $1 AND currency = $2 LIMIT 1;
+
+-- name: ListFeeSchedules :many
+SELECT * FROM fee_schedules
+ORDER BY tier, currency;
+
+-- name: DeleteFeeSchedule :exec
+DELETE FROM fee_schedules
+WHERE tier = $1 AND currency = $2;
+
+-- name: SetRevenueAccount :one
+INSERT INTO revenue_accounts (
+  currency,
+  account_id
+) VALUES (
+  $1, $2
+) ON CONFLICT (currency) DO UPDATE SET
+  account_id = EXCLUDED.account_id,
+  updated_at = now()
+RETURNING *;
+
+-- name: GetRevenueAccount :one
+SELECT * FROM revenue_accounts
+WHERE currency = $1 LIMIT 1;
+
+-- name: ListRevenueAccounts :many
+SELECT * FROM revenue_accounts
+ORDER BY currency;
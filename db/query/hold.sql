@@ -0,0 +1,32 @@
+-- name: CreateHold :one
+INSERT INTO holds (
+  account_id,
+  amount,
+  expires_at
+) VALUES (
+  $1, $2, $3
+) RETURNING *;
+
+-- name: GetHold :one
+SELECT * FROM holds
+WHERE id = $1 LIMIT 1;
+
+-- name: GetHoldForUpdate :one
+SELECT * FROM holds
+WHERE id = $1 LIMIT 1
+FOR NO KEY UPDATE;
+
+-- name: ListExpiredHolds :many
+SELECT * FROM holds
+WHERE status = 'active' AND expires_at <= $1
+ORDER BY expires_at
+LIMIT $2;
+
+-- name: UpdateHoldStatus :one
+UPDATE holds
+SET
+  status = $2,
+  captured_amount = $3,
+  transfer_id = $4
+WHERE id = $1
+RETURNING *;
@@ -0,0 +1,58 @@
+-- name: CreateLoginAttempt :one
+INSERT INTO login_attempts (
+  username,
+  client_ip,
+  user_agent,
+  succeeded,
+  failure_reason
+) VALUES (
+  $1, $2, $3, $4, $5
+) RETURNING *;
+
+-- name: ListLoginAttempts :many
+SELECT * FROM login_attempts
+WHERE username = $1
+ORDER BY id DESC
+LIMIT $2
+OFFSET $3;
+
+-- name: ListLoginThrottles :many
+SELECT * FROM login_throttles
+WHERE key = ANY(sqlc.arg(keys)::varchar[]);
+
+-- name: IncrementLoginThrottle :one
+INSERT INTO login_throttles (
+  key,
+  failures,
+  last_failure_at
+) VALUES (
+  sqlc.arg(key), 1, sqlc.arg(now)
+)
+ON CONFLICT (key) DO UPDATE SET
+  failures = CASE
+    WHEN login_throttles.last_failure_at < sqlc.arg(window_start) THEN 1
+    ELSE login_throttles.failures + 1
+  END,
+  last_failure_at = sqlc.arg(now)
+RETURNING *;
+
+-- name: DeleteLoginThrottle :exec
+DELETE FROM login_throttles
+WHERE key = $1;
+
+-- name: LockLoginThrottle :one
+INSERT INTO login_throttles (
+  key,
+  failures,
+  last_failure_at
+) VALUES (
+  sqlc.arg(key), 0, sqlc.arg(now)
+)
+ON CONFLICT (key) DO UPDATE SET
+  key = EXCLUDED.key
+RETURNING *;
+
+-- name: UncountLoginFailures :exec
+UPDATE login_throttles
+SET failures = GREATEST(failures - 1, 0)
+WHERE key = ANY(sqlc.arg(keys)::varchar[]);
@@ -0,0 +1,53 @@
+-- name: UpsertTOTPSecret :one
+INSERT INTO totp_secrets (
+  username,
+  encrypted_secret
+) VALUES (
+  $1, $2
+)
+ON CONFLICT (username) DO UPDATE SET
+  encrypted_secret = EXCLUDED.encrypted_secret,
+  last_used_step = 0,
+  created_at = now()
+WHERE totp_secrets.is_confirmed = FALSE
+RETURNING *;
+
+-- name: GetTOTPSecret :one
+SELECT * FROM totp_secrets
+WHERE username = $1 LIMIT 1;
+
+-- name: ConfirmTOTPSecret :one
+UPDATE totp_secrets
+SET is_confirmed = TRUE,
+    last_used_step = sqlc.arg(step)
+WHERE username = sqlc.arg(username)
+  AND is_confirmed = FALSE
+RETURNING *;
+
+-- name: UseTOTPStep :one
+UPDATE totp_secrets
+SET last_used_step = sqlc.arg(step)
+WHERE username = sqlc.arg(username)
+  AND is_confirmed = TRUE
+  AND last_used_step < sqlc.arg(step)
+RETURNING *;
+
+-- name: CreateRecoveryCode :one
+INSERT INTO recovery_codes (
+  username,
+  code_hash
+) VALUES (
+  $1, $2
+) RETURNING *;
+
+-- name: DeleteRecoveryCodes :exec
+DELETE FROM recovery_codes
+WHERE username = $1;
+
+-- name: UseRecoveryCode :one
+UPDATE recovery_codes
+SET is_used = TRUE
+WHERE username = sqlc.arg(username)
+  AND code_hash = sqlc.arg(code_hash)
+  AND is_used = FALSE
+RETURNING *;
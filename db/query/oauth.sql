@@ -0,0 +1,82 @@
+-- name: CreateOAuthClient :one
+INSERT INTO oauth_clients (
+  id,
+  name,
+  secret_hash,
+  redirect_uris,
+  scopes,
+  created_by
+) VALUES (
+  $1, $2, $3, $4, $5, $6
+) RETURNING *;
+
+-- name: GetOAuthClient :one
+SELECT * FROM oauth_clients
+WHERE id = $1 LIMIT 1;
+
+-- name: ListOAuthClients :many
+SELECT * FROM oauth_clients
+ORDER BY created_at;
+
+-- name: CreateOAuthAuthorizationCode :one
+INSERT INTO oauth_authorization_codes (
+  code_hash,
+  client_id,
+  username,
+  redirect_uri,
+  scopes,
+  code_challenge,
+  expires_at
+) VALUES (
+  $1, $2, $3, $4, $5, $6, $7
+) RETURNING *;
+
+-- name: UseOAuthAuthorizationCode :one
+UPDATE oauth_authorization_codes
+SET used_at = sqlc.arg(now)
+WHERE code_hash = sqlc.arg(code_hash)
+  AND used_at IS NULL
+  AND expires_at > sqlc.arg(now)
+RETURNING *;
+
+-- name: CreateOAuthRefreshToken :one
+INSERT INTO oauth_refresh_tokens (
+  token_hash,
+  client_id,
+  username,
+  scopes,
+  expires_at
+) VALUES (
+  $1, $2, $3, $4, $5
+) RETURNING *;
+
+-- name: GetOAuthRefreshTokenForUpdate :one
+SELECT * FROM oauth_refresh_tokens
+WHERE token_hash = $1 LIMIT 1
+FOR NO KEY UPDATE;
+
+-- name: RevokeOAuthRefreshToken :exec
+UPDATE oauth_refresh_tokens
+SET revoked_at = sqlc.arg(revoked_at)
+WHERE id = sqlc.arg(id)
+  AND revoked_at IS NULL;
+
+-- name: RevokeOAuthRefreshTokenByHash :exec
+UPDATE oauth_refresh_tokens
+SET revoked_at = sqlc.arg(revoked_at)
+WHERE token_hash = sqlc.arg(token_hash)
+  AND client_id = sqlc.arg(client_id)
+  AND revoked_at IS NULL;
+
+-- name: RevokeOAuthGrant :execrows
+UPDATE oauth_refresh_tokens
+SET revoked_at = sqlc.arg(revoked_at)
+WHERE client_id = sqlc.arg(client_id)
+  AND username = sqlc.arg(username)
+  AND revoked_at IS NULL;
+
+-- name: RevokeUserOAuthRefreshTokens :exec
+UPDATE oauth_refresh_tokens
+SET revoked_at = sqlc.arg(revoked_at)
+WHERE username = sqlc.arg(username)
+  AND revoked_at IS NULL;
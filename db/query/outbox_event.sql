@@ -0,0 +1,58 @@
+-- name: CreateOutboxEvent :one
+INSERT INTO outbox_events (
+  aggregate_type,
+  aggregate_id,
+  event_type,
+  payload
+) VALUES (
+  $1, $2, $3, $4
+) RETURNING *;
+
+-- name: ClaimOutboxEvents :many
+UPDATE outbox_events
+SET claimed_until = sqlc.arg(lease_until)::timestamptz
+WHERE id IN (
+  SELECT head.id FROM outbox_events AS head
+  WHERE head.id IN (
+    SELECT DISTINCT ON (aggregate_type, aggregate_id) id FROM outbox_events
+    WHERE sent_at IS NULL
+    ORDER BY aggregate_type, aggregate_id, id
+  )
+    AND NOT EXISTS (
+      SELECT 1 FROM outbox_events AS leased
+      WHERE leased.aggregate_type = head.aggregate_type
+        AND leased.aggregate_id = head.aggregate_id
+        AND leased.sent_at IS NULL
+        AND leased.claimed_until > sqlc.arg(now)::timestamptz
+    )
+  ORDER BY head.id
+  LIMIT sqlc.arg(max_events)::int
+  FOR UPDATE SKIP LOCKED
+)
+RETURNING *;
+
+-- name: ReleaseOutboxEvents :exec
+UPDATE outbox_events
+SET claimed_until = NULL
+WHERE id = ANY(sqlc.arg(ids)::bigint[]) AND sent_at IS NULL;
+
+-- name: ListOutboxEvents :many
+SELECT * FROM outbox_events
+WHERE id >= $1
+ORDER BY id
+LIMIT $2;
+
+-- name: MarkOutboxEventSent :exec
+UPDATE outbox_events
+SET sent_at = now()
+WHERE id = $1;
+
+-- name: ReplayOutboxEvents :execrows
+UPDATE outbox_events
+SET sent_at = NULL, claimed_until = NULL
+WHERE id >= $1 AND sent_at IS NOT NULL;
+
+
+-- name: GetOutboxEvent :one
+SELECT * FROM outbox_events
+WHERE id = $1 LIMIT 1;
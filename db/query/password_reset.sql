@@ -0,0 +1,34 @@
+-- name: CreatePasswordReset :one
+INSERT INTO password_resets (
+  username,
+  token_hash,
+  expired_at
+) VALUES (
+  $1, $2, $3
+) RETURNING *;
+
+-- name: CountPasswordResetsSince :one
+SELECT count(*) FROM password_resets
+WHERE username = sqlc.arg(username)
+  AND created_at >= sqlc.arg(since);
+
+-- name: UsePasswordReset :one
+UPDATE password_resets
+SET is_used = TRUE
+WHERE token_hash = sqlc.arg(token_hash)
+  AND is_used = FALSE
+  AND expired_at > sqlc.arg(now)
+RETURNING *;
+
+-- name: InvalidatePasswordResets :exec
+UPDATE password_resets
+SET is_used = TRUE
+WHERE username = $1
+  AND is_used = FALSE;
+
+-- name: GetPasswordResetUser :one
+SELECT users.* FROM password_resets
+JOIN users ON users.username = password_resets.username
+WHERE password_resets.token_hash = sqlc.arg(token_hash)
+  AND password_resets.is_used = FALSE
+  AND password_resets.expired_at > sqlc.arg(now);
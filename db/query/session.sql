@@ -0,0 +1,49 @@
+-- name: CreateSession :one
+INSERT INTO sessions (
+  username,
+  user_agent,
+  client_ip,
+  expires_at,
+  last_used_at
+) VALUES (
+  $1, $2, $3, $4, $5
+) RETURNING *;
+
+-- name: GetSession :one
+SELECT * FROM sessions
+WHERE id = $1 LIMIT 1;
+
+-- name: ListActiveSessions :many
+SELECT * FROM sessions
+WHERE username = sqlc.arg(username)
+  AND revoked_at IS NULL
+  AND expires_at > sqlc.arg(now)
+ORDER BY last_used_at DESC;
+
+-- name: RevokeSession :one
+UPDATE sessions
+SET revoked_at = sqlc.arg(revoked_at)
+WHERE id = sqlc.arg(id)
+  AND revoked_at IS NULL
+RETURNING *;
+
+-- name: RevokeOtherSessions :execrows
+UPDATE sessions
+SET revoked_at = sqlc.arg(revoked_at)
+WHERE username = sqlc.arg(username)
+  AND id <> sqlc.arg(keep_id)
+  AND revoked_at IS NULL
+  AND expires_at > sqlc.arg(revoked_at);
+
+-- name: RevokeUserSessions :execrows
+UPDATE sessions
+SET revoked_at = sqlc.arg(revoked_at)
+WHERE username = sqlc.arg(username)
+  AND revoked_at IS NULL
+  AND expires_at > sqlc.arg(revoked_at);
+
+-- name: TouchSession :exec
+UPDATE sessions
+SET last_used_at = sqlc.arg(last_used_at)
+WHERE id = sqlc.arg(id)
+  AND last_used_at < sqlc.arg(used_before);
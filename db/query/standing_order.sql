@@ -0,0 +1,80 @@
+-- name: CreateStandingOrder :one
+INSERT INTO standing_orders (
+  from_account_id,
+  to_account_id,
+  amount,
+  frequency,
+  interval_count,
+  cron_expression,
+  start_at,
+  end_at,
+  max_occurrences,
+  next_run_at,
+  creator
+) VALUES (
+  $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11
+) RETURNING *;
+
+-- name: GetStandingOrder :one
+SELECT * FROM standing_orders
+WHERE id = $1 LIMIT 1;
+
+-- name: GetStandingOrderForUpdate :one
+SELECT * FROM standing_orders
+WHERE id = $1 LIMIT 1
+FOR NO KEY UPDATE;
+
+-- name: ListDueStandingOrders :many
+SELECT * FROM standing_orders
+WHERE status = 'active' AND next_run_at <= $1
+ORDER BY next_run_at
+LIMIT $2;
+
+-- name: UpdateStandingOrderSchedule :one
+UPDATE standing_orders
+SET
+  occurrences = $2,
+  next_run_at = $3,
+  status = $4
+WHERE id = $1
+RETURNING *;
+
+-- name: PauseStandingOrder :one
+UPDATE standing_orders
+SET status = 'paused'
+WHERE id = $1 AND status = 'active'
+RETURNING *;
+
+-- name: ResumeStandingOrder :one
+UPDATE standing_orders
+SET
+  status = 'active',
+  next_run_at = $2
+WHERE id = $1 AND status = 'paused'
+RETURNING *;
+
+-- name: CancelStandingOrder :one
+UPDATE standing_orders
+SET
+  status = 'cancelled',
+  next_run_at = NULL
+WHERE id = $1 AND status IN ('active', 'paused')
+RETURNING *;
+
+-- name: CreateStandingOrderRun :one
+INSERT INTO standing_order_runs (
+  standing_order_id,
+  scheduled_at,
+  status,
+  transfer_id,
+  error
+) VALUES (
+  $1, $2, $3, $4, $5
+) RETURNING *;
+
+-- name: ListStandingOrderRuns :many
+SELECT * FROM standing_order_runs
+WHERE standing_order_id = $1
+ORDER BY id
+LIMIT $2
+OFFSET $3;
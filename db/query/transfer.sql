@@ -2,15 +2,37 @@
 INSERT INTO transfers (
   from_account_id,
   to_account_id,
-  amount
+  amount,
+  initiator,
+  fee,
+  fee_account_id
 ) VALUES (
-  $1, $2, $3
+  $1, $2, $3, $4, $5, $6
+) RETURNING *;
+
+-- name: CreatePendingTransfer :one
+INSERT INTO transfers (
+  from_account_id,
+  to_account_id,
+  amount,
+  initiator,
+  fee,
+  fee_account_id,
+  status,
+  expires_at
+) VALUES (
+  $1, $2, $3, $4, $5, $6, 'pending', $7
 ) RETURNING *;
 
 -- name: GetTransfer :one
 SELECT * FROM transfers
 WHERE id = $1 LIMIT 1;
 
+-- name: GetTransferForUpdate :one
+SELECT * FROM transfers
+WHERE id = $1 LIMIT 1
+FOR NO KEY UPDATE;
+
 -- name: ListTransfers :many
 SELECT * FROM transfers
 WHERE
@@ -19,3 +41,18 @@ WHERE
 ORDER BY id
   LIMIT $3
 OFFSET $4;
+
+-- name: ListExpiredPendingTransfers :many
+SELECT * FROM transfers
+WHERE status = 'pending' AND expires_at <= $1
+ORDER BY expires_at
+LIMIT $2;
+
+-- name: UpdateTransferStatus :one
+UPDATE transfers
+SET
+  status = $2,
+  approver = $3,
+  decided_at = now()
+WHERE id = $1
+RETURNING *;
@@ -0,0 +1,37 @@
+-- name: CreateTransferBatch :one
+INSERT INTO transfer_batches (
+  mode,
+  status,
+  creator
+) VALUES (
+  $1, $2, $3
+) RETURNING *;
+
+-- name: GetTransferBatch :one
+SELECT * FROM transfer_batches
+WHERE id = $1 LIMIT 1;
+
+-- name: UpdateTransferBatchStatus :one
+UPDATE transfer_batches
+SET status = $2
+WHERE id = $1
+RETURNING *;
+
+-- name: CreateTransferBatchItem :one
+INSERT INTO transfer_batch_items (
+  batch_id,
+  item_index,
+  from_account_id,
+  to_account_id,
+  amount,
+  status,
+  transfer_id,
+  error
+) VALUES (
+  $1, $2, $3, $4, $5, $6, $7, $8
+) RETURNING *;
+
+-- name: ListTransferBatchItems :many
+SELECT * FROM transfer_batch_items
+WHERE batch_id = $1
+ORDER BY item_index;
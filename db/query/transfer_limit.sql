@@ -0,0 +1,59 @@
+-- name: UpsertTransferLimit :one
+INSERT INTO transfer_limits (
+  tier,
+  currency,
+  max_single_amount,
+  daily_account_amount,
+  monthly_account_amount,
+  daily_user_amount,
+  monthly_user_amount,
+  approval_threshold
+) VALUES (
+  $1, $2, $3, $4, $5, $6, $7, $8
+) ON CONFLICT (tier, currency) DO UPDATE SET
+  max_single_amount = EXCLUDED.max_single_amount,
+  daily_account_amount = EXCLUDED.daily_account_amount,
+  monthly_account_amount = EXCLUDED.monthly_account_amount,
+  daily_user_amount = EXCLUDED.daily_user_amount,
+  monthly_user_amount = EXCLUDED.monthly_user_amount,
+  approval_threshold = EXCLUDED.approval_threshold,
+  updated_at = now()
+RETURNING *;
+
+-- name: GetTransferLimit :one
+SELECT * FROM transfer_limits
+WHERE tier = $1 AND currency = $2 LIMIT 1;
+
+-- name: ListTransferLimits :many
+SELECT * FROM transfer_limits
+ORDER BY tier, currency;
+
+-- name: DeleteTransferLimit :exec
+DELETE FROM transfer_limits
+WHERE tier = $1 AND currency = $2;
+
+-- name: AddTransferUsage :one
+INSERT INTO transfer_usages (
+  subject,
+  currency,
+  period,
+  period_start,
+  amount
+) VALUES (
+  $1, $2, $3, date_trunc($3, now())::date, $4
+) ON CONFLICT (subject, currency, period, period_start) DO UPDATE SET
+  amount = transfer_usages.amount + EXCLUDED.amount
+RETURNING amount;
+
+-- name: SubtractTransferUsage :exec
+UPDATE transfer_usages
+SET amount = amount - sqlc.arg(amount)
+WHERE subject = sqlc.arg(subject)
+  AND currency = sqlc.arg(currency)
+  AND period = sqlc.arg(period)
+  AND period_start = date_trunc(sqlc.arg(period), sqlc.arg(accounted_at)::timestamptz)::date;
+
+-- name: GetTransferUsage :one
+SELECT COALESCE(SUM(amount), 0)::bigint AS amount
+FROM transfer_usages
+WHERE subject = $1 AND currency = $2 AND period = $3 AND period_start = date_trunc($3, now())::date;
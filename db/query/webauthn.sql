@@ -0,0 +1,62 @@
+-- name: CreateWebAuthnCredential :one
+INSERT INTO webauthn_credentials (
+  credential_id,
+  username,
+  user_handle,
+  name,
+  public_key,
+  attestation_type,
+  transports,
+  aaguid,
+  sign_count,
+  backup_eligible,
+  backup_state
+) VALUES (
+  $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11
+) RETURNING *;
+
+-- name: GetWebAuthnCredential :one
+SELECT * FROM webauthn_credentials
+WHERE id = $1 LIMIT 1;
+
+-- name: GetWebAuthnCredentialByCredentialID :one
+SELECT * FROM webauthn_credentials
+WHERE credential_id = $1 LIMIT 1;
+
+-- name: ListWebAuthnCredentials :many
+SELECT * FROM webauthn_credentials
+WHERE username = $1
+ORDER BY id;
+
+-- name: UpdateWebAuthnCredentialUse :one
+UPDATE webauthn_credentials
+SET
+  sign_count = sqlc.arg(sign_count),
+  backup_state = sqlc.arg(backup_state),
+  last_used_at = sqlc.arg(last_used_at)
+WHERE id = sqlc.arg(id)
+RETURNING *;
+
+-- name: DeleteWebAuthnCredential :exec
+DELETE FROM webauthn_credentials
+WHERE id = $1;
+
+-- name: CreateWebAuthnChallenge :one
+INSERT INTO webauthn_challenges (
+  challenge,
+  ceremony,
+  username,
+  session_data,
+  expires_at
+) VALUES (
+  $1, $2, $3, $4, $5
+) RETURNING *;
+
+-- name: UseWebAuthnChallenge :one
+UPDATE webauthn_challenges
+SET used_at = sqlc.arg(now)
+WHERE challenge = sqlc.arg(challenge)
+  AND ceremony = sqlc.arg(ceremony)
+  AND used_at IS NULL
+  AND expires_at > sqlc.arg(now)
+RETURNING *;
@@ -0,0 +1,99 @@
+-- name: CreateWebhookSubscription :one
+INSERT INTO webhook_subscriptions (
+  owner,
+  url,
+  event_types,
+  secret
+) VALUES (
+  $1, $2, $3, $4
+) RETURNING *;
+
+-- name: GetWebhookSubscription :one
+SELECT * FROM webhook_subscriptions
+WHERE id = $1 LIMIT 1;
+
+-- name: GetWebhookSubscriptionForUpdate :one
+SELECT * FROM webhook_subscriptions
+WHERE id = $1 LIMIT 1
+FOR NO KEY UPDATE;
+
+-- name: ListWebhookSubscriptions :many
+SELECT * FROM webhook_subscriptions
+WHERE owner = $1
+ORDER BY id;
+
+-- name: UpdateWebhookSubscription :one
+UPDATE webhook_subscriptions
+SET
+  url = $2,
+  event_types = $3
+WHERE id = $1
+RETURNING *;
+
+-- name: UpdateWebhookSubscriptionFailures :one
+UPDATE webhook_subscriptions
+SET
+  failure_count = $2,
+  disabled_at = $3
+WHERE id = $1
+RETURNING *;
+
+-- name: DeleteWebhookSubscription :exec
+DELETE FROM webhook_subscriptions
+WHERE id = $1;
+
+-- name: CreateWebhookDeliveries :execrows
+INSERT INTO webhook_deliveries (subscription_id, event_id)
+SELECT webhook_subscriptions.id, sqlc.arg(event_id)::bigint
+FROM webhook_subscriptions
+WHERE disabled_at IS NULL
+  AND sqlc.arg(event_type)::varchar = ANY(event_types)
+  AND owner IN (
+    SELECT accounts.owner FROM accounts
+    WHERE accounts.id = ANY(sqlc.arg(account_ids)::bigint[])
+  );
+
+-- name: GetWebhookDelivery :one
+SELECT * FROM webhook_deliveries
+WHERE id = $1 LIMIT 1;
+
+-- name: GetWebhookDeliveryForUpdate :one
+SELECT * FROM webhook_deliveries
+WHERE id = $1 LIMIT 1
+FOR NO KEY UPDATE;
+
+-- name: ListWebhookDeliveries :many
+SELECT * FROM webhook_deliveries
+WHERE subscription_id = $1
+ORDER BY id DESC
+LIMIT $2
+OFFSET $3;
+
+-- name: ClaimWebhookDeliveries :many
+UPDATE webhook_deliveries
+SET next_attempt_at = sqlc.arg(lease_until)::timestamptz
+WHERE id IN (
+  SELECT webhook_deliveries.id FROM webhook_deliveries
+  WHERE status = 'pending'
+    AND next_attempt_at <= sqlc.arg(now)::timestamptz
+    AND subscription_id IN (
+      SELECT webhook_subscriptions.id FROM webhook_subscriptions
+      WHERE disabled_at IS NULL
+    )
+  ORDER BY next_attempt_at
+  LIMIT sqlc.arg(max_deliveries)::int
+  FOR UPDATE SKIP LOCKED
+)
+RETURNING *;
+
+-- name: UpdateWebhookDelivery :one
+UPDATE webhook_deliveries
+SET
+  status = $2,
+  attempts = attempts + 1,
+  response_status = $3,
+  error = $4,
+  next_attempt_at = $5,
+  delivered_at = $6
+WHERE id = $1
+RETURNING *;
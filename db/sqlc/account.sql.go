@@ -7,13 +7,15 @@ package db
 
 import (
 	"context"
+
+	"github.com/jackc/pgx/v5/pgtype"
 )
 
 const addAccountBalanceParams = `-- name: AddAccountBalanceParams :one
 UPDATE accounts
 SET balance = balance + $1
 WHERE id = $2
-RETURNING id, owner, balance, currency, created_at
+RETURNING id, owner, balance, currency, created_at, held_balance, available_balance, closed_at
 `
 
 type AddAccountBalanceParamsParams struct {
@@ -30,10 +32,85 @@ func (q *Queries) AddAccountBalanceParams(ctx context.Context, arg AddAccountBal
 		&i.Balance,
 		&i.Currency,
 		&i.CreatedAt,
+		&i.HeldBalance,
+		&i.AvailableBalance,
+		&i.ClosedAt,
 	)
 	return i, err
 }
 
+const addAccountHeldBalance = `-- name: AddAccountHeldBalance :one
+UPDATE accounts
+SET held_balance = held_balance + $1
+WHERE id = $2
+RETURNING id, owner, balance, currency, created_at, held_balance, available_balance, closed_at
+`
+
+type AddAccountHeldBalanceParams struct {
+	Amount int64 `json:"amount"`
+	ID     int64 `json:"id"`
+}
+
+func (q *Queries) AddAccountHeldBalance(ctx context.Context, arg AddAccountHeldBalanceParams) (Account, error) {
+	row := q.db.QueryRow(ctx, addAccountHeldBalance, arg.Amount, arg.ID)
+	var i Account
+	err := row.Scan(
+		&i.ID,
+		&i.Owner,
+		&i.Balance,
+		&i.Currency,
+		&i.CreatedAt,
+		&i.HeldBalance,
+		&i.AvailableBalance,
+		&i.ClosedAt,
+	)
+	return i, err
+}
+
+const closeZeroBalanceAccounts = `-- name: CloseZeroBalanceAccounts :many
+UPDATE accounts
+SET closed_at = $1
+WHERE owner = $2
+  AND balance = 0
+  AND held_balance = 0
+  AND closed_at IS NULL
+RETURNING id, owner, balance, currency, created_at, held_balance, available_balance, closed_at
+`
+
+type CloseZeroBalanceAccountsParams struct {
+	ClosedAt pgtype.Timestamptz `json:"closed_at"`
+	Owner    string             `json:"owner"`
+}
+
+func (q *Queries) CloseZeroBalanceAccounts(ctx context.Context, arg CloseZeroBalanceAccountsParams) ([]Account, error) {
+	rows, err := q.db.Query(ctx, closeZeroBalanceAccounts, arg.ClosedAt, arg.Owner)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Account{}
+	for rows.Next() {
+		var i Account
+		if err := rows.Scan(
+			&i.ID,
+			&i.Owner,
+			&i.Balance,
+			&i.Currency,
+			&i.CreatedAt,
+			&i.HeldBalance,
+			&i.AvailableBalance,
+			&i.ClosedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const createAccount = `-- name: CreateAccount :one
 INSERT INTO accounts (
   owner,
@@ -41,7 +118,7 @@ INSERT INTO accounts (
   currency
 ) VALUES (
   $1, $2, $3
-) RETURNING id, owner, balance, currency, created_at
+) RETURNING id, owner, balance, currency, created_at, held_balance, available_balance, closed_at
 `
 
 type CreateAccountParams struct {
@@ -59,6 +136,9 @@ func (q *Queries) CreateAccount(ctx context.Context, arg CreateAccountParams) (A
 		&i.Balance,
 		&i.Currency,
 		&i.CreatedAt,
+		&i.HeldBalance,
+		&i.AvailableBalance,
+		&i.ClosedAt,
 	)
 	return i, err
 }
@@ -74,7 +154,7 @@ func (q *Queries) DeleteAccount(ctx context.Context, id int64) error {
 }
 
 const getAccount = `-- name: GetAccount :one
-SELECT id, owner, balance, currency, created_at FROM accounts
+SELECT id, owner, balance, currency, created_at, held_balance, available_balance, closed_at FROM accounts
 WHERE id = $1 LIMIT 1
 `
 
@@ -87,12 +167,15 @@ func (q *Queries) GetAccount(ctx context.Context, id int64) (Account, error) {
 		&i.Balance,
 		&i.Currency,
 		&i.CreatedAt,
+		&i.HeldBalance,
+		&i.AvailableBalance,
+		&i.ClosedAt,
 	)
 	return i, err
 }
 
 const getAccountForUpdate = `-- name: GetAccountForUpdate :one
-SELECT id, owner, balance, currency, created_at FROM accounts
+SELECT id, owner, balance, currency, created_at, held_balance, available_balance, closed_at FROM accounts
 WHERE id = $1 LIMIT 1
 FOR NO KEY UPDATE
 `
@@ -106,12 +189,15 @@ func (q *Queries) GetAccountForUpdate(ctx context.Context, id int64) (Account, e
 		&i.Balance,
 		&i.Currency,
 		&i.CreatedAt,
+		&i.HeldBalance,
+		&i.AvailableBalance,
+		&i.ClosedAt,
 	)
 	return i, err
 }
 
 const listAccounts = `-- name: ListAccounts :many
-SELECT id, owner, balance, currency, created_at FROM accounts
+SELECT id, owner, balance, currency, created_at, held_balance, available_balance, closed_at FROM accounts
 ORDER BY id
 LIMIT $1
 OFFSET $2
@@ -137,6 +223,9 @@ func (q *Queries) ListAccounts(ctx context.Context, arg ListAccountsParams) ([]A
 			&i.Balance,
 			&i.Currency,
 			&i.CreatedAt,
+			&i.HeldBalance,
+			&i.AvailableBalance,
+			&i.ClosedAt,
 		); err != nil {
 			return nil, err
 		}
@@ -152,7 +241,7 @@ const updateAccount = `-- name: UpdateAccount :one
 UPDATE accounts
 SET balance = $2
 WHERE id = $1
-RETURNING id, owner, balance, currency, created_at
+RETURNING id, owner, balance, currency, created_at, held_balance, available_balance, closed_at
 `
 
 type UpdateAccountParams struct {
@@ -169,6 +258,9 @@ func (q *Queries) UpdateAccount(ctx context.Context, arg UpdateAccountParams) (A
 		&i.Balance,
 		&i.Currency,
 		&i.CreatedAt,
+		&i.HeldBalance,
+		&i.AvailableBalance,
+		&i.ClosedAt,
 	)
 	return i, err
 }
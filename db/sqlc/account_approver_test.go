@@ -0,0 +1,47 @@
+package db
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAccountApprovers(t *testing.T) {
+	account := CreateRandomAccount(t)
+	user1 := CreateRandomUser(t)
+	user2 := CreateRandomUser(t)
+
+	for _, user := range []User{user1, user2} {
+		approver, err := testQueries.CreateAccountApprover(context.Background(), CreateAccountApproverParams{
+			AccountID: account.ID,
+			Username:  user.Username,
+		})
+		require.NoError(t, err)
+		require.Equal(t, account.ID, approver.AccountID)
+		require.Equal(t, user.Username, approver.Username)
+		require.NotZero(t, approver.CreatedAt)
+	}
+
+	approvers, err := testQueries.ListAccountApprovers(context.Background(), account.ID)
+	require.NoError(t, err)
+	require.Len(t, approvers, 2)
+
+	err = testQueries.DeleteAccountApprover(context.Background(), DeleteAccountApproverParams{
+		AccountID: account.ID,
+		Username:  user1.Username,
+	})
+	require.NoError(t, err)
+
+	_, err = testQueries.GetAccountApprover(context.Background(), GetAccountApproverParams{
+		AccountID: account.ID,
+		Username:  user1.Username,
+	})
+	require.ErrorIs(t, err, ErrRecordNotFound)
+
+	_, err = testQueries.GetAccountApprover(context.Background(), GetAccountApproverParams{
+		AccountID: account.ID,
+		Username:  user2.Username,
+	})
+	require.NoError(t, err)
+}
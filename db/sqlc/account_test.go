@@ -5,7 +5,7 @@ import (
 	"testing"
 	"time"
 
-	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/roman-adamchik/simplebank/util"
 	"github.com/stretchr/testify/require"
 )
@@ -18,7 +18,7 @@ func CreateRandomAccount(t *testing.T) Account {
 	args := CreateAccountParams{
 		Owner:    user.Username,
 		Currency: util.RandomCurrency(),
-		Balance:  util.RandomMoney(),
+		Balance:  util.RandomInt(100, 1000),
 	}
 	account, err := testQueries.CreateAccount(context.Background(), args)
 	require.NoError(t, err)
@@ -32,6 +32,27 @@ func CreateRandomAccount(t *testing.T) Account {
 	return account
 }
 
+// closeAccount empties and closes the account
+func closeAccount(t *testing.T, account Account) Account {
+	t.Helper()
+
+	_, err := testQueries.UpdateAccount(context.Background(), UpdateAccountParams{
+		ID:      account.ID,
+		Balance: 0,
+	})
+	require.NoError(t, err)
+
+	accounts, err := testQueries.CloseZeroBalanceAccounts(context.Background(), CloseZeroBalanceAccountsParams{
+		ClosedAt: pgtype.Timestamptz{Time: time.Now(), Valid: true},
+		Owner:    account.Owner,
+	})
+	require.NoError(t, err)
+	require.Len(t, accounts, 1)
+	require.True(t, accounts[0].ClosedAt.Valid)
+
+	return accounts[0]
+}
+
 func TestCreateAccount(t *testing.T) {
 	CreateRandomAccount(t)
 }
@@ -70,7 +91,7 @@ func TestDeleteAccount(t *testing.T) {
 	require.NoError(t, err)
 	checkAccount, err := testQueries.GetAccount(context.Background(), account.ID)
 	require.Error(t, err)
-	require.EqualError(t, err, pgx.ErrNoRows.Error())
+	require.ErrorIs(t, err, ErrRecordNotFound)
 	require.Empty(t, checkAccount)
 }
 
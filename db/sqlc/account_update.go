@@ -0,0 +1,28 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+// AccountUpdatesChannel is the Postgres notification channel account updates are sent on
+const AccountUpdatesChannel = "account_updates"
+
+// AccountUpdate is the notification sent when an entry changes the balance of an account
+type AccountUpdate struct {
+	Entry   Entry   `json:"entry"`
+	Account Account `json:"account"`
+}
+
+// notifyAccountUpdate sends an account update on AccountUpdatesChannel.
+// Notifications are delivered when the db transaction commits and dropped if it rolls back
+func notifyAccountUpdate(ctx context.Context, q *Queries, entry Entry, account Account) error {
+	payload, err := json.Marshal(AccountUpdate{Entry: entry, Account: account})
+	if err != nil {
+		return fmt.Errorf("cannot marshal account update: %w", err)
+	}
+
+	_, err = q.db.Exec(ctx, "SELECT pg_notify($1, $2)", AccountUpdatesChannel, string(payload))
+	return err
+}
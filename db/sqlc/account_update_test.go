@@ -0,0 +1,90 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTransferTxNotifiesAccountUpdates(t *testing.T) {
+	store := NewStore(testPool)
+	account1 := CreateRandomAccount(t)
+	account2 := CreateRandomAccount(t)
+
+	conn, err := testPool.Acquire(context.Background())
+	require.NoError(t, err)
+	defer conn.Release()
+
+	_, err = conn.Exec(context.Background(), "LISTEN "+AccountUpdatesChannel)
+	require.NoError(t, err)
+	defer conn.Exec(context.Background(), "UNLISTEN *")
+
+	result, err := store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: account1.ID,
+		ToAccountID:   account2.ID,
+		Amount:        10,
+	})
+	require.NoError(t, err)
+
+	// other tests may transfer concurrently, so only the updates of this transfer are checked
+	updates := make(map[int64]AccountUpdate)
+	for len(updates) < 2 {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		notification, err := conn.Conn().WaitForNotification(ctx)
+		cancel()
+		require.NoError(t, err)
+		require.Equal(t, AccountUpdatesChannel, notification.Channel)
+
+		var update AccountUpdate
+		require.NoError(t, json.Unmarshal([]byte(notification.Payload), &update))
+		if update.Entry.TransferID.Int64 == result.Transfer.ID {
+			updates[update.Entry.ID] = update
+		}
+	}
+
+	fromUpdate := updates[result.FromEntry.ID]
+	require.Equal(t, account1.ID, fromUpdate.Account.ID)
+	require.Equal(t, result.FromAccount.Balance, fromUpdate.Account.Balance)
+	require.Equal(t, int64(-10), fromUpdate.Entry.Amount)
+
+	toUpdate := updates[result.ToEntry.ID]
+	require.Equal(t, account2.Owner, toUpdate.Account.Owner)
+	require.Equal(t, result.ToAccount.Balance, toUpdate.Account.Balance)
+}
+
+func TestFailedTransferTxDoesNotNotify(t *testing.T) {
+	store := NewStore(testPool)
+	account1 := CreateRandomAccount(t)
+	account2 := CreateRandomAccount(t)
+
+	conn, err := testPool.Acquire(context.Background())
+	require.NoError(t, err)
+	defer conn.Release()
+
+	_, err = conn.Exec(context.Background(), "LISTEN "+AccountUpdatesChannel)
+	require.NoError(t, err)
+	defer conn.Exec(context.Background(), "UNLISTEN *")
+
+	_, err = store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: account1.ID,
+		ToAccountID:   account2.ID,
+		Amount:        account1.Balance + 1,
+	})
+	require.ErrorIs(t, err, ErrInsufficientFunds)
+
+	for {
+		ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+		notification, err := conn.Conn().WaitForNotification(ctx)
+		cancel()
+		if err != nil {
+			return
+		}
+
+		var update AccountUpdate
+		require.NoError(t, json.Unmarshal([]byte(notification.Payload), &update))
+		require.NotEqual(t, account1.ID, update.Account.ID)
+	}
+}
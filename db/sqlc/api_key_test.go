@@ -0,0 +1,87 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/roman-adamchik/simplebank/util"
+	"github.com/stretchr/testify/require"
+)
+
+func createRandomAPIKey(t *testing.T, user User) ApiKey {
+	t.Helper()
+
+	arg := CreateAPIKeyParams{
+		Username:   user.Username,
+		Name:       util.RandomOwner(),
+		KeyPrefix:  "sbk_" + util.RandomString(6),
+		KeyHash:    util.HashSecret(util.RandomString(32)),
+		Scopes:     []string{"accounts:read", "transfers:write"},
+		AllowedIps: []string{"198.51.100.0/24"},
+		CreatedBy:  user.Username,
+		ExpiresAt:  pgtype.Timestamptz{Time: time.Now().Add(time.Hour), Valid: true},
+	}
+	apiKey, err := testQueries.CreateAPIKey(context.Background(), arg)
+	require.NoError(t, err)
+	require.NotZero(t, apiKey.ID)
+	require.Equal(t, arg.Username, apiKey.Username)
+	require.Equal(t, arg.KeyHash, apiKey.KeyHash)
+	require.Equal(t, arg.Scopes, apiKey.Scopes)
+	require.Equal(t, arg.AllowedIps, apiKey.AllowedIps)
+	require.WithinDuration(t, arg.ExpiresAt.Time, apiKey.ExpiresAt.Time, time.Second)
+	require.False(t, apiKey.LastUsedAt.Valid)
+	require.False(t, apiKey.RevokedAt.Valid)
+
+	return apiKey
+}
+
+func TestGetAPIKeyByHash(t *testing.T) {
+	apiKey := createRandomAPIKey(t, CreateRandomUser(t))
+
+	got, err := testQueries.GetAPIKeyByHash(context.Background(), apiKey.KeyHash)
+	require.NoError(t, err)
+	require.Equal(t, apiKey.ID, got.ID)
+
+	_, err = testQueries.GetAPIKeyByHash(context.Background(), util.HashSecret(util.RandomString(32)))
+	require.ErrorIs(t, err, ErrRecordNotFound)
+}
+
+func TestRevokeAPIKey(t *testing.T) {
+	apiKey := createRandomAPIKey(t, CreateRandomUser(t))
+	now := pgtype.Timestamptz{Time: time.Now(), Valid: true}
+
+	revoked, err := testQueries.RevokeAPIKey(context.Background(), RevokeAPIKeyParams{RevokedAt: now, ID: apiKey.ID})
+	require.NoError(t, err)
+	require.WithinDuration(t, now.Time, revoked.RevokedAt.Time, time.Second)
+
+	_, err = testQueries.RevokeAPIKey(context.Background(), RevokeAPIKeyParams{RevokedAt: now, ID: apiKey.ID})
+	require.ErrorIs(t, err, ErrRecordNotFound)
+}
+
+func TestTouchAPIKey(t *testing.T) {
+	apiKey := createRandomAPIKey(t, CreateRandomUser(t))
+	now := time.Now()
+
+	touch := func(at time.Time) {
+		err := testQueries.TouchAPIKey(context.Background(), TouchAPIKeyParams{
+			LastUsedAt: pgtype.Timestamptz{Time: at, Valid: true},
+			ID:         apiKey.ID,
+			UsedBefore: pgtype.Timestamptz{Time: at.Add(-time.Minute), Valid: true},
+		})
+		require.NoError(t, err)
+	}
+
+	touch(now)
+	// a use within the interval is not written down
+	touch(now.Add(30 * time.Second))
+	got, err := testQueries.GetAPIKey(context.Background(), apiKey.ID)
+	require.NoError(t, err)
+	require.WithinDuration(t, now, got.LastUsedAt.Time, time.Second)
+
+	touch(now.Add(2 * time.Minute))
+	got, err = testQueries.GetAPIKey(context.Background(), apiKey.ID)
+	require.NoError(t, err)
+	require.WithinDuration(t, now.Add(2*time.Minute), got.LastUsedAt.Time, time.Second)
+}
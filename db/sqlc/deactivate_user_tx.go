@@ -0,0 +1,79 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// ErrUserDeactivated is returned when deactivating a user who is already deactivated
+var ErrUserDeactivated = errors.New("user is deactivated")
+
+// DeactivateUserTxParams contains the input parameters of the deactivate user transaction
+type DeactivateUserTxParams struct {
+	Username string    `json:"username"`
+	Now      time.Time `json:"now"`
+}
+
+// DeactivateUserTxResult is the result of the deactivate user transaction
+type DeactivateUserTxResult struct {
+	User User `json:"user"`
+	// ClosedAccounts are the accounts of the user without funds, the others stay open until they are emptied
+	ClosedAccounts []Account `json:"closed_accounts"`
+}
+
+// DeactivateUserTx deactivates a user, signs out their sessions, revokes their API keys and OAuth grants
+// and closes their accounts without funds within a single db transaction
+func (s *SQLStore) DeactivateUserTx(ctx context.Context, arg DeactivateUserTxParams) (DeactivateUserTxResult, error) {
+	var result DeactivateUserTxResult
+
+	err := s.execTx(ctx, func(q *Queries) error {
+		var err error
+
+		now := pgtype.Timestamptz{Time: arg.Now, Valid: true}
+		result.User, err = q.DeactivateUser(ctx, DeactivateUserParams{
+			DeactivatedAt: now,
+			Username:      arg.Username,
+		})
+		if err != nil {
+			if errors.Is(err, ErrRecordNotFound) {
+				return ErrUserDeactivated
+			}
+			return err
+		}
+
+		_, err = q.RevokeUserSessions(ctx, RevokeUserSessionsParams{
+			RevokedAt: now,
+			Username:  arg.Username,
+		})
+		if err != nil {
+			return err
+		}
+
+		err = q.RevokeUserAPIKeys(ctx, RevokeUserAPIKeysParams{
+			RevokedAt: now,
+			Username:  arg.Username,
+		})
+		if err != nil {
+			return err
+		}
+
+		err = q.RevokeUserOAuthRefreshTokens(ctx, RevokeUserOAuthRefreshTokensParams{
+			RevokedAt: now,
+			Username:  arg.Username,
+		})
+		if err != nil {
+			return err
+		}
+
+		result.ClosedAccounts, err = q.CloseZeroBalanceAccounts(ctx, CloseZeroBalanceAccountsParams{
+			ClosedAt: now,
+			Owner:    arg.Username,
+		})
+		return err
+	})
+
+	return result, err
+}
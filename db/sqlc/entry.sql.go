@@ -7,6 +7,8 @@ package db
 
 import (
 	"context"
+
+	"github.com/jackc/pgx/v5/pgtype"
 )
 
 const createEntry = `-- name: CreateEntry :one
@@ -15,7 +17,7 @@ INSERT INTO entries (
   amount
 ) VALUES (
   $1, $2
-) RETURNING id, account_id, amount, created_at
+) RETURNING id, account_id, amount, created_at, kind, transfer_id
 `
 
 type CreateEntryParams struct {
@@ -31,12 +33,51 @@ func (q *Queries) CreateEntry(ctx context.Context, arg CreateEntryParams) (Entry
 		&i.AccountID,
 		&i.Amount,
 		&i.CreatedAt,
+		&i.Kind,
+		&i.TransferID,
+	)
+	return i, err
+}
+
+const createTransferEntry = `-- name: CreateTransferEntry :one
+INSERT INTO entries (
+  account_id,
+  amount,
+  kind,
+  transfer_id
+) VALUES (
+  $1, $2, $3, $4
+) RETURNING id, account_id, amount, created_at, kind, transfer_id
+`
+
+type CreateTransferEntryParams struct {
+	AccountID  int64       `json:"account_id"`
+	Amount     int64       `json:"amount"`
+	Kind       string      `json:"kind"`
+	TransferID pgtype.Int8 `json:"transfer_id"`
+}
+
+func (q *Queries) CreateTransferEntry(ctx context.Context, arg CreateTransferEntryParams) (Entry, error) {
+	row := q.db.QueryRow(ctx, createTransferEntry,
+		arg.AccountID,
+		arg.Amount,
+		arg.Kind,
+		arg.TransferID,
+	)
+	var i Entry
+	err := row.Scan(
+		&i.ID,
+		&i.AccountID,
+		&i.Amount,
+		&i.CreatedAt,
+		&i.Kind,
+		&i.TransferID,
 	)
 	return i, err
 }
 
 const getEntry = `-- name: GetEntry :one
-SELECT id, account_id, amount, created_at FROM entries
+SELECT id, account_id, amount, created_at, kind, transfer_id FROM entries
 WHERE id = $1 LIMIT 1
 `
 
@@ -48,12 +89,14 @@ func (q *Queries) GetEntry(ctx context.Context, id int64) (Entry, error) {
 		&i.AccountID,
 		&i.Amount,
 		&i.CreatedAt,
+		&i.Kind,
+		&i.TransferID,
 	)
 	return i, err
 }
 
 const listEntries = `-- name: ListEntries :many
-SELECT id, account_id, amount, created_at FROM entries
+SELECT id, account_id, amount, created_at, kind, transfer_id FROM entries
 WHERE account_id = $1
 ORDER BY id
   LIMIT $2
@@ -80,6 +123,49 @@ func (q *Queries) ListEntries(ctx context.Context, arg ListEntriesParams) ([]Ent
 			&i.AccountID,
 			&i.Amount,
 			&i.CreatedAt,
+			&i.Kind,
+			&i.TransferID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listOwnerEntriesAfter = `-- name: ListOwnerEntriesAfter :many
+SELECT entries.id, entries.account_id, entries.amount, entries.created_at, entries.kind, entries.transfer_id FROM entries
+JOIN accounts ON accounts.id = entries.account_id
+WHERE accounts.owner = $1 AND entries.id > $2
+ORDER BY entries.id
+LIMIT $3
+`
+
+type ListOwnerEntriesAfterParams struct {
+	Owner string `json:"owner"`
+	ID    int64  `json:"id"`
+	Limit int32  `json:"limit"`
+}
+
+func (q *Queries) ListOwnerEntriesAfter(ctx context.Context, arg ListOwnerEntriesAfterParams) ([]Entry, error) {
+	rows, err := q.db.Query(ctx, listOwnerEntriesAfter, arg.Owner, arg.ID, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Entry{}
+	for rows.Next() {
+		var i Entry
+		if err := rows.Scan(
+			&i.ID,
+			&i.AccountID,
+			&i.Amount,
+			&i.CreatedAt,
+			&i.Kind,
+			&i.TransferID,
 		); err != nil {
 			return nil, err
 		}
@@ -0,0 +1,128 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgerrcode"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// Errors returned by the Store methods, so callers do not depend on the errors of the database driver.
+// A Store implementation must classify its errors the same way
+var (
+	// ErrRecordNotFound is returned when a query expecting a row finds none
+	ErrRecordNotFound = errors.New("record not found")
+	// ErrUniqueViolation is returned when a write would duplicate a unique value, see ConstraintError
+	ErrUniqueViolation = errors.New("unique constraint violation")
+	// ErrForeignKeyViolation is returned when a write refers to a missing record, see ConstraintError
+	ErrForeignKeyViolation = errors.New("foreign key violation")
+	// ErrRetryable is returned when a transaction failed with a serialization failure or a deadlock
+	// and can succeed when it is run again
+	ErrRetryable = errors.New("transaction conflict, retry")
+)
+
+// ConstraintError is a violation of an integrity constraint.
+// It matches its Violation, ErrUniqueViolation or ErrForeignKeyViolation, with errors.Is
+type ConstraintError struct {
+	Violation  error
+	Constraint string
+	cause      error
+}
+
+func (e *ConstraintError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Violation, e.Constraint)
+}
+
+func (e *ConstraintError) Unwrap() []error {
+	if e.cause == nil {
+		return []error{e.Violation}
+	}
+	return []error{e.Violation, e.cause}
+}
+
+// ConstraintName returns the name of the constraint a write violated, or an empty string if err is no violation
+func ConstraintName(err error) string {
+	var constraintErr *ConstraintError
+	if errors.As(err, &constraintErr) {
+		return constraintErr.Constraint
+	}
+	return ""
+}
+
+// classifyError wraps the database errors callers branch on with the matching Store error.
+// The original error stays in the chain, already classified errors are returned as they are
+func classifyError(err error) error {
+	if err == nil || isClassified(err) {
+		return err
+	}
+
+	if errors.Is(err, pgx.ErrNoRows) {
+		return fmt.Errorf("%w: %w", ErrRecordNotFound, err)
+	}
+
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		return err
+	}
+
+	switch pgErr.Code {
+	case pgerrcode.UniqueViolation:
+		return &ConstraintError{Violation: ErrUniqueViolation, Constraint: pgErr.ConstraintName, cause: err}
+	case pgerrcode.ForeignKeyViolation:
+		return &ConstraintError{Violation: ErrForeignKeyViolation, Constraint: pgErr.ConstraintName, cause: err}
+	case pgerrcode.SerializationFailure, pgerrcode.DeadlockDetected:
+		return fmt.Errorf("%w: %w", ErrRetryable, err)
+	}
+
+	return err
+}
+
+func isClassified(err error) bool {
+	var constraintErr *ConstraintError
+	return errors.As(err, &constraintErr) || errors.Is(err, ErrRecordNotFound) || errors.Is(err, ErrRetryable)
+}
+
+// classifyingDBTX classifies the errors of the queries it runs
+type classifyingDBTX struct {
+	db DBTX
+}
+
+func (c classifyingDBTX) Exec(ctx context.Context, sql string, args ...interface{}) (pgconn.CommandTag, error) {
+	tag, err := c.db.Exec(ctx, sql, args...)
+	return tag, classifyError(err)
+}
+
+func (c classifyingDBTX) Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error) {
+	rows, err := c.db.Query(ctx, sql, args...)
+	if err != nil {
+		return rows, classifyError(err)
+	}
+	return classifyingRows{rows}, nil
+}
+
+func (c classifyingDBTX) QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row {
+	return classifyingRow{c.db.QueryRow(ctx, sql, args...)}
+}
+
+type classifyingRow struct {
+	row pgx.Row
+}
+
+func (r classifyingRow) Scan(dest ...any) error {
+	return classifyError(r.row.Scan(dest...))
+}
+
+type classifyingRows struct {
+	pgx.Rows
+}
+
+func (r classifyingRows) Scan(dest ...any) error {
+	return classifyError(r.Rows.Scan(dest...))
+}
+
+func (r classifyingRows) Err() error {
+	return classifyError(r.Rows.Err())
+}
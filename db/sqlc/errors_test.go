@@ -0,0 +1,73 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/jackc/pgerrcode"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/roman-adamchik/simplebank/util"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClassifyError(t *testing.T) {
+	require.NoError(t, classifyError(nil))
+	require.Equal(t, ErrInsufficientFunds, classifyError(ErrInsufficientFunds))
+
+	err := classifyError(pgx.ErrNoRows)
+	require.ErrorIs(t, err, ErrRecordNotFound)
+	require.ErrorIs(t, err, pgx.ErrNoRows)
+
+	err = classifyError(&pgconn.PgError{Code: pgerrcode.UniqueViolation, ConstraintName: "owner_currency_key"})
+	require.ErrorIs(t, err, ErrUniqueViolation)
+	require.NotErrorIs(t, err, ErrForeignKeyViolation)
+	require.Equal(t, "owner_currency_key", ConstraintName(err))
+
+	err = classifyError(&pgconn.PgError{Code: pgerrcode.ForeignKeyViolation, ConstraintName: "accounts_owner_fkey"})
+	require.ErrorIs(t, err, ErrForeignKeyViolation)
+	require.Equal(t, "accounts_owner_fkey", ConstraintName(err))
+
+	for _, code := range []string{pgerrcode.SerializationFailure, pgerrcode.DeadlockDetected} {
+		err = classifyError(fmt.Errorf("tx err: %w", &pgconn.PgError{Code: code}))
+		require.ErrorIs(t, err, ErrRetryable)
+		retryCode, ok := retryableCode(err)
+		require.True(t, ok)
+		require.Equal(t, code, retryCode)
+	}
+
+	pgErr := &pgconn.PgError{Code: pgerrcode.CheckViolation}
+	require.Equal(t, error(pgErr), classifyError(pgErr))
+
+	// classifying twice does not wrap again
+	err = classifyError(pgx.ErrNoRows)
+	require.Equal(t, err, classifyError(err))
+	require.Empty(t, ConstraintName(err))
+}
+
+func TestStoreClassifiesErrors(t *testing.T) {
+	store := NewStore(testPool)
+	account := CreateRandomAccount(t)
+
+	_, err := store.GetAccount(context.Background(), -1)
+	require.ErrorIs(t, err, ErrRecordNotFound)
+
+	_, err = store.CreateAccountTx(context.Background(), CreateAccountParams{
+		Owner:    account.Owner,
+		Currency: account.Currency,
+	})
+	require.ErrorIs(t, err, ErrUniqueViolation)
+	require.Equal(t, "owner_currency_key", ConstraintName(err))
+
+	_, err = store.CreateAccountTx(context.Background(), CreateAccountParams{
+		Owner:    util.RandomOwner(),
+		Currency: util.RandomCurrency(),
+	})
+	require.ErrorIs(t, err, ErrForeignKeyViolation)
+	require.Equal(t, "accounts_owner_fkey", ConstraintName(err))
+
+	var constraintErr *ConstraintError
+	require.True(t, errors.As(err, &constraintErr))
+}
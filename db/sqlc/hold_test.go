@@ -0,0 +1,303 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/require"
+)
+
+func createRandomHold(t *testing.T, account Account, amount int64, ttl time.Duration) Hold {
+	t.Helper()
+
+	store := NewStore(testPool)
+
+	result, err := store.CreateHoldTx(context.Background(), CreateHoldTxParams{
+		AccountID: account.ID,
+		Amount:    amount,
+		ExpiresAt: time.Now().Add(ttl),
+	})
+	require.NoError(t, err)
+
+	hold := result.Hold
+	require.NotZero(t, hold.ID)
+	require.Equal(t, account.ID, hold.AccountID)
+	require.Equal(t, amount, hold.Amount)
+	require.Equal(t, HoldActive, hold.Status)
+	require.Zero(t, hold.CapturedAmount)
+	require.False(t, hold.TransferID.Valid)
+	require.WithinDuration(t, time.Now().Add(ttl), hold.ExpiresAt.Time, 5*time.Second)
+
+	// the money stays on the account but is no longer available
+	require.Equal(t, account.Balance, result.Account.Balance)
+	require.Equal(t, account.HeldBalance+amount, result.Account.HeldBalance)
+	require.Equal(t, account.AvailableBalance-amount, result.Account.AvailableBalance)
+
+	return hold
+}
+
+// accountUsage returns the outgoing amount accounted for the account in the current period
+func accountUsage(t *testing.T, account Account, period string) int64 {
+	t.Helper()
+
+	used, err := testQueries.GetTransferUsage(context.Background(), GetTransferUsageParams{
+		Subject:  fmt.Sprintf("account:%d", account.ID),
+		Currency: account.Currency,
+		Period:   period,
+	})
+	require.NoError(t, err)
+	return used
+}
+
+func TestCreateHoldTx(t *testing.T) {
+	store := NewStore(testPool)
+
+	account := CreateRandomAccount(t)
+	toAccount := CreateRandomAccount(t)
+	createRandomHold(t, account, account.Balance-10, time.Hour)
+
+	// transfers only see the available balance
+	_, err := store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: account.ID,
+		ToAccountID:   toAccount.ID,
+		Amount:        11,
+	})
+	require.ErrorIs(t, err, ErrInsufficientFunds)
+
+	_, err = store.CreateHoldTx(context.Background(), CreateHoldTxParams{
+		AccountID: account.ID,
+		Amount:    11,
+		ExpiresAt: time.Now().Add(time.Hour),
+	})
+	require.ErrorIs(t, err, ErrInsufficientFunds)
+
+	result, err := store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: account.ID,
+		ToAccountID:   toAccount.ID,
+		Amount:        10,
+	})
+	require.NoError(t, err)
+	require.Zero(t, result.FromAccount.AvailableBalance)
+}
+
+func TestCaptureHoldTx(t *testing.T) {
+	store := NewStore(testPool)
+
+	account := CreateRandomAccount(t)
+	toAccount := CreateRandomAccount(t)
+	hold := createRandomHold(t, account, 50, time.Hour)
+
+	result, err := store.CaptureHoldTx(context.Background(), CaptureHoldTxParams{
+		HoldID:      hold.ID,
+		ToAccountID: toAccount.ID,
+		Amount:      30,
+		Initiator:   account.Owner,
+	})
+	require.NoError(t, err)
+
+	require.Equal(t, HoldCaptured, result.Hold.Status)
+	require.Equal(t, int64(30), result.Hold.CapturedAmount)
+	require.True(t, result.Hold.TransferID.Valid)
+	require.Equal(t, result.Transfer.Transfer.ID, result.Hold.TransferID.Int64)
+
+	transfer := result.Transfer.Transfer
+	require.Equal(t, TransferCompleted, transfer.Status)
+	require.Equal(t, int64(30), transfer.Amount)
+	require.Equal(t, account.Owner, transfer.Initiator)
+
+	// the rest of the hold is released
+	require.Equal(t, account.Balance-30, result.Transfer.FromAccount.Balance)
+	require.Zero(t, result.Transfer.FromAccount.HeldBalance)
+	require.Equal(t, toAccount.Balance+30, result.Transfer.ToAccount.Balance)
+	require.Equal(t, int64(30), accountUsage(t, account, PeriodDay))
+	require.Equal(t, int64(30), accountUsage(t, account, PeriodMonth))
+
+	_, err = store.CaptureHoldTx(context.Background(), CaptureHoldTxParams{
+		HoldID:      hold.ID,
+		ToAccountID: toAccount.ID,
+		Amount:      10,
+	})
+	require.ErrorIs(t, err, ErrHoldNotActive)
+}
+
+func TestCreateHoldTxRequiresApproval(t *testing.T) {
+	store := NewStore(testPool)
+
+	account := createLimitedAccount(t, UpsertTransferLimitParams{
+		ApprovalThreshold: pgtype.Int8{Int64: 10, Valid: true},
+	})
+
+	// a hold would let the capture skip the approval of the transfer
+	_, err := store.CreateHoldTx(context.Background(), CreateHoldTxParams{
+		AccountID: account.ID,
+		Amount:    11,
+		ExpiresAt: time.Now().Add(time.Hour),
+	})
+	require.ErrorIs(t, err, ErrApprovalRequired)
+
+	createRandomHold(t, account, 10, time.Hour)
+}
+
+func TestCaptureHoldTxFee(t *testing.T) {
+	store := NewStore(testPool)
+
+	account, revenueAccount := createChargedAccount(t, UpsertFeeScheduleParams{
+		FlatAmount:    2,
+		PercentageBps: 1000,
+	})
+	account, err := testQueries.UpdateAccount(context.Background(), UpdateAccountParams{
+		ID:      account.ID,
+		Balance: 100,
+	})
+	require.NoError(t, err)
+	toAccount := CreateRandomAccount(t)
+
+	// the fee of the whole hold has to be available when it is placed
+	_, err = store.CreateHoldTx(context.Background(), CreateHoldTxParams{
+		AccountID: account.ID,
+		Amount:    95,
+		ExpiresAt: time.Now().Add(time.Hour),
+	})
+	require.ErrorIs(t, err, ErrInsufficientFunds)
+
+	hold := createRandomHold(t, account, 50, time.Hour)
+
+	result, err := store.CaptureHoldTx(context.Background(), CaptureHoldTxParams{
+		HoldID:      hold.ID,
+		ToAccountID: toAccount.ID,
+		Amount:      30,
+		Initiator:   account.Owner,
+	})
+	require.NoError(t, err)
+
+	transfer := result.Transfer.Transfer
+	require.Equal(t, int64(30), transfer.Amount)
+	require.Equal(t, int64(5), transfer.Fee)
+	require.Equal(t, revenueAccount.ID, transfer.FeeAccountID.Int64)
+	require.Equal(t, int64(-5), result.Transfer.FeeEntry.Amount)
+	require.Equal(t, EntryFee, result.Transfer.FeeEntry.Kind)
+
+	require.Equal(t, int64(65), result.Transfer.FromAccount.Balance)
+	require.Zero(t, result.Transfer.FromAccount.HeldBalance)
+
+	revenueAccount2, err := testQueries.GetAccount(context.Background(), revenueAccount.ID)
+	require.NoError(t, err)
+	require.Equal(t, revenueAccount.Balance+5, revenueAccount2.Balance)
+}
+
+func TestCaptureHoldTxExceedsHold(t *testing.T) {
+	store := NewStore(testPool)
+
+	account := CreateRandomAccount(t)
+	toAccount := CreateRandomAccount(t)
+	hold := createRandomHold(t, account, 50, time.Hour)
+
+	_, err := store.CaptureHoldTx(context.Background(), CaptureHoldTxParams{
+		HoldID:      hold.ID,
+		ToAccountID: toAccount.ID,
+		Amount:      51,
+	})
+	require.ErrorIs(t, err, ErrCaptureExceedsHold)
+
+	// nothing changed
+	hold2, err := testQueries.GetHold(context.Background(), hold.ID)
+	require.NoError(t, err)
+	require.Equal(t, HoldActive, hold2.Status)
+}
+
+func TestCaptureHoldTxClosedAccount(t *testing.T) {
+	store := NewStore(testPool)
+
+	account := CreateRandomAccount(t)
+	toAccount := closeAccount(t, CreateRandomAccount(t))
+	hold := createRandomHold(t, account, 50, time.Hour)
+
+	_, err := store.CaptureHoldTx(context.Background(), CaptureHoldTxParams{
+		HoldID:      hold.ID,
+		ToAccountID: toAccount.ID,
+		Amount:      50,
+	})
+	require.ErrorIs(t, err, ErrAccountClosed)
+
+	// the hold can still be released
+	released, err := store.ReleaseHoldTx(context.Background(), hold.ID)
+	require.NoError(t, err)
+	require.Equal(t, HoldReleased, released.Status)
+}
+
+func TestCaptureHoldTxExpired(t *testing.T) {
+	store := NewStore(testPool)
+
+	account := CreateRandomAccount(t)
+	toAccount := CreateRandomAccount(t)
+	hold := createRandomHold(t, account, 50, time.Second)
+	time.Sleep(time.Second)
+
+	_, err := store.CaptureHoldTx(context.Background(), CaptureHoldTxParams{
+		HoldID:      hold.ID,
+		ToAccountID: toAccount.ID,
+		Amount:      50,
+	})
+	require.ErrorIs(t, err, ErrHoldExpired)
+}
+
+func TestReleaseHoldTx(t *testing.T) {
+	store := NewStore(testPool)
+
+	account := CreateRandomAccount(t)
+	hold := createRandomHold(t, account, 50, time.Hour)
+
+	released, err := store.ReleaseHoldTx(context.Background(), hold.ID)
+	require.NoError(t, err)
+	require.Equal(t, HoldReleased, released.Status)
+	require.Zero(t, released.CapturedAmount)
+	require.Zero(t, accountUsage(t, account, PeriodDay))
+
+	account2, err := testQueries.GetAccount(context.Background(), account.ID)
+	require.NoError(t, err)
+	require.Equal(t, account.Balance, account2.Balance)
+	require.Equal(t, account.AvailableBalance, account2.AvailableBalance)
+
+	_, err = store.ReleaseHoldTx(context.Background(), hold.ID)
+	require.ErrorIs(t, err, ErrHoldNotActive)
+}
+
+func TestExpireHoldTx(t *testing.T) {
+	store := NewStore(testPool)
+
+	account := CreateRandomAccount(t)
+	hold := createRandomHold(t, account, 50, time.Hour)
+
+	// not overdue yet
+	_, err := store.ExpireHoldTx(context.Background(), ExpireHoldTxParams{
+		HoldID: hold.ID,
+		Now:    time.Now(),
+	})
+	require.ErrorIs(t, err, ErrHoldNotActive)
+
+	now := time.Now().Add(2 * time.Hour)
+	holds, err := testQueries.ListExpiredHolds(context.Background(), ListExpiredHoldsParams{
+		ExpiresAt: pgtype.Timestamptz{Time: now, Valid: true},
+		Limit:     1000,
+	})
+	require.NoError(t, err)
+	ids := make(map[int64]bool)
+	for _, hold := range holds {
+		ids[hold.ID] = true
+	}
+	require.Contains(t, ids, hold.ID)
+
+	expired, err := store.ExpireHoldTx(context.Background(), ExpireHoldTxParams{
+		HoldID: hold.ID,
+		Now:    now,
+	})
+	require.NoError(t, err)
+	require.Equal(t, HoldExpired, expired.Status)
+
+	account2, err := testQueries.GetAccount(context.Background(), account.ID)
+	require.NoError(t, err)
+	require.Equal(t, account.AvailableBalance, account2.AvailableBalance)
+}
@@ -0,0 +1,253 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// list of hold statuses
+const (
+	HoldActive   = "active"
+	HoldCaptured = "captured"
+	HoldReleased = "released"
+	HoldExpired  = "expired"
+)
+
+// DefaultHoldTTL is how long funds stay on hold by default before they are released
+const DefaultHoldTTL = 7 * 24 * time.Hour
+
+// Different types of errors returned by the hold transactions
+var (
+	ErrHoldNotActive      = errors.New("hold is not active")
+	ErrHoldExpired        = errors.New("hold has expired")
+	ErrCaptureExceedsHold = errors.New("captured amount exceeds the held amount")
+)
+
+// CreateHoldTxParams contains the input parameters of the hold transaction
+type CreateHoldTxParams struct {
+	AccountID int64     `json:"account_id"`
+	Amount    int64     `json:"amount"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// CreateHoldTxResult is the result of the hold transaction
+type CreateHoldTxResult struct {
+	Hold    Hold    `json:"hold"`
+	Account Account `json:"account"`
+}
+
+// CreateHoldTx reserves an amount of the account's available balance without moving it.
+// Transfer limits are checked when the funds are reserved, so a hold can always be captured.
+// An amount that needs a second approval cannot be held, it returns ErrApprovalRequired and has to be transferred.
+// The available balance must also cover the fee of capturing the full amount, the fee itself is not reserved
+func (s *SQLStore) CreateHoldTx(ctx context.Context, arg CreateHoldTxParams) (CreateHoldTxResult, error) {
+	var result CreateHoldTxResult
+
+	err := s.ExecTxWithOptions(ctx, serializableTx, func(q *Queries) error {
+		err := lockOpenAccounts(ctx, q, arg.AccountID)
+		if err != nil {
+			return err
+		}
+
+		policy, err := loadTransferPolicy(ctx, q, arg.AccountID)
+		if err != nil {
+			return err
+		}
+		if policy.requiresApproval(arg.Amount) {
+			return ErrApprovalRequired
+		}
+		fee, _, err := transferFee(ctx, q, policy, arg.Amount)
+		if err != nil {
+			return err
+		}
+		if policy.account.AvailableBalance < arg.Amount+fee {
+			return ErrInsufficientFunds
+		}
+		err = checkTransferLimits(ctx, q, policy, arg.Amount)
+		if err != nil {
+			return err
+		}
+
+		result.Hold, err = q.CreateHold(ctx, CreateHoldParams{
+			AccountID: arg.AccountID,
+			Amount:    arg.Amount,
+			ExpiresAt: pgtype.Timestamptz{Time: arg.ExpiresAt, Valid: true},
+		})
+		if err != nil {
+			return err
+		}
+
+		result.Account, err = q.AddAccountHeldBalance(ctx, AddAccountHeldBalanceParams{
+			ID:     arg.AccountID,
+			Amount: arg.Amount,
+		})
+		return err
+	})
+
+	return result, err
+}
+
+// CaptureHoldTxParams contains the input parameters of the capture transaction
+type CaptureHoldTxParams struct {
+	HoldID      int64  `json:"hold_id"`
+	ToAccountID int64  `json:"to_account_id"`
+	Amount      int64  `json:"amount"`
+	Initiator   string `json:"initiator"`
+}
+
+// CaptureHoldTxResult is the result of the capture transaction
+type CaptureHoldTxResult struct {
+	Hold     Hold             `json:"hold"`
+	Transfer TransferTxResult `json:"transfer"`
+}
+
+// CaptureHoldTx moves all or part of the held amount to another account within a single db transaction.
+// The hold is closed by the capture, any amount not captured is released and given back to the transfer usage.
+// The captured amount is charged the fee of a transfer, which is paid from the available balance of the account
+func (s *SQLStore) CaptureHoldTx(ctx context.Context, arg CaptureHoldTxParams) (CaptureHoldTxResult, error) {
+	var result CaptureHoldTxResult
+
+	err := s.execTx(ctx, func(q *Queries) error {
+		hold, err := getActiveHold(ctx, q, arg.HoldID, time.Now(), arg.ToAccountID)
+		if err != nil {
+			return err
+		}
+		if arg.Amount > hold.Amount {
+			return ErrCaptureExceedsHold
+		}
+		err = lockOpenAccounts(ctx, q, hold.AccountID, arg.ToAccountID)
+		if err != nil {
+			return err
+		}
+
+		err = releaseHeldFunds(ctx, q, hold.AccountID, hold.Amount)
+		if err != nil {
+			return err
+		}
+
+		policy, err := loadTransferPolicy(ctx, q, hold.AccountID)
+		if err != nil {
+			return err
+		}
+		fee, feeAccountID, err := transferFee(ctx, q, policy, arg.Amount)
+		if err != nil {
+			return err
+		}
+		if policy.account.AvailableBalance < arg.Amount+fee {
+			return ErrInsufficientFunds
+		}
+
+		transfer, err := q.CreateTransfer(ctx, CreateTransferParams{
+			FromAccountID: hold.AccountID,
+			ToAccountID:   arg.ToAccountID,
+			Amount:        arg.Amount,
+			Initiator:     arg.Initiator,
+			Fee:           fee,
+			FeeAccountID:  feeAccountID,
+		})
+		if err != nil {
+			return err
+		}
+
+		result.Transfer, err = moveMoney(ctx, q, transfer)
+		if err != nil {
+			return err
+		}
+
+		err = refundTransferUsage(ctx, q, policy, hold.Amount-arg.Amount, hold.CreatedAt)
+		if err != nil {
+			return err
+		}
+
+		result.Hold, err = q.UpdateHoldStatus(ctx, UpdateHoldStatusParams{
+			ID:             hold.ID,
+			Status:         HoldCaptured,
+			CapturedAmount: arg.Amount,
+			TransferID:     pgtype.Int8{Int64: transfer.ID, Valid: true},
+		})
+		return err
+	})
+
+	return result, err
+}
+
+// ReleaseHoldTx releases an active hold, making its amount available again and giving it back to the transfer usage
+func (s *SQLStore) ReleaseHoldTx(ctx context.Context, holdID int64) (Hold, error) {
+	return s.closeHoldTx(ctx, holdID, HoldReleased, time.Now())
+}
+
+// ExpireHoldTxParams contains the input parameters of the hold expiry transaction
+type ExpireHoldTxParams struct {
+	HoldID int64     `json:"hold_id"`
+	Now    time.Time `json:"now"`
+}
+
+// ExpireHoldTx releases a hold that was not captured before its expiry.
+// It returns ErrHoldNotActive if the hold was closed or is not overdue yet
+func (s *SQLStore) ExpireHoldTx(ctx context.Context, arg ExpireHoldTxParams) (Hold, error) {
+	return s.closeHoldTx(ctx, arg.HoldID, HoldExpired, arg.Now)
+}
+
+func (s *SQLStore) closeHoldTx(ctx context.Context, holdID int64, status string, now time.Time) (Hold, error) {
+	var hold Hold
+
+	err := s.execTx(ctx, func(q *Queries) error {
+		var err error
+		hold, err = getActiveHold(ctx, q, holdID, now)
+		switch {
+		case status == HoldExpired && errors.Is(err, ErrHoldExpired):
+		case status == HoldExpired && err == nil:
+			return ErrHoldNotActive
+		case err != nil:
+			return err
+		}
+
+		err = releaseHeldFunds(ctx, q, hold.AccountID, hold.Amount)
+		if err != nil {
+			return err
+		}
+
+		policy, err := loadTransferPolicy(ctx, q, hold.AccountID)
+		if err != nil {
+			return err
+		}
+		err = refundTransferUsage(ctx, q, policy, hold.Amount, hold.CreatedAt)
+		if err != nil {
+			return err
+		}
+
+		hold, err = q.UpdateHoldStatus(ctx, UpdateHoldStatusParams{
+			ID:     hold.ID,
+			Status: status,
+		})
+		return err
+	})
+
+	return hold, err
+}
+
+// getActiveHold locks a hold together with its account and the other accounts the caller is going to change.
+// It returns ErrHoldExpired along with the hold if the hold is overdue at the given time
+func getActiveHold(ctx context.Context, q *Queries, holdID int64, now time.Time, accountIDs ...int64) (Hold, error) {
+	hold, err := q.GetHoldForUpdate(ctx, holdID)
+	if err != nil {
+		return hold, err
+	}
+
+	err = lockAccounts(ctx, q, append(accountIDs, hold.AccountID)...)
+	if err != nil {
+		return hold, err
+	}
+
+	if hold.Status != HoldActive {
+		return hold, ErrHoldNotActive
+	}
+	if !hold.ExpiresAt.Time.After(now) {
+		return hold, ErrHoldExpired
+	}
+
+	return hold, nil
+}
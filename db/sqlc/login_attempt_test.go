@@ -0,0 +1,174 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/roman-adamchik/simplebank/util"
+	"github.com/stretchr/testify/require"
+)
+
+func loginThrottleFailures(t *testing.T, username, clientIP string) map[string]int32 {
+	t.Helper()
+
+	throttles, err := testQueries.ListLoginThrottles(context.Background(), []string{UserThrottleKey(username), IPThrottleKey(clientIP)})
+	require.NoError(t, err)
+
+	failures := make(map[string]int32)
+	for _, throttle := range throttles {
+		failures[throttle.Key] = throttle.Failures
+	}
+	return failures
+}
+
+// blockAfter returns the check of a login that refuses it once a throttle has reached the given failures
+func blockAfter(failures int32, until time.Time) func([]LoginThrottle) time.Time {
+	return func(throttles []LoginThrottle) time.Time {
+		for _, throttle := range throttles {
+			if throttle.Failures >= failures {
+				return until
+			}
+		}
+		return time.Time{}
+	}
+}
+
+func TestStartLoginAttemptTx(t *testing.T) {
+	store := NewStore(testPool)
+	username := util.RandomOwner()
+	clientIP := fmt.Sprintf("198.51.100.%d", util.RandomInt(1, 254))
+	now := time.Now()
+	until := now.Add(time.Minute)
+
+	start := func(now time.Time, check func([]LoginThrottle) time.Time) time.Time {
+		blockedUntil, err := store.StartLoginAttemptTx(context.Background(), StartLoginAttemptTxParams{
+			Username:     username,
+			ClientIP:     clientIP,
+			Now:          now,
+			Window:       time.Hour,
+			BlockedUntil: check,
+		})
+		require.NoError(t, err)
+		return blockedUntil
+	}
+
+	// started attempts count against both the username and the client IP
+	require.Zero(t, start(now, blockAfter(2, until)))
+	require.Zero(t, start(now, blockAfter(2, until)))
+	failures := loginThrottleFailures(t, username, clientIP)
+	require.Equal(t, int32(2), failures[UserThrottleKey(username)])
+	require.Equal(t, int32(2), failures[IPThrottleKey(clientIP)])
+
+	// refused attempts do not count
+	require.WithinDuration(t, until, start(now, blockAfter(2, until)), time.Second)
+	failures = loginThrottleFailures(t, username, clientIP)
+	require.Equal(t, int32(2), failures[UserThrottleKey(username)])
+
+	// an attempt after the window starts counting again
+	require.Zero(t, start(now.Add(2*time.Hour), blockAfter(3, until)))
+	failures = loginThrottleFailures(t, username, clientIP)
+	require.Equal(t, int32(1), failures[UserThrottleKey(username)])
+	require.Equal(t, int32(1), failures[IPThrottleKey(clientIP)])
+}
+
+func TestStartLoginAttemptTxConcurrent(t *testing.T) {
+	store := NewStore(testPool)
+	username := util.RandomOwner()
+	clientIP := fmt.Sprintf("203.0.113.%d", util.RandomInt(1, 254))
+	now := time.Now()
+
+	// concurrent attempts cannot all pass the check before any of them is counted
+	n := 10
+	free := int32(3)
+	results := make(chan time.Time)
+	errs := make(chan error)
+
+	for i := 0; i < n; i++ {
+		go func() {
+			blockedUntil, err := store.StartLoginAttemptTx(context.Background(), StartLoginAttemptTxParams{
+				Username:     username,
+				ClientIP:     clientIP,
+				Now:          now,
+				Window:       time.Hour,
+				BlockedUntil: blockAfter(free, now.Add(time.Minute)),
+			})
+			errs <- err
+			results <- blockedUntil
+		}()
+	}
+
+	started := 0
+	for i := 0; i < n; i++ {
+		require.NoError(t, <-errs)
+		if (<-results).IsZero() {
+			started++
+		}
+	}
+	require.Equal(t, int(free), started)
+
+	failures := loginThrottleFailures(t, username, clientIP)
+	require.Equal(t, free, failures[UserThrottleKey(username)])
+	require.Equal(t, free, failures[IPThrottleKey(clientIP)])
+}
+
+func TestRecordLoginAttemptTx(t *testing.T) {
+	store := NewStore(testPool)
+	username := util.RandomOwner()
+	clientIP := fmt.Sprintf("192.0.2.%d", util.RandomInt(1, 254))
+
+	start := func() {
+		blockedUntil, err := store.StartLoginAttemptTx(context.Background(), StartLoginAttemptTxParams{
+			Username:     username,
+			ClientIP:     clientIP,
+			Now:          time.Now(),
+			Window:       time.Hour,
+			BlockedUntil: func([]LoginThrottle) time.Time { return time.Time{} },
+		})
+		require.NoError(t, err)
+		require.Zero(t, blockedUntil)
+	}
+	record := func(reason string) LoginAttempt {
+		attempt, err := store.RecordLoginAttemptTx(context.Background(), RecordLoginAttemptTxParams{
+			Username:      username,
+			ClientIP:      clientIP,
+			UserAgent:     "test",
+			FailureReason: reason,
+		})
+		require.NoError(t, err)
+		require.Equal(t, username, attempt.Username)
+		require.Equal(t, reason == "", attempt.Succeeded)
+		return attempt
+	}
+
+	// failures were counted when their attempts started
+	start()
+	record(LoginWrongPassword)
+	start()
+	record(LoginUnknownUser)
+	failures := loginThrottleFailures(t, username, clientIP)
+	require.Equal(t, int32(2), failures[UserThrottleKey(username)])
+	require.Equal(t, int32(2), failures[IPThrottleKey(clientIP)])
+
+	// throttled logins do not count
+	record(LoginThrottled)
+	failures = loginThrottleFailures(t, username, clientIP)
+	require.Equal(t, int32(2), failures[UserThrottleKey(username)])
+
+	// a success resets the username and takes back its own failure of the client IP
+	start()
+	record("")
+	failures = loginThrottleFailures(t, username, clientIP)
+	require.NotContains(t, failures, UserThrottleKey(username))
+	require.Equal(t, int32(2), failures[IPThrottleKey(clientIP)])
+
+	attempts, err := testQueries.ListLoginAttempts(context.Background(), ListLoginAttemptsParams{
+		Username: username,
+		Limit:    10,
+	})
+	require.NoError(t, err)
+	require.Len(t, attempts, 4)
+	require.True(t, attempts[0].Succeeded)
+	require.Equal(t, LoginThrottled, attempts[1].FailureReason)
+}
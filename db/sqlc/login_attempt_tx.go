@@ -0,0 +1,137 @@
+package db
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// list of reasons a login fails for
+const (
+	LoginUnknownUser   = "unknown_user"
+	LoginWrongPassword = "wrong_password"
+	LoginWrongMFACode  = "wrong_mfa_code"
+	// LoginInvalidPasskey is a passkey login whose assertion could not be verified
+	LoginInvalidPasskey = "invalid_passkey"
+	// LoginDeactivated is a login of a deactivated user with the right password
+	LoginDeactivated = "deactivated"
+	// LoginThrottled is a login refused before its credentials were checked
+	LoginThrottled = "throttled"
+)
+
+// UserThrottleKey is the key of the login throttle of a username
+func UserThrottleKey(username string) string {
+	return "user:" + username
+}
+
+// IPThrottleKey is the key of the login throttle of a client IP
+func IPThrottleKey(clientIP string) string {
+	return "ip:" + clientIP
+}
+
+// StartLoginAttemptTxParams contains the input parameters of the transaction starting a login attempt
+type StartLoginAttemptTxParams struct {
+	Username string    `json:"username"`
+	ClientIP string    `json:"client_ip"`
+	Now      time.Time `json:"now"`
+	// Window is how long a failure counts against the throttles
+	Window time.Duration `json:"window"`
+	// BlockedUntil is the time before which the throttles refuse a login, zero if they allow it
+	BlockedUntil func(throttles []LoginThrottle) time.Time `json:"-"`
+}
+
+// StartLoginAttemptTx checks the login throttles of the username and the client IP before the credentials are,
+// and counts the attempt as a failure against both unless they refuse it, within a single db transaction.
+// The throttles are locked while they are checked and counted, so concurrent logins cannot all pass the same check.
+// It returns the time before which the login is refused, zero if the attempt was started.
+// A started attempt is finished with RecordLoginAttemptTx, or UncountLoginFailures if it needs another step
+func (s *SQLStore) StartLoginAttemptTx(ctx context.Context, arg StartLoginAttemptTxParams) (time.Time, error) {
+	var blockedUntil time.Time
+
+	err := s.execTx(ctx, func(q *Queries) error {
+		now := pgtype.Timestamptz{Time: arg.Now, Valid: true}
+
+		// keys are locked in order, so concurrent attempts cannot deadlock
+		keys := []string{UserThrottleKey(arg.Username), IPThrottleKey(arg.ClientIP)}
+		slices.Sort(keys)
+
+		throttles := make([]LoginThrottle, 0, len(keys))
+		for _, key := range keys {
+			throttle, err := q.LockLoginThrottle(ctx, LockLoginThrottleParams{
+				Key: key,
+				Now: now,
+			})
+			if err != nil {
+				return err
+			}
+			throttles = append(throttles, throttle)
+		}
+
+		blockedUntil = arg.BlockedUntil(throttles)
+		if !blockedUntil.IsZero() {
+			return nil
+		}
+
+		for _, key := range keys {
+			_, err := q.IncrementLoginThrottle(ctx, IncrementLoginThrottleParams{
+				Key:         key,
+				Now:         now,
+				WindowStart: pgtype.Timestamptz{Time: arg.Now.Add(-arg.Window), Valid: true},
+			})
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+
+	return blockedUntil, err
+}
+
+// RecordLoginAttemptTxParams contains the input parameters of the login attempt transaction
+type RecordLoginAttemptTxParams struct {
+	Username  string `json:"username"`
+	ClientIP  string `json:"client_ip"`
+	UserAgent string `json:"user_agent"`
+	// FailureReason is empty if the login succeeded
+	FailureReason string `json:"failure_reason"`
+}
+
+// RecordLoginAttemptTx records the outcome of a login attempt in the audit log and updates the login throttles
+// within a single db transaction.
+// A failure was already counted against the throttles when the attempt was started, while a success
+// resets the throttle of the username and takes back the failure counted against the client IP,
+// so that a valid account does not lift the limit of an IP.
+// A throttled login was never started and changes no throttle
+func (s *SQLStore) RecordLoginAttemptTx(ctx context.Context, arg RecordLoginAttemptTxParams) (LoginAttempt, error) {
+	var attempt LoginAttempt
+
+	err := s.execTx(ctx, func(q *Queries) error {
+		var err error
+		attempt, err = q.CreateLoginAttempt(ctx, CreateLoginAttemptParams{
+			Username:      arg.Username,
+			ClientIp:      arg.ClientIP,
+			UserAgent:     arg.UserAgent,
+			Succeeded:     arg.FailureReason == "",
+			FailureReason: arg.FailureReason,
+		})
+		if err != nil {
+			return err
+		}
+
+		if arg.FailureReason != "" {
+			return nil
+		}
+
+		err = q.DeleteLoginThrottle(ctx, UserThrottleKey(arg.Username))
+		if err != nil {
+			return err
+		}
+		return q.UncountLoginFailures(ctx, []string{IPThrottleKey(arg.ClientIP)})
+	})
+
+	return attempt, err
+}
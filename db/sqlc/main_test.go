@@ -36,7 +36,7 @@ func TestMain(m *testing.M) {
 		os.Exit(1)
 	}
 
-	testQueries = New(testPool)
+	testQueries = New(classifyingDBTX{testPool})
 	exitCode := m.Run()
 	testPool.Close()
 
@@ -0,0 +1,79 @@
+package db
+
+import (
+	"context"
+	"testing"
+
+	"github.com/roman-adamchik/simplebank/util"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUpsertTOTPSecret(t *testing.T) {
+	user := CreateRandomUser(t)
+
+	secret, err := testQueries.UpsertTOTPSecret(context.Background(), UpsertTOTPSecretParams{
+		Username:        user.Username,
+		EncryptedSecret: "first",
+	})
+	require.NoError(t, err)
+	require.False(t, secret.IsConfirmed)
+
+	// enrolling again before confirming replaces the secret
+	secret, err = testQueries.UpsertTOTPSecret(context.Background(), UpsertTOTPSecretParams{
+		Username:        user.Username,
+		EncryptedSecret: "second",
+	})
+	require.NoError(t, err)
+	require.Equal(t, "second", secret.EncryptedSecret)
+}
+
+func TestEnableMFATx(t *testing.T) {
+	store := NewStore(testPool)
+	user := CreateRandomUser(t)
+
+	_, err := testQueries.UpsertTOTPSecret(context.Background(), UpsertTOTPSecretParams{
+		Username:        user.Username,
+		EncryptedSecret: "secret",
+	})
+	require.NoError(t, err)
+
+	codeHashes := []string{util.HashSecret(util.RandomString(16)), util.HashSecret(util.RandomString(16))}
+	arg := EnableMFATxParams{
+		Username:           user.Username,
+		Step:               100,
+		RecoveryCodeHashes: codeHashes,
+	}
+	enabled, err := store.EnableMFATx(context.Background(), arg)
+	require.NoError(t, err)
+	require.True(t, enabled.IsMfaEnabled)
+
+	_, err = store.EnableMFATx(context.Background(), arg)
+	require.ErrorIs(t, err, ErrMFAAlreadyEnabled)
+
+	// a confirmed secret cannot be replaced by enrolling again
+	_, err = testQueries.UpsertTOTPSecret(context.Background(), UpsertTOTPSecretParams{
+		Username:        user.Username,
+		EncryptedSecret: "other",
+	})
+	require.ErrorIs(t, err, ErrRecordNotFound)
+
+	// a step is accepted once, and only after the one the secret was confirmed with
+	_, err = testQueries.UseTOTPStep(context.Background(), UseTOTPStepParams{Step: 100, Username: user.Username})
+	require.ErrorIs(t, err, ErrRecordNotFound)
+
+	totp, err := testQueries.UseTOTPStep(context.Background(), UseTOTPStepParams{Step: 101, Username: user.Username})
+	require.NoError(t, err)
+	require.Equal(t, int64(101), totp.LastUsedStep)
+
+	_, err = testQueries.UseTOTPStep(context.Background(), UseTOTPStepParams{Step: 101, Username: user.Username})
+	require.ErrorIs(t, err, ErrRecordNotFound)
+
+	// a recovery code is accepted once
+	useArg := UseRecoveryCodeParams{Username: user.Username, CodeHash: codeHashes[0]}
+	code, err := testQueries.UseRecoveryCode(context.Background(), useArg)
+	require.NoError(t, err)
+	require.True(t, code.IsUsed)
+
+	_, err = testQueries.UseRecoveryCode(context.Background(), useArg)
+	require.ErrorIs(t, err, ErrRecordNotFound)
+}
@@ -0,0 +1,54 @@
+package db
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrMFAAlreadyEnabled is returned when confirming a TOTP secret of a user whose 2FA is already enabled
+var ErrMFAAlreadyEnabled = errors.New("two-factor authentication is already enabled")
+
+// EnableMFATxParams contains the input parameters of the enable MFA transaction
+type EnableMFATxParams struct {
+	Username string `json:"username"`
+	// Step is the time step of the code the secret was confirmed with
+	Step               int64    `json:"step"`
+	RecoveryCodeHashes []string `json:"recovery_code_hashes"`
+}
+
+// EnableMFATx confirms the TOTP secret of a user, replaces their recovery codes and turns on 2FA
+// within a single db transaction
+func (s *SQLStore) EnableMFATx(ctx context.Context, arg EnableMFATxParams) (User, error) {
+	var user User
+
+	err := s.execTx(ctx, func(q *Queries) error {
+		_, err := q.ConfirmTOTPSecret(ctx, ConfirmTOTPSecretParams{
+			Step:     arg.Step,
+			Username: arg.Username,
+		})
+		if err != nil {
+			if errors.Is(err, ErrRecordNotFound) {
+				return ErrMFAAlreadyEnabled
+			}
+			return err
+		}
+
+		if err := q.DeleteRecoveryCodes(ctx, arg.Username); err != nil {
+			return err
+		}
+		for _, hash := range arg.RecoveryCodeHashes {
+			_, err := q.CreateRecoveryCode(ctx, CreateRecoveryCodeParams{
+				Username: arg.Username,
+				CodeHash: hash,
+			})
+			if err != nil {
+				return err
+			}
+		}
+
+		user, err = q.EnableUserMFA(ctx, arg.Username)
+		return err
+	})
+
+	return user, err
+}
@@ -9,11 +9,43 @@ import (
 )
 
 type Account struct {
-	ID        int64              `json:"id"`
-	Owner     string             `json:"owner"`
+	ID    int64  `json:"id"`
+	Owner string `json:"owner"`
+	// Ledger balance
 	Balance   int64              `json:"balance"`
 	Currency  string             `json:"currency"`
 	CreatedAt pgtype.Timestamptz `json:"created_at"`
+	// Funds reserved by pending transfers and active holds
+	HeldBalance int64 `json:"held_balance"`
+	// Ledger balance minus held funds
+	AvailableBalance int64 `json:"available_balance"`
+	// Set once the account is closed, it can no longer be used in transfers
+	ClosedAt pgtype.Timestamptz `json:"closed_at"`
+}
+
+type AccountApprover struct {
+	AccountID int64              `json:"account_id"`
+	Username  string             `json:"username"`
+	CreatedAt pgtype.Timestamptz `json:"created_at"`
+}
+
+type ApiKey struct {
+	ID       int64  `json:"id"`
+	Username string `json:"username"`
+	Name     string `json:"name"`
+	// Start of the key, shown so that the user can tell their keys apart
+	KeyPrefix string `json:"key_prefix"`
+	// SHA-256 of the key, the key itself is only shown to the user once
+	KeyHash string   `json:"key_hash"`
+	Scopes  []string `json:"scopes"`
+	// IPs and CIDR ranges the key can be used from, any if empty
+	AllowedIps []string `json:"allowed_ips"`
+	// User who created the key, an admin for the keys of service accounts
+	CreatedBy  string             `json:"created_by"`
+	ExpiresAt  pgtype.Timestamptz `json:"expires_at"`
+	LastUsedAt pgtype.Timestamptz `json:"last_used_at"`
+	RevokedAt  pgtype.Timestamptz `json:"revoked_at"`
+	CreatedAt  pgtype.Timestamptz `json:"created_at"`
 }
 
 type Entry struct {
@@ -22,6 +54,192 @@ type Entry struct {
 	// Can be negative or positive
 	Amount    int64              `json:"amount"`
 	CreatedAt pgtype.Timestamptz `json:"created_at"`
+	// transfer or fee
+	Kind       string      `json:"kind"`
+	TransferID pgtype.Int8 `json:"transfer_id"`
+}
+
+type FeeSchedule struct {
+	Tier       string `json:"tier"`
+	Currency   string `json:"currency"`
+	FlatAmount int64  `json:"flat_amount"`
+	// Percentage of the amount in basis points
+	PercentageBps int32 `json:"percentage_bps"`
+	// NULL means no minimum
+	MinAmount pgtype.Int8 `json:"min_amount"`
+	// NULL means no maximum
+	MaxAmount pgtype.Int8        `json:"max_amount"`
+	UpdatedAt pgtype.Timestamptz `json:"updated_at"`
+}
+
+type Hold struct {
+	ID             int64 `json:"id"`
+	AccountID      int64 `json:"account_id"`
+	Amount         int64 `json:"amount"`
+	CapturedAmount int64 `json:"captured_amount"`
+	// active, captured, released or expired
+	Status string `json:"status"`
+	// Transfer the hold was captured into
+	TransferID pgtype.Int8        `json:"transfer_id"`
+	ExpiresAt  pgtype.Timestamptz `json:"expires_at"`
+	CreatedAt  pgtype.Timestamptz `json:"created_at"`
+}
+
+type LoginAttempt struct {
+	ID int64 `json:"id"`
+	// Username as given, it may not belong to a user
+	Username  string `json:"username"`
+	ClientIp  string `json:"client_ip"`
+	UserAgent string `json:"user_agent"`
+	Succeeded bool   `json:"succeeded"`
+	// unknown_user, wrong_password, wrong_mfa_code, deactivated or throttled, empty if the login succeeded
+	FailureReason string             `json:"failure_reason"`
+	CreatedAt     pgtype.Timestamptz `json:"created_at"`
+}
+
+type LoginThrottle struct {
+	// user:<username> or ip:<client ip>
+	Key string `json:"key"`
+	// Failed logins since the last success, counted again from 1 once the last failure is older than the window
+	Failures      int32              `json:"failures"`
+	LastFailureAt pgtype.Timestamptz `json:"last_failure_at"`
+}
+
+type OauthAuthorizationCode struct {
+	// SHA-256 of the code, the code itself is only sent to the client
+	CodeHash    string   `json:"code_hash"`
+	ClientID    string   `json:"client_id"`
+	Username    string   `json:"username"`
+	RedirectUri string   `json:"redirect_uri"`
+	Scopes      []string `json:"scopes"`
+	// PKCE S256 challenge the code verifier must match
+	CodeChallenge string             `json:"code_challenge"`
+	ExpiresAt     pgtype.Timestamptz `json:"expires_at"`
+	UsedAt        pgtype.Timestamptz `json:"used_at"`
+	CreatedAt     pgtype.Timestamptz `json:"created_at"`
+}
+
+type OauthClient struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	// SHA-256 of the client secret, empty for public clients that cannot keep a secret
+	SecretHash   string   `json:"secret_hash"`
+	RedirectUris []string `json:"redirect_uris"`
+	// Scopes the client is allowed to ask users for
+	Scopes    []string           `json:"scopes"`
+	CreatedBy string             `json:"created_by"`
+	CreatedAt pgtype.Timestamptz `json:"created_at"`
+}
+
+type OauthRefreshToken struct {
+	ID int64 `json:"id"`
+	// SHA-256 of the token, the token itself is only sent to the client
+	TokenHash string             `json:"token_hash"`
+	ClientID  string             `json:"client_id"`
+	Username  string             `json:"username"`
+	Scopes    []string           `json:"scopes"`
+	ExpiresAt pgtype.Timestamptz `json:"expires_at"`
+	// Set when the token is rotated or revoked
+	RevokedAt pgtype.Timestamptz `json:"revoked_at"`
+	CreatedAt pgtype.Timestamptz `json:"created_at"`
+}
+
+type OutboxEvent struct {
+	// Offset of the event, events are relayed in id order as far as their transactions committed in that order
+	ID            int64              `json:"id"`
+	AggregateType string             `json:"aggregate_type"`
+	AggregateID   string             `json:"aggregate_id"`
+	EventType     string             `json:"event_type"`
+	Payload       []byte             `json:"payload"`
+	CreatedAt     pgtype.Timestamptz `json:"created_at"`
+	// NULL until the event is delivered
+	SentAt pgtype.Timestamptz `json:"sent_at"`
+	// Time until which a relay publishing the unsent event keeps other relays from claiming it
+	ClaimedUntil pgtype.Timestamptz `json:"claimed_until"`
+}
+
+type PasswordReset struct {
+	ID       int64  `json:"id"`
+	Username string `json:"username"`
+	// SHA-256 of the reset token, the token itself is only sent to the user
+	TokenHash string             `json:"token_hash"`
+	IsUsed    bool               `json:"is_used"`
+	CreatedAt pgtype.Timestamptz `json:"created_at"`
+	ExpiredAt pgtype.Timestamptz `json:"expired_at"`
+}
+
+type RecoveryCode struct {
+	ID       int64  `json:"id"`
+	Username string `json:"username"`
+	// SHA-256 of the recovery code, the code itself is only shown to the user once
+	CodeHash  string             `json:"code_hash"`
+	IsUsed    bool               `json:"is_used"`
+	CreatedAt pgtype.Timestamptz `json:"created_at"`
+}
+
+type RevenueAccount struct {
+	Currency string `json:"currency"`
+	// Account the fees in the currency are posted to
+	AccountID int64              `json:"account_id"`
+	UpdatedAt pgtype.Timestamptz `json:"updated_at"`
+}
+
+type Session struct {
+	ID        int64  `json:"id"`
+	Username  string `json:"username"`
+	UserAgent string `json:"user_agent"`
+	ClientIp  string `json:"client_ip"`
+	// When the access token of the session expires
+	ExpiresAt pgtype.Timestamptz `json:"expires_at"`
+	// Updated at most once a minute from the auth middleware
+	LastUsedAt pgtype.Timestamptz `json:"last_used_at"`
+	// Set when the user or support signs the session out
+	RevokedAt pgtype.Timestamptz `json:"revoked_at"`
+	CreatedAt pgtype.Timestamptz `json:"created_at"`
+}
+
+type StandingOrder struct {
+	ID            int64 `json:"id"`
+	FromAccountID int64 `json:"from_account_id"`
+	ToAccountID   int64 `json:"to_account_id"`
+	Amount        int64 `json:"amount"`
+	// weekly, monthly or custom
+	Frequency     string `json:"frequency"`
+	IntervalCount int32  `json:"interval_count"`
+	// Only used by custom schedules
+	CronExpression string             `json:"cron_expression"`
+	StartAt        pgtype.Timestamptz `json:"start_at"`
+	EndAt          pgtype.Timestamptz `json:"end_at"`
+	MaxOccurrences pgtype.Int4        `json:"max_occurrences"`
+	Occurrences    int32              `json:"occurrences"`
+	NextRunAt      pgtype.Timestamptz `json:"next_run_at"`
+	// active, paused, completed or cancelled
+	Status    string             `json:"status"`
+	CreatedAt pgtype.Timestamptz `json:"created_at"`
+	// User who created the standing order, its transfers are initiated by them
+	Creator string `json:"creator"`
+}
+
+type StandingOrderRun struct {
+	ID              int64              `json:"id"`
+	StandingOrderID int64              `json:"standing_order_id"`
+	ScheduledAt     pgtype.Timestamptz `json:"scheduled_at"`
+	// succeeded or failed
+	Status     string             `json:"status"`
+	TransferID pgtype.Int8        `json:"transfer_id"`
+	Error      string             `json:"error"`
+	CreatedAt  pgtype.Timestamptz `json:"created_at"`
+}
+
+type TotpSecret struct {
+	Username string `json:"username"`
+	// TOTP secret encrypted with the MFA encryption key
+	EncryptedSecret string `json:"encrypted_secret"`
+	// Set once the user proved they can generate codes, only then is the secret asked for at login
+	IsConfirmed bool `json:"is_confirmed"`
+	// Time step of the last accepted code, so that a code cannot be replayed
+	LastUsedStep int64              `json:"last_used_step"`
+	CreatedAt    pgtype.Timestamptz `json:"created_at"`
 }
 
 type Transfer struct {
@@ -31,6 +249,63 @@ type Transfer struct {
 	// Must be only positive
 	Amount    int64              `json:"amount"`
 	CreatedAt pgtype.Timestamptz `json:"created_at"`
+	// pending, approved, rejected, completed or expired
+	Status    string `json:"status"`
+	Initiator string `json:"initiator"`
+	// User who approved or rejected a pending transfer
+	Approver  string             `json:"approver"`
+	ExpiresAt pgtype.Timestamptz `json:"expires_at"`
+	DecidedAt pgtype.Timestamptz `json:"decided_at"`
+	// Charged to the sender on top of the amount
+	Fee          int64       `json:"fee"`
+	FeeAccountID pgtype.Int8 `json:"fee_account_id"`
+}
+
+type TransferBatch struct {
+	ID int64 `json:"id"`
+	// atomic or best_effort
+	Mode string `json:"mode"`
+	// processing, completed, partially_completed or failed
+	Status    string             `json:"status"`
+	CreatedAt pgtype.Timestamptz `json:"created_at"`
+	// User who created the batch, only they can read it
+	Creator string `json:"creator"`
+}
+
+type TransferBatchItem struct {
+	BatchID       int64 `json:"batch_id"`
+	ItemIndex     int32 `json:"item_index"`
+	FromAccountID int64 `json:"from_account_id"`
+	ToAccountID   int64 `json:"to_account_id"`
+	Amount        int64 `json:"amount"`
+	// succeeded, failed or rolled_back
+	Status     string      `json:"status"`
+	TransferID pgtype.Int8 `json:"transfer_id"`
+	Error      string      `json:"error"`
+}
+
+type TransferLimit struct {
+	Tier     string `json:"tier"`
+	Currency string `json:"currency"`
+	// NULL means no limit
+	MaxSingleAmount      pgtype.Int8        `json:"max_single_amount"`
+	DailyAccountAmount   pgtype.Int8        `json:"daily_account_amount"`
+	MonthlyAccountAmount pgtype.Int8        `json:"monthly_account_amount"`
+	DailyUserAmount      pgtype.Int8        `json:"daily_user_amount"`
+	MonthlyUserAmount    pgtype.Int8        `json:"monthly_user_amount"`
+	UpdatedAt            pgtype.Timestamptz `json:"updated_at"`
+	// Transfers above it need a second approval, NULL means never
+	ApprovalThreshold pgtype.Int8 `json:"approval_threshold"`
+}
+
+type TransferUsage struct {
+	// account:<id> or user:<username>
+	Subject  string `json:"subject"`
+	Currency string `json:"currency"`
+	// day or month
+	Period      string      `json:"period"`
+	PeriodStart pgtype.Date `json:"period_start"`
+	Amount      int64       `json:"amount"`
 }
 
 type User struct {
@@ -40,4 +315,87 @@ type User struct {
 	Email             string             `json:"email"`
 	PasswordChangedAt pgtype.Timestamptz `json:"password_changed_at"`
 	CreatedAt         pgtype.Timestamptz `json:"created_at"`
+	Role              string             `json:"role"`
+	Tier              string             `json:"tier"`
+	IsEmailVerified   bool               `json:"is_email_verified"`
+	IsMfaEnabled      bool               `json:"is_mfa_enabled"`
+	// Set once the user deactivated themselves, they can no longer log in
+	DeactivatedAt pgtype.Timestamptz `json:"deactivated_at"`
+}
+
+type VerifyEmail struct {
+	ID       int64  `json:"id"`
+	Username string `json:"username"`
+	// Address the code was sent to, the user is verified only if it is still their email
+	Email string `json:"email"`
+	// SHA-256 of the code of the verification link, the code itself is only sent to the user
+	SecretCodeHash string             `json:"secret_code_hash"`
+	IsUsed         bool               `json:"is_used"`
+	CreatedAt      pgtype.Timestamptz `json:"created_at"`
+	ExpiredAt      pgtype.Timestamptz `json:"expired_at"`
+}
+
+type WebauthnChallenge struct {
+	Challenge string `json:"challenge"`
+	// registration or login
+	Ceremony string `json:"ceremony"`
+	// User registering a passkey, null for logins where the passkey names the user
+	Username pgtype.Text `json:"username"`
+	// Session data the ceremony is finished with
+	SessionData []byte             `json:"session_data"`
+	ExpiresAt   pgtype.Timestamptz `json:"expires_at"`
+	UsedAt      pgtype.Timestamptz `json:"used_at"`
+	CreatedAt   pgtype.Timestamptz `json:"created_at"`
+}
+
+type WebauthnCredential struct {
+	ID int64 `json:"id"`
+	// ID the authenticator gave the credential
+	CredentialID []byte `json:"credential_id"`
+	Username     string `json:"username"`
+	// Random ID of the user stored on the authenticator, shared by all passkeys of the user
+	UserHandle []byte `json:"user_handle"`
+	Name       string `json:"name"`
+	// COSE encoded public key of the credential
+	PublicKey       []byte   `json:"public_key"`
+	AttestationType string   `json:"attestation_type"`
+	Transports      []string `json:"transports"`
+	Aaguid          []byte   `json:"aaguid"`
+	// Signature counter of the last assertion, a counter that does not increase hints at a cloned authenticator
+	SignCount      int64              `json:"sign_count"`
+	BackupEligible bool               `json:"backup_eligible"`
+	BackupState    bool               `json:"backup_state"`
+	LastUsedAt     pgtype.Timestamptz `json:"last_used_at"`
+	CreatedAt      pgtype.Timestamptz `json:"created_at"`
+}
+
+type WebhookDelivery struct {
+	ID             int64 `json:"id"`
+	SubscriptionID int64 `json:"subscription_id"`
+	EventID        int64 `json:"event_id"`
+	// pending, succeeded or failed
+	Status   string `json:"status"`
+	Attempts int32  `json:"attempts"`
+	// HTTP status of the last attempt, 0 if no response was received
+	ResponseStatus int32 `json:"response_status"`
+	// Error of the last failed attempt
+	Error         string             `json:"error"`
+	NextAttemptAt pgtype.Timestamptz `json:"next_attempt_at"`
+	DeliveredAt   pgtype.Timestamptz `json:"delivered_at"`
+	CreatedAt     pgtype.Timestamptz `json:"created_at"`
+}
+
+type WebhookSubscription struct {
+	ID    int64  `json:"id"`
+	Owner string `json:"owner"`
+	Url   string `json:"url"`
+	// Event types delivered to the endpoint
+	EventTypes []string `json:"event_types"`
+	// Key of the HMAC-SHA256 signature of every delivery
+	Secret string `json:"secret"`
+	// Consecutive failed delivery attempts
+	FailureCount int32 `json:"failure_count"`
+	// Set when the endpoint is disabled after repeated failures
+	DisabledAt pgtype.Timestamptz `json:"disabled_at"`
+	CreatedAt  pgtype.Timestamptz `json:"created_at"`
 }
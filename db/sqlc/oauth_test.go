@@ -0,0 +1,154 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/roman-adamchik/simplebank/util"
+	"github.com/stretchr/testify/require"
+)
+
+func createRandomOAuthClient(t *testing.T, user User) OauthClient {
+	t.Helper()
+
+	arg := CreateOAuthClientParams{
+		ID:           util.RandomString(16),
+		Name:         util.RandomOwner(),
+		RedirectUris: []string{"https://example.com/callback"},
+		Scopes:       []string{"accounts:read", "transfers:write"},
+		CreatedBy:    user.Username,
+	}
+	client, err := testQueries.CreateOAuthClient(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, arg.ID, client.ID)
+	require.Empty(t, client.SecretHash)
+	require.Equal(t, arg.RedirectUris, client.RedirectUris)
+	require.Equal(t, arg.Scopes, client.Scopes)
+
+	return client
+}
+
+func createRandomAuthorizationCode(t *testing.T, client OauthClient, user User, challenge string) string {
+	t.Helper()
+
+	code := util.RandomString(32)
+	_, err := testQueries.CreateOAuthAuthorizationCode(context.Background(), CreateOAuthAuthorizationCodeParams{
+		CodeHash:      util.HashSecret(code),
+		ClientID:      client.ID,
+		Username:      user.Username,
+		RedirectUri:   client.RedirectUris[0],
+		Scopes:        client.Scopes,
+		CodeChallenge: challenge,
+		ExpiresAt:     pgtype.Timestamptz{Time: time.Now().Add(time.Minute), Valid: true},
+	})
+	require.NoError(t, err)
+
+	return code
+}
+
+func exchangeRandomAuthorizationCode(t *testing.T, store Store, client OauthClient, user User) string {
+	t.Helper()
+
+	code := createRandomAuthorizationCode(t, client, user, "challenge")
+	refreshToken := util.RandomString(32)
+	_, err := store.ExchangeAuthorizationCodeTx(context.Background(), ExchangeAuthorizationCodeTxParams{
+		CodeHash:              util.HashSecret(code),
+		ClientID:              client.ID,
+		RedirectURI:           client.RedirectUris[0],
+		CodeChallenge:         "challenge",
+		RefreshTokenHash:      util.HashSecret(refreshToken),
+		RefreshTokenExpiresAt: time.Now().Add(time.Hour),
+		Now:                   time.Now(),
+	})
+	require.NoError(t, err)
+
+	return refreshToken
+}
+
+func TestExchangeAuthorizationCodeTx(t *testing.T) {
+	store := NewStore(testPool)
+	user := CreateRandomUser(t)
+	client := createRandomOAuthClient(t, user)
+	code := createRandomAuthorizationCode(t, client, user, "challenge")
+
+	arg := ExchangeAuthorizationCodeTxParams{
+		CodeHash:              util.HashSecret(code),
+		ClientID:              client.ID,
+		RedirectURI:           client.RedirectUris[0],
+		CodeChallenge:         "wrong",
+		RefreshTokenHash:      util.HashSecret(util.RandomString(32)),
+		RefreshTokenExpiresAt: time.Now().Add(time.Hour),
+		Now:                   time.Now(),
+	}
+	_, err := store.ExchangeAuthorizationCodeTx(context.Background(), arg)
+	require.ErrorIs(t, err, ErrInvalidGrant)
+
+	arg.CodeChallenge = "challenge"
+	refreshToken, err := store.ExchangeAuthorizationCodeTx(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, client.ID, refreshToken.ClientID)
+	require.Equal(t, user.Username, refreshToken.Username)
+	require.Equal(t, client.Scopes, refreshToken.Scopes)
+
+	// the code can be used only once
+	arg.RefreshTokenHash = util.HashSecret(util.RandomString(32))
+	_, err = store.ExchangeAuthorizationCodeTx(context.Background(), arg)
+	require.ErrorIs(t, err, ErrInvalidGrant)
+}
+
+func TestRefreshOAuthTokenTx(t *testing.T) {
+	store := NewStore(testPool)
+	user := CreateRandomUser(t)
+	client := createRandomOAuthClient(t, user)
+	token1 := exchangeRandomAuthorizationCode(t, store, client, user)
+
+	refresh := func(token string, scopes []string) (string, OauthRefreshToken, error) {
+		newToken := util.RandomString(32)
+		refreshToken, err := store.RefreshOAuthTokenTx(context.Background(), RefreshOAuthTokenTxParams{
+			TokenHash:    util.HashSecret(token),
+			ClientID:     client.ID,
+			Scopes:       scopes,
+			NewTokenHash: util.HashSecret(newToken),
+			ExpiresAt:    time.Now().Add(time.Hour),
+			Now:          time.Now(),
+		})
+		return newToken, refreshToken, err
+	}
+
+	_, _, err := refresh(token1, []string{"accounts:write"})
+	require.ErrorIs(t, err, ErrInvalidScope)
+
+	token2, refreshToken, err := refresh(token1, []string{"accounts:read"})
+	require.NoError(t, err)
+	require.Equal(t, []string{"accounts:read"}, refreshToken.Scopes)
+
+	// reusing a rotated token revokes the whole grant
+	_, _, err = refresh(token1, nil)
+	require.ErrorIs(t, err, ErrInvalidGrant)
+
+	_, _, err = refresh(token2, nil)
+	require.ErrorIs(t, err, ErrInvalidGrant)
+}
+
+func TestRevokeOAuthGrant(t *testing.T) {
+	store := NewStore(testPool)
+	user := CreateRandomUser(t)
+	client := createRandomOAuthClient(t, user)
+	exchangeRandomAuthorizationCode(t, store, client, user)
+	exchangeRandomAuthorizationCode(t, store, client, user)
+
+	arg := RevokeOAuthGrantParams{
+		RevokedAt: pgtype.Timestamptz{Time: time.Now(), Valid: true},
+		ClientID:  client.ID,
+		Username:  user.Username,
+	}
+	rows, err := testQueries.RevokeOAuthGrant(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, int64(2), rows)
+
+	rows, err = testQueries.RevokeOAuthGrant(context.Background(), arg)
+	require.NoError(t, err)
+	require.Zero(t, rows)
+}
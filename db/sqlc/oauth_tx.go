@@ -0,0 +1,148 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+var (
+	// ErrInvalidGrant is returned when an authorization code or refresh token is unknown, used, expired,
+	// or was issued to another client
+	ErrInvalidGrant = errors.New("authorization grant is invalid or expired")
+	// ErrInvalidScope is returned when a refresh asks for scopes the grant does not have
+	ErrInvalidScope = errors.New("requested scope exceeds the granted scope")
+)
+
+// ExchangeAuthorizationCodeTxParams contains the input parameters of the exchange authorization code transaction
+type ExchangeAuthorizationCodeTxParams struct {
+	CodeHash    string `json:"code_hash"`
+	ClientID    string `json:"client_id"`
+	RedirectURI string `json:"redirect_uri"`
+	// CodeChallenge is computed from the code verifier the client sent
+	CodeChallenge         string    `json:"code_challenge"`
+	RefreshTokenHash      string    `json:"refresh_token_hash"`
+	RefreshTokenExpiresAt time.Time `json:"refresh_token_expires_at"`
+	Now                   time.Time `json:"now"`
+}
+
+// ExchangeAuthorizationCodeTx uses an authorization code and creates the first refresh token of the grant
+// within a single db transaction
+func (s *SQLStore) ExchangeAuthorizationCodeTx(ctx context.Context, arg ExchangeAuthorizationCodeTxParams) (OauthRefreshToken, error) {
+	var refreshToken OauthRefreshToken
+
+	err := s.execTx(ctx, func(q *Queries) error {
+		code, err := q.UseOAuthAuthorizationCode(ctx, UseOAuthAuthorizationCodeParams{
+			Now:      pgtype.Timestamptz{Time: arg.Now, Valid: true},
+			CodeHash: arg.CodeHash,
+		})
+		if err != nil {
+			if errors.Is(err, ErrRecordNotFound) {
+				return ErrInvalidGrant
+			}
+			return err
+		}
+
+		if code.ClientID != arg.ClientID ||
+			code.RedirectUri != arg.RedirectURI ||
+			code.CodeChallenge != arg.CodeChallenge {
+			return ErrInvalidGrant
+		}
+
+		refreshToken, err = q.CreateOAuthRefreshToken(ctx, CreateOAuthRefreshTokenParams{
+			TokenHash: arg.RefreshTokenHash,
+			ClientID:  code.ClientID,
+			Username:  code.Username,
+			Scopes:    code.Scopes,
+			ExpiresAt: pgtype.Timestamptz{Time: arg.RefreshTokenExpiresAt, Valid: true},
+		})
+		return err
+	})
+
+	return refreshToken, err
+}
+
+// RefreshOAuthTokenTxParams contains the input parameters of the refresh oauth token transaction
+type RefreshOAuthTokenTxParams struct {
+	TokenHash string `json:"token_hash"`
+	ClientID  string `json:"client_id"`
+	// Scopes narrow the scopes of the new token, it keeps the scopes of the grant if empty
+	Scopes       []string  `json:"scopes"`
+	NewTokenHash string    `json:"new_token_hash"`
+	ExpiresAt    time.Time `json:"expires_at"`
+	Now          time.Time `json:"now"`
+}
+
+// RefreshOAuthTokenTx rotates a refresh token within a single db transaction.
+// Using a token that was already rotated revokes the whole grant, since the token has likely leaked
+func (s *SQLStore) RefreshOAuthTokenTx(ctx context.Context, arg RefreshOAuthTokenTxParams) (OauthRefreshToken, error) {
+	var refreshToken OauthRefreshToken
+	var reused bool
+
+	err := s.execTx(ctx, func(q *Queries) error {
+		reused = false
+		now := pgtype.Timestamptz{Time: arg.Now, Valid: true}
+
+		token, err := q.GetOAuthRefreshTokenForUpdate(ctx, arg.TokenHash)
+		if err != nil {
+			if errors.Is(err, ErrRecordNotFound) {
+				return ErrInvalidGrant
+			}
+			return err
+		}
+
+		if token.ClientID != arg.ClientID {
+			return ErrInvalidGrant
+		}
+
+		if token.RevokedAt.Valid {
+			// the revocation has to be committed, so the error is returned after the transaction
+			reused = true
+			_, err = q.RevokeOAuthGrant(ctx, RevokeOAuthGrantParams{
+				RevokedAt: now,
+				ClientID:  token.ClientID,
+				Username:  token.Username,
+			})
+			return err
+		}
+
+		if !token.ExpiresAt.Time.After(arg.Now) {
+			return ErrInvalidGrant
+		}
+
+		scopes := token.Scopes
+		if len(arg.Scopes) > 0 {
+			for _, scope := range arg.Scopes {
+				if !slices.Contains(token.Scopes, scope) {
+					return ErrInvalidScope
+				}
+			}
+			scopes = arg.Scopes
+		}
+
+		err = q.RevokeOAuthRefreshToken(ctx, RevokeOAuthRefreshTokenParams{
+			RevokedAt: now,
+			ID:        token.ID,
+		})
+		if err != nil {
+			return err
+		}
+
+		refreshToken, err = q.CreateOAuthRefreshToken(ctx, CreateOAuthRefreshTokenParams{
+			TokenHash: arg.NewTokenHash,
+			ClientID:  token.ClientID,
+			Username:  token.Username,
+			Scopes:    scopes,
+			ExpiresAt: pgtype.Timestamptz{Time: arg.ExpiresAt, Valid: true},
+		})
+		return err
+	})
+	if err == nil && reused {
+		return OauthRefreshToken{}, ErrInvalidGrant
+	}
+
+	return refreshToken, err
+}
@@ -0,0 +1,240 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/roman-adamchik/simplebank/util"
+	"github.com/stretchr/testify/require"
+)
+
+// relayAll publishes every unsent event and returns them by id
+func relayAll(t *testing.T, store Store) map[int64]OutboxEvent {
+	t.Helper()
+
+	published := make(map[int64]OutboxEvent)
+	lastIDs := make(map[string]int64)
+
+	for {
+		sent, err := store.RelayOutbox(context.Background(), RelayOutboxParams{
+			Limit: 100,
+			Now:   time.Now(),
+			Lease: time.Minute,
+			Publish: func(ctx context.Context, event OutboxEvent) error {
+				aggregate := event.AggregateType + ":" + event.AggregateID
+				require.Greater(t, event.ID, lastIDs[aggregate])
+				lastIDs[aggregate] = event.ID
+				published[event.ID] = event
+				return nil
+			},
+		})
+		require.NoError(t, err)
+		if sent == 0 {
+			return published
+		}
+	}
+}
+
+func findEvent(events map[int64]OutboxEvent, eventType string, aggregateID string) (OutboxEvent, bool) {
+	for _, event := range events {
+		if event.EventType == eventType && event.AggregateID == aggregateID {
+			return event, true
+		}
+	}
+	return OutboxEvent{}, false
+}
+
+func TestCreateAccountTxRecordsEvent(t *testing.T) {
+	store := NewStore(testPool)
+	user := CreateRandomUser(t)
+
+	account, err := store.CreateAccountTx(context.Background(), CreateAccountParams{
+		Owner:    user.Username,
+		Currency: util.USD,
+	})
+	require.NoError(t, err)
+
+	event, ok := findEvent(relayAll(t, store), EventAccountCreated, strconv.FormatInt(account.ID, 10))
+	require.True(t, ok)
+	require.Equal(t, AggregateAccount, event.AggregateType)
+
+	var payload Account
+	require.NoError(t, json.Unmarshal(event.Payload, &payload))
+	require.Equal(t, account.Owner, payload.Owner)
+}
+
+func TestCreateUserTxRecordsEvent(t *testing.T) {
+	store := NewStore(testPool)
+
+	user, err := store.CreateUserTx(context.Background(), CreateUserParams{
+		Username:       util.RandomOwner(),
+		HashedPassword: "secret",
+		FullName:       util.RandomOwner(),
+		Email:          util.RandomEmail(),
+	})
+	require.NoError(t, err)
+
+	event, ok := findEvent(relayAll(t, store), EventUserCreated, user.Username)
+	require.True(t, ok)
+	require.NotContains(t, string(event.Payload), "secret")
+}
+
+func TestTransferTxRecordsEvent(t *testing.T) {
+	store := NewStore(testPool)
+	account1 := CreateRandomAccount(t)
+	account2 := CreateRandomAccount(t)
+
+	result, err := store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: account1.ID,
+		ToAccountID:   account2.ID,
+		Amount:        10,
+	})
+	require.NoError(t, err)
+
+	event, ok := findEvent(relayAll(t, store), EventTransferCompleted, strconv.FormatInt(result.Transfer.ID, 10))
+	require.True(t, ok)
+	require.Equal(t, AggregateTransfer, event.AggregateType)
+}
+
+func TestRelayOutboxStopsAtFailure(t *testing.T) {
+	store := NewStore(testPool)
+	relayAll(t, store)
+
+	account1 := CreateRandomAccount(t)
+	account2 := CreateRandomAccount(t)
+	first, err := store.CreateAccountTx(context.Background(), CreateAccountParams{Owner: account1.Owner, Currency: util.EUR})
+	require.NoError(t, err)
+	second, err := store.CreateAccountTx(context.Background(), CreateAccountParams{Owner: account2.Owner, Currency: util.EUR})
+	require.NoError(t, err)
+
+	// the second event fails, so it is left unsent and released, and nothing after it is published
+	publishErr := errors.New("broker unavailable")
+	var published []string
+	sent, err := store.RelayOutbox(context.Background(), RelayOutboxParams{
+		Limit: 100,
+		Now:   time.Now(),
+		Lease: time.Minute,
+		Publish: func(ctx context.Context, event OutboxEvent) error {
+			if event.AggregateID == strconv.FormatInt(second.ID, 10) {
+				return publishErr
+			}
+			published = append(published, event.AggregateID)
+			return nil
+		},
+	})
+	require.ErrorIs(t, err, publishErr)
+	require.Equal(t, 1, sent)
+	require.Equal(t, []string{strconv.FormatInt(first.ID, 10)}, published)
+
+	events := relayAll(t, store)
+	_, ok := findEvent(events, EventAccountCreated, strconv.FormatInt(first.ID, 10))
+	require.False(t, ok)
+	secondEvent, ok := findEvent(events, EventAccountCreated, strconv.FormatInt(second.ID, 10))
+	require.True(t, ok)
+
+	// replaying from an offset publishes the events again
+	replayed, err := testQueries.ReplayOutboxEvents(context.Background(), secondEvent.ID)
+	require.NoError(t, err)
+	require.GreaterOrEqual(t, replayed, int64(1))
+
+	_, ok = findEvent(relayAll(t, store), EventAccountCreated, strconv.FormatInt(second.ID, 10))
+	require.True(t, ok)
+}
+
+func TestRelayOutboxClaim(t *testing.T) {
+	store := NewStore(testPool)
+	relayAll(t, store)
+
+	account := CreateRandomAccount(t)
+	created, err := store.CreateAccountTx(context.Background(), CreateAccountParams{Owner: account.Owner, Currency: util.EUR})
+	require.NoError(t, err)
+	aggregateID := strconv.FormatInt(created.ID, 10)
+
+	// the claimed events are not locked while they are published, and another relay skips them
+	var published []string
+	sent, err := store.RelayOutbox(context.Background(), RelayOutboxParams{
+		Limit: 100,
+		Now:   time.Now(),
+		Lease: time.Minute,
+		Publish: func(ctx context.Context, event OutboxEvent) error {
+			_, ok := findEvent(relayAll(t, store), EventAccountCreated, aggregateID)
+			require.False(t, ok)
+
+			published = append(published, event.AggregateID)
+			return nil
+		},
+	})
+	require.NoError(t, err)
+	require.Equal(t, 1, sent)
+	require.Equal(t, []string{aggregateID}, published)
+
+	// an event whose lease ran out before it was marked as sent is published again
+	second, err := store.CreateAccountTx(context.Background(), CreateAccountParams{Owner: account.Owner, Currency: util.ILS})
+	require.NoError(t, err)
+	secondID := strconv.FormatInt(second.ID, 10)
+
+	past := time.Now().Add(-time.Hour)
+	claimed, err := testQueries.ClaimOutboxEvents(context.Background(), ClaimOutboxEventsParams{
+		LeaseUntil: pgtype.Timestamptz{Time: past.Add(time.Minute), Valid: true},
+		Now:        pgtype.Timestamptz{Time: past, Valid: true},
+		MaxEvents:  100,
+	})
+	require.NoError(t, err)
+	require.NotEmpty(t, claimed)
+
+	_, ok := findEvent(relayAll(t, store), EventAccountCreated, secondID)
+	require.True(t, ok)
+}
+
+func TestRelayOutboxAggregateOrder(t *testing.T) {
+	store := NewStore(testPool)
+	relayAll(t, store)
+
+	aggregateID := util.RandomString(12)
+	events := make([]OutboxEvent, 3)
+	for i := range events {
+		var err error
+		events[i], err = testQueries.CreateOutboxEvent(context.Background(), CreateOutboxEventParams{
+			AggregateType: AggregateUser,
+			AggregateID:   aggregateID,
+			EventType:     EventUserCreated,
+			Payload:       []byte("{}"),
+		})
+		require.NoError(t, err)
+	}
+
+	claim := func() []int64 {
+		claimed, err := testQueries.ClaimOutboxEvents(context.Background(), ClaimOutboxEventsParams{
+			LeaseUntil: pgtype.Timestamptz{Time: time.Now().Add(time.Minute), Valid: true},
+			Now:        pgtype.Timestamptz{Time: time.Now(), Valid: true},
+			MaxEvents:  100,
+		})
+		require.NoError(t, err)
+
+		var ids []int64
+		for _, event := range claimed {
+			if event.AggregateID == aggregateID {
+				ids = append(ids, event.ID)
+			}
+		}
+		return ids
+	}
+
+	// only the oldest event of the aggregate is claimed, and nothing while it is leased
+	require.Equal(t, []int64{events[0].ID}, claim())
+	require.Empty(t, claim())
+
+	// the next event is claimed once the one before it was sent
+	require.NoError(t, testQueries.MarkOutboxEventSent(context.Background(), events[0].ID))
+	require.Equal(t, []int64{events[1].ID}, claim())
+
+	require.NoError(t, testQueries.ReleaseOutboxEvents(context.Background(), []int64{events[1].ID}))
+	published := relayAll(t, store)
+	require.Contains(t, published, events[1].ID)
+	require.Contains(t, published, events[2].ID)
+}
@@ -0,0 +1,173 @@
+package db
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// list of aggregates events are recorded for
+const (
+	AggregateTransfer = "transfer"
+	AggregateAccount  = "account"
+	AggregateUser     = "user"
+)
+
+// list of event types
+const (
+	EventTransferCompleted = "transfer.completed"
+	EventTransferPending   = "transfer.pending"
+	EventTransferRejected  = "transfer.rejected"
+	EventTransferExpired   = "transfer.expired"
+	EventAccountCreated    = "account.created"
+	EventUserCreated       = "user.created"
+)
+
+// UserCreatedEvent is the payload of the user.created event; it leaves out the user's credentials
+type UserCreatedEvent struct {
+	Username  string    `json:"username"`
+	FullName  string    `json:"full_name"`
+	Email     string    `json:"email"`
+	Role      string    `json:"role"`
+	Tier      string    `json:"tier"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// recordEvent writes an event to the outbox, so it is published only if the db transaction commits.
+// The event is also queued for the webhook subscriptions of the owners of the given accounts
+func recordEvent(ctx context.Context, q *Queries, aggregateType string, aggregateID string, eventType string, payload any, accountIDs ...int64) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("cannot marshal %s event: %w", eventType, err)
+	}
+
+	event, err := q.CreateOutboxEvent(ctx, CreateOutboxEventParams{
+		AggregateType: aggregateType,
+		AggregateID:   aggregateID,
+		EventType:     eventType,
+		Payload:       data,
+	})
+	if err != nil || len(accountIDs) == 0 {
+		return err
+	}
+
+	_, err = q.CreateWebhookDeliveries(ctx, CreateWebhookDeliveriesParams{
+		EventID:    event.ID,
+		EventType:  event.EventType,
+		AccountIds: accountIDs,
+	})
+	return err
+}
+
+// recordTransferEvent records an event of a transfer, which is delivered to the owners of both accounts
+func recordTransferEvent(ctx context.Context, q *Queries, eventType string, transfer Transfer) error {
+	return recordEvent(ctx, q, AggregateTransfer, strconv.FormatInt(transfer.ID, 10), eventType, transfer,
+		transfer.FromAccountID, transfer.ToAccountID)
+}
+
+// CreateAccountTx creates an account and records the account.created event within a single db transaction
+func (s *SQLStore) CreateAccountTx(ctx context.Context, arg CreateAccountParams) (Account, error) {
+	var account Account
+
+	err := s.execTx(ctx, func(q *Queries) error {
+		var err error
+		account, err = q.CreateAccount(ctx, arg)
+		if err != nil {
+			return err
+		}
+
+		return recordEvent(ctx, q, AggregateAccount, strconv.FormatInt(account.ID, 10), EventAccountCreated, account, account.ID)
+	})
+
+	return account, err
+}
+
+// CreateUserTx creates a user and records the user.created event within a single db transaction
+func (s *SQLStore) CreateUserTx(ctx context.Context, arg CreateUserParams) (User, error) {
+	var user User
+
+	err := s.execTx(ctx, func(q *Queries) error {
+		var err error
+		user, err = q.CreateUser(ctx, arg)
+		if err != nil {
+			return err
+		}
+
+		return recordEvent(ctx, q, AggregateUser, user.Username, EventUserCreated, UserCreatedEvent{
+			Username:  user.Username,
+			FullName:  user.FullName,
+			Email:     user.Email,
+			Role:      user.Role,
+			Tier:      user.Tier,
+			CreatedAt: user.CreatedAt.Time,
+		})
+	})
+
+	return user, err
+}
+
+// RelayOutboxParams contains the input parameters of the outbox relay
+type RelayOutboxParams struct {
+	Limit int32
+	Now   time.Time
+	// Lease is how long the claimed events are kept from other relays while they are published
+	Lease time.Duration
+	// Publish delivers a single event; the event is marked as sent if it returns no error
+	Publish func(ctx context.Context, event OutboxEvent) error
+}
+
+// RelayOutbox claims the oldest unsent event of every aggregate, publishes them in id order and marks them as sent.
+// The events are claimed and committed before they are published, so no lock is held while the publisher is called;
+// the claim only keeps other relays from publishing them until the lease runs out.
+// An aggregate is skipped while one of its events is claimed, so the next event of an aggregate is only claimed
+// once the one before it was sent, by this relay or a concurrent one.
+// Delivery stops at the first event that cannot be published and its claim and the ones after it are released,
+// so that the next run starts from it; the events delivered before it stay marked as sent and the publishing error is returned.
+//
+// Delivery is at least once, and in id order per aggregate only as far as ids are in commit order:
+//   - an event is published again if it cannot be marked as sent, or if its lease runs out while it is published
+//   - an event whose transaction commits after a later event was claimed is published after that event
+//   - the events of different aggregates are published in no particular order across runs and relays
+//
+// Consumers are expected to deduplicate events by id and not to rely on the order of events of different aggregates
+func (s *SQLStore) RelayOutbox(ctx context.Context, arg RelayOutboxParams) (int, error) {
+	events, err := s.ClaimOutboxEvents(ctx, ClaimOutboxEventsParams{
+		LeaseUntil: pgtype.Timestamptz{Time: arg.Now.Add(arg.Lease), Valid: true},
+		Now:        pgtype.Timestamptz{Time: arg.Now, Valid: true},
+		MaxEvents:  arg.Limit,
+	})
+	if err != nil {
+		return 0, err
+	}
+	// the rows an update returns are in no particular order
+	slices.SortFunc(events, func(a, b OutboxEvent) int {
+		return cmp.Compare(a.ID, b.ID)
+	})
+
+	for sent, event := range events {
+		if publishErr := arg.Publish(ctx, event); publishErr != nil {
+			publishErr = fmt.Errorf("cannot publish event %d: %w", event.ID, publishErr)
+
+			ids := make([]int64, 0, len(events)-sent)
+			for _, unsent := range events[sent:] {
+				ids = append(ids, unsent.ID)
+			}
+			if err := s.ReleaseOutboxEvents(ctx, ids); err != nil {
+				return sent, fmt.Errorf("%w, and cannot release the claims: %w", publishErr, err)
+			}
+			return sent, publishErr
+		}
+
+		if err := s.MarkOutboxEventSent(ctx, event.ID); err != nil {
+			return sent, err
+		}
+	}
+
+	return len(events), nil
+}
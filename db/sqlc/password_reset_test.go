@@ -0,0 +1,155 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/roman-adamchik/simplebank/util"
+	"github.com/stretchr/testify/require"
+)
+
+func createRandomPasswordReset(t *testing.T, user User, expiredAt time.Time) (PasswordReset, string) {
+	t.Helper()
+
+	token := util.RandomString(32)
+	reset, err := testQueries.CreatePasswordReset(context.Background(), CreatePasswordResetParams{
+		Username:  user.Username,
+		TokenHash: util.HashSecret(token),
+		ExpiredAt: pgtype.Timestamptz{Time: expiredAt, Valid: true},
+	})
+	require.NoError(t, err)
+	require.Equal(t, user.Username, reset.Username)
+	require.False(t, reset.IsUsed)
+
+	return reset, token
+}
+
+func TestCountPasswordResetsSince(t *testing.T) {
+	user := CreateRandomUser(t)
+	start := time.Now().Add(-time.Second)
+
+	for range 2 {
+		createRandomPasswordReset(t, user, time.Now().Add(time.Hour))
+	}
+
+	count, err := testQueries.CountPasswordResetsSince(context.Background(), CountPasswordResetsSinceParams{
+		Username: user.Username,
+		Since:    pgtype.Timestamptz{Time: start, Valid: true},
+	})
+	require.NoError(t, err)
+	require.Equal(t, int64(2), count)
+
+	count, err = testQueries.CountPasswordResetsSince(context.Background(), CountPasswordResetsSinceParams{
+		Username: user.Username,
+		Since:    pgtype.Timestamptz{Time: time.Now().Add(time.Minute), Valid: true},
+	})
+	require.NoError(t, err)
+	require.Zero(t, count)
+}
+
+func TestResetPasswordTx(t *testing.T) {
+	store := NewStore(testPool)
+	user := CreateRandomUser(t)
+	_, token1 := createRandomPasswordReset(t, user, time.Now().Add(time.Hour))
+	_, token2 := createRandomPasswordReset(t, user, time.Now().Add(time.Hour))
+	session := createRandomSession(t, user)
+	now := time.Now()
+
+	updated, err := store.ResetPasswordTx(context.Background(), ResetPasswordTxParams{
+		TokenHash:      util.HashSecret(token1),
+		HashedPassword: "new-hash",
+		Now:            now,
+	})
+	require.NoError(t, err)
+	require.Equal(t, user.Username, updated.Username)
+	require.Equal(t, "new-hash", updated.HashedPassword)
+	require.WithinDuration(t, now, updated.PasswordChangedAt.Time, time.Second)
+
+	// the sessions started with the old password are signed out
+	session, err = testQueries.GetSession(context.Background(), session.ID)
+	require.NoError(t, err)
+	require.True(t, session.RevokedAt.Valid)
+
+	// the token is used and the other tokens of the user are invalidated
+	for _, token := range []string{token1, token2} {
+		_, err = store.ResetPasswordTx(context.Background(), ResetPasswordTxParams{
+			TokenHash:      util.HashSecret(token),
+			HashedPassword: "another-hash",
+			Now:            now,
+		})
+		require.ErrorIs(t, err, ErrInvalidPasswordReset)
+	}
+}
+
+func TestResetPasswordTxExpired(t *testing.T) {
+	store := NewStore(testPool)
+	user := CreateRandomUser(t)
+	_, token := createRandomPasswordReset(t, user, time.Now().Add(time.Hour))
+
+	_, err := store.ResetPasswordTx(context.Background(), ResetPasswordTxParams{
+		TokenHash:      util.HashSecret(token),
+		HashedPassword: "new-hash",
+		Now:            time.Now().Add(2 * time.Hour),
+	})
+	require.ErrorIs(t, err, ErrInvalidPasswordReset)
+
+	user, err = testQueries.GetUser(context.Background(), user.Username)
+	require.NoError(t, err)
+	require.NotEqual(t, "new-hash", user.HashedPassword)
+}
+
+func TestChangePasswordTx(t *testing.T) {
+	store := NewStore(testPool)
+	user := CreateRandomUser(t)
+	session1 := createRandomSession(t, user)
+	session2 := createRandomSession(t, user)
+	otherSession := createRandomSession(t, CreateRandomUser(t))
+	client := createRandomOAuthClient(t, user)
+	refreshToken := exchangeRandomAuthorizationCode(t, store, client, user)
+	now := time.Now()
+
+	updated, err := store.ChangePasswordTx(context.Background(), ChangePasswordTxParams{
+		Username:       user.Username,
+		HashedPassword: "new-hash",
+		Now:            now,
+	})
+	require.NoError(t, err)
+	require.Equal(t, "new-hash", updated.HashedPassword)
+	require.WithinDuration(t, now, updated.PasswordChangedAt.Time, time.Second)
+
+	for _, session := range []Session{session1, session2} {
+		session, err = testQueries.GetSession(context.Background(), session.ID)
+		require.NoError(t, err)
+		require.True(t, session.RevokedAt.Valid)
+	}
+
+	otherSession, err = testQueries.GetSession(context.Background(), otherSession.ID)
+	require.NoError(t, err)
+	require.False(t, otherSession.RevokedAt.Valid)
+
+	// the apps the user connected have to be authorized again
+	grant, err := testQueries.GetOAuthRefreshTokenForUpdate(context.Background(), util.HashSecret(refreshToken))
+	require.NoError(t, err)
+	require.True(t, grant.RevokedAt.Valid)
+}
+
+func TestGetPasswordResetUser(t *testing.T) {
+	user := CreateRandomUser(t)
+	_, token := createRandomPasswordReset(t, user, time.Now().Add(time.Hour))
+
+	resetUser, err := testQueries.GetPasswordResetUser(context.Background(), GetPasswordResetUserParams{
+		TokenHash: util.HashSecret(token),
+		Now:       pgtype.Timestamptz{Time: time.Now(), Valid: true},
+	})
+	require.NoError(t, err)
+	require.Equal(t, user.Username, resetUser.Username)
+	require.Equal(t, user.Email, resetUser.Email)
+
+	_, err = testQueries.GetPasswordResetUser(context.Background(), GetPasswordResetUserParams{
+		TokenHash: util.HashSecret(token),
+		Now:       pgtype.Timestamptz{Time: time.Now().Add(2 * time.Hour), Valid: true},
+	})
+	require.ErrorIs(t, err, ErrRecordNotFound)
+}
@@ -0,0 +1,97 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// ErrInvalidPasswordReset is returned when a reset token does not exist, is used or expired
+var ErrInvalidPasswordReset = errors.New("password reset token is invalid or expired")
+
+// ResetPasswordTxParams contains the input parameters of the reset password transaction
+type ResetPasswordTxParams struct {
+	TokenHash      string    `json:"token_hash"`
+	HashedPassword string    `json:"hashed_password"`
+	Now            time.Time `json:"now"`
+}
+
+// ResetPasswordTx uses a reset token and sets the new password of its user within a single db transaction.
+// The other reset tokens of the user are invalidated, so that a link sent before cannot change the password again,
+// and every session and app grant of the user is revoked
+func (s *SQLStore) ResetPasswordTx(ctx context.Context, arg ResetPasswordTxParams) (User, error) {
+	var user User
+
+	err := s.execTx(ctx, func(q *Queries) error {
+		now := pgtype.Timestamptz{Time: arg.Now, Valid: true}
+
+		reset, err := q.UsePasswordReset(ctx, UsePasswordResetParams{
+			TokenHash: arg.TokenHash,
+			Now:       now,
+		})
+		if err != nil {
+			if errors.Is(err, ErrRecordNotFound) {
+				return ErrInvalidPasswordReset
+			}
+			return err
+		}
+
+		user, err = setPassword(ctx, q, reset.Username, arg.HashedPassword, now)
+		if err != nil {
+			return err
+		}
+
+		return q.InvalidatePasswordResets(ctx, reset.Username)
+	})
+
+	return user, err
+}
+
+// ChangePasswordTxParams contains the input parameters of the change password transaction
+type ChangePasswordTxParams struct {
+	Username       string    `json:"username"`
+	HashedPassword string    `json:"hashed_password"`
+	Now            time.Time `json:"now"`
+}
+
+// ChangePasswordTx sets the new password of a user and revokes every session and app grant of the user within a single db transaction,
+// so that a stolen token stops working along with the old password
+func (s *SQLStore) ChangePasswordTx(ctx context.Context, arg ChangePasswordTxParams) (User, error) {
+	var user User
+
+	err := s.execTx(ctx, func(q *Queries) error {
+		var err error
+		user, err = setPassword(ctx, q, arg.Username, arg.HashedPassword, pgtype.Timestamptz{Time: arg.Now, Valid: true})
+		return err
+	})
+
+	return user, err
+}
+
+// setPassword sets the new password of a user and revokes the sessions and app grants started with the old one
+func setPassword(ctx context.Context, q *Queries, username string, hashedPassword string, now pgtype.Timestamptz) (User, error) {
+	user, err := q.UpdateUserPassword(ctx, UpdateUserPasswordParams{
+		HashedPassword:    hashedPassword,
+		PasswordChangedAt: now,
+		Username:          username,
+	})
+	if err != nil {
+		return user, err
+	}
+
+	_, err = q.RevokeUserSessions(ctx, RevokeUserSessionsParams{
+		RevokedAt: now,
+		Username:  username,
+	})
+	if err != nil {
+		return user, err
+	}
+
+	err = q.RevokeUserOAuthRefreshTokens(ctx, RevokeUserOAuthRefreshTokensParams{
+		RevokedAt: now,
+		Username:  username,
+	})
+	return user, err
+}
@@ -0,0 +1,266 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/require"
+)
+
+// CreateRandomPendingTransfer creates a transfer above the approval threshold of its account
+func CreateRandomPendingTransfer(t *testing.T, ttl time.Duration) (Transfer, Account, User) {
+	t.Helper()
+
+	store := NewStore(testPool)
+
+	fromAccount := createLimitedAccount(t, UpsertTransferLimitParams{
+		ApprovalThreshold: pgtype.Int8{Int64: 10, Valid: true},
+	})
+	_, err := testQueries.UpdateAccount(context.Background(), UpdateAccountParams{
+		ID:      fromAccount.ID,
+		Balance: 100,
+	})
+	require.NoError(t, err)
+	toAccount := CreateRandomAccount(t)
+
+	approver := CreateRandomUser(t)
+	_, err = testQueries.CreateAccountApprover(context.Background(), CreateAccountApproverParams{
+		AccountID: fromAccount.ID,
+		Username:  approver.Username,
+	})
+	require.NoError(t, err)
+
+	result, err := store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: fromAccount.ID,
+		ToAccountID:   toAccount.ID,
+		Amount:        30,
+		Initiator:     fromAccount.Owner,
+		PendingTTL:    ttl,
+	})
+	require.NoError(t, err)
+
+	transfer := result.Transfer
+	require.Equal(t, TransferPending, transfer.Status)
+	require.Equal(t, fromAccount.Owner, transfer.Initiator)
+	require.WithinDuration(t, time.Now().Add(ttl), transfer.ExpiresAt.Time, 5*time.Second)
+	require.Empty(t, result.FromEntry)
+	require.Empty(t, result.ToEntry)
+
+	// the money is only put on hold
+	require.Equal(t, int64(100), result.FromAccount.Balance)
+	require.Equal(t, int64(30), result.FromAccount.HeldBalance)
+
+	return transfer, result.FromAccount, approver
+}
+
+func TestTransferTxBelowApprovalThreshold(t *testing.T) {
+	store := NewStore(testPool)
+
+	fromAccount := createLimitedAccount(t, UpsertTransferLimitParams{
+		ApprovalThreshold: pgtype.Int8{Int64: 10, Valid: true},
+	})
+	toAccount := CreateRandomAccount(t)
+
+	result, err := store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: fromAccount.ID,
+		ToAccountID:   toAccount.ID,
+		Amount:        10,
+	})
+	require.NoError(t, err)
+	require.Equal(t, TransferCompleted, result.Transfer.Status)
+	require.Equal(t, fromAccount.Balance-10, result.FromAccount.Balance)
+}
+
+func TestTransferTxPendingInsufficientFunds(t *testing.T) {
+	store := NewStore(testPool)
+
+	fromAccount := createLimitedAccount(t, UpsertTransferLimitParams{
+		ApprovalThreshold: pgtype.Int8{Int64: 10, Valid: true},
+	})
+	toAccount := CreateRandomAccount(t)
+
+	_, err := store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: fromAccount.ID,
+		ToAccountID:   toAccount.ID,
+		Amount:        fromAccount.Balance + 11,
+	})
+	require.ErrorIs(t, err, ErrInsufficientFunds)
+}
+
+func TestTransferTxPendingLimitExceeded(t *testing.T) {
+	store := NewStore(testPool)
+
+	fromAccount := createLimitedAccount(t, UpsertTransferLimitParams{
+		ApprovalThreshold:  pgtype.Int8{Int64: 10, Valid: true},
+		MaxSingleAmount:    pgtype.Int8{Int64: 40, Valid: true},
+		DailyAccountAmount: pgtype.Int8{Int64: 45, Valid: true},
+	})
+	_, err := testQueries.UpdateAccount(context.Background(), UpdateAccountParams{
+		ID:      fromAccount.ID,
+		Balance: 100,
+	})
+	require.NoError(t, err)
+	toAccount := CreateRandomAccount(t)
+
+	// a transfer above the single maximum does not wait for an approval that must fail
+	_, err = store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: fromAccount.ID,
+		ToAccountID:   toAccount.ID,
+		Amount:        41,
+	})
+	var limitErr *LimitExceededError
+	require.ErrorAs(t, err, &limitErr)
+	require.Equal(t, LimitMaxSingle, limitErr.Limit)
+
+	// a pending transfer is checked against the usage but only accounted once approved
+	result, err := store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: fromAccount.ID,
+		ToAccountID:   toAccount.ID,
+		Amount:        30,
+	})
+	require.NoError(t, err)
+	require.Equal(t, TransferPending, result.Transfer.Status)
+
+	used, err := testQueries.GetTransferUsage(context.Background(), GetTransferUsageParams{
+		Subject:  fmt.Sprintf("account:%d", fromAccount.ID),
+		Currency: fromAccount.Currency,
+		Period:   PeriodDay,
+	})
+	require.NoError(t, err)
+	require.Zero(t, used)
+
+	_, err = store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: fromAccount.ID,
+		ToAccountID:   toAccount.ID,
+		Amount:        10,
+	})
+	require.NoError(t, err)
+
+	_, err = store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: fromAccount.ID,
+		ToAccountID:   toAccount.ID,
+		Amount:        40,
+	})
+	require.ErrorAs(t, err, &limitErr)
+	require.Equal(t, LimitDailyAccount, limitErr.Limit)
+	require.Equal(t, int64(35), limitErr.Remaining)
+}
+
+func TestApproveTransferTx(t *testing.T) {
+	store := NewStore(testPool)
+	transfer, fromAccount, approver := CreateRandomPendingTransfer(t, time.Hour)
+
+	// the initiator cannot approve its own transfer
+	_, err := store.ApproveTransferTx(context.Background(), DecideTransferTxParams{
+		TransferID: transfer.ID,
+		Approver:   transfer.Initiator,
+	})
+	require.ErrorIs(t, err, ErrInitiatorCannotDecide)
+
+	result, err := store.ApproveTransferTx(context.Background(), DecideTransferTxParams{
+		TransferID: transfer.ID,
+		Approver:   approver.Username,
+	})
+	require.NoError(t, err)
+	require.Equal(t, transfer.ID, result.Transfer.ID)
+	require.Equal(t, TransferCompleted, result.Transfer.Status)
+	require.Equal(t, approver.Username, result.Transfer.Approver)
+	require.True(t, result.Transfer.DecidedAt.Valid)
+	require.Equal(t, -transfer.Amount, result.FromEntry.Amount)
+	require.Equal(t, transfer.Amount, result.ToEntry.Amount)
+	require.Equal(t, fromAccount.Balance-transfer.Amount, result.FromAccount.Balance)
+	require.Zero(t, result.FromAccount.HeldBalance)
+
+	_, err = store.ApproveTransferTx(context.Background(), DecideTransferTxParams{
+		TransferID: transfer.ID,
+		Approver:   approver.Username,
+	})
+	require.ErrorIs(t, err, ErrTransferNotPending)
+}
+
+func TestApproveTransferTxClosedAccount(t *testing.T) {
+	store := NewStore(testPool)
+	transfer, _, approver := CreateRandomPendingTransfer(t, time.Hour)
+
+	toAccount, err := testQueries.GetAccount(context.Background(), transfer.ToAccountID)
+	require.NoError(t, err)
+	closeAccount(t, toAccount)
+
+	_, err = store.ApproveTransferTx(context.Background(), DecideTransferTxParams{
+		TransferID: transfer.ID,
+		Approver:   approver.Username,
+	})
+	require.ErrorIs(t, err, ErrAccountClosed)
+
+	// the transfer is still pending and can be rejected
+	rejected, err := store.RejectTransferTx(context.Background(), DecideTransferTxParams{
+		TransferID: transfer.ID,
+		Approver:   approver.Username,
+	})
+	require.NoError(t, err)
+	require.Equal(t, TransferRejected, rejected.Status)
+}
+
+func TestRejectTransferTx(t *testing.T) {
+	store := NewStore(testPool)
+	transfer, fromAccount, approver := CreateRandomPendingTransfer(t, time.Hour)
+
+	rejected, err := store.RejectTransferTx(context.Background(), DecideTransferTxParams{
+		TransferID: transfer.ID,
+		Approver:   approver.Username,
+	})
+	require.NoError(t, err)
+	require.Equal(t, TransferRejected, rejected.Status)
+	require.Equal(t, approver.Username, rejected.Approver)
+
+	updatedAccount, err := testQueries.GetAccount(context.Background(), fromAccount.ID)
+	require.NoError(t, err)
+	require.Equal(t, fromAccount.Balance, updatedAccount.Balance)
+	require.Zero(t, updatedAccount.HeldBalance)
+}
+
+func TestExpireTransferTx(t *testing.T) {
+	store := NewStore(testPool)
+	transfer, fromAccount, approver := CreateRandomPendingTransfer(t, time.Hour)
+
+	// not overdue yet
+	_, err := store.ExpireTransferTx(context.Background(), ExpireTransferTxParams{
+		TransferID: transfer.ID,
+		Now:        time.Now(),
+	})
+	require.ErrorIs(t, err, ErrTransferNotPending)
+
+	now := time.Now().Add(2 * time.Hour)
+	transfers, err := testQueries.ListExpiredPendingTransfers(context.Background(), ListExpiredPendingTransfersParams{
+		ExpiresAt: pgtype.Timestamptz{Time: now, Valid: true},
+		Limit:     1000,
+	})
+	require.NoError(t, err)
+	ids := make(map[int64]bool)
+	for _, transfer := range transfers {
+		ids[transfer.ID] = true
+	}
+	require.Contains(t, ids, transfer.ID)
+
+	expired, err := store.ExpireTransferTx(context.Background(), ExpireTransferTxParams{
+		TransferID: transfer.ID,
+		Now:        now,
+	})
+	require.NoError(t, err)
+	require.Equal(t, TransferExpired, expired.Status)
+	require.Empty(t, expired.Approver)
+
+	updatedAccount, err := testQueries.GetAccount(context.Background(), fromAccount.ID)
+	require.NoError(t, err)
+	require.Zero(t, updatedAccount.HeldBalance)
+
+	// an expired transfer can no longer be approved
+	_, err = store.ApproveTransferTx(context.Background(), DecideTransferTxParams{
+		TransferID: transfer.ID,
+		Approver:   approver.Username,
+	})
+	require.ErrorIs(t, err, ErrTransferNotPending)
+}
@@ -0,0 +1,238 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// list of transfer statuses
+const (
+	TransferPending   = "pending"
+	TransferApproved  = "approved"
+	TransferRejected  = "rejected"
+	TransferCompleted = "completed"
+	TransferExpired   = "expired"
+)
+
+// DefaultPendingTransferTTL is how long a pending transfer waits for approval by default
+const DefaultPendingTransferTTL = 72 * time.Hour
+
+// Different types of errors returned by the pending transfer transactions
+var (
+	ErrApprovalRequired      = errors.New("transfer requires approval")
+	ErrTransferNotPending    = errors.New("transfer is not pending")
+	ErrTransferExpired       = errors.New("transfer approval has expired")
+	ErrInitiatorCannotDecide = errors.New("transfer cannot be approved or rejected by its initiator")
+)
+
+// holdPendingTransfer creates a transfer waiting for approval and puts its amount and fee on hold.
+// It is refused if it exceeds the transfer limits already, its usage is accounted once it is approved
+func holdPendingTransfer(ctx context.Context, q *Queries, arg TransferTxParams) (TransferTxResult, error) {
+	var result TransferTxResult
+
+	err := lockOpenAccounts(ctx, q, arg.FromAccountID, arg.ToAccountID)
+	if err != nil {
+		return result, err
+	}
+
+	policy, err := loadTransferPolicy(ctx, q, arg.FromAccountID)
+	if err != nil {
+		return result, err
+	}
+	fee, feeAccountID, err := transferFee(ctx, q, policy, arg.Amount)
+	if err != nil {
+		return result, err
+	}
+	if policy.account.AvailableBalance < arg.Amount+fee {
+		return result, ErrInsufficientFunds
+	}
+	err = previewTransferLimits(ctx, q, policy, arg.Amount)
+	if err != nil {
+		return result, err
+	}
+
+	ttl := arg.PendingTTL
+	if ttl <= 0 {
+		ttl = DefaultPendingTransferTTL
+	}
+
+	result.Transfer, err = q.CreatePendingTransfer(ctx, CreatePendingTransferParams{
+		FromAccountID: arg.FromAccountID,
+		ToAccountID:   arg.ToAccountID,
+		Amount:        arg.Amount,
+		Initiator:     arg.Initiator,
+		Fee:           fee,
+		FeeAccountID:  feeAccountID,
+		ExpiresAt:     pgtype.Timestamptz{Time: time.Now().Add(ttl), Valid: true},
+	})
+	if err != nil {
+		return result, err
+	}
+
+	result.FromAccount, err = q.AddAccountHeldBalance(ctx, AddAccountHeldBalanceParams{
+		ID:     arg.FromAccountID,
+		Amount: arg.Amount + fee,
+	})
+	if err != nil {
+		return result, err
+	}
+
+	err = recordTransferEvent(ctx, q, EventTransferPending, result.Transfer)
+	if err != nil {
+		return result, err
+	}
+
+	result.ToAccount, err = q.GetAccount(ctx, arg.ToAccountID)
+	return result, err
+}
+
+// DecideTransferTxParams contains the input parameters of the approval and rejection transactions
+type DecideTransferTxParams struct {
+	TransferID int64  `json:"transfer_id"`
+	Approver   string `json:"approver"`
+}
+
+// ApproveTransferTx approves a pending transfer: its hold is released and the money is moved
+// within a single db transaction. Transfer limits are checked at the time of approval
+func (s *SQLStore) ApproveTransferTx(ctx context.Context, arg DecideTransferTxParams) (TransferTxResult, error) {
+	var result TransferTxResult
+
+	err := s.ExecTxWithOptions(ctx, serializableTx, func(q *Queries) error {
+		transfer, err := decidePendingTransfer(ctx, q, arg, TransferApproved)
+		if err != nil {
+			return err
+		}
+		err = lockOpenAccounts(ctx, q, transfer.FromAccountID, transfer.ToAccountID)
+		if err != nil {
+			return err
+		}
+
+		policy, err := loadTransferPolicy(ctx, q, transfer.FromAccountID)
+		if err != nil {
+			return err
+		}
+		err = checkTransferLimits(ctx, q, policy, transfer.Amount)
+		if err != nil {
+			return err
+		}
+
+		transfer, err = q.UpdateTransferStatus(ctx, UpdateTransferStatusParams{
+			ID:       transfer.ID,
+			Status:   TransferCompleted,
+			Approver: arg.Approver,
+		})
+		if err != nil {
+			return err
+		}
+
+		result, err = moveMoney(ctx, q, transfer)
+		return err
+	})
+
+	return result, err
+}
+
+// RejectTransferTx rejects a pending transfer and releases its hold
+func (s *SQLStore) RejectTransferTx(ctx context.Context, arg DecideTransferTxParams) (Transfer, error) {
+	var transfer Transfer
+
+	err := s.execTx(ctx, func(q *Queries) error {
+		var err error
+		transfer, err = decidePendingTransfer(ctx, q, arg, TransferRejected)
+		if err != nil {
+			return err
+		}
+
+		return recordTransferEvent(ctx, q, EventTransferRejected, transfer)
+	})
+
+	return transfer, err
+}
+
+// ExpireTransferTxParams contains the input parameters of the expiry transaction
+type ExpireTransferTxParams struct {
+	TransferID int64     `json:"transfer_id"`
+	Now        time.Time `json:"now"`
+}
+
+// ExpireTransferTx expires a pending transfer whose approval is overdue and releases its hold.
+// It returns ErrTransferNotPending if the transfer was decided or is not overdue yet
+func (s *SQLStore) ExpireTransferTx(ctx context.Context, arg ExpireTransferTxParams) (Transfer, error) {
+	var transfer Transfer
+
+	err := s.execTx(ctx, func(q *Queries) error {
+		var err error
+		transfer, err = q.GetTransferForUpdate(ctx, arg.TransferID)
+		if err != nil {
+			return err
+		}
+		err = lockAccounts(ctx, q, transfer.FromAccountID, transfer.ToAccountID)
+		if err != nil {
+			return err
+		}
+		if transfer.Status != TransferPending || transfer.ExpiresAt.Time.After(arg.Now) {
+			return ErrTransferNotPending
+		}
+
+		transfer, err = q.UpdateTransferStatus(ctx, UpdateTransferStatusParams{
+			ID:     transfer.ID,
+			Status: TransferExpired,
+		})
+		if err != nil {
+			return err
+		}
+
+		err = releaseHeldFunds(ctx, q, transfer.FromAccountID, transfer.Amount+transfer.Fee)
+		if err != nil {
+			return err
+		}
+
+		return recordTransferEvent(ctx, q, EventTransferExpired, transfer)
+	})
+
+	return transfer, err
+}
+
+// decidePendingTransfer locks a pending transfer, releases its hold and records the decision
+func decidePendingTransfer(ctx context.Context, q *Queries, arg DecideTransferTxParams, status string) (Transfer, error) {
+	transfer, err := q.GetTransferForUpdate(ctx, arg.TransferID)
+	if err != nil {
+		return transfer, err
+	}
+	err = lockAccounts(ctx, q, transfer.FromAccountID, transfer.ToAccountID)
+	if err != nil {
+		return transfer, err
+	}
+	if transfer.Status != TransferPending {
+		return transfer, ErrTransferNotPending
+	}
+	if !transfer.ExpiresAt.Time.After(time.Now()) {
+		return transfer, ErrTransferExpired
+	}
+	if transfer.Initiator == arg.Approver {
+		return transfer, ErrInitiatorCannotDecide
+	}
+
+	transfer, err = q.UpdateTransferStatus(ctx, UpdateTransferStatusParams{
+		ID:       transfer.ID,
+		Status:   status,
+		Approver: arg.Approver,
+	})
+	if err != nil {
+		return transfer, err
+	}
+
+	return transfer, releaseHeldFunds(ctx, q, transfer.FromAccountID, transfer.Amount+transfer.Fee)
+}
+
+// releaseHeldFunds makes funds on hold available again
+func releaseHeldFunds(ctx context.Context, q *Queries, accountID int64, amount int64) error {
+	_, err := q.AddAccountHeldBalance(ctx, AddAccountHeldBalanceParams{
+		ID:     accountID,
+		Amount: -amount,
+	})
+	return err
+}
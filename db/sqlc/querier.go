@@ -10,20 +10,149 @@ import (
 
 type Querier interface {
 	AddAccountBalanceParams(ctx context.Context, arg AddAccountBalanceParamsParams) (Account, error)
+	AddAccountHeldBalance(ctx context.Context, arg AddAccountHeldBalanceParams) (Account, error)
+	AddTransferUsage(ctx context.Context, arg AddTransferUsageParams) (int64, error)
+	CancelStandingOrder(ctx context.Context, id int64) (StandingOrder, error)
+	ClaimOutboxEvents(ctx context.Context, arg ClaimOutboxEventsParams) ([]OutboxEvent, error)
+	ClaimWebhookDeliveries(ctx context.Context, arg ClaimWebhookDeliveriesParams) ([]WebhookDelivery, error)
+	CloseZeroBalanceAccounts(ctx context.Context, arg CloseZeroBalanceAccountsParams) ([]Account, error)
+	ConfirmTOTPSecret(ctx context.Context, arg ConfirmTOTPSecretParams) (TotpSecret, error)
+	CountPasswordResetsSince(ctx context.Context, arg CountPasswordResetsSinceParams) (int64, error)
+	CountVerifyEmailsSince(ctx context.Context, arg CountVerifyEmailsSinceParams) (int64, error)
+	CreateAPIKey(ctx context.Context, arg CreateAPIKeyParams) (ApiKey, error)
 	CreateAccount(ctx context.Context, arg CreateAccountParams) (Account, error)
+	CreateAccountApprover(ctx context.Context, arg CreateAccountApproverParams) (AccountApprover, error)
 	CreateEntry(ctx context.Context, arg CreateEntryParams) (Entry, error)
+	CreateHold(ctx context.Context, arg CreateHoldParams) (Hold, error)
+	CreateLoginAttempt(ctx context.Context, arg CreateLoginAttemptParams) (LoginAttempt, error)
+	CreateOAuthAuthorizationCode(ctx context.Context, arg CreateOAuthAuthorizationCodeParams) (OauthAuthorizationCode, error)
+	CreateOAuthClient(ctx context.Context, arg CreateOAuthClientParams) (OauthClient, error)
+	CreateOAuthRefreshToken(ctx context.Context, arg CreateOAuthRefreshTokenParams) (OauthRefreshToken, error)
+	CreateOutboxEvent(ctx context.Context, arg CreateOutboxEventParams) (OutboxEvent, error)
+	CreatePasswordReset(ctx context.Context, arg CreatePasswordResetParams) (PasswordReset, error)
+	CreatePendingTransfer(ctx context.Context, arg CreatePendingTransferParams) (Transfer, error)
+	CreateRecoveryCode(ctx context.Context, arg CreateRecoveryCodeParams) (RecoveryCode, error)
+	CreateSession(ctx context.Context, arg CreateSessionParams) (Session, error)
+	CreateStandingOrder(ctx context.Context, arg CreateStandingOrderParams) (StandingOrder, error)
+	CreateStandingOrderRun(ctx context.Context, arg CreateStandingOrderRunParams) (StandingOrderRun, error)
 	CreateTransfer(ctx context.Context, arg CreateTransferParams) (Transfer, error)
+	CreateTransferBatch(ctx context.Context, arg CreateTransferBatchParams) (TransferBatch, error)
+	CreateTransferBatchItem(ctx context.Context, arg CreateTransferBatchItemParams) (TransferBatchItem, error)
+	CreateTransferEntry(ctx context.Context, arg CreateTransferEntryParams) (Entry, error)
 	CreateUser(ctx context.Context, arg CreateUserParams) (User, error)
+	CreateVerifyEmail(ctx context.Context, arg CreateVerifyEmailParams) (VerifyEmail, error)
+	CreateWebAuthnChallenge(ctx context.Context, arg CreateWebAuthnChallengeParams) (WebauthnChallenge, error)
+	CreateWebAuthnCredential(ctx context.Context, arg CreateWebAuthnCredentialParams) (WebauthnCredential, error)
+	CreateWebhookDeliveries(ctx context.Context, arg CreateWebhookDeliveriesParams) (int64, error)
+	CreateWebhookSubscription(ctx context.Context, arg CreateWebhookSubscriptionParams) (WebhookSubscription, error)
+	DeactivateUser(ctx context.Context, arg DeactivateUserParams) (User, error)
 	DeleteAccount(ctx context.Context, id int64) error
+	DeleteAccountApprover(ctx context.Context, arg DeleteAccountApproverParams) error
+	DeleteFeeSchedule(ctx context.Context, arg DeleteFeeScheduleParams) error
+	DeleteLoginThrottle(ctx context.Context, key string) error
+	DeleteRecoveryCodes(ctx context.Context, username string) error
+	DeleteTransferLimit(ctx context.Context, arg DeleteTransferLimitParams) error
+	DeleteWebAuthnCredential(ctx context.Context, id int64) error
+	DeleteWebhookSubscription(ctx context.Context, id int64) error
+	EnableUserMFA(ctx context.Context, username string) (User, error)
+	GetAPIKey(ctx context.Context, id int64) (ApiKey, error)
+	GetAPIKeyByHash(ctx context.Context, keyHash string) (ApiKey, error)
 	GetAccount(ctx context.Context, id int64) (Account, error)
+	GetAccountApprover(ctx context.Context, arg GetAccountApproverParams) (AccountApprover, error)
 	GetAccountForUpdate(ctx context.Context, id int64) (Account, error)
 	GetEntry(ctx context.Context, id int64) (Entry, error)
+	GetFeeSchedule(ctx context.Context, arg GetFeeScheduleParams) (FeeSchedule, error)
+	GetHold(ctx context.Context, id int64) (Hold, error)
+	GetHoldForUpdate(ctx context.Context, id int64) (Hold, error)
+	GetOAuthClient(ctx context.Context, id string) (OauthClient, error)
+	GetOAuthRefreshTokenForUpdate(ctx context.Context, tokenHash string) (OauthRefreshToken, error)
+	GetOutboxEvent(ctx context.Context, id int64) (OutboxEvent, error)
+	GetPasswordResetUser(ctx context.Context, arg GetPasswordResetUserParams) (User, error)
+	GetRevenueAccount(ctx context.Context, currency string) (RevenueAccount, error)
+	GetSession(ctx context.Context, id int64) (Session, error)
+	GetStandingOrder(ctx context.Context, id int64) (StandingOrder, error)
+	GetStandingOrderForUpdate(ctx context.Context, id int64) (StandingOrder, error)
+	GetTOTPSecret(ctx context.Context, username string) (TotpSecret, error)
 	GetTransfer(ctx context.Context, id int64) (Transfer, error)
+	GetTransferBatch(ctx context.Context, id int64) (TransferBatch, error)
+	GetTransferForUpdate(ctx context.Context, id int64) (Transfer, error)
+	GetTransferLimit(ctx context.Context, arg GetTransferLimitParams) (TransferLimit, error)
+	GetTransferUsage(ctx context.Context, arg GetTransferUsageParams) (int64, error)
 	GetUser(ctx context.Context, username string) (User, error)
+	GetUserByEmail(ctx context.Context, email string) (User, error)
+	GetWebAuthnCredential(ctx context.Context, id int64) (WebauthnCredential, error)
+	GetWebAuthnCredentialByCredentialID(ctx context.Context, credentialID []byte) (WebauthnCredential, error)
+	GetWebhookDelivery(ctx context.Context, id int64) (WebhookDelivery, error)
+	GetWebhookDeliveryForUpdate(ctx context.Context, id int64) (WebhookDelivery, error)
+	GetWebhookSubscription(ctx context.Context, id int64) (WebhookSubscription, error)
+	GetWebhookSubscriptionForUpdate(ctx context.Context, id int64) (WebhookSubscription, error)
+	IncrementLoginThrottle(ctx context.Context, arg IncrementLoginThrottleParams) (LoginThrottle, error)
+	InvalidatePasswordResets(ctx context.Context, username string) error
+	ListAPIKeys(ctx context.Context, username string) ([]ApiKey, error)
+	ListAccountApprovers(ctx context.Context, accountID int64) ([]AccountApprover, error)
 	ListAccounts(ctx context.Context, arg ListAccountsParams) ([]Account, error)
+	ListActiveSessions(ctx context.Context, arg ListActiveSessionsParams) ([]Session, error)
+	ListDueStandingOrders(ctx context.Context, arg ListDueStandingOrdersParams) ([]StandingOrder, error)
 	ListEntries(ctx context.Context, arg ListEntriesParams) ([]Entry, error)
+	ListExpiredHolds(ctx context.Context, arg ListExpiredHoldsParams) ([]Hold, error)
+	ListExpiredPendingTransfers(ctx context.Context, arg ListExpiredPendingTransfersParams) ([]Transfer, error)
+	ListFeeSchedules(ctx context.Context) ([]FeeSchedule, error)
+	ListLoginAttempts(ctx context.Context, arg ListLoginAttemptsParams) ([]LoginAttempt, error)
+	ListLoginThrottles(ctx context.Context, keys []string) ([]LoginThrottle, error)
+	ListOAuthClients(ctx context.Context) ([]OauthClient, error)
+	ListOutboxEvents(ctx context.Context, arg ListOutboxEventsParams) ([]OutboxEvent, error)
+	ListOwnerEntriesAfter(ctx context.Context, arg ListOwnerEntriesAfterParams) ([]Entry, error)
+	ListRevenueAccounts(ctx context.Context) ([]RevenueAccount, error)
+	ListStandingOrderRuns(ctx context.Context, arg ListStandingOrderRunsParams) ([]StandingOrderRun, error)
+	ListTransferBatchItems(ctx context.Context, batchID int64) ([]TransferBatchItem, error)
+	ListTransferLimits(ctx context.Context) ([]TransferLimit, error)
 	ListTransfers(ctx context.Context, arg ListTransfersParams) ([]Transfer, error)
+	ListWebAuthnCredentials(ctx context.Context, username string) ([]WebauthnCredential, error)
+	ListWebhookDeliveries(ctx context.Context, arg ListWebhookDeliveriesParams) ([]WebhookDelivery, error)
+	ListWebhookSubscriptions(ctx context.Context, owner string) ([]WebhookSubscription, error)
+	LockLoginThrottle(ctx context.Context, arg LockLoginThrottleParams) (LoginThrottle, error)
+	MarkOutboxEventSent(ctx context.Context, id int64) error
+	PauseStandingOrder(ctx context.Context, id int64) (StandingOrder, error)
+	RehashUserPassword(ctx context.Context, arg RehashUserPasswordParams) error
+	ReleaseOutboxEvents(ctx context.Context, ids []int64) error
+	ReplayOutboxEvents(ctx context.Context, id int64) (int64, error)
+	ResumeStandingOrder(ctx context.Context, arg ResumeStandingOrderParams) (StandingOrder, error)
+	RevokeAPIKey(ctx context.Context, arg RevokeAPIKeyParams) (ApiKey, error)
+	RevokeOAuthGrant(ctx context.Context, arg RevokeOAuthGrantParams) (int64, error)
+	RevokeOAuthRefreshToken(ctx context.Context, arg RevokeOAuthRefreshTokenParams) error
+	RevokeOAuthRefreshTokenByHash(ctx context.Context, arg RevokeOAuthRefreshTokenByHashParams) error
+	RevokeOtherSessions(ctx context.Context, arg RevokeOtherSessionsParams) (int64, error)
+	RevokeSession(ctx context.Context, arg RevokeSessionParams) (Session, error)
+	RevokeUserAPIKeys(ctx context.Context, arg RevokeUserAPIKeysParams) error
+	RevokeUserOAuthRefreshTokens(ctx context.Context, arg RevokeUserOAuthRefreshTokensParams) error
+	RevokeUserSessions(ctx context.Context, arg RevokeUserSessionsParams) (int64, error)
+	SetRevenueAccount(ctx context.Context, arg SetRevenueAccountParams) (RevenueAccount, error)
+	SubtractTransferUsage(ctx context.Context, arg SubtractTransferUsageParams) error
+	TouchAPIKey(ctx context.Context, arg TouchAPIKeyParams) error
+	TouchSession(ctx context.Context, arg TouchSessionParams) error
+	UncountLoginFailures(ctx context.Context, keys []string) error
 	UpdateAccount(ctx context.Context, arg UpdateAccountParams) (Account, error)
+	UpdateHoldStatus(ctx context.Context, arg UpdateHoldStatusParams) (Hold, error)
+	UpdateStandingOrderSchedule(ctx context.Context, arg UpdateStandingOrderScheduleParams) (StandingOrder, error)
+	UpdateTransferBatchStatus(ctx context.Context, arg UpdateTransferBatchStatusParams) (TransferBatch, error)
+	UpdateTransferStatus(ctx context.Context, arg UpdateTransferStatusParams) (Transfer, error)
+	UpdateUser(ctx context.Context, arg UpdateUserParams) (User, error)
+	UpdateUserPassword(ctx context.Context, arg UpdateUserPasswordParams) (User, error)
+	UpdateUserTier(ctx context.Context, arg UpdateUserTierParams) (User, error)
+	UpdateWebAuthnCredentialUse(ctx context.Context, arg UpdateWebAuthnCredentialUseParams) (WebauthnCredential, error)
+	UpdateWebhookDelivery(ctx context.Context, arg UpdateWebhookDeliveryParams) (WebhookDelivery, error)
+	UpdateWebhookSubscription(ctx context.Context, arg UpdateWebhookSubscriptionParams) (WebhookSubscription, error)
+	UpdateWebhookSubscriptionFailures(ctx context.Context, arg UpdateWebhookSubscriptionFailuresParams) (WebhookSubscription, error)
+	UpsertFeeSchedule(ctx context.Context, arg UpsertFeeScheduleParams) (FeeSchedule, error)
+	UpsertTOTPSecret(ctx context.Context, arg UpsertTOTPSecretParams) (TotpSecret, error)
+	UpsertTransferLimit(ctx context.Context, arg UpsertTransferLimitParams) (TransferLimit, error)
+	UseOAuthAuthorizationCode(ctx context.Context, arg UseOAuthAuthorizationCodeParams) (OauthAuthorizationCode, error)
+	UsePasswordReset(ctx context.Context, arg UsePasswordResetParams) (PasswordReset, error)
+	UseRecoveryCode(ctx context.Context, arg UseRecoveryCodeParams) (RecoveryCode, error)
+	UseTOTPStep(ctx context.Context, arg UseTOTPStepParams) (TotpSecret, error)
+	UseVerifyEmail(ctx context.Context, arg UseVerifyEmailParams) (VerifyEmail, error)
+	UseWebAuthnChallenge(ctx context.Context, arg UseWebAuthnChallengeParams) (WebauthnChallenge, error)
+	VerifyUserEmail(ctx context.Context, arg VerifyUserEmailParams) (User, error)
 }
 
 var _ Querier = (*Queries)(nil)
@@ -0,0 +1,122 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/roman-adamchik/simplebank/util"
+	"github.com/stretchr/testify/require"
+)
+
+func createRandomSession(t *testing.T, user User) Session {
+	t.Helper()
+
+	now := time.Now()
+	arg := CreateSessionParams{
+		Username:   user.Username,
+		UserAgent:  "Mozilla/5.0 " + util.RandomString(6),
+		ClientIp:   "198.51.100.7",
+		ExpiresAt:  pgtype.Timestamptz{Time: now.Add(time.Hour), Valid: true},
+		LastUsedAt: pgtype.Timestamptz{Time: now, Valid: true},
+	}
+	session, err := testQueries.CreateSession(context.Background(), arg)
+	require.NoError(t, err)
+	require.NotZero(t, session.ID)
+	require.Equal(t, arg.Username, session.Username)
+	require.Equal(t, arg.UserAgent, session.UserAgent)
+	require.Equal(t, arg.ClientIp, session.ClientIp)
+	require.WithinDuration(t, arg.ExpiresAt.Time, session.ExpiresAt.Time, time.Second)
+	require.False(t, session.RevokedAt.Valid)
+
+	return session
+}
+
+func TestListActiveSessions(t *testing.T) {
+	user := CreateRandomUser(t)
+	session1 := createRandomSession(t, user)
+	session2 := createRandomSession(t, user)
+	revoked := createRandomSession(t, user)
+	createRandomSession(t, CreateRandomUser(t))
+
+	now := pgtype.Timestamptz{Time: time.Now(), Valid: true}
+	_, err := testQueries.RevokeSession(context.Background(), RevokeSessionParams{RevokedAt: now, ID: revoked.ID})
+	require.NoError(t, err)
+
+	sessions, err := testQueries.ListActiveSessions(context.Background(), ListActiveSessionsParams{
+		Username: user.Username,
+		Now:      now,
+	})
+	require.NoError(t, err)
+	require.Len(t, sessions, 2)
+	require.ElementsMatch(t, []int64{session1.ID, session2.ID}, []int64{sessions[0].ID, sessions[1].ID})
+
+	// the sessions whose access token has expired are not active anymore
+	sessions, err = testQueries.ListActiveSessions(context.Background(), ListActiveSessionsParams{
+		Username: user.Username,
+		Now:      pgtype.Timestamptz{Time: time.Now().Add(2 * time.Hour), Valid: true},
+	})
+	require.NoError(t, err)
+	require.Empty(t, sessions)
+}
+
+func TestRevokeSession(t *testing.T) {
+	session := createRandomSession(t, CreateRandomUser(t))
+	now := pgtype.Timestamptz{Time: time.Now(), Valid: true}
+
+	revoked, err := testQueries.RevokeSession(context.Background(), RevokeSessionParams{RevokedAt: now, ID: session.ID})
+	require.NoError(t, err)
+	require.WithinDuration(t, now.Time, revoked.RevokedAt.Time, time.Second)
+
+	_, err = testQueries.RevokeSession(context.Background(), RevokeSessionParams{RevokedAt: now, ID: session.ID})
+	require.ErrorIs(t, err, ErrRecordNotFound)
+}
+
+func TestRevokeOtherSessions(t *testing.T) {
+	user := CreateRandomUser(t)
+	current := createRandomSession(t, user)
+	other := createRandomSession(t, user)
+	createRandomSession(t, user)
+
+	rows, err := testQueries.RevokeOtherSessions(context.Background(), RevokeOtherSessionsParams{
+		RevokedAt: pgtype.Timestamptz{Time: time.Now(), Valid: true},
+		Username:  user.Username,
+		KeepID:    current.ID,
+	})
+	require.NoError(t, err)
+	require.Equal(t, int64(2), rows)
+
+	current, err = testQueries.GetSession(context.Background(), current.ID)
+	require.NoError(t, err)
+	require.False(t, current.RevokedAt.Valid)
+
+	other, err = testQueries.GetSession(context.Background(), other.ID)
+	require.NoError(t, err)
+	require.True(t, other.RevokedAt.Valid)
+}
+
+func TestTouchSession(t *testing.T) {
+	session := createRandomSession(t, CreateRandomUser(t))
+	now := session.LastUsedAt.Time
+
+	touch := func(at time.Time) {
+		err := testQueries.TouchSession(context.Background(), TouchSessionParams{
+			LastUsedAt: pgtype.Timestamptz{Time: at, Valid: true},
+			ID:         session.ID,
+			UsedBefore: pgtype.Timestamptz{Time: at.Add(-time.Minute), Valid: true},
+		})
+		require.NoError(t, err)
+	}
+
+	// a use within the interval is not written down
+	touch(now.Add(30 * time.Second))
+	got, err := testQueries.GetSession(context.Background(), session.ID)
+	require.NoError(t, err)
+	require.WithinDuration(t, now, got.LastUsedAt.Time, time.Second)
+
+	touch(now.Add(2 * time.Minute))
+	got, err = testQueries.GetSession(context.Background(), session.ID)
+	require.NoError(t, err)
+	require.WithinDuration(t, now.Add(2*time.Minute), got.LastUsedAt.Time, time.Second)
+}
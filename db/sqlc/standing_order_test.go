@@ -0,0 +1,261 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/roman-adamchik/simplebank/util"
+	"github.com/stretchr/testify/require"
+)
+
+func CreateRandomStandingOrder(t *testing.T, fromAccountID int64, toAccountID int64, startAt time.Time) StandingOrder {
+	t.Helper()
+
+	args := CreateStandingOrderParams{
+		FromAccountID: fromAccountID,
+		ToAccountID:   toAccountID,
+		Amount:        util.RandomInt(1, 10),
+		Frequency:     FrequencyMonthly,
+		IntervalCount: 1,
+		StartAt:       pgtype.Timestamptz{Time: startAt, Valid: true},
+		NextRunAt:     pgtype.Timestamptz{Time: startAt, Valid: true},
+		Creator:       util.RandomOwner(),
+	}
+
+	order, err := testQueries.CreateStandingOrder(context.Background(), args)
+	require.NoError(t, err)
+	require.NotEmpty(t, order)
+	require.Equal(t, args.FromAccountID, order.FromAccountID)
+	require.Equal(t, args.ToAccountID, order.ToAccountID)
+	require.Equal(t, args.Amount, order.Amount)
+	require.Equal(t, args.Frequency, order.Frequency)
+	require.Equal(t, args.IntervalCount, order.IntervalCount)
+	require.WithinDuration(t, startAt, order.StartAt.Time, time.Second)
+	require.WithinDuration(t, startAt, order.NextRunAt.Time, time.Second)
+	require.False(t, order.EndAt.Valid)
+	require.False(t, order.MaxOccurrences.Valid)
+	require.Zero(t, order.Occurrences)
+	require.Equal(t, StandingOrderActive, order.Status)
+	require.NotZero(t, order.ID)
+	require.NotZero(t, order.CreatedAt)
+
+	return order
+}
+
+func TestCreateStandingOrder(t *testing.T) {
+	account1 := CreateRandomAccount(t)
+	account2 := CreateRandomAccount(t)
+	CreateRandomStandingOrder(t, account1.ID, account2.ID, time.Now())
+}
+
+func TestGetStandingOrder(t *testing.T) {
+	account1 := CreateRandomAccount(t)
+	account2 := CreateRandomAccount(t)
+	order1 := CreateRandomStandingOrder(t, account1.ID, account2.ID, time.Now())
+
+	order2, err := testQueries.GetStandingOrder(context.Background(), order1.ID)
+	require.NoError(t, err)
+	require.Equal(t, order1.ID, order2.ID)
+	require.Equal(t, order1.FromAccountID, order2.FromAccountID)
+	require.Equal(t, order1.ToAccountID, order2.ToAccountID)
+	require.Equal(t, order1.Amount, order2.Amount)
+	require.Equal(t, order1.Status, order2.Status)
+	require.WithinDuration(t, order1.NextRunAt.Time, order2.NextRunAt.Time, time.Second)
+}
+
+func TestPauseResumeStandingOrder(t *testing.T) {
+	account1 := CreateRandomAccount(t)
+	account2 := CreateRandomAccount(t)
+	order := CreateRandomStandingOrder(t, account1.ID, account2.ID, time.Now())
+
+	paused, err := testQueries.PauseStandingOrder(context.Background(), order.ID)
+	require.NoError(t, err)
+	require.Equal(t, StandingOrderPaused, paused.Status)
+
+	_, err = testQueries.PauseStandingOrder(context.Background(), order.ID)
+	require.ErrorIs(t, err, ErrRecordNotFound)
+
+	nextRunAt := pgtype.Timestamptz{Time: time.Now().Add(time.Hour), Valid: true}
+	resumed, err := testQueries.ResumeStandingOrder(context.Background(), ResumeStandingOrderParams{
+		ID:        order.ID,
+		NextRunAt: nextRunAt,
+	})
+	require.NoError(t, err)
+	require.Equal(t, StandingOrderActive, resumed.Status)
+	require.WithinDuration(t, nextRunAt.Time, resumed.NextRunAt.Time, time.Second)
+}
+
+func TestListDueStandingOrders(t *testing.T) {
+	account1 := CreateRandomAccount(t)
+	account2 := CreateRandomAccount(t)
+	due := CreateRandomStandingOrder(t, account1.ID, account2.ID, time.Now().Add(-time.Minute))
+	notDue := CreateRandomStandingOrder(t, account1.ID, account2.ID, time.Now().Add(time.Hour))
+
+	orders, err := testQueries.ListDueStandingOrders(context.Background(), ListDueStandingOrdersParams{
+		NextRunAt: pgtype.Timestamptz{Time: time.Now(), Valid: true},
+		Limit:     1000,
+	})
+	require.NoError(t, err)
+
+	ids := make(map[int64]bool)
+	for _, order := range orders {
+		require.Equal(t, StandingOrderActive, order.Status)
+		ids[order.ID] = true
+	}
+	require.Contains(t, ids, due.ID)
+	require.NotContains(t, ids, notDue.ID)
+}
+
+func TestStandingOrderNextRunAfter(t *testing.T) {
+	startAt := time.Date(2024, time.January, 31, 9, 0, 0, 0, time.UTC)
+	order := StandingOrder{
+		Frequency:      FrequencyMonthly,
+		IntervalCount:  1,
+		StartAt:        pgtype.Timestamptz{Time: startAt, Valid: true},
+		EndAt:          pgtype.Timestamptz{Time: time.Date(2024, time.April, 1, 0, 0, 0, 0, time.UTC), Valid: true},
+		MaxOccurrences: pgtype.Int4{Int32: 5, Valid: true},
+	}
+
+	next, err := order.NextRunAfter(startAt.Add(-time.Nanosecond))
+	require.NoError(t, err)
+	require.Equal(t, startAt, next.Time)
+
+	next, err = order.NextRunAfter(startAt)
+	require.NoError(t, err)
+	require.Equal(t, time.Date(2024, time.February, 29, 9, 0, 0, 0, time.UTC), next.Time)
+
+	// after the end date
+	next, err = order.NextRunAfter(time.Date(2024, time.March, 31, 9, 0, 0, 0, time.UTC))
+	require.NoError(t, err)
+	require.False(t, next.Valid)
+
+	// all occurrences used
+	order.Occurrences = 5
+	next, err = order.NextRunAfter(startAt)
+	require.NoError(t, err)
+	require.False(t, next.Valid)
+}
+
+func TestExecuteStandingOrderTx(t *testing.T) {
+	store := NewStore(testPool)
+
+	fromAccount := CreateRandomAccount(t)
+	toAccount := CreateRandomAccount(t)
+	startAt := time.Now().Add(-time.Minute)
+	order := CreateRandomStandingOrder(t, fromAccount.ID, toAccount.ID, startAt)
+
+	result, err := store.ExecuteStandingOrderTx(context.Background(), ExecuteStandingOrderTxParams{
+		StandingOrderID: order.ID,
+		Now:             time.Now(),
+	})
+	require.NoError(t, err)
+
+	// check run
+	run := result.Run
+	require.NotZero(t, run.ID)
+	require.Equal(t, order.ID, run.StandingOrderID)
+	require.Equal(t, StandingOrderRunSucceeded, run.Status)
+	require.True(t, run.TransferID.Valid)
+	require.Equal(t, result.Transfer.Transfer.ID, run.TransferID.Int64)
+	require.Equal(t, order.Creator, result.Transfer.Transfer.Initiator)
+	require.Empty(t, run.Error)
+	require.WithinDuration(t, order.NextRunAt.Time, run.ScheduledAt.Time, time.Second)
+
+	// check transfer
+	require.Equal(t, fromAccount.ID, result.Transfer.Transfer.FromAccountID)
+	require.Equal(t, toAccount.ID, result.Transfer.Transfer.ToAccountID)
+	require.Equal(t, order.Amount, result.Transfer.Transfer.Amount)
+	require.Equal(t, fromAccount.Balance-order.Amount, result.Transfer.FromAccount.Balance)
+	require.Equal(t, toAccount.Balance+order.Amount, result.Transfer.ToAccount.Balance)
+
+	// check schedule
+	updated := result.StandingOrder
+	require.Equal(t, int32(1), updated.Occurrences)
+	require.Equal(t, StandingOrderActive, updated.Status)
+	require.WithinDuration(t, startAt.AddDate(0, 1, 0), updated.NextRunAt.Time, 72*time.Hour)
+
+	// the same occurrence cannot be executed twice
+	_, err = store.ExecuteStandingOrderTx(context.Background(), ExecuteStandingOrderTxParams{
+		StandingOrderID: order.ID,
+		Now:             time.Now(),
+	})
+	require.ErrorIs(t, err, ErrStandingOrderNotDue)
+
+	runs, err := testQueries.ListStandingOrderRuns(context.Background(), ListStandingOrderRunsParams{
+		StandingOrderID: order.ID,
+		Limit:           5,
+		Offset:          0,
+	})
+	require.NoError(t, err)
+	require.Len(t, runs, 1)
+}
+
+func TestExecuteStandingOrderTxLastOccurrence(t *testing.T) {
+	store := NewStore(testPool)
+
+	fromAccount := CreateRandomAccount(t)
+	toAccount := CreateRandomAccount(t)
+	startAt := time.Now().Add(-time.Minute)
+
+	order, err := testQueries.CreateStandingOrder(context.Background(), CreateStandingOrderParams{
+		FromAccountID:  fromAccount.ID,
+		ToAccountID:    toAccount.ID,
+		Amount:         1,
+		Frequency:      FrequencyWeekly,
+		IntervalCount:  1,
+		StartAt:        pgtype.Timestamptz{Time: startAt, Valid: true},
+		MaxOccurrences: pgtype.Int4{Int32: 1, Valid: true},
+		NextRunAt:      pgtype.Timestamptz{Time: startAt, Valid: true},
+	})
+	require.NoError(t, err)
+
+	result, err := store.ExecuteStandingOrderTx(context.Background(), ExecuteStandingOrderTxParams{
+		StandingOrderID: order.ID,
+		Now:             time.Now(),
+	})
+	require.NoError(t, err)
+	require.Equal(t, StandingOrderRunSucceeded, result.Run.Status)
+	require.Equal(t, StandingOrderCompleted, result.StandingOrder.Status)
+	require.False(t, result.StandingOrder.NextRunAt.Valid)
+}
+
+func TestExecuteStandingOrderTxFailedTransfer(t *testing.T) {
+	store := NewStore(testPool)
+
+	fromAccount := createLimitedAccount(t, UpsertTransferLimitParams{
+		MaxSingleAmount: pgtype.Int8{Int64: 1, Valid: true},
+	})
+	toAccount := CreateRandomAccount(t)
+	startAt := time.Now().Add(-time.Minute)
+
+	order, err := testQueries.CreateStandingOrder(context.Background(), CreateStandingOrderParams{
+		FromAccountID: fromAccount.ID,
+		ToAccountID:   toAccount.ID,
+		Amount:        2,
+		Frequency:     FrequencyWeekly,
+		IntervalCount: 1,
+		StartAt:       pgtype.Timestamptz{Time: startAt, Valid: true},
+		NextRunAt:     pgtype.Timestamptz{Time: startAt, Valid: true},
+	})
+	require.NoError(t, err)
+
+	result, err := store.ExecuteStandingOrderTx(context.Background(), ExecuteStandingOrderTxParams{
+		StandingOrderID: order.ID,
+		Now:             time.Now(),
+	})
+	require.NoError(t, err)
+	require.Equal(t, StandingOrderRunFailed, result.Run.Status)
+	require.False(t, result.Run.TransferID.Valid)
+	require.Contains(t, result.Run.Error, LimitMaxSingle)
+
+	// the schedule moves on to the next occurrence
+	require.Equal(t, int32(1), result.StandingOrder.Occurrences)
+	require.Equal(t, StandingOrderActive, result.StandingOrder.Status)
+	require.WithinDuration(t, startAt.AddDate(0, 0, 7), result.StandingOrder.NextRunAt.Time, time.Second)
+
+	updatedAccount, err := testQueries.GetAccount(context.Background(), fromAccount.ID)
+	require.NoError(t, err)
+	require.Equal(t, fromAccount.Balance, updatedAccount.Balance)
+}
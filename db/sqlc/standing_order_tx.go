@@ -0,0 +1,194 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/roman-adamchik/simplebank/schedule"
+)
+
+// Frequencies a standing order can be executed with
+const (
+	FrequencyWeekly  = "weekly"
+	FrequencyMonthly = "monthly"
+	FrequencyCustom  = "custom"
+)
+
+// Statuses of a standing order
+const (
+	StandingOrderActive    = "active"
+	StandingOrderPaused    = "paused"
+	StandingOrderCompleted = "completed"
+	StandingOrderCancelled = "cancelled"
+)
+
+// Outcomes of a single standing order occurrence
+const (
+	StandingOrderRunSucceeded = "succeeded"
+	StandingOrderRunFailed    = "failed"
+)
+
+// ErrStandingOrderNotDue is returned when a standing order is executed before its next run,
+// e.g. because it was paused or another worker already executed the occurrence
+var ErrStandingOrderNotDue = errors.New("standing order is not due")
+
+// Schedule returns the schedule of the standing order. Schedules are evaluated in UTC.
+func (order StandingOrder) Schedule() (schedule.Schedule, error) {
+	anchor := order.StartAt.Time.UTC()
+
+	switch order.Frequency {
+	case FrequencyWeekly:
+		return schedule.Weekly(anchor, int(order.IntervalCount))
+	case FrequencyMonthly:
+		return schedule.Monthly(anchor, int(order.IntervalCount))
+	case FrequencyCustom:
+		return schedule.ParseCron(order.CronExpression, time.UTC)
+	}
+
+	return nil, fmt.Errorf("unknown standing order frequency %q", order.Frequency)
+}
+
+// NextRunAfter returns the first occurrence of the standing order after the given time.
+// The returned timestamp is not valid if the standing order has no occurrences left.
+func (order StandingOrder) NextRunAfter(after time.Time) (pgtype.Timestamptz, error) {
+	if order.MaxOccurrences.Valid && order.Occurrences >= order.MaxOccurrences.Int32 {
+		return pgtype.Timestamptz{}, nil
+	}
+
+	s, err := order.Schedule()
+	if err != nil {
+		return pgtype.Timestamptz{}, err
+	}
+
+	var next time.Time
+	if after.Before(order.StartAt.Time) {
+		next = schedule.First(s, order.StartAt.Time)
+	} else {
+		next = s.Next(after)
+	}
+
+	if next.IsZero() || (order.EndAt.Valid && next.After(order.EndAt.Time)) {
+		return pgtype.Timestamptz{}, nil
+	}
+
+	return pgtype.Timestamptz{Time: next, Valid: true}, nil
+}
+
+// ExecuteStandingOrderTxParams contains the input parameters of the standing order transaction
+type ExecuteStandingOrderTxParams struct {
+	StandingOrderID int64     `json:"standing_order_id"`
+	Now             time.Time `json:"now"`
+}
+
+// ExecuteStandingOrderTxResult is the result of the standing order transaction
+type ExecuteStandingOrderTxResult struct {
+	StandingOrder StandingOrder    `json:"standing_order"`
+	Run           StandingOrderRun `json:"run"`
+	// Transfer is empty if the occurrence failed
+	Transfer TransferTxResult `json:"transfer"`
+}
+
+// ExecuteStandingOrderTx executes the due occurrence of a standing order.
+// The transfer, the run record and the updated schedule are written within a single db transaction.
+// If the transfer is rejected, it is rolled back and the failed occurrence is recorded in a separate transaction.
+// Any other error is returned and the occurrence stays due, so that it is executed again.
+func (s *SQLStore) ExecuteStandingOrderTx(ctx context.Context, arg ExecuteStandingOrderTxParams) (ExecuteStandingOrderTxResult, error) {
+	var result ExecuteStandingOrderTxResult
+	var transferErr error
+
+	err := s.ExecTxWithOptions(ctx, serializableTx, func(q *Queries) error {
+		transferErr = nil
+
+		order, err := getDueStandingOrder(ctx, q, arg)
+		if err != nil {
+			return err
+		}
+
+		result.Transfer, transferErr = transfer(ctx, q, TransferTxParams{
+			FromAccountID: order.FromAccountID,
+			ToAccountID:   order.ToAccountID,
+			Amount:        order.Amount,
+			Initiator:     order.Creator,
+		})
+		if transferErr != nil {
+			return transferErr
+		}
+
+		transferID := pgtype.Int8{Int64: result.Transfer.Transfer.ID, Valid: true}
+		result.StandingOrder, result.Run, err = recordStandingOrderRun(ctx, q, order, transferID, nil)
+		return err
+	})
+	if transferErr == nil || !isTransferRejection(transferErr) {
+		return result, err
+	}
+
+	result.Transfer = TransferTxResult{}
+	err = s.execTx(ctx, func(q *Queries) error {
+		order, err := getDueStandingOrder(ctx, q, arg)
+		if err != nil {
+			return err
+		}
+
+		result.StandingOrder, result.Run, err = recordStandingOrderRun(ctx, q, order, pgtype.Int8{}, transferErr)
+		return err
+	})
+
+	return result, err
+}
+
+// getDueStandingOrder locks the standing order and makes sure that it is due
+func getDueStandingOrder(ctx context.Context, q *Queries, arg ExecuteStandingOrderTxParams) (StandingOrder, error) {
+	order, err := q.GetStandingOrderForUpdate(ctx, arg.StandingOrderID)
+	if err != nil {
+		return order, err
+	}
+
+	if order.Status != StandingOrderActive || !order.NextRunAt.Valid || order.NextRunAt.Time.After(arg.Now) {
+		return order, ErrStandingOrderNotDue
+	}
+
+	return order, nil
+}
+
+// recordStandingOrderRun stores the outcome of the current occurrence and moves the standing order to its next run.
+// Failed occurrences count towards the maximum number of occurrences, so that every schedule ends.
+func recordStandingOrderRun(ctx context.Context, q *Queries, order StandingOrder, transferID pgtype.Int8, runErr error) (StandingOrder, StandingOrderRun, error) {
+	runArg := CreateStandingOrderRunParams{
+		StandingOrderID: order.ID,
+		ScheduledAt:     order.NextRunAt,
+		Status:          StandingOrderRunSucceeded,
+		TransferID:      transferID,
+	}
+	if runErr != nil {
+		runArg.Status = StandingOrderRunFailed
+		runArg.Error = runErr.Error()
+	}
+
+	run, err := q.CreateStandingOrderRun(ctx, runArg)
+	if err != nil {
+		return order, run, err
+	}
+
+	order.Occurrences++
+	nextRunAt, err := order.NextRunAfter(order.NextRunAt.Time)
+	if err != nil {
+		return order, run, err
+	}
+
+	status := order.Status
+	if !nextRunAt.Valid {
+		status = StandingOrderCompleted
+	}
+
+	order, err = q.UpdateStandingOrderSchedule(ctx, UpdateStandingOrderScheduleParams{
+		ID:          order.ID,
+		Occurrences: order.Occurrences,
+		NextRunAt:   nextRunAt,
+		Status:      status,
+	})
+
+	return order, run, err
+}
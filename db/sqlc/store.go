@@ -2,52 +2,131 @@ package db
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"slices"
+	"time"
 
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 type Store interface {
 	Querier
 	TransferTx(ctx context.Context, arg TransferTxParams) (TransferTxResult, error)
+	ApproveTransferTx(ctx context.Context, arg DecideTransferTxParams) (TransferTxResult, error)
+	RejectTransferTx(ctx context.Context, arg DecideTransferTxParams) (Transfer, error)
+	ExpireTransferTx(ctx context.Context, arg ExpireTransferTxParams) (Transfer, error)
+	CreateHoldTx(ctx context.Context, arg CreateHoldTxParams) (CreateHoldTxResult, error)
+	CaptureHoldTx(ctx context.Context, arg CaptureHoldTxParams) (CaptureHoldTxResult, error)
+	ReleaseHoldTx(ctx context.Context, holdID int64) (Hold, error)
+	ExpireHoldTx(ctx context.Context, arg ExpireHoldTxParams) (Hold, error)
+	ExecuteStandingOrderTx(ctx context.Context, arg ExecuteStandingOrderTxParams) (ExecuteStandingOrderTxResult, error)
+	BatchTransferTx(ctx context.Context, arg BatchTransferTxParams) (BatchTransferTxResult, error)
+	CreateAccountTx(ctx context.Context, arg CreateAccountParams) (Account, error)
+	CreateUserTx(ctx context.Context, arg CreateUserParams) (User, error)
+	RelayOutbox(ctx context.Context, arg RelayOutboxParams) (int, error)
+	RecordWebhookAttemptTx(ctx context.Context, arg RecordWebhookAttemptTxParams) (RecordWebhookAttemptTxResult, error)
+	VerifyEmailTx(ctx context.Context, arg VerifyEmailTxParams) (VerifyEmailTxResult, error)
+	ResetPasswordTx(ctx context.Context, arg ResetPasswordTxParams) (User, error)
+	ChangePasswordTx(ctx context.Context, arg ChangePasswordTxParams) (User, error)
+	StartLoginAttemptTx(ctx context.Context, arg StartLoginAttemptTxParams) (time.Time, error)
+	RecordLoginAttemptTx(ctx context.Context, arg RecordLoginAttemptTxParams) (LoginAttempt, error)
+	EnableMFATx(ctx context.Context, arg EnableMFATxParams) (User, error)
+	DeactivateUserTx(ctx context.Context, arg DeactivateUserTxParams) (DeactivateUserTxResult, error)
+	ExchangeAuthorizationCodeTx(ctx context.Context, arg ExchangeAuthorizationCodeTxParams) (OauthRefreshToken, error)
+	RefreshOAuthTokenTx(ctx context.Context, arg RefreshOAuthTokenTxParams) (OauthRefreshToken, error)
+	ExecTxWithOptions(ctx context.Context, opts pgx.TxOptions, fn func(*Queries) error) error
 }
 
 // SQLStore provides all functions to execute db queries and transactions
 type SQLStore struct {
 	*Queries
-	pool *pgxpool.Pool
+	pool  *pgxpool.Pool
+	retry txRetryPolicy
 }
 
-// NewStore creates a new Store
+// NewStore creates a new Store, its methods return the errors of the driver classified as in classifyError
 func NewStore(pool *pgxpool.Pool) Store {
 	return &SQLStore{
-		Queries: New(pool),
+		Queries: New(classifyingDBTX{pool}),
 		pool:    pool,
+		retry:   defaultTxRetryPolicy,
 	}
 }
 
-// execTx executes fn within a database transaction
+// execTx executes fn within a database transaction with the default options
 func (s *SQLStore) execTx(ctx context.Context, fn func(*Queries) error) error {
-	tx, err := s.pool.Begin(ctx)
+	return s.ExecTxWithOptions(ctx, pgx.TxOptions{}, fn)
+}
+
+// serializableTx are the options of the transactions checking transfer limits, the usage they read
+// and add to must not be changed by a concurrent transfer before they commit
+var serializableTx = pgx.TxOptions{IsoLevel: pgx.Serializable}
+
+// ReadOnlyTx are the options of a report, which reads several queries from one snapshot and writes nothing
+var ReadOnlyTx = pgx.TxOptions{IsoLevel: pgx.RepeatableRead, AccessMode: pgx.ReadOnly}
+
+// ExecTxWithOptions executes fn within a database transaction with the given isolation level and access mode.
+// A transaction failing with a serialization failure or a deadlock is run again after a jittered backoff,
+// so fn must not leave anything behind from a failed attempt but the values it assigns on every run
+func (s *SQLStore) ExecTxWithOptions(ctx context.Context, opts pgx.TxOptions, fn func(*Queries) error) error {
+	for attempt := 1; ; attempt++ {
+		err := classifyError(s.runTx(ctx, opts, fn))
+
+		code, retryable := retryableCode(err)
+		if !retryable {
+			return err
+		}
+		if attempt >= s.retry.maxAttempts {
+			TxMetrics.Add("exhausted", 1)
+			return err
+		}
+
+		TxMetrics.Add("retries", 1)
+		TxMetrics.Add("retries_"+code, 1)
+		if sleepErr := sleepCtx(ctx, s.retry.backoff(attempt)); sleepErr != nil {
+			return fmt.Errorf("tx err: %w, retry cancelled: %w", err, sleepErr)
+		}
+	}
+}
+
+// runTx runs a single attempt of a database transaction
+func (s *SQLStore) runTx(ctx context.Context, opts pgx.TxOptions, fn func(*Queries) error) error {
+	tx, err := s.pool.BeginTx(ctx, opts)
 	if err != nil {
 		return err
 	}
 
-	qtx := s.Queries.WithTx(tx)
+	qtx := New(classifyingDBTX{tx})
 
 	err = fn(qtx)
 	if err != nil {
 		if rbErr := tx.Rollback(ctx); rbErr != nil {
-			return fmt.Errorf("tx err: %v, rollback err: %v", err, rbErr)
+			return fmt.Errorf("tx err: %w, rollback err: %w", err, rbErr)
 		}
 		return err
 	}
 
-	if err := tx.Commit(ctx); err != nil {
-		return err
-	}
+	return tx.Commit(ctx)
+}
 
-	return nil
+// ErrInsufficientFunds is returned when the available balance of an account does not cover an amount
+var ErrInsufficientFunds = errors.New("insufficient funds")
+
+// ErrAccountClosed is returned when money would be moved from or to a closed account
+var ErrAccountClosed = errors.New("account is closed")
+
+// isTransferRejection reports whether a transfer failed because of the accounts or the amount involved,
+// and not because the database could not complete it. Only a rejected transfer is recorded as failed,
+// any other error, including a missing revenue account, is returned so the transfer can be tried again.
+func isTransferRejection(err error) bool {
+	var limitErr *LimitExceededError
+	return errors.Is(err, ErrInsufficientFunds) ||
+		errors.Is(err, ErrAccountClosed) ||
+		errors.Is(err, ErrApprovalRequired) ||
+		errors.As(err, &limitErr)
 }
 
 // TransferTxParams contains the input parameters of the transfer transaction
@@ -55,6 +134,10 @@ type TransferTxParams struct {
 	FromAccountID int64 `json:"from_account_id"`
 	ToAccountID   int64 `json:"to_account_id"`
 	Amount        int64 `json:"amount"`
+	// Initiator is the user who requested the transfer
+	Initiator string `json:"initiator"`
+	// PendingTTL is how long a transfer waits for approval, DefaultPendingTransferTTL if not set
+	PendingTTL time.Duration `json:"pending_ttl"`
 }
 
 // TransferTxResult is the result of the transfer transaction
@@ -64,57 +147,174 @@ type TransferTxResult struct {
 	ToAccount   Account  `json:"to_account"`
 	FromEntry   Entry    `json:"from_entry"`
 	ToEntry     Entry    `json:"to_entry"`
+	// FeeEntry is the sender's fee entry, empty for a transfer without a fee
+	FeeEntry Entry `json:"fee_entry"`
 }
 
 // TransferTx performs a money transfer from one account to the other.
-// It creates a transfer record, add account entries, and update accounts' balance within a single db transaction
+// It creates a transfer record, add account entries, and update accounts' balance within a single db transaction.
+// A transfer that needs a second approval is created as pending and its amount is put on hold instead
 func (s *SQLStore) TransferTx(ctx context.Context, arg TransferTxParams) (TransferTxResult, error) {
 	var result TransferTxResult
 
-	err := s.execTx(ctx, func(q *Queries) error {
+	err := s.ExecTxWithOptions(ctx, serializableTx, func(q *Queries) error {
 		var txError error
-
-		// create transfer record
-		result.Transfer, txError = q.CreateTransfer(ctx, CreateTransferParams{
-			FromAccountID: arg.FromAccountID,
-			ToAccountID:   arg.ToAccountID,
-			Amount:        arg.Amount,
-		})
-		if txError != nil {
-			return txError
+		result, txError = transfer(ctx, q, arg)
+		if errors.Is(txError, ErrApprovalRequired) {
+			result, txError = holdPendingTransfer(ctx, q, arg)
 		}
+		return txError
+	})
 
-		// create account entries
-		result.FromEntry, txError = q.CreateEntry(ctx, CreateEntryParams{
-			AccountID: arg.FromAccountID,
-			Amount:    -arg.Amount,
-		})
-		if txError != nil {
-			return txError
-		}
-		result.ToEntry, txError = q.CreateEntry(ctx, CreateEntryParams{
-			AccountID: arg.ToAccountID,
-			Amount:    arg.Amount,
-		})
+	return result, err
+}
+
+// transfer performs the steps of a money transfer using the given queries,
+// so it can be part of a larger db transaction
+func transfer(ctx context.Context, q *Queries, arg TransferTxParams) (TransferTxResult, error) {
+	var result TransferTxResult
+
+	err := lockOpenAccounts(ctx, q, arg.FromAccountID, arg.ToAccountID)
+	if err != nil {
+		return result, err
+	}
+
+	policy, err := loadTransferPolicy(ctx, q, arg.FromAccountID)
+	if err != nil {
+		return result, err
+	}
+	if policy.requiresApproval(arg.Amount) {
+		return result, ErrApprovalRequired
+	}
+
+	fee, feeAccountID, err := transferFee(ctx, q, policy, arg.Amount)
+	if err != nil {
+		return result, err
+	}
+	if policy.account.AvailableBalance < arg.Amount+fee {
+		return result, ErrInsufficientFunds
+	}
+
+	err = checkTransferLimits(ctx, q, policy, arg.Amount)
+	if err != nil {
+		return result, err
+	}
+
+	// create transfer record
+	transfer, err := q.CreateTransfer(ctx, CreateTransferParams{
+		FromAccountID: arg.FromAccountID,
+		ToAccountID:   arg.ToAccountID,
+		Amount:        arg.Amount,
+		Initiator:     arg.Initiator,
+		Fee:           fee,
+		FeeAccountID:  feeAccountID,
+	})
+	if err != nil {
+		return result, err
+	}
+
+	return moveMoney(ctx, q, transfer)
+}
+
+// moveMoney creates the account entries of a transfer record, posts its fee, updates accounts' balance,
+// notifies the account updates and records the transfer.completed event
+func moveMoney(ctx context.Context, q *Queries, transfer Transfer) (TransferTxResult, error) {
+	result := TransferTxResult{Transfer: transfer}
+	var txError error
+
+	// create account entries
+	result.FromEntry, txError = q.CreateTransferEntry(ctx, CreateTransferEntryParams{
+		AccountID:  transfer.FromAccountID,
+		Amount:     -transfer.Amount,
+		Kind:       EntryTransfer,
+		TransferID: pgtype.Int8{Int64: transfer.ID, Valid: true},
+	})
+	if txError != nil {
+		return result, txError
+	}
+	result.ToEntry, txError = q.CreateTransferEntry(ctx, CreateTransferEntryParams{
+		AccountID:  transfer.ToAccountID,
+		Amount:     transfer.Amount,
+		Kind:       EntryTransfer,
+		TransferID: pgtype.Int8{Int64: transfer.ID, Valid: true},
+	})
+	if txError != nil {
+		return result, txError
+	}
+
+	if transfer.Fee > 0 {
+		result.FeeEntry, txError = postFee(ctx, q, transfer)
 		if txError != nil {
-			return txError
+			return result, txError
 		}
+	}
 
-		// update accounts' balance
-		if arg.FromAccountID < arg.ToAccountID {
-			result.FromAccount, result.ToAccount, txError = addMoney(ctx, q, arg.FromAccountID, -arg.Amount, arg.ToAccountID, arg.Amount)
-		} else {
-			result.ToAccount, result.FromAccount, txError = addMoney(ctx, q, arg.ToAccountID, arg.Amount, arg.FromAccountID, -arg.Amount)
+	// update accounts' balance, the fee is charged to the sender on top of the amount
+	debit := transfer.Amount + transfer.Fee
+	if transfer.FromAccountID < transfer.ToAccountID {
+		result.FromAccount, result.ToAccount, txError = addMoney(ctx, q, transfer.FromAccountID, -debit, transfer.ToAccountID, transfer.Amount)
+	} else {
+		result.ToAccount, result.FromAccount, txError = addMoney(ctx, q, transfer.ToAccountID, transfer.Amount, transfer.FromAccountID, -debit)
+	}
+	if txError != nil {
+		return result, txError
+	}
+
+	updates := []AccountUpdate{
+		{Entry: result.FromEntry, Account: result.FromAccount},
+		{Entry: result.ToEntry, Account: result.ToAccount},
+	}
+	if transfer.Fee > 0 {
+		updates = append(updates, AccountUpdate{Entry: result.FeeEntry, Account: result.FromAccount})
+	}
+	for _, update := range updates {
+		if txError = notifyAccountUpdate(ctx, q, update.Entry, update.Account); txError != nil {
+			return result, txError
 		}
+	}
 
-		if txError != nil {
-			return txError
+	return result, recordTransferEvent(ctx, q, EventTransferCompleted, transfer)
+}
+
+// lockAccounts locks the given accounts in id order, so concurrent transactions cannot deadlock.
+// Accounts must be locked before anything else a transfer writes to
+func lockAccounts(ctx context.Context, q *Queries, accountIDs ...int64) error {
+	_, err := lockAccountRows(ctx, q, accountIDs)
+	return err
+}
+
+// lockOpenAccounts locks the given accounts like lockAccounts and returns ErrAccountClosed if one of them is closed.
+// The check runs on the locked rows, so an account cannot be closed while money is moved from or to it.
+// Accounts already locked by the transaction are only checked
+func lockOpenAccounts(ctx context.Context, q *Queries, accountIDs ...int64) error {
+	accounts, err := lockAccountRows(ctx, q, accountIDs)
+	if err != nil {
+		return err
+	}
+
+	for _, account := range accounts {
+		if account.ClosedAt.Valid {
+			return fmt.Errorf("%w: [%d]", ErrAccountClosed, account.ID)
 		}
+	}
 
-		return nil
-	})
+	return nil
+}
 
-	return result, err
+func lockAccountRows(ctx context.Context, q *Queries, accountIDs []int64) ([]Account, error) {
+	ids := slices.Clone(accountIDs)
+	slices.Sort(ids)
+
+	accounts := make([]Account, 0, len(ids))
+	for _, id := range slices.Compact(ids) {
+		account, err := q.GetAccountForUpdate(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		accounts = append(accounts, account)
+	}
+
+	return accounts, nil
 }
 
 func addMoney(ctx context.Context, q *Queries, accountID1 int64, amount1 int64, accountID2 int64, amount2 int64) (account1 Account, account2 Account, err error) {
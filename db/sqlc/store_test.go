@@ -2,8 +2,11 @@ package db
 
 import (
 	"context"
+	"fmt"
 	"testing"
 
+	"github.com/jackc/pgerrcode"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/stretchr/testify/require"
 )
 
@@ -153,3 +156,41 @@ func TestTransferTxDeadlock(t *testing.T) {
 	require.Equal(t, fromAccountInitial.Balance, fromAccountUpdated.Balance)
 	require.Equal(t, toAccountInitial.Balance, toAccountUpdated.Balance)
 }
+
+func TestTransferTxClosedAccount(t *testing.T) {
+	store := NewStore(testPool)
+
+	account := CreateRandomAccount(t)
+	closedAccount := closeAccount(t, CreateRandomAccount(t))
+
+	_, err := store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: account.ID,
+		ToAccountID:   closedAccount.ID,
+		Amount:        10,
+	})
+	require.ErrorIs(t, err, ErrAccountClosed)
+
+	_, err = store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: closedAccount.ID,
+		ToAccountID:   account.ID,
+		Amount:        10,
+	})
+	require.ErrorIs(t, err, ErrAccountClosed)
+
+	// nothing was moved
+	updatedAccount, err := testQueries.GetAccount(context.Background(), account.ID)
+	require.NoError(t, err)
+	require.Equal(t, account.Balance, updatedAccount.Balance)
+}
+
+func TestIsTransferRejection(t *testing.T) {
+	require.True(t, isTransferRejection(ErrInsufficientFunds))
+	require.True(t, isTransferRejection(fmt.Errorf("leg 1: %w", ErrAccountClosed)))
+	require.True(t, isTransferRejection(ErrApprovalRequired))
+	require.True(t, isTransferRejection(&LimitExceededError{Limit: LimitMaxSingle}))
+
+	require.False(t, isTransferRejection(ErrRecordNotFound))
+	require.False(t, isTransferRejection(ErrNoRevenueAccount))
+	require.False(t, isTransferRejection(fmt.Errorf("%w: %w", ErrRetryable, &pgconn.PgError{Code: pgerrcode.SerializationFailure})))
+	require.False(t, isTransferRejection(context.DeadlineExceeded))
+}
@@ -7,26 +7,94 @@ package db
 
 import (
 	"context"
+
+	"github.com/jackc/pgx/v5/pgtype"
 )
 
+const createPendingTransfer = `-- name: CreatePendingTransfer :one
+INSERT INTO transfers (
+  from_account_id,
+  to_account_id,
+  amount,
+  initiator,
+  fee,
+  fee_account_id,
+  status,
+  expires_at
+) VALUES (
+  $1, $2, $3, $4, $5, $6, 'pending', $7
+) RETURNING id, from_account_id, to_account_id, amount, created_at, status, initiator, approver, expires_at, decided_at, fee, fee_account_id
+`
+
+type CreatePendingTransferParams struct {
+	FromAccountID int64              `json:"from_account_id"`
+	ToAccountID   int64              `json:"to_account_id"`
+	Amount        int64              `json:"amount"`
+	Initiator     string             `json:"initiator"`
+	Fee           int64              `json:"fee"`
+	FeeAccountID  pgtype.Int8        `json:"fee_account_id"`
+	ExpiresAt     pgtype.Timestamptz `json:"expires_at"`
+}
+
+func (q *Queries) CreatePendingTransfer(ctx context.Context, arg CreatePendingTransferParams) (Transfer, error) {
+	row := q.db.QueryRow(ctx, createPendingTransfer,
+		arg.FromAccountID,
+		arg.ToAccountID,
+		arg.Amount,
+		arg.Initiator,
+		arg.Fee,
+		arg.FeeAccountID,
+		arg.ExpiresAt,
+	)
+	var i Transfer
+	err := row.Scan(
+		&i.ID,
+		&i.FromAccountID,
+		&i.ToAccountID,
+		&i.Amount,
+		&i.CreatedAt,
+		&i.Status,
+		&i.Initiator,
+		&i.Approver,
+		&i.ExpiresAt,
+		&i.DecidedAt,
+		&i.Fee,
+		&i.FeeAccountID,
+	)
+	return i, err
+}
+
 const createTransfer = `-- name: CreateTransfer :one
 INSERT INTO transfers (
   from_account_id,
   to_account_id,
-  amount
+  amount,
+  initiator,
+  fee,
+  fee_account_id
 ) VALUES (
-  $1, $2, $3
-) RETURNING id, from_account_id, to_account_id, amount, created_at
+  $1, $2, $3, $4, $5, $6
+) RETURNING id, from_account_id, to_account_id, amount, created_at, status, initiator, approver, expires_at, decided_at, fee, fee_account_id
 `
 
 type CreateTransferParams struct {
-	FromAccountID int64 `json:"from_account_id"`
-	ToAccountID   int64 `json:"to_account_id"`
-	Amount        int64 `json:"amount"`
+	FromAccountID int64       `json:"from_account_id"`
+	ToAccountID   int64       `json:"to_account_id"`
+	Amount        int64       `json:"amount"`
+	Initiator     string      `json:"initiator"`
+	Fee           int64       `json:"fee"`
+	FeeAccountID  pgtype.Int8 `json:"fee_account_id"`
 }
 
 func (q *Queries) CreateTransfer(ctx context.Context, arg CreateTransferParams) (Transfer, error) {
-	row := q.db.QueryRow(ctx, createTransfer, arg.FromAccountID, arg.ToAccountID, arg.Amount)
+	row := q.db.QueryRow(ctx, createTransfer,
+		arg.FromAccountID,
+		arg.ToAccountID,
+		arg.Amount,
+		arg.Initiator,
+		arg.Fee,
+		arg.FeeAccountID,
+	)
 	var i Transfer
 	err := row.Scan(
 		&i.ID,
@@ -34,12 +102,19 @@ func (q *Queries) CreateTransfer(ctx context.Context, arg CreateTransferParams)
 		&i.ToAccountID,
 		&i.Amount,
 		&i.CreatedAt,
+		&i.Status,
+		&i.Initiator,
+		&i.Approver,
+		&i.ExpiresAt,
+		&i.DecidedAt,
+		&i.Fee,
+		&i.FeeAccountID,
 	)
 	return i, err
 }
 
 const getTransfer = `-- name: GetTransfer :one
-SELECT id, from_account_id, to_account_id, amount, created_at FROM transfers
+SELECT id, from_account_id, to_account_id, amount, created_at, status, initiator, approver, expires_at, decided_at, fee, fee_account_id FROM transfers
 WHERE id = $1 LIMIT 1
 `
 
@@ -52,12 +127,90 @@ func (q *Queries) GetTransfer(ctx context.Context, id int64) (Transfer, error) {
 		&i.ToAccountID,
 		&i.Amount,
 		&i.CreatedAt,
+		&i.Status,
+		&i.Initiator,
+		&i.Approver,
+		&i.ExpiresAt,
+		&i.DecidedAt,
+		&i.Fee,
+		&i.FeeAccountID,
 	)
 	return i, err
 }
 
+const getTransferForUpdate = `-- name: GetTransferForUpdate :one
+SELECT id, from_account_id, to_account_id, amount, created_at, status, initiator, approver, expires_at, decided_at, fee, fee_account_id FROM transfers
+WHERE id = $1 LIMIT 1
+FOR NO KEY UPDATE
+`
+
+func (q *Queries) GetTransferForUpdate(ctx context.Context, id int64) (Transfer, error) {
+	row := q.db.QueryRow(ctx, getTransferForUpdate, id)
+	var i Transfer
+	err := row.Scan(
+		&i.ID,
+		&i.FromAccountID,
+		&i.ToAccountID,
+		&i.Amount,
+		&i.CreatedAt,
+		&i.Status,
+		&i.Initiator,
+		&i.Approver,
+		&i.ExpiresAt,
+		&i.DecidedAt,
+		&i.Fee,
+		&i.FeeAccountID,
+	)
+	return i, err
+}
+
+const listExpiredPendingTransfers = `-- name: ListExpiredPendingTransfers :many
+SELECT id, from_account_id, to_account_id, amount, created_at, status, initiator, approver, expires_at, decided_at, fee, fee_account_id FROM transfers
+WHERE status = 'pending' AND expires_at <= $1
+ORDER BY expires_at
+LIMIT $2
+`
+
+type ListExpiredPendingTransfersParams struct {
+	ExpiresAt pgtype.Timestamptz `json:"expires_at"`
+	Limit     int32              `json:"limit"`
+}
+
+func (q *Queries) ListExpiredPendingTransfers(ctx context.Context, arg ListExpiredPendingTransfersParams) ([]Transfer, error) {
+	rows, err := q.db.Query(ctx, listExpiredPendingTransfers, arg.ExpiresAt, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Transfer{}
+	for rows.Next() {
+		var i Transfer
+		if err := rows.Scan(
+			&i.ID,
+			&i.FromAccountID,
+			&i.ToAccountID,
+			&i.Amount,
+			&i.CreatedAt,
+			&i.Status,
+			&i.Initiator,
+			&i.Approver,
+			&i.ExpiresAt,
+			&i.DecidedAt,
+			&i.Fee,
+			&i.FeeAccountID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const listTransfers = `-- name: ListTransfers :many
-SELECT id, from_account_id, to_account_id, amount, created_at FROM transfers
+SELECT id, from_account_id, to_account_id, amount, created_at, status, initiator, approver, expires_at, decided_at, fee, fee_account_id FROM transfers
 WHERE
   from_account_id = $1 OR
   to_account_id = $2
@@ -93,6 +246,13 @@ func (q *Queries) ListTransfers(ctx context.Context, arg ListTransfersParams) ([
 			&i.ToAccountID,
 			&i.Amount,
 			&i.CreatedAt,
+			&i.Status,
+			&i.Initiator,
+			&i.Approver,
+			&i.ExpiresAt,
+			&i.DecidedAt,
+			&i.Fee,
+			&i.FeeAccountID,
 		); err != nil {
 			return nil, err
 		}
@@ -103,3 +263,39 @@ func (q *Queries) ListTransfers(ctx context.Context, arg ListTransfersParams) ([
 	}
 	return items, nil
 }
+
+const updateTransferStatus = `-- name: UpdateTransferStatus :one
+UPDATE transfers
+SET
+  status = $2,
+  approver = $3,
+  decided_at = now()
+WHERE id = $1
+RETURNING id, from_account_id, to_account_id, amount, created_at, status, initiator, approver, expires_at, decided_at, fee, fee_account_id
+`
+
+type UpdateTransferStatusParams struct {
+	ID       int64  `json:"id"`
+	Status   string `json:"status"`
+	Approver string `json:"approver"`
+}
+
+func (q *Queries) UpdateTransferStatus(ctx context.Context, arg UpdateTransferStatusParams) (Transfer, error) {
+	row := q.db.QueryRow(ctx, updateTransferStatus, arg.ID, arg.Status, arg.Approver)
+	var i Transfer
+	err := row.Scan(
+		&i.ID,
+		&i.FromAccountID,
+		&i.ToAccountID,
+		&i.Amount,
+		&i.CreatedAt,
+		&i.Status,
+		&i.Initiator,
+		&i.Approver,
+		&i.ExpiresAt,
+		&i.DecidedAt,
+		&i.Fee,
+		&i.FeeAccountID,
+	)
+	return i, err
+}
@@ -0,0 +1,273 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgerrcode"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBatchTransferTxAtomic(t *testing.T) {
+	store := NewStore(testPool)
+
+	fundingAccount := CreateRandomAccount(t)
+	account1 := CreateRandomAccount(t)
+	account2 := CreateRandomAccount(t)
+
+	result, err := store.BatchTransferTx(context.Background(), BatchTransferTxParams{
+		Mode: BatchModeAtomic,
+		Legs: []BatchTransferLeg{
+			{TransferTxParams: TransferTxParams{FromAccountID: fundingAccount.ID, ToAccountID: account1.ID, Amount: 10}},
+			{TransferTxParams: TransferTxParams{FromAccountID: fundingAccount.ID, ToAccountID: account2.ID, Amount: 20}},
+		},
+		Creator: fundingAccount.Owner,
+	})
+	require.NoError(t, err)
+	require.NotZero(t, result.Batch.ID)
+	require.Equal(t, BatchModeAtomic, result.Batch.Mode)
+	require.Equal(t, fundingAccount.Owner, result.Batch.Creator)
+	require.Equal(t, BatchCompleted, result.Batch.Status)
+	require.Len(t, result.Items, 2)
+
+	for i, item := range result.Items {
+		require.Equal(t, result.Batch.ID, item.BatchID)
+		require.Equal(t, int32(i), item.ItemIndex)
+		require.Equal(t, BatchItemSucceeded, item.Status)
+		require.True(t, item.TransferID.Valid)
+		require.Empty(t, item.Error)
+	}
+
+	updatedFundingAccount, err := testQueries.GetAccount(context.Background(), fundingAccount.ID)
+	require.NoError(t, err)
+	require.Equal(t, fundingAccount.Balance-30, updatedFundingAccount.Balance)
+
+	batch, err := testQueries.GetTransferBatch(context.Background(), result.Batch.ID)
+	require.NoError(t, err)
+	require.Equal(t, result.Batch, batch)
+
+	items, err := testQueries.ListTransferBatchItems(context.Background(), result.Batch.ID)
+	require.NoError(t, err)
+	require.Equal(t, result.Items, items)
+}
+
+func TestBatchTransferTxAtomicRollback(t *testing.T) {
+	store := NewStore(testPool)
+
+	fundingAccount := createLimitedAccount(t, UpsertTransferLimitParams{
+		DailyAccountAmount: pgtype.Int8{Int64: 25, Valid: true},
+	})
+	account1 := CreateRandomAccount(t)
+	account2 := CreateRandomAccount(t)
+
+	result, err := store.BatchTransferTx(context.Background(), BatchTransferTxParams{
+		Mode: BatchModeAtomic,
+		Legs: []BatchTransferLeg{
+			{TransferTxParams: TransferTxParams{FromAccountID: fundingAccount.ID, ToAccountID: account1.ID, Amount: 10}},
+			{TransferTxParams: TransferTxParams{FromAccountID: fundingAccount.ID, ToAccountID: account2.ID, Amount: 20}},
+		},
+	})
+	require.NoError(t, err)
+	require.Equal(t, BatchFailed, result.Batch.Status)
+	require.Len(t, result.Items, 2)
+	require.Equal(t, BatchItemRolledBack, result.Items[0].Status)
+	require.False(t, result.Items[0].TransferID.Valid)
+	require.Equal(t, BatchItemFailed, result.Items[1].Status)
+	require.Contains(t, result.Items[1].Error, LimitDailyAccount)
+
+	// none of the legs was executed
+	updatedFundingAccount, err := testQueries.GetAccount(context.Background(), fundingAccount.ID)
+	require.NoError(t, err)
+	require.Equal(t, fundingAccount.Balance, updatedFundingAccount.Balance)
+}
+
+func TestBatchTransferTxBestEffort(t *testing.T) {
+	store := NewStore(testPool)
+
+	fundingAccount := createLimitedAccount(t, UpsertTransferLimitParams{
+		MaxSingleAmount: pgtype.Int8{Int64: 15, Valid: true},
+	})
+	account1 := CreateRandomAccount(t)
+	account2 := CreateRandomAccount(t)
+
+	result, err := store.BatchTransferTx(context.Background(), BatchTransferTxParams{
+		Mode: BatchModeBestEffort,
+		Legs: []BatchTransferLeg{
+			{TransferTxParams: TransferTxParams{FromAccountID: fundingAccount.ID, ToAccountID: account1.ID, Amount: 10}},
+			{TransferTxParams: TransferTxParams{FromAccountID: fundingAccount.ID, ToAccountID: account2.ID, Amount: 20}},
+			{
+				TransferTxParams: TransferTxParams{FromAccountID: fundingAccount.ID, ToAccountID: account2.ID, Amount: 5},
+				RejectReason:     "currency mismatch",
+			},
+		},
+	})
+	require.NoError(t, err)
+	require.Equal(t, BatchPartiallyCompleted, result.Batch.Status)
+	require.Len(t, result.Items, 3)
+
+	require.Equal(t, BatchItemSucceeded, result.Items[0].Status)
+	require.True(t, result.Items[0].TransferID.Valid)
+	require.Equal(t, BatchItemFailed, result.Items[1].Status)
+	require.Contains(t, result.Items[1].Error, LimitMaxSingle)
+	require.Equal(t, BatchItemFailed, result.Items[2].Status)
+	require.Equal(t, "currency mismatch", result.Items[2].Error)
+
+	updatedFundingAccount, err := testQueries.GetAccount(context.Background(), fundingAccount.ID)
+	require.NoError(t, err)
+	require.Equal(t, fundingAccount.Balance-10, updatedFundingAccount.Balance)
+}
+
+func TestBatchTransferTxBestEffortClosedAccount(t *testing.T) {
+	store := NewStore(testPool)
+
+	fundingAccount := CreateRandomAccount(t)
+	account1 := CreateRandomAccount(t)
+	closedAccount := closeAccount(t, CreateRandomAccount(t))
+
+	result, err := store.BatchTransferTx(context.Background(), BatchTransferTxParams{
+		Mode: BatchModeBestEffort,
+		Legs: []BatchTransferLeg{
+			{TransferTxParams: TransferTxParams{FromAccountID: fundingAccount.ID, ToAccountID: account1.ID, Amount: 10}},
+			{TransferTxParams: TransferTxParams{FromAccountID: fundingAccount.ID, ToAccountID: closedAccount.ID, Amount: 20}},
+		},
+	})
+	require.NoError(t, err)
+	require.Equal(t, BatchPartiallyCompleted, result.Batch.Status)
+	require.Len(t, result.Items, 2)
+	require.Equal(t, BatchItemSucceeded, result.Items[0].Status)
+	require.Equal(t, BatchItemFailed, result.Items[1].Status)
+	require.Contains(t, result.Items[1].Error, ErrAccountClosed.Error())
+}
+
+func TestBatchTransferTxBestEffortAborted(t *testing.T) {
+	store := NewStore(testPool)
+
+	fundingAccount := CreateRandomAccount(t)
+	account1 := CreateRandomAccount(t)
+	missingAccountID := account1.ID + 1_000_000
+
+	// a leg failing for another reason than its accounts or amount ends the batch
+	result, err := store.BatchTransferTx(context.Background(), BatchTransferTxParams{
+		Mode: BatchModeBestEffort,
+		Legs: []BatchTransferLeg{
+			{TransferTxParams: TransferTxParams{FromAccountID: fundingAccount.ID, ToAccountID: account1.ID, Amount: 10}},
+			{TransferTxParams: TransferTxParams{FromAccountID: fundingAccount.ID, ToAccountID: missingAccountID, Amount: 20}},
+			{TransferTxParams: TransferTxParams{FromAccountID: fundingAccount.ID, ToAccountID: account1.ID, Amount: 30}},
+		},
+		Creator: fundingAccount.Owner,
+	})
+	require.ErrorIs(t, err, ErrRecordNotFound)
+	require.Equal(t, BatchPartiallyCompleted, result.Batch.Status)
+	require.Len(t, result.Items, 1)
+
+	items, err := testQueries.ListTransferBatchItems(context.Background(), result.Batch.ID)
+	require.NoError(t, err)
+	require.Equal(t, result.Items, items)
+
+	updatedFundingAccount, err := testQueries.GetAccount(context.Background(), fundingAccount.ID)
+	require.NoError(t, err)
+	require.Equal(t, fundingAccount.Balance-10, updatedFundingAccount.Balance)
+}
+
+func TestBatchTransferTxBestEffortRetry(t *testing.T) {
+	store := NewStore(testPool)
+	ctx := context.Background()
+
+	account1 := CreateRandomAccount(t)
+	account2 := CreateRandomAccount(t)
+	retries := txMetric("retries_" + pgerrcode.DeadlockDetected)
+
+	// lock the second account of the leg, so the leg locks the first one and waits for it
+	tx, err := testPool.Begin(ctx)
+	require.NoError(t, err)
+	defer tx.Rollback(ctx)
+	_, err = tx.Exec(ctx, "SET LOCAL deadlock_timeout = '10s'")
+	require.NoError(t, err)
+	_, err = New(tx).GetAccountForUpdate(ctx, account2.ID)
+	require.NoError(t, err)
+
+	results := make(chan BatchTransferTxResult)
+	errs := make(chan error)
+	go func() {
+		result, err := store.BatchTransferTx(ctx, BatchTransferTxParams{
+			Mode: BatchModeBestEffort,
+			Legs: []BatchTransferLeg{
+				{TransferTxParams: TransferTxParams{FromAccountID: account1.ID, ToAccountID: account2.ID, Amount: 10}},
+			},
+		})
+		results <- result
+		errs <- err
+	}()
+
+	require.Eventually(t, func() bool {
+		var waiting int
+		err := testPool.QueryRow(ctx, `SELECT count(*) FROM pg_stat_activity
+			WHERE wait_event_type = 'Lock' AND query LIKE '%FOR NO KEY UPDATE%'`).Scan(&waiting)
+		return err == nil && waiting > 0
+	}, 5*time.Second, 10*time.Millisecond)
+
+	// waiting for the first account deadlocks the leg, which detects it first and is run again
+	_, err = New(tx).GetAccountForUpdate(ctx, account1.ID)
+	require.NoError(t, err)
+	require.NoError(t, tx.Rollback(ctx))
+
+	result := <-results
+	require.NoError(t, <-errs)
+	require.Equal(t, BatchCompleted, result.Batch.Status)
+	require.Len(t, result.Items, 1)
+	require.Equal(t, BatchItemSucceeded, result.Items[0].Status)
+	require.Greater(t, txMetric("retries_"+pgerrcode.DeadlockDetected), retries)
+
+	updatedAccount1, err := testQueries.GetAccount(ctx, account1.ID)
+	require.NoError(t, err)
+	require.Equal(t, account1.Balance-10, updatedAccount1.Balance)
+}
+
+func TestBatchTransferTxAtomicDeadlock(t *testing.T) {
+	store := NewStore(testPool)
+
+	account1 := CreateRandomAccount(t)
+	account2 := CreateRandomAccount(t)
+	account3 := CreateRandomAccount(t)
+
+	n := 10
+	errs := make(chan error)
+
+	for i := 0; i < n; i++ {
+		legs := []BatchTransferLeg{
+			{TransferTxParams: TransferTxParams{FromAccountID: account1.ID, ToAccountID: account2.ID, Amount: 10}},
+			{TransferTxParams: TransferTxParams{FromAccountID: account3.ID, ToAccountID: account1.ID, Amount: 10}},
+		}
+		if i%2 == 1 {
+			legs = []BatchTransferLeg{
+				{TransferTxParams: TransferTxParams{FromAccountID: account2.ID, ToAccountID: account1.ID, Amount: 10}},
+				{TransferTxParams: TransferTxParams{FromAccountID: account1.ID, ToAccountID: account3.ID, Amount: 10}},
+			}
+		}
+
+		go func() {
+			result, err := store.BatchTransferTx(context.Background(), BatchTransferTxParams{
+				Mode: BatchModeAtomic,
+				Legs: legs,
+			})
+			if err == nil && result.Batch.Status != BatchCompleted {
+				err = fmt.Errorf("batch %d failed: %v", result.Batch.ID, result.Items)
+			}
+			errs <- err
+		}()
+	}
+
+	for i := 0; i < n; i++ {
+		require.NoError(t, <-errs)
+	}
+
+	// every account received as much as it sent
+	for _, account := range []Account{account1, account2, account3} {
+		updatedAccount, err := testQueries.GetAccount(context.Background(), account.ID)
+		require.NoError(t, err)
+		require.Equal(t, account.Balance, updatedAccount.Balance)
+	}
+}
@@ -0,0 +1,280 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// list of batch modes
+const (
+	BatchModeAtomic     = "atomic"
+	BatchModeBestEffort = "best_effort"
+)
+
+// list of batch statuses
+const (
+	BatchProcessing         = "processing"
+	BatchCompleted          = "completed"
+	BatchPartiallyCompleted = "partially_completed"
+	BatchFailed             = "failed"
+)
+
+// list of batch item statuses
+const (
+	BatchItemSucceeded  = "succeeded"
+	BatchItemFailed     = "failed"
+	BatchItemRolledBack = "rolled_back"
+)
+
+// BatchTransferLeg is a single transfer of a batch
+type BatchTransferLeg struct {
+	TransferTxParams
+	// RejectReason marks a leg that failed validation; it is recorded as failed without being executed
+	RejectReason string `json:"reject_reason"`
+}
+
+// BatchTransferTxParams contains the input parameters of the batch transfer transaction
+type BatchTransferTxParams struct {
+	Mode string             `json:"mode"`
+	Legs []BatchTransferLeg `json:"legs"`
+	// Creator is the user who submitted the batch
+	Creator string `json:"creator"`
+}
+
+// BatchTransferTxResult is the result of the batch transfer transaction
+type BatchTransferTxResult struct {
+	Batch TransferBatch       `json:"batch"`
+	Items []TransferBatchItem `json:"items"`
+}
+
+// BatchTransferTx performs a batch of money transfers.
+// In atomic mode all legs are executed within a single db transaction and either all of them succeed or none;
+// in best effort mode every leg is executed in its own db transaction.
+// A rejected leg is reported in the result, any other error of a leg is returned.
+// A best effort batch is then finished with the legs executed so far, so it does not stay processing
+func (s *SQLStore) BatchTransferTx(ctx context.Context, arg BatchTransferTxParams) (BatchTransferTxResult, error) {
+	switch arg.Mode {
+	case BatchModeAtomic:
+		return s.atomicBatchTransferTx(ctx, arg)
+	case BatchModeBestEffort:
+		return s.bestEffortBatchTransferTx(ctx, arg)
+	}
+
+	return BatchTransferTxResult{}, fmt.Errorf("unsupported batch mode %s", arg.Mode)
+}
+
+func (s *SQLStore) atomicBatchTransferTx(ctx context.Context, arg BatchTransferTxParams) (BatchTransferTxResult, error) {
+	var result BatchTransferTxResult
+	var failedIndex int
+	var legErr error
+
+	for i, leg := range arg.Legs {
+		if leg.RejectReason != "" {
+			failedIndex, legErr = i, errors.New(leg.RejectReason)
+			break
+		}
+	}
+
+	if legErr == nil {
+		err := s.ExecTxWithOptions(ctx, serializableTx, func(q *Queries) error {
+			legErr = nil
+
+			var err error
+			result.Batch, err = q.CreateTransferBatch(ctx, CreateTransferBatchParams{
+				Mode:    arg.Mode,
+				Status:  BatchCompleted,
+				Creator: arg.Creator,
+			})
+			if err != nil {
+				return err
+			}
+
+			// lock all accounts of the batch upfront, so concurrent batches cannot deadlock
+			accountIDs := make([]int64, 0, 2*len(arg.Legs))
+			for _, leg := range arg.Legs {
+				accountIDs = append(accountIDs, leg.FromAccountID, leg.ToAccountID)
+			}
+			err = lockAccounts(ctx, q, accountIDs...)
+			if err != nil {
+				return err
+			}
+
+			result.Items = make([]TransferBatchItem, 0, len(arg.Legs))
+			for i, leg := range arg.Legs {
+				transferResult, err := transfer(ctx, q, leg.TransferTxParams)
+				if err != nil {
+					if isTransferRejection(err) {
+						failedIndex, legErr = i, err
+					}
+					return err
+				}
+
+				item, err := createBatchItem(ctx, q, result.Batch.ID, i, leg, transferResult.Transfer.ID, nil)
+				if err != nil {
+					return err
+				}
+				result.Items = append(result.Items, item)
+			}
+
+			return nil
+		})
+		if legErr == nil {
+			return result, err
+		}
+	}
+
+	// the transaction was rolled back, so only the failure is recorded
+	result = BatchTransferTxResult{}
+	err := s.execTx(ctx, func(q *Queries) error {
+		var err error
+		result.Batch, err = q.CreateTransferBatch(ctx, CreateTransferBatchParams{
+			Mode:    arg.Mode,
+			Status:  BatchFailed,
+			Creator: arg.Creator,
+		})
+		if err != nil {
+			return err
+		}
+
+		result.Items = make([]TransferBatchItem, 0, len(arg.Legs))
+		for i, leg := range arg.Legs {
+			var item TransferBatchItem
+			if i == failedIndex {
+				item, err = createBatchItem(ctx, q, result.Batch.ID, i, leg, 0, legErr)
+			} else {
+				item, err = q.CreateTransferBatchItem(ctx, CreateTransferBatchItemParams{
+					BatchID:       result.Batch.ID,
+					ItemIndex:     int32(i),
+					FromAccountID: leg.FromAccountID,
+					ToAccountID:   leg.ToAccountID,
+					Amount:        leg.Amount,
+					Status:        BatchItemRolledBack,
+				})
+			}
+			if err != nil {
+				return err
+			}
+			result.Items = append(result.Items, item)
+		}
+
+		return nil
+	})
+
+	return result, err
+}
+
+func (s *SQLStore) bestEffortBatchTransferTx(ctx context.Context, arg BatchTransferTxParams) (BatchTransferTxResult, error) {
+	var result BatchTransferTxResult
+
+	batch, err := s.CreateTransferBatch(ctx, CreateTransferBatchParams{
+		Mode:    arg.Mode,
+		Status:  BatchProcessing,
+		Creator: arg.Creator,
+	})
+	if err != nil {
+		return result, err
+	}
+
+	result.Items = make([]TransferBatchItem, 0, len(arg.Legs))
+	succeeded := 0
+	for i, leg := range arg.Legs {
+		var item TransferBatchItem
+		var legErr error
+
+		if leg.RejectReason != "" {
+			legErr = errors.New(leg.RejectReason)
+		} else {
+			err = s.ExecTxWithOptions(ctx, serializableTx, func(q *Queries) error {
+				// a retried attempt must not see the outcome of the one that failed
+				item, legErr = TransferBatchItem{}, nil
+
+				transferResult, err := transfer(ctx, q, leg.TransferTxParams)
+				if err != nil {
+					if isTransferRejection(err) {
+						legErr = err
+					}
+					return err
+				}
+
+				item, err = createBatchItem(ctx, q, batch.ID, i, leg, transferResult.Transfer.ID, nil)
+				return err
+			})
+			if err != nil && legErr == nil {
+				result.Batch, err = s.abortBatch(ctx, batch, succeeded, err)
+				return result, err
+			}
+		}
+
+		if legErr != nil {
+			item, err = createBatchItem(ctx, s.Queries, batch.ID, i, leg, 0, legErr)
+			if err != nil {
+				result.Batch, err = s.abortBatch(ctx, batch, succeeded, err)
+				return result, err
+			}
+		} else {
+			succeeded++
+		}
+		result.Items = append(result.Items, item)
+	}
+
+	status := BatchPartiallyCompleted
+	switch succeeded {
+	case len(arg.Legs):
+		status = BatchCompleted
+	case 0:
+		status = BatchFailed
+	}
+
+	result.Batch, err = s.UpdateTransferBatchStatus(ctx, UpdateTransferBatchStatusParams{
+		ID:     batch.ID,
+		Status: status,
+	})
+
+	return result, err
+}
+
+// abortBatch finishes a best effort batch whose remaining legs cannot be executed because of err.
+// It is partially completed if some of its legs already succeeded and failed otherwise;
+// the status is written even if ctx was cancelled, so the batch does not stay processing
+func (s *SQLStore) abortBatch(ctx context.Context, batch TransferBatch, succeeded int, err error) (TransferBatch, error) {
+	status := BatchFailed
+	if succeeded > 0 {
+		status = BatchPartiallyCompleted
+	}
+
+	if ctx.Err() != nil {
+		ctx = context.WithoutCancel(ctx)
+	}
+
+	updated, updateErr := s.UpdateTransferBatchStatus(ctx, UpdateTransferBatchStatusParams{
+		ID:     batch.ID,
+		Status: status,
+	})
+	if updateErr != nil {
+		return batch, fmt.Errorf("leg err: %w, update batch err: %w", err, updateErr)
+	}
+
+	return updated, err
+}
+
+func createBatchItem(ctx context.Context, q *Queries, batchID int64, index int, leg BatchTransferLeg, transferID int64, legErr error) (TransferBatchItem, error) {
+	arg := CreateTransferBatchItemParams{
+		BatchID:       batchID,
+		ItemIndex:     int32(index),
+		FromAccountID: leg.FromAccountID,
+		ToAccountID:   leg.ToAccountID,
+		Amount:        leg.Amount,
+		Status:        BatchItemSucceeded,
+		TransferID:    pgtype.Int8{Int64: transferID, Valid: true},
+	}
+	if legErr != nil {
+		arg.Status = BatchItemFailed
+		arg.TransferID = pgtype.Int8{}
+		arg.Error = legErr.Error()
+	}
+
+	return q.CreateTransferBatchItem(ctx, arg)
+}
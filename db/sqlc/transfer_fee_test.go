@@ -0,0 +1,231 @@
+package db
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/roman-adamchik/simplebank/util"
+	"github.com/stretchr/testify/require"
+)
+
+// createChargedAccount creates an account whose owner is on a random tier with the given fee schedule
+// and a new revenue account for its currency
+func createChargedAccount(t *testing.T, arg UpsertFeeScheduleParams) (Account, Account) {
+	t.Helper()
+
+	account := CreateRandomAccount(t)
+
+	tier := util.RandomString(12)
+	_, err := testQueries.UpdateUserTier(context.Background(), UpdateUserTierParams{
+		Username: account.Owner,
+		Tier:     tier,
+	})
+	require.NoError(t, err)
+
+	arg.Tier = tier
+	arg.Currency = account.Currency
+	_, err = testQueries.UpsertFeeSchedule(context.Background(), arg)
+	require.NoError(t, err)
+
+	revenueAccount, err := testQueries.CreateAccount(context.Background(), CreateAccountParams{
+		Owner:    CreateRandomUser(t).Username,
+		Currency: account.Currency,
+	})
+	require.NoError(t, err)
+	_, err = testQueries.SetRevenueAccount(context.Background(), SetRevenueAccountParams{
+		Currency:  account.Currency,
+		AccountID: revenueAccount.ID,
+	})
+	require.NoError(t, err)
+
+	return account, revenueAccount
+}
+
+func TestFeeScheduleFee(t *testing.T) {
+	testCases := []struct {
+		name     string
+		schedule FeeSchedule
+		amount   int64
+		fee      int64
+	}{
+		{
+			name:     "NoSchedule",
+			schedule: FeeSchedule{},
+			amount:   1000,
+			fee:      0,
+		},
+		{
+			name:     "Flat",
+			schedule: FeeSchedule{FlatAmount: 3},
+			amount:   1000,
+			fee:      3,
+		},
+		{
+			name:     "Percentage",
+			schedule: FeeSchedule{PercentageBps: 150},
+			amount:   1000,
+			fee:      15,
+		},
+		{
+			name:     "PercentageRoundedHalfUp",
+			schedule: FeeSchedule{PercentageBps: 150},
+			amount:   100,
+			fee:      2,
+		},
+		{
+			name:     "FlatAndPercentage",
+			schedule: FeeSchedule{FlatAmount: 1, PercentageBps: 100},
+			amount:   1000,
+			fee:      11,
+		},
+		{
+			name:     "Min",
+			schedule: FeeSchedule{PercentageBps: 100, MinAmount: pgtype.Int8{Int64: 5, Valid: true}},
+			amount:   100,
+			fee:      5,
+		},
+		{
+			name:     "Max",
+			schedule: FeeSchedule{PercentageBps: 100, MaxAmount: pgtype.Int8{Int64: 5, Valid: true}},
+			amount:   1000,
+			fee:      5,
+		},
+		{
+			name:     "PercentageOfMaxAmount",
+			schedule: FeeSchedule{PercentageBps: 150},
+			amount:   math.MaxInt64,
+			fee:      138350580552821637,
+		},
+		{
+			name:     "FullPercentageOfMaxAmount",
+			schedule: FeeSchedule{PercentageBps: 10000},
+			amount:   math.MaxInt64,
+			fee:      math.MaxInt64,
+		},
+		{
+			name:     "CappedAtMaxInt64",
+			schedule: FeeSchedule{FlatAmount: 1, PercentageBps: 10000},
+			amount:   math.MaxInt64,
+			fee:      math.MaxInt64,
+		},
+		{
+			name:     "MaxOfMaxAmount",
+			schedule: FeeSchedule{PercentageBps: 10000, MaxAmount: pgtype.Int8{Int64: 5, Valid: true}},
+			amount:   math.MaxInt64,
+			fee:      5,
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.fee, tc.schedule.Fee(tc.amount))
+		})
+	}
+}
+
+func TestTransferTxFee(t *testing.T) {
+	store := NewStore(testPool)
+
+	fromAccount, revenueAccount := createChargedAccount(t, UpsertFeeScheduleParams{
+		FlatAmount:    2,
+		PercentageBps: 1000,
+	})
+	toAccount := CreateRandomAccount(t)
+
+	result, err := store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: fromAccount.ID,
+		ToAccountID:   toAccount.ID,
+		Amount:        50,
+	})
+	require.NoError(t, err)
+
+	transfer := result.Transfer
+	require.Equal(t, int64(50), transfer.Amount)
+	require.Equal(t, int64(7), transfer.Fee)
+	require.Equal(t, revenueAccount.ID, transfer.FeeAccountID.Int64)
+
+	require.Equal(t, int64(-50), result.FromEntry.Amount)
+	require.Equal(t, EntryTransfer, result.FromEntry.Kind)
+	require.Equal(t, int64(-7), result.FeeEntry.Amount)
+	require.Equal(t, EntryFee, result.FeeEntry.Kind)
+	require.Equal(t, transfer.ID, result.FeeEntry.TransferID.Int64)
+
+	require.Equal(t, fromAccount.Balance-57, result.FromAccount.Balance)
+	require.Equal(t, toAccount.Balance+50, result.ToAccount.Balance)
+
+	revenueAccount2, err := testQueries.GetAccount(context.Background(), revenueAccount.ID)
+	require.NoError(t, err)
+	require.Equal(t, revenueAccount.Balance+7, revenueAccount2.Balance)
+
+	entries, err := testQueries.ListEntries(context.Background(), ListEntriesParams{
+		AccountID: revenueAccount.ID,
+		Limit:     5,
+	})
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	require.Equal(t, EntryFee, entries[0].Kind)
+	require.Equal(t, int64(7), entries[0].Amount)
+}
+
+func TestTransferTxFeeInsufficientFunds(t *testing.T) {
+	store := NewStore(testPool)
+
+	fromAccount, _ := createChargedAccount(t, UpsertFeeScheduleParams{
+		FlatAmount: 1,
+	})
+	toAccount := CreateRandomAccount(t)
+
+	// the amount is covered but the fee is not
+	_, err := store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: fromAccount.ID,
+		ToAccountID:   toAccount.ID,
+		Amount:        fromAccount.Balance,
+	})
+	require.ErrorIs(t, err, ErrInsufficientFunds)
+}
+
+func TestTransferTxFeeOverflow(t *testing.T) {
+	store := NewStore(testPool)
+
+	fromAccount, _ := createChargedAccount(t, UpsertFeeScheduleParams{
+		PercentageBps: 100,
+	})
+	toAccount := CreateRandomAccount(t)
+
+	// the amount and its fee add up to more than an int64
+	_, err := store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: fromAccount.ID,
+		ToAccountID:   toAccount.ID,
+		Amount:        math.MaxInt64,
+	})
+	require.ErrorIs(t, err, ErrInsufficientFunds)
+}
+
+func TestTransferTxFeeClosedRevenueAccount(t *testing.T) {
+	store := NewStore(testPool)
+
+	fromAccount, revenueAccount := createChargedAccount(t, UpsertFeeScheduleParams{
+		FlatAmount: 1,
+	})
+	toAccount := CreateRandomAccount(t)
+
+	closed, err := testQueries.CloseZeroBalanceAccounts(context.Background(), CloseZeroBalanceAccountsParams{
+		ClosedAt: pgtype.Timestamptz{Time: time.Now(), Valid: true},
+		Owner:    revenueAccount.Owner,
+	})
+	require.NoError(t, err)
+	require.Len(t, closed, 1)
+
+	// a closed revenue account collects no fees, as if none was set
+	_, err = store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: fromAccount.ID,
+		ToAccountID:   toAccount.ID,
+		Amount:        1,
+	})
+	require.ErrorIs(t, err, ErrNoRevenueAccount)
+}
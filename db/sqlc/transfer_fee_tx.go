@@ -0,0 +1,114 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"math"
+	"math/big"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// list of entry kinds
+const (
+	EntryTransfer = "transfer"
+	EntryFee      = "fee"
+)
+
+// ErrNoRevenueAccount is returned when a fee is due but no open revenue account is set for the currency.
+// It is a configuration error and not a rejection of the transfer, so it is never recorded as a failed transfer
+var ErrNoRevenueAccount = errors.New("no revenue account for the currency")
+
+// Fee returns the fee charged on a transfer of the given amount.
+// The percentage is rounded half up before the minimum and maximum are applied.
+// It is computed without overflow, a fee that does not fit in an int64 is capped at math.MaxInt64
+func (schedule FeeSchedule) Fee(amount int64) int64 {
+	fee := new(big.Int).Mul(big.NewInt(amount), big.NewInt(int64(schedule.PercentageBps)))
+	fee.Add(fee, big.NewInt(5000)).Quo(fee, big.NewInt(10000))
+	fee.Add(fee, big.NewInt(schedule.FlatAmount))
+
+	if schedule.MinAmount.Valid && fee.Cmp(big.NewInt(schedule.MinAmount.Int64)) < 0 {
+		fee.SetInt64(schedule.MinAmount.Int64)
+	}
+	if schedule.MaxAmount.Valid && fee.Cmp(big.NewInt(schedule.MaxAmount.Int64)) > 0 {
+		fee.SetInt64(schedule.MaxAmount.Int64)
+	}
+
+	if !fee.IsInt64() {
+		return math.MaxInt64
+	}
+	return fee.Int64()
+}
+
+// transferFee computes the fee of a transfer from the policy's account and the revenue account it is posted to.
+// Transfers from the revenue account itself are free. An amount and fee adding up to more than
+// an account can hold return ErrInsufficientFunds, so callers can add them without overflow
+func transferFee(ctx context.Context, q *Queries, policy transferPolicy, amount int64) (int64, pgtype.Int8, error) {
+	fee := policy.fee.Fee(amount)
+	if fee <= 0 {
+		return 0, pgtype.Int8{}, nil
+	}
+
+	revenueAccount, err := q.GetRevenueAccount(ctx, policy.account.Currency)
+	if err != nil {
+		if errors.Is(err, ErrRecordNotFound) {
+			return 0, pgtype.Int8{}, ErrNoRevenueAccount
+		}
+		return 0, pgtype.Int8{}, err
+	}
+	if revenueAccount.AccountID == policy.account.ID {
+		return 0, pgtype.Int8{}, nil
+	}
+	// a closed revenue account collects no fees, as if none was set
+	account, err := q.GetAccount(ctx, revenueAccount.AccountID)
+	if err != nil {
+		return 0, pgtype.Int8{}, err
+	}
+	if account.ClosedAt.Valid {
+		return 0, pgtype.Int8{}, ErrNoRevenueAccount
+	}
+	if fee > math.MaxInt64-amount {
+		return 0, pgtype.Int8{}, ErrInsufficientFunds
+	}
+
+	return fee, pgtype.Int8{Int64: revenueAccount.AccountID, Valid: true}, nil
+}
+
+// postFee creates the fee entries of a transfer and credits the fee to the revenue account.
+// The revenue account is not locked upfront, so transfers without a fee don't contend on it;
+// it is locked by its balance update, once the accounts of the transfer are locked
+func postFee(ctx context.Context, q *Queries, transfer Transfer) (Entry, error) {
+	feeEntry, err := q.CreateTransferEntry(ctx, CreateTransferEntryParams{
+		AccountID:  transfer.FromAccountID,
+		Amount:     -transfer.Fee,
+		Kind:       EntryFee,
+		TransferID: pgtype.Int8{Int64: transfer.ID, Valid: true},
+	})
+	if err != nil {
+		return feeEntry, err
+	}
+
+	revenueEntry, err := q.CreateTransferEntry(ctx, CreateTransferEntryParams{
+		AccountID:  transfer.FeeAccountID.Int64,
+		Amount:     transfer.Fee,
+		Kind:       EntryFee,
+		TransferID: pgtype.Int8{Int64: transfer.ID, Valid: true},
+	})
+	if err != nil {
+		return feeEntry, err
+	}
+
+	revenueAccount, err := q.AddAccountBalanceParams(ctx, AddAccountBalanceParamsParams{
+		ID:     transfer.FeeAccountID.Int64,
+		Amount: transfer.Fee,
+	})
+	if err != nil {
+		return feeEntry, err
+	}
+	// the revenue account may have been closed since the fee was computed, which its locked row tells
+	if revenueAccount.ClosedAt.Valid {
+		return feeEntry, ErrNoRevenueAccount
+	}
+
+	return feeEntry, notifyAccountUpdate(ctx, q, revenueEntry, revenueAccount)
+}
@@ -0,0 +1,163 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/roman-adamchik/simplebank/util"
+	"github.com/stretchr/testify/require"
+)
+
+// createLimitedAccount creates an account whose owner is on a random tier,
+// so limits set by the test don't affect other tests
+func createLimitedAccount(t *testing.T, arg UpsertTransferLimitParams) Account {
+	t.Helper()
+
+	account := CreateRandomAccount(t)
+
+	tier := util.RandomString(12)
+	_, err := testQueries.UpdateUserTier(context.Background(), UpdateUserTierParams{
+		Username: account.Owner,
+		Tier:     tier,
+	})
+	require.NoError(t, err)
+
+	arg.Tier = tier
+	arg.Currency = account.Currency
+	_, err = testQueries.UpsertTransferLimit(context.Background(), arg)
+	require.NoError(t, err)
+
+	return account
+}
+
+func TestUpsertTransferLimit(t *testing.T) {
+	tier := util.RandomString(12)
+
+	arg := UpsertTransferLimitParams{
+		Tier:            tier,
+		Currency:        util.USD,
+		MaxSingleAmount: pgtype.Int8{Int64: 100, Valid: true},
+	}
+	limit1, err := testQueries.UpsertTransferLimit(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, tier, limit1.Tier)
+	require.Equal(t, util.USD, limit1.Currency)
+	require.Equal(t, arg.MaxSingleAmount, limit1.MaxSingleAmount)
+	require.False(t, limit1.DailyAccountAmount.Valid)
+
+	arg.MaxSingleAmount = pgtype.Int8{}
+	arg.DailyUserAmount = pgtype.Int8{Int64: 500, Valid: true}
+	limit2, err := testQueries.UpsertTransferLimit(context.Background(), arg)
+	require.NoError(t, err)
+	require.False(t, limit2.MaxSingleAmount.Valid)
+	require.Equal(t, arg.DailyUserAmount, limit2.DailyUserAmount)
+
+	limit3, err := testQueries.GetTransferLimit(context.Background(), GetTransferLimitParams{
+		Tier:     tier,
+		Currency: util.USD,
+	})
+	require.NoError(t, err)
+	require.Equal(t, limit2, limit3)
+
+	err = testQueries.DeleteTransferLimit(context.Background(), DeleteTransferLimitParams{
+		Tier:     tier,
+		Currency: util.USD,
+	})
+	require.NoError(t, err)
+
+	_, err = testQueries.GetTransferLimit(context.Background(), GetTransferLimitParams{
+		Tier:     tier,
+		Currency: util.USD,
+	})
+	require.ErrorIs(t, err, ErrRecordNotFound)
+}
+
+func TestAddTransferUsage(t *testing.T) {
+	arg := AddTransferUsageParams{
+		Subject:  "user:" + util.RandomOwner(),
+		Currency: util.EUR,
+		Period:   PeriodDay,
+		Amount:   10,
+	}
+
+	total, err := testQueries.AddTransferUsage(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, int64(10), total)
+
+	total, err = testQueries.AddTransferUsage(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, int64(20), total)
+}
+
+func TestTransferTxMaxSingleLimit(t *testing.T) {
+	store := NewStore(testPool)
+
+	account1 := createLimitedAccount(t, UpsertTransferLimitParams{
+		MaxSingleAmount: pgtype.Int8{Int64: 10, Valid: true},
+	})
+	account2 := CreateRandomAccount(t)
+
+	_, err := store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: account1.ID,
+		ToAccountID:   account2.ID,
+		Amount:        11,
+	})
+	var limitErr *LimitExceededError
+	require.ErrorAs(t, err, &limitErr)
+	require.Equal(t, LimitMaxSingle, limitErr.Limit)
+	require.Equal(t, account1.Currency, limitErr.Currency)
+	require.Equal(t, int64(10), limitErr.Max)
+	require.Zero(t, limitErr.Remaining)
+
+	_, err = store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: account1.ID,
+		ToAccountID:   account2.ID,
+		Amount:        10,
+	})
+	require.NoError(t, err)
+}
+
+func TestTransferTxDailyLimitConcurrent(t *testing.T) {
+	store := NewStore(testPool)
+
+	account1 := createLimitedAccount(t, UpsertTransferLimitParams{
+		DailyAccountAmount: pgtype.Int8{Int64: 50, Valid: true},
+	})
+	account2 := CreateRandomAccount(t)
+
+	n := 8
+	amount := int64(10)
+	errs := make(chan error)
+
+	for i := 0; i < n; i++ {
+		go func() {
+			_, err := store.TransferTx(context.Background(), TransferTxParams{
+				FromAccountID: account1.ID,
+				ToAccountID:   account2.ID,
+				Amount:        amount,
+			})
+			errs <- err
+		}()
+	}
+
+	succeeded := 0
+	for i := 0; i < n; i++ {
+		err := <-errs
+		if err == nil {
+			succeeded++
+			continue
+		}
+
+		var limitErr *LimitExceededError
+		require.True(t, errors.As(err, &limitErr), "unexpected error: %v", err)
+		require.Equal(t, LimitDailyAccount, limitErr.Limit)
+		require.Zero(t, limitErr.Remaining)
+	}
+	require.Equal(t, 5, succeeded)
+
+	updatedAccount1, err := testQueries.GetAccount(context.Background(), account1.ID)
+	require.NoError(t, err)
+	require.Equal(t, account1.Balance-5*amount, updatedAccount1.Balance)
+}
@@ -0,0 +1,184 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// list of transfer limits
+const (
+	LimitMaxSingle      = "max_single"
+	LimitDailyAccount   = "daily_account"
+	LimitMonthlyAccount = "monthly_account"
+	LimitDailyUser      = "daily_user"
+	LimitMonthlyUser    = "monthly_user"
+)
+
+// list of periods transfer usage is accounted for
+const (
+	PeriodDay   = "day"
+	PeriodMonth = "month"
+)
+
+// LimitExceededError is returned when a transfer would exceed one of the transfer limits
+type LimitExceededError struct {
+	Limit     string `json:"limit"`
+	Currency  string `json:"currency"`
+	Max       int64  `json:"max"`
+	Remaining int64  `json:"remaining"`
+}
+
+func (e *LimitExceededError) Error() string {
+	return fmt.Sprintf("transfer limit %s exceeded: max %d %s, remaining %d %s", e.Limit, e.Max, e.Currency, e.Remaining, e.Currency)
+}
+
+// transferPolicy holds the limits and fees that apply to transfers from an account
+type transferPolicy struct {
+	account Account
+	user    User
+	limit   TransferLimit
+	fee     FeeSchedule
+}
+
+// loadTransferPolicy loads the limits and fees of the tier of the account's owner.
+// Without configured limits or fees for the tier and currency, transfers are not limited or free
+func loadTransferPolicy(ctx context.Context, q *Queries, accountID int64) (transferPolicy, error) {
+	var policy transferPolicy
+	var err error
+
+	policy.account, err = q.GetAccount(ctx, accountID)
+	if err != nil {
+		return policy, err
+	}
+	policy.user, err = q.GetUser(ctx, policy.account.Owner)
+	if err != nil {
+		return policy, err
+	}
+
+	policy.limit, err = q.GetTransferLimit(ctx, GetTransferLimitParams{
+		Tier:     policy.user.Tier,
+		Currency: policy.account.Currency,
+	})
+	if err != nil && !errors.Is(err, ErrRecordNotFound) {
+		return policy, err
+	}
+
+	policy.fee, err = q.GetFeeSchedule(ctx, GetFeeScheduleParams{
+		Tier:     policy.user.Tier,
+		Currency: policy.account.Currency,
+	})
+	if err != nil && !errors.Is(err, ErrRecordNotFound) {
+		return policy, err
+	}
+
+	return policy, nil
+}
+
+func (policy transferPolicy) requiresApproval(amount int64) bool {
+	return policy.limit.ApprovalThreshold.Valid && amount > policy.limit.ApprovalThreshold.Int64
+}
+
+// checkTransferLimits accounts the outgoing amount against the usage of the account and its owner
+// and returns a *LimitExceededError if any limit of the owner's tier is exceeded.
+// The usage rows stay locked until the end of the db transaction, so concurrent transfers are accounted one by one
+func checkTransferLimits(ctx context.Context, q *Queries, policy transferPolicy, amount int64) error {
+	return applyTransferLimits(policy, amount, func(subject, period string) (int64, error) {
+		return q.AddTransferUsage(ctx, AddTransferUsageParams{
+			Subject:  subject,
+			Currency: policy.account.Currency,
+			Period:   period,
+			Amount:   amount,
+		})
+	})
+}
+
+// previewTransferLimits returns the error checkTransferLimits would return without accounting the amount,
+// so a transfer waiting for approval is refused when it is created if it exceeds the limits already
+func previewTransferLimits(ctx context.Context, q *Queries, policy transferPolicy, amount int64) error {
+	return applyTransferLimits(policy, amount, func(subject, period string) (int64, error) {
+		used, err := q.GetTransferUsage(ctx, GetTransferUsageParams{
+			Subject:  subject,
+			Currency: policy.account.Currency,
+			Period:   period,
+		})
+		return used + amount, err
+	})
+}
+
+// applyTransferLimits checks the amount against the limits of the policy,
+// use returns the total of a usage period including the amount
+func applyTransferLimits(policy transferPolicy, amount int64, use func(subject, period string) (int64, error)) error {
+	account, user, limit := policy.account, policy.user, policy.limit
+
+	if limit.MaxSingleAmount.Valid && amount > limit.MaxSingleAmount.Int64 {
+		return &LimitExceededError{
+			Limit:     LimitMaxSingle,
+			Currency:  account.Currency,
+			Max:       limit.MaxSingleAmount.Int64,
+			Remaining: 0,
+		}
+	}
+
+	// usage is accounted even without limits, so limits set later apply to the current period
+	usages := []struct {
+		name    string
+		subject string
+		period  string
+		max     pgtype.Int8
+	}{
+		{LimitDailyAccount, fmt.Sprintf("account:%d", account.ID), PeriodDay, limit.DailyAccountAmount},
+		{LimitMonthlyAccount, fmt.Sprintf("account:%d", account.ID), PeriodMonth, limit.MonthlyAccountAmount},
+		{LimitDailyUser, fmt.Sprintf("user:%s", user.Username), PeriodDay, limit.DailyUserAmount},
+		{LimitMonthlyUser, fmt.Sprintf("user:%s", user.Username), PeriodMonth, limit.MonthlyUserAmount},
+	}
+
+	for _, usage := range usages {
+		total, err := use(usage.subject, usage.period)
+		if err != nil {
+			return err
+		}
+
+		if usage.max.Valid && total > usage.max.Int64 {
+			return &LimitExceededError{
+				Limit:     usage.name,
+				Currency:  account.Currency,
+				Max:       usage.max.Int64,
+				Remaining: max(usage.max.Int64-(total-amount), 0),
+			}
+		}
+	}
+
+	return nil
+}
+
+// refundTransferUsage gives back an amount checkTransferLimits accounted at the given time
+// and that is not moved after all, e.g. the part of a hold that is not captured
+func refundTransferUsage(ctx context.Context, q *Queries, policy transferPolicy, amount int64, accountedAt pgtype.Timestamptz) error {
+	if amount <= 0 {
+		return nil
+	}
+
+	subjects := []string{
+		fmt.Sprintf("account:%d", policy.account.ID),
+		fmt.Sprintf("user:%s", policy.user.Username),
+	}
+	for _, subject := range subjects {
+		for _, period := range []string{PeriodDay, PeriodMonth} {
+			err := q.SubtractTransferUsage(ctx, SubtractTransferUsageParams{
+				Amount:      amount,
+				Subject:     subject,
+				Currency:    policy.account.Currency,
+				Period:      period,
+				AccountedAt: accountedAt,
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
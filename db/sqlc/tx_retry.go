@@ -0,0 +1,69 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"expvar"
+	"math/rand/v2"
+	"time"
+
+	"github.com/jackc/pgerrcode"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// TxMetrics counts the retries of db transactions, it is published as db_tx by expvar.
+// retries_<code> counts the retries per Postgres error code, exhausted counts the transactions
+// that still failed with a retryable error after the last attempt
+var TxMetrics = expvar.NewMap("db_tx")
+
+// txRetryPolicy configures how transactions failing with a retryable error are retried
+type txRetryPolicy struct {
+	maxAttempts int
+	baseDelay   time.Duration
+	maxDelay    time.Duration
+}
+
+var defaultTxRetryPolicy = txRetryPolicy{
+	maxAttempts: 5,
+	baseDelay:   10 * time.Millisecond,
+	maxDelay:    500 * time.Millisecond,
+}
+
+// backoff returns the delay before the given retry, with full jitter over an exponentially growing window
+func (policy txRetryPolicy) backoff(retry int) time.Duration {
+	window := policy.maxDelay
+	if retry < 32 && policy.baseDelay<<retry < window {
+		window = policy.baseDelay << retry
+	}
+
+	return rand.N(window + 1)
+}
+
+// retryableCode returns the error code of a serialization failure or a deadlock,
+// which can succeed when the transaction is run again
+func retryableCode(err error) (string, bool) {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		return "", false
+	}
+
+	switch pgErr.Code {
+	case pgerrcode.SerializationFailure, pgerrcode.DeadlockDetected:
+		return pgErr.Code, true
+	}
+
+	return "", false
+}
+
+// sleepCtx waits for the given duration and returns early with the context's error if it is cancelled
+func sleepCtx(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,157 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"expvar"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgerrcode"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRetryableCode(t *testing.T) {
+	code, ok := retryableCode(&pgconn.PgError{Code: pgerrcode.SerializationFailure})
+	require.True(t, ok)
+	require.Equal(t, pgerrcode.SerializationFailure, code)
+
+	code, ok = retryableCode(fmt.Errorf("tx err: %w, rollback err: %w", &pgconn.PgError{Code: pgerrcode.DeadlockDetected}, pgx.ErrTxClosed))
+	require.True(t, ok)
+	require.Equal(t, pgerrcode.DeadlockDetected, code)
+
+	_, ok = retryableCode(&pgconn.PgError{Code: pgerrcode.UniqueViolation})
+	require.False(t, ok)
+
+	_, ok = retryableCode(ErrInsufficientFunds)
+	require.False(t, ok)
+}
+
+func TestTxRetryPolicyBackoff(t *testing.T) {
+	policy := txRetryPolicy{maxAttempts: 5, baseDelay: 10 * time.Millisecond, maxDelay: 50 * time.Millisecond}
+
+	for retry := 1; retry < 40; retry++ {
+		window := min(policy.maxDelay, policy.baseDelay<<min(retry, 31))
+		delay := policy.backoff(retry)
+		require.GreaterOrEqual(t, delay, time.Duration(0))
+		require.LessOrEqual(t, delay, window)
+	}
+}
+
+func TestExecTxRetriesSerializationFailure(t *testing.T) {
+	store := NewStore(testPool).(*SQLStore)
+	account := CreateRandomAccount(t)
+	retries := txMetric("retries_" + pgerrcode.SerializationFailure)
+
+	// both transactions read the balance before either of them writes it,
+	// so one of them fails to serialize on its first attempt
+	var read sync.WaitGroup
+	read.Add(2)
+	var once [2]sync.Once
+
+	errs := make(chan error)
+	for i := 0; i < 2; i++ {
+		go func() {
+			errs <- store.ExecTxWithOptions(context.Background(), serializableTx, func(q *Queries) error {
+				current, err := q.GetAccount(context.Background(), account.ID)
+				if err != nil {
+					return err
+				}
+				once[i].Do(func() {
+					read.Done()
+					read.Wait()
+				})
+
+				_, err = q.UpdateAccount(context.Background(), UpdateAccountParams{
+					ID:      account.ID,
+					Balance: current.Balance + 1,
+				})
+				return err
+			})
+		}()
+	}
+
+	for i := 0; i < 2; i++ {
+		require.NoError(t, <-errs)
+	}
+
+	updated, err := testQueries.GetAccount(context.Background(), account.ID)
+	require.NoError(t, err)
+	require.Equal(t, account.Balance+2, updated.Balance)
+	require.Greater(t, txMetric("retries_"+pgerrcode.SerializationFailure), retries)
+}
+
+func TestExecTxRetryCancelled(t *testing.T) {
+	store := &SQLStore{
+		Queries: New(testPool),
+		pool:    testPool,
+		retry:   txRetryPolicy{maxAttempts: 5, baseDelay: time.Hour, maxDelay: time.Hour},
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	attempts := 0
+	deadlock := &pgconn.PgError{Code: pgerrcode.DeadlockDetected}
+	err := store.execTx(ctx, func(q *Queries) error {
+		attempts++
+		return deadlock
+	})
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	require.ErrorIs(t, err, deadlock)
+	require.LessOrEqual(t, attempts, 2)
+}
+
+func TestExecTxRetryExhausted(t *testing.T) {
+	store := &SQLStore{
+		Queries: New(testPool),
+		pool:    testPool,
+		retry:   txRetryPolicy{maxAttempts: 3, baseDelay: time.Millisecond, maxDelay: time.Millisecond},
+	}
+	exhausted := txMetric("exhausted")
+
+	attempts := 0
+	err := store.execTx(context.Background(), func(q *Queries) error {
+		attempts++
+		return &pgconn.PgError{Code: pgerrcode.SerializationFailure}
+	})
+	var pgErr *pgconn.PgError
+	require.True(t, errors.As(err, &pgErr))
+	require.Equal(t, 3, attempts)
+	require.Equal(t, exhausted+1, txMetric("exhausted"))
+}
+
+func TestExecTxReadOnly(t *testing.T) {
+	store := NewStore(testPool)
+	account := CreateRandomAccount(t)
+
+	err := store.ExecTxWithOptions(context.Background(), ReadOnlyTx, func(q *Queries) error {
+		current, err := q.GetAccount(context.Background(), account.ID)
+		require.NoError(t, err)
+		require.Equal(t, account.Balance, current.Balance)
+
+		_, err = q.UpdateAccount(context.Background(), UpdateAccountParams{
+			ID:      account.ID,
+			Balance: current.Balance + 1,
+		})
+		return err
+	})
+	var pgErr *pgconn.PgError
+	require.True(t, errors.As(err, &pgErr))
+	require.Equal(t, pgerrcode.ReadOnlySQLTransaction, pgErr.Code)
+
+	unchanged, err := testQueries.GetAccount(context.Background(), account.ID)
+	require.NoError(t, err)
+	require.Equal(t, account.Balance, unchanged.Balance)
+}
+
+func txMetric(key string) int64 {
+	if v, ok := TxMetrics.Get(key).(*expvar.Int); ok {
+		return v.Value()
+	}
+	return 0
+}
@@ -7,6 +7,8 @@ package db
 
 import (
 	"context"
+
+	"github.com/jackc/pgx/v5/pgtype"
 )
 
 const createUser = `-- name: CreateUser :one
@@ -17,7 +19,7 @@ INSERT INTO users (
   email
 ) VALUES (
   $1, $2, $3, $4
-) RETURNING username, hashed_password, full_name, email, password_changed_at, created_at
+) RETURNING username, hashed_password, full_name, email, password_changed_at, created_at, role, tier, is_email_verified, is_mfa_enabled, deactivated_at
 `
 
 type CreateUserParams struct {
@@ -42,12 +44,75 @@ func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, e
 		&i.Email,
 		&i.PasswordChangedAt,
 		&i.CreatedAt,
+		&i.Role,
+		&i.Tier,
+		&i.IsEmailVerified,
+		&i.IsMfaEnabled,
+		&i.DeactivatedAt,
+	)
+	return i, err
+}
+
+const deactivateUser = `-- name: DeactivateUser :one
+UPDATE users
+SET deactivated_at = $1
+WHERE username = $2
+  AND deactivated_at IS NULL
+RETURNING username, hashed_password, full_name, email, password_changed_at, created_at, role, tier, is_email_verified, is_mfa_enabled, deactivated_at
+`
+
+type DeactivateUserParams struct {
+	DeactivatedAt pgtype.Timestamptz `json:"deactivated_at"`
+	Username      string             `json:"username"`
+}
+
+func (q *Queries) DeactivateUser(ctx context.Context, arg DeactivateUserParams) (User, error) {
+	row := q.db.QueryRow(ctx, deactivateUser, arg.DeactivatedAt, arg.Username)
+	var i User
+	err := row.Scan(
+		&i.Username,
+		&i.HashedPassword,
+		&i.FullName,
+		&i.Email,
+		&i.PasswordChangedAt,
+		&i.CreatedAt,
+		&i.Role,
+		&i.Tier,
+		&i.IsEmailVerified,
+		&i.IsMfaEnabled,
+		&i.DeactivatedAt,
+	)
+	return i, err
+}
+
+const enableUserMFA = `-- name: EnableUserMFA :one
+UPDATE users
+SET is_mfa_enabled = TRUE
+WHERE username = $1
+RETURNING username, hashed_password, full_name, email, password_changed_at, created_at, role, tier, is_email_verified, is_mfa_enabled, deactivated_at
+`
+
+func (q *Queries) EnableUserMFA(ctx context.Context, username string) (User, error) {
+	row := q.db.QueryRow(ctx, enableUserMFA, username)
+	var i User
+	err := row.Scan(
+		&i.Username,
+		&i.HashedPassword,
+		&i.FullName,
+		&i.Email,
+		&i.PasswordChangedAt,
+		&i.CreatedAt,
+		&i.Role,
+		&i.Tier,
+		&i.IsEmailVerified,
+		&i.IsMfaEnabled,
+		&i.DeactivatedAt,
 	)
 	return i, err
 }
 
 const getUser = `-- name: GetUser :one
-SELECT username, hashed_password, full_name, email, password_changed_at, created_at FROM users
+SELECT username, hashed_password, full_name, email, password_changed_at, created_at, role, tier, is_email_verified, is_mfa_enabled, deactivated_at FROM users
 WHERE username = $1 LIMIT 1
 `
 
@@ -61,6 +126,191 @@ func (q *Queries) GetUser(ctx context.Context, username string) (User, error) {
 		&i.Email,
 		&i.PasswordChangedAt,
 		&i.CreatedAt,
+		&i.Role,
+		&i.Tier,
+		&i.IsEmailVerified,
+		&i.IsMfaEnabled,
+		&i.DeactivatedAt,
+	)
+	return i, err
+}
+
+const getUserByEmail = `-- name: GetUserByEmail :one
+SELECT username, hashed_password, full_name, email, password_changed_at, created_at, role, tier, is_email_verified, is_mfa_enabled, deactivated_at FROM users
+WHERE email = $1 LIMIT 1
+`
+
+func (q *Queries) GetUserByEmail(ctx context.Context, email string) (User, error) {
+	row := q.db.QueryRow(ctx, getUserByEmail, email)
+	var i User
+	err := row.Scan(
+		&i.Username,
+		&i.HashedPassword,
+		&i.FullName,
+		&i.Email,
+		&i.PasswordChangedAt,
+		&i.CreatedAt,
+		&i.Role,
+		&i.Tier,
+		&i.IsEmailVerified,
+		&i.IsMfaEnabled,
+		&i.DeactivatedAt,
+	)
+	return i, err
+}
+
+const rehashUserPassword = `-- name: RehashUserPassword :exec
+UPDATE users
+SET hashed_password = $1
+WHERE username = $2
+  AND hashed_password = $3
+`
+
+type RehashUserPasswordParams struct {
+	NewHashedPassword string `json:"new_hashed_password"`
+	Username          string `json:"username"`
+	OldHashedPassword string `json:"old_hashed_password"`
+}
+
+func (q *Queries) RehashUserPassword(ctx context.Context, arg RehashUserPasswordParams) error {
+	_, err := q.db.Exec(ctx, rehashUserPassword, arg.NewHashedPassword, arg.Username, arg.OldHashedPassword)
+	return err
+}
+
+const updateUser = `-- name: UpdateUser :one
+UPDATE users
+SET
+  full_name = COALESCE($1, full_name),
+  email = COALESCE($2, email),
+  is_email_verified = COALESCE($3, is_email_verified)
+WHERE username = $4
+RETURNING username, hashed_password, full_name, email, password_changed_at, created_at, role, tier, is_email_verified, is_mfa_enabled, deactivated_at
+`
+
+type UpdateUserParams struct {
+	FullName        pgtype.Text `json:"full_name"`
+	Email           pgtype.Text `json:"email"`
+	IsEmailVerified pgtype.Bool `json:"is_email_verified"`
+	Username        string      `json:"username"`
+}
+
+func (q *Queries) UpdateUser(ctx context.Context, arg UpdateUserParams) (User, error) {
+	row := q.db.QueryRow(ctx, updateUser,
+		arg.FullName,
+		arg.Email,
+		arg.IsEmailVerified,
+		arg.Username,
+	)
+	var i User
+	err := row.Scan(
+		&i.Username,
+		&i.HashedPassword,
+		&i.FullName,
+		&i.Email,
+		&i.PasswordChangedAt,
+		&i.CreatedAt,
+		&i.Role,
+		&i.Tier,
+		&i.IsEmailVerified,
+		&i.IsMfaEnabled,
+		&i.DeactivatedAt,
+	)
+	return i, err
+}
+
+const updateUserPassword = `-- name: UpdateUserPassword :one
+UPDATE users
+SET
+  hashed_password = $1,
+  password_changed_at = $2
+WHERE username = $3
+RETURNING username, hashed_password, full_name, email, password_changed_at, created_at, role, tier, is_email_verified, is_mfa_enabled, deactivated_at
+`
+
+type UpdateUserPasswordParams struct {
+	HashedPassword    string             `json:"hashed_password"`
+	PasswordChangedAt pgtype.Timestamptz `json:"password_changed_at"`
+	Username          string             `json:"username"`
+}
+
+func (q *Queries) UpdateUserPassword(ctx context.Context, arg UpdateUserPasswordParams) (User, error) {
+	row := q.db.QueryRow(ctx, updateUserPassword, arg.HashedPassword, arg.PasswordChangedAt, arg.Username)
+	var i User
+	err := row.Scan(
+		&i.Username,
+		&i.HashedPassword,
+		&i.FullName,
+		&i.Email,
+		&i.PasswordChangedAt,
+		&i.CreatedAt,
+		&i.Role,
+		&i.Tier,
+		&i.IsEmailVerified,
+		&i.IsMfaEnabled,
+		&i.DeactivatedAt,
+	)
+	return i, err
+}
+
+const updateUserTier = `-- name: UpdateUserTier :one
+UPDATE users
+SET tier = $2
+WHERE username = $1
+RETURNING username, hashed_password, full_name, email, password_changed_at, created_at, role, tier, is_email_verified, is_mfa_enabled, deactivated_at
+`
+
+type UpdateUserTierParams struct {
+	Username string `json:"username"`
+	Tier     string `json:"tier"`
+}
+
+func (q *Queries) UpdateUserTier(ctx context.Context, arg UpdateUserTierParams) (User, error) {
+	row := q.db.QueryRow(ctx, updateUserTier, arg.Username, arg.Tier)
+	var i User
+	err := row.Scan(
+		&i.Username,
+		&i.HashedPassword,
+		&i.FullName,
+		&i.Email,
+		&i.PasswordChangedAt,
+		&i.CreatedAt,
+		&i.Role,
+		&i.Tier,
+		&i.IsEmailVerified,
+		&i.IsMfaEnabled,
+		&i.DeactivatedAt,
+	)
+	return i, err
+}
+
+const verifyUserEmail = `-- name: VerifyUserEmail :one
+UPDATE users
+SET is_email_verified = TRUE
+WHERE username = $1
+  AND email = $2
+RETURNING username, hashed_password, full_name, email, password_changed_at, created_at, role, tier, is_email_verified, is_mfa_enabled, deactivated_at
+`
+
+type VerifyUserEmailParams struct {
+	Username string `json:"username"`
+	Email    string `json:"email"`
+}
+
+func (q *Queries) VerifyUserEmail(ctx context.Context, arg VerifyUserEmailParams) (User, error) {
+	row := q.db.QueryRow(ctx, verifyUserEmail, arg.Username, arg.Email)
+	var i User
+	err := row.Scan(
+		&i.Username,
+		&i.HashedPassword,
+		&i.FullName,
+		&i.Email,
+		&i.PasswordChangedAt,
+		&i.CreatedAt,
+		&i.Role,
+		&i.Tier,
+		&i.IsEmailVerified,
+		&i.IsMfaEnabled,
+		&i.DeactivatedAt,
 	)
 	return i, err
 }
@@ -5,6 +5,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/roman-adamchik/simplebank/util"
 	"github.com/stretchr/testify/require"
 )
@@ -29,6 +30,8 @@ func CreateRandomUser(t *testing.T) User {
 	require.Equal(t, args.HashedPassword, user.HashedPassword)
 	require.Equal(t, args.FullName, user.FullName)
 	require.Equal(t, args.Email, user.Email)
+	require.Equal(t, util.DepositorRole, user.Role)
+	require.Equal(t, util.StandardTier, user.Tier)
 	require.True(t, user.PasswordChangedAt.Time.IsZero())
 	require.NotZero(t, user.CreatedAt)
 
@@ -51,3 +54,114 @@ func TestGetUser(t *testing.T) {
 	require.WithinDuration(t, user1.PasswordChangedAt.Time, user2.PasswordChangedAt.Time, time.Second)
 	require.WithinDuration(t, user1.CreatedAt.Time, user2.CreatedAt.Time, time.Second)
 }
+
+func TestRehashUserPassword(t *testing.T) {
+	user := CreateRandomUser(t)
+
+	err := testQueries.RehashUserPassword(context.Background(), RehashUserPasswordParams{
+		NewHashedPassword: "new-hash",
+		Username:          user.Username,
+		OldHashedPassword: user.HashedPassword,
+	})
+	require.NoError(t, err)
+
+	// the hash is left alone once the password changed
+	err = testQueries.RehashUserPassword(context.Background(), RehashUserPasswordParams{
+		NewHashedPassword: "other-hash",
+		Username:          user.Username,
+		OldHashedPassword: user.HashedPassword,
+	})
+	require.NoError(t, err)
+
+	rehashed, err := testQueries.GetUser(context.Background(), user.Username)
+	require.NoError(t, err)
+	require.Equal(t, "new-hash", rehashed.HashedPassword)
+	require.True(t, rehashed.PasswordChangedAt.Time.IsZero())
+}
+
+func TestUpdateUserOnlyFullName(t *testing.T) {
+	oldUser := CreateRandomUser(t)
+
+	newFullName := util.RandomOwner()
+	updatedUser, err := testQueries.UpdateUser(context.Background(), UpdateUserParams{
+		Username: oldUser.Username,
+		FullName: pgtype.Text{String: newFullName, Valid: true},
+	})
+	require.NoError(t, err)
+	require.Equal(t, newFullName, updatedUser.FullName)
+	require.Equal(t, oldUser.Email, updatedUser.Email)
+	require.Equal(t, oldUser.IsEmailVerified, updatedUser.IsEmailVerified)
+	require.Equal(t, oldUser.HashedPassword, updatedUser.HashedPassword)
+}
+
+func TestUpdateUserEmail(t *testing.T) {
+	oldUser := CreateRandomUser(t)
+	oldUser, err := testQueries.VerifyUserEmail(context.Background(), VerifyUserEmailParams{
+		Username: oldUser.Username,
+		Email:    oldUser.Email,
+	})
+	require.NoError(t, err)
+	require.True(t, oldUser.IsEmailVerified)
+
+	newEmail := util.RandomEmail()
+	updatedUser, err := testQueries.UpdateUser(context.Background(), UpdateUserParams{
+		Username:        oldUser.Username,
+		Email:           pgtype.Text{String: newEmail, Valid: true},
+		IsEmailVerified: pgtype.Bool{Bool: false, Valid: true},
+	})
+	require.NoError(t, err)
+	require.Equal(t, newEmail, updatedUser.Email)
+	require.False(t, updatedUser.IsEmailVerified)
+	require.Equal(t, oldUser.FullName, updatedUser.FullName)
+}
+
+func TestDeactivateUserTx(t *testing.T) {
+	store := NewStore(testPool)
+	user := CreateRandomUser(t)
+	now := time.Now()
+
+	emptyAccount, err := testQueries.CreateAccount(context.Background(), CreateAccountParams{
+		Owner:    user.Username,
+		Currency: util.USD,
+	})
+	require.NoError(t, err)
+	fundedAccount, err := testQueries.CreateAccount(context.Background(), CreateAccountParams{
+		Owner:    user.Username,
+		Currency: util.EUR,
+		Balance:  util.RandomInt(1, 1000),
+	})
+	require.NoError(t, err)
+
+	apiKey := createRandomAPIKey(t, user)
+	session := createRandomSession(t, user)
+
+	result, err := store.DeactivateUserTx(context.Background(), DeactivateUserTxParams{
+		Username: user.Username,
+		Now:      now,
+	})
+	require.NoError(t, err)
+	require.Equal(t, user.Username, result.User.Username)
+	require.WithinDuration(t, now, result.User.DeactivatedAt.Time, time.Second)
+	require.Len(t, result.ClosedAccounts, 1)
+	require.Equal(t, emptyAccount.ID, result.ClosedAccounts[0].ID)
+	require.WithinDuration(t, now, result.ClosedAccounts[0].ClosedAt.Time, time.Second)
+
+	apiKey, err = testQueries.GetAPIKey(context.Background(), apiKey.ID)
+	require.NoError(t, err)
+	require.True(t, apiKey.RevokedAt.Valid)
+
+	session, err = testQueries.GetSession(context.Background(), session.ID)
+	require.NoError(t, err)
+	require.True(t, session.RevokedAt.Valid)
+
+	// the account with funds stays open
+	fundedAccount, err = testQueries.GetAccount(context.Background(), fundedAccount.ID)
+	require.NoError(t, err)
+	require.False(t, fundedAccount.ClosedAt.Valid)
+
+	_, err = store.DeactivateUserTx(context.Background(), DeactivateUserTxParams{
+		Username: user.Username,
+		Now:      now,
+	})
+	require.ErrorIs(t, err, ErrUserDeactivated)
+}
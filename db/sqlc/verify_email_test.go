@@ -0,0 +1,80 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/roman-adamchik/simplebank/util"
+	"github.com/stretchr/testify/require"
+)
+
+func createRandomVerifyEmail(t *testing.T, user User, expiredAt time.Time) VerifyEmail {
+	t.Helper()
+
+	arg := CreateVerifyEmailParams{
+		Username:       user.Username,
+		Email:          user.Email,
+		SecretCodeHash: util.HashSecret(util.RandomString(32)),
+		ExpiredAt:      pgtype.Timestamptz{Time: expiredAt, Valid: true},
+	}
+
+	verifyEmail, err := testQueries.CreateVerifyEmail(context.Background(), arg)
+	require.NoError(t, err)
+	require.NotZero(t, verifyEmail.ID)
+	require.Equal(t, arg.Username, verifyEmail.Username)
+	require.Equal(t, arg.Email, verifyEmail.Email)
+	require.Equal(t, arg.SecretCodeHash, verifyEmail.SecretCodeHash)
+	require.False(t, verifyEmail.IsUsed)
+	require.WithinDuration(t, expiredAt, verifyEmail.ExpiredAt.Time, time.Second)
+
+	return verifyEmail
+}
+
+func TestVerifyEmailTx(t *testing.T) {
+	store := NewStore(testPool)
+	user := CreateRandomUser(t)
+	require.False(t, user.IsEmailVerified)
+
+	verifyEmail := createRandomVerifyEmail(t, user, time.Now().Add(time.Hour))
+
+	// a wrong code is rejected
+	_, err := store.VerifyEmailTx(context.Background(), VerifyEmailTxParams{
+		EmailID:        verifyEmail.ID,
+		SecretCodeHash: util.HashSecret(util.RandomString(32)),
+	})
+	require.ErrorIs(t, err, ErrInvalidVerifyEmail)
+
+	result, err := store.VerifyEmailTx(context.Background(), VerifyEmailTxParams{
+		EmailID:        verifyEmail.ID,
+		SecretCodeHash: verifyEmail.SecretCodeHash,
+	})
+	require.NoError(t, err)
+	require.True(t, result.VerifyEmail.IsUsed)
+	require.True(t, result.User.IsEmailVerified)
+	require.Equal(t, user.Username, result.User.Username)
+
+	// a code can be used once
+	_, err = store.VerifyEmailTx(context.Background(), VerifyEmailTxParams{
+		EmailID:        verifyEmail.ID,
+		SecretCodeHash: verifyEmail.SecretCodeHash,
+	})
+	require.ErrorIs(t, err, ErrInvalidVerifyEmail)
+}
+
+func TestVerifyEmailTxExpired(t *testing.T) {
+	store := NewStore(testPool)
+	user := CreateRandomUser(t)
+	verifyEmail := createRandomVerifyEmail(t, user, time.Now().Add(-time.Minute))
+
+	_, err := store.VerifyEmailTx(context.Background(), VerifyEmailTxParams{
+		EmailID:        verifyEmail.ID,
+		SecretCodeHash: verifyEmail.SecretCodeHash,
+	})
+	require.ErrorIs(t, err, ErrInvalidVerifyEmail)
+
+	user, err = testQueries.GetUser(context.Background(), user.Username)
+	require.NoError(t, err)
+	require.False(t, user.IsEmailVerified)
+}
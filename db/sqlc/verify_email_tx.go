@@ -0,0 +1,52 @@
+package db
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrInvalidVerifyEmail is returned when a verification code does not exist, is used or expired,
+// or was sent to an address that is no longer the email of the user
+var ErrInvalidVerifyEmail = errors.New("email verification code is invalid or expired")
+
+// VerifyEmailTxParams contains the input parameters of the verify email transaction
+type VerifyEmailTxParams struct {
+	EmailID        int64  `json:"email_id"`
+	SecretCodeHash string `json:"secret_code_hash"`
+}
+
+// VerifyEmailTxResult is the result of the verify email transaction
+type VerifyEmailTxResult struct {
+	User        User        `json:"user"`
+	VerifyEmail VerifyEmail `json:"verify_email"`
+}
+
+// VerifyEmailTx uses a verification code and marks the email of its user as verified within a single db transaction
+func (s *SQLStore) VerifyEmailTx(ctx context.Context, arg VerifyEmailTxParams) (VerifyEmailTxResult, error) {
+	var result VerifyEmailTxResult
+
+	err := s.execTx(ctx, func(q *Queries) error {
+		var err error
+		result.VerifyEmail, err = q.UseVerifyEmail(ctx, UseVerifyEmailParams{
+			ID:             arg.EmailID,
+			SecretCodeHash: arg.SecretCodeHash,
+		})
+		if err != nil {
+			if errors.Is(err, ErrRecordNotFound) {
+				return ErrInvalidVerifyEmail
+			}
+			return err
+		}
+
+		result.User, err = q.VerifyUserEmail(ctx, VerifyUserEmailParams{
+			Username: result.VerifyEmail.Username,
+			Email:    result.VerifyEmail.Email,
+		})
+		if errors.Is(err, ErrRecordNotFound) {
+			return ErrInvalidVerifyEmail
+		}
+		return err
+	})
+
+	return result, err
+}
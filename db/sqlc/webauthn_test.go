@@ -0,0 +1,140 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/roman-adamchik/simplebank/util"
+	"github.com/stretchr/testify/require"
+)
+
+func createRandomWebAuthnCredential(t *testing.T, user User) WebauthnCredential {
+	t.Helper()
+
+	arg := CreateWebAuthnCredentialParams{
+		CredentialID:    []byte(util.RandomString(16)),
+		Username:        user.Username,
+		UserHandle:      []byte(util.RandomString(32)),
+		Name:            util.RandomString(6),
+		PublicKey:       []byte(util.RandomString(77)),
+		AttestationType: "none",
+		Transports:      []string{"internal", "hybrid"},
+		Aaguid:          make([]byte, 16),
+		SignCount:       1,
+		BackupEligible:  true,
+	}
+	credential, err := testQueries.CreateWebAuthnCredential(context.Background(), arg)
+	require.NoError(t, err)
+	require.NotZero(t, credential.ID)
+	require.Equal(t, arg.CredentialID, credential.CredentialID)
+	require.Equal(t, arg.UserHandle, credential.UserHandle)
+	require.Equal(t, arg.PublicKey, credential.PublicKey)
+	require.Equal(t, arg.Transports, credential.Transports)
+	require.Equal(t, arg.SignCount, credential.SignCount)
+	require.True(t, credential.BackupEligible)
+	require.False(t, credential.LastUsedAt.Valid)
+
+	return credential
+}
+
+func TestGetWebAuthnCredentialByCredentialID(t *testing.T) {
+	credential := createRandomWebAuthnCredential(t, CreateRandomUser(t))
+
+	found, err := testQueries.GetWebAuthnCredentialByCredentialID(context.Background(), credential.CredentialID)
+	require.NoError(t, err)
+	require.Equal(t, credential.ID, found.ID)
+
+	_, err = testQueries.GetWebAuthnCredentialByCredentialID(context.Background(), []byte(util.RandomString(16)))
+	require.ErrorIs(t, err, ErrRecordNotFound)
+}
+
+func TestListWebAuthnCredentials(t *testing.T) {
+	user := CreateRandomUser(t)
+	credential1 := createRandomWebAuthnCredential(t, user)
+	credential2 := createRandomWebAuthnCredential(t, user)
+	createRandomWebAuthnCredential(t, CreateRandomUser(t))
+
+	credentials, err := testQueries.ListWebAuthnCredentials(context.Background(), user.Username)
+	require.NoError(t, err)
+	require.Len(t, credentials, 2)
+	require.Equal(t, credential1.ID, credentials[0].ID)
+	require.Equal(t, credential2.ID, credentials[1].ID)
+
+	err = testQueries.DeleteWebAuthnCredential(context.Background(), credential1.ID)
+	require.NoError(t, err)
+
+	credentials, err = testQueries.ListWebAuthnCredentials(context.Background(), user.Username)
+	require.NoError(t, err)
+	require.Len(t, credentials, 1)
+}
+
+func TestUpdateWebAuthnCredentialUse(t *testing.T) {
+	credential := createRandomWebAuthnCredential(t, CreateRandomUser(t))
+	now := pgtype.Timestamptz{Time: time.Now(), Valid: true}
+
+	updated, err := testQueries.UpdateWebAuthnCredentialUse(context.Background(), UpdateWebAuthnCredentialUseParams{
+		SignCount:   credential.SignCount + 1,
+		BackupState: true,
+		LastUsedAt:  now,
+		ID:          credential.ID,
+	})
+	require.NoError(t, err)
+	require.Equal(t, credential.SignCount+1, updated.SignCount)
+	require.True(t, updated.BackupState)
+	require.WithinDuration(t, now.Time, updated.LastUsedAt.Time, time.Second)
+}
+
+func TestUseWebAuthnChallenge(t *testing.T) {
+	user := CreateRandomUser(t)
+	now := time.Now()
+
+	challenge, err := testQueries.CreateWebAuthnChallenge(context.Background(), CreateWebAuthnChallengeParams{
+		Challenge:   util.RandomString(43),
+		Ceremony:    "registration",
+		Username:    pgtype.Text{String: user.Username, Valid: true},
+		SessionData: []byte(`{"challenge":"abc"}`),
+		ExpiresAt:   pgtype.Timestamptz{Time: now.Add(time.Minute), Valid: true},
+	})
+	require.NoError(t, err)
+
+	arg := UseWebAuthnChallengeParams{
+		Now:       pgtype.Timestamptz{Time: now, Valid: true},
+		Challenge: challenge.Challenge,
+		Ceremony:  "login",
+	}
+
+	// a challenge only answers the ceremony it was issued for
+	_, err = testQueries.UseWebAuthnChallenge(context.Background(), arg)
+	require.ErrorIs(t, err, ErrRecordNotFound)
+
+	arg.Ceremony = "registration"
+	used, err := testQueries.UseWebAuthnChallenge(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, user.Username, used.Username.String)
+	require.JSONEq(t, `{"challenge":"abc"}`, string(used.SessionData))
+	require.True(t, used.UsedAt.Valid)
+
+	// and only once
+	_, err = testQueries.UseWebAuthnChallenge(context.Background(), arg)
+	require.ErrorIs(t, err, ErrRecordNotFound)
+}
+
+func TestUseExpiredWebAuthnChallenge(t *testing.T) {
+	challenge, err := testQueries.CreateWebAuthnChallenge(context.Background(), CreateWebAuthnChallengeParams{
+		Challenge:   util.RandomString(43),
+		Ceremony:    "login",
+		SessionData: []byte(`{}`),
+		ExpiresAt:   pgtype.Timestamptz{Time: time.Now().Add(-time.Second), Valid: true},
+	})
+	require.NoError(t, err)
+	require.False(t, challenge.Username.Valid)
+
+	_, err = testQueries.UseWebAuthnChallenge(context.Background(), UseWebAuthnChallengeParams{
+		Now:       pgtype.Timestamptz{Time: time.Now(), Valid: true},
+		Challenge: challenge.Challenge,
+		Ceremony:  "login",
+	})
+	require.ErrorIs(t, err, ErrRecordNotFound)
+}
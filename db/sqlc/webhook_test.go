@@ -0,0 +1,149 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/roman-adamchik/simplebank/util"
+	"github.com/stretchr/testify/require"
+)
+
+func createRandomWebhookSubscription(t *testing.T, owner string, eventTypes ...string) WebhookSubscription {
+	t.Helper()
+
+	arg := CreateWebhookSubscriptionParams{
+		Owner:      owner,
+		Url:        "https://example.com/hooks/" + util.RandomString(6),
+		EventTypes: eventTypes,
+		Secret:     util.RandomString(32),
+	}
+	subscription, err := testQueries.CreateWebhookSubscription(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, arg.Owner, subscription.Owner)
+	require.Equal(t, arg.EventTypes, subscription.EventTypes)
+	require.Zero(t, subscription.FailureCount)
+	require.False(t, subscription.DisabledAt.Valid)
+
+	return subscription
+}
+
+// listDeliveries returns the deliveries of a subscription, newest first
+func listDeliveries(t *testing.T, subscriptionID int64) []WebhookDelivery {
+	t.Helper()
+
+	deliveries, err := testQueries.ListWebhookDeliveries(context.Background(), ListWebhookDeliveriesParams{
+		SubscriptionID: subscriptionID,
+		Limit:          100,
+	})
+	require.NoError(t, err)
+	return deliveries
+}
+
+func TestTransferTxQueuesWebhookDeliveries(t *testing.T) {
+	store := NewStore(testPool)
+	account1 := CreateRandomAccount(t)
+	account2 := CreateRandomAccount(t)
+
+	sender := createRandomWebhookSubscription(t, account1.Owner, EventTransferCompleted)
+	recipient := createRandomWebhookSubscription(t, account2.Owner, EventTransferCompleted, EventTransferPending)
+	uninterested := createRandomWebhookSubscription(t, account2.Owner, EventAccountCreated)
+
+	_, err := store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: account1.ID,
+		ToAccountID:   account2.ID,
+		Amount:        10,
+	})
+	require.NoError(t, err)
+
+	for _, subscription := range []WebhookSubscription{sender, recipient} {
+		deliveries := listDeliveries(t, subscription.ID)
+		require.Len(t, deliveries, 1)
+		require.Equal(t, WebhookDeliveryPending, deliveries[0].Status)
+
+		event, err := testQueries.GetOutboxEvent(context.Background(), deliveries[0].EventID)
+		require.NoError(t, err)
+		require.Equal(t, EventTransferCompleted, event.EventType)
+	}
+	require.Empty(t, listDeliveries(t, uninterested.ID))
+}
+
+func TestRecordWebhookAttemptTx(t *testing.T) {
+	store := NewStore(testPool)
+	account1 := CreateRandomAccount(t)
+	account2 := CreateRandomAccount(t)
+	subscription := createRandomWebhookSubscription(t, account2.Owner, EventTransferCompleted)
+
+	for range 2 {
+		_, err := store.TransferTx(context.Background(), TransferTxParams{
+			FromAccountID: account1.ID,
+			ToAccountID:   account2.ID,
+			Amount:        10,
+		})
+		require.NoError(t, err)
+	}
+	deliveries := listDeliveries(t, subscription.ID)
+	require.Len(t, deliveries, 2)
+
+	// a failed attempt is retried later
+	now := time.Now()
+	result, err := store.RecordWebhookAttemptTx(context.Background(), RecordWebhookAttemptTxParams{
+		DeliveryID:     deliveries[0].ID,
+		ResponseStatus: 500,
+		Error:          "webhook endpoint responded with status 500",
+		NextAttemptAt:  now.Add(time.Minute),
+		DisableAfter:   2,
+		Now:            now,
+	})
+	require.NoError(t, err)
+	require.Equal(t, WebhookDeliveryPending, result.Delivery.Status)
+	require.Equal(t, int32(1), result.Delivery.Attempts)
+	require.Equal(t, int32(500), result.Delivery.ResponseStatus)
+	require.WithinDuration(t, now.Add(time.Minute), result.Delivery.NextAttemptAt.Time, time.Second)
+	require.Equal(t, int32(1), result.Subscription.FailureCount)
+	require.False(t, result.Subscription.DisabledAt.Valid)
+
+	// the second failure in a row gives up the delivery and disables the subscription
+	result, err = store.RecordWebhookAttemptTx(context.Background(), RecordWebhookAttemptTxParams{
+		DeliveryID:   deliveries[1].ID,
+		Error:        "connection refused",
+		DisableAfter: 2,
+		Now:          now,
+	})
+	require.NoError(t, err)
+	require.Equal(t, WebhookDeliveryFailed, result.Delivery.Status)
+	require.Equal(t, int32(2), result.Subscription.FailureCount)
+	require.True(t, result.Subscription.DisabledAt.Valid)
+
+	_, err = store.RecordWebhookAttemptTx(context.Background(), RecordWebhookAttemptTxParams{
+		DeliveryID: deliveries[1].ID,
+		Now:        now,
+	})
+	require.ErrorIs(t, err, ErrWebhookDeliveryNotPending)
+
+	// deliveries of a disabled subscription are not claimed
+	claimed, err := testQueries.ClaimWebhookDeliveries(context.Background(), ClaimWebhookDeliveriesParams{
+		LeaseUntil:    pgtype.Timestamptz{Time: now.Add(2 * time.Hour), Valid: true},
+		Now:           pgtype.Timestamptz{Time: now.Add(time.Hour), Valid: true},
+		MaxDeliveries: 1000,
+	})
+	require.NoError(t, err)
+	for _, delivery := range claimed {
+		require.NotEqual(t, subscription.ID, delivery.SubscriptionID)
+	}
+
+	// a success after enabling the subscription again resets the failure count
+	_, err = testQueries.UpdateWebhookSubscriptionFailures(context.Background(), UpdateWebhookSubscriptionFailuresParams{ID: subscription.ID})
+	require.NoError(t, err)
+
+	result, err = store.RecordWebhookAttemptTx(context.Background(), RecordWebhookAttemptTxParams{
+		DeliveryID:     deliveries[0].ID,
+		ResponseStatus: 200,
+		Now:            now,
+	})
+	require.NoError(t, err)
+	require.Equal(t, WebhookDeliverySucceeded, result.Delivery.Status)
+	require.True(t, result.Delivery.DeliveredAt.Valid)
+	require.Zero(t, result.Subscription.FailureCount)
+}
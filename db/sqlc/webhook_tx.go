@@ -0,0 +1,113 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// list of webhook delivery statuses
+const (
+	WebhookDeliveryPending   = "pending"
+	WebhookDeliverySucceeded = "succeeded"
+	WebhookDeliveryFailed    = "failed"
+)
+
+// webhookEventTypes are the event types a webhook can subscribe to
+var webhookEventTypes = []string{
+	EventTransferCompleted,
+	EventTransferPending,
+	EventTransferRejected,
+	EventTransferExpired,
+	EventAccountCreated,
+}
+
+// IsWebhookEventType returns true if a webhook can subscribe to the event type
+func IsWebhookEventType(eventType string) bool {
+	return slices.Contains(webhookEventTypes, eventType)
+}
+
+// ErrWebhookDeliveryNotPending is returned when an attempt is recorded for a delivery that is already finished
+var ErrWebhookDeliveryNotPending = errors.New("webhook delivery is not pending")
+
+// RecordWebhookAttemptTxParams contains the input parameters of the webhook attempt transaction
+type RecordWebhookAttemptTxParams struct {
+	DeliveryID int64 `json:"delivery_id"`
+	// ResponseStatus is the HTTP status returned by the endpoint, 0 if no response was received
+	ResponseStatus int32 `json:"response_status"`
+	// Error is empty if the attempt succeeded
+	Error string `json:"error"`
+	// NextAttemptAt is when a failed delivery is tried again; a failed delivery without it is given up
+	NextAttemptAt time.Time `json:"next_attempt_at"`
+	// DisableAfter is the number of consecutive failed attempts after which the subscription is disabled
+	DisableAfter int32     `json:"disable_after"`
+	Now          time.Time `json:"now"`
+}
+
+// RecordWebhookAttemptTxResult is the result of the webhook attempt transaction
+type RecordWebhookAttemptTxResult struct {
+	Delivery     WebhookDelivery     `json:"delivery"`
+	Subscription WebhookSubscription `json:"subscription"`
+}
+
+// RecordWebhookAttemptTx records the outcome of a delivery attempt within a single db transaction.
+// A success resets the failure count of the subscription, and a failure disables the subscription
+// once it has failed DisableAfter times in a row
+func (s *SQLStore) RecordWebhookAttemptTx(ctx context.Context, arg RecordWebhookAttemptTxParams) (RecordWebhookAttemptTxResult, error) {
+	var result RecordWebhookAttemptTxResult
+
+	err := s.execTx(ctx, func(q *Queries) error {
+		delivery, err := q.GetWebhookDeliveryForUpdate(ctx, arg.DeliveryID)
+		if err != nil {
+			return err
+		}
+		if delivery.Status != WebhookDeliveryPending {
+			return ErrWebhookDeliveryNotPending
+		}
+
+		subscription, err := q.GetWebhookSubscriptionForUpdate(ctx, delivery.SubscriptionID)
+		if err != nil {
+			return err
+		}
+
+		update := UpdateWebhookDeliveryParams{
+			ID:             delivery.ID,
+			Status:         WebhookDeliverySucceeded,
+			ResponseStatus: arg.ResponseStatus,
+			Error:          arg.Error,
+			NextAttemptAt:  delivery.NextAttemptAt,
+		}
+		failures := UpdateWebhookSubscriptionFailuresParams{
+			ID:         subscription.ID,
+			DisabledAt: subscription.DisabledAt,
+		}
+
+		if arg.Error == "" {
+			update.DeliveredAt = pgtype.Timestamptz{Time: arg.Now, Valid: true}
+		} else {
+			update.Status = WebhookDeliveryFailed
+			if !arg.NextAttemptAt.IsZero() {
+				update.Status = WebhookDeliveryPending
+				update.NextAttemptAt = pgtype.Timestamptz{Time: arg.NextAttemptAt, Valid: true}
+			}
+
+			failures.FailureCount = subscription.FailureCount + 1
+			if arg.DisableAfter > 0 && failures.FailureCount >= arg.DisableAfter && !subscription.DisabledAt.Valid {
+				failures.DisabledAt = pgtype.Timestamptz{Time: arg.Now, Valid: true}
+			}
+		}
+
+		result.Delivery, err = q.UpdateWebhookDelivery(ctx, update)
+		if err != nil {
+			return err
+		}
+
+		result.Subscription, err = q.UpdateWebhookSubscriptionFailures(ctx, failures)
+		return err
+	})
+
+	return result, err
+}
@@ -0,0 +1,68 @@
+package mail
+
+import (
+	"context"
+	"fmt"
+	"log"
+	netmail "net/mail"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// LogMailer writes emails to the log, it is meant for development
+type LogMailer struct{}
+
+// NewLogMailer creates a new LogMailer
+func NewLogMailer() *LogMailer {
+	return &LogMailer{}
+}
+
+// Send logs the message
+func (mailer *LogMailer) Send(ctx context.Context, message Message) error {
+	to, err := message.recipients()
+	if err != nil {
+		return err
+	}
+
+	log.Printf("email to %v: %s\n%s", to, message.Subject, message.Body)
+	return nil
+}
+
+// FileMailer writes every email to a .eml file of a directory, it is meant for development
+type FileMailer struct {
+	dir  string
+	from *netmail.Address
+}
+
+// NewFileMailer creates a new FileMailer writing to the given directory, which is created if it does not exist
+func NewFileMailer(dir string, from string) (*FileMailer, error) {
+	if dir == "" {
+		return nil, fmt.Errorf("mail directory is not set")
+	}
+	sender, err := netmail.ParseAddress(from)
+	if err != nil {
+		return nil, fmt.Errorf("invalid sender %q: %w", from, err)
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("cannot create mail directory: %w", err)
+	}
+
+	return &FileMailer{
+		dir:  dir,
+		from: sender,
+	}, nil
+}
+
+// Send writes the message to a new file named after the time it was sent and its first recipient
+func (mailer *FileMailer) Send(ctx context.Context, message Message) error {
+	now := time.Now()
+	data, err := message.bytes(mailer.from, now)
+	if err != nil {
+		return err
+	}
+
+	to, _ := message.recipients()
+	name := fmt.Sprintf("%d-%s.eml", now.UnixNano(), to[0])
+	return os.WriteFile(filepath.Join(mailer.dir, name), data, 0o644)
+}
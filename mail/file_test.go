@@ -0,0 +1,90 @@
+package mail
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/roman-adamchik/simplebank/util"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileMailer(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "mail")
+
+	mailer, err := NewFileMailer(dir, defaultFrom)
+	require.NoError(t, err)
+
+	err = mailer.Send(context.Background(), Message{
+		To:      []string{"alice@email.com"},
+		Subject: "Vérifiez votre email",
+		Body:    "Hello Alice",
+	})
+	require.NoError(t, err)
+
+	files, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+	require.True(t, strings.HasSuffix(files[0].Name(), "-alice@email.com.eml"))
+
+	data, err := os.ReadFile(filepath.Join(dir, files[0].Name()))
+	require.NoError(t, err)
+	require.Contains(t, string(data), "From: \"Simple Bank\" <no-reply@simplebank.local>\r\n")
+	require.Contains(t, string(data), "To: alice@email.com\r\n")
+	require.Contains(t, string(data), "Subject: =?utf-8?q?V=C3=A9rifiez_votre_email?=\r\n")
+	require.Contains(t, string(data), "Hello Alice")
+}
+
+func TestMessageInvalid(t *testing.T) {
+	testCases := []struct {
+		name    string
+		message Message
+	}{
+		{
+			name:    "NoRecipient",
+			message: Message{Subject: "Welcome"},
+		},
+		{
+			name:    "InvalidRecipient",
+			message: Message{To: []string{"not an address"}, Subject: "Welcome"},
+		},
+		{
+			name:    "SubjectLineBreak",
+			message: Message{To: []string{"alice@email.com"}, Subject: "Welcome\r\nBcc: eve@email.com"},
+		},
+	}
+
+	mailer, err := NewFileMailer(t.TempDir(), defaultFrom)
+	require.NoError(t, err)
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			err := mailer.Send(context.Background(), tc.message)
+			require.Error(t, err)
+		})
+	}
+}
+
+func TestNewMailer(t *testing.T) {
+	mailer, err := NewMailer(util.Config{})
+	require.NoError(t, err)
+	require.IsType(t, &LogMailer{}, mailer)
+
+	mailer, err = NewMailer(util.Config{Mailer: MailerFile, MailDir: t.TempDir()})
+	require.NoError(t, err)
+	require.IsType(t, &FileMailer{}, mailer)
+
+	mailer, err = NewMailer(util.Config{Mailer: MailerSMTP, SMTPAddress: "localhost:25"})
+	require.NoError(t, err)
+	require.IsType(t, &SMTPMailer{}, mailer)
+
+	_, err = NewMailer(util.Config{Mailer: MailerFile})
+	require.Error(t, err)
+
+	_, err = NewMailer(util.Config{Mailer: "pigeon"})
+	require.Error(t, err)
+}
@@ -0,0 +1,103 @@
+package mail
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"mime"
+	"mime/quotedprintable"
+	netmail "net/mail"
+	"strings"
+	"time"
+
+	"github.com/roman-adamchik/simplebank/util"
+)
+
+// list of mailers emails can be sent with
+const (
+	MailerLog  = "log"
+	MailerFile = "file"
+	MailerSMTP = "smtp"
+)
+
+const defaultFrom = "Simple Bank <no-reply@simplebank.local>"
+
+// Mailer sends emails to users
+type Mailer interface {
+	Send(ctx context.Context, message Message) error
+}
+
+// Message is a plain text email
+type Message struct {
+	To      []string
+	Subject string
+	Body    string
+}
+
+// NewMailer creates the mailer selected by the config, emails are logged by default
+func NewMailer(config util.Config) (Mailer, error) {
+	from := config.MailFrom
+	if from == "" {
+		from = defaultFrom
+	}
+
+	switch config.Mailer {
+	case "", MailerLog:
+		return NewLogMailer(), nil
+	case MailerFile:
+		return NewFileMailer(config.MailDir, from)
+	case MailerSMTP:
+		return NewSMTPMailer(config.SMTPAddress, config.SMTPUsername, config.SMTPPassword, from)
+	}
+
+	return nil, fmt.Errorf("unsupported mailer %q", config.Mailer)
+}
+
+// recipients parses the addresses a message is sent to
+func (message Message) recipients() ([]string, error) {
+	if len(message.To) == 0 {
+		return nil, errors.New("email has no recipient")
+	}
+
+	addresses := make([]string, len(message.To))
+	for i, to := range message.To {
+		address, err := netmail.ParseAddress(to)
+		if err != nil {
+			return nil, fmt.Errorf("invalid recipient %q: %w", to, err)
+		}
+		addresses[i] = address.Address
+	}
+
+	return addresses, nil
+}
+
+// bytes formats the message as a MIME email sent by from
+func (message Message) bytes(from *netmail.Address, now time.Time) ([]byte, error) {
+	to, err := message.recipients()
+	if err != nil {
+		return nil, err
+	}
+	if strings.ContainsAny(message.Subject, "\r\n") {
+		return nil, errors.New("email subject contains a line break")
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "From: %s\r\n", from.String())
+	fmt.Fprintf(&buf, "To: %s\r\n", strings.Join(to, ", "))
+	fmt.Fprintf(&buf, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", message.Subject))
+	fmt.Fprintf(&buf, "Date: %s\r\n", now.Format(time.RFC1123Z))
+	buf.WriteString("MIME-Version: 1.0\r\n")
+	buf.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	buf.WriteString("Content-Transfer-Encoding: quoted-printable\r\n\r\n")
+
+	body := quotedprintable.NewWriter(&buf)
+	if _, err := body.Write([]byte(message.Body)); err != nil {
+		return nil, err
+	}
+	if err := body.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,111 @@
+package mail
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	netmail "net/mail"
+	"net/smtp"
+	"time"
+)
+
+const defaultSMTPTimeout = 30 * time.Second
+
+// SMTPMailer sends emails through an SMTP server.
+// The connection is upgraded with STARTTLS when the server supports it
+type SMTPMailer struct {
+	address  string
+	host     string
+	username string
+	password string
+	from     *netmail.Address
+}
+
+// NewSMTPMailer creates a new SMTPMailer for the server at address (host:port).
+// It authenticates with PLAIN if a username is given, which requires TLS unless the server is on localhost
+func NewSMTPMailer(address string, username string, password string, from string) (*SMTPMailer, error) {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return nil, fmt.Errorf("invalid SMTP address %q: %w", address, err)
+	}
+	sender, err := netmail.ParseAddress(from)
+	if err != nil {
+		return nil, fmt.Errorf("invalid sender %q: %w", from, err)
+	}
+
+	return &SMTPMailer{
+		address:  address,
+		host:     host,
+		username: username,
+		password: password,
+		from:     sender,
+	}, nil
+}
+
+// Send delivers the message to the SMTP server, giving up when the context is done
+func (mailer *SMTPMailer) Send(ctx context.Context, message Message) error {
+	to, err := message.recipients()
+	if err != nil {
+		return err
+	}
+	data, err := message.bytes(mailer.from, time.Now())
+	if err != nil {
+		return err
+	}
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", mailer.address)
+	if err != nil {
+		return fmt.Errorf("cannot connect to SMTP server: %w", err)
+	}
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(defaultSMTPTimeout)
+	}
+	if err := conn.SetDeadline(deadline); err != nil {
+		conn.Close()
+		return err
+	}
+
+	client, err := smtp.NewClient(conn, mailer.host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err := client.StartTLS(&tls.Config{ServerName: mailer.host}); err != nil {
+			return err
+		}
+	}
+	if mailer.username != "" {
+		auth := smtp.PlainAuth("", mailer.username, mailer.password, mailer.host)
+		if err := client.Auth(auth); err != nil {
+			return err
+		}
+	}
+
+	if err := client.Mail(mailer.from.Address); err != nil {
+		return err
+	}
+	for _, address := range to {
+		if err := client.Rcpt(address); err != nil {
+			return err
+		}
+	}
+
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(data); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+
+	return client.Quit()
+}
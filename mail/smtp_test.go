@@ -0,0 +1,127 @@
+package mail
+
+import (
+	"context"
+	"encoding/base64"
+	"io"
+	"net"
+	"net/textproto"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// smtpSession is what the fake SMTP server received from a client
+type smtpSession struct {
+	auth string
+	from string
+	to   []string
+	data string
+}
+
+// runSMTPServer starts a fake SMTP server accepting a single session, which is sent to the returned channel
+func runSMTPServer(t *testing.T) (string, <-chan smtpSession) {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { listener.Close() })
+
+	sessions := make(chan smtpSession, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		text := textproto.NewConn(conn)
+		var session smtpSession
+		text.PrintfLine("220 localhost ESMTP")
+		for {
+			line, err := text.ReadLine()
+			if err != nil {
+				return
+			}
+			command, arg, _ := strings.Cut(line, " ")
+
+			switch strings.ToUpper(command) {
+			case "EHLO":
+				text.PrintfLine("250-localhost")
+				text.PrintfLine("250 AUTH PLAIN")
+			case "AUTH":
+				credentials, _ := strings.CutPrefix(arg, "PLAIN ")
+				decoded, _ := base64.StdEncoding.DecodeString(credentials)
+				session.auth = string(decoded)
+				text.PrintfLine("235 authenticated")
+			case "MAIL":
+				session.from = arg
+				text.PrintfLine("250 ok")
+			case "RCPT":
+				session.to = append(session.to, arg)
+				text.PrintfLine("250 ok")
+			case "DATA":
+				text.PrintfLine("354 go ahead")
+				data, _ := io.ReadAll(text.DotReader())
+				session.data = string(data)
+				text.PrintfLine("250 queued")
+			case "QUIT":
+				text.PrintfLine("221 bye")
+				sessions <- session
+				return
+			default:
+				text.PrintfLine("502 unknown command")
+			}
+		}
+	}()
+
+	return listener.Addr().String(), sessions
+}
+
+func TestSMTPMailer(t *testing.T) {
+	address, sessions := runSMTPServer(t)
+
+	mailer, err := NewSMTPMailer(address, "bank", "secret", defaultFrom)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	err = mailer.Send(ctx, Message{
+		To:      []string{"Alice <alice@email.com>", "bob@email.com"},
+		Subject: "Welcome",
+		Body:    "Hello Alice",
+	})
+	require.NoError(t, err)
+
+	session := <-sessions
+	require.Equal(t, "\x00bank\x00secret", session.auth)
+	require.Equal(t, "FROM:<no-reply@simplebank.local>", session.from)
+	require.Equal(t, []string{"TO:<alice@email.com>", "TO:<bob@email.com>"}, session.to)
+	require.Contains(t, session.data, "Subject: Welcome\n")
+	require.Contains(t, session.data, "To: alice@email.com, bob@email.com\n")
+	require.Contains(t, session.data, "Hello Alice")
+}
+
+func TestSMTPMailerUnreachable(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	address := listener.Addr().String()
+	listener.Close()
+
+	mailer, err := NewSMTPMailer(address, "", "", defaultFrom)
+	require.NoError(t, err)
+
+	err = mailer.Send(context.Background(), Message{To: []string{"alice@email.com"}, Subject: "Welcome"})
+	require.Error(t, err)
+}
+
+func TestNewSMTPMailerInvalid(t *testing.T) {
+	_, err := NewSMTPMailer("localhost", "", "", defaultFrom)
+	require.Error(t, err)
+
+	_, err = NewSMTPMailer("localhost:25", "", "", "not an address")
+	require.Error(t, err)
+}
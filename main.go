@@ -9,7 +9,13 @@ import (
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/roman-adamchik/simplebank/api"
 	db "github.com/roman-adamchik/simplebank/db/sqlc"
+	"github.com/roman-adamchik/simplebank/mail"
+	"github.com/roman-adamchik/simplebank/outbox"
+	"github.com/roman-adamchik/simplebank/stream"
+	"github.com/roman-adamchik/simplebank/task"
 	"github.com/roman-adamchik/simplebank/util"
+	"github.com/roman-adamchik/simplebank/webhook"
+	"github.com/roman-adamchik/simplebank/worker"
 )
 
 func main() {
@@ -33,7 +39,55 @@ func main() {
 	}
 
 	store := db.NewStore(pool)
-	server, err := api.NewServer(config, store)
+
+	taskDistributor, taskProcessor, err := task.NewQueue(config)
+	if err != nil {
+		log.Fatal("Cannot create task queue:", err)
+	}
+
+	publisher, err := outbox.NewPublisher(config)
+	if err != nil {
+		log.Fatal("Cannot create event publisher:", err)
+	}
+
+	webhookDispatcher := worker.NewWebhookDispatcher(store, webhook.NewSender(config.WebhookTimeout), taskDistributor, config.WebhookInterval)
+	taskProcessor.Handle(worker.TaskDeliverWebhook, webhookDispatcher.DeliverWebhook)
+
+	// background jobs run as periodic tasks, so with several instances each run is processed by one of them
+	periodicJobs := []interface {
+		Schedule(ctx context.Context, distributor task.Distributor, processor task.Processor) error
+	}{
+		worker.NewStandingOrderProcessor(store, config.StandingOrderInterval),
+		worker.NewPendingTransferExpirer(store, config.PendingTransferInterval),
+		worker.NewHoldExpirer(store, config.HoldInterval),
+		worker.NewOutboxRelay(store, publisher, config.OutboxInterval),
+		webhookDispatcher,
+	}
+	for _, job := range periodicJobs {
+		if err := job.Schedule(ctx, taskDistributor, taskProcessor); err != nil {
+			log.Fatal("Cannot schedule background job:", err)
+		}
+	}
+
+	mailer, err := mail.NewMailer(config)
+	if err != nil {
+		log.Fatal("Cannot create mailer:", err)
+	}
+	verifyEmailSender := worker.NewVerifyEmailSender(store, mailer, config.VerifyEmailURL, config.VerifyEmailTTL)
+	taskProcessor.Handle(worker.TaskSendVerifyEmail, verifyEmailSender.SendVerifyEmail)
+	passwordResetSender := worker.NewPasswordResetSender(store, mailer, config.PasswordResetURL, config.PasswordResetTTL, config.PasswordResetLimit)
+	taskProcessor.Handle(worker.TaskSendPasswordReset, passwordResetSender.SendPasswordReset)
+
+	if err := taskProcessor.Start(); err != nil {
+		log.Fatal("Cannot start task processor:", err)
+	}
+	defer taskProcessor.Shutdown()
+
+	accountUpdates := stream.NewBroker()
+	accountUpdatesListener := stream.NewListener(config.DBSource, accountUpdates)
+	go accountUpdatesListener.Start(ctx)
+
+	server, err := api.NewServer(config, store, accountUpdates, taskDistributor)
 	if err != nil {
 		log.Fatal("Cannot create server:", err)
 	}
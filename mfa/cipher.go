@@ -0,0 +1,64 @@
+package mfa
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+)
+
+// ErrInvalidCiphertext is returned when a ciphertext cannot be decrypted with the key
+var ErrInvalidCiphertext = errors.New("ciphertext is invalid")
+
+// Cipher encrypts the secrets stored in the db with AES-256-GCM
+type Cipher struct {
+	aead cipher.AEAD
+}
+
+// NewCipher creates a new Cipher with a 32 characters key
+func NewCipher(key string) (*Cipher, error) {
+	if len(key) != 32 {
+		return nil, fmt.Errorf("invalid key size: must be exactly 32 characters")
+	}
+
+	block, err := aes.NewCipher([]byte(key))
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Cipher{aead: aead}, nil
+}
+
+// Encrypt encrypts a plaintext bound to the associated data, which must be given again to decrypt it.
+// Binding a secret to its owner keeps a ciphertext copied to another row from being decrypted
+func (c *Cipher) Encrypt(plaintext string, associatedData string) (string, error) {
+	nonce := make([]byte, c.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("cannot generate nonce: %w", err)
+	}
+
+	sealed := c.aead.Seal(nonce, nonce, []byte(plaintext), []byte(associatedData))
+	return base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt decrypts a ciphertext returned by Encrypt
+func (c *Cipher) Decrypt(ciphertext string, associatedData string) (string, error) {
+	sealed, err := base64.StdEncoding.DecodeString(ciphertext)
+	if err != nil || len(sealed) < c.aead.NonceSize() {
+		return "", ErrInvalidCiphertext
+	}
+
+	nonce, sealed := sealed[:c.aead.NonceSize()], sealed[c.aead.NonceSize():]
+	plaintext, err := c.aead.Open(nil, nonce, sealed, []byte(associatedData))
+	if err != nil {
+		return "", ErrInvalidCiphertext
+	}
+
+	return string(plaintext), nil
+}
@@ -0,0 +1,42 @@
+package mfa
+
+import (
+	"testing"
+
+	"github.com/roman-adamchik/simplebank/util"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCipher(t *testing.T) {
+	c, err := NewCipher(util.RandomString(32))
+	require.NoError(t, err)
+
+	ciphertext, err := c.Encrypt("secret", "alice")
+	require.NoError(t, err)
+	require.NotContains(t, ciphertext, "secret")
+
+	// every encryption uses a new nonce
+	other, err := c.Encrypt("secret", "alice")
+	require.NoError(t, err)
+	require.NotEqual(t, ciphertext, other)
+
+	plaintext, err := c.Decrypt(ciphertext, "alice")
+	require.NoError(t, err)
+	require.Equal(t, "secret", plaintext)
+
+	_, err = c.Decrypt(ciphertext, "bob")
+	require.ErrorIs(t, err, ErrInvalidCiphertext)
+
+	otherKey, err := NewCipher(util.RandomString(32))
+	require.NoError(t, err)
+	_, err = otherKey.Decrypt(ciphertext, "alice")
+	require.ErrorIs(t, err, ErrInvalidCiphertext)
+
+	_, err = c.Decrypt("not base64!", "alice")
+	require.ErrorIs(t, err, ErrInvalidCiphertext)
+}
+
+func TestNewCipherInvalidKey(t *testing.T) {
+	_, err := NewCipher(util.RandomString(16))
+	require.Error(t, err)
+}
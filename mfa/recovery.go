@@ -0,0 +1,53 @@
+package mfa
+
+import (
+	"crypto/rand"
+	"fmt"
+	"strings"
+)
+
+const (
+	// RecoveryCodeCount is the number of recovery codes generated when 2FA is enabled
+	RecoveryCodeCount     = 10
+	recoveryCodeGroups    = 4
+	recoveryCodeGroupSize = 4
+	recoveryCodeAlphabet  = "abcdefghijklmnopqrstuvwxyz234567"
+)
+
+// GenerateRecoveryCodes returns n random recovery codes such as "k3xq-7hma-2pzd-wn5c".
+// Each code carries 80 bits, so that a fast hash of it can be stored like a token
+func GenerateRecoveryCodes(n int) ([]string, error) {
+	codes := make([]string, n)
+	for i := range codes {
+		b := make([]byte, recoveryCodeGroups*recoveryCodeGroupSize)
+		if _, err := rand.Read(b); err != nil {
+			return nil, fmt.Errorf("cannot generate recovery code: %w", err)
+		}
+
+		var code strings.Builder
+		for j, c := range b {
+			if j > 0 && j%recoveryCodeGroupSize == 0 {
+				code.WriteByte('-')
+			}
+			code.WriteByte(recoveryCodeAlphabet[int(c)%len(recoveryCodeAlphabet)])
+		}
+		codes[i] = code.String()
+	}
+
+	return codes, nil
+}
+
+// NormalizeRecoveryCode returns the code as generated, whatever its case, spacing and dashes
+func NormalizeRecoveryCode(code string) string {
+	code = strings.ToLower(code)
+	code = strings.NewReplacer("-", "", " ", "").Replace(code)
+
+	var normalized strings.Builder
+	for i, c := range code {
+		if i > 0 && i%recoveryCodeGroupSize == 0 {
+			normalized.WriteByte('-')
+		}
+		normalized.WriteRune(c)
+	}
+	return normalized.String()
+}
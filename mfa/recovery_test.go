@@ -0,0 +1,27 @@
+package mfa
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerateRecoveryCodes(t *testing.T) {
+	codes, err := GenerateRecoveryCodes(RecoveryCodeCount)
+	require.NoError(t, err)
+	require.Len(t, codes, RecoveryCodeCount)
+
+	seen := make(map[string]bool)
+	for _, code := range codes {
+		require.Regexp(t, `^[a-z2-7]{4}-[a-z2-7]{4}-[a-z2-7]{4}-[a-z2-7]{4}$`, code)
+		require.False(t, seen[code])
+		seen[code] = true
+
+		require.Equal(t, code, NormalizeRecoveryCode(code))
+	}
+}
+
+func TestNormalizeRecoveryCode(t *testing.T) {
+	require.Equal(t, "k3xq-7hma-2pzd-wn5c", NormalizeRecoveryCode("K3XQ7HMA2PZDWN5C"))
+	require.Equal(t, "k3xq-7hma-2pzd-wn5c", NormalizeRecoveryCode(" k3xq 7hma-2pzd wn5c"))
+}
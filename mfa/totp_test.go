@@ -0,0 +1,81 @@
+package mfa
+
+import (
+	"encoding/base32"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// rfc6238Secret is the SHA1 secret of the test vectors of RFC 6238
+var rfc6238Secret = base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString([]byte("12345678901234567890"))
+
+func TestGenerateTOTP(t *testing.T) {
+	// the RFC gives 8 digits codes, a 6 digits code is their last 6 digits
+	testCases := []struct {
+		unix int64
+		code string
+	}{
+		{unix: 59, code: "287082"},
+		{unix: 1111111109, code: "081804"},
+		{unix: 1111111111, code: "050471"},
+		{unix: 1234567890, code: "005924"},
+		{unix: 2000000000, code: "279037"},
+		{unix: 20000000000, code: "353130"},
+	}
+
+	for _, tc := range testCases {
+		code, err := GenerateTOTP(rfc6238Secret, time.Unix(tc.unix, 0))
+		require.NoError(t, err)
+		require.Equal(t, tc.code, code)
+	}
+}
+
+func TestValidateTOTP(t *testing.T) {
+	secret, err := GenerateTOTPSecret()
+	require.NoError(t, err)
+	require.Len(t, secret, 32)
+
+	now := time.Now()
+	code, err := GenerateTOTP(secret, now)
+	require.NoError(t, err)
+
+	step, ok := ValidateTOTP(secret, code, now)
+	require.True(t, ok)
+	require.Equal(t, TOTPStep(now), step)
+
+	// a code is accepted one step before and after its own to allow for clock drift
+	_, ok = ValidateTOTP(secret, code, now.Add(totpPeriod))
+	require.True(t, ok)
+	_, ok = ValidateTOTP(secret, code, now.Add(-totpPeriod))
+	require.True(t, ok)
+
+	_, ok = ValidateTOTP(secret, code, now.Add(3*totpPeriod))
+	require.False(t, ok)
+
+	otherSecret, err := GenerateTOTPSecret()
+	require.NoError(t, err)
+	_, ok = ValidateTOTP(otherSecret, code, now)
+	require.False(t, ok)
+
+	_, ok = ValidateTOTP(secret, "12345", now)
+	require.False(t, ok)
+	_, ok = ValidateTOTP("not base32!", code, now)
+	require.False(t, ok)
+}
+
+func TestTOTPURI(t *testing.T) {
+	uri := TOTPURI("Simple Bank", "alice", "JBSWY3DPEHPK3PXP")
+
+	parsed, err := url.Parse(uri)
+	require.NoError(t, err)
+	require.Equal(t, "otpauth", parsed.Scheme)
+	require.Equal(t, "totp", parsed.Host)
+	require.Equal(t, "/Simple Bank:alice", parsed.Path)
+	require.Equal(t, "JBSWY3DPEHPK3PXP", parsed.Query().Get("secret"))
+	require.Equal(t, "Simple Bank", parsed.Query().Get("issuer"))
+	require.Equal(t, "6", parsed.Query().Get("digits"))
+	require.Equal(t, "30", parsed.Query().Get("period"))
+}
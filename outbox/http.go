@@ -0,0 +1,66 @@
+package outbox
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	db "github.com/roman-adamchik/simplebank/db/sqlc"
+)
+
+const defaultHTTPTimeout = 10 * time.Second
+
+// HTTPPublisher posts events as JSON messages to a webhook URL
+type HTTPPublisher struct {
+	url    string
+	client *http.Client
+}
+
+// NewHTTPPublisher creates a new HTTPPublisher posting to the given URL with a request timeout
+func NewHTTPPublisher(url string, timeout time.Duration) (*HTTPPublisher, error) {
+	if url == "" {
+		return nil, errors.New("event webhook URL is not set")
+	}
+	if timeout <= 0 {
+		timeout = defaultHTTPTimeout
+	}
+
+	return &HTTPPublisher{
+		url:    url,
+		client: &http.Client{Timeout: timeout},
+	}, nil
+}
+
+// Publish posts the event, any response other than 2xx is an error
+func (publisher *HTTPPublisher) Publish(ctx context.Context, event db.OutboxEvent) error {
+	body, err := json.Marshal(NewMessage(event))
+	if err != nil {
+		return err
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, publisher.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("Idempotency-Key", strconv.FormatInt(event.ID, 10))
+
+	response, err := publisher.client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	_, _ = io.Copy(io.Discard, response.Body)
+
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return fmt.Errorf("webhook responded with status %d", response.StatusCode)
+	}
+
+	return nil
+}
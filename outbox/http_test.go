@@ -0,0 +1,80 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	db "github.com/roman-adamchik/simplebank/db/sqlc"
+	"github.com/roman-adamchik/simplebank/util"
+	"github.com/stretchr/testify/require"
+)
+
+func randomEvent() db.OutboxEvent {
+	return db.OutboxEvent{
+		ID:            util.RandomInt(1, 1000),
+		AggregateType: db.AggregateAccount,
+		AggregateID:   "42",
+		EventType:     db.EventAccountCreated,
+		Payload:       []byte(`{"id":42,"owner":"` + util.RandomOwner() + `"}`),
+		CreatedAt:     pgtype.Timestamptz{Time: time.Now().UTC().Truncate(time.Microsecond), Valid: true},
+	}
+}
+
+func TestHTTPPublisher(t *testing.T) {
+	event := randomEvent()
+
+	var got Message
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, http.MethodPost, r.Method)
+		require.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		require.NotEmpty(t, r.Header.Get("Idempotency-Key"))
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&got))
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+
+	publisher, err := NewHTTPPublisher(server.URL, time.Second)
+	require.NoError(t, err)
+
+	err = publisher.Publish(context.Background(), event)
+	require.NoError(t, err)
+	require.Equal(t, event.ID, got.ID)
+	require.Equal(t, event.EventType, got.EventType)
+	require.Equal(t, event.AggregateID, got.AggregateID)
+	require.JSONEq(t, string(event.Payload), string(got.Payload))
+	require.True(t, event.CreatedAt.Time.Equal(got.CreatedAt))
+}
+
+func TestHTTPPublisherErrorStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	publisher, err := NewHTTPPublisher(server.URL, time.Second)
+	require.NoError(t, err)
+
+	err = publisher.Publish(context.Background(), randomEvent())
+	require.ErrorContains(t, err, "503")
+}
+
+func TestNewPublisher(t *testing.T) {
+	publisher, err := NewPublisher(util.Config{})
+	require.NoError(t, err)
+	require.IsType(t, &LogPublisher{}, publisher)
+
+	_, err = NewPublisher(util.Config{EventPublisher: PublisherHTTP})
+	require.Error(t, err)
+
+	publisher, err = NewPublisher(util.Config{EventPublisher: PublisherHTTP, EventHTTPURL: "http://localhost/events"})
+	require.NoError(t, err)
+	require.IsType(t, &HTTPPublisher{}, publisher)
+
+	_, err = NewPublisher(util.Config{EventPublisher: "kafka"})
+	require.Error(t, err)
+}
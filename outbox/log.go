@@ -0,0 +1,22 @@
+package outbox
+
+import (
+	"context"
+	"log"
+
+	db "github.com/roman-adamchik/simplebank/db/sqlc"
+)
+
+// LogPublisher writes events to the log, it is meant for development
+type LogPublisher struct{}
+
+// NewLogPublisher creates a new LogPublisher
+func NewLogPublisher() *LogPublisher {
+	return &LogPublisher{}
+}
+
+// Publish logs the event
+func (publisher *LogPublisher) Publish(ctx context.Context, event db.OutboxEvent) error {
+	log.Printf("event %d %s %s/%s: %s", event.ID, event.EventType, event.AggregateType, event.AggregateID, event.Payload)
+	return nil
+}
@@ -0,0 +1,61 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	db "github.com/roman-adamchik/simplebank/db/sqlc"
+	"github.com/roman-adamchik/simplebank/util"
+)
+
+// list of publishers events can be delivered with
+const (
+	PublisherLog   = "log"
+	PublisherHTTP  = "http"
+	PublisherRedis = "redis"
+)
+
+// Publisher delivers outbox events to other services
+type Publisher interface {
+	Publish(ctx context.Context, event db.OutboxEvent) error
+}
+
+// Message is the representation of an outbox event published to other services.
+// Consumers should use the id to drop events delivered more than once, and cannot rely on the order of ids
+// across aggregates, see db.SQLStore.RelayOutbox
+type Message struct {
+	ID            int64           `json:"id"`
+	AggregateType string          `json:"aggregate_type"`
+	AggregateID   string          `json:"aggregate_id"`
+	EventType     string          `json:"event_type"`
+	Payload       json.RawMessage `json:"payload"`
+	CreatedAt     time.Time       `json:"created_at"`
+}
+
+// NewMessage creates the message of an outbox event
+func NewMessage(event db.OutboxEvent) Message {
+	return Message{
+		ID:            event.ID,
+		AggregateType: event.AggregateType,
+		AggregateID:   event.AggregateID,
+		EventType:     event.EventType,
+		Payload:       event.Payload,
+		CreatedAt:     event.CreatedAt.Time,
+	}
+}
+
+// NewPublisher creates the publisher selected by the config, events are logged by default
+func NewPublisher(config util.Config) (Publisher, error) {
+	switch config.EventPublisher {
+	case "", PublisherLog:
+		return NewLogPublisher(), nil
+	case PublisherHTTP:
+		return NewHTTPPublisher(config.EventHTTPURL, 0)
+	case PublisherRedis:
+		return NewRedisStreamPublisher(config.RedisAddress, config.EventStream)
+	}
+
+	return nil, fmt.Errorf("unsupported event publisher %q", config.EventPublisher)
+}
@@ -0,0 +1,51 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	db "github.com/roman-adamchik/simplebank/db/sqlc"
+)
+
+const defaultEventStream = "simplebank:events"
+
+// RedisStreamPublisher appends events to a Redis stream
+type RedisStreamPublisher struct {
+	client *redis.Client
+	stream string
+}
+
+// NewRedisStreamPublisher creates a new RedisStreamPublisher appending to the given stream
+func NewRedisStreamPublisher(address string, stream string) (*RedisStreamPublisher, error) {
+	if address == "" {
+		return nil, errors.New("redis address is not set")
+	}
+	if stream == "" {
+		stream = defaultEventStream
+	}
+
+	return &RedisStreamPublisher{
+		client: redis.NewClient(&redis.Options{Addr: address}),
+		stream: stream,
+	}, nil
+}
+
+// Publish adds the event to the stream, one field per attribute of the message
+func (publisher *RedisStreamPublisher) Publish(ctx context.Context, event db.OutboxEvent) error {
+	message := NewMessage(event)
+
+	return publisher.client.XAdd(ctx, &redis.XAddArgs{
+		Stream: publisher.stream,
+		Values: map[string]any{
+			"id":             strconv.FormatInt(message.ID, 10),
+			"aggregate_type": message.AggregateType,
+			"aggregate_id":   message.AggregateID,
+			"event_type":     message.EventType,
+			"payload":        string(message.Payload),
+			"created_at":     message.CreatedAt.Format(time.RFC3339Nano),
+		},
+	}).Err()
+}
@@ -0,0 +1,31 @@
+package outbox
+
+import (
+	"context"
+	"strconv"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRedisStreamPublisher(t *testing.T) {
+	redisServer := miniredis.RunT(t)
+
+	publisher, err := NewRedisStreamPublisher(redisServer.Addr(), "")
+	require.NoError(t, err)
+
+	event := randomEvent()
+	err = publisher.Publish(context.Background(), event)
+	require.NoError(t, err)
+
+	entries, err := publisher.client.XRange(context.Background(), defaultEventStream, "-", "+").Result()
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+
+	values := entries[0].Values
+	require.Equal(t, strconv.FormatInt(event.ID, 10), values["id"])
+	require.Equal(t, event.EventType, values["event_type"])
+	require.Equal(t, event.AggregateType, values["aggregate_type"])
+	require.Equal(t, string(event.Payload), values["payload"])
+}
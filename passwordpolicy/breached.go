@@ -0,0 +1,67 @@
+package passwordpolicy
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// breachedPrefixLength is the number of hex characters of the SHA-1 of a password naming its range file
+const breachedPrefixLength = 5
+
+// BreachedList is a local copy of a breached password list split in k-anonymity ranges, as served by the
+// range API of Have I Been Pwned and saved by its downloader: the file <PREFIX>.txt holds a "SUFFIX:COUNT" line
+// for every breached password whose uppercase hex SHA-1 starts with the 5 characters prefix.
+// Only the range of a password is read, so the list can be far larger than the memory
+type BreachedList struct {
+	dir string
+}
+
+// NewBreachedList creates a new BreachedList reading the range files of a directory
+func NewBreachedList(dir string) (*BreachedList, error) {
+	info, err := os.Stat(dir)
+	if err != nil {
+		return nil, fmt.Errorf("cannot open breached password list: %w", err)
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("breached password list %s is not a directory", dir)
+	}
+
+	return &BreachedList{dir: dir}, nil
+}
+
+// Contains tells whether a password is in the list
+func (list *BreachedList) Contains(password string) (bool, error) {
+	sum := sha1.Sum([]byte(password))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+	prefix, suffix := hash[:breachedPrefixLength], hash[breachedPrefixLength:]
+
+	file, err := os.Open(filepath.Join(list.dir, prefix+".txt"))
+	if err != nil {
+		// a range without a file has no breached password
+		if errors.Is(err, fs.ErrNotExist) {
+			return false, nil
+		}
+		return false, fmt.Errorf("cannot read breached password list: %w", err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		lineSuffix, _, _ := strings.Cut(scanner.Text(), ":")
+		if strings.EqualFold(strings.TrimSpace(lineSuffix), suffix) {
+			return true, nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return false, fmt.Errorf("cannot read breached password list: %w", err)
+	}
+
+	return false, nil
+}
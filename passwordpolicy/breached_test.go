@@ -0,0 +1,55 @@
+package passwordpolicy
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/roman-adamchik/simplebank/util"
+	"github.com/stretchr/testify/require"
+)
+
+// writeBreachedRange writes the range file of "password", whose SHA-1 is 5BAA61E4C9B93F3F0682250B6CF8331B7EE68FD8
+func writeBreachedRange(t *testing.T, dir string) {
+	t.Helper()
+
+	content := "0018A45C4D1DEF81644B54AB7F969B88D65:1\r\n" +
+		"1E4C9B93F3F0682250B6CF8331B7EE68FD8:10434004\r\n" +
+		"1E4CDB93F3F0682250B6CF8331B7EE68FD9:2\r\n"
+	err := os.WriteFile(filepath.Join(dir, "5BAA6.txt"), []byte(content), 0o600)
+	require.NoError(t, err)
+}
+
+func TestBreachedList(t *testing.T) {
+	dir := t.TempDir()
+	writeBreachedRange(t, dir)
+
+	list, err := NewBreachedList(dir)
+	require.NoError(t, err)
+
+	breached, err := list.Contains("password")
+	require.NoError(t, err)
+	require.True(t, breached)
+
+	// a range without a file has no breached password
+	breached, err = list.Contains("Correct-Horse-Battery-9")
+	require.NoError(t, err)
+	require.False(t, breached)
+
+	_, err = NewBreachedList(filepath.Join(dir, "5BAA6.txt"))
+	require.Error(t, err)
+}
+
+func TestPolicyBreached(t *testing.T) {
+	dir := t.TempDir()
+	writeBreachedRange(t, dir)
+
+	policy, err := New(util.Config{BreachedPasswordsDir: dir})
+	require.NoError(t, err)
+
+	err = policy.Check("password", "alice", "alice@example.com")
+	requireViolations(t, err, RuleBreached)
+
+	err = policy.Check("Correct-Horse-Battery-9", "alice", "alice@example.com")
+	require.NoError(t, err)
+}
@@ -0,0 +1,189 @@
+package passwordpolicy
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/roman-adamchik/simplebank/util"
+)
+
+const (
+	defaultMinLength = 8
+	defaultMaxLength = 128
+	// bcryptMaxLength is the length in bytes after which bcrypt ignores the rest of a password
+	bcryptMaxLength = 72
+	// minUserInfoLength is the length from which a username or email must not appear in a password,
+	// shorter ones would refuse too many passwords by chance
+	minUserInfoLength = 3
+)
+
+// list of character classes a password can be required to contain
+const (
+	ClassLower  = "lower"
+	ClassUpper  = "upper"
+	ClassDigit  = "digit"
+	ClassSymbol = "symbol"
+)
+
+// list of rules a password can break
+const (
+	RuleMinLength = "min_length"
+	RuleMaxLength = "max_length"
+	RuleLower     = "lower"
+	RuleUpper     = "upper"
+	RuleDigit     = "digit"
+	RuleSymbol    = "symbol"
+	RuleUsername  = "username"
+	RuleEmail     = "email"
+	RuleBreached  = "breached"
+)
+
+var classes = map[string]struct {
+	rule    string
+	message string
+	matches func(rune) bool
+}{
+	ClassLower:  {rule: RuleLower, message: "must contain a lowercase letter", matches: unicode.IsLower},
+	ClassUpper:  {rule: RuleUpper, message: "must contain an uppercase letter", matches: unicode.IsUpper},
+	ClassDigit:  {rule: RuleDigit, message: "must contain a digit", matches: unicode.IsDigit},
+	ClassSymbol: {rule: RuleSymbol, message: "must contain a symbol", matches: isSymbol},
+}
+
+func isSymbol(r rune) bool {
+	return !unicode.IsLetter(r) && !unicode.IsDigit(r) && !unicode.IsSpace(r)
+}
+
+// Violation is a rule a password breaks
+type Violation struct {
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+// Error lists every rule a password breaks, so that they can all be fixed at once
+type Error struct {
+	Violations []Violation
+}
+
+func (e *Error) Error() string {
+	messages := make([]string, len(e.Violations))
+	for i, violation := range e.Violations {
+		messages[i] = violation.Message
+	}
+	return "password " + strings.Join(messages, ", ")
+}
+
+// Policy is the set of rules passwords must follow
+type Policy struct {
+	minLength int
+	maxLength int
+	// maxBytes is the length in bytes the hasher reads of a password, 0 if it reads all of it
+	maxBytes        int
+	requiredClasses []string
+	// breached is nil if passwords are not checked against a breached password list
+	breached *BreachedList
+}
+
+// New creates the password policy set by the config
+func New(config util.Config) (*Policy, error) {
+	policy := &Policy{
+		minLength: config.PasswordMinLength,
+		maxLength: config.PasswordMaxLength,
+	}
+	if policy.minLength <= 0 {
+		policy.minLength = defaultMinLength
+	}
+	if policy.maxLength <= 0 {
+		policy.maxLength = defaultMaxLength
+	}
+	if config.PasswordHasher == util.PasswordBcrypt {
+		policy.maxLength = min(policy.maxLength, bcryptMaxLength)
+		policy.maxBytes = bcryptMaxLength
+	}
+	if policy.minLength > policy.maxLength {
+		return nil, fmt.Errorf("password min length %d is over the max length %d", policy.minLength, policy.maxLength)
+	}
+
+	for _, class := range config.PasswordRequiredClasses {
+		if _, ok := classes[class]; !ok {
+			return nil, fmt.Errorf("unknown password character class %q", class)
+		}
+		policy.requiredClasses = append(policy.requiredClasses, class)
+	}
+
+	if config.BreachedPasswordsDir != "" {
+		breached, err := NewBreachedList(config.BreachedPasswordsDir)
+		if err != nil {
+			return nil, err
+		}
+		policy.breached = breached
+	}
+
+	return policy, nil
+}
+
+// Check returns an *Error listing the rules the password of a user breaks, or nil if it follows them all.
+// Any other error means the breached password list could not be read
+func (policy *Policy) Check(password string, username string, email string) error {
+	var violations []Violation
+
+	length := utf8.RuneCountInString(password)
+	if length < policy.minLength {
+		violations = append(violations, Violation{
+			Rule:    RuleMinLength,
+			Message: fmt.Sprintf("must be at least %d characters long", policy.minLength),
+		})
+	}
+	// characters outside ASCII take several bytes, so a password can fit the max length but not the hasher
+	switch {
+	case length > policy.maxLength:
+		violations = append(violations, Violation{
+			Rule:    RuleMaxLength,
+			Message: fmt.Sprintf("must be at most %d characters long", policy.maxLength),
+		})
+	case policy.maxBytes > 0 && len(password) > policy.maxBytes:
+		violations = append(violations, Violation{
+			Rule:    RuleMaxLength,
+			Message: fmt.Sprintf("must be at most %d bytes long", policy.maxBytes),
+		})
+	}
+
+	for _, name := range policy.requiredClasses {
+		class := classes[name]
+		if !strings.ContainsFunc(password, class.matches) {
+			violations = append(violations, Violation{Rule: class.rule, Message: class.message})
+		}
+	}
+
+	lowerPassword := strings.ToLower(password)
+	if containsUserInfo(lowerPassword, username) {
+		violations = append(violations, Violation{Rule: RuleUsername, Message: "must not contain the username"})
+	}
+	localPart, _, _ := strings.Cut(email, "@")
+	if containsUserInfo(lowerPassword, localPart) {
+		violations = append(violations, Violation{Rule: RuleEmail, Message: "must not contain the email address"})
+	}
+
+	if policy.breached != nil {
+		breached, err := policy.breached.Contains(password)
+		if err != nil {
+			return err
+		}
+		if breached {
+			violations = append(violations, Violation{
+				Rule:    RuleBreached,
+				Message: "must not be a password known from a data breach",
+			})
+		}
+	}
+
+	if len(violations) > 0 {
+		return &Error{Violations: violations}
+	}
+	return nil
+}
+
+func containsUserInfo(lowerPassword string, info string) bool {
+	return len(info) >= minUserInfoLength && strings.Contains(lowerPassword, strings.ToLower(info))
+}
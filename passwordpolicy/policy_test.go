@@ -0,0 +1,122 @@
+package passwordpolicy
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/roman-adamchik/simplebank/util"
+	"github.com/stretchr/testify/require"
+)
+
+func requireViolations(t *testing.T, err error, rules ...string) {
+	t.Helper()
+
+	if len(rules) == 0 {
+		require.NoError(t, err)
+		return
+	}
+
+	var policyErr *Error
+	require.ErrorAs(t, err, &policyErr)
+
+	got := make([]string, len(policyErr.Violations))
+	for i, violation := range policyErr.Violations {
+		got[i] = violation.Rule
+	}
+	require.Equal(t, rules, got)
+}
+
+func TestPolicyCheck(t *testing.T) {
+	policy, err := New(util.Config{
+		PasswordMinLength:       8,
+		PasswordMaxLength:       20,
+		PasswordRequiredClasses: []string{ClassLower, ClassUpper, ClassDigit, ClassSymbol},
+	})
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name     string
+		password string
+		rules    []string
+	}{
+		{name: "OK", password: "Correct-Horse-9"},
+		{name: "TooShort", password: "Ab1-", rules: []string{RuleMinLength}},
+		{name: "TooLong", password: "Ab1-" + strings.Repeat("a", 20), rules: []string{RuleMaxLength}},
+		{name: "NoLower", password: "CORRECT-HORSE-9", rules: []string{RuleLower}},
+		{name: "NoUpper", password: "correct-horse-9", rules: []string{RuleUpper}},
+		{name: "NoDigit", password: "Correct-Horse", rules: []string{RuleDigit}},
+		{name: "NoSymbol", password: "CorrectHorse9", rules: []string{RuleSymbol}},
+		{name: "Unicode", password: "Ünïcödé-Ñ9"},
+		{name: "Username", password: "My-Alice-Pass-9", rules: []string{RuleUsername}},
+		{name: "Email", password: "Mr-ASmith-Pass9", rules: []string{RuleEmail}},
+		{name: "Several", password: "alice", rules: []string{RuleMinLength, RuleUpper, RuleDigit, RuleSymbol, RuleUsername}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := policy.Check(tc.password, "alice", "asmith@example.com")
+			requireViolations(t, err, tc.rules...)
+		})
+	}
+}
+
+func TestPolicyBcryptMultibyte(t *testing.T) {
+	policy, err := New(util.Config{PasswordHasher: util.PasswordBcrypt})
+	require.NoError(t, err)
+
+	// 40 characters of two bytes each fit the max length but not the 72 bytes bcrypt reads
+	password := strings.Repeat("é", 40)
+	require.Equal(t, 40, utf8.RuneCountInString(password))
+	err = policy.Check(password, "alice", "asmith@example.com")
+	requireViolations(t, err, RuleMaxLength)
+	require.EqualError(t, err, "password must be at most 72 bytes long")
+
+	err = policy.Check(strings.Repeat("é", 36), "alice", "asmith@example.com")
+	require.NoError(t, err)
+
+	// the min length still counts characters
+	err = policy.Check(strings.Repeat("é", 7), "alice", "asmith@example.com")
+	requireViolations(t, err, RuleMinLength)
+}
+
+func TestPolicyShortUserInfo(t *testing.T) {
+	policy, err := New(util.Config{})
+	require.NoError(t, err)
+
+	// too short a username or email would refuse passwords by chance
+	err = policy.Check("correct-horse-ab", "ab", "ho@example.com")
+	require.NoError(t, err)
+}
+
+func TestPolicyErrorMessage(t *testing.T) {
+	policy, err := New(util.Config{PasswordRequiredClasses: []string{ClassDigit}})
+	require.NoError(t, err)
+
+	err = policy.Check("short", "alice", "alice@example.com")
+	require.EqualError(t, err, "password must be at least 8 characters long, must contain a digit")
+}
+
+func TestNewPolicy(t *testing.T) {
+	policy, err := New(util.Config{})
+	require.NoError(t, err)
+	require.Equal(t, defaultMinLength, policy.minLength)
+	require.Equal(t, defaultMaxLength, policy.maxLength)
+	require.Empty(t, policy.requiredClasses)
+	require.Nil(t, policy.breached)
+
+	// bcrypt ignores what comes after 72 bytes
+	policy, err = New(util.Config{PasswordHasher: util.PasswordBcrypt})
+	require.NoError(t, err)
+	require.Equal(t, bcryptMaxLength, policy.maxLength)
+	require.Equal(t, bcryptMaxLength, policy.maxBytes)
+
+	_, err = New(util.Config{PasswordRequiredClasses: []string{"emoji"}})
+	require.ErrorContains(t, err, "emoji")
+
+	_, err = New(util.Config{PasswordMinLength: 30, PasswordMaxLength: 20})
+	require.Error(t, err)
+
+	_, err = New(util.Config{BreachedPasswordsDir: t.TempDir() + "/missing"})
+	require.Error(t, err)
+}
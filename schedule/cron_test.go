@@ -0,0 +1,119 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseCron(t *testing.T) {
+	testCases := []struct {
+		name  string
+		expr  string
+		after time.Time
+		want  time.Time
+	}{
+		{
+			name:  "EveryMinute",
+			expr:  "* * * * *",
+			after: date(2024, time.March, 10, 10, 15),
+			want:  date(2024, time.March, 10, 10, 16),
+		},
+		{
+			name:  "DailyAtTime",
+			expr:  "30 9 * * *",
+			after: date(2024, time.March, 10, 10, 0),
+			want:  date(2024, time.March, 11, 9, 30),
+		},
+		{
+			name:  "Step",
+			expr:  "*/15 * * * *",
+			after: date(2024, time.March, 10, 10, 16),
+			want:  date(2024, time.March, 10, 10, 30),
+		},
+		{
+			name:  "WeekdaysRange",
+			expr:  "0 8 * * 1-5",
+			after: date(2024, time.March, 8, 9, 0), // Friday
+			want:  date(2024, time.March, 11, 8, 0),
+		},
+		{
+			name:  "SundayAsSeven",
+			expr:  "0 0 * * 7",
+			after: date(2024, time.March, 11, 0, 0),
+			want:  date(2024, time.March, 17, 0, 0),
+		},
+		{
+			name:  "List",
+			expr:  "0 12 1,15 * *",
+			after: date(2024, time.March, 2, 0, 0),
+			want:  date(2024, time.March, 15, 12, 0),
+		},
+		{
+			name:  "DayOfMonthOrDayOfWeek",
+			expr:  "0 0 20 * 1",
+			after: date(2024, time.March, 12, 0, 0),
+			want:  date(2024, time.March, 18, 0, 0), // Monday comes before the 20th
+		},
+		{
+			name:  "MonthWrap",
+			expr:  "0 0 1 1 *",
+			after: date(2024, time.March, 1, 0, 0),
+			want:  date(2025, time.January, 1, 0, 0),
+		},
+		{
+			name:  "LeapDay",
+			expr:  "0 0 29 2 *",
+			after: date(2024, time.March, 1, 0, 0),
+			want:  date(2028, time.February, 29, 0, 0),
+		},
+		{
+			name:  "Never",
+			expr:  "0 0 30 2 *",
+			after: date(2024, time.March, 1, 0, 0),
+			want:  time.Time{},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			s, err := ParseCron(tc.expr, time.UTC)
+			require.NoError(t, err)
+			require.Equal(t, tc.want, s.Next(tc.after))
+		})
+	}
+}
+
+func TestParseCronFirst(t *testing.T) {
+	s, err := ParseCron("0 9 * * *", nil)
+	require.NoError(t, err)
+
+	start := date(2024, time.March, 10, 9, 0)
+	require.Equal(t, start, First(s, start))
+	require.Equal(t, date(2024, time.March, 11, 9, 0), First(s, start.Add(time.Second)))
+}
+
+func TestParseCronInvalid(t *testing.T) {
+	expressions := []string{
+		"",
+		"* * * *",
+		"* * * * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"5-1 * * * *",
+		"*/0 * * * *",
+		"a * * * *",
+	}
+
+	for _, expr := range expressions {
+		s, err := ParseCron(expr, time.UTC)
+		require.Error(t, err, expr)
+		require.Nil(t, s)
+	}
+}
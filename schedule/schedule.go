@@ -0,0 +1,101 @@
+package schedule
+
+import (
+	"fmt"
+	"time"
+)
+
+// Schedule describes a recurring sequence of points in time
+type Schedule interface {
+	// Next returns the first occurrence strictly after the given time
+	Next(after time.Time) time.Time
+}
+
+// First returns the first occurrence of the schedule at or after start
+func First(s Schedule, start time.Time) time.Time {
+	return s.Next(start.Add(-time.Nanosecond))
+}
+
+// weeklySchedule repeats every n weeks on the weekday and time of day of its anchor
+type weeklySchedule struct {
+	anchor time.Time
+	every  int
+}
+
+// Weekly returns a schedule repeating every n weeks, starting at anchor
+func Weekly(anchor time.Time, every int) (Schedule, error) {
+	if every < 1 {
+		return nil, fmt.Errorf("invalid interval: must be at least 1, got %d", every)
+	}
+
+	return &weeklySchedule{anchor: anchor, every: every}, nil
+}
+
+// Next returns the first occurrence strictly after the given time
+func (s *weeklySchedule) Next(after time.Time) time.Time {
+	if after.Before(s.anchor) {
+		return s.anchor
+	}
+
+	period := time.Duration(s.every) * 7 * 24 * time.Hour
+	n := int(after.Sub(s.anchor) / period)
+
+	for {
+		next := s.anchor.AddDate(0, 0, 7*s.every*n)
+		if next.After(after) {
+			return next
+		}
+		n++
+	}
+}
+
+// monthlySchedule repeats every n months on the day of month and time of day of its anchor.
+// In months that do not have the anchor day, the last day of the month is used instead.
+type monthlySchedule struct {
+	anchor time.Time
+	every  int
+}
+
+// Monthly returns a schedule repeating every n months, starting at anchor
+func Monthly(anchor time.Time, every int) (Schedule, error) {
+	if every < 1 {
+		return nil, fmt.Errorf("invalid interval: must be at least 1, got %d", every)
+	}
+
+	return &monthlySchedule{anchor: anchor, every: every}, nil
+}
+
+// Next returns the first occurrence strictly after the given time
+func (s *monthlySchedule) Next(after time.Time) time.Time {
+	if after.Before(s.anchor) {
+		return s.anchor
+	}
+
+	after = after.In(s.anchor.Location())
+	months := (after.Year()-s.anchor.Year())*12 + int(after.Month()-s.anchor.Month())
+	n := months / s.every
+
+	for {
+		next := s.occurrence(n)
+		if next.After(after) {
+			return next
+		}
+		n++
+	}
+}
+
+// occurrence returns the n-th occurrence of the schedule, counting from zero
+func (s *monthlySchedule) occurrence(n int) time.Time {
+	// the first day of the target month avoids time.Date normalising e.g. Feb 31 into March
+	first := time.Date(s.anchor.Year(), s.anchor.Month()+time.Month(n*s.every), 1,
+		s.anchor.Hour(), s.anchor.Minute(), s.anchor.Second(), s.anchor.Nanosecond(), s.anchor.Location())
+
+	day := min(s.anchor.Day(), daysIn(first.Year(), first.Month()))
+
+	return first.AddDate(0, 0, day-1)
+}
+
+// daysIn returns the number of days in the given month
+func daysIn(year int, month time.Month) int {
+	return time.Date(year, month+1, 0, 0, 0, 0, 0, time.UTC).Day()
+}
@@ -0,0 +1,81 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func date(year int, month time.Month, day, hour, minute int) time.Time {
+	return time.Date(year, month, day, hour, minute, 0, 0, time.UTC)
+}
+
+func TestWeekly(t *testing.T) {
+	anchor := date(2024, time.January, 3, 9, 30) // Wednesday
+
+	s, err := Weekly(anchor, 2)
+	require.NoError(t, err)
+
+	require.Equal(t, anchor, First(s, anchor))
+	require.Equal(t, anchor, s.Next(anchor.Add(-time.Hour)))
+	require.Equal(t, date(2024, time.January, 17, 9, 30), s.Next(anchor))
+	require.Equal(t, date(2024, time.January, 31, 9, 30), s.Next(date(2024, time.January, 20, 0, 0)))
+	require.Equal(t, time.Wednesday, s.Next(date(2025, time.June, 1, 0, 0)).Weekday())
+}
+
+func TestWeeklyInvalidInterval(t *testing.T) {
+	s, err := Weekly(time.Now(), 0)
+	require.Error(t, err)
+	require.Nil(t, s)
+}
+
+func TestMonthly(t *testing.T) {
+	anchor := date(2024, time.January, 15, 8, 0)
+
+	s, err := Monthly(anchor, 1)
+	require.NoError(t, err)
+
+	require.Equal(t, anchor, First(s, anchor))
+	require.Equal(t, date(2024, time.February, 15, 8, 0), s.Next(anchor))
+	require.Equal(t, date(2024, time.March, 15, 8, 0), s.Next(date(2024, time.February, 15, 8, 0)))
+	require.Equal(t, date(2025, time.January, 15, 8, 0), s.Next(date(2024, time.December, 20, 0, 0)))
+}
+
+func TestMonthlyShortMonths(t *testing.T) {
+	anchor := date(2024, time.January, 31, 12, 0)
+
+	s, err := Monthly(anchor, 1)
+	require.NoError(t, err)
+
+	expected := []time.Time{
+		date(2024, time.February, 29, 12, 0), // leap year
+		date(2024, time.March, 31, 12, 0),
+		date(2024, time.April, 30, 12, 0),
+		date(2024, time.May, 31, 12, 0),
+	}
+
+	next := anchor
+	for _, want := range expected {
+		next = s.Next(next)
+		require.Equal(t, want, next)
+	}
+
+	require.Equal(t, date(2025, time.February, 28, 12, 0), s.Next(date(2025, time.February, 1, 0, 0)))
+}
+
+func TestMonthlyEveryQuarter(t *testing.T) {
+	anchor := date(2024, time.November, 30, 0, 0)
+
+	s, err := Monthly(anchor, 3)
+	require.NoError(t, err)
+
+	require.Equal(t, date(2025, time.February, 28, 0, 0), s.Next(anchor))
+	require.Equal(t, date(2025, time.May, 30, 0, 0), s.Next(date(2025, time.February, 28, 0, 0)))
+}
+
+func TestMonthlyInvalidInterval(t *testing.T) {
+	s, err := Monthly(time.Now(), -1)
+	require.Error(t, err)
+	require.Nil(t, s)
+}
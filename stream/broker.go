@@ -0,0 +1,108 @@
+package stream
+
+import (
+	"sync"
+
+	db "github.com/roman-adamchik/simplebank/db/sqlc"
+)
+
+// defaultBufferSize is how many updates a subscriber can fall behind before it is dropped
+const defaultBufferSize = 64
+
+// Broker fans account updates out to the subscribers of the account owners
+type Broker struct {
+	mu          sync.Mutex
+	subscribers map[string]map[*Subscription]struct{}
+	bufferSize  int
+}
+
+// NewBroker creates a new Broker
+func NewBroker() *Broker {
+	return &Broker{
+		subscribers: make(map[string]map[*Subscription]struct{}),
+		bufferSize:  defaultBufferSize,
+	}
+}
+
+// Subscription receives the updates of the accounts of an owner.
+// Its channel is closed when the subscription is dropped, after which the subscriber should
+// catch up on what it missed from the database
+type Subscription struct {
+	C      <-chan db.AccountUpdate
+	ch     chan db.AccountUpdate
+	owner  string
+	broker *Broker
+}
+
+// Subscribe subscribes to the updates of the accounts of an owner
+func (broker *Broker) Subscribe(owner string) *Subscription {
+	ch := make(chan db.AccountUpdate, broker.bufferSize)
+	sub := &Subscription{
+		C:      ch,
+		ch:     ch,
+		owner:  owner,
+		broker: broker,
+	}
+
+	broker.mu.Lock()
+	defer broker.mu.Unlock()
+
+	if broker.subscribers[owner] == nil {
+		broker.subscribers[owner] = make(map[*Subscription]struct{})
+	}
+	broker.subscribers[owner][sub] = struct{}{}
+
+	return sub
+}
+
+// Close unsubscribes, it is safe to call more than once
+func (sub *Subscription) Close() {
+	sub.broker.mu.Lock()
+	defer sub.broker.mu.Unlock()
+
+	sub.broker.drop(sub)
+}
+
+// Publish sends an update to the subscribers of the account owner without blocking.
+// A subscriber whose buffer is full is dropped instead of missing the update silently
+func (broker *Broker) Publish(update db.AccountUpdate) {
+	broker.mu.Lock()
+	defer broker.mu.Unlock()
+
+	for sub := range broker.subscribers[update.Account.Owner] {
+		select {
+		case sub.ch <- update:
+		default:
+			broker.drop(sub)
+		}
+	}
+}
+
+// DropAll drops every subscription, so subscribers catch up after updates may have been missed
+func (broker *Broker) DropAll() {
+	broker.mu.Lock()
+	defer broker.mu.Unlock()
+
+	for _, subs := range broker.subscribers {
+		for sub := range subs {
+			broker.drop(sub)
+		}
+	}
+}
+
+// drop removes a subscription and closes its channel, the caller must hold the lock
+func (broker *Broker) drop(sub *Subscription) {
+	subs, ok := broker.subscribers[sub.owner]
+	if !ok {
+		return
+	}
+	if _, ok := subs[sub]; !ok {
+		return
+	}
+
+	delete(subs, sub)
+	if len(subs) == 0 {
+		delete(broker.subscribers, sub.owner)
+	}
+	close(sub.ch)
+}
@@ -0,0 +1,98 @@
+package stream
+
+import (
+	"testing"
+
+	db "github.com/roman-adamchik/simplebank/db/sqlc"
+	"github.com/roman-adamchik/simplebank/util"
+	"github.com/stretchr/testify/require"
+)
+
+func randomUpdate(owner string) db.AccountUpdate {
+	account := db.Account{
+		ID:       util.RandomInt(1, 1000),
+		Owner:    owner,
+		Balance:  util.RandomMoney(),
+		Currency: util.USD,
+	}
+
+	return db.AccountUpdate{
+		Entry: db.Entry{
+			ID:        util.RandomInt(1, 1000),
+			AccountID: account.ID,
+			Amount:    util.RandomMoney(),
+		},
+		Account: account,
+	}
+}
+
+func TestBrokerPublish(t *testing.T) {
+	broker := NewBroker()
+	owner := util.RandomOwner()
+
+	sub1 := broker.Subscribe(owner)
+	defer sub1.Close()
+	sub2 := broker.Subscribe(owner)
+	defer sub2.Close()
+	other := broker.Subscribe(util.RandomOwner())
+	defer other.Close()
+
+	update := randomUpdate(owner)
+	broker.Publish(update)
+
+	require.Equal(t, update, <-sub1.C)
+	require.Equal(t, update, <-sub2.C)
+	require.Empty(t, other.C)
+}
+
+func TestBrokerDropsSlowSubscriber(t *testing.T) {
+	broker := NewBroker()
+	broker.bufferSize = 2
+	owner := util.RandomOwner()
+
+	sub := broker.Subscribe(owner)
+	for range 3 {
+		broker.Publish(randomUpdate(owner))
+	}
+
+	// the buffered updates are still received before the channel is closed
+	for range 2 {
+		_, ok := <-sub.C
+		require.True(t, ok)
+	}
+	_, ok := <-sub.C
+	require.False(t, ok)
+
+	// closing a dropped subscription is a no-op
+	sub.Close()
+	require.Empty(t, broker.subscribers)
+}
+
+func TestBrokerDropAll(t *testing.T) {
+	broker := NewBroker()
+
+	sub1 := broker.Subscribe(util.RandomOwner())
+	sub2 := broker.Subscribe(util.RandomOwner())
+	broker.DropAll()
+
+	_, ok := <-sub1.C
+	require.False(t, ok)
+	_, ok = <-sub2.C
+	require.False(t, ok)
+	require.Empty(t, broker.subscribers)
+}
+
+func TestSubscriptionClose(t *testing.T) {
+	broker := NewBroker()
+	owner := util.RandomOwner()
+
+	sub := broker.Subscribe(owner)
+	sub.Close()
+	sub.Close()
+
+	_, ok := <-sub.C
+	require.False(t, ok)
+
+	// publishing without subscribers does not block
+	broker.Publish(randomUpdate(owner))
+}
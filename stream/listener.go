@@ -0,0 +1,75 @@
+package stream
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	db "github.com/roman-adamchik/simplebank/db/sqlc"
+)
+
+const reconnectDelay = time.Second
+
+// Listener receives account updates with Postgres LISTEN/NOTIFY and publishes them to a broker
+type Listener struct {
+	dbSource string
+	broker   *Broker
+}
+
+// NewListener creates a new Listener connecting to the given database
+func NewListener(dbSource string, broker *Broker) *Listener {
+	return &Listener{
+		dbSource: dbSource,
+		broker:   broker,
+	}
+}
+
+// Start listens for account updates until the context is cancelled, reconnecting when the connection is lost
+func (listener *Listener) Start(ctx context.Context) {
+	for {
+		err := listener.listen(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		log.Printf("account updates listener disconnected: %v", err)
+
+		// notifications sent while disconnected are lost, so the subscribers have to catch up
+		listener.broker.DropAll()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(reconnectDelay):
+		}
+	}
+}
+
+// listen uses a dedicated connection, since a pooled one may be handed to other queries between notifications
+func (listener *Listener) listen(ctx context.Context) error {
+	conn, err := pgx.Connect(ctx, listener.dbSource)
+	if err != nil {
+		return err
+	}
+	defer conn.Close(context.Background())
+
+	_, err = conn.Exec(ctx, "LISTEN "+pgx.Identifier{db.AccountUpdatesChannel}.Sanitize())
+	if err != nil {
+		return err
+	}
+
+	for {
+		notification, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			return err
+		}
+
+		var update db.AccountUpdate
+		if err := json.Unmarshal([]byte(notification.Payload), &update); err != nil {
+			log.Printf("cannot decode account update: %v", err)
+			continue
+		}
+		listener.broker.Publish(update)
+	}
+}
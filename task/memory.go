@@ -0,0 +1,248 @@
+package task
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// queueOrder is the order the in-memory queues are drained in
+var queueOrder = []string{QueueCritical, QueueDefault, QueueLow}
+
+type memoryTask struct {
+	id       string
+	taskType string
+	queue    string
+	payload  []byte
+	maxRetry int
+	retried  int
+}
+
+// Memory is an in-process task queue implementing both Distributor and Processor, for tests and for running without Redis.
+// Queues are drained in strict priority order and queued tasks are lost when the process exits.
+// As with Redis, the id of a processed task can be used again while the id of a dead-lettered task cannot
+type Memory struct {
+	mu          sync.Mutex
+	handlers    map[string]Handler
+	ready       map[string][]*memoryTask
+	queued      map[string]bool
+	deadLetters []DeadLetter
+	nextID      int64
+	retryDelay  func(retried int) time.Duration
+
+	wake    chan struct{}
+	stop    chan struct{}
+	workers sync.WaitGroup
+	started bool
+	// pending counts the tasks that are queued, delayed or running
+	pending sync.WaitGroup
+}
+
+// NewMemory creates a new in-memory task queue
+func NewMemory() *Memory {
+	return &Memory{
+		handlers:   make(map[string]Handler),
+		ready:      make(map[string][]*memoryTask),
+		queued:     make(map[string]bool),
+		retryDelay: retryDelay,
+		wake:       make(chan struct{}, 1),
+		stop:       make(chan struct{}),
+	}
+}
+
+// Enqueue adds a task to its queue, a delayed task is added once its delay has passed
+func (m *Memory) Enqueue(ctx context.Context, taskType string, payload any, opts ...Option) error {
+	o, err := newOptions(opts)
+	if err != nil {
+		return err
+	}
+	data, err := marshalPayload(taskType, payload)
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	id := o.id
+	if id == "" {
+		m.nextID++
+		id = strconv.FormatInt(m.nextID, 10)
+	} else if m.queued[id] {
+		m.mu.Unlock()
+		return fmt.Errorf("%w: %s", ErrDuplicateTask, id)
+	}
+	m.queued[id] = true
+	m.pending.Add(1)
+	m.mu.Unlock()
+
+	m.schedule(&memoryTask{
+		id:       id,
+		taskType: taskType,
+		queue:    o.queue,
+		payload:  data,
+		maxRetry: o.maxRetry,
+	}, o.delay)
+
+	return nil
+}
+
+func (m *Memory) schedule(task *memoryTask, delay time.Duration) {
+	if delay <= 0 {
+		m.push(task)
+		return
+	}
+	time.AfterFunc(delay, func() { m.push(task) })
+}
+
+func (m *Memory) push(task *memoryTask) {
+	m.mu.Lock()
+	m.ready[task.queue] = append(m.ready[task.queue], task)
+	m.mu.Unlock()
+
+	select {
+	case m.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Handle registers the handler of a task type, it must be called before Start
+func (m *Memory) Handle(taskType string, handler Handler) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.handlers[taskType] = handler
+}
+
+// Start starts processing tasks in the background, one at a time
+func (m *Memory) Start() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.started {
+		return errors.New("task queue is already started")
+	}
+	m.started = true
+
+	m.workers.Add(1)
+	go m.work()
+	return nil
+}
+
+// Shutdown waits for the running task to finish and stops processing
+func (m *Memory) Shutdown() {
+	m.mu.Lock()
+	started := m.started
+	m.started = false
+	m.mu.Unlock()
+
+	if started {
+		close(m.stop)
+		m.workers.Wait()
+	}
+}
+
+// Wait blocks until every enqueued task is processed or dead-lettered, including delayed tasks and retries
+func (m *Memory) Wait() {
+	m.pending.Wait()
+}
+
+// DeadLetters lists the tasks of a queue that failed every retry
+func (m *Memory) DeadLetters(ctx context.Context, queue string) ([]DeadLetter, error) {
+	if _, ok := queuePriorities[queue]; !ok {
+		return nil, fmt.Errorf("%w %q", ErrUnknownQueue, queue)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	letters := []DeadLetter{}
+	for _, letter := range m.deadLetters {
+		if letter.Queue == queue {
+			letters = append(letters, letter)
+		}
+	}
+	return letters, nil
+}
+
+func (m *Memory) work() {
+	defer m.workers.Done()
+
+	for {
+		task, handler := m.next()
+		if task == nil {
+			select {
+			case <-m.stop:
+				return
+			case <-m.wake:
+				continue
+			}
+		}
+
+		m.run(task, handler)
+
+		select {
+		case <-m.stop:
+			return
+		default:
+		}
+	}
+}
+
+// next pops the first ready task of the highest priority queue
+func (m *Memory) next() (*memoryTask, Handler) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, queue := range queueOrder {
+		if tasks := m.ready[queue]; len(tasks) > 0 {
+			m.ready[queue] = tasks[1:]
+			return tasks[0], m.handlers[tasks[0].taskType]
+		}
+	}
+	return nil, nil
+}
+
+func (m *Memory) run(task *memoryTask, handler Handler) {
+	var err error
+	if handler == nil {
+		err = fmt.Errorf("no handler for task %s", task.taskType)
+	} else {
+		err = handler(context.Background(), task.payload)
+	}
+	if err == nil {
+		m.finish(task)
+		return
+	}
+
+	log.Printf("task %s failed (retry %d of %d): %v", task.taskType, task.retried, task.maxRetry, err)
+	if task.retried >= task.maxRetry || errors.Is(err, ErrSkipRetry) {
+		m.mu.Lock()
+		m.deadLetters = append(m.deadLetters, DeadLetter{
+			ID:        task.id,
+			Type:      task.taskType,
+			Queue:     task.queue,
+			Payload:   task.payload,
+			Retried:   task.retried,
+			LastError: err.Error(),
+			FailedAt:  time.Now(),
+		})
+		m.mu.Unlock()
+		// like an archived asynq task, a dead-lettered task keeps its id
+		m.pending.Done()
+		return
+	}
+
+	task.retried++
+	m.schedule(task, m.retryDelay(task.retried))
+}
+
+func (m *Memory) finish(task *memoryTask) {
+	m.mu.Lock()
+	delete(m.queued, task.id)
+	m.mu.Unlock()
+
+	m.pending.Done()
+}
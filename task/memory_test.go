@@ -0,0 +1,236 @@
+package task
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/roman-adamchik/simplebank/util"
+	"github.com/stretchr/testify/require"
+)
+
+const testTask = "test:task"
+
+type testPayload struct {
+	Name string `json:"name"`
+}
+
+// recorder is a handler recording the payloads it processed, failing the first failures calls
+type recorder struct {
+	mu       sync.Mutex
+	names    []string
+	failures int
+	err      error
+}
+
+func (r *recorder) handle(ctx context.Context, payload []byte) error {
+	var p testPayload
+	if err := json.Unmarshal(payload, &p); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.names = append(r.names, p.Name)
+	if r.failures > 0 {
+		r.failures--
+		return r.err
+	}
+	return nil
+}
+
+func (r *recorder) processed() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return append([]string{}, r.names...)
+}
+
+func newTestMemory(t *testing.T, handler Handler) *Memory {
+	m := NewMemory()
+	m.retryDelay = func(int) time.Duration { return time.Millisecond }
+	m.Handle(testTask, handler)
+	t.Cleanup(m.Shutdown)
+	return m
+}
+
+func TestMemoryPriority(t *testing.T) {
+	r := &recorder{}
+	m := newTestMemory(t, r.handle)
+	ctx := context.Background()
+
+	// tasks are queued before the processor starts so that they are all ready at once
+	require.NoError(t, m.Enqueue(ctx, testTask, testPayload{Name: "low"}, Queue(QueueLow)))
+	require.NoError(t, m.Enqueue(ctx, testTask, testPayload{Name: "default"}))
+	require.NoError(t, m.Enqueue(ctx, testTask, testPayload{Name: "critical"}, Queue(QueueCritical)))
+
+	require.NoError(t, m.Start())
+	m.Wait()
+
+	require.Equal(t, []string{"critical", "default", "low"}, r.processed())
+}
+
+func TestMemoryDelay(t *testing.T) {
+	r := &recorder{}
+	m := newTestMemory(t, r.handle)
+	ctx := context.Background()
+	require.NoError(t, m.Start())
+
+	start := time.Now()
+	require.NoError(t, m.Enqueue(ctx, testTask, testPayload{Name: "later"}, Delay(50*time.Millisecond)))
+	require.NoError(t, m.Enqueue(ctx, testTask, testPayload{Name: "now"}))
+	m.Wait()
+
+	require.GreaterOrEqual(t, time.Since(start), 50*time.Millisecond)
+	require.Equal(t, []string{"now", "later"}, r.processed())
+}
+
+func TestMemoryRetry(t *testing.T) {
+	testCases := []struct {
+		name        string
+		failures    int
+		err         error
+		maxRetry    int
+		checkResult func(t *testing.T, r *recorder, letters []DeadLetter)
+	}{
+		{
+			name:     "SucceedsOnRetry",
+			failures: 2,
+			err:      errors.New("temporary"),
+			maxRetry: 3,
+			checkResult: func(t *testing.T, r *recorder, letters []DeadLetter) {
+				require.Len(t, r.processed(), 3)
+				require.Empty(t, letters)
+			},
+		},
+		{
+			name:     "DeadLetter",
+			failures: 10,
+			err:      errors.New("permanent"),
+			maxRetry: 2,
+			checkResult: func(t *testing.T, r *recorder, letters []DeadLetter) {
+				require.Len(t, r.processed(), 3)
+				require.Len(t, letters, 1)
+				require.Equal(t, testTask, letters[0].Type)
+				require.Equal(t, QueueDefault, letters[0].Queue)
+				require.Equal(t, 2, letters[0].Retried)
+				require.Equal(t, "permanent", letters[0].LastError)
+				require.JSONEq(t, `{"name":"task"}`, string(letters[0].Payload))
+			},
+		},
+		{
+			name:     "SkipRetry",
+			failures: 10,
+			err:      fmt.Errorf("invalid: %w", ErrSkipRetry),
+			maxRetry: 3,
+			checkResult: func(t *testing.T, r *recorder, letters []DeadLetter) {
+				require.Len(t, r.processed(), 1)
+				require.Len(t, letters, 1)
+				require.Zero(t, letters[0].Retried)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			r := &recorder{failures: tc.failures, err: tc.err}
+			m := newTestMemory(t, r.handle)
+			ctx := context.Background()
+			require.NoError(t, m.Start())
+
+			require.NoError(t, m.Enqueue(ctx, testTask, testPayload{Name: "task"}, MaxRetry(tc.maxRetry)))
+			m.Wait()
+
+			letters, err := m.DeadLetters(ctx, QueueDefault)
+			require.NoError(t, err)
+			tc.checkResult(t, r, letters)
+		})
+	}
+}
+
+func TestMemoryDuplicateID(t *testing.T) {
+	r := &recorder{}
+	m := newTestMemory(t, r.handle)
+	ctx := context.Background()
+	id := util.RandomString(10)
+
+	require.NoError(t, m.Enqueue(ctx, testTask, testPayload{Name: "first"}, ID(id)))
+	err := m.Enqueue(ctx, testTask, testPayload{Name: "second"}, ID(id))
+	require.ErrorIs(t, err, ErrDuplicateTask)
+
+	require.NoError(t, m.Start())
+	m.Wait()
+	require.Equal(t, []string{"first"}, r.processed())
+
+	// the id can be used again once the task is processed
+	require.NoError(t, m.Enqueue(ctx, testTask, testPayload{Name: "third"}, ID(id)))
+	m.Wait()
+	require.Equal(t, []string{"first", "third"}, r.processed())
+}
+
+func TestMemoryDeadLetterKeepsID(t *testing.T) {
+	r := &recorder{failures: 1, err: errors.New("failed")}
+	m := newTestMemory(t, r.handle)
+	ctx := context.Background()
+	id := util.RandomString(10)
+	require.NoError(t, m.Start())
+
+	require.NoError(t, m.Enqueue(ctx, testTask, testPayload{Name: "first"}, ID(id), MaxRetry(0)))
+	m.Wait()
+
+	// as with an archived asynq task, the id of a dead-lettered task cannot be used again
+	err := m.Enqueue(ctx, testTask, testPayload{Name: "second"}, ID(id))
+	require.ErrorIs(t, err, ErrDuplicateTask)
+	m.Wait()
+	require.Equal(t, []string{"first"}, r.processed())
+}
+
+func TestMemoryUnknownQueue(t *testing.T) {
+	m := NewMemory()
+
+	err := m.Enqueue(context.Background(), testTask, testPayload{}, Queue("unknown"))
+	require.ErrorIs(t, err, ErrUnknownQueue)
+
+	_, err = m.DeadLetters(context.Background(), "unknown")
+	require.ErrorIs(t, err, ErrUnknownQueue)
+}
+
+func TestMemoryStartTwice(t *testing.T) {
+	m := newTestMemory(t, (&recorder{}).handle)
+
+	require.NoError(t, m.Start())
+	require.Error(t, m.Start())
+}
+
+func TestRetryDelay(t *testing.T) {
+	for retried := 1; retried <= 20; retried++ {
+		delay := retryDelay(retried)
+		require.Greater(t, delay, time.Duration(0))
+		require.LessOrEqual(t, delay, retryMaxDelay)
+	}
+	require.GreaterOrEqual(t, retryDelay(1), retryBaseDelay/2)
+	require.LessOrEqual(t, retryDelay(1), retryBaseDelay)
+	require.GreaterOrEqual(t, retryDelay(20), retryMaxDelay/2)
+}
+
+func TestNewQueue(t *testing.T) {
+	distributor, processor, err := NewQueue(util.Config{})
+	require.NoError(t, err)
+	require.IsType(t, &Memory{}, distributor)
+	require.Same(t, distributor, processor)
+
+	distributor, processor, err = NewQueue(util.Config{TaskBackend: BackendRedis, RedisAddress: "localhost:6379"})
+	require.NoError(t, err)
+	require.IsType(t, &RedisDistributor{}, distributor)
+	require.IsType(t, &RedisProcessor{}, processor)
+
+	_, _, err = NewQueue(util.Config{TaskBackend: "kafka"})
+	require.Error(t, err)
+}
@@ -0,0 +1,74 @@
+package task
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+)
+
+// Job is the work a periodic task runs
+type Job func(ctx context.Context) error
+
+// Every runs a job every interval as a task that enqueues its next run before running the job, and enqueues the first run.
+// Runs are identified by the interval they are due in, so however many processes schedule the same job,
+// each run is queued once. A failed run is logged and not retried, the next run takes over.
+// Every interval until ctx is done, the next run is enqueued again, so that a run that could not be enqueued
+// does not stop the job for good. It registers the handler of the task, so it must be called before the processor is started
+func Every(ctx context.Context, distributor Distributor, processor Processor, taskType string, interval time.Duration, job Job) error {
+	if interval <= 0 {
+		return fmt.Errorf("invalid interval %s of periodic task %s", interval, taskType)
+	}
+
+	processor.Handle(taskType, func(ctx context.Context, _ []byte) error {
+		if err := enqueueRun(ctx, distributor, taskType, interval, time.Now().Add(interval)); err != nil {
+			log.Printf("cannot enqueue the next run of periodic task %s: %v", taskType, err)
+		}
+
+		if err := job(ctx); err != nil {
+			log.Printf("periodic task %s failed: %v", taskType, err)
+		}
+		return nil
+	})
+
+	if err := enqueueRun(ctx, distributor, taskType, interval, time.Now()); err != nil {
+		return err
+	}
+
+	go rearm(ctx, distributor, taskType, interval)
+	return nil
+}
+
+// rearm enqueues the next run of a periodic task every interval until ctx is done.
+// The next run is normally queued already, in which case it is kept
+func rearm(ctx context.Context, distributor Distributor, taskType string, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := enqueueRun(ctx, distributor, taskType, interval, time.Now().Add(interval)); err != nil {
+				log.Printf("cannot enqueue the next run of periodic task %s: %v", taskType, err)
+			}
+		}
+	}
+}
+
+// enqueueRun enqueues the run of a periodic task due in the interval of the given time,
+// a run that is already queued is kept
+func enqueueRun(ctx context.Context, distributor Distributor, taskType string, interval time.Duration, at time.Time) error {
+	at = at.Truncate(interval)
+
+	err := distributor.Enqueue(ctx, taskType, struct{}{},
+		ID(fmt.Sprintf("%s-%d", taskType, at.UnixNano())),
+		At(at),
+	)
+	if errors.Is(err, ErrDuplicateTask) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,95 @@
+package task
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+const testPeriodicTask = "test:periodic"
+
+func TestEvery(t *testing.T) {
+	m := newTestMemory(t, nil)
+	ctx := context.Background()
+
+	// a failed run does not stop the next ones
+	var runs atomic.Int32
+	err := Every(ctx, m, m, testPeriodicTask, 10*time.Millisecond, func(ctx context.Context) error {
+		runs.Add(1)
+		return errors.New("failed")
+	})
+	require.NoError(t, err)
+	require.NoError(t, m.Start())
+
+	require.Eventually(t, func() bool { return runs.Load() >= 3 }, time.Second, time.Millisecond)
+
+	letters, err := m.DeadLetters(ctx, QueueDefault)
+	require.NoError(t, err)
+	require.Empty(t, letters)
+}
+
+// failingDistributor fails the enqueues made while failing is set, as a Redis outage would
+type failingDistributor struct {
+	Distributor
+	failing atomic.Bool
+}
+
+func (d *failingDistributor) Enqueue(ctx context.Context, taskType string, payload any, opts ...Option) error {
+	if d.failing.Load() {
+		return errors.New("connection refused")
+	}
+	return d.Distributor.Enqueue(ctx, taskType, payload, opts...)
+}
+
+func TestEveryRearm(t *testing.T) {
+	m := newTestMemory(t, nil)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	distributor := &failingDistributor{Distributor: m}
+
+	var runs atomic.Int32
+	err := Every(ctx, distributor, m, testPeriodicTask, 10*time.Millisecond, func(ctx context.Context) error {
+		runs.Add(1)
+		return nil
+	})
+	require.NoError(t, err)
+
+	// the first run cannot enqueue the next one, which breaks the chain of runs
+	distributor.failing.Store(true)
+	require.NoError(t, m.Start())
+	require.Eventually(t, func() bool { return runs.Load() == 1 }, time.Second, time.Millisecond)
+	require.Never(t, func() bool { return runs.Load() > 1 }, 30*time.Millisecond, time.Millisecond)
+
+	// the next run is enqueued again once the distributor recovers
+	distributor.failing.Store(false)
+	require.Eventually(t, func() bool { return runs.Load() >= 3 }, time.Second, time.Millisecond)
+}
+
+func TestEveryScheduledTwice(t *testing.T) {
+	m := newTestMemory(t, nil)
+	ctx := context.Background()
+
+	// the second process finds the run of the first one already queued
+	var runs atomic.Int32
+	job := func(ctx context.Context) error {
+		runs.Add(1)
+		return nil
+	}
+	require.NoError(t, Every(ctx, m, m, testPeriodicTask, time.Hour, job))
+	require.NoError(t, Every(ctx, m, m, testPeriodicTask, time.Hour, job))
+	require.NoError(t, m.Start())
+
+	require.Eventually(t, func() bool { return runs.Load() == 1 }, time.Second, time.Millisecond)
+	require.Never(t, func() bool { return runs.Load() > 1 }, 50*time.Millisecond, time.Millisecond)
+}
+
+func TestEveryInvalidInterval(t *testing.T) {
+	m := NewMemory()
+
+	err := Every(context.Background(), m, m, testPeriodicTask, 0, func(ctx context.Context) error { return nil })
+	require.Error(t, err)
+}
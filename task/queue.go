@@ -0,0 +1,27 @@
+package task
+
+import (
+	"fmt"
+
+	"github.com/roman-adamchik/simplebank/util"
+)
+
+// list of backends tasks can be queued with
+const (
+	BackendMemory = "memory"
+	BackendRedis  = "redis"
+)
+
+// NewQueue creates the distributor and processor of the backend selected by the config,
+// tasks are queued in memory by default
+func NewQueue(config util.Config) (Distributor, Processor, error) {
+	switch config.TaskBackend {
+	case "", BackendMemory:
+		queue := NewMemory()
+		return queue, queue, nil
+	case BackendRedis:
+		return NewRedisDistributor(config.RedisAddress), NewRedisProcessor(config.RedisAddress, config.TaskConcurrency), nil
+	}
+
+	return nil, nil, fmt.Errorf("unsupported task backend %q", config.TaskBackend)
+}
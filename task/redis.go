@@ -0,0 +1,145 @@
+package task
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/hibiken/asynq"
+)
+
+const defaultConcurrency = 10
+
+// RedisDistributor enqueues tasks to Redis
+type RedisDistributor struct {
+	client *asynq.Client
+}
+
+// NewRedisDistributor creates a new RedisDistributor connected to the given address
+func NewRedisDistributor(address string) *RedisDistributor {
+	return &RedisDistributor{
+		client: asynq.NewClient(asynq.RedisClientOpt{Addr: address}),
+	}
+}
+
+// Enqueue adds a task to its queue
+func (distributor *RedisDistributor) Enqueue(ctx context.Context, taskType string, payload any, opts ...Option) error {
+	o, err := newOptions(opts)
+	if err != nil {
+		return err
+	}
+	data, err := marshalPayload(taskType, payload)
+	if err != nil {
+		return err
+	}
+
+	taskOpts := []asynq.Option{
+		asynq.Queue(o.queue),
+		asynq.MaxRetry(o.maxRetry),
+		asynq.ProcessIn(o.delay),
+	}
+	if o.id != "" {
+		taskOpts = append(taskOpts, asynq.TaskID(o.id))
+	}
+
+	_, err = distributor.client.EnqueueContext(ctx, asynq.NewTask(taskType, data), taskOpts...)
+	if errors.Is(err, asynq.ErrTaskIDConflict) {
+		return fmt.Errorf("%w: %s", ErrDuplicateTask, o.id)
+	}
+	return err
+}
+
+// Close closes the connection to Redis
+func (distributor *RedisDistributor) Close() error {
+	return distributor.client.Close()
+}
+
+// RedisProcessor processes the tasks queued in Redis.
+// Queues are processed by weight and tasks that failed every retry are kept as archived tasks
+type RedisProcessor struct {
+	server    *asynq.Server
+	mux       *asynq.ServeMux
+	inspector *asynq.Inspector
+}
+
+// NewRedisProcessor creates a new RedisProcessor connected to the given address,
+// running at most concurrency tasks at a time
+func NewRedisProcessor(address string, concurrency int) *RedisProcessor {
+	if concurrency <= 0 {
+		concurrency = defaultConcurrency
+	}
+
+	redisOpt := asynq.RedisClientOpt{Addr: address}
+	server := asynq.NewServer(redisOpt, asynq.Config{
+		Concurrency: concurrency,
+		Queues:      queuePriorities,
+		RetryDelayFunc: func(n int, _ error, _ *asynq.Task) time.Duration {
+			return retryDelay(n)
+		},
+		ErrorHandler: asynq.ErrorHandlerFunc(func(ctx context.Context, task *asynq.Task, err error) {
+			retried, _ := asynq.GetRetryCount(ctx)
+			maxRetry, _ := asynq.GetMaxRetry(ctx)
+			log.Printf("task %s failed (retry %d of %d): %v", task.Type(), retried, maxRetry, err)
+		}),
+	})
+
+	return &RedisProcessor{
+		server:    server,
+		mux:       asynq.NewServeMux(),
+		inspector: asynq.NewInspector(redisOpt),
+	}
+}
+
+// Handle registers the handler of a task type, it must be called before Start
+func (processor *RedisProcessor) Handle(taskType string, handler Handler) {
+	processor.mux.HandleFunc(taskType, func(ctx context.Context, task *asynq.Task) error {
+		err := handler(ctx, task.Payload())
+		if errors.Is(err, ErrSkipRetry) {
+			return fmt.Errorf("%w: %w", err, asynq.SkipRetry)
+		}
+		return err
+	})
+}
+
+// Start starts processing tasks in the background
+func (processor *RedisProcessor) Start() error {
+	return processor.server.Start(processor.mux)
+}
+
+// Shutdown waits for the running tasks to finish and stops processing
+func (processor *RedisProcessor) Shutdown() {
+	processor.server.Shutdown()
+}
+
+// DeadLetters lists the archived tasks of a queue
+func (processor *RedisProcessor) DeadLetters(ctx context.Context, queue string) ([]DeadLetter, error) {
+	if _, ok := queuePriorities[queue]; !ok {
+		return nil, fmt.Errorf("%w %q", ErrUnknownQueue, queue)
+	}
+
+	tasks, err := processor.inspector.ListArchivedTasks(queue)
+	if err != nil {
+		// a queue that never had a task does not exist yet
+		if errors.Is(err, asynq.ErrQueueNotFound) {
+			return []DeadLetter{}, nil
+		}
+		return nil, err
+	}
+
+	letters := make([]DeadLetter, len(tasks))
+	for i, task := range tasks {
+		letters[i] = DeadLetter{
+			ID:        task.ID,
+			Type:      task.Type,
+			Queue:     task.Queue,
+			Payload:   task.Payload,
+			Retried:   task.Retried,
+			LastError: task.LastErr,
+			FailedAt:  task.LastFailedAt,
+		}
+	}
+
+	return letters, nil
+}
@@ -0,0 +1,47 @@
+package task
+
+import (
+	"context"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/roman-adamchik/simplebank/util"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRedisDistributor(t *testing.T) {
+	redisServer := miniredis.RunT(t)
+
+	distributor := NewRedisDistributor(redisServer.Addr())
+	defer distributor.Close()
+	ctx := context.Background()
+	id := util.RandomString(10)
+
+	err := distributor.Enqueue(ctx, testTask, testPayload{Name: "task"}, ID(id), Queue(QueueCritical))
+	require.NoError(t, err)
+
+	err = distributor.Enqueue(ctx, testTask, testPayload{Name: "task"}, ID(id), Queue(QueueCritical))
+	require.ErrorIs(t, err, ErrDuplicateTask)
+
+	err = distributor.Enqueue(ctx, testTask, testPayload{}, Queue("unknown"))
+	require.ErrorIs(t, err, ErrUnknownQueue)
+
+	processor := NewRedisProcessor(redisServer.Addr(), 1)
+	info, err := processor.inspector.GetTaskInfo(QueueCritical, id)
+	require.NoError(t, err)
+	require.Equal(t, testTask, info.Type)
+	require.JSONEq(t, `{"name":"task"}`, string(info.Payload))
+	require.Equal(t, defaultMaxRetry, info.MaxRetry)
+}
+
+func TestRedisProcessorDeadLetters(t *testing.T) {
+	redisServer := miniredis.RunT(t)
+
+	processor := NewRedisProcessor(redisServer.Addr(), 1)
+	letters, err := processor.DeadLetters(context.Background(), QueueLow)
+	require.NoError(t, err)
+	require.Empty(t, letters)
+
+	_, err = processor.DeadLetters(context.Background(), "unknown")
+	require.ErrorIs(t, err, ErrUnknownQueue)
+}
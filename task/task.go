@@ -0,0 +1,140 @@
+package task
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"time"
+)
+
+// list of queues, from the highest priority to the lowest
+const (
+	QueueCritical = "critical"
+	QueueDefault  = "default"
+	QueueLow      = "low"
+)
+
+// queuePriorities are the weights queues are processed with,
+// a critical task is picked six times as often as a low one
+var queuePriorities = map[string]int{
+	QueueCritical: 6,
+	QueueDefault:  3,
+	QueueLow:      1,
+}
+
+const (
+	defaultMaxRetry = 5
+	retryBaseDelay  = 5 * time.Second
+	retryMaxDelay   = time.Hour
+)
+
+// Different types of errors returned by the task queues
+var (
+	// ErrSkipRetry sends a failed task to the dead-letter queue without retrying it; handlers wrap it into their error
+	ErrSkipRetry = errors.New("skip retry")
+	// ErrDuplicateTask is returned when a task is enqueued with the id of a task that is still queued or was dead-lettered
+	ErrDuplicateTask = errors.New("task with the same id is already queued")
+	ErrUnknownQueue  = errors.New("unknown queue")
+)
+
+// Handler processes the JSON payload of a task; a task whose handler fails is retried later
+type Handler func(ctx context.Context, payload []byte) error
+
+// Distributor enqueues tasks to be processed in the background
+type Distributor interface {
+	Enqueue(ctx context.Context, taskType string, payload any, opts ...Option) error
+}
+
+// Processor runs the handlers of the enqueued tasks
+type Processor interface {
+	Handle(taskType string, handler Handler)
+	Start() error
+	Shutdown()
+	// DeadLetters lists the tasks of a queue that failed every retry
+	DeadLetters(ctx context.Context, queue string) ([]DeadLetter, error)
+}
+
+// DeadLetter is a task that failed every retry
+type DeadLetter struct {
+	ID        string    `json:"id"`
+	Type      string    `json:"type"`
+	Queue     string    `json:"queue"`
+	Payload   []byte    `json:"payload"`
+	Retried   int       `json:"retried"`
+	LastError string    `json:"last_error"`
+	FailedAt  time.Time `json:"failed_at"`
+}
+
+type options struct {
+	id       string
+	queue    string
+	delay    time.Duration
+	maxRetry int
+}
+
+// Option configures how a task is enqueued
+type Option func(*options)
+
+// ID makes the task unique while it is queued and once it is dead-lettered, enqueueing it again returns ErrDuplicateTask
+func ID(id string) Option {
+	return func(o *options) { o.id = id }
+}
+
+// Queue sets the queue of the task, QueueDefault if not set
+func Queue(name string) Option {
+	return func(o *options) { o.queue = name }
+}
+
+// Delay processes the task no earlier than after the given duration
+func Delay(d time.Duration) Option {
+	return func(o *options) { o.delay = d }
+}
+
+// At processes the task no earlier than the given time
+func At(t time.Time) Option {
+	return func(o *options) { o.delay = time.Until(t) }
+}
+
+// MaxRetry sets how many times a failed task is retried before it is dead-lettered
+func MaxRetry(n int) Option {
+	return func(o *options) { o.maxRetry = n }
+}
+
+func newOptions(opts []Option) (options, error) {
+	o := options{
+		queue:    QueueDefault,
+		maxRetry: defaultMaxRetry,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	if _, ok := queuePriorities[o.queue]; !ok {
+		return o, fmt.Errorf("%w %q", ErrUnknownQueue, o.queue)
+	}
+	o.delay = max(o.delay, 0)
+	o.maxRetry = max(o.maxRetry, 0)
+
+	return o, nil
+}
+
+func marshalPayload(taskType string, payload any) ([]byte, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("cannot marshal %s task: %w", taskType, err)
+	}
+	return data, nil
+}
+
+// retryDelay is the exponential backoff with jitter before the given retry of a task
+func retryDelay(retried int) time.Duration {
+	delay := retryBaseDelay
+	for i := 1; i < retried && delay < retryMaxDelay; i++ {
+		delay *= 2
+	}
+	delay = min(delay, retryMaxDelay)
+
+	return delay/2 + rand.N(delay/2+1)
+}
@@ -1,7 +1,6 @@
 package token
 
 import (
-	"errors"
 	"fmt"
 	"time"
 
@@ -24,9 +23,9 @@ func NewJWTMaker(secretKey string) (Maker, error) {
 	return &JWTMaker{secretKey}, nil
 }
 
-// CreateToken creates a new token for a specific username and duration
-func (maker *JWTMaker) CreateToken(username string, duration time.Duration) (string, error) {
-	payload, err := NewPayload(username, duration)
+// CreateToken creates a new token with some claims for a specific duration
+func (maker *JWTMaker) CreateToken(claims Claims, duration time.Duration) (string, error) {
+	payload, err := NewPayload(claims, duration)
 	if err != nil {
 		return "", err
 	}
@@ -35,24 +34,20 @@ func (maker *JWTMaker) CreateToken(username string, duration time.Duration) (str
 	return token.SignedString([]byte(maker.secretKey))
 }
 
-// VerifyToken checks if the token is valid or not
-func (maker *JWTMaker) VerifyToken(token string) (*Payload, error) {
+// VerifyToken checks if the token is valid or not, and that its claims meet the options
+func (maker *JWTMaker) VerifyToken(token string, opts VerifyOptions) (*Payload, error) {
 	keyFunc := func(token *jwt.Token) (interface{}, error) {
 		_, ok := token.Method.(*jwt.SigningMethodHMAC)
 		if !ok {
 			return nil, ErrInvalidToken
 		}
-
 		return []byte(maker.secretKey), nil
 	}
 
-	jwtToken, err := jwt.ParseWithClaims(token, &Payload{}, keyFunc)
-
+	// the claims are checked below with the options, the same way PasetoMaker does
+	parser := jwt.Parser{SkipClaimsValidation: true}
+	jwtToken, err := parser.ParseWithClaims(token, &Payload{}, keyFunc)
 	if err != nil {
-		vErr, ok := err.(*jwt.ValidationError)
-		if ok && errors.Is(vErr.Inner, ErrExpiredToken) {
-			return nil, ErrExpiredToken
-		}
 		return nil, ErrInvalidToken
 	}
 
@@ -61,5 +56,10 @@ func (maker *JWTMaker) VerifyToken(token string) (*Payload, error) {
 		return nil, ErrInvalidToken
 	}
 
+	err = payload.Verify(opts)
+	if err != nil {
+		return nil, err
+	}
+
 	return payload, nil
 }
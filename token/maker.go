@@ -6,9 +6,9 @@ import (
 
 // Maker is an interface for creating and verifying tokens
 type Maker interface {
-	// CreateToken creates a new token for a specific username and duration
-	CreateToken(username string, duration time.Duration) (string, error)
+	// CreateToken creates a new token with some claims for a specific duration
+	CreateToken(claims Claims, duration time.Duration) (string, error)
 
-	// VerifyToken checks if the token is valid or not
-	VerifyToken(token string) (*Payload, error)
+	// VerifyToken checks if the token is valid or not, and that its claims meet the options
+	VerifyToken(token string, opts VerifyOptions) (*Payload, error)
 }
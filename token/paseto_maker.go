@@ -28,9 +28,9 @@ func NewPasetoMaker(symmetricKey string) (Maker, error) {
 	return maker, nil
 }
 
-// CreateToken creates a new token for a specific username and duration
-func (maker *PasetoMaker) CreateToken(username string, duration time.Duration) (string, error) {
-	payload, err := NewPayload(username, duration)
+// CreateToken creates a new token with some claims for a specific duration
+func (maker *PasetoMaker) CreateToken(claims Claims, duration time.Duration) (string, error) {
+	payload, err := NewPayload(claims, duration)
 	if err != nil {
 		return "", err
 	}
@@ -38,8 +38,8 @@ func (maker *PasetoMaker) CreateToken(username string, duration time.Duration) (
 	return maker.paseto.Encrypt(maker.symmetricKey, payload, nil)
 }
 
-// VerifyToken checks if the token is valid or not
-func (maker *PasetoMaker) VerifyToken(token string) (*Payload, error) {
+// VerifyToken checks if the token is valid or not, and that its claims meet the options
+func (maker *PasetoMaker) VerifyToken(token string, opts VerifyOptions) (*Payload, error) {
 	payload := &Payload{}
 
 	err := maker.paseto.Decrypt(token, maker.symmetricKey, payload, nil)
@@ -47,7 +47,7 @@ func (maker *PasetoMaker) VerifyToken(token string) (*Payload, error) {
 		return nil, ErrInvalidToken
 	}
 
-	err = payload.Valid()
+	err = payload.Verify(opts)
 	if err != nil {
 		return nil, err
 	}
@@ -18,11 +18,11 @@ func TestPasetoMaker(t *testing.T) {
 	issuedAt := time.Now()
 	expiredAt := issuedAt.Add(duration)
 
-	token, err := maker.CreateToken(userName, duration)
+	token, err := maker.CreateToken(Claims{Username: userName}, duration)
 	require.NoError(t, err)
 	require.NotEmpty(t, token)
 
-	payload, err := maker.VerifyToken(token)
+	payload, err := maker.VerifyToken(token, VerifyOptions{})
 	require.NoError(t, err)
 	require.NotEmpty(t, payload)
 
@@ -38,11 +38,11 @@ func TestExpiredPasetoToken(t *testing.T) {
 
 	userName := util.RandomOwner()
 
-	token, err := maker.CreateToken(userName, -time.Minute)
+	token, err := maker.CreateToken(Claims{Username: userName}, -time.Minute)
 	require.NoError(t, err)
 	require.NotEmpty(t, token)
 
-	payload, err := maker.VerifyToken(token)
+	payload, err := maker.VerifyToken(token, VerifyOptions{})
 	require.Error(t, err)
 	require.EqualError(t, err, ErrExpiredToken.Error())
 	require.Nil(t, payload)
@@ -54,12 +54,19 @@ func TestInvalidPasetoToken(t *testing.T) {
 
 	userName := util.RandomOwner()
 
-	token, err := maker.CreateToken(userName, time.Minute)
+	token, err := maker.CreateToken(Claims{Username: userName}, time.Minute)
 	require.NoError(t, err)
 	require.NotEmpty(t, token)
 
-	payload, err := maker.VerifyToken(token + "invalid")
+	payload, err := maker.VerifyToken(token+"invalid", VerifyOptions{})
 	require.Error(t, err)
 	require.EqualError(t, err, ErrInvalidToken.Error())
 	require.Nil(t, payload)
 }
+
+func TestPasetoMakerClaims(t *testing.T) {
+	maker, err := NewPasetoMaker(util.RandomString(32))
+	require.NoError(t, err)
+
+	testMakerClaims(t, maker)
+}
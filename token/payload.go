@@ -2,6 +2,7 @@ package token
 
 import (
 	"errors"
+	"slices"
 	"time"
 
 	"github.com/google/uuid"
@@ -9,8 +10,11 @@ import (
 
 // Different types of errors returned by the VerifyToken function
 var (
-	ErrExpiredToken = errors.New("token is expired")
-	ErrInvalidToken = errors.New("token is invalid")
+	ErrExpiredToken     = errors.New("token is expired")
+	ErrInvalidToken     = errors.New("token is invalid")
+	ErrTokenNotValidYet = errors.New("token is not valid yet")
+	ErrInvalidIssuer    = errors.New("token has an unexpected issuer")
+	ErrInvalidAudience  = errors.New("token is not meant for this audience")
 )
 
 type Payload struct {
@@ -18,30 +22,95 @@ type Payload struct {
 	Username  string    `json:"username"`
 	IssuedAt  time.Time `json:"issued_at"`
 	ExpiredAt time.Time `json:"expired_at"`
+	// NotBefore is when the token starts to be valid
+	NotBefore time.Time `json:"not_before"`
+	// Issuer is the service that created the token
+	Issuer string `json:"issuer,omitempty"`
+	// Audience lists the services the token is meant for
+	Audience []string `json:"audience,omitempty"`
+	// Role of the user when the token was created
+	Role string `json:"role,omitempty"`
+	// SessionID is the login session the token belongs to, zero for tokens without a session
+	SessionID int64 `json:"session_id,omitempty"`
+	// ClientID is the OAuth client the token was issued to on behalf of the user, empty for the user's own tokens
+	ClientID string `json:"client_id,omitempty"`
+	// Scopes limit what a token issued to a client can access
+	Scopes []string `json:"scopes,omitempty"`
 }
 
-// NewPayload creates a new payload for a specific username and duration
-func NewPayload(username string, duration time.Duration) (*Payload, error) {
+// Claims are the claims a token is created with, besides its ID and lifetime
+type Claims struct {
+	Username string
+	Role     string
+	Issuer   string
+	Audience []string
+	// NotBefore delays the start of the token, it starts when it is issued if zero
+	NotBefore time.Time
+	SessionID int64
+	ClientID  string
+	Scopes    []string
+}
+
+// VerifyOptions are what a verifier expects of the claims of a token
+type VerifyOptions struct {
+	// Issuer must be the issuer of the token, any issuer is accepted if empty
+	Issuer string
+	// Audience must be one of the audiences of the token, any audience is accepted if empty
+	Audience string
+	// ClockSkew is how much the clocks of the issuer and the verifier may differ
+	ClockSkew time.Duration
+}
+
+// NewPayload creates a new payload with some claims for a specific duration
+func NewPayload(claims Claims, duration time.Duration) (*Payload, error) {
 	tokenID, err := uuid.NewRandom()
 	if err != nil {
 		return nil, err
 	}
 
+	issuedAt := time.Now()
+	notBefore := claims.NotBefore
+	if notBefore.IsZero() {
+		notBefore = issuedAt
+	}
+
 	payload := &Payload{
 		ID:        tokenID,
-		Username:  username,
-		IssuedAt:  time.Now(),
-		ExpiredAt: time.Now().Add(duration),
+		Username:  claims.Username,
+		IssuedAt:  issuedAt,
+		ExpiredAt: issuedAt.Add(duration),
+		NotBefore: notBefore,
+		Issuer:    claims.Issuer,
+		Audience:  claims.Audience,
+		Role:      claims.Role,
+		SessionID: claims.SessionID,
+		ClientID:  claims.ClientID,
+		Scopes:    claims.Scopes,
 	}
-
 	return payload, nil
 }
 
-// Valid checks if the payload is valid or not
+// Valid checks if the payload is valid or not at the current time
 func (payload *Payload) Valid() error {
-	if time.Now().After(payload.ExpiredAt) {
+	return payload.Verify(VerifyOptions{})
+}
+
+// Verify checks the lifetime of the payload, allowing for clock skew, and that its issuer and audience
+// are the expected ones. Both makers verify tokens with it, so they accept and reject the same claims
+func (payload *Payload) Verify(opts VerifyOptions) error {
+	now := time.Now()
+	if now.After(payload.ExpiredAt.Add(opts.ClockSkew)) {
 		return ErrExpiredToken
 	}
+	if now.Add(opts.ClockSkew).Before(payload.NotBefore) {
+		return ErrTokenNotValidYet
+	}
+	if opts.Issuer != "" && payload.Issuer != opts.Issuer {
+		return ErrInvalidIssuer
+	}
+	if opts.Audience != "" && !slices.Contains(payload.Audience, opts.Audience) {
+		return ErrInvalidAudience
+	}
 
 	return nil
 }
@@ -0,0 +1,150 @@
+package token
+
+import (
+	"testing"
+	"time"
+
+	"github.com/roman-adamchik/simplebank/util"
+	"github.com/stretchr/testify/require"
+)
+
+// testMakerClaims checks that a maker keeps the claims of its tokens and verifies them against the options
+func testMakerClaims(t *testing.T, maker Maker) {
+	t.Helper()
+
+	claims := Claims{
+		Username:  util.RandomOwner(),
+		Role:      util.DepositorRole,
+		Issuer:    "simplebank",
+		Audience:  []string{"simplebank-api"},
+		SessionID: 7,
+		ClientID:  "client-id",
+		Scopes:    []string{"accounts:read", "transfers:write"},
+	}
+	opts := VerifyOptions{
+		Issuer:   "simplebank",
+		Audience: "simplebank-api",
+	}
+
+	testCases := []struct {
+		name     string
+		claims   func() Claims
+		duration time.Duration
+		opts     func() VerifyOptions
+		err      error
+	}{
+		{
+			name:     "OK",
+			claims:   func() Claims { return claims },
+			duration: time.Minute,
+			opts:     func() VerifyOptions { return opts },
+		},
+		{
+			name:     "NoExpectations",
+			claims:   func() Claims { return claims },
+			duration: time.Minute,
+			opts:     func() VerifyOptions { return VerifyOptions{} },
+		},
+		{
+			name: "WrongIssuer",
+			claims: func() Claims {
+				wrong := claims
+				wrong.Issuer = "other"
+				return wrong
+			},
+			duration: time.Minute,
+			opts:     func() VerifyOptions { return opts },
+			err:      ErrInvalidIssuer,
+		},
+		{
+			name: "WrongAudience",
+			claims: func() Claims {
+				wrong := claims
+				wrong.Audience = []string{"simplebank-mfa"}
+				return wrong
+			},
+			duration: time.Minute,
+			opts:     func() VerifyOptions { return opts },
+			err:      ErrInvalidAudience,
+		},
+		{
+			name: "NoAudience",
+			claims: func() Claims {
+				wrong := claims
+				wrong.Audience = nil
+				return wrong
+			},
+			duration: time.Minute,
+			opts:     func() VerifyOptions { return opts },
+			err:      ErrInvalidAudience,
+		},
+		{
+			name: "NotValidYet",
+			claims: func() Claims {
+				later := claims
+				later.NotBefore = time.Now().Add(time.Minute)
+				return later
+			},
+			duration: 2 * time.Minute,
+			opts:     func() VerifyOptions { return opts },
+			err:      ErrTokenNotValidYet,
+		},
+		{
+			name: "NotValidYetWithinSkew",
+			claims: func() Claims {
+				later := claims
+				later.NotBefore = time.Now().Add(10 * time.Second)
+				return later
+			},
+			duration: time.Minute,
+			opts: func() VerifyOptions {
+				skewed := opts
+				skewed.ClockSkew = 30 * time.Second
+				return skewed
+			},
+		},
+		{
+			name:     "Expired",
+			claims:   func() Claims { return claims },
+			duration: -10 * time.Second,
+			opts:     func() VerifyOptions { return opts },
+			err:      ErrExpiredToken,
+		},
+		{
+			name:     "ExpiredWithinSkew",
+			claims:   func() Claims { return claims },
+			duration: -10 * time.Second,
+			opts: func() VerifyOptions {
+				skewed := opts
+				skewed.ClockSkew = 30 * time.Second
+				return skewed
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			token, err := maker.CreateToken(tc.claims(), tc.duration)
+			require.NoError(t, err)
+
+			payload, err := maker.VerifyToken(token, tc.opts())
+			if tc.err != nil {
+				require.ErrorIs(t, err, tc.err)
+				require.Nil(t, payload)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, tc.claims().Username, payload.Username)
+			require.Equal(t, tc.claims().Role, payload.Role)
+			require.Equal(t, tc.claims().Issuer, payload.Issuer)
+			require.Equal(t, tc.claims().Audience, payload.Audience)
+			require.Equal(t, tc.claims().SessionID, payload.SessionID)
+			require.Equal(t, tc.claims().ClientID, payload.ClientID)
+			require.Equal(t, tc.claims().Scopes, payload.Scopes)
+			require.False(t, payload.NotBefore.IsZero())
+		})
+	}
+}
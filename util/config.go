@@ -7,10 +7,60 @@ import (
 )
 
 type Config struct {
-	DBSource            string        `mapstructure:"DB_SOURCE"`
-	ServerAddress       string        `mapstructure:"SERVER_ADDRESS"`
-	TokenSymmetricKey   string        `mapstructure:"TOKEN_SYMMETRIC_KEY"`
-	AccessTokenDuration time.Duration `mapstructure:"ACCESS_TOKEN_DURATION"`
+	DBSource                string        `mapstructure:"DB_SOURCE"`
+	ServerAddress           string        `mapstructure:"SERVER_ADDRESS"`
+	TrustedProxies          []string      `mapstructure:"TRUSTED_PROXIES"`
+	TokenSymmetricKey       string        `mapstructure:"TOKEN_SYMMETRIC_KEY"`
+	AccessTokenDuration     time.Duration `mapstructure:"ACCESS_TOKEN_DURATION"`
+	TokenIssuer             string        `mapstructure:"TOKEN_ISSUER"`
+	TokenAudience           string        `mapstructure:"TOKEN_AUDIENCE"`
+	TokenClockSkew          time.Duration `mapstructure:"TOKEN_CLOCK_SKEW"`
+	StandingOrderInterval   time.Duration `mapstructure:"STANDING_ORDER_INTERVAL"`
+	PendingTransferTTL      time.Duration `mapstructure:"PENDING_TRANSFER_TTL"`
+	PendingTransferInterval time.Duration `mapstructure:"PENDING_TRANSFER_INTERVAL"`
+	HoldTTL                 time.Duration `mapstructure:"HOLD_TTL"`
+	HoldInterval            time.Duration `mapstructure:"HOLD_INTERVAL"`
+	RedisAddress            string        `mapstructure:"REDIS_ADDRESS"`
+	EventPublisher          string        `mapstructure:"EVENT_PUBLISHER"`
+	EventHTTPURL            string        `mapstructure:"EVENT_HTTP_URL"`
+	EventStream             string        `mapstructure:"EVENT_STREAM"`
+	OutboxInterval          time.Duration `mapstructure:"OUTBOX_INTERVAL"`
+	WebhookInterval         time.Duration `mapstructure:"WEBHOOK_INTERVAL"`
+	WebhookTimeout          time.Duration `mapstructure:"WEBHOOK_TIMEOUT"`
+	TaskBackend             string        `mapstructure:"TASK_BACKEND"`
+	TaskConcurrency         int           `mapstructure:"TASK_CONCURRENCY"`
+	Mailer                  string        `mapstructure:"MAILER"`
+	MailFrom                string        `mapstructure:"MAIL_FROM"`
+	MailDir                 string        `mapstructure:"MAIL_DIR"`
+	SMTPAddress             string        `mapstructure:"SMTP_ADDRESS"`
+	SMTPUsername            string        `mapstructure:"SMTP_USERNAME"`
+	SMTPPassword            string        `mapstructure:"SMTP_PASSWORD"`
+	VerifyEmailURL          string        `mapstructure:"VERIFY_EMAIL_URL"`
+	VerifyEmailTTL          time.Duration `mapstructure:"VERIFY_EMAIL_TTL"`
+	VerifyEmailLimit        int           `mapstructure:"VERIFY_EMAIL_LIMIT"`
+	UnverifiedRestrictions  []string      `mapstructure:"UNVERIFIED_RESTRICTIONS"`
+	PasswordResetURL        string        `mapstructure:"PASSWORD_RESET_URL"`
+	PasswordResetTTL        time.Duration `mapstructure:"PASSWORD_RESET_TTL"`
+	PasswordResetLimit      int           `mapstructure:"PASSWORD_RESET_LIMIT"`
+	LoginLockoutThreshold   int           `mapstructure:"LOGIN_LOCKOUT_THRESHOLD"`
+	LoginIPLockoutThreshold int           `mapstructure:"LOGIN_IP_LOCKOUT_THRESHOLD"`
+	LoginLockoutDuration    time.Duration `mapstructure:"LOGIN_LOCKOUT_DURATION"`
+	MFAEncryptionKey        string        `mapstructure:"MFA_ENCRYPTION_KEY"`
+	MFAIssuer               string        `mapstructure:"MFA_ISSUER"`
+	MFATokenDuration        time.Duration `mapstructure:"MFA_TOKEN_DURATION"`
+	PasswordHasher          string        `mapstructure:"PASSWORD_HASHER"`
+	BcryptCost              int           `mapstructure:"BCRYPT_COST"`
+	Argon2Memory            uint32        `mapstructure:"ARGON2_MEMORY"`
+	Argon2Iterations        uint32        `mapstructure:"ARGON2_ITERATIONS"`
+	Argon2Parallelism       uint8         `mapstructure:"ARGON2_PARALLELISM"`
+	PasswordMinLength       int           `mapstructure:"PASSWORD_MIN_LENGTH"`
+	PasswordMaxLength       int           `mapstructure:"PASSWORD_MAX_LENGTH"`
+	PasswordRequiredClasses []string      `mapstructure:"PASSWORD_REQUIRED_CLASSES"`
+	BreachedPasswordsDir    string        `mapstructure:"BREACHED_PASSWORDS_DIR"`
+	OAuthRefreshTokenTTL    time.Duration `mapstructure:"OAUTH_REFRESH_TOKEN_TTL"`
+	WebAuthnRPID            string        `mapstructure:"WEBAUTHN_RP_ID"`
+	WebAuthnRPDisplayName   string        `mapstructure:"WEBAUTHN_RP_DISPLAY_NAME"`
+	WebAuthnRPOrigins       []string      `mapstructure:"WEBAUTHN_RP_ORIGINS"`
 }
 
 func LoadConfig(path string) (config Config, err error) {
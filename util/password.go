@@ -1,18 +1,112 @@
 package util
 
 import (
+	"errors"
 	"fmt"
+	"strings"
 
 	"golang.org/x/crypto/bcrypt"
 )
 
-// HashPassword returns the bcrypt hash of the password
+// list of supported password hashing algorithms
+const (
+	PasswordArgon2id = "argon2id"
+	PasswordBcrypt   = "bcrypt"
+)
+
+// ErrPasswordMismatch is returned when a password does not match its hash
+var ErrPasswordMismatch = errors.New("password does not match")
+
+// PasswordHasher hashes passwords with its preferred algorithm and verifies the hashes of every supported one.
+// Hashes are stored in the PHC string format, so that each of them carries its algorithm and parameters
+type PasswordHasher interface {
+	// Hash returns the hash of a password
+	Hash(password string) (string, error)
+
+	// Verify checks a password against a hash of any supported algorithm
+	Verify(password string, hashedPassword string) error
+
+	// NeedsRehash tells whether a hash was made with another algorithm or other parameters than the hasher's
+	NeedsRehash(hashedPassword string) bool
+}
+
+// defaultPasswordHasher is the hasher of HashPassword
+var defaultPasswordHasher = NewArgon2idHasher(DefaultArgon2idParams)
+
+// NewPasswordHasher creates the password hasher set by the config, Argon2id by default
+func NewPasswordHasher(config Config) (PasswordHasher, error) {
+	switch config.PasswordHasher {
+	case "", PasswordArgon2id:
+		params := DefaultArgon2idParams
+		if config.Argon2Memory > 0 {
+			params.Memory = config.Argon2Memory
+		}
+		if config.Argon2Iterations > 0 {
+			params.Iterations = config.Argon2Iterations
+		}
+		if config.Argon2Parallelism > 0 {
+			params.Parallelism = config.Argon2Parallelism
+		}
+		return NewArgon2idHasher(params), nil
+	case PasswordBcrypt:
+		cost := config.BcryptCost
+		if cost == 0 {
+			cost = bcrypt.DefaultCost
+		}
+		return NewBcryptHasher(cost)
+	}
+
+	return nil, fmt.Errorf("unsupported password hasher %q", config.PasswordHasher)
+}
+
+// HashPassword returns the hash of the password with the default parameters of Argon2id
 func HashPassword(password string) (string, error) {
+	return defaultPasswordHasher.Hash(password)
+}
+
+// CheckPassword checks if the provided password is correct or not
+func CheckPassword(password string, hashedPassword string) error {
+	return verifyPassword(password, hashedPassword)
+}
+
+// verifyPassword checks a password against a hash of any supported algorithm, told apart by its prefix
+func verifyPassword(password string, hashedPassword string) error {
+	switch {
+	case strings.HasPrefix(hashedPassword, "$argon2id$"):
+		return verifyArgon2id(password, hashedPassword)
+	case isBcryptHash(hashedPassword):
+		err := bcrypt.CompareHashAndPassword([]byte(hashedPassword), []byte(password))
+		if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+			return ErrPasswordMismatch
+		}
+		return err
+	}
+
+	return errors.New("unsupported password hash")
+}
+
+// BcryptHasher hashes passwords with bcrypt, which only uses their first 72 bytes,
+// so longer passwords are refused rather than silently truncated
+type BcryptHasher struct {
+	cost int
+}
+
+// NewBcryptHasher creates a new BcryptHasher with the given cost
+func NewBcryptHasher(cost int) (*BcryptHasher, error) {
+	if cost < bcrypt.MinCost || cost > bcrypt.MaxCost {
+		return nil, fmt.Errorf("invalid bcrypt cost %d: must be between %d and %d", cost, bcrypt.MinCost, bcrypt.MaxCost)
+	}
+
+	return &BcryptHasher{cost: cost}, nil
+}
+
+// Hash returns the bcrypt hash of the password
+func (hasher *BcryptHasher) Hash(password string) (string, error) {
 	if password == "" {
 		return "", fmt.Errorf("password is empty")
 	}
 
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), hasher.cost)
 	if err != nil {
 		return "", fmt.Errorf("failed to hash password: %w", err)
 	}
@@ -20,7 +114,26 @@ func HashPassword(password string) (string, error) {
 	return string(hashedPassword), nil
 }
 
-// CheckPassword checks if the provided password is correct or not
-func CheckPassword(password string, hashedPassword string) error {
-	return bcrypt.CompareHashAndPassword([]byte(hashedPassword), []byte(password))
+// Verify checks a password against a hash of any supported algorithm
+func (hasher *BcryptHasher) Verify(password string, hashedPassword string) error {
+	return verifyPassword(password, hashedPassword)
+}
+
+// NeedsRehash tells whether a hash is not a bcrypt hash of the hasher's cost
+func (hasher *BcryptHasher) NeedsRehash(hashedPassword string) bool {
+	if !isBcryptHash(hashedPassword) {
+		return true
+	}
+
+	cost, err := bcrypt.Cost([]byte(hashedPassword))
+	return err != nil || cost != hasher.cost
+}
+
+func isBcryptHash(hashedPassword string) bool {
+	for _, prefix := range []string{"$2a$", "$2b$", "$2y$"} {
+		if strings.HasPrefix(hashedPassword, prefix) {
+			return true
+		}
+	}
+	return false
 }
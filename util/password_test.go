@@ -1,6 +1,7 @@
 package util
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/require"
@@ -24,7 +25,7 @@ func TestHashPassword(t *testing.T) {
 		hashedPassword2, err := HashPassword(password)
 		require.NoError(t, err)
 
-		// bcrypt generates different hashes each time due to salt
+		// every hash has its own random salt
 		require.NotEqual(t, hashedPassword1, hashedPassword2)
 	})
 
@@ -52,7 +53,7 @@ func TestCheckPassword(t *testing.T) {
 
 		wrongPassword := RandomString(6)
 		err = CheckPassword(wrongPassword, hashedPassword)
-		require.EqualError(t, err, bcrypt.ErrMismatchedHashAndPassword.Error())
+		require.ErrorIs(t, err, ErrPasswordMismatch)
 	})
 
 	t.Run("should reject empty password", func(t *testing.T) {
@@ -61,6 +62,110 @@ func TestCheckPassword(t *testing.T) {
 		require.NoError(t, err)
 
 		err = CheckPassword("", hashedPassword)
-		require.EqualError(t, err, bcrypt.ErrMismatchedHashAndPassword.Error())
+		require.ErrorIs(t, err, ErrPasswordMismatch)
 	})
 }
+
+func TestArgon2idHasher(t *testing.T) {
+	hasher := NewArgon2idHasher(DefaultArgon2idParams)
+	password := RandomString(6)
+
+	hashedPassword, err := hasher.Hash(password)
+	require.NoError(t, err)
+	require.Regexp(t, `^\$argon2id\$v=19\$m=19456,t=2,p=1\$[A-Za-z0-9+/]{22}\$[A-Za-z0-9+/]{43}$`, hashedPassword)
+
+	require.NoError(t, hasher.Verify(password, hashedPassword))
+	require.ErrorIs(t, hasher.Verify(RandomString(6), hashedPassword), ErrPasswordMismatch)
+	require.False(t, hasher.NeedsRehash(hashedPassword))
+
+	// passwords longer than 72 bytes are not truncated
+	long := strings.Repeat("a", 72)
+	hashedPassword, err = hasher.Hash(long + "1")
+	require.NoError(t, err)
+	require.ErrorIs(t, hasher.Verify(long+"2", hashedPassword), ErrPasswordMismatch)
+
+	require.Error(t, hasher.Verify(password, "$argon2id$v=19$m=19456,t=2,p=1$bad$hash!"))
+}
+
+func TestBcryptHasher(t *testing.T) {
+	hasher, err := NewBcryptHasher(bcrypt.MinCost)
+	require.NoError(t, err)
+	password := RandomString(6)
+
+	hashedPassword, err := hasher.Hash(password)
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(hashedPassword, "$2a$04$"))
+
+	require.NoError(t, hasher.Verify(password, hashedPassword))
+	require.ErrorIs(t, hasher.Verify(RandomString(6), hashedPassword), ErrPasswordMismatch)
+	require.False(t, hasher.NeedsRehash(hashedPassword))
+
+	// bcrypt only uses the first 72 bytes of a password, so longer ones are refused
+	_, err = hasher.Hash(strings.Repeat("a", 73))
+	require.Error(t, err)
+
+	_, err = NewBcryptHasher(bcrypt.MaxCost + 1)
+	require.Error(t, err)
+}
+
+func TestPasswordHasherNeedsRehash(t *testing.T) {
+	password := RandomString(6)
+
+	argon2id := NewArgon2idHasher(DefaultArgon2idParams)
+	argon2idHash, err := argon2id.Hash(password)
+	require.NoError(t, err)
+
+	stronger := DefaultArgon2idParams
+	stronger.Iterations++
+	strongerArgon2id := NewArgon2idHasher(stronger)
+
+	bcryptHasher, err := NewBcryptHasher(bcrypt.MinCost)
+	require.NoError(t, err)
+	bcryptHash, err := bcryptHasher.Hash(password)
+	require.NoError(t, err)
+
+	costlierBcrypt, err := NewBcryptHasher(bcrypt.MinCost + 1)
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name        string
+		hasher      PasswordHasher
+		hash        string
+		needsRehash bool
+	}{
+		{name: "SameArgon2id", hasher: argon2id, hash: argon2idHash, needsRehash: false},
+		{name: "WeakerArgon2id", hasher: strongerArgon2id, hash: argon2idHash, needsRehash: true},
+		{name: "BcryptToArgon2id", hasher: argon2id, hash: bcryptHash, needsRehash: true},
+		{name: "SameBcrypt", hasher: bcryptHasher, hash: bcryptHash, needsRehash: false},
+		{name: "CheaperBcrypt", hasher: costlierBcrypt, hash: bcryptHash, needsRehash: true},
+		{name: "Argon2idToBcrypt", hasher: bcryptHasher, hash: argon2idHash, needsRehash: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.needsRehash, tc.hasher.NeedsRehash(tc.hash))
+
+			// any hasher verifies the hashes of every algorithm
+			require.NoError(t, tc.hasher.Verify(password, tc.hash))
+		})
+	}
+}
+
+func TestNewPasswordHasher(t *testing.T) {
+	hasher, err := NewPasswordHasher(Config{})
+	require.NoError(t, err)
+	require.IsType(t, &Argon2idHasher{}, hasher)
+
+	hasher, err = NewPasswordHasher(Config{PasswordHasher: PasswordArgon2id, Argon2Iterations: 3})
+	require.NoError(t, err)
+	hashedPassword, err := hasher.Hash(RandomString(6))
+	require.NoError(t, err)
+	require.Contains(t, hashedPassword, "t=3")
+
+	hasher, err = NewPasswordHasher(Config{PasswordHasher: PasswordBcrypt})
+	require.NoError(t, err)
+	require.IsType(t, &BcryptHasher{}, hasher)
+
+	_, err = NewPasswordHasher(Config{PasswordHasher: "md5"})
+	require.ErrorContains(t, err, "md5")
+}
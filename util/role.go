@@ -0,0 +1,7 @@
+package util
+
+// list of user roles
+const (
+	DepositorRole = "depositor"
+	AdminRole     = "admin"
+)
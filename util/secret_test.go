@@ -0,0 +1,31 @@
+package util
+
+import (
+	"encoding/base64"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRandomSecret(t *testing.T) {
+	secret1, err := RandomSecret(32)
+	require.NoError(t, err)
+
+	decoded, err := base64.RawURLEncoding.DecodeString(secret1)
+	require.NoError(t, err)
+	require.Len(t, decoded, 32)
+
+	secret2, err := RandomSecret(32)
+	require.NoError(t, err)
+	require.NotEqual(t, secret1, secret2)
+}
+
+func TestHashSecret(t *testing.T) {
+	secret, err := RandomSecret(32)
+	require.NoError(t, err)
+
+	hash := HashSecret(secret)
+	require.Len(t, hash, 64)
+	require.Equal(t, hash, HashSecret(secret))
+	require.NotEqual(t, hash, HashSecret(secret+"x"))
+}
@@ -0,0 +1,16 @@
+package util
+
+// list of customer tiers
+const (
+	StandardTier  = "standard"
+	PremiumTier   = "premium"
+	CorporateTier = "corporate"
+)
+
+func IsSupportedTier(tier string) bool {
+	switch tier {
+	case StandardTier, PremiumTier, CorporateTier:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,42 @@
+package webhook
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckURL(t *testing.T) {
+	testCases := []struct {
+		url     string
+		allowed bool
+	}{
+		{url: "https://93.184.216.34/hooks", allowed: true},
+		{url: "https://[2606:2800:220:1:248:1893:25c8:1946]/hooks", allowed: true},
+		{url: "http://127.0.0.1:8080/hooks", allowed: false},
+		{url: "http://[::1]/hooks", allowed: false},
+		{url: "http://localhost:3000/hooks", allowed: false},
+		{url: "http://api.localhost/hooks", allowed: false},
+		{url: "http://10.1.2.3/hooks", allowed: false},
+		{url: "http://172.16.0.1/hooks", allowed: false},
+		{url: "http://192.168.1.1/hooks", allowed: false},
+		{url: "http://100.64.0.1/hooks", allowed: false},
+		{url: "http://169.254.169.254/latest/meta-data", allowed: false},
+		{url: "http://[fe80::1]/hooks", allowed: false},
+		{url: "http://[fd00::1]/hooks", allowed: false},
+		{url: "http://[::ffff:127.0.0.1]/hooks", allowed: false},
+		{url: "http://0.0.0.0/hooks", allowed: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.url, func(t *testing.T) {
+			err := CheckURL(context.Background(), tc.url)
+			if tc.allowed {
+				require.NoError(t, err)
+			} else {
+				require.ErrorIs(t, err, ErrForbiddenAddress)
+			}
+		})
+	}
+}
@@ -0,0 +1,95 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const defaultTimeout = 10 * time.Second
+
+// Sender posts signed payloads to webhook endpoints
+type Sender struct {
+	client *http.Client
+	now    func() time.Time
+}
+
+// SenderOption configures a Sender
+type SenderOption func(*senderOptions)
+
+type senderOptions struct {
+	allowPrivate bool
+}
+
+// AllowPrivateAddresses lets a Sender post to loopback and private addresses, for local receivers and tests
+func AllowPrivateAddresses() SenderOption {
+	return func(opts *senderOptions) {
+		opts.allowPrivate = true
+	}
+}
+
+// NewSender creates a new Sender with a request timeout.
+// It only connects to public addresses and does not follow redirects, so that a subscription
+// cannot reach the bank's own network; a redirect is recorded as a failed delivery
+func NewSender(timeout time.Duration, opts ...SenderOption) *Sender {
+	if timeout <= 0 {
+		timeout = defaultTimeout
+	}
+	var options senderOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	dialer := &net.Dialer{Timeout: timeout}
+	if !options.allowPrivate {
+		dialer.Control = dialPublic
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	// a proxy would be the address checked by the dialer instead of the endpoint
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+
+	return &Sender{
+		client: &http.Client{
+			Transport: transport,
+			Timeout:   timeout,
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+		now: time.Now,
+	}
+}
+
+// Send posts a JSON body to the URL, signed with the secret of the subscription.
+// It returns the status of the response, or 0 if no response was received; any status other than 2xx is an error
+func (sender *Sender) Send(ctx context.Context, url string, secret string, deliveryID int64, body []byte) (int, error) {
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+
+	timestamp := sender.now()
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set(IDHeader, strconv.FormatInt(deliveryID, 10))
+	request.Header.Set(TimestampHeader, strconv.FormatInt(timestamp.Unix(), 10))
+	request.Header.Set(SignatureHeader, Sign(secret, timestamp, body))
+
+	response, err := sender.client.Do(request)
+	if err != nil {
+		return 0, err
+	}
+	defer response.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(response.Body, 64<<10))
+
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return response.StatusCode, fmt.Errorf("webhook endpoint responded with status %d", response.StatusCode)
+	}
+
+	return response.StatusCode, nil
+}
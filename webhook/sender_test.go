@@ -0,0 +1,89 @@
+package webhook
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSenderSend(t *testing.T) {
+	secret, err := NewSecret()
+	require.NoError(t, err)
+	body := []byte(`{"id":1}`)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		require.Equal(t, body, got)
+		require.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		require.Equal(t, "42", r.Header.Get(IDHeader))
+
+		err = Verify(secret, r.Header.Get(TimestampHeader), r.Header.Get(SignatureHeader), got, time.Now(), time.Minute)
+		require.NoError(t, err)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	status, err := NewSender(time.Second, AllowPrivateAddresses()).Send(context.Background(), server.URL, secret, 42, body)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusNoContent, status)
+}
+
+func TestSenderSendErrorStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	status, err := NewSender(time.Second, AllowPrivateAddresses()).Send(context.Background(), server.URL, "secret", 1, []byte(`{}`))
+	require.Error(t, err)
+	require.Equal(t, http.StatusInternalServerError, status)
+}
+
+func TestSenderSendTimeout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+	}))
+	defer server.Close()
+
+	status, err := NewSender(50*time.Millisecond, AllowPrivateAddresses()).Send(context.Background(), server.URL, "secret", 1, []byte(`{}`))
+	require.Error(t, err)
+	require.Zero(t, status)
+}
+
+func TestSenderSendPrivateAddress(t *testing.T) {
+	var called bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}))
+	defer server.Close()
+
+	status, err := NewSender(time.Second).Send(context.Background(), server.URL, "secret", 1, []byte(`{}`))
+	require.ErrorIs(t, err, ErrForbiddenAddress)
+	require.Zero(t, status)
+	require.False(t, called)
+}
+
+func TestSenderSendRedirect(t *testing.T) {
+	var redirected bool
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		redirected = true
+	}))
+	defer target.Close()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, target.URL, http.StatusTemporaryRedirect)
+	}))
+	defer server.Close()
+
+	// the redirect is not followed, so it cannot lead the delivery to another address
+	status, err := NewSender(time.Second, AllowPrivateAddresses()).Send(context.Background(), server.URL, "secret", 1, []byte(`{}`))
+	require.Error(t, err)
+	require.Equal(t, http.StatusTemporaryRedirect, status)
+	require.False(t, redirected)
+}
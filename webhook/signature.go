@@ -0,0 +1,79 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Headers sent with every delivery
+const (
+	IDHeader        = "Webhook-Id"
+	TimestampHeader = "Webhook-Timestamp"
+	SignatureHeader = "Webhook-Signature"
+)
+
+const (
+	signatureVersion = "v1"
+	secretPrefix     = "whsec_"
+	secretBytes      = 32
+)
+
+// Different types of errors returned when a signature is verified
+var (
+	ErrInvalidSignature = errors.New("webhook signature is invalid")
+	ErrStaleTimestamp   = errors.New("webhook timestamp is outside the tolerance")
+)
+
+// NewSecret generates a random signing secret for a subscription
+func NewSecret() (string, error) {
+	key := make([]byte, secretBytes)
+	if _, err := rand.Read(key); err != nil {
+		return "", fmt.Errorf("cannot generate webhook secret: %w", err)
+	}
+
+	return secretPrefix + hex.EncodeToString(key), nil
+}
+
+// Sign computes the signature header of a payload sent at the given time.
+// The HMAC-SHA256 covers the unix timestamp and the body, so a captured request cannot be replayed later
+func Sign(secret string, timestamp time.Time, body []byte) string {
+	return signatureVersion + "=" + hex.EncodeToString(mac(secret, timestamp.Unix(), body))
+}
+
+// Verify checks the timestamp and signature headers of a delivery received at now.
+// Receivers should reject deliveries signed more than tolerance away from their clock
+func Verify(secret string, timestampHeader string, signatureHeader string, body []byte, now time.Time, tolerance time.Duration) error {
+	unix, err := strconv.ParseInt(timestampHeader, 10, 64)
+	if err != nil {
+		return ErrInvalidSignature
+	}
+	if delta := now.Sub(time.Unix(unix, 0)).Abs(); delta > tolerance {
+		return ErrStaleTimestamp
+	}
+
+	signature, ok := strings.CutPrefix(signatureHeader, signatureVersion+"=")
+	if !ok {
+		return ErrInvalidSignature
+	}
+	got, err := hex.DecodeString(signature)
+	if err != nil || !hmac.Equal(got, mac(secret, unix, body)) {
+		return ErrInvalidSignature
+	}
+
+	return nil
+}
+
+func mac(secret string, unix int64, body []byte) []byte {
+	h := hmac.New(sha256.New, []byte(secret))
+	h.Write([]byte(strconv.FormatInt(unix, 10)))
+	h.Write([]byte("."))
+	h.Write(body)
+	return h.Sum(nil)
+}
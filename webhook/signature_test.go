@@ -0,0 +1,44 @@
+package webhook
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSignVerify(t *testing.T) {
+	secret, err := NewSecret()
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(secret, secretPrefix))
+
+	body := []byte(`{"id":1,"event_type":"transfer.completed"}`)
+	signedAt := time.Now()
+	timestamp := strconv.FormatInt(signedAt.Unix(), 10)
+	signature := Sign(secret, signedAt, body)
+
+	err = Verify(secret, timestamp, signature, body, signedAt.Add(time.Minute), 5*time.Minute)
+	require.NoError(t, err)
+
+	// tampered body
+	err = Verify(secret, timestamp, signature, []byte(`{"id":2}`), signedAt, 5*time.Minute)
+	require.ErrorIs(t, err, ErrInvalidSignature)
+
+	// wrong secret
+	err = Verify("another secret", timestamp, signature, body, signedAt, 5*time.Minute)
+	require.ErrorIs(t, err, ErrInvalidSignature)
+
+	// the timestamp is part of the signature
+	forged := strconv.FormatInt(signedAt.Add(time.Second).Unix(), 10)
+	err = Verify(secret, forged, signature, body, signedAt, 5*time.Minute)
+	require.ErrorIs(t, err, ErrInvalidSignature)
+
+	// replayed too late
+	err = Verify(secret, timestamp, signature, body, signedAt.Add(10*time.Minute), 5*time.Minute)
+	require.ErrorIs(t, err, ErrStaleTimestamp)
+
+	err = Verify(secret, timestamp, "v0=abc", body, signedAt, 5*time.Minute)
+	require.ErrorIs(t, err, ErrInvalidSignature)
+}
@@ -0,0 +1,81 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	db "github.com/roman-adamchik/simplebank/db/sqlc"
+	"github.com/roman-adamchik/simplebank/task"
+)
+
+// TaskExpireHolds is the periodic task expiring stale holds
+const TaskExpireHolds = "hold:expire"
+
+const (
+	defaultHoldInterval = time.Minute
+	holdBatchSize       = 100
+)
+
+// HoldExpirer releases holds that were not captured in time
+type HoldExpirer struct {
+	store    db.Store
+	interval time.Duration
+}
+
+// NewHoldExpirer creates a new HoldExpirer polling for stale holds every interval
+func NewHoldExpirer(store db.Store, interval time.Duration) *HoldExpirer {
+	if interval <= 0 {
+		interval = defaultHoldInterval
+	}
+
+	return &HoldExpirer{
+		store:    store,
+		interval: interval,
+	}
+}
+
+// Schedule expires stale holds every interval through the task queue
+func (expirer *HoldExpirer) Schedule(ctx context.Context, distributor task.Distributor, processor task.Processor) error {
+	return task.Every(ctx, distributor, processor, TaskExpireHolds, expirer.interval, func(ctx context.Context) error {
+		return expirer.ExpireStale(ctx, time.Now())
+	})
+}
+
+// ExpireStale expires all active holds that are overdue at the given time and releases their amounts
+func (expirer *HoldExpirer) ExpireStale(ctx context.Context, now time.Time) error {
+	for {
+		holds, err := expirer.store.ListExpiredHolds(ctx, db.ListExpiredHoldsParams{
+			ExpiresAt: pgtype.Timestamptz{Time: now, Valid: true},
+			Limit:     holdBatchSize,
+		})
+		if err != nil {
+			return err
+		}
+
+		expired := 0
+		for _, hold := range holds {
+			_, err := expirer.store.ExpireHoldTx(ctx, db.ExpireHoldTxParams{
+				HoldID: hold.ID,
+				Now:    now,
+			})
+			if err != nil {
+				// the hold was captured or released in the meantime
+				if !errors.Is(err, db.ErrHoldNotActive) {
+					log.Printf("cannot expire hold %d: %v", hold.ID, err)
+				}
+				continue
+			}
+
+			expired++
+			log.Printf("hold %d expired without capture", hold.ID)
+		}
+
+		// stop when the batch is exhausted or nothing could be expired, so that a persistent error does not spin
+		if len(holds) < holdBatchSize || expired == 0 {
+			return nil
+		}
+	}
+}
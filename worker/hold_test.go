@@ -0,0 +1,103 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	mockdb "github.com/roman-adamchik/simplebank/db/mock"
+	db "github.com/roman-adamchik/simplebank/db/sqlc"
+	"github.com/roman-adamchik/simplebank/util"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func TestHoldExpirerExpireStale(t *testing.T) {
+	now := time.Now()
+	holds := []db.Hold{
+		{ID: util.RandomInt(1, 1000), Status: db.HoldActive},
+		{ID: util.RandomInt(1001, 2000), Status: db.HoldActive},
+	}
+
+	testCases := []struct {
+		name       string
+		buildStubs func(store *mockdb.MockStore)
+		checkError func(t *testing.T, err error)
+	}{
+		{
+			name: "OK",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					ListExpiredHolds(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(holds, nil)
+				for _, hold := range holds {
+					expired := hold
+					expired.Status = db.HoldExpired
+					store.EXPECT().
+						ExpireHoldTx(gomock.Any(), gomock.Eq(db.ExpireHoldTxParams{
+							HoldID: hold.ID,
+							Now:    now,
+						})).
+						Times(1).
+						Return(expired, nil)
+				}
+			},
+			checkError: func(t *testing.T, err error) {
+				require.NoError(t, err)
+			},
+		},
+		{
+			name: "DecidedConcurrently",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					ListExpiredHolds(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(holds, nil)
+				store.EXPECT().
+					ExpireHoldTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.Hold{}, db.ErrHoldNotActive)
+				store.EXPECT().
+					ExpireHoldTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.Hold{}, errors.New("connection reset"))
+			},
+			checkError: func(t *testing.T, err error) {
+				require.NoError(t, err)
+			},
+		},
+		{
+			name: "ListError",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					ListExpiredHolds(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(nil, errors.New("connection reset"))
+				store.EXPECT().
+					ExpireHoldTx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkError: func(t *testing.T, err error) {
+				require.Error(t, err)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			expirer := NewHoldExpirer(store, time.Minute)
+			err := expirer.ExpireStale(context.Background(), now)
+			tc.checkError(t, err)
+		})
+	}
+}
@@ -0,0 +1,68 @@
+package worker
+
+import (
+	"context"
+	"time"
+
+	db "github.com/roman-adamchik/simplebank/db/sqlc"
+	"github.com/roman-adamchik/simplebank/outbox"
+	"github.com/roman-adamchik/simplebank/task"
+)
+
+// TaskRelayOutbox is the periodic task relaying unsent outbox events
+const TaskRelayOutbox = "outbox:relay"
+
+const (
+	defaultOutboxInterval = time.Second
+	outboxBatchSize       = 100
+	// outboxLease keeps claimed events from being published by another relay while they are in flight
+	outboxLease = time.Minute
+)
+
+// OutboxRelay delivers the events recorded in the outbox to a publisher
+type OutboxRelay struct {
+	store     db.Store
+	publisher outbox.Publisher
+	interval  time.Duration
+}
+
+// NewOutboxRelay creates a new OutboxRelay polling for unsent events every interval
+func NewOutboxRelay(store db.Store, publisher outbox.Publisher, interval time.Duration) *OutboxRelay {
+	if interval <= 0 {
+		interval = defaultOutboxInterval
+	}
+
+	return &OutboxRelay{
+		store:     store,
+		publisher: publisher,
+		interval:  interval,
+	}
+}
+
+// Schedule relays unsent events every interval through the task queue
+func (relay *OutboxRelay) Schedule(ctx context.Context, distributor task.Distributor, processor task.Processor) error {
+	return task.Every(ctx, distributor, processor, TaskRelayOutbox, relay.interval, func(ctx context.Context) error {
+		return relay.RelayPending(ctx)
+	})
+}
+
+// RelayPending delivers unsent events until none are left, at least once and in id order per aggregate as far as
+// db.RelayOutbox guarantees it. A run only claims the oldest event of every aggregate, so it relays until a run sends nothing.
+// It stops at the first event that cannot be published, which is retried on the next run
+func (relay *OutboxRelay) RelayPending(ctx context.Context) error {
+	for {
+		sent, err := relay.store.RelayOutbox(ctx, db.RelayOutboxParams{
+			Limit:   outboxBatchSize,
+			Now:     time.Now(),
+			Lease:   outboxLease,
+			Publish: relay.publisher.Publish,
+		})
+		if err != nil {
+			return err
+		}
+
+		if sent == 0 {
+			return nil
+		}
+	}
+}
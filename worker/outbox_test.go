@@ -0,0 +1,78 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	mockdb "github.com/roman-adamchik/simplebank/db/mock"
+	db "github.com/roman-adamchik/simplebank/db/sqlc"
+	"github.com/roman-adamchik/simplebank/outbox"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func TestOutboxRelayRelayPending(t *testing.T) {
+	testCases := []struct {
+		name       string
+		buildStubs func(store *mockdb.MockStore)
+		checkError func(t *testing.T, err error)
+	}{
+		{
+			name: "OK",
+			buildStubs: func(store *mockdb.MockStore) {
+				gomock.InOrder(
+					store.EXPECT().
+						RelayOutbox(gomock.Any(), gomock.Any()).
+						Times(1).
+						DoAndReturn(func(_ any, arg db.RelayOutboxParams) (int, error) {
+							require.Equal(t, int32(outboxBatchSize), arg.Limit)
+							require.Equal(t, outboxLease, arg.Lease)
+							require.WithinDuration(t, time.Now(), arg.Now, time.Second)
+							return outboxBatchSize, nil
+						}),
+					store.EXPECT().
+						RelayOutbox(gomock.Any(), gomock.Any()).
+						Times(1).
+						Return(3, nil),
+					store.EXPECT().
+						RelayOutbox(gomock.Any(), gomock.Any()).
+						Times(1).
+						Return(0, nil),
+				)
+			},
+			checkError: func(t *testing.T, err error) {
+				require.NoError(t, err)
+			},
+		},
+		{
+			name: "PublishError",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					RelayOutbox(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(2, errors.New("cannot publish event 3: connection refused"))
+			},
+			checkError: func(t *testing.T, err error) {
+				require.ErrorContains(t, err, "connection refused")
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			relay := NewOutboxRelay(store, outbox.NewLogPublisher(), time.Second)
+			err := relay.RelayPending(context.Background())
+			tc.checkError(t, err)
+		})
+	}
+}
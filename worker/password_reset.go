@@ -0,0 +1,141 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	db "github.com/roman-adamchik/simplebank/db/sqlc"
+	"github.com/roman-adamchik/simplebank/mail"
+	"github.com/roman-adamchik/simplebank/task"
+	"github.com/roman-adamchik/simplebank/util"
+)
+
+// TaskSendPasswordReset is the task sending a password reset link to an email
+const TaskSendPasswordReset = "email:send_password_reset"
+
+const (
+	defaultPasswordResetURL   = "http://localhost:3000/reset_password"
+	defaultPasswordResetTTL   = time.Hour
+	defaultPasswordResetLimit = 3
+	// passwordResetWindow is the period the number of reset links sent to a user is limited over
+	passwordResetWindow     = time.Hour
+	passwordResetTokenBytes = 32
+)
+
+// SendPasswordResetPayload is the payload of TaskSendPasswordReset
+type SendPasswordResetPayload struct {
+	Email string `json:"email"`
+}
+
+// PasswordResetSender sends the links users reset their password with.
+// Only the hash of a reset token is stored, the token itself is in the link
+type PasswordResetSender struct {
+	store  db.Store
+	mailer mail.Mailer
+	url    string
+	ttl    time.Duration
+	limit  int
+}
+
+// NewPasswordResetSender creates a new PasswordResetSender whose links point to resetURL and expire after ttl.
+// At most limit links are sent to a user per hour
+func NewPasswordResetSender(store db.Store, mailer mail.Mailer, resetURL string, ttl time.Duration, limit int) *PasswordResetSender {
+	if resetURL == "" {
+		resetURL = defaultPasswordResetURL
+	}
+	if ttl <= 0 {
+		ttl = defaultPasswordResetTTL
+	}
+	if limit <= 0 {
+		limit = defaultPasswordResetLimit
+	}
+
+	return &PasswordResetSender{
+		store:  store,
+		mailer: mailer,
+		url:    resetURL,
+		ttl:    ttl,
+		limit:  limit,
+	}
+}
+
+// SendPasswordReset is the handler of TaskSendPasswordReset.
+// Nothing is sent to an email that does not belong to a user or that was sent too many links already,
+// which the requester is never told about
+func (sender *PasswordResetSender) SendPasswordReset(ctx context.Context, payload []byte) error {
+	var arg SendPasswordResetPayload
+	if err := json.Unmarshal(payload, &arg); err != nil {
+		return fmt.Errorf("%w: %w", err, task.ErrSkipRetry)
+	}
+
+	user, err := sender.store.GetUserByEmail(ctx, arg.Email)
+	if err != nil {
+		if errors.Is(err, db.ErrRecordNotFound) {
+			return nil
+		}
+		return err
+	}
+	// a deactivated user cannot log in, so they get no link to set a password with
+	if user.DeactivatedAt.Valid {
+		return nil
+	}
+
+	now := time.Now()
+	sent, err := sender.store.CountPasswordResetsSince(ctx, db.CountPasswordResetsSinceParams{
+		Username: user.Username,
+		Since:    pgtype.Timestamptz{Time: now.Add(-passwordResetWindow), Valid: true},
+	})
+	if err != nil {
+		return err
+	}
+	if sent >= int64(sender.limit) {
+		log.Printf("password reset of user %s is rate limited: %d links sent in the last %s", user.Username, sent, passwordResetWindow)
+		return nil
+	}
+
+	token, err := util.RandomSecret(passwordResetTokenBytes)
+	if err != nil {
+		return err
+	}
+	link, err := sender.link(token)
+	if err != nil {
+		return fmt.Errorf("%w: %w", err, task.ErrSkipRetry)
+	}
+
+	_, err = sender.store.CreatePasswordReset(ctx, db.CreatePasswordResetParams{
+		Username:  user.Username,
+		TokenHash: util.HashSecret(token),
+		ExpiredAt: pgtype.Timestamptz{Time: now.Add(sender.ttl), Valid: true},
+	})
+	if err != nil {
+		return err
+	}
+
+	return sender.mailer.Send(ctx, mail.Message{
+		To:      []string{user.Email},
+		Subject: "Reset your password",
+		Body: fmt.Sprintf("Hello %s,\n\nA password reset was requested for your account. Open this link to choose a new password:\n%s\n\n"+
+			"The link expires in %s. If you did not request it, you can ignore this email.\n",
+			user.FullName, link, sender.ttl),
+	})
+}
+
+// link is the reset link of a token
+func (sender *PasswordResetSender) link(token string) (string, error) {
+	link, err := url.Parse(sender.url)
+	if err != nil {
+		return "", fmt.Errorf("invalid password reset url: %w", err)
+	}
+
+	query := link.Query()
+	query.Set("token", token)
+	link.RawQuery = query.Encode()
+
+	return link.String(), nil
+}
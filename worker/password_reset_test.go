@@ -0,0 +1,151 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	mockdb "github.com/roman-adamchik/simplebank/db/mock"
+	db "github.com/roman-adamchik/simplebank/db/sqlc"
+	"github.com/roman-adamchik/simplebank/mail"
+	"github.com/roman-adamchik/simplebank/util"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func TestSendPasswordReset(t *testing.T) {
+	user := db.User{
+		Username: util.RandomOwner(),
+		FullName: util.RandomOwner(),
+		Email:    util.RandomEmail(),
+	}
+	resetURL := "https://bank.example.com/reset_password"
+	ttl := 30 * time.Minute
+	limit := 3
+	deactivated := user
+	deactivated.DeactivatedAt = pgtype.Timestamptz{Time: time.Now(), Valid: true}
+
+	testCases := []struct {
+		name        string
+		buildStubs  func(store *mockdb.MockStore, tokenHash *string)
+		checkResult func(t *testing.T, messages []mail.Message, tokenHash string)
+	}{
+		{
+			name: "OK",
+			buildStubs: func(store *mockdb.MockStore, tokenHash *string) {
+				store.EXPECT().
+					GetUserByEmail(gomock.Any(), gomock.Eq(user.Email)).
+					Times(1).
+					Return(user, nil)
+				store.EXPECT().
+					CountPasswordResetsSince(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ context.Context, arg db.CountPasswordResetsSinceParams) (int64, error) {
+						require.Equal(t, user.Username, arg.Username)
+						require.WithinDuration(t, time.Now().Add(-passwordResetWindow), arg.Since.Time, time.Second)
+						return int64(limit - 1), nil
+					})
+				store.EXPECT().
+					CreatePasswordReset(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ context.Context, arg db.CreatePasswordResetParams) (db.PasswordReset, error) {
+						require.Equal(t, user.Username, arg.Username)
+						require.WithinDuration(t, time.Now().Add(ttl), arg.ExpiredAt.Time, time.Second)
+						*tokenHash = arg.TokenHash
+						return db.PasswordReset{ID: 1, Username: arg.Username, TokenHash: arg.TokenHash}, nil
+					})
+			},
+			checkResult: func(t *testing.T, messages []mail.Message, tokenHash string) {
+				require.Len(t, messages, 1)
+				require.Equal(t, []string{user.Email}, messages[0].To)
+
+				start := strings.Index(messages[0].Body, resetURL)
+				require.GreaterOrEqual(t, start, 0)
+				link, err := url.Parse(strings.Fields(messages[0].Body[start:])[0])
+				require.NoError(t, err)
+
+				// only the hash of the token in the link is stored
+				token := link.Query().Get("token")
+				require.NotEmpty(t, token)
+				require.NotEqual(t, token, tokenHash)
+				require.Equal(t, util.HashSecret(token), tokenHash)
+			},
+		},
+		{
+			name: "UnknownEmail",
+			buildStubs: func(store *mockdb.MockStore, tokenHash *string) {
+				store.EXPECT().
+					GetUserByEmail(gomock.Any(), gomock.Eq(user.Email)).
+					Times(1).
+					Return(db.User{}, db.ErrRecordNotFound)
+				store.EXPECT().
+					CreatePasswordReset(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResult: func(t *testing.T, messages []mail.Message, tokenHash string) {
+				require.Empty(t, messages)
+			},
+		},
+		{
+			name: "DeactivatedUser",
+			buildStubs: func(store *mockdb.MockStore, tokenHash *string) {
+				store.EXPECT().
+					GetUserByEmail(gomock.Any(), gomock.Eq(user.Email)).
+					Times(1).
+					Return(deactivated, nil)
+				store.EXPECT().
+					CreatePasswordReset(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResult: func(t *testing.T, messages []mail.Message, tokenHash string) {
+				require.Empty(t, messages)
+			},
+		},
+		{
+			name: "RateLimited",
+			buildStubs: func(store *mockdb.MockStore, tokenHash *string) {
+				store.EXPECT().
+					GetUserByEmail(gomock.Any(), gomock.Eq(user.Email)).
+					Times(1).
+					Return(user, nil)
+				store.EXPECT().
+					CountPasswordResetsSince(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(int64(limit), nil)
+				store.EXPECT().
+					CreatePasswordReset(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResult: func(t *testing.T, messages []mail.Message, tokenHash string) {
+				require.Empty(t, messages)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			var tokenHash string
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store, &tokenHash)
+
+			mailer := &testMailer{}
+			sender := NewPasswordResetSender(store, mailer, resetURL, ttl, limit)
+
+			payload, err := json.Marshal(SendPasswordResetPayload{Email: user.Email})
+			require.NoError(t, err)
+
+			err = sender.SendPasswordReset(context.Background(), payload)
+			require.NoError(t, err)
+			tc.checkResult(t, mailer.messages, tokenHash)
+		})
+	}
+}
@@ -0,0 +1,81 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	db "github.com/roman-adamchik/simplebank/db/sqlc"
+	"github.com/roman-adamchik/simplebank/task"
+)
+
+// TaskExpirePendingTransfers is the periodic task expiring stale pending transfers
+const TaskExpirePendingTransfers = "pending_transfer:expire"
+
+const (
+	defaultPendingTransferInterval = time.Minute
+	pendingTransferBatchSize       = 100
+)
+
+// PendingTransferExpirer expires pending transfers that were not approved in time
+type PendingTransferExpirer struct {
+	store    db.Store
+	interval time.Duration
+}
+
+// NewPendingTransferExpirer creates a new PendingTransferExpirer polling for stale pending transfers every interval
+func NewPendingTransferExpirer(store db.Store, interval time.Duration) *PendingTransferExpirer {
+	if interval <= 0 {
+		interval = defaultPendingTransferInterval
+	}
+
+	return &PendingTransferExpirer{
+		store:    store,
+		interval: interval,
+	}
+}
+
+// Schedule expires stale pending transfers every interval through the task queue
+func (expirer *PendingTransferExpirer) Schedule(ctx context.Context, distributor task.Distributor, processor task.Processor) error {
+	return task.Every(ctx, distributor, processor, TaskExpirePendingTransfers, expirer.interval, func(ctx context.Context) error {
+		return expirer.ExpireStale(ctx, time.Now())
+	})
+}
+
+// ExpireStale expires all pending transfers whose approval is overdue at the given time and releases their holds
+func (expirer *PendingTransferExpirer) ExpireStale(ctx context.Context, now time.Time) error {
+	for {
+		transfers, err := expirer.store.ListExpiredPendingTransfers(ctx, db.ListExpiredPendingTransfersParams{
+			ExpiresAt: pgtype.Timestamptz{Time: now, Valid: true},
+			Limit:     pendingTransferBatchSize,
+		})
+		if err != nil {
+			return err
+		}
+
+		expired := 0
+		for _, transfer := range transfers {
+			_, err := expirer.store.ExpireTransferTx(ctx, db.ExpireTransferTxParams{
+				TransferID: transfer.ID,
+				Now:        now,
+			})
+			if err != nil {
+				// the transfer was approved or rejected in the meantime
+				if !errors.Is(err, db.ErrTransferNotPending) {
+					log.Printf("cannot expire transfer %d: %v", transfer.ID, err)
+				}
+				continue
+			}
+
+			expired++
+			log.Printf("transfer %d expired without approval", transfer.ID)
+		}
+
+		// stop when the batch is exhausted or nothing could be expired, so that a persistent error does not spin
+		if len(transfers) < pendingTransferBatchSize || expired == 0 {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	mockdb "github.com/roman-adamchik/simplebank/db/mock"
+	db "github.com/roman-adamchik/simplebank/db/sqlc"
+	"github.com/roman-adamchik/simplebank/util"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func TestPendingTransferExpirerExpireStale(t *testing.T) {
+	now := time.Now()
+	transfers := []db.Transfer{
+		{ID: util.RandomInt(1, 1000), Status: db.TransferPending},
+		{ID: util.RandomInt(1001, 2000), Status: db.TransferPending},
+	}
+
+	testCases := []struct {
+		name       string
+		buildStubs func(store *mockdb.MockStore)
+		checkError func(t *testing.T, err error)
+	}{
+		{
+			name: "OK",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					ListExpiredPendingTransfers(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(transfers, nil)
+				for _, transfer := range transfers {
+					expired := transfer
+					expired.Status = db.TransferExpired
+					store.EXPECT().
+						ExpireTransferTx(gomock.Any(), gomock.Eq(db.ExpireTransferTxParams{
+							TransferID: transfer.ID,
+							Now:        now,
+						})).
+						Times(1).
+						Return(expired, nil)
+				}
+			},
+			checkError: func(t *testing.T, err error) {
+				require.NoError(t, err)
+			},
+		},
+		{
+			name: "DecidedConcurrently",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					ListExpiredPendingTransfers(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(transfers, nil)
+				store.EXPECT().
+					ExpireTransferTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.Transfer{}, db.ErrTransferNotPending)
+				store.EXPECT().
+					ExpireTransferTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.Transfer{}, errors.New("connection reset"))
+			},
+			checkError: func(t *testing.T, err error) {
+				require.NoError(t, err)
+			},
+		},
+		{
+			name: "ListError",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					ListExpiredPendingTransfers(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(nil, errors.New("connection reset"))
+				store.EXPECT().
+					ExpireTransferTx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkError: func(t *testing.T, err error) {
+				require.Error(t, err)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			expirer := NewPendingTransferExpirer(store, time.Minute)
+			err := expirer.ExpireStale(context.Background(), now)
+			tc.checkError(t, err)
+		})
+	}
+}
@@ -0,0 +1,81 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	db "github.com/roman-adamchik/simplebank/db/sqlc"
+	"github.com/roman-adamchik/simplebank/task"
+)
+
+// TaskProcessStandingOrders is the periodic task executing due standing orders
+const TaskProcessStandingOrders = "standing_order:process"
+
+const (
+	defaultStandingOrderInterval = time.Minute
+	standingOrderBatchSize       = 100
+)
+
+// StandingOrderProcessor executes standing orders once they become due
+type StandingOrderProcessor struct {
+	store    db.Store
+	interval time.Duration
+}
+
+// NewStandingOrderProcessor creates a new StandingOrderProcessor polling for due standing orders every interval
+func NewStandingOrderProcessor(store db.Store, interval time.Duration) *StandingOrderProcessor {
+	if interval <= 0 {
+		interval = defaultStandingOrderInterval
+	}
+
+	return &StandingOrderProcessor{
+		store:    store,
+		interval: interval,
+	}
+}
+
+// Schedule processes due standing orders every interval through the task queue
+func (processor *StandingOrderProcessor) Schedule(ctx context.Context, distributor task.Distributor, taskProcessor task.Processor) error {
+	return task.Every(ctx, distributor, taskProcessor, TaskProcessStandingOrders, processor.interval, func(ctx context.Context) error {
+		return processor.ProcessDue(ctx, time.Now())
+	})
+}
+
+// ProcessDue executes the occurrences of all standing orders that are due at the given time.
+// A failed occurrence is recorded in the standing order history and does not stop the others.
+func (processor *StandingOrderProcessor) ProcessDue(ctx context.Context, now time.Time) error {
+	for {
+		orders, err := processor.store.ListDueStandingOrders(ctx, db.ListDueStandingOrdersParams{
+			NextRunAt: pgtype.Timestamptz{Time: now, Valid: true},
+			Limit:     standingOrderBatchSize,
+		})
+		if err != nil {
+			return err
+		}
+
+		executed := 0
+		for _, order := range orders {
+			result, err := processor.store.ExecuteStandingOrderTx(ctx, db.ExecuteStandingOrderTxParams{
+				StandingOrderID: order.ID,
+				Now:             now,
+			})
+			if err != nil {
+				if !errors.Is(err, db.ErrStandingOrderNotDue) {
+					log.Printf("cannot execute standing order %d: %v", order.ID, err)
+				}
+				continue
+			}
+
+			executed++
+			log.Printf("standing order %d: occurrence scheduled at %s %s", order.ID, result.Run.ScheduledAt.Time.Format(time.RFC3339), result.Run.Status)
+		}
+
+		// stop when the batch is exhausted or nothing could be executed, so that a persistent error does not spin
+		if len(orders) < standingOrderBatchSize || executed == 0 {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	mockdb "github.com/roman-adamchik/simplebank/db/mock"
+	db "github.com/roman-adamchik/simplebank/db/sqlc"
+	"github.com/roman-adamchik/simplebank/util"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func TestStandingOrderProcessorProcessDue(t *testing.T) {
+	now := time.Now()
+	orders := []db.StandingOrder{
+		{ID: util.RandomInt(1, 1000), Status: db.StandingOrderActive},
+		{ID: util.RandomInt(1001, 2000), Status: db.StandingOrderActive},
+		{ID: util.RandomInt(2001, 3000), Status: db.StandingOrderActive},
+	}
+
+	testCases := []struct {
+		name       string
+		buildStubs func(store *mockdb.MockStore)
+		checkError func(t *testing.T, err error)
+	}{
+		{
+			name: "OK",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					ListDueStandingOrders(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(orders, nil)
+				for _, order := range orders {
+					store.EXPECT().
+						ExecuteStandingOrderTx(gomock.Any(), gomock.Eq(db.ExecuteStandingOrderTxParams{
+							StandingOrderID: order.ID,
+							Now:             now,
+						})).
+						Times(1).
+						Return(db.ExecuteStandingOrderTxResult{StandingOrder: order}, nil)
+				}
+			},
+			checkError: func(t *testing.T, err error) {
+				require.NoError(t, err)
+			},
+		},
+		{
+			name: "ContinuesAfterFailure",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					ListDueStandingOrders(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(orders, nil)
+				store.EXPECT().
+					ExecuteStandingOrderTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.ExecuteStandingOrderTxResult{}, db.ErrStandingOrderNotDue)
+				store.EXPECT().
+					ExecuteStandingOrderTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.ExecuteStandingOrderTxResult{}, errors.New("connection reset"))
+				store.EXPECT().
+					ExecuteStandingOrderTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.ExecuteStandingOrderTxResult{}, nil)
+			},
+			checkError: func(t *testing.T, err error) {
+				require.NoError(t, err)
+			},
+		},
+		{
+			name: "NothingDue",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					ListDueStandingOrders(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return([]db.StandingOrder{}, nil)
+				store.EXPECT().
+					ExecuteStandingOrderTx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkError: func(t *testing.T, err error) {
+				require.NoError(t, err)
+			},
+		},
+		{
+			name: "ListError",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					ListDueStandingOrders(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(nil, errors.New("connection reset"))
+				store.EXPECT().
+					ExecuteStandingOrderTx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkError: func(t *testing.T, err error) {
+				require.Error(t, err)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			processor := NewStandingOrderProcessor(store, time.Minute)
+			err := processor.ProcessDue(context.Background(), now)
+			tc.checkError(t, err)
+		})
+	}
+}
@@ -0,0 +1,118 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	db "github.com/roman-adamchik/simplebank/db/sqlc"
+	"github.com/roman-adamchik/simplebank/mail"
+	"github.com/roman-adamchik/simplebank/task"
+	"github.com/roman-adamchik/simplebank/util"
+)
+
+// TaskSendVerifyEmail is the task sending a verification link to the email of a user
+const TaskSendVerifyEmail = "email:send_verify"
+
+const (
+	defaultVerifyEmailURL = "http://localhost:8080/verify_email"
+	defaultVerifyEmailTTL = 24 * time.Hour
+	verifyEmailCodeBytes  = 32
+)
+
+// SendVerifyEmailPayload is the payload of TaskSendVerifyEmail
+type SendVerifyEmailPayload struct {
+	Username string `json:"username"`
+}
+
+// VerifyEmailSender sends the links users verify their email with.
+// Only the hash of a code is stored, the code itself is in the link
+type VerifyEmailSender struct {
+	store  db.Store
+	mailer mail.Mailer
+	url    string
+	ttl    time.Duration
+}
+
+// NewVerifyEmailSender creates a new VerifyEmailSender whose links point to verifyURL and expire after ttl
+func NewVerifyEmailSender(store db.Store, mailer mail.Mailer, verifyURL string, ttl time.Duration) *VerifyEmailSender {
+	if verifyURL == "" {
+		verifyURL = defaultVerifyEmailURL
+	}
+	if ttl <= 0 {
+		ttl = defaultVerifyEmailTTL
+	}
+
+	return &VerifyEmailSender{
+		store:  store,
+		mailer: mailer,
+		url:    verifyURL,
+		ttl:    ttl,
+	}
+}
+
+// SendVerifyEmail is the handler of TaskSendVerifyEmail.
+// Every attempt creates a new code, so a retry never sends a link that was not stored
+func (sender *VerifyEmailSender) SendVerifyEmail(ctx context.Context, payload []byte) error {
+	var arg SendVerifyEmailPayload
+	if err := json.Unmarshal(payload, &arg); err != nil {
+		return fmt.Errorf("%w: %w", err, task.ErrSkipRetry)
+	}
+
+	user, err := sender.store.GetUser(ctx, arg.Username)
+	if err != nil {
+		if errors.Is(err, db.ErrRecordNotFound) {
+			return fmt.Errorf("user %s not found: %w", arg.Username, task.ErrSkipRetry)
+		}
+		return err
+	}
+	if user.IsEmailVerified {
+		return nil
+	}
+
+	secretCode, err := util.RandomSecret(verifyEmailCodeBytes)
+	if err != nil {
+		return err
+	}
+
+	verifyEmail, err := sender.store.CreateVerifyEmail(ctx, db.CreateVerifyEmailParams{
+		Username:       user.Username,
+		Email:          user.Email,
+		SecretCodeHash: util.HashSecret(secretCode),
+		ExpiredAt:      pgtype.Timestamptz{Time: time.Now().Add(sender.ttl), Valid: true},
+	})
+	if err != nil {
+		return err
+	}
+
+	link, err := sender.link(verifyEmail, secretCode)
+	if err != nil {
+		return fmt.Errorf("%w: %w", err, task.ErrSkipRetry)
+	}
+
+	return sender.mailer.Send(ctx, mail.Message{
+		To:      []string{user.Email},
+		Subject: "Verify your email",
+		Body: fmt.Sprintf("Hello %s,\n\nPlease verify your email address by opening this link:\n%s\n\nThe link expires in %s.\n",
+			user.FullName, link, sender.ttl),
+	})
+}
+
+// link is the verification link of a code
+func (sender *VerifyEmailSender) link(verifyEmail db.VerifyEmail, secretCode string) (string, error) {
+	link, err := url.Parse(sender.url)
+	if err != nil {
+		return "", fmt.Errorf("invalid verify email url: %w", err)
+	}
+
+	query := link.Query()
+	query.Set("email_id", fmt.Sprint(verifyEmail.ID))
+	query.Set("secret_code", secretCode)
+	link.RawQuery = query.Encode()
+
+	return link.String(), nil
+}
@@ -0,0 +1,161 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	mockdb "github.com/roman-adamchik/simplebank/db/mock"
+	db "github.com/roman-adamchik/simplebank/db/sqlc"
+	"github.com/roman-adamchik/simplebank/mail"
+	"github.com/roman-adamchik/simplebank/task"
+	"github.com/roman-adamchik/simplebank/util"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+// testMailer records the messages it sends, failing with err if it is set
+type testMailer struct {
+	messages []mail.Message
+	err      error
+}
+
+func (mailer *testMailer) Send(ctx context.Context, message mail.Message) error {
+	if mailer.err != nil {
+		return mailer.err
+	}
+	mailer.messages = append(mailer.messages, message)
+	return nil
+}
+
+func TestSendVerifyEmail(t *testing.T) {
+	user := db.User{
+		Username: util.RandomOwner(),
+		FullName: util.RandomOwner(),
+		Email:    util.RandomEmail(),
+	}
+	verified := user
+	verified.IsEmailVerified = true
+	verifyURL := "https://bank.example.com/verify_email"
+	ttl := time.Hour
+	var codeHash string
+
+	testCases := []struct {
+		name        string
+		mailErr     error
+		buildStubs  func(store *mockdb.MockStore)
+		checkResult func(t *testing.T, err error, messages []mail.Message)
+	}{
+		{
+			name: "OK",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(user, nil)
+				store.EXPECT().
+					CreateVerifyEmail(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ context.Context, arg db.CreateVerifyEmailParams) (db.VerifyEmail, error) {
+						require.Equal(t, user.Username, arg.Username)
+						require.Equal(t, user.Email, arg.Email)
+						require.Len(t, arg.SecretCodeHash, 64)
+						codeHash = arg.SecretCodeHash
+						require.WithinDuration(t, time.Now().Add(ttl), arg.ExpiredAt.Time, time.Second)
+						return db.VerifyEmail{
+							ID:             7,
+							Username:       arg.Username,
+							Email:          arg.Email,
+							SecretCodeHash: arg.SecretCodeHash,
+						}, nil
+					})
+			},
+			checkResult: func(t *testing.T, err error, messages []mail.Message) {
+				require.NoError(t, err)
+				require.Len(t, messages, 1)
+				require.Equal(t, []string{user.Email}, messages[0].To)
+
+				start := strings.Index(messages[0].Body, verifyURL)
+				require.GreaterOrEqual(t, start, 0)
+				link, err := url.Parse(strings.Fields(messages[0].Body[start:])[0])
+				require.NoError(t, err)
+				require.Equal(t, "7", link.Query().Get("email_id"))
+				require.Len(t, link.Query().Get("secret_code"), 43)
+				// only the hash of the code in the link is stored
+				require.Equal(t, codeHash, util.HashSecret(link.Query().Get("secret_code")))
+			},
+		},
+		{
+			name: "AlreadyVerified",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(verified, nil)
+				store.EXPECT().
+					CreateVerifyEmail(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResult: func(t *testing.T, err error, messages []mail.Message) {
+				require.NoError(t, err)
+				require.Empty(t, messages)
+			},
+		},
+		{
+			name: "UserNotFound",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(db.User{}, db.ErrRecordNotFound)
+			},
+			checkResult: func(t *testing.T, err error, messages []mail.Message) {
+				require.ErrorIs(t, err, task.ErrSkipRetry)
+				require.Empty(t, messages)
+			},
+		},
+		{
+			name:    "MailerError",
+			mailErr: errors.New("connection refused"),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(user, nil)
+				store.EXPECT().
+					CreateVerifyEmail(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.VerifyEmail{ID: 7}, nil)
+			},
+			checkResult: func(t *testing.T, err error, messages []mail.Message) {
+				require.Error(t, err)
+				require.NotErrorIs(t, err, task.ErrSkipRetry)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			mailer := &testMailer{err: tc.mailErr}
+			sender := NewVerifyEmailSender(store, mailer, verifyURL, ttl)
+
+			payload, err := json.Marshal(SendVerifyEmailPayload{Username: user.Username})
+			require.NoError(t, err)
+
+			err = sender.SendVerifyEmail(context.Background(), payload)
+			tc.checkResult(t, err, mailer.messages)
+		})
+	}
+}
@@ -0,0 +1,179 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	db "github.com/roman-adamchik/simplebank/db/sqlc"
+	"github.com/roman-adamchik/simplebank/outbox"
+	"github.com/roman-adamchik/simplebank/task"
+	"github.com/roman-adamchik/simplebank/webhook"
+)
+
+// list of webhook tasks
+const (
+	// TaskDeliverWebhook is the task making one attempt of a webhook delivery
+	TaskDeliverWebhook = "webhook:deliver"
+	// TaskDispatchWebhooks is the periodic task enqueueing the attempts of due deliveries
+	TaskDispatchWebhooks = "webhook:dispatch"
+)
+
+const (
+	defaultWebhookInterval = 5 * time.Second
+	webhookBatchSize       = 100
+	// webhookLease keeps a claimed delivery from being sent again by another dispatcher while it is in flight
+	webhookLease = 5 * time.Minute
+	// webhookMaxAttempts is the number of attempts after which a delivery is given up
+	webhookMaxAttempts = 8
+	// webhookDisableAfter is the number of consecutive failed attempts after which a subscription is disabled
+	webhookDisableAfter = 20
+	webhookBaseDelay    = 30 * time.Second
+	webhookMaxDelay     = 6 * time.Hour
+)
+
+// WebhookDispatcher delivers the events queued for webhook subscriptions.
+// It polls for due deliveries and hands every attempt to the task queue, so attempts run on the task processors
+type WebhookDispatcher struct {
+	store       db.Store
+	sender      *webhook.Sender
+	distributor task.Distributor
+	interval    time.Duration
+}
+
+// NewWebhookDispatcher creates a new WebhookDispatcher polling for due deliveries every interval
+func NewWebhookDispatcher(store db.Store, sender *webhook.Sender, distributor task.Distributor, interval time.Duration) *WebhookDispatcher {
+	if interval <= 0 {
+		interval = defaultWebhookInterval
+	}
+
+	return &WebhookDispatcher{
+		store:       store,
+		sender:      sender,
+		distributor: distributor,
+		interval:    interval,
+	}
+}
+
+type deliverWebhookPayload struct {
+	DeliveryID int64 `json:"delivery_id"`
+}
+
+// Schedule dispatches due deliveries every interval through the task queue
+func (dispatcher *WebhookDispatcher) Schedule(ctx context.Context, distributor task.Distributor, processor task.Processor) error {
+	return task.Every(ctx, distributor, processor, TaskDispatchWebhooks, dispatcher.interval, func(ctx context.Context) error {
+		return dispatcher.DispatchDue(ctx, time.Now())
+	})
+}
+
+// DispatchDue enqueues an attempt of every pending delivery that is due at the given time
+func (dispatcher *WebhookDispatcher) DispatchDue(ctx context.Context, now time.Time) error {
+	for {
+		deliveries, err := dispatcher.store.ClaimWebhookDeliveries(ctx, db.ClaimWebhookDeliveriesParams{
+			LeaseUntil:    pgtype.Timestamptz{Time: now.Add(webhookLease), Valid: true},
+			Now:           pgtype.Timestamptz{Time: now, Valid: true},
+			MaxDeliveries: webhookBatchSize,
+		})
+		if err != nil {
+			return err
+		}
+
+		enqueued := 0
+		for _, delivery := range deliveries {
+			// the delivery schedules its own retries, and the id is unique to the claim, since a dead-lettered
+			// attempt keeps its id and would stop a later claim of the same attempt from being queued
+			err := dispatcher.distributor.Enqueue(ctx, TaskDeliverWebhook, deliverWebhookPayload{DeliveryID: delivery.ID},
+				task.ID(fmt.Sprintf("webhook-delivery-%d-%d-%d", delivery.ID, delivery.Attempts, delivery.NextAttemptAt.Time.UnixNano())),
+				task.MaxRetry(0),
+			)
+			if err != nil {
+				log.Printf("cannot enqueue webhook delivery %d: %v", delivery.ID, err)
+				continue
+			}
+			enqueued++
+		}
+
+		// stop when the batch is exhausted or nothing could be enqueued, so that a persistent error does not spin
+		if len(deliveries) < webhookBatchSize || enqueued == 0 {
+			return nil
+		}
+	}
+}
+
+// DeliverWebhook is the handler of TaskDeliverWebhook
+func (dispatcher *WebhookDispatcher) DeliverWebhook(ctx context.Context, payload []byte) error {
+	var arg deliverWebhookPayload
+	if err := json.Unmarshal(payload, &arg); err != nil {
+		return fmt.Errorf("%w: %w", err, task.ErrSkipRetry)
+	}
+
+	delivery, err := dispatcher.store.GetWebhookDelivery(ctx, arg.DeliveryID)
+	if err != nil {
+		return err
+	}
+	if delivery.Status != db.WebhookDeliveryPending {
+		return nil
+	}
+
+	return dispatcher.dispatch(ctx, delivery)
+}
+
+// dispatch makes one attempt of a delivery and records its outcome
+func (dispatcher *WebhookDispatcher) dispatch(ctx context.Context, delivery db.WebhookDelivery) error {
+	subscription, err := dispatcher.store.GetWebhookSubscription(ctx, delivery.SubscriptionID)
+	if err != nil {
+		return err
+	}
+	event, err := dispatcher.store.GetOutboxEvent(ctx, delivery.EventID)
+	if err != nil {
+		return err
+	}
+	body, err := json.Marshal(outbox.NewMessage(event))
+	if err != nil {
+		return err
+	}
+
+	status, sendErr := dispatcher.sender.Send(ctx, subscription.Url, subscription.Secret, delivery.ID, body)
+
+	arg := db.RecordWebhookAttemptTxParams{
+		DeliveryID:     delivery.ID,
+		ResponseStatus: int32(status),
+		DisableAfter:   webhookDisableAfter,
+		Now:            time.Now(),
+	}
+	if sendErr != nil {
+		arg.Error = sendErr.Error()
+		if attempt := delivery.Attempts + 1; attempt < webhookMaxAttempts {
+			arg.NextAttemptAt = arg.Now.Add(webhookRetryDelay(attempt))
+		}
+	}
+
+	result, err := dispatcher.store.RecordWebhookAttemptTx(ctx, arg)
+	if err != nil {
+		// the delivery was finished by another dispatcher after its lease ran out
+		if errors.Is(err, db.ErrWebhookDeliveryNotPending) {
+			return nil
+		}
+		return err
+	}
+
+	if result.Subscription.DisabledAt.Valid && !subscription.DisabledAt.Valid {
+		log.Printf("webhook subscription %d disabled after %d consecutive failures", subscription.ID, result.Subscription.FailureCount)
+	}
+
+	return nil
+}
+
+// webhookRetryDelay is the exponential backoff before the attempt following the given one
+func webhookRetryDelay(attempt int32) time.Duration {
+	delay := webhookBaseDelay
+	for i := int32(1); i < attempt && delay < webhookMaxDelay; i++ {
+		delay *= 2
+	}
+
+	return min(delay, webhookMaxDelay)
+}
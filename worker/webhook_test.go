@@ -0,0 +1,239 @@
+package worker
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	mockdb "github.com/roman-adamchik/simplebank/db/mock"
+	db "github.com/roman-adamchik/simplebank/db/sqlc"
+	"github.com/roman-adamchik/simplebank/outbox"
+	"github.com/roman-adamchik/simplebank/task"
+	"github.com/roman-adamchik/simplebank/util"
+	"github.com/roman-adamchik/simplebank/webhook"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func TestWebhookDispatcherDispatchDue(t *testing.T) {
+	now := time.Now()
+	event := db.OutboxEvent{
+		ID:            util.RandomInt(1, 1000),
+		AggregateType: db.AggregateTransfer,
+		AggregateID:   "7",
+		EventType:     db.EventTransferCompleted,
+		Payload:       []byte(`{"id":7,"amount":10}`),
+		CreatedAt:     pgtype.Timestamptz{Time: now, Valid: true},
+	}
+	secret, err := webhook.NewSecret()
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name         string
+		attempts     int32
+		status       int
+		checkAttempt func(t *testing.T, arg db.RecordWebhookAttemptTxParams)
+	}{
+		{
+			name:   "OK",
+			status: http.StatusOK,
+			checkAttempt: func(t *testing.T, arg db.RecordWebhookAttemptTxParams) {
+				require.Empty(t, arg.Error)
+				require.Equal(t, int32(http.StatusOK), arg.ResponseStatus)
+				require.True(t, arg.NextAttemptAt.IsZero())
+			},
+		},
+		{
+			name:     "Retry",
+			attempts: 2,
+			status:   http.StatusBadGateway,
+			checkAttempt: func(t *testing.T, arg db.RecordWebhookAttemptTxParams) {
+				require.NotEmpty(t, arg.Error)
+				require.Equal(t, int32(http.StatusBadGateway), arg.ResponseStatus)
+				require.WithinDuration(t, arg.Now.Add(4*webhookBaseDelay), arg.NextAttemptAt, time.Second)
+				require.Equal(t, int32(webhookDisableAfter), arg.DisableAfter)
+			},
+		},
+		{
+			name:     "GiveUp",
+			attempts: webhookMaxAttempts - 1,
+			status:   http.StatusInternalServerError,
+			checkAttempt: func(t *testing.T, arg db.RecordWebhookAttemptTxParams) {
+				require.NotEmpty(t, arg.Error)
+				require.True(t, arg.NextAttemptAt.IsZero())
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			received := 0
+			receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				received++
+				body, err := io.ReadAll(r.Body)
+				require.NoError(t, err)
+
+				err = webhook.Verify(secret, r.Header.Get(webhook.TimestampHeader), r.Header.Get(webhook.SignatureHeader), body, time.Now(), time.Minute)
+				require.NoError(t, err)
+
+				var message outbox.Message
+				require.NoError(t, json.Unmarshal(body, &message))
+				require.Equal(t, event.ID, message.ID)
+				require.Equal(t, event.EventType, message.EventType)
+				w.WriteHeader(tc.status)
+			}))
+			defer receiver.Close()
+
+			subscription := db.WebhookSubscription{
+				ID:         util.RandomInt(1, 1000),
+				Url:        receiver.URL,
+				EventTypes: []string{db.EventTransferCompleted},
+				Secret:     secret,
+			}
+			delivery := db.WebhookDelivery{
+				ID:             util.RandomInt(1, 1000),
+				SubscriptionID: subscription.ID,
+				EventID:        event.ID,
+				Status:         db.WebhookDeliveryPending,
+				Attempts:       tc.attempts,
+			}
+
+			store := mockdb.NewMockStore(ctrl)
+			store.EXPECT().
+				ClaimWebhookDeliveries(gomock.Any(), gomock.Eq(db.ClaimWebhookDeliveriesParams{
+					LeaseUntil:    pgtype.Timestamptz{Time: now.Add(webhookLease), Valid: true},
+					Now:           pgtype.Timestamptz{Time: now, Valid: true},
+					MaxDeliveries: webhookBatchSize,
+				})).
+				Times(1).
+				Return([]db.WebhookDelivery{delivery}, nil)
+			store.EXPECT().
+				GetWebhookDelivery(gomock.Any(), gomock.Eq(delivery.ID)).
+				Times(1).
+				Return(delivery, nil)
+			store.EXPECT().
+				GetWebhookSubscription(gomock.Any(), gomock.Eq(subscription.ID)).
+				Times(1).
+				Return(subscription, nil)
+			store.EXPECT().
+				GetOutboxEvent(gomock.Any(), gomock.Eq(event.ID)).
+				Times(1).
+				Return(event, nil)
+			store.EXPECT().
+				RecordWebhookAttemptTx(gomock.Any(), gomock.Any()).
+				Times(1).
+				DoAndReturn(func(_ context.Context, arg db.RecordWebhookAttemptTxParams) (db.RecordWebhookAttemptTxResult, error) {
+					require.Equal(t, delivery.ID, arg.DeliveryID)
+					tc.checkAttempt(t, arg)
+					return db.RecordWebhookAttemptTxResult{Subscription: subscription}, nil
+				})
+
+			queue := task.NewMemory()
+			dispatcher := NewWebhookDispatcher(store, webhook.NewSender(time.Second, webhook.AllowPrivateAddresses()), queue, time.Second)
+			queue.Handle(TaskDeliverWebhook, dispatcher.DeliverWebhook)
+			require.NoError(t, queue.Start())
+			defer queue.Shutdown()
+
+			err := dispatcher.DispatchDue(context.Background(), now)
+			require.NoError(t, err)
+			queue.Wait()
+			require.Equal(t, 1, received)
+		})
+	}
+}
+
+func TestWebhookDispatcherDispatchDueAfterDeadLetter(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	delivery := db.WebhookDelivery{
+		ID:             util.RandomInt(1, 1000),
+		SubscriptionID: util.RandomInt(1, 1000),
+		Status:         db.WebhookDeliveryPending,
+		Attempts:       1,
+	}
+
+	store := mockdb.NewMockStore(ctrl)
+	// the same attempt is claimed again once the lease of the first claim ran out
+	store.EXPECT().
+		ClaimWebhookDeliveries(gomock.Any(), gomock.Any()).
+		Times(2).
+		DoAndReturn(func(_ context.Context, arg db.ClaimWebhookDeliveriesParams) ([]db.WebhookDelivery, error) {
+			claimed := delivery
+			claimed.NextAttemptAt = arg.LeaseUntil
+			return []db.WebhookDelivery{claimed}, nil
+		})
+	store.EXPECT().
+		GetWebhookDelivery(gomock.Any(), gomock.Eq(delivery.ID)).
+		Times(2).
+		Return(delivery, nil)
+	store.EXPECT().
+		GetWebhookSubscription(gomock.Any(), gomock.Eq(delivery.SubscriptionID)).
+		Times(2).
+		Return(db.WebhookSubscription{}, sql.ErrConnDone)
+
+	// the memory queue keeps the id of a dead-lettered task as asynq keeps the id of an archived one
+	queue := task.NewMemory()
+	dispatcher := NewWebhookDispatcher(store, webhook.NewSender(time.Second, webhook.AllowPrivateAddresses()), queue, time.Second)
+	queue.Handle(TaskDeliverWebhook, dispatcher.DeliverWebhook)
+	require.NoError(t, queue.Start())
+	defer queue.Shutdown()
+
+	now := time.Now()
+	require.NoError(t, dispatcher.DispatchDue(context.Background(), now))
+	queue.Wait()
+	require.NoError(t, dispatcher.DispatchDue(context.Background(), now.Add(webhookLease)))
+	queue.Wait()
+
+	letters, err := queue.DeadLetters(context.Background(), task.QueueDefault)
+	require.NoError(t, err)
+	require.Len(t, letters, 2)
+	require.NotEqual(t, letters[0].ID, letters[1].ID)
+}
+
+func TestWebhookDispatcherDeliverWebhookNotPending(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	delivery := db.WebhookDelivery{
+		ID:     util.RandomInt(1, 1000),
+		Status: db.WebhookDeliverySucceeded,
+	}
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().
+		GetWebhookDelivery(gomock.Any(), gomock.Eq(delivery.ID)).
+		Times(1).
+		Return(delivery, nil)
+	store.EXPECT().
+		GetWebhookSubscription(gomock.Any(), gomock.Any()).
+		Times(0)
+	store.EXPECT().
+		RecordWebhookAttemptTx(gomock.Any(), gomock.Any()).
+		Times(0)
+
+	dispatcher := NewWebhookDispatcher(store, webhook.NewSender(time.Second, webhook.AllowPrivateAddresses()), task.NewMemory(), time.Second)
+	payload, err := json.Marshal(deliverWebhookPayload{DeliveryID: delivery.ID})
+	require.NoError(t, err)
+
+	err = dispatcher.DeliverWebhook(context.Background(), payload)
+	require.NoError(t, err)
+}
+
+func TestWebhookRetryDelay(t *testing.T) {
+	require.Equal(t, webhookBaseDelay, webhookRetryDelay(1))
+	require.Equal(t, 2*webhookBaseDelay, webhookRetryDelay(2))
+	require.Equal(t, 8*webhookBaseDelay, webhookRetryDelay(4))
+	require.Equal(t, webhookMaxDelay, webhookRetryDelay(100))
+}